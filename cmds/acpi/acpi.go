@@ -0,0 +1,109 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Acpi lists, extracts and decodes the ACPI tables, as acpidump does.
+//
+// Synopsis:
+//     acpi [-d] [-x DIR] [-n SIG] [-hex]
+//
+// Description:
+//     acpi lists the tables in /sys/firmware/acpi/tables: their signature,
+//     length, revision, OEM, and whether their checksum is right.
+//
+// Options:
+//     -n:   only the tables with signature SIG, such as APIC or SSDT
+//     -d:   decode the MADT (APIC), SRAT, FADT (FACP) and MCFG too
+//     -hex: dump the tables in hex too
+//     -x:   write each table to DIR/NAME.dat, such as DIR/dsdt.dat, for
+//           iasl -d to disassemble
+//
+// Example:
+//     $ acpi -d -n APIC
+//     APIC      64 rev 6  FIRECK FCVMMADT checksum ok
+//             Local APIC Address: 0xfee00000, Flags: 0x0
+//             I/O APIC: ID 0, Address 0xfec00000, GSI Base 0
+//             Local APIC: Processor 0, APIC ID 0, enabled
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/acpi"
+)
+
+var (
+	decode  = flag.Bool("d", false, "decode the MADT, SRAT, FADT and MCFG")
+	hexDump = flag.Bool("hex", false, "dump the tables in hex")
+	extract = flag.String("x", "", "write the tables to files in this directory")
+	sig     = flag.String("n", "", "only the tables with this signature")
+
+	// Tests change this.
+	sysTables = acpi.SysTables
+)
+
+// list prints a line for each table, and, with -d and -hex, what is in it.
+func list(w io.Writer, tables []*acpi.Table) {
+	for _, t := range tables {
+		ok := "ok"
+		if !t.ChecksumOK() {
+			ok = "BAD"
+		}
+		fmt.Fprintf(w, "%-6s %6d rev %-2d %-6s %-8s checksum %s\n", t.Name, t.Length, t.Revision, t.OEMID, t.OEMTableID, ok)
+		if d, known := decoders[t.Signature]; known && *decode {
+			for _, l := range d(t) {
+				fmt.Fprintf(w, "\t%s\n", l)
+			}
+		}
+		if *hexDump {
+			fmt.Fprint(w, hex.Dump(t.Data))
+		}
+	}
+}
+
+// write writes each table to dir, named as acpixtract names them: its name
+// in lower case, with .dat after it.
+func write(dir string, tables []*acpi.Table) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		n := strings.ToLower(filepath.Base(t.Name)) + ".dat"
+		if err := ioutil.WriteFile(filepath.Join(dir, n), t.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	tables, err := acpi.ReadTables(sysTables)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *sig != "" {
+		var only []*acpi.Table
+		for _, t := range tables {
+			if t.Signature == *sig {
+				only = append(only, t)
+			}
+		}
+		tables = only
+	}
+	if *extract != "" {
+		if err := write(*extract, tables); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	list(os.Stdout, tables)
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/acpi"
+)
+
+// madt is the MADT of a Firecracker VM with one CPU.
+const madt = "4150494340000000066946495245434b4643564d4d4144540000000046434154190124200000e0fe00000000010c00000000c0fe000000000008000001000000"
+
+// table makes a table with signature sig, and data after its header, with
+// the right length and checksum.
+func table(t *testing.T, sig string, data []byte) *acpi.Table {
+	b := append([]byte(sig+"\x00\x00\x00\x00\x01\x00OEMID OEMTABLECRTR"), make([]byte, 8)...)
+	b = append(b[:acpi.HeaderLen], data...)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+	var sum uint8
+	for _, c := range b {
+		sum += c
+	}
+	b[9] = -sum
+	tab, err := acpi.Parse(sig, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tab
+}
+
+func TestDecode(t *testing.T) {
+	b, err := hex.DecodeString(madt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apic, err := acpi.Parse("APIC", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two nodes, of a CPU and 2GB each.
+	var srat []byte
+	srat = append(srat, make([]byte, 12)...)
+	srat = append(srat, 0, 16, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	srat = append(srat, 0, 16, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	for i := uint64(0); i < 2; i++ {
+		m := make([]byte, 40)
+		m[0], m[1] = 1, 40
+		binary.LittleEndian.PutUint32(m[2:], uint32(i))
+		binary.LittleEndian.PutUint64(m[8:], i<<31)
+		binary.LittleEndian.PutUint64(m[16:], 1<<31)
+		binary.LittleEndian.PutUint32(m[28:], 1|uint32(i)<<1)
+		srat = append(srat, m...)
+	}
+
+	mcfg := make([]byte, 24)
+	binary.LittleEndian.PutUint64(mcfg[8:], 0xe0000000)
+	mcfg[8+11] = 0xff
+
+	for _, tt := range []struct {
+		t    *acpi.Table
+		want []string
+	}{
+		{apic, []string{
+			"Local APIC Address: 0xfee00000, Flags: 0x0",
+			"I/O APIC: ID 0, Address 0xfec00000, GSI Base 0",
+			"Local APIC: Processor 0, APIC ID 0, enabled",
+		}},
+		{table(t, "SRAT", srat), []string{
+			"Processor Affinity: APIC ID 0, Domain 0, enabled",
+			"Processor Affinity: APIC ID 1, Domain 1, enabled",
+			"Memory Affinity: Domain 0, 0x0-0x7fffffff, enabled",
+			"Memory Affinity: Domain 1, 0x80000000-0xffffffff, enabled, hot pluggable",
+		}},
+		{table(t, "MCFG", mcfg), []string{"Segment 0, Buses 0-255: 0xe0000000"}},
+		{table(t, "MCFG", nil), []string{"too short"}},
+	} {
+		if got := decoders[tt.t.Signature](tt.t); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("decode %v: got %q, want %q", tt.t.Signature, got, tt.want)
+		}
+	}
+}
+
+func TestListAndWrite(t *testing.T) {
+	b, err := hex.DecodeString(madt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apic, err := acpi.Parse("APIC", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssdt := table(t, "SSDT", []byte{1, 2, 3})
+	ssdt.Name = "dynamic/SSDT1"
+	ssdt.Data[20]++
+
+	var out bytes.Buffer
+	list(&out, []*acpi.Table{apic, ssdt})
+	want := `APIC       64 rev 6  FIRECK FCVMMADT checksum ok
+dynamic/SSDT1     39 rev 1  OEMID  OEMTABLE checksum BAD
+`
+	if out.String() != want {
+		t.Errorf("list: got\n%s\nwant\n%s", out.String(), want)
+	}
+
+	tmp, err := ioutil.TempDir("", "acpi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := write(filepath.Join(tmp, "x"), []*acpi.Table{apic, ssdt}); err != nil {
+		t.Fatal(err)
+	}
+	for n, want := range map[string][]byte{"apic.dat": apic.Data, "ssdt1.dat": ssdt.Data} {
+		got, err := ioutil.ReadFile(filepath.Join(tmp, "x", n))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%v: got %v, %v, want %v", n, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/acpi"
+)
+
+var (
+	u16 = binary.LittleEndian.Uint16
+	u32 = binary.LittleEndian.Uint32
+	u64 = binary.LittleEndian.Uint64
+)
+
+// decoders decode tables, by signature, into lines.
+var decoders = map[string]func(t *acpi.Table) []string{
+	"APIC": decodeMADT,
+	"SRAT": decodeSRAT,
+	"FACP": decodeFADT,
+	"MCFG": decodeMCFG,
+}
+
+// enabled describes the enabled bit, bit 0, of flags.
+func enabled(flags uint32) string {
+	if flags&1 != 0 {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// subtables calls f with the type and data of each of the subtables, which
+// start with their type and length, in b.
+func subtables(b []byte, f func(typ uint8, d []byte) string) []string {
+	var lines []string
+	for len(b) >= 2 {
+		l := int(b[1])
+		if l < 2 || l > len(b) {
+			return append(lines, fmt.Sprintf("bad subtable length %d", l))
+		}
+		lines = append(lines, f(b[0], b[:l]))
+		b = b[l:]
+	}
+	return lines
+}
+
+// decodeMADT decodes the Multiple APIC Description Table, which lists the
+// interrupt controllers.
+func decodeMADT(t *acpi.Table) []string {
+	b := t.Data
+	if len(b) < 44 {
+		return []string{"too short"}
+	}
+	lines := []string{fmt.Sprintf("Local APIC Address: %#x, Flags: %#x", u32(b[36:]), u32(b[40:]))}
+	return append(lines, subtables(b[44:], func(typ uint8, d []byte) string {
+		switch {
+		case typ == 0 && len(d) >= 8:
+			return fmt.Sprintf("Local APIC: Processor %d, APIC ID %d, %s", d[2], d[3], enabled(u32(d[4:])))
+		case typ == 1 && len(d) >= 12:
+			return fmt.Sprintf("I/O APIC: ID %d, Address %#x, GSI Base %d", d[2], u32(d[4:]), u32(d[8:]))
+		case typ == 2 && len(d) >= 10:
+			return fmt.Sprintf("Interrupt Source Override: Bus %d, Source %d, GSI %d, Flags %#x", d[2], d[3], u32(d[4:]), u16(d[8:]))
+		case typ == 3 && len(d) >= 8:
+			return fmt.Sprintf("NMI Source: Flags %#x, GSI %d", u16(d[2:]), u32(d[4:]))
+		case typ == 4 && len(d) >= 6:
+			return fmt.Sprintf("Local APIC NMI: Processor %#x, Flags %#x, LINT%d", d[2], u16(d[3:]), d[5])
+		case typ == 5 && len(d) >= 12:
+			return fmt.Sprintf("Local APIC Address Override: %#x", u64(d[4:]))
+		case typ == 9 && len(d) >= 16:
+			return fmt.Sprintf("Local x2APIC: x2APIC ID %d, UID %d, %s", u32(d[4:]), u32(d[12:]), enabled(u32(d[8:])))
+		case typ == 0xa && len(d) >= 12:
+			return fmt.Sprintf("Local x2APIC NMI: UID %#x, Flags %#x, LINT%d", u32(d[4:]), u16(d[2:]), d[8])
+		case typ == 0xb && len(d) >= 16:
+			return fmt.Sprintf("GIC CPU Interface: Number %d, UID %d, %s", u32(d[4:]), u32(d[8:]), enabled(u32(d[12:])))
+		case typ == 0xc && len(d) >= 24:
+			return fmt.Sprintf("GIC Distributor: ID %d, Address %#x, GSI Base %d", u32(d[4:]), u64(d[8:]), u32(d[16:]))
+		}
+		return fmt.Sprintf("Type %d: % x", typ, d[2:])
+	})...)
+}
+
+// decodeSRAT decodes the System Resource Affinity Table, which says which
+// NUMA node, proximity domain, processors and memory are in.
+func decodeSRAT(t *acpi.Table) []string {
+	b := t.Data
+	if len(b) < 48 {
+		return []string{"too short"}
+	}
+	return subtables(b[48:], func(typ uint8, d []byte) string {
+		switch {
+		case typ == 0 && len(d) >= 16:
+			// The proximity domain is split: bits 0-7, then 8-31.
+			dom := uint32(d[2]) | uint32(d[9])<<8 | uint32(d[10])<<16 | uint32(d[11])<<24
+			return fmt.Sprintf("Processor Affinity: APIC ID %d, Domain %d, %s", d[3], dom, enabled(u32(d[4:])))
+		case typ == 1 && len(d) >= 40:
+			base, length := u64(d[8:]), u64(d[16:])
+			s := fmt.Sprintf("Memory Affinity: Domain %d, %#x-%#x, %s", u32(d[2:]), base, base+length-1, enabled(u32(d[28:])))
+			if u32(d[28:])&2 != 0 {
+				s += ", hot pluggable"
+			}
+			if u32(d[28:])&4 != 0 {
+				s += ", non-volatile"
+			}
+			return s
+		case typ == 2 && len(d) >= 24:
+			return fmt.Sprintf("x2APIC Affinity: x2APIC ID %d, Domain %d, %s", u32(d[8:]), u32(d[4:]), enabled(u32(d[12:])))
+		case typ == 3 && len(d) >= 18:
+			return fmt.Sprintf("GICC Affinity: UID %d, Domain %d, %s", u32(d[6:]), u32(d[2:]), enabled(u32(d[10:])))
+		}
+		return fmt.Sprintf("Type %d: % x", typ, d[2:])
+	})
+}
+
+// pmProfiles are the Preferred_PM_Profile values of the FADT.
+var pmProfiles = []string{"Unspecified", "Desktop", "Mobile", "Workstation", "Enterprise Server", "SOHO Server",
+	"Appliance PC", "Performance Server", "Tablet"}
+
+// fadtFlags are the names of the bits of the Flags of the FADT.
+var fadtFlags = []string{"WBINVD", "WBINVD_FLUSH", "PROC_C1", "P_LVL2_UP", "PWR_BUTTON", "SLP_BUTTON", "FIX_RTC",
+	"RTC_S4", "TMR_VAL_EXT", "DCK_CAP", "RESET_REG_SUP", "SEALED_CASE", "HEADLESS", "CPU_SW_SLP", "PCI_EXP_WAK",
+	"USE_PLATFORM_CLOCK", "S4_RTC_STS_VALID", "REMOTE_POWER_ON_CAPABLE", "FORCE_APIC_CLUSTER_MODEL",
+	"FORCE_APIC_PHYSICAL_DESTINATION_MODE", "HW_REDUCED_ACPI", "LOW_POWER_S0_IDLE_CAPABLE"}
+
+// gas formats the Generic Address Structure b: an address, and the space
+// it is in.
+func gas(b []byte) string {
+	spaces := map[uint8]string{0: "Memory", 1: "I/O", 2: "PCI", 3: "EC", 4: "SMBus", 0x7f: "FFixedHW"}
+	s, ok := spaces[b[0]]
+	if !ok {
+		s = fmt.Sprintf("Space %d", b[0])
+	}
+	return fmt.Sprintf("%s %#x, %d bits at bit %d", s, u64(b[4:]), b[1], b[2])
+}
+
+// decodeFADT decodes the Fixed ACPI Description Table, which says where
+// the power management registers are, and where the DSDT is.
+func decodeFADT(t *acpi.Table) []string {
+	b := t.Data
+	if len(b) < 116 {
+		return []string{"too short"}
+	}
+	profile := fmt.Sprint(b[45])
+	if int(b[45]) < len(pmProfiles) {
+		profile = pmProfiles[b[45]]
+	}
+	lines := []string{
+		fmt.Sprintf("FACS Address: %#x", u32(b[36:])),
+		fmt.Sprintf("DSDT Address: %#x", u32(b[40:])),
+		fmt.Sprintf("Preferred PM Profile: %s", profile),
+		fmt.Sprintf("SCI Interrupt: %d", u16(b[46:])),
+		fmt.Sprintf("SMI Command Port: %#x, ACPI Enable %#x, ACPI Disable %#x", u32(b[48:]), b[52], b[53]),
+		fmt.Sprintf("PM1a Event Block: %#x", u32(b[56:])),
+		fmt.Sprintf("PM1a Control Block: %#x", u32(b[64:])),
+		fmt.Sprintf("PM Timer Block: %#x", u32(b[76:])),
+	}
+	flags := u32(b[112:])
+	var names []string
+	for i, n := range fadtFlags {
+		if flags&(1<<uint(i)) != 0 {
+			names = append(names, n)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("Flags: %#x %s", flags, strings.Join(names, " ")))
+	if len(b) >= 129 {
+		lines = append(lines, fmt.Sprintf("Reset Register: %s, Value %#x", gas(b[116:128]), b[128]))
+	}
+	if len(b) >= 148 {
+		lines = append(lines, fmt.Sprintf("X FACS Address: %#x", u64(b[132:])), fmt.Sprintf("X DSDT Address: %#x", u64(b[140:])))
+	}
+	return lines
+}
+
+// decodeMCFG decodes the PCI Express memory mapped configuration space
+// table, which says where the configuration space of each bus is.
+func decodeMCFG(t *acpi.Table) []string {
+	if len(t.Data) < acpi.HeaderLen+8 {
+		return []string{"too short"}
+	}
+	var lines []string
+	for b := t.Data[acpi.HeaderLen+8:]; len(b) >= 16; b = b[16:] {
+		lines = append(lines, fmt.Sprintf("Segment %d, Buses %d-%d: %#x", u16(b[8:]), b[10], b[11], u64(b)))
+	}
+	return lines
+}
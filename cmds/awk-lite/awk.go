@@ -0,0 +1,150 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Awk-lite runs awk programs, with most of the language, but not user
+// functions, getline, or output to commands.
+//
+// Synopsis:
+//     awk-lite [-F FS] [-v NAME=VALUE]... PROGRAM [FILE|NAME=VALUE]...
+//     awk-lite [-F FS] [-v NAME=VALUE]... -f PROGFILE... [FILE|NAME=VALUE]...
+//
+// Description:
+//     awk-lite reads the FILEs, or stdin, a record, or line, at a time,
+//     splits each into fields, $1 to $NF, and runs each PATTERN { ACTION }
+//     of PROGRAM on it whose PATTERN matches; BEGIN and END actions run
+//     before the first record and after the last. A PATTERN is an
+//     expression, such as /RE/ or $3 > 100, or a range, PATTERN,
+//     PATTERN; without one, an ACTION runs on every record, and without
+//     an ACTION, the record is printed. NAME=VALUE among the FILEs sets
+//     NAME before the FILEs after it are read.
+//
+//     The statements are print, printf, with > FILE or >> FILE, if, else,
+//     while, do, for (;;), for (KEY in ARRAY), break, continue, next,
+//     exit and delete. The expressions are those of awk: numbers,
+//     strings, fields, variables, arrays, the operators, from loosest to
+//     tightest,
+//         = += -= *= /= %= ^=, ?:, ||, &&, in, ~ !~, < <= == != >= >,
+//         concatenation, + -, * / %, unary ! - +, ^, ++ --, $
+//     and the builtins length, substr, index, split, sub, gsub, match,
+//     sprintf, tolower, toupper, int, sqrt, exp, log, sin, cos, atan2,
+//     rand, srand and close.
+//
+//     The variables FS, OFS, ORS, RS, NR, NF, FNR, FILENAME, SUBSEP,
+//     CONVFMT, OFMT, RSTART, RLENGTH and ENVIRON are as in awk. An FS
+//     of " " splits on runs of blanks, one other character on it, and
+//     anything else is a regex. RS is one character, or "", for records
+//     separated by blank lines.
+//
+//     REs are in the syntax of Go, which is much like that of awk.
+//
+// Options:
+//     -F: set FS; \t is a tab
+//     -v: set NAME to VALUE before the program runs
+//     -f: read the program from PROGFILE; several are joined
+//
+// Example:
+//     $ awk-lite -F: '$3 >= 1000 { print $1 }' /etc/passwd
+//     $ awk-lite '/^MemTotal/ { printf "%.1f GiB\n", $2 / 1048576 }' /proc/meminfo
+//     $ ls -l | awk-lite '{ n += $5 } END { print n, "bytes" }'
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+var (
+	fs        = flag.String("F", "", "the field separator, `FS`")
+	assigns   list
+	progFiles list
+)
+
+func init() {
+	flag.Var(&assigns, "v", "set `NAME=VALUE` before the program runs")
+	flag.Var(&progFiles, "f", "read the program from `progfile`")
+}
+
+// args splits -FFS, -vNAME=VALUE and -fPROGFILE, as awk takes them, into
+// two, as flag does.
+func args(a []string) []string {
+	out := []string{a[0]}
+	for i := 1; i < len(a); i++ {
+		s := a[i]
+		if s == "--" || len(s) < 2 || s[0] != '-' {
+			return append(out, a[i:]...)
+		}
+		if len(s) > 2 && strings.IndexByte("Fvf", s[1]) >= 0 {
+			out = append(out, s[:2], s[2:])
+			continue
+		}
+		out = append(out, s)
+		if (s == "-F" || s == "-v" || s == "-f") && i+1 < len(a) {
+			i++
+			out = append(out, a[i])
+		}
+	}
+	return out
+}
+
+func main() {
+	os.Args = args(os.Args)
+	flag.Parse()
+	args := flag.Args()
+	var src string
+	if len(progFiles) > 0 {
+		var progs []string
+		for _, n := range progFiles {
+			b, err := ioutil.ReadFile(n)
+			if err != nil {
+				log.Fatalf("awk-lite: %v", err)
+			}
+			progs = append(progs, string(b))
+		}
+		src = strings.Join(progs, "\n")
+	} else {
+		if len(args) == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		src, args = args[0], args[1:]
+	}
+
+	prog, err := parse(src)
+	if err != nil {
+		log.Fatalf("awk-lite: %v", err)
+	}
+	in := newInterp(prog, os.Stdin, os.Stdout)
+	if *fs != "" {
+		f := unescape(*fs)
+		if *fs == "t" {
+			f = "\t"
+		}
+		in.vars["FS"] = str(f)
+	}
+	for _, a := range assigns {
+		name, v, ok := assignment(a)
+		if !ok {
+			log.Fatalf("awk-lite: -v %q: not NAME=VALUE", a)
+		}
+		in.vars[name] = input(v)
+	}
+	if err := in.run(args); err != nil {
+		log.Fatalf("awk-lite: %v", err)
+	}
+	os.Exit(in.status)
+}
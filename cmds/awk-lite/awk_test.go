@@ -0,0 +1,270 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const passwd = `root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+alice:x:1000:1000:Alice:/home/alice:/bin/bash
+bob:x:1001:1001:Bob:/home/bob:/bin/sh
+`
+
+// run runs prog on in, and returns what it prints.
+func run(prog, in string, args ...string) (string, error) {
+	p, err := parse(prog)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	i := newInterp(p, strings.NewReader(in), &b)
+	err = i.run(args)
+	return b.String(), err
+}
+
+func TestAwk(t *testing.T) {
+	for _, tt := range []struct {
+		prog, in, want string
+	}{
+		{`{ print $2 }`, "a b c\nd e f\n", "b\ne\n"},
+		{`{ print $NF, NF }`, "a b c\n  d  \n\n", "c 3\nd 1\n 0\n"},
+		{`BEGIN { FS = ":" } $3 >= 1000 { print $1 }`, passwd, "alice\nbob\n"},
+		{`BEGIN { FS = ":" } $7 ~ /bash$/ { n++ } END { print n }`, passwd, "2\n"},
+		{`BEGIN { FS = ":" } $1 !~ /^(root|daemon)$/`, passwd, "alice:x:1000:1000:Alice:/home/alice:/bin/bash\nbob:x:1001:1001:Bob:/home/bob:/bin/sh\n"},
+		{`/daemon/,/alice/ { print NR }`, passwd, "2\n3\n"},
+		{`NR == 2`, "a\nb\nc\n", "b\n"},
+		{`NR % 2`, "a\nb\nc\n", "a\nc\n"},
+		{`{ s += $1 } END { print s, s / NR }`, "1\n2\n3\n4\n", "10 2.5\n"},
+		{`{ printf "%-5s|%5.2f|%03d|%x|%c|%%\n", $1, $2, $2, $3, $4 }`, "ab 3.14159 255 65\n", "ab   | 3.14|003|ff|A|%\n"},
+		{`BEGIN { printf "%*d|%.*f\n", 4, 7, 1, 2.25 }`, "", "   7|2.2\n"},
+		{`BEGIN { x = sprintf("%s-%d", "a", 1.9); print x, length(x) }`, "", "a-1 3\n"},
+		{`BEGIN { print 1/3; OFMT = "%.2f"; print 1/3; x = 1/3 ""; print x }`, "", "0.333333\n0.33\n0.333333\n"},
+		{`BEGIN { print 2^10, 2^3^2, -2^2, 7 % 3, 1e3, 0.1 + 0.2 }`, "", "1024 512 -4 1 1000 0.3\n"},
+		{`BEGIN { print 1 " " 2, 1 + 2 "x" }`, "", "1 2 3x\n"},
+		{`BEGIN { x = 5; x += 2; x *= 3; x -= 1; x /= 4; x %= 3; x ^= 2; print x }`, "", "4\n"},
+		{`BEGIN { i = 1; print i++ + ++i, i--, --i, i }`, "", "4 3 1 1\n"},
+		{`{ $2 = "X"; print; print NF }`, "a b c\n", "a X c\n3\n"},
+		{`BEGIN { OFS = "-" } { $1 = $1; print }`, "a b  c\n", "a-b-c\n"},
+		{`{ $5 = "e"; print }`, "a b\n", "a b   e\n"},
+		{`{ NF = 2; print }`, "a b c\n", "a b\n"},
+		{`{ $0 = "x y"; print $2, NF }`, "a\n", "y 2\n"},
+		{`{ print $(1+1), $NF-1 }`, "5 6 7\n", "6 6\n"},
+		{`{ i = 1; print $i++, i }`, "5 6\n", "5 1\n"},
+		{`{ for (i = NF; i > 0; i--) printf "%s%s", $i, (i > 1 ? " " : "\n") }`, "a b c\n", "c b a\n"},
+		{`{ c[$1]++ } END { for (k in c) print k, c[k] }`, "b\na\nb\n10\n9\n", "9 1\n10 1\na 1\nb 2\n"},
+		{`BEGIN { a["x"] = 1; if ("x" in a) print "in"; if (!("y" in a)) print "out"; delete a["x"]; print length(a) }`, "", "in\nout\n0\n"},
+		{`BEGIN { a[1, 2] = 3; for (k in a) { split(k, p, SUBSEP); print p[1], p[2] }; if ((1, 2) in a) print "yes" }`, "", "1 2\nyes\n"},
+		{`BEGIN { n = split("a:b:c", p, ":"); print n, p[1], p[3]; n = split("a1b22c", q, /[0-9]+/); print n, q[2] }`, "", "3 a c\n3 b\n"},
+		{`BEGIN { s = "hello world"; print substr(s, 7), substr(s, 1, 4), substr(s, 0, 2), substr(s, -1), index(s, "o"), index(s, "z") }`, "", "world hell h hello world 5 0\n"},
+		{`BEGIN { s = "aaa"; n = gsub(/a/, "<&>", s); print n, s; t = "a.b.c"; sub(/\./, "\\&", t); print t }`, "", "3 <a><a><a>\na&b.c\n"},
+		{`{ gsub(/o/, "0"); print }`, "foo boo\n", "f00 b00\n"},
+		{`{ sub(/b+/, "X", $2); print }`, "abb abb\n", "abb aX\n"},
+		{`BEGIN { print match("foobar", /o+b/), RSTART, RLENGTH; print match("x", "y"), RSTART, RLENGTH }`, "", "2 2 3\n0 0 -1\n"},
+		{`BEGIN { print toupper("aBc"), tolower("AbC"), int(3.9), int(-3.9), length("héllo") }`, "", "ABC abc 3 -3 5\n"},
+		{`BEGIN { while (i < 3) { i++; if (i == 2) continue; print i } }`, "", "1\n3\n"},
+		{`BEGIN { do { print "once" } while (0) }`, "", "once\n"},
+		{`BEGIN { for (;;) { if (++i > 2) break }; print i }`, "", "3\n"},
+		{`BEGIN { if (0) print "a"; else if (1) print "b"; else print "c" }`, "", "b\n"},
+		{"BEGIN {\n\tif (1)\n\t\tprint \"a\"\n\telse\n\t\tprint \"b\"\n}\n", "", "a\n"},
+		{`{ if ($1 == "skip") next; print }`, "a\nskip\nb\n", "a\nb\n"},
+		{`{ print } NR == 2 { exit } END { print "end" }`, "a\nb\nc\n", "a\nb\nend\n"},
+		{`BEGIN { exit } END { print "end" }`, "a\n", "end\n"},
+		{`END { print NR, $0 }`, "a\nb\n", "2 b\n"},
+		// Comparisons are of numbers if both look like them.
+		{`{ print ($1 < $2), ($1 < "9") }`, "10 9\n", "0 1\n"},
+		{`{ print ($1 == 1), ($1 == "1.0"), (x == 0), (x == "") }`, "1.0\n", "1 1 1 1\n"},
+		{`BEGIN { print (2 < 10), ("2" < "10"), ("a" < "b") }`, "", "1 0 1\n"},
+		{`BEGIN { print 1 == 1 ? "y" : "n" }`, "", "y\n"},
+		{`BEGIN { print length() }`, "", "0\n"},
+		{`{ print length }`, "abc\n", "3\n"},
+		{`BEGIN { RS = "" } { print NR ": " $1 "," $NF }`, "\n\na b\nc\n\n\nd\ne f\n", "1: a,c\n2: d,f\n"},
+		{`BEGIN { RS = ";" } { print }`, "a;b;c", "a\nb\nc\n"},
+		{`BEGIN { FS = "," } { print $2 }`, "a,,c\n", "\n"},
+		{`BEGIN { FS = ", *" } { print $3 }`, "a,  b,c\n", "c\n"},
+		{`BEGIN { FS = "" } { print $2, NF }`, "abc\n", "b 3\n"},
+		{`BEGIN { ORS = "|"; OFS = "-" } { print $1, $2 }`, "a b\nc d\n", "a-b|c-d|"},
+		{`BEGIN { printf "%s %s\n", "only" }`, "", "only \n"},
+		{`BEGIN { print "a" > "/dev/stdout" }`, "", "a\n"},
+		{"# comment\nBEGIN { x = 1 # more\n print x }", "", "1\n"},
+		{`BEGIN { x["a"]; print length(x) }`, "", "1\n"},
+		{`BEGIN { print substr("hello", 2, 3) substr("hello", 1.5, 1) }`, "", "elle\n"},
+		{`BEGIN { print -"3x", +"1e2", "0x10" + 0, ".5" + 0 }`, "", "-3 100 0 0.5\n"},
+		{`BEGIN { print 100000000, 1e20, 3.0, 0.000001 }`, "", "100000000 1e+20 3 1e-06\n"},
+		{`BEGIN { print(1, 2) }`, "", "1 2\n"},
+		{`BEGIN { print (1)(2) }`, "", "12\n"},
+		{"BEGIN { s = \"a\" \\\n \"b\"; print s }", "", "ab\n"},
+		{`BEGIN { print 6 / 4 * 2, 2 - 1 - 1 }`, "", "3 0\n"},
+		{`BEGIN { print 1, 2 }` + "\n" + `BEGIN { print 3 }`, "", "1 2\n3\n"},
+	} {
+		got, err := run(tt.prog, tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.prog, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q of %q: got %q, want %q", tt.prog, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	d, err := ioutil.TempDir("", "awk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	a, b, out := filepath.Join(d, "a"), filepath.Join(d, "b"), filepath.Join(d, "out")
+	if err := ioutil.WriteFile(a, []byte("1\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assignments among the files are made before the files after them.
+	got, err := run(`{ print FILENAME == ARGV1, FNR, NR, x $1 }`, "", "ARGV1="+a, a, "x=b", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 1 1 1\n1 2 2 2\n0 1 3 b3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// > truncates the file when it is first written, then appends.
+	if err := ioutil.WriteFile(out, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(`{ print $1 * 2 > "`+out+`" }`, "", a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(`{ printf "%d\n", $1 >> "`+out+`" }`, "", b); err != nil {
+		t.Fatal(err)
+	}
+	got2, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2\n4\n3\n"; string(got2) != want {
+		t.Errorf("%s: got %q, want %q", out, got2, want)
+	}
+
+	// - is stdin.
+	got, err = run(`{ print FILENAME, $0 }`, "in\n", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "- in\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := run(`{ print }`, "", filepath.Join(d, "none")); err == nil {
+		t.Errorf("a file which is not there: got nil, want an error")
+	}
+}
+
+func TestExit(t *testing.T) {
+	for _, tt := range []struct {
+		prog   string
+		status int
+	}{
+		{`BEGIN { exit 3 }`, 3},
+		{`BEGIN { exit 3 } END { exit }`, 3},
+		{`BEGIN { exit 3 } END { exit 4 }`, 4},
+		{`{ exit NR + 1 }`, 2},
+	} {
+		p, err := parse(tt.prog)
+		if err != nil {
+			t.Errorf("%q: %v", tt.prog, err)
+			continue
+		}
+		i := newInterp(p, strings.NewReader("a\nb\n"), ioutil.Discard)
+		if err := i.run(nil); err != nil {
+			t.Errorf("%q: %v", tt.prog, err)
+			continue
+		}
+		if i.status != tt.status {
+			t.Errorf("%q: got status %d, want %d", tt.prog, i.status, tt.status)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, prog := range []string{
+		`{ print `,
+		`{ print "a }`,
+		`{ x = }`,
+		`BEGIN`,
+		`{ print $1 | "cat" }`,
+		`{ substr("a") }`,
+		`{ split("a", "b") }`,
+		`{ x = /(/ }`,
+		`{ 1 = 2 }`,
+		`{ a b = 1 }`,
+		`{ getline; print }`,
+		`function f(x) { return x }`,
+	} {
+		if _, err := run(prog, "a\n"); err == nil {
+			t.Errorf("%q: got nil, want an error", prog)
+		}
+	}
+	for _, prog := range []string{
+		`{ print 1 / 0 }`,
+		`{ print 1 % 0 }`,
+		`{ a[1] = 1; a = 2 }`,
+		`{ x = 1; x[1] = 2 }`,
+		`{ print $(-1) }`,
+		`{ print "a" ~ "(" }`,
+	} {
+		if _, err := run(prog, "a\n"); err == nil {
+			t.Errorf("%q: got nil, want an error", prog)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"awk", "-F:", "{}", "f"}, []string{"awk", "-F", ":", "{}", "f"}},
+		{[]string{"awk", "-F", ":", "-vx=1", "-fp", "f"}, []string{"awk", "-F", ":", "-v", "x=1", "-f", "p", "f"}},
+		{[]string{"awk", "-v", "-F", "-F-v"}, []string{"awk", "-v", "-F", "-F", "-v"}},
+		{[]string{"awk", "{}", "-F:"}, []string{"awk", "{}", "-F:"}},
+		{[]string{"awk", "--", "-F:"}, []string{"awk", "--", "-F:"}},
+	} {
+		if got := args(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("args(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		args   []value
+		want   string
+	}{
+		{"%d %i %u", []value{num(1.9), num(-2.9), str("3x")}, "1 -2 3"},
+		{"%5s|%-5s|%.2s", []value{str("ab"), str("ab"), str("abc")}, "   ab|ab   |ab"},
+		{"%e %E %G", []value{num(1234.5), num(0.5), num(1e-10)}, "1.234500e+03 5.000000E-01 1E-10"},
+		{"%o %X %#x", []value{num(8), num(255), num(255)}, "10 FF 0xff"},
+		{"%c%c%c", []value{num(104), str("iota"), input("33")}, "hi!"},
+		{"%+d % d %05.1f", []value{num(1), num(2), num(3.14159)}, "+1  2 003.1"},
+		{"%s %s", []value{num(0.1), num(100)}, "0.1 100"},
+		{"%d%%", []value{num(50)}, "50%"},
+		{"%q %", []value{num(1)}, "%q %"},
+		{"%s|%d", nil, "|0"},
+	} {
+		if got := sprintf(tt.format, tt.args); got != tt.want {
+			t.Errorf("sprintf(%q, %v): got %q, want %q", tt.format, tt.args, got, tt.want)
+		}
+	}
+}
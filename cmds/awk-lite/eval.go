@@ -0,0 +1,626 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// An expr is an expression.
+type expr interface {
+	eval(in *interp) value
+}
+
+// An lvalue is an expression which can be assigned to: a variable, a
+// field, or an element of an array.
+type lvalue interface {
+	expr
+	assign(in *interp, v value)
+}
+
+type numExpr struct{ n float64 }
+
+func (e *numExpr) eval(in *interp) value { return num(e.n) }
+
+type strExpr struct{ s string }
+
+func (e *strExpr) eval(in *interp) value { return str(e.s) }
+
+// A regexExpr is /RE/. On the right of ~, and as an argument of the
+// builtins, it is RE; elsewhere, it is whether RE matches $0.
+type regexExpr struct{ re *regexp.Regexp }
+
+func (e *regexExpr) eval(in *interp) value {
+	return boolean(e.re.MatchString(in.record))
+}
+
+type varExpr struct{ name string }
+
+func (e *varExpr) eval(in *interp) value { return in.getVar(e.name) }
+
+func (e *varExpr) assign(in *interp, v value) { in.setVar(e.name, v) }
+
+type fieldExpr struct{ index expr }
+
+func (e *fieldExpr) eval(in *interp) value {
+	return in.getField(int(e.index.eval(in).num()))
+}
+
+func (e *fieldExpr) assign(in *interp, v value) {
+	in.setField(int(e.index.eval(in).num()), v.str(in.convFmt()))
+}
+
+type indexExpr struct {
+	name  string
+	index []expr
+}
+
+// key returns the key of the index l, which, of several expressions, is
+// them joined by SUBSEP.
+func (in *interp) key(l []expr) string {
+	var k []string
+	for _, e := range l {
+		k = append(k, e.eval(in).str(in.convFmt()))
+	}
+	return strings.Join(k, in.special("SUBSEP"))
+}
+
+func (e *indexExpr) eval(in *interp) value {
+	a := in.array(e.name)
+	k := in.key(e.index)
+	// Referring to an element makes it.
+	v, ok := a[k]
+	if !ok {
+		v = unset
+		a[k] = v
+	}
+	return v
+}
+
+func (e *indexExpr) assign(in *interp, v value) {
+	in.array(e.name)[in.key(e.index)] = v
+}
+
+type assignExpr struct {
+	lv lvalue
+	// op is the operator of +=, -=, and so on, or "" for =.
+	op  string
+	rhs expr
+}
+
+func (e *assignExpr) eval(in *interp) value {
+	v := e.rhs.eval(in)
+	if e.op != "" {
+		v = num(arith(e.op, e.lv.eval(in).num(), v.num()))
+	}
+	e.lv.assign(in, v)
+	return v
+}
+
+type condExpr struct {
+	cond, yes, no expr
+}
+
+func (e *condExpr) eval(in *interp) value {
+	if e.cond.eval(in).bool() {
+		return e.yes.eval(in)
+	}
+	return e.no.eval(in)
+}
+
+type unaryExpr struct {
+	op string
+	e  expr
+}
+
+func (e *unaryExpr) eval(in *interp) value {
+	v := e.e.eval(in)
+	switch e.op {
+	case "-":
+		return num(-v.num())
+	case "+":
+		return num(v.num())
+	}
+	return boolean(!v.bool())
+}
+
+type incrExpr struct {
+	lv    lvalue
+	delta float64
+	pre   bool
+}
+
+func (e *incrExpr) eval(in *interp) value {
+	n := e.lv.eval(in).num()
+	e.lv.assign(in, num(n+e.delta))
+	if e.pre {
+		return num(n + e.delta)
+	}
+	return num(n)
+}
+
+type binaryExpr struct {
+	op   string
+	l, r expr
+}
+
+// arith returns l op r.
+func arith(op string, l, r float64) float64 {
+	switch op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			errorf("division by zero")
+		}
+		return l / r
+	case "%":
+		if r == 0 {
+			errorf("division by zero in %%")
+		}
+		return math.Mod(l, r)
+	case "^":
+		return math.Pow(l, r)
+	}
+	panic("arith: unknown operator " + op)
+}
+
+// compare compares l and r: as numbers, if both are numbers, or strings
+// which look like them; else as strings.
+func (in *interp) compare(l, r value) int {
+	if l.kind != kindStr && r.kind != kindStr {
+		switch {
+		case l.n < r.n:
+			return -1
+		case l.n > r.n:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(l.str(in.convFmt()), r.str(in.convFmt()))
+}
+
+func (e *binaryExpr) eval(in *interp) value {
+	switch e.op {
+	case "&&":
+		return boolean(e.l.eval(in).bool() && e.r.eval(in).bool())
+	case "||":
+		return boolean(e.l.eval(in).bool() || e.r.eval(in).bool())
+	}
+	l, r := e.l.eval(in), e.r.eval(in)
+	switch e.op {
+	case " ":
+		return str(l.str(in.convFmt()) + r.str(in.convFmt()))
+	case "<":
+		return boolean(in.compare(l, r) < 0)
+	case "<=":
+		return boolean(in.compare(l, r) <= 0)
+	case "==":
+		return boolean(in.compare(l, r) == 0)
+	case "!=":
+		return boolean(in.compare(l, r) != 0)
+	case ">=":
+		return boolean(in.compare(l, r) >= 0)
+	case ">":
+		return boolean(in.compare(l, r) > 0)
+	}
+	return num(arith(e.op, l.num(), r.num()))
+}
+
+// regex returns the regex of e: that of /RE/, or else e as a string,
+// compiled.
+func (in *interp) regex(e expr) *regexp.Regexp {
+	if r, ok := e.(*regexExpr); ok {
+		return r.re
+	}
+	return in.compile(e.eval(in).str(in.convFmt()))
+}
+
+type matchExpr struct {
+	l, re expr
+	not   bool
+}
+
+func (e *matchExpr) eval(in *interp) value {
+	s := e.l.eval(in).str(in.convFmt())
+	return boolean(in.regex(e.re).MatchString(s) != e.not)
+}
+
+type inExpr struct {
+	index []expr
+	array string
+}
+
+func (e *inExpr) eval(in *interp) value {
+	_, ok := in.array(e.array)[in.key(e.index)]
+	return boolean(ok)
+}
+
+type callExpr struct {
+	name string
+	args []expr
+}
+
+// runes returns the number of characters of s before byte i.
+func runes(s string, i int) int {
+	return utf8.RuneCountInString(s[:i])
+}
+
+// substr returns the characters of s from m, the first of which is 1,
+// and n of them, or with n < 0, the rest; they are rounded, as awk does.
+func substr(s string, m, n float64) string {
+	r := []rune(s)
+	start := math.Floor(m + .5)
+	end := math.Inf(1)
+	if n >= 0 {
+		end = start + math.Floor(n+.5)
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end > float64(len(r)+1) {
+		end = float64(len(r) + 1)
+	}
+	if end <= start {
+		return ""
+	}
+	return string(r[int(start)-1 : int(end)-1])
+}
+
+// subst replaces the first match of re in s, or with global all of them,
+// with repl, in which & is the match, and \& a &. It returns the string,
+// and how many it replaced.
+func subst(re *regexp.Regexp, repl, s string, global bool) (string, int) {
+	var (
+		b    bytes.Buffer
+		n    int
+		prev int
+	)
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		if n > 0 && !global {
+			break
+		}
+		b.WriteString(s[prev:m[0]])
+		for i := 0; i < len(repl); i++ {
+			switch c := repl[i]; {
+			case c == '\\' && i+1 < len(repl) && (repl[i+1] == '&' || repl[i+1] == '\\'):
+				i++
+				b.WriteByte(repl[i])
+			case c == '&':
+				b.WriteString(s[m[0]:m[1]])
+			default:
+				b.WriteByte(c)
+			}
+		}
+		prev = m[1]
+		n++
+	}
+	if n == 0 {
+		return s, 0
+	}
+	b.WriteString(s[prev:])
+	return b.String(), n
+}
+
+func (e *callExpr) eval(in *interp) value {
+	cf := in.convFmt()
+	arg := func(i int) value {
+		return e.args[i].eval(in)
+	}
+	switch e.name {
+	case "length":
+		if len(e.args) == 0 {
+			return num(float64(utf8.RuneCountInString(in.record)))
+		}
+		if v, ok := e.args[0].(*varExpr); ok {
+			if a, ok := in.arrays[v.name]; ok {
+				return num(float64(len(a)))
+			}
+		}
+		return num(float64(utf8.RuneCountInString(arg(0).str(cf))))
+	case "substr":
+		n := -1.0
+		if len(e.args) == 3 {
+			if n = arg(2).num(); n < 0 {
+				n = 0
+			}
+		}
+		return str(substr(arg(0).str(cf), arg(1).num(), n))
+	case "index":
+		s := arg(0).str(cf)
+		i := strings.Index(s, arg(1).str(cf))
+		if i < 0 {
+			return num(0)
+		}
+		return num(float64(runes(s, i) + 1))
+	case "split":
+		s := arg(0).str(cf)
+		name := e.args[1].(*varExpr).name
+		var parts []string
+		if len(e.args) == 3 {
+			if r, ok := e.args[2].(*regexExpr); ok {
+				if s != "" {
+					parts = r.re.Split(s, -1)
+				}
+			} else {
+				parts = in.split(s, arg(2).str(cf))
+			}
+		} else {
+			parts = in.split(s, in.special("FS"))
+		}
+		a := in.array(name)
+		for k := range a {
+			delete(a, k)
+		}
+		for i, p := range parts {
+			a[numStr(float64(i+1), cf)] = input(p)
+		}
+		return num(float64(len(parts)))
+	case "sub", "gsub":
+		var target lvalue = &fieldExpr{&numExpr{0}}
+		if len(e.args) == 3 {
+			target = e.args[2].(lvalue)
+		}
+		s, n := subst(in.regex(e.args[0]), arg(1).str(cf), target.eval(in).str(cf), e.name == "gsub")
+		if n > 0 {
+			target.assign(in, str(s))
+		}
+		return num(float64(n))
+	case "match":
+		s := arg(0).str(cf)
+		start, length := 0, -1
+		if m := in.regex(e.args[1]).FindStringIndex(s); m != nil {
+			start, length = runes(s, m[0])+1, utf8.RuneCountInString(s[m[0]:m[1]])
+		}
+		in.vars["RSTART"] = num(float64(start))
+		in.vars["RLENGTH"] = num(float64(length))
+		return num(float64(start))
+	case "sprintf":
+		var args []value
+		for i := 1; i < len(e.args); i++ {
+			args = append(args, arg(i))
+		}
+		return str(sprintf(arg(0).str(cf), args))
+	case "tolower":
+		return str(strings.ToLower(arg(0).str(cf)))
+	case "toupper":
+		return str(strings.ToUpper(arg(0).str(cf)))
+	case "int":
+		return num(math.Trunc(arg(0).num()))
+	case "sqrt":
+		return num(math.Sqrt(arg(0).num()))
+	case "exp":
+		return num(math.Exp(arg(0).num()))
+	case "log":
+		return num(math.Log(arg(0).num()))
+	case "sin":
+		return num(math.Sin(arg(0).num()))
+	case "cos":
+		return num(math.Cos(arg(0).num()))
+	case "atan2":
+		return num(math.Atan2(arg(0).num(), arg(1).num()))
+	case "rand":
+		return num(in.rand.Float64())
+	case "srand":
+		if len(e.args) == 0 {
+			return num(in.srand(nil))
+		}
+		seed := arg(0).num()
+		return num(in.srand(&seed))
+	case "close":
+		if in.close(arg(0).str(cf)) {
+			return num(0)
+		}
+		return num(-1)
+	}
+	panic("call: unknown builtin " + e.name)
+}
+
+// A flow is how a statement ends: normally, or by break, continue, next
+// or exit.
+type flow int
+
+const (
+	flowNormal flow = iota
+	flowBreak
+	flowContinue
+	flowNext
+	flowExit
+)
+
+// A stmt is a statement.
+type stmt interface {
+	exec(in *interp) flow
+}
+
+type block []stmt
+
+func (b block) exec(in *interp) flow {
+	for _, s := range b {
+		if f := s.exec(in); f != flowNormal {
+			return f
+		}
+	}
+	return flowNormal
+}
+
+type exprStmt struct{ e expr }
+
+func (s *exprStmt) exec(in *interp) flow {
+	s.e.eval(in)
+	return flowNormal
+}
+
+// cond returns whether e is true; /RE/ is whether it matches $0.
+func (in *interp) cond(e expr) bool {
+	return e.eval(in).bool()
+}
+
+type printStmt struct {
+	printf bool
+	args   []expr
+	// redirect is > or >>, to write to dest.
+	redirect string
+	dest     expr
+}
+
+func (s *printStmt) exec(in *interp) flow {
+	var name string
+	if s.dest != nil {
+		name = s.dest.eval(in).str(in.convFmt())
+	}
+	w := in.output(s.redirect, name)
+	if s.printf {
+		var args []value
+		for _, a := range s.args[1:] {
+			args = append(args, a.eval(in))
+		}
+		w.WriteString(sprintf(s.args[0].eval(in).str(in.convFmt()), args))
+		return flowNormal
+	}
+	if len(s.args) == 0 {
+		w.WriteString(in.record)
+	}
+	ofmt := in.special("OFMT")
+	for i, a := range s.args {
+		if i > 0 {
+			w.WriteString(in.special("OFS"))
+		}
+		w.WriteString(a.eval(in).str(ofmt))
+	}
+	w.WriteString(in.special("ORS"))
+	return flowNormal
+}
+
+type ifStmt struct {
+	cond      expr
+	then, els stmt
+}
+
+func (s *ifStmt) exec(in *interp) flow {
+	if in.cond(s.cond) {
+		return s.then.exec(in)
+	}
+	if s.els != nil {
+		return s.els.exec(in)
+	}
+	return flowNormal
+}
+
+// loop runs the body of a loop, and returns whether to go on, and if
+// not, how the loop ends.
+func loop(in *interp, body stmt) (bool, flow) {
+	switch f := body.exec(in); f {
+	case flowBreak:
+		return false, flowNormal
+	case flowNext, flowExit:
+		return false, f
+	}
+	return true, flowNormal
+}
+
+// A whileStmt is while, or with do, do while.
+type whileStmt struct {
+	cond expr
+	body stmt
+	do   bool
+}
+
+func (s *whileStmt) exec(in *interp) flow {
+	for first := s.do; first || in.cond(s.cond); first = false {
+		if ok, f := loop(in, s.body); !ok {
+			return f
+		}
+	}
+	return flowNormal
+}
+
+type forStmt struct {
+	init, cond, post expr
+	body             stmt
+}
+
+func (s *forStmt) exec(in *interp) flow {
+	if s.init != nil {
+		s.init.eval(in)
+	}
+	for s.cond == nil || in.cond(s.cond) {
+		if ok, f := loop(in, s.body); !ok {
+			return f
+		}
+		if s.post != nil {
+			s.post.eval(in)
+		}
+	}
+	return flowNormal
+}
+
+type forInStmt struct {
+	key, array string
+	body       stmt
+}
+
+func (s *forInStmt) exec(in *interp) flow {
+	// The keys are sorted, as they compare, so that the order is the
+	// same each time, and 10 is after 9.
+	a := in.array(s.array)
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return in.compare(input(keys[i]), input(keys[j])) < 0
+	})
+	for _, k := range keys {
+		if _, ok := a[k]; !ok {
+			// Deleted by the body.
+			continue
+		}
+		in.setVar(s.key, input(k))
+		if ok, f := loop(in, s.body); !ok {
+			return f
+		}
+	}
+	return flowNormal
+}
+
+type flowStmt flow
+
+func (s flowStmt) exec(in *interp) flow { return flow(s) }
+
+type exitStmt struct{ code expr }
+
+func (s *exitStmt) exec(in *interp) flow {
+	if s.code != nil {
+		in.status = int(s.code.eval(in).num())
+	}
+	return flowExit
+}
+
+type deleteStmt struct {
+	name  string
+	index []expr
+}
+
+func (s *deleteStmt) exec(in *interp) flow {
+	a := in.array(s.name)
+	if s.index == nil {
+		for k := range a {
+			delete(a, k)
+		}
+		return flowNormal
+	}
+	delete(a, in.key(s.index))
+	return flowNormal
+}
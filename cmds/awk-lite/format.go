@@ -0,0 +1,104 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// defaultConvFmt is the default of CONVFMT and OFMT.
+const defaultConvFmt = "%.6g"
+
+// sprintf formats args as printf does. The conversions are those of C,
+// %c, %d, %i, %o, %u, %x, %X, %e, %E, %f, %F, %g, %G, %s and %%, with the
+// flags -, +, space, 0 and #, and a width and precision, either of which
+// may be *, taken from the arguments. Missing arguments are "" and 0.
+func sprintf(format string, args []value) string {
+	var b bytes.Buffer
+	next := func() value {
+		if len(args) == 0 {
+			return unset
+		}
+		v := args[0]
+		args = args[1:]
+		return v
+	}
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+		// The spec is copied, with * replaced by the number.
+		spec := []byte{'%'}
+		j := i + 1
+		for ; j < len(format) && bytes.IndexByte([]byte("-+ 0#"), format[j]) >= 0; j++ {
+			spec = append(spec, format[j])
+		}
+		for _, part := range []string{"width", "precision"} {
+			if part == "precision" {
+				if j >= len(format) || format[j] != '.' {
+					break
+				}
+				spec = append(spec, '.')
+				j++
+			}
+			if j < len(format) && format[j] == '*' {
+				spec = strconv.AppendInt(spec, int64(next().num()), 10)
+				j++
+				continue
+			}
+			for ; j < len(format) && format[j] >= '0' && format[j] <= '9'; j++ {
+				spec = append(spec, format[j])
+			}
+		}
+		if j >= len(format) {
+			// A spec without a conversion is printed as it is.
+			b.WriteString(format[i:])
+			break
+		}
+		i = j
+		switch verb := format[j]; verb {
+		case 'd', 'i', 'u':
+			n := next().num()
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				fmt.Fprintf(&b, string(append(spec, 'f')), n)
+				break
+			}
+			fmt.Fprintf(&b, string(append(spec, 'd')), int64(n))
+		case 'o', 'x', 'X':
+			fmt.Fprintf(&b, string(append(spec, verb)), int64(next().num()))
+		case 'e', 'E', 'f', 'g', 'G':
+			fmt.Fprintf(&b, string(append(spec, verb)), next().num())
+		case 'F':
+			fmt.Fprintf(&b, string(append(spec, 'f')), next().num())
+		case 'c':
+			v := next()
+			var s string
+			if v.kind == kindNum || v.kind == kindStrNum && v.s != "" {
+				s = string(rune(v.n))
+			} else if r, n := utf8.DecodeRuneInString(v.s); n > 0 {
+				s = string(r)
+			}
+			fmt.Fprintf(&b, string(append(spec, 's')), s)
+		case 's':
+			fmt.Fprintf(&b, string(append(spec, 's')), next().str(defaultConvFmt))
+		default:
+			// An unknown conversion is printed as it is.
+			b.Write(spec)
+			b.WriteByte(verb)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,460 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// An interp runs a program.
+type interp struct {
+	prog   *program
+	vars   map[string]value
+	arrays map[string]map[string]value
+
+	// record is $0, and fields are $1 to $NF.
+	record string
+	fields []string
+
+	stdin  io.Reader
+	out    *bufio.Writer
+	files  map[string]*outFile
+	regexp map[string]*regexp.Regexp
+
+	rand *rand.Rand
+	seed float64
+	// status is what exit exits with.
+	status int
+}
+
+// An outFile is a file print and printf write to.
+type outFile struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// runtimeError is how the interpreter gives up on a program; run
+// recovers it.
+type runtimeError struct {
+	error
+}
+
+func errorf(format string, a ...interface{}) {
+	panic(runtimeError{fmt.Errorf(format, a...)})
+}
+
+func newInterp(prog *program, stdin io.Reader, stdout io.Writer) *interp {
+	in := &interp{
+		prog:   prog,
+		arrays: map[string]map[string]value{},
+		stdin:  stdin,
+		out:    bufio.NewWriter(stdout),
+		files:  map[string]*outFile{},
+		regexp: map[string]*regexp.Regexp{},
+	}
+	in.rand = rand.New(rand.NewSource(0))
+	in.vars = map[string]value{
+		"FS":      str(" "),
+		"OFS":     str(" "),
+		"ORS":     str("\n"),
+		"RS":      str("\n"),
+		"NR":      num(0),
+		"FNR":     num(0),
+		"SUBSEP":  str("\x1c"),
+		"CONVFMT": str(defaultConvFmt),
+		"OFMT":    str(defaultConvFmt),
+		"RSTART":  num(0),
+		"RLENGTH": num(-1),
+	}
+	env := map[string]value{}
+	for _, e := range os.Environ() {
+		if i := strings.IndexByte(e, '='); i > 0 {
+			env[e[:i]] = input(e[i+1:])
+		}
+	}
+	in.arrays["ENVIRON"] = env
+	return in
+}
+
+// special returns the string of the variable name, which is one the
+// interpreter uses, such as FS.
+func (in *interp) special(name string) string {
+	return in.vars[name].str(defaultConvFmt)
+}
+
+func (in *interp) convFmt() string {
+	return in.special("CONVFMT")
+}
+
+// getVar returns the variable name.
+func (in *interp) getVar(name string) value {
+	if name == "NF" {
+		return num(float64(len(in.fields)))
+	}
+	if _, ok := in.arrays[name]; ok {
+		errorf("%s is an array", name)
+	}
+	if v, ok := in.vars[name]; ok {
+		return v
+	}
+	return unset
+}
+
+// setVar sets the variable name.
+func (in *interp) setVar(name string, v value) {
+	if _, ok := in.arrays[name]; ok {
+		errorf("%s is an array", name)
+	}
+	if name == "NF" {
+		n := int(v.num())
+		if n < 0 {
+			errorf("NF set to %d", n)
+		}
+		for len(in.fields) < n {
+			in.fields = append(in.fields, "")
+		}
+		in.fields = in.fields[:n]
+		in.rebuild()
+		return
+	}
+	in.vars[name] = v
+}
+
+// array returns the array name, making it if there is none.
+func (in *interp) array(name string) map[string]value {
+	a, ok := in.arrays[name]
+	if !ok {
+		if _, ok := in.vars[name]; ok {
+			errorf("%s is not an array", name)
+		}
+		a = map[string]value{}
+		in.arrays[name] = a
+	}
+	return a
+}
+
+// compile returns the regex s, which is compiled once.
+func (in *interp) compile(s string) *regexp.Regexp {
+	re, ok := in.regexp[s]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(s); err != nil {
+			errorf("%v", err)
+		}
+		in.regexp[s] = re
+	}
+	return re
+}
+
+// split splits s into fields by fs, as FS does: " " is runs of blanks
+// and newlines, with those at the ends ignored; another single character
+// is that character; and anything else is a regex.
+func (in *interp) split(s, fs string) []string {
+	switch {
+	case fs == " ":
+		return strings.Fields(s)
+	case s == "":
+		return nil
+	case fs == "":
+		return strings.Split(s, "")
+	case len(fs) == 1 && fs != "\\":
+		return strings.Split(s, fs)
+	}
+	return in.compile(fs).Split(s, -1)
+}
+
+// setRecord sets $0, and splits it into fields.
+func (in *interp) setRecord(s string) {
+	in.record = s
+	in.fields = in.split(s, in.special("FS"))
+}
+
+// rebuild makes $0 of the fields, separated by OFS.
+func (in *interp) rebuild() {
+	in.record = strings.Join(in.fields, in.special("OFS"))
+}
+
+// getField returns $i.
+func (in *interp) getField(i int) value {
+	switch {
+	case i < 0:
+		errorf("$%d: negative field", i)
+	case i == 0:
+		return input(in.record)
+	case i <= len(in.fields):
+		return input(in.fields[i-1])
+	}
+	return unset
+}
+
+// setField sets $i, which makes $0 again, or with $0, the fields.
+func (in *interp) setField(i int, s string) {
+	switch {
+	case i < 0:
+		errorf("$%d: negative field", i)
+	case i == 0:
+		in.setRecord(s)
+		return
+	}
+	for len(in.fields) < i {
+		in.fields = append(in.fields, "")
+	}
+	in.fields[i-1] = s
+	in.rebuild()
+}
+
+// output returns where print writes to: stdout, or the file name, which
+// is truncated when it is first written, or with >>, appended to.
+func (in *interp) output(redirect, name string) *bufio.Writer {
+	if redirect == "" || name == "-" || name == "/dev/stdout" {
+		return in.out
+	}
+	if o, ok := in.files[name]; ok {
+		return o.w
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if redirect == ">>" {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(name, flags, 0666)
+	if err != nil {
+		errorf("%v", err)
+	}
+	o := &outFile{f: f, w: bufio.NewWriter(f)}
+	in.files[name] = o
+	return o.w
+}
+
+// close closes the file name, and returns whether it was open and
+// closed.
+func (in *interp) close(name string) bool {
+	o, ok := in.files[name]
+	if !ok {
+		return false
+	}
+	delete(in.files, name)
+	err := o.w.Flush()
+	if cerr := o.f.Close(); err == nil {
+		err = cerr
+	}
+	return err == nil
+}
+
+// reader reads records.
+type reader struct {
+	*bufio.Reader
+}
+
+// read reads a record separated by rs: with an empty rs, records are
+// separated by blank lines.
+func (r reader) read(rs string) (string, bool, error) {
+	if rs != "" {
+		s, err := r.ReadString(rs[0])
+		if err == io.EOF {
+			return s, s != "", nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return s[:len(s)-1], true, nil
+	}
+	var lines []string
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", false, err
+		}
+		l = strings.TrimSuffix(l, "\n")
+		if l == "" {
+			if len(lines) > 0 || err == io.EOF {
+				return strings.Join(lines, "\n"), len(lines) > 0, nil
+			}
+			continue
+		}
+		lines = append(lines, l)
+		if err == io.EOF {
+			return strings.Join(lines, "\n"), true, nil
+		}
+	}
+}
+
+// assignment returns the name and value of an argument of the form
+// NAME=VALUE, which assigns to the variable NAME.
+func assignment(arg string) (string, string, bool) {
+	i := strings.IndexByte(arg, '=')
+	if i <= 0 {
+		return "", "", false
+	}
+	name := arg[:i]
+	for j, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 0 && c >= '0' && c <= '9') {
+			return "", "", false
+		}
+	}
+	if keywords[name] || builtins[name] {
+		return "", "", false
+	}
+	return name, unescape(arg[i+1:]), true
+}
+
+// runRecord runs the items on the record s.
+func (in *interp) runRecord(s string) flow {
+	in.vars["NR"] = num(in.vars["NR"].num() + 1)
+	in.vars["FNR"] = num(in.vars["FNR"].num() + 1)
+	in.setRecord(s)
+	for _, it := range in.prog.items {
+		if !in.matches(it) {
+			continue
+		}
+		if it.action == nil {
+			in.out.WriteString(in.record)
+			in.out.WriteString(in.special("ORS"))
+			continue
+		}
+		switch f := it.action.exec(in); f {
+		case flowNext:
+			return flowNormal
+		case flowExit:
+			return f
+		}
+	}
+	return flowNormal
+}
+
+// matches returns whether the pattern of it matches the record.
+func (in *interp) matches(it *item) bool {
+	switch {
+	case it.pattern == nil:
+		return true
+	case it.pattern2 == nil:
+		return in.cond(it.pattern)
+	case it.inRange:
+		it.inRange = !in.cond(it.pattern2)
+		return true
+	case in.cond(it.pattern):
+		it.inRange = !in.cond(it.pattern2)
+		return true
+	}
+	return false
+}
+
+// runFile runs the items on the records of r.
+func (in *interp) runFile(r io.Reader) (flow, error) {
+	in.vars["FNR"] = num(0)
+	rr := reader{bufio.NewReader(r)}
+	for {
+		s, ok, err := rr.read(in.special("RS"))
+		if err != nil {
+			return flowNormal, err
+		}
+		if !ok {
+			return flowNormal, nil
+		}
+		if f := in.runRecord(s); f == flowExit {
+			return f, nil
+		}
+	}
+}
+
+// run runs the program on args, which are files, and assignments,
+// NAME=VALUE, which are made when they are come to. Without files, it
+// runs on stdin.
+func (in *interp) run(args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(runtimeError)
+			if !ok {
+				panic(r)
+			}
+			err = e.error
+		}
+		if ferr := in.flush(); err == nil {
+			err = ferr
+		}
+	}()
+	if in.runBlock(in.prog.begin) != flowExit && (len(in.prog.items) > 0 || len(in.prog.end) > 0) {
+		if err := in.runInput(args); err != nil {
+			return err
+		}
+	}
+	// exit still runs the END actions, unless it is in one.
+	in.runBlock(in.prog.end)
+	return nil
+}
+
+// runInput runs the items on the records of args, as run says.
+func (in *interp) runInput(args []string) error {
+	var files bool
+	for _, a := range args {
+		if name, v, ok := assignment(a); ok {
+			in.setVar(name, input(v))
+			continue
+		}
+		files = true
+		in.vars["FILENAME"] = str(a)
+		r := in.stdin
+		var f *os.File
+		if a != "-" {
+			var err error
+			if f, err = os.Open(a); err != nil {
+				return err
+			}
+			r = f
+		}
+		fl, err := in.runFile(r)
+		if f != nil {
+			f.Close()
+		}
+		if err != nil || fl == flowExit {
+			return err
+		}
+	}
+	if !files {
+		_, err := in.runFile(in.stdin)
+		return err
+	}
+	return nil
+}
+
+// runBlock runs the BEGIN or END actions l, and returns flowExit if one
+// exits.
+func (in *interp) runBlock(l []stmt) flow {
+	for _, s := range l {
+		if s.exec(in) == flowExit {
+			return flowExit
+		}
+	}
+	return flowNormal
+}
+
+// flush flushes stdout and the files.
+func (in *interp) flush() error {
+	err := in.out.Flush()
+	for name := range in.files {
+		if !in.close(name) && err == nil {
+			err = fmt.Errorf("%s: close failed", name)
+		}
+	}
+	return err
+}
+
+// srand seeds rand with seed, or the time, and returns the seed before.
+func (in *interp) srand(seed *float64) float64 {
+	prev := in.seed
+	if seed == nil {
+		in.seed = float64(time.Now().Unix())
+	} else {
+		in.seed = *seed
+	}
+	in.rand.Seed(int64(in.seed))
+	return prev
+}
@@ -0,0 +1,236 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokType int
+
+const (
+	tokEOF tokType = iota
+	tokNewline
+	tokNumber
+	tokString
+	tokRegex
+	tokName
+	tokBuiltin
+	tokKeyword
+	// tokPunct is an operator or punctuation; its text says which.
+	tokPunct
+)
+
+// A token is a token of a program.
+type token struct {
+	typ  tokType
+	text string
+	num  float64
+	line int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "end of program"
+	case tokNewline:
+		return "newline"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokRegex:
+		return "/" + t.text + "/"
+	}
+	return t.text
+}
+
+var keywords = map[string]bool{
+	"BEGIN": true, "END": true, "if": true, "else": true, "while": true, "for": true,
+	"do": true, "break": true, "continue": true, "next": true, "exit": true,
+	"print": true, "printf": true, "delete": true, "in": true,
+}
+
+var builtins = map[string]bool{
+	"length": true, "substr": true, "index": true, "split": true, "sub": true,
+	"gsub": true, "match": true, "sprintf": true, "tolower": true, "toupper": true,
+	"int": true, "sqrt": true, "exp": true, "log": true, "sin": true, "cos": true,
+	"atan2": true, "rand": true, "srand": true, "close": true,
+}
+
+// puncts are the operators and punctuation, longest first, so that the
+// first which matches is the one.
+var puncts = []string{
+	"+=", "-=", "*=", "/=", "%=", "^=", "==", "<=", ">=", "!=", "++", "--",
+	"&&", "||", "!~", ">>",
+	"{", "}", "(", ")", "[", "]", ";", ",", "+", "-", "*", "/", "%", "^",
+	"!", ">", "<", "|", "?", ":", "~", "$", "=",
+}
+
+// escapes are the escapes of strings and regexes, other than \NNN.
+var escapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '"': '"', '/': '/',
+}
+
+// unescape turns the escapes of s into what they are.
+func unescape(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if c, ok := escapes[s[i]]; ok {
+			b.WriteByte(c)
+			continue
+		}
+		n := 0
+		for n < 3 && i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '7' {
+			n++
+		}
+		if n == 0 {
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+			continue
+		}
+		v, _ := strconv.ParseUint(s[i:i+n], 8, 8)
+		b.WriteByte(byte(v))
+		i += n - 1
+	}
+	return b.String()
+}
+
+// regexAllowed returns whether a / after t starts a regex, rather than
+// dividing.
+func regexAllowed(t token) bool {
+	switch t.typ {
+	case tokNumber, tokString, tokRegex, tokName, tokBuiltin:
+		return false
+	case tokPunct:
+		return t.text != ")" && t.text != "]" && t.text != "$" && t.text != "++" && t.text != "--"
+	}
+	return true
+}
+
+// lex splits program into tokens.
+func lex(program string) ([]token, error) {
+	var (
+		toks []token
+		line = 1
+		s    = program
+	)
+	last := func() token {
+		if len(toks) == 0 {
+			return token{typ: tokNewline}
+		}
+		return toks[len(toks)-1]
+	}
+	for {
+		// A \ at the end of a line joins it to the next.
+		for len(s) > 0 && (s[0] == ' ' || s[0] == '\t' || s[0] == '\r' || strings.HasPrefix(s, "\\\n")) {
+			if s[0] == '\\' {
+				s = s[1:]
+				line++
+			}
+			s = s[1:]
+		}
+		if s == "" {
+			return append(toks, token{typ: tokEOF, line: line}), nil
+		}
+		t := token{line: line}
+		switch c := s[0]; {
+		case c == '#':
+			i := strings.IndexByte(s, '\n')
+			if i < 0 {
+				i = len(s)
+			}
+			s = s[i:]
+			continue
+		case c == '\n':
+			t.typ, s = tokNewline, s[1:]
+			line++
+		case c == '"':
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+				if i < len(s) && s[i] == '\n' {
+					return nil, fmt.Errorf("line %d: newline in string", line)
+				}
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			t.typ, t.text, s = tokString, unescape(s[1:i]), s[i+1:]
+		case c == '/' && regexAllowed(last()):
+			i := 1
+			for inClass := false; i < len(s) && (s[i] != '/' || inClass); i++ {
+				switch s[i] {
+				case '\\':
+					i++
+				case '[':
+					inClass = true
+				case ']':
+					inClass = false
+				case '\n':
+					return nil, fmt.Errorf("line %d: newline in regex", line)
+				}
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("line %d: unterminated regex", line)
+			}
+			t.typ, t.text, s = tokRegex, strings.Replace(s[1:i], `\/`, "/", -1), s[i+1:]
+		case c >= '0' && c <= '9' || c == '.' && len(s) > 1 && s[1] >= '0' && s[1] <= '9':
+			i := 0
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				j := i + 1
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					for i = j; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+					}
+				}
+			}
+			n, err := strconv.ParseFloat(s[:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad number %q", line, s[:i])
+			}
+			t.typ, t.text, t.num, s = tokNumber, s[:i], n, s[i:]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			i := 0
+			for i < len(s) && (s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+			t.text, s = s[:i], s[i:]
+			switch {
+			case keywords[t.text]:
+				t.typ = tokKeyword
+			case builtins[t.text]:
+				t.typ = tokBuiltin
+			default:
+				t.typ = tokName
+			}
+		default:
+			for _, p := range puncts {
+				if strings.HasPrefix(s, p) {
+					t.typ, t.text, s = tokPunct, p, s[len(p):]
+					break
+				}
+			}
+			if t.typ != tokPunct {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, c)
+			}
+		}
+		toks = append(toks, t)
+	}
+}
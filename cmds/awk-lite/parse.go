@@ -0,0 +1,599 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// An item is a pattern and its action. With pattern2, the pattern is a
+// range, from a record pattern matches to one pattern2 does. A nil
+// pattern matches every record, and a nil action prints it.
+type item struct {
+	pattern, pattern2 expr
+	inRange           bool
+	action            stmt
+}
+
+// A program is a parsed program.
+type program struct {
+	begin, end []stmt
+	items      []*item
+}
+
+// A parser parses a program, a token at a time.
+type parser struct {
+	toks []token
+	pos  int
+	// noGt is set in the arguments of print and printf, where an
+	// unparenthesized > redirects the output, rather than comparing.
+	noGt bool
+}
+
+// syntaxError is how the parser gives up; parse recovers it.
+type syntaxError struct {
+	error
+}
+
+func (p *parser) errorf(format string, a ...interface{}) {
+	panic(syntaxError{fmt.Errorf("line %d: "+format, append([]interface{}{p.peek().line}, a...)...)})
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is returns whether the next token is the punctuation or keyword s.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.typ == tokPunct || t.typ == tokKeyword) && t.text == s
+}
+
+// accept takes the next token if it is s.
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) {
+	if !p.accept(s) {
+		p.errorf("got %v, want %s", p.peek(), s)
+	}
+}
+
+// newlines skips newlines, which are allowed after {, &&, ||, ,, do,
+// else, and the ) of if, for and while.
+func (p *parser) newlines() {
+	for p.peek().typ == tokNewline {
+		p.pos++
+	}
+}
+
+// terminators skips newlines and semicolons.
+func (p *parser) terminators() {
+	for p.peek().typ == tokNewline || p.is(";") {
+		p.pos++
+	}
+}
+
+// parse parses src.
+func parse(src string) (prog *program, err error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = e.error
+		}
+	}()
+	prog = &program{}
+	for p.terminators(); p.peek().typ != tokEOF; p.terminators() {
+		switch {
+		case p.accept("BEGIN"):
+			prog.begin = append(prog.begin, p.action())
+		case p.accept("END"):
+			prog.end = append(prog.end, p.action())
+		default:
+			it := &item{}
+			if !p.is("{") {
+				it.pattern = p.expr()
+				if p.accept(",") {
+					p.newlines()
+					it.pattern2 = p.expr()
+				}
+			}
+			if p.is("{") {
+				it.action = p.action()
+			}
+			prog.items = append(prog.items, it)
+		}
+	}
+	return prog, nil
+}
+
+// action parses { statements }.
+func (p *parser) action() stmt {
+	p.expect("{")
+	var b block
+	for p.terminators(); !p.accept("}"); p.terminators() {
+		if p.peek().typ == tokEOF {
+			p.errorf("missing }")
+		}
+		b = append(b, p.stmt())
+	}
+	return b
+}
+
+// end ends a simple statement: it is followed by ;, a newline, or }.
+func (p *parser) end() {
+	switch {
+	case p.accept(";"), p.peek().typ == tokNewline:
+	case p.is("}"), p.peek().typ == tokEOF:
+	default:
+		p.errorf("unexpected %v", p.peek())
+	}
+}
+
+func (p *parser) stmt() stmt {
+	switch {
+	case p.is("{"):
+		return p.action()
+	case p.accept(";"):
+		return block{}
+	case p.accept("if"):
+		s := &ifStmt{}
+		p.expect("(")
+		s.cond = p.expr()
+		p.expect(")")
+		p.newlines()
+		s.then = p.stmt()
+		// else may be after the ; or newline which ends the statement.
+		save := p.pos
+		p.terminators()
+		if p.accept("else") {
+			p.newlines()
+			s.els = p.stmt()
+		} else {
+			p.pos = save
+		}
+		return s
+	case p.accept("while"):
+		s := &whileStmt{}
+		p.expect("(")
+		s.cond = p.expr()
+		p.expect(")")
+		if p.accept(";") {
+			s.body = block{}
+			return s
+		}
+		p.newlines()
+		s.body = p.stmt()
+		return s
+	case p.accept("do"):
+		s := &whileStmt{do: true}
+		p.newlines()
+		s.body = p.stmt()
+		p.terminators()
+		p.expect("while")
+		p.expect("(")
+		s.cond = p.expr()
+		p.expect(")")
+		p.end()
+		return s
+	case p.accept("for"):
+		return p.forStmt()
+	case p.accept("break"):
+		p.end()
+		return flowStmt(flowBreak)
+	case p.accept("continue"):
+		p.end()
+		return flowStmt(flowContinue)
+	case p.accept("next"):
+		p.end()
+		return flowStmt(flowNext)
+	case p.accept("exit"):
+		s := &exitStmt{}
+		if !p.is(";") && !p.is("}") && p.peek().typ != tokNewline && p.peek().typ != tokEOF {
+			s.code = p.expr()
+		}
+		p.end()
+		return s
+	case p.accept("delete"):
+		s := &deleteStmt{name: p.name()}
+		if p.accept("[") {
+			s.index = p.exprList("]")
+		}
+		p.end()
+		return s
+	case p.is("print"), p.is("printf"):
+		s := p.print()
+		p.end()
+		return s
+	}
+	s := &exprStmt{p.expr()}
+	p.end()
+	return s
+}
+
+// name parses the name of a variable.
+func (p *parser) name() string {
+	t := p.next()
+	if t.typ != tokName {
+		p.errorf("got %v, want a name", t)
+	}
+	return t.text
+}
+
+func (p *parser) forStmt() stmt {
+	p.expect("(")
+	// for (NAME in ARRAY)
+	if t := p.toks[p.pos:]; len(t) > 4 && t[0].typ == tokName && t[1].text == "in" && t[2].typ == tokName && t[3].text == ")" {
+		s := &forInStmt{key: p.name()}
+		p.next()
+		s.array = p.name()
+		p.next()
+		p.newlines()
+		s.body = p.stmt()
+		return s
+	}
+	s := &forStmt{}
+	if !p.is(";") {
+		s.init = p.expr()
+	}
+	p.expect(";")
+	p.newlines()
+	if !p.is(";") {
+		s.cond = p.expr()
+	}
+	p.expect(";")
+	p.newlines()
+	if !p.is(")") {
+		s.post = p.expr()
+	}
+	p.expect(")")
+	if p.accept(";") {
+		s.body = block{}
+		return s
+	}
+	p.newlines()
+	s.body = p.stmt()
+	return s
+}
+
+// print parses print or printf, and where its output goes.
+func (p *parser) print() stmt {
+	s := &printStmt{printf: p.next().text == "printf"}
+	// print (a, b) is print a, b; but print (a)(b) is print a b.
+	save := p.pos
+	if p.accept("(") {
+		args := p.exprList(")")
+		if p.is(">") || p.is(">>") || p.is("|") || p.is(";") || p.is("}") || p.peek().typ == tokNewline || p.peek().typ == tokEOF {
+			s.args = args
+		} else {
+			p.pos = save
+		}
+	}
+	if s.args == nil && !p.is(";") && !p.is("}") && !p.is(">") && !p.is(">>") && p.peek().typ != tokNewline && p.peek().typ != tokEOF {
+		p.noGt = true
+		s.args = append(s.args, p.expr())
+		for p.accept(",") {
+			p.newlines()
+			s.args = append(s.args, p.expr())
+		}
+		p.noGt = false
+	}
+	if s.printf && len(s.args) == 0 {
+		p.errorf("printf: no format")
+	}
+	switch {
+	case p.is(">"), p.is(">>"):
+		s.redirect = p.next().text
+		// The file name is a concatenation, so print > "f" x is to
+		// the file named "f" x.
+		p.noGt = true
+		s.dest = p.concat()
+		p.noGt = false
+	case p.is("|"):
+		p.errorf("output to commands is not supported")
+	}
+	return s
+}
+
+// exprList parses expressions separated by commas, up to end.
+func (p *parser) exprList(end string) []expr {
+	noGt := p.noGt
+	p.noGt = false
+	defer func() { p.noGt = noGt }()
+	var l []expr
+	p.newlines()
+	for !p.accept(end) {
+		if len(l) > 0 {
+			p.expect(",")
+			p.newlines()
+		}
+		l = append(l, p.expr())
+		p.newlines()
+	}
+	return l
+}
+
+// The parsers of expressions are in order of precedence, lowest first.
+
+func (p *parser) expr() expr {
+	e := p.ternary()
+	if lv, ok := e.(lvalue); ok {
+		for _, op := range []string{"=", "+=", "-=", "*=", "/=", "%=", "^="} {
+			if p.accept(op) {
+				p.newlines()
+				return &assignExpr{lv: lv, op: op[:len(op)-1], rhs: p.expr()}
+			}
+		}
+	}
+	return e
+}
+
+func (p *parser) ternary() expr {
+	cond := p.or()
+	if !p.accept("?") {
+		return cond
+	}
+	p.newlines()
+	yes := p.expr()
+	p.newlines()
+	p.expect(":")
+	p.newlines()
+	return &condExpr{cond: cond, yes: yes, no: p.expr()}
+}
+
+func (p *parser) or() expr {
+	e := p.and()
+	for p.accept("||") {
+		p.newlines()
+		e = &binaryExpr{op: "||", l: e, r: p.and()}
+	}
+	return e
+}
+
+func (p *parser) and() expr {
+	e := p.in()
+	for p.accept("&&") {
+		p.newlines()
+		e = &binaryExpr{op: "&&", l: e, r: p.in()}
+	}
+	return e
+}
+
+func (p *parser) in() expr {
+	e := p.match()
+	for p.accept("in") {
+		e = &inExpr{index: []expr{e}, array: p.name()}
+	}
+	return e
+}
+
+func (p *parser) match() expr {
+	e := p.comparison()
+	for p.is("~") || p.is("!~") {
+		not := p.next().text == "!~"
+		e = &matchExpr{l: e, re: p.comparison(), not: not}
+	}
+	return e
+}
+
+func (p *parser) comparison() expr {
+	e := p.concat()
+	for _, op := range []string{"<", "<=", "==", "!=", ">=", ">"} {
+		if op == ">" && p.noGt {
+			continue
+		}
+		if p.accept(op) {
+			return &binaryExpr{op: op, l: e, r: p.concat()}
+		}
+	}
+	return e
+}
+
+// concatStarts returns whether t starts an expression which is
+// concatenated to the one before it.
+func concatStarts(t token) bool {
+	switch t.typ {
+	case tokNumber, tokString, tokRegex, tokName, tokBuiltin:
+		return true
+	case tokPunct:
+		return t.text == "$" || t.text == "(" || t.text == "++" || t.text == "--"
+	}
+	return false
+}
+
+func (p *parser) concat() expr {
+	e := p.additive()
+	for concatStarts(p.peek()) {
+		e = &binaryExpr{op: " ", l: e, r: p.additive()}
+	}
+	return e
+}
+
+func (p *parser) additive() expr {
+	e := p.multiplicative()
+	for p.is("+") || p.is("-") {
+		op := p.next().text
+		e = &binaryExpr{op: op, l: e, r: p.multiplicative()}
+	}
+	return e
+}
+
+func (p *parser) multiplicative() expr {
+	e := p.unary()
+	for p.is("*") || p.is("/") || p.is("%") {
+		op := p.next().text
+		e = &binaryExpr{op: op, l: e, r: p.unary()}
+	}
+	return e
+}
+
+func (p *parser) unary() expr {
+	if p.is("!") || p.is("-") || p.is("+") {
+		op := p.next().text
+		return &unaryExpr{op: op, e: p.unary()}
+	}
+	return p.power()
+}
+
+func (p *parser) power() expr {
+	e := p.incr()
+	if p.accept("^") {
+		// ^ is right associative, and binds tighter than unary minus
+		// on its left, but not on its right: 2^-1 is 0.5.
+		return &binaryExpr{op: "^", l: e, r: p.unary()}
+	}
+	return e
+}
+
+func (p *parser) incr() expr {
+	if p.is("++") || p.is("--") {
+		delta := 1.0
+		if p.next().text == "--" {
+			delta = -1
+		}
+		lv, ok := p.incr().(lvalue)
+		if !ok {
+			p.errorf("++ or -- of something which is not a variable")
+		}
+		return &incrExpr{lv: lv, delta: delta, pre: true}
+	}
+	e := p.primary()
+	if lv, ok := e.(lvalue); ok && (p.is("++") || p.is("--")) {
+		delta := 1.0
+		if p.next().text == "--" {
+			delta = -1
+		}
+		return &incrExpr{lv: lv, delta: delta}
+	}
+	return e
+}
+
+// regex compiles the regex of t.
+func (p *parser) regex(t token) *regexp.Regexp {
+	re, err := regexp.Compile(t.text)
+	if err != nil {
+		p.errorf("%v", err)
+	}
+	return re
+}
+
+func (p *parser) primary() expr {
+	t := p.next()
+	switch t.typ {
+	case tokNumber:
+		return &numExpr{t.num}
+	case tokString:
+		return &strExpr{t.text}
+	case tokRegex:
+		return &regexExpr{p.regex(t)}
+	case tokName:
+		if unsupported[t.text] {
+			p.pos--
+			p.errorf("%s is not supported", t.text)
+		}
+		if p.accept("[") {
+			return &indexExpr{name: t.text, index: p.exprList("]")}
+		}
+		return &varExpr{t.text}
+	case tokBuiltin:
+		c := &callExpr{name: t.text}
+		if p.accept("(") {
+			c.args = p.exprList(")")
+		} else if t.text != "length" {
+			p.errorf("%s: missing (", t.text)
+		}
+		p.checkCall(c)
+		return c
+	case tokPunct:
+		switch t.text {
+		case "$":
+			// $ binds tighter than ++, so $i++ is ($i)++, but $++i
+			// is $(++i).
+			if p.is("++") || p.is("--") {
+				return &fieldExpr{p.incr()}
+			}
+			return &fieldExpr{p.primary()}
+		case "(":
+			noGt := p.noGt
+			p.noGt = false
+			e := p.expr()
+			p.newlines()
+			// (i, j) in a is in a[i, j].
+			if p.is(",") {
+				l := []expr{e}
+				for p.accept(",") {
+					p.newlines()
+					l = append(l, p.expr())
+				}
+				p.expect(")")
+				p.expect("in")
+				p.noGt = noGt
+				return &inExpr{index: l, array: p.name()}
+			}
+			p.expect(")")
+			p.noGt = noGt
+			return e
+		}
+	}
+	p.pos--
+	p.errorf("unexpected %v", t)
+	return nil
+}
+
+// unsupported are the names of awk which awk-lite does not have.
+var unsupported = map[string]bool{
+	"function": true, "func": true, "return": true, "getline": true, "system": true,
+}
+
+// arity is the least and most arguments of each builtin.
+var arity = map[string][2]int{
+	"length": {0, 1}, "substr": {2, 3}, "index": {2, 2}, "split": {2, 3},
+	"sub": {2, 3}, "gsub": {2, 3}, "match": {2, 2}, "sprintf": {1, 1 << 16},
+	"tolower": {1, 1}, "toupper": {1, 1}, "int": {1, 1}, "sqrt": {1, 1},
+	"exp": {1, 1}, "log": {1, 1}, "sin": {1, 1}, "cos": {1, 1}, "atan2": {2, 2},
+	"rand": {0, 0}, "srand": {0, 1}, "close": {1, 1},
+}
+
+// checkCall checks the arguments of the builtin c.
+func (p *parser) checkCall(c *callExpr) {
+	a := arity[c.name]
+	if len(c.args) < a[0] || len(c.args) > a[1] {
+		p.errorf("%s: wrong number of arguments", c.name)
+	}
+	switch c.name {
+	case "split":
+		if _, ok := c.args[1].(*varExpr); !ok {
+			p.errorf("split: the second argument must be an array")
+		}
+	case "sub", "gsub":
+		if len(c.args) == 3 {
+			if _, ok := c.args[2].(lvalue); !ok {
+				p.errorf("%s: the third argument must be a variable", c.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindNum kind = iota
+	kindStr
+	// kindStrNum is a string from the input which looks like a number:
+	// a field, an element of split, or a -v or command line variable.
+	// It compares as a number with numbers, as does an unset variable.
+	kindStrNum
+)
+
+// A value is what expressions evaluate to: a number, or a string.
+type value struct {
+	kind kind
+	s    string
+	n    float64
+}
+
+func num(n float64) value {
+	return value{kind: kindNum, n: n}
+}
+
+func str(s string) value {
+	return value{kind: kindStr, s: s}
+}
+
+// unset is the value of a variable which is not set: "" and 0.
+var unset = value{kind: kindStrNum}
+
+// input returns the value of s, a string from the input.
+func input(s string) value {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return str(s)
+	}
+	if n, err := strconv.ParseFloat(t, 64); err == nil && !strings.ContainsAny(t, "xXnNiI") {
+		return value{kind: kindStrNum, s: s, n: n}
+	}
+	return str(s)
+}
+
+func boolean(b bool) value {
+	if b {
+		return num(1)
+	}
+	return num(0)
+}
+
+// toNum returns the number at the start of s, or 0.
+func toNum(s string) float64 {
+	s = strings.TrimLeft(s, " \t\n\r\f\v")
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+	n := digits()
+	if i < len(s) && s[i] == '.' {
+		i++
+		n += digits()
+	}
+	if n == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			i = j
+		}
+	}
+	f, _ := strconv.ParseFloat(s[:i], 64)
+	return f
+}
+
+// numStr returns the string of the number n; integers are printed as
+// integers, and others with format.
+func numStr(n float64, format string) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e16 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	switch {
+	case math.IsNaN(n):
+		return "nan"
+	case math.IsInf(n, 1):
+		return "inf"
+	case math.IsInf(n, -1):
+		return "-inf"
+	}
+	return sprintf(format, []value{num(n)})
+}
+
+func (v value) num() float64 {
+	if v.kind == kindStr {
+		return toNum(v.s)
+	}
+	return v.n
+}
+
+// str returns v as a string; numbers are converted with format, which is
+// CONVFMT, or OFMT when printed.
+func (v value) str(format string) string {
+	if v.kind == kindNum {
+		return numStr(v.n, format)
+	}
+	return v.s
+}
+
+// bool returns whether v is true: a number which is not 0, or a string
+// which is not "". A strnum is a number.
+func (v value) bool() bool {
+	if v.kind == kindStr {
+		return v.s != ""
+	}
+	return v.n != 0
+}
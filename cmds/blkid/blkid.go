@@ -0,0 +1,121 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Blkid prints the types, UUIDs and labels of file systems.
+//
+// Synopsis:
+//     blkid [-s TAG] [-o full|value] [DEV...]
+//     blkid -U UUID
+//     blkid -L LABEL
+//
+// Description:
+//     blkid reads the superblocks of the DEVs, or, without any, of all the
+//     block devices, and prints the UUID, LABEL and TYPE of those with a
+//     file system, or swap, it knows. -U and -L print the device with the
+//     file system with that UUID or LABEL. It exits with status 2 if none
+//     is found.
+//
+// Options:
+//     -s: only print TAG, UUID, LABEL or TYPE
+//     -o: print DEV: TAG="VALUE"..., full, or only the values, value
+//     -U: print the device with UUID
+//     -L: print the device with LABEL
+//
+// Example:
+//     $ blkid /dev/sda1
+//     /dev/sda1: UUID="0e2a0c1e-6e2f-4d2e-9f3a-6c1b1e0c2d4f" LABEL="root" TYPE="ext4"
+//     $ blkid -s TYPE -o value /dev/sda1
+//     ext4
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+var (
+	tag    = flag.String("s", "", "only print `tag`")
+	format = flag.String("o", "full", "output `format`, full or value")
+	uuid   = flag.String("U", "", "print the device with `uuid`")
+	label  = flag.String("L", "", "print the device with `label`")
+)
+
+// tags returns the tags and values of fs, in the order blkid prints them.
+func tags(fs *mount.FSInfo) [][2]string {
+	var t [][2]string
+	for _, kv := range [][2]string{{"UUID", fs.UUID}, {"LABEL", fs.Label}, {"TYPE", fs.Type}} {
+		if kv[1] != "" && (*tag == "" || *tag == kv[0]) {
+			t = append(t, kv)
+		}
+	}
+	return t
+}
+
+// blkid prints what it finds of the file systems on devs, and returns
+// whether it found any.
+func blkid(w io.Writer, devs []string) (bool, error) {
+	if *format != "full" && *format != "value" {
+		return false, fmt.Errorf("unknown output format %q", *format)
+	}
+	found := false
+	for _, d := range devs {
+		fs, err := mount.ProbeFile(d)
+		if err != nil {
+			continue
+		}
+		t := tags(fs)
+		if len(t) == 0 {
+			continue
+		}
+		found = true
+		if *format == "value" {
+			for _, kv := range t {
+				fmt.Fprintln(w, kv[1])
+			}
+			continue
+		}
+		fmt.Fprintf(w, "%s:", d)
+		for _, kv := range t {
+			fmt.Fprintf(w, " %s=%q", kv[0], kv[1])
+		}
+		fmt.Fprintln(w)
+	}
+	return found, nil
+}
+
+func main() {
+	flag.Parse()
+	if *uuid != "" || *label != "" {
+		spec := "UUID=" + *uuid
+		if *label != "" {
+			spec = "LABEL=" + *label
+		}
+		d, err := mount.FindDevice(spec)
+		if err != nil {
+			os.Exit(2)
+		}
+		fmt.Println(d)
+		return
+	}
+
+	devs := flag.Args()
+	if len(devs) == 0 {
+		var err error
+		if devs, err = mount.BlockDevices(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	found, err := blkid(os.Stdout, devs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !found {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// images writes an ext2 and a FAT16 image, ext and fat, and a file with
+// neither, zero, in a new directory.
+func images(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "blkid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := make([]byte, 4096)
+	copy(ext[1024+0x38:], []byte{0x53, 0xef})
+	copy(ext[1024+0x68:], []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef})
+	copy(ext[1024+0x78:], "root")
+	fat := make([]byte, 4096)
+	copy(fat[39:], []byte{0xef, 0xbe, 0xad, 0xde})
+	copy(fat[43:], "NO NAME    FAT16   ")
+	copy(fat[510:], []byte{0x55, 0xaa})
+
+	for n, b := range map[string][]byte{"ext": ext, "fat": fat, "zero": make([]byte, 4096)} {
+		p := filepath.Join(dir, n)
+		if err := ioutil.WriteFile(p, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBlkid(t *testing.T) {
+	dir := images(t)
+	defer os.RemoveAll(dir)
+	ext, fat, zero := filepath.Join(dir, "ext"), filepath.Join(dir, "fat"), filepath.Join(dir, "zero")
+
+	for _, tt := range []struct {
+		tag, format string
+		devs        []string
+		want        string
+		found       bool
+	}{
+		{"", "full", []string{ext, zero, fat}, ext + `: UUID="12345678-9abc-def0-0123-456789abcdef" LABEL="root" TYPE="ext2"` + "\n" + fat + `: UUID="DEAD-BEEF" TYPE="vfat"` + "\n", true},
+		{"TYPE", "value", []string{ext, fat}, "ext2\nvfat\n", true},
+		{"LABEL", "full", []string{fat}, "", false},
+		{"", "full", []string{zero, filepath.Join(dir, "missing")}, "", false},
+	} {
+		*tag, *format = tt.tag, tt.format
+		var b bytes.Buffer
+		found, err := blkid(&b, tt.devs)
+		if err != nil || found != tt.found || b.String() != tt.want {
+			t.Errorf("blkid -s %q -o %v %v: got %v, %v, %q, want %v, nil, %q", tt.tag, tt.format, tt.devs, found, err, b.String(), tt.found, tt.want)
+		}
+	}
+
+	*tag, *format = "", "json"
+	if _, err := blkid(ioutil.Discard, []string{ext}); err == nil {
+		t.Errorf("blkid -o json: got nil, want an error")
+	}
+}
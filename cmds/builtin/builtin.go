@@ -31,7 +31,7 @@ type mount struct {
 
 var (
 	startPart = "package main\n"
-	initPart  = "func init() {\n	addBuiltIn(\"%s\", %s)\n}\nfunc %s(c*Command) error {\nvar err error\n"
+	initPart  = "func init() {\n	addBuiltIn(\"%s\", \"\", %s)\n}\nfunc %s(c*Command) error {\nvar err error\n"
 	//	endPart = "\n}\n)\n}\n"
 	endPart   = "\nreturn err\n}\n"
 	namespace = []mount{
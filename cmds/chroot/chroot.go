@@ -0,0 +1,202 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Chroot runs a command with another root directory.
+//
+// Synopsis:
+//     chroot [-userspec USER[:GROUP]] [-d DIR] [-bind] NEWROOT [COMMAND [ARGS...]]
+//
+// Description:
+//     chroot runs COMMAND, by default $SHELL or /bin/sh, with NEWROOT as
+//     its root directory, and exits with its exit status. USER and GROUP
+//     are numbers, or names in NEWROOT/etc/passwd and NEWROOT/etc/group;
+//     the group is that of the user if it is not given.
+//
+//     With -bind, proc and sysfs are mounted on NEWROOT/proc and
+//     NEWROOT/sys, and /dev is bound on NEWROOT/dev, for the command, and
+//     unmounted once it exits. That is what most programs need to run,
+//     e.g. to repair an installed system from the initramfs.
+//
+// Options:
+//     -userspec: user and group to run COMMAND as
+//     -d:        directory in NEWROOT to run COMMAND in; the default is /
+//     -bind:     mount /proc, /sys and /dev in NEWROOT
+//
+// Example:
+//     mount /dev/sda1 /mnt
+//     chroot -bind /mnt grub-install /dev/sda
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var (
+	userspec = flag.String("userspec", "", "`USER[:GROUP]` to run the command as")
+	dir      = flag.String("d", "/", "Directory in the new root to run the command in")
+	bind     = flag.Bool("bind", false, "Mount /proc, /sys and /dev in the new root")
+)
+
+// mounts are what -bind mounts in the new root.
+var mounts = []struct {
+	source string
+	target string
+	fstype string
+	flags  uintptr
+}{
+	{"proc", "proc", "proc", 0},
+	{"sysfs", "sys", "sysfs", 0},
+	{"/dev", "dev", "", syscall.MS_BIND | syscall.MS_REC},
+}
+
+// lookup returns the number of name, a number or a name in the first field
+// of a line of the file db, in the form of /etc/passwd, and the number in
+// the field after that, e.g. the group of a user. A number has no group.
+func lookup(db, name string, field int) (uint32, uint32, error) {
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(n), 0, nil
+	}
+	f, err := os.Open(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Split(s.Text(), ":")
+		if len(fields) <= field+1 || fields[0] != name {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[field], 10, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%v: bad id for %v: %v", db, name, err)
+		}
+		m, _ := strconv.ParseUint(fields[field+1], 10, 32)
+		return uint32(n), uint32(m), nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("%v: no %v", db, name)
+}
+
+// credential returns the credential of spec, USER[:GROUP], in root.
+func credential(root, spec string) (*syscall.Credential, error) {
+	us := strings.SplitN(spec, ":", 2)
+	uid, gid, err := lookup(filepath.Join(root, "etc/passwd"), us[0], 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(us) == 2 && us[1] != "" {
+		if gid, _, err = lookup(filepath.Join(root, "etc/group"), us[1], 2); err != nil {
+			return nil, err
+		}
+	}
+	return &syscall.Credential{Uid: uid, Gid: gid}, nil
+}
+
+// mountAll mounts mounts in root, and returns those it mounted.
+func mountAll(root string) ([]string, error) {
+	var done []string
+	for _, m := range mounts {
+		t := filepath.Join(root, m.target)
+		if err := syscall.Mount(m.source, t, m.fstype, m.flags, ""); err != nil {
+			return done, fmt.Errorf("mounting %v on %v: %v", m.source, t, err)
+		}
+		done = append(done, t)
+	}
+	return done, nil
+}
+
+// unmountAll unmounts targets, last first.
+func unmountAll(targets []string) {
+	for i := len(targets) - 1; i >= 0; i-- {
+		if err := syscall.Unmount(targets[i], syscall.MNT_DETACH); err != nil {
+			log.Printf("chroot: unmounting %v: %v", targets[i], err)
+		}
+	}
+}
+
+// lookPath returns the path in root of the program name, found in $PATH in
+// root, if it has no slash in it.
+func lookPath(root, name string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	path := os.Getenv("PATH")
+	if path == "" {
+		path = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	}
+	for _, d := range filepath.SplitList(path) {
+		p := filepath.Join("/", d, name)
+		if fi, err := os.Stat(filepath.Join(root, p)); err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+			return p
+		}
+	}
+	return name
+}
+
+// chroot runs args in root, and returns its exit status.
+func chroot(root string, args []string) (int, error) {
+	if len(args) == 0 {
+		sh := os.Getenv("SHELL")
+		if sh == "" {
+			sh = "/bin/sh"
+		}
+		args = []string{sh, "-i"}
+	}
+	c := exec.Command(args[0], args[1:]...)
+	// exec.Command looks the command up in our root rather than the
+	// new one.
+	c.Path = lookPath(root, args[0])
+	c.Dir = *dir
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Chroot: root}
+	if *userspec != "" {
+		cred, err := credential(root, *userspec)
+		if err != nil {
+			return 1, err
+		}
+		c.SysProcAttr.Credential = cred
+	}
+
+	if *bind {
+		done, err := mountAll(root)
+		defer unmountAll(done)
+		if err != nil {
+			return 1, err
+		}
+	}
+
+	err := c.Run()
+	if e, ok := err.(*exec.ExitError); ok {
+		return e.Sys().(syscall.WaitStatus).ExitStatus(), nil
+	}
+	if err != nil {
+		return 127, err
+	}
+	return 0, nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	status, err := chroot(flag.Arg(0), flag.Args()[1:])
+	if err != nil {
+		log.Printf("chroot: %v", err)
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestCredential(t *testing.T) {
+	root, err := ioutil.TempDir("", "chroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.Mkdir(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"etc/passwd": "root:x:0:0:root:/root:/bin/sh\nbob:x:1000:100:Bob:/home/bob:/bin/sh\nbad:x:nope:1::/:\n",
+		"etc/group":  "root:x:0:\nusers:x:100:bob\nwheel:x:10:bob,root\n",
+	}
+	for n, c := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, n), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		spec string
+		want *syscall.Credential
+		err  bool
+	}{
+		{spec: "bob", want: &syscall.Credential{Uid: 1000, Gid: 100}},
+		{spec: "bob:wheel", want: &syscall.Credential{Uid: 1000, Gid: 10}},
+		{spec: "bob:", want: &syscall.Credential{Uid: 1000, Gid: 100}},
+		{spec: "42", want: &syscall.Credential{Uid: 42, Gid: 0}},
+		{spec: "42:7", want: &syscall.Credential{Uid: 42, Gid: 7}},
+		{spec: "root:users", want: &syscall.Credential{Uid: 0, Gid: 100}},
+		{spec: "alice", err: true},
+		{spec: "bob:nogroup", err: true},
+		{spec: "bad", err: true},
+	} {
+		got, err := credential(root, tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("credential(%q): got err %v, want err %v", tt.spec, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("credential(%q): got %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestLookPath(t *testing.T) {
+	root, err := ioutil.TempDir("", "chroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.MkdirAll(filepath.Join(root, "sbin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "sbin/fsck"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "/bin:/sbin")
+
+	for _, tt := range []struct{ name, want string }{
+		{"fsck", "/sbin/fsck"},
+		{"nosuch", "nosuch"},
+		{"./fsck", "./fsck"},
+	} {
+		if got := lookPath(root, tt.name); got != tt.want {
+			t.Errorf("lookPath(%q): got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
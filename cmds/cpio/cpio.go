@@ -7,44 +7,70 @@
 //
 //
 // Synopsis:
-//     cpio
+//     cpio -i|-o|-t [-H FORMAT] [-v]
+//     cpio i|o|t
 //
 // Description:
+//     The archive is read from stdin or written to stdout. FORMAT is newc,
+//     odc or bin; the default is newc.
 //
 // Options:
-//     o: output an archive to stdout given a pattern
-//     i: output files from a stdin stream
-//     t: print table of contents
+//     -o: output an archive to stdout of the files named on stdin
+//     -i: extract the files of the archive on stdin
+//     -t: print table of contents
+//     -H: archive format
 //     -v: debug prints
 //
-// Bugs: in i mode, it can't use non-seekable stdin, i.e. a pipe. Yep, this sucks.
-// But if we implement seek on such things, we have to do it by reading, which
-// really sucks. It's doable, we'll do it if we have to, but for now I'd like
-// to avoid the complexity. cpio is a 40 year old concept. If you want something
-// better, see ../archive which has a VTOC and separates data from metadata (unlike cpio).
-// We could test for ESPIPE and fix it that way ... later.
+// Example:
+//     find etc | cpio -o > etc.cpio
+//     gzip -dc /initramfs.cpio.gz | cpio -t
+//
+// If stdin is not seekable, e.g. a pipe, the archive is read into memory
+// for -i and -t. cpio is a 40 year old concept. If you want something
+// better, see ../archive which has a VTOC and separates data from metadata
+// (unlike cpio).
 package main
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 
 	"github.com/u-root/u-root/pkg/cpio"
+	_ "github.com/u-root/u-root/pkg/cpio/bin"
 	_ "github.com/u-root/u-root/pkg/cpio/newc"
+	_ "github.com/u-root/u-root/pkg/cpio/odc"
 )
 
 var (
 	debug  = func(string, ...interface{}) {}
 	d      = flag.Bool("v", false, "Debug prints")
 	format = flag.String("H", "newc", "format")
+	input  = flag.Bool("i", false, "extract an archive")
+	output = flag.Bool("o", false, "create an archive")
+	list   = flag.Bool("t", false, "list an archive")
 )
 
 func usage() {
-	log.Fatalf("Usage: cpio")
+	log.Fatalf("Usage: cpio -i|-o|-t [-H format] [-v]")
+}
+
+// archive returns stdin as an io.ReaderAt, reading it into memory if it
+// can not seek.
+func archive() io.ReaderAt {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode().IsRegular() {
+		return os.Stdin
+	}
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalf("Reading stdin: %v", err)
+	}
+	return bytes.NewReader(b)
 }
 
 func main() {
@@ -55,10 +81,19 @@ func main() {
 
 	a := flag.Args()
 	debug("Args %v", a)
-	if len(a) < 1 {
+	var op string
+	switch {
+	case *input:
+		op = "i"
+	case *output:
+		op = "o"
+	case *list:
+		op = "t"
+	case len(a) > 0:
+		op = a[0]
+	default:
 		usage()
 	}
-	op := a[0]
 
 	archiver, err := cpio.Format(*format)
 	if err != nil {
@@ -67,7 +102,8 @@ func main() {
 
 	switch op {
 	case "i":
-		rr := archiver.Reader(os.Stdin)
+		rr := archiver.Reader(archive())
+		e := cpio.NewExtractor()
 		for {
 			rec, err := rr.ReadRecord()
 			if err == io.EOF {
@@ -77,7 +113,7 @@ func main() {
 				log.Fatalf("error reading records: %v", err)
 			}
 			debug("Creating %s\n", rec)
-			if err := cpio.CreateFile(rec); err != nil {
+			if err := e.CreateFile(rec); err != nil {
 				log.Printf("Creating %q failed: %v", rec.Name, err)
 			}
 		}
@@ -105,7 +141,7 @@ func main() {
 		}
 
 	case "t":
-		rr := archiver.Reader(os.Stdin)
+		rr := archiver.Reader(archive())
 		for {
 			rec, err := rr.ReadRecord()
 			if err == io.EOF {
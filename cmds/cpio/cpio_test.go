@@ -0,0 +1,78 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+// TestCreateExtract archives a directory with a hard link in it, reads the
+// archive back through a pipe, and extracts it.
+func TestCreateExtract(t *testing.T) {
+	tmpDir, cpioPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	src := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "d", "f"), []byte("hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(src, "d", "f"), filepath.Join(src, "d", "g")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"newc", "odc", "bin"} {
+		c := exec.Command(cpioPath, "-o", "-H", format)
+		c.Dir = src
+		c.Stdin = strings.NewReader("d\nd/f\nd/g\n")
+		archive, err := c.Output()
+		if err != nil {
+			t.Errorf("%s: cpio -o: %v", format, err)
+			continue
+		}
+
+		c = exec.Command(cpioPath, "-t", "-H", format)
+		c.Stdin = bytes.NewReader(archive)
+		out, err := c.Output()
+		if err != nil {
+			t.Errorf("%s: cpio -t: %v", format, err)
+			continue
+		}
+		if n := strings.Count(string(out), "\n"); n != 3 {
+			t.Errorf("%s: cpio -t listed %d files, want 3:\n%s", format, n, out)
+		}
+
+		dst := filepath.Join(tmpDir, format)
+		if err := os.Mkdir(dst, 0755); err != nil {
+			t.Fatal(err)
+		}
+		c = exec.Command(cpioPath, "-i", "-H", format)
+		c.Dir = dst
+		c.Stdin = bytes.NewReader(archive)
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Errorf("%s: cpio -i: %v\n%s", format, err, out)
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dst, "d", "g"))
+		if err != nil || string(b) != "hi\n" {
+			t.Errorf("%s: d/g is %q, %v, want %q", format, b, err, "hi\n")
+		}
+		f, err1 := os.Stat(filepath.Join(dst, "d", "f"))
+		g, err2 := os.Stat(filepath.Join(dst, "d", "g"))
+		if err1 != nil || err2 != nil || !os.SameFile(f, g) {
+			t.Errorf("%s: d/f and d/g are not the same file: %v, %v", format, err1, err2)
+		}
+	}
+}
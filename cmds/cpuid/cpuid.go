@@ -0,0 +1,117 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// cpuid prints what the CPUID instruction says of the CPU it runs on.
+//
+// Synopsis:
+//     cpuid [-raw]
+//
+// Description:
+//     cpuid runs CPUID itself, not reading /proc/cpuinfo, and prints the
+//     vendor, brand, family, model and stepping of the CPU, its features,
+//     named as in /proc/cpuinfo, and its caches. It only runs on x86.
+//
+//     The CPU is the one cpuid runs on; on a machine whose CPUs are not
+//     all the same, run it under taskset for the others.
+//
+// Options:
+//     -raw: print the registers of every leaf and subleaf, one per line,
+//           to compare machines with diff
+//
+// Example:
+//     $ cpuid
+//     Vendor:   GenuineIntel
+//     Brand:    Intel(R) Xeon(R) Processor
+//     Family:   6 (0x6)
+//     Model:    143 (0x8f)
+//     Stepping: 8
+//     Features: fpu vme de pse tsc msr pae mce cx8 apic sep mtrr pge mca
+//               ...
+//     Caches:   L1d 48 KiB, 12-way, 64 byte lines, shared by 2 threads
+//               ...
+//     $ cpuid -raw
+//     0x00000000 0x00: eax=0x00000020 ebx=0x756e6547 ecx=0x6c65746e edx=0x49656e69
+//     ...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/cpuid"
+)
+
+var raw = flag.Bool("raw", false, "print the registers of every leaf")
+
+// width is how wide the lists of features are wrapped to.
+const width = 72
+
+// size returns s bytes in the largest unit it is a whole number of.
+func size(s int) string {
+	switch {
+	case s >= 1<<20 && s%(1<<20) == 0:
+		return fmt.Sprintf("%d MiB", s>>20)
+	case s >= 1<<10 && s%(1<<10) == 0:
+		return fmt.Sprintf("%d KiB", s>>10)
+	}
+	return fmt.Sprintf("%d bytes", s)
+}
+
+// show prints i.
+func show(w io.Writer, i *cpuid.Info) {
+	const indent = "          "
+	fmt.Fprintf(w, "Vendor:   %s\n", i.Vendor)
+	if i.Brand != "" {
+		fmt.Fprintf(w, "Brand:    %s\n", i.Brand)
+	}
+	fmt.Fprintf(w, "Family:   %d (%#x)\n", i.Family, i.Family)
+	fmt.Fprintf(w, "Model:    %d (%#x)\n", i.Model, i.Model)
+	fmt.Fprintf(w, "Stepping: %d\n", i.Stepping)
+
+	// Each feature has a space before it, so the line starts a space
+	// short.
+	line := "Features:"
+	for _, f := range i.Features {
+		if len(line)+1+len(f) > width && line != indent[1:] {
+			fmt.Fprintln(w, line)
+			line = indent[1:]
+		}
+		line += " " + f
+	}
+	fmt.Fprintln(w, line)
+
+	for n, c := range i.Caches {
+		p := indent
+		if n == 0 {
+			p = "Caches:   "
+		}
+		fmt.Fprintf(w, "%s%-3s %s, %d-way, %d byte lines, shared by %d threads\n", p, c.Name(), size(c.Size), c.Ways, c.LineSize, c.SharedBy)
+	}
+}
+
+// dump prints the leaves l.
+func dump(w io.Writer, l []cpuid.Leaf) {
+	for _, l := range l {
+		fmt.Fprintf(w, "0x%08x 0x%02x: eax=0x%08x ebx=0x%08x ecx=0x%08x edx=0x%08x\n", l.Leaf, l.Sub, l.EAX, l.EBX, l.ECX, l.EDX)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if !cpuid.Supported {
+		log.Fatal("there is no CPUID on this architecture")
+	}
+	if *raw {
+		dump(os.Stdout, cpuid.Dump(cpuid.Native))
+		return
+	}
+	show(os.Stdout, cpuid.Decode(cpuid.Native))
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cpuid"
+)
+
+func TestSize(t *testing.T) {
+	for _, tt := range []struct {
+		size int
+		want string
+	}{
+		{64, "64 bytes"},
+		{48 << 10, "48 KiB"},
+		{1536 << 10, "1536 KiB"},
+		{105 << 20, "105 MiB"},
+	} {
+		if got := size(tt.size); got != tt.want {
+			t.Errorf("size(%d): got %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestShow(t *testing.T) {
+	i := &cpuid.Info{
+		Vendor:   "GenuineIntel",
+		Family:   6,
+		Model:    0x8f,
+		Stepping: 8,
+		Features: []string{"fpu", "vme", "de", "pse", "tsc", "msr", "pae", "mce", "cx8", "apic", "sep", "mtrr", "pge", "mca", "cmov", "pat"},
+		Caches: []cpuid.Cache{
+			{Level: 1, Type: "Data", Size: 48 << 10, Ways: 12, LineSize: 64, Sets: 64, SharedBy: 2},
+			{Level: 2, Type: "Unified", Size: 2 << 20, Ways: 16, LineSize: 64, Sets: 2048, SharedBy: 2},
+		},
+	}
+	want := `Vendor:   GenuineIntel
+Family:   6 (0x6)
+Model:    143 (0x8f)
+Stepping: 8
+Features: fpu vme de pse tsc msr pae mce cx8 apic sep mtrr pge mca cmov
+          pat
+Caches:   L1d 48 KiB, 12-way, 64 byte lines, shared by 2 threads
+          L2  2 MiB, 16-way, 64 byte lines, shared by 2 threads
+`
+	var b bytes.Buffer
+	show(&b, i)
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestDump(t *testing.T) {
+	var b bytes.Buffer
+	dump(&b, []cpuid.Leaf{
+		{Leaf: 0x80000000, Sub: 0, Regs: cpuid.Regs{EAX: 0x80000008}},
+		{Leaf: 4, Sub: 1, Regs: cpuid.Regs{EAX: 0x122, EBX: 0x1c0003f, ECX: 0x3f}},
+	})
+	want := `0x80000000 0x00: eax=0x80000008 ebx=0x00000000 ecx=0x00000000 edx=0x00000000
+0x00000004 0x01: eax=0x00000122 ebx=0x01c0003f ecx=0x0000003f edx=0x00000000
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
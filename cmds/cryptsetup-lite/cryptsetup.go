@@ -0,0 +1,181 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Cryptsetup-lite opens and closes LUKS volumes with dm-crypt, as
+// cryptsetup does, so that an encrypted root can be unlocked.
+//
+// Synopsis:
+//     cryptsetup-lite [-d KEYFILE] [-r] open DEVICE NAME
+//     cryptsetup-lite close NAME
+//     cryptsetup-lite dump DEVICE
+//     cryptsetup-lite isLuks DEVICE
+//
+// Description:
+//     open unlocks the LUKS1 or LUKS2 volume on DEVICE with a passphrase,
+//     and maps it to /dev/mapper/NAME. The passphrase is read from the
+//     terminal, or is the contents of KEYFILE. Key slots with PBKDF2,
+//     argon2i and argon2id are supported, of AES volumes.
+//
+//     close removes the mapping NAME.
+//
+//     dump prints the header of DEVICE, and isLuks exits with status 0 if
+//     it has one, and 1 if not.
+//
+//     luksOpen, luksClose and luksDump are open, close and dump.
+//
+// Options:
+//     -d: read the passphrase from KEYFILE
+//     -r: map the volume read only
+//
+// Example:
+//     $ cryptsetup-lite open /dev/sda2 root
+//     Enter passphrase for /dev/sda2:
+//     $ mount /dev/mapper/root /mnt
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/dmsetup"
+	"github.com/u-root/u-root/pkg/luks"
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	keyFile  = flag.String("d", "", "read the passphrase from this file")
+	readOnly = flag.Bool("r", false, "map the volume read only")
+)
+
+// passphrase reads the passphrase for dev from the terminal, without
+// echoing it, or from the key file.
+func passphrase(dev string) ([]byte, error) {
+	if *keyFile != "" {
+		return ioutil.ReadFile(*keyFile)
+	}
+	fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", dev)
+	if t, err := termios.GetTermios(os.Stdin.Fd()); err == nil {
+		noEcho := *t
+		noEcho.Lflag &^= unix.ECHO
+		if err := termios.SetTermios(os.Stdin.Fd(), &noEcho); err != nil {
+			return nil, err
+		}
+		defer func() {
+			termios.SetTermios(os.Stdin.Fd(), t)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	l, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return bytes.TrimSuffix(l, []byte("\n")), nil
+}
+
+// dmUUID is the UUID cryptsetup gives mappings, so that they can be told
+// apart from others.
+func dmUUID(h *luks.Header, name string) string {
+	return fmt.Sprintf("CRYPT-LUKS%d-%s-%s", h.Version, strings.Replace(h.UUID, "-", "", -1), name)
+}
+
+func open(dev, name string) error {
+	f, err := os.Open(dev)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h, err := luks.ReadHeader(f)
+	if err != nil {
+		return fmt.Errorf("%v: %v", dev, err)
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	pass, err := passphrase(dev)
+	if err != nil {
+		return err
+	}
+	key, _, err := h.Unlock(f, pass)
+	if err != nil {
+		return fmt.Errorf("%v: %v", dev, err)
+	}
+	t, err := h.Target(dev, size, key)
+	if err != nil {
+		return err
+	}
+	return dmsetup.Create(name, dmUUID(h, name), *readOnly, []dmsetup.Target{t})
+}
+
+func dump(w io.Writer, dev string, h *luks.Header) {
+	fmt.Fprintf(w, "LUKS header information for %s\n\n", dev)
+	fmt.Fprintf(w, "Version:     %d\n", h.Version)
+	fmt.Fprintf(w, "UUID:        %s\n", h.UUID)
+	fmt.Fprintf(w, "Cipher:      %s\n", h.Cipher)
+	fmt.Fprintf(w, "Key size:    %d bits\n", h.KeySize*8)
+	fmt.Fprintf(w, "Data offset: %d bytes\n", h.Offset)
+	fmt.Fprintf(w, "Sector size: %d\n\n", h.SectorSize)
+	fmt.Fprintf(w, "Keyslots:\n")
+	for _, k := range h.Keyslots {
+		switch k.KDF.Type {
+		case "pbkdf2":
+			fmt.Fprintf(w, "  %d: pbkdf2 %s, %d iterations, %s\n", k.ID, k.KDF.Hash, k.KDF.Iterations, k.Cipher)
+		default:
+			fmt.Fprintf(w, "  %d: %s, time %d, memory %d KiB, %d CPUs, %s\n", k.ID, k.KDF.Type, k.KDF.Time, k.KDF.Memory, k.KDF.CPUs, k.Cipher)
+		}
+	}
+}
+
+func readHeader(dev string) (*luks.Header, error) {
+	f, err := os.Open(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return luks.ReadHeader(f)
+}
+
+func run(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: cryptsetup-lite open DEVICE NAME | close NAME | dump DEVICE | isLuks DEVICE")
+	}
+	switch args[0] {
+	case "open", "luksOpen":
+		if len(args) != 3 {
+			return errors.New("usage: cryptsetup-lite open DEVICE NAME")
+		}
+		return open(args[1], args[2])
+	case "close", "luksClose", "remove":
+		return dmsetup.Remove(args[1])
+	case "dump", "luksDump":
+		h, err := readHeader(args[1])
+		if err != nil {
+			return err
+		}
+		dump(os.Stdout, args[1], h)
+		return nil
+	case "isLuks":
+		if _, err := readHeader(args[1]); err != nil {
+			os.Exit(1)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", args[0])
+}
+
+func main() {
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/luks"
+)
+
+func TestDump(t *testing.T) {
+	h := &luks.Header{
+		Version:    2,
+		UUID:       "6f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9",
+		Cipher:     "aes-xts-plain64",
+		KeySize:    64,
+		Offset:     16 << 20,
+		SectorSize: 4096,
+		Keyslots: []*luks.Keyslot{
+			{ID: 0, KDF: luks.KDF{Type: "argon2id", Time: 4, Memory: 1 << 20, CPUs: 4}, Cipher: "aes-xts-plain64"},
+			{ID: 1, KDF: luks.KDF{Type: "pbkdf2", Hash: "sha256", Iterations: 100000}, Cipher: "aes-xts-plain64"},
+		},
+	}
+	var b bytes.Buffer
+	dump(&b, "/dev/sda2", h)
+	want := `LUKS header information for /dev/sda2
+
+Version:     2
+UUID:        6f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9
+Cipher:      aes-xts-plain64
+Key size:    512 bits
+Data offset: 16777216 bytes
+Sector size: 4096
+
+Keyslots:
+  0: argon2id, time 4, memory 1048576 KiB, 4 CPUs, aes-xts-plain64
+  1: pbkdf2 sha256, 100000 iterations, aes-xts-plain64
+`
+	if b.String() != want {
+		t.Errorf("dump: got\n%s\nwant\n%s", b.String(), want)
+	}
+	if u, want := dmUUID(h, "root"), "CRYPT-LUKS2-6f1e2d3c4b5a49788695a4b3c2d1e0f9-root"; u != want {
+		t.Errorf("dmUUID: got %q, want %q", u, want)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "cryptsetup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	*keyFile = filepath.Join(tmp, "key")
+	defer func() { *keyFile = "" }()
+	// A key file is used as it is, newlines and all.
+	if err := ioutil.WriteFile(*keyFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := passphrase("/dev/sda2"); err != nil || string(p) != "secret\n" {
+		t.Errorf("passphrase: got %q, %v, want %q", p, err, "secret\n")
+	}
+	if err := run([]string{"dump", filepath.Join(tmp, "key")}); err == nil {
+		t.Errorf("dump of a file which is not LUKS: got nil, want error")
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Print parts of lines.
+//
+// Synopsis:
+//     cut -f LIST [-d DELIM] [-s] [FILE...]
+//     cut -c LIST [FILE...]
+//     cut -b LIST [FILE...]
+//
+// Description:
+//     cut prints the fields, characters or bytes LIST says of each line
+//     of the FILEs, or of stdin if there is none or FILE is -. LIST is
+//     ranges, with commas between: N, N-M, N- to the end, or -M from the
+//     start, counting from 1. What is printed is in the order of the line,
+//     each part once, whatever the order of LIST.
+//
+//     Fields are separated by DELIM, which is a tab unless -d is given,
+//     and are printed with DELIM between. A line without DELIM is printed
+//     as it is, unless -s is given.
+//
+// Options:
+//     -f: print the fields of LIST
+//     -c: print the characters of LIST
+//     -b: print the bytes of LIST
+//     -d: the delimiter of fields
+//     -s: with -f, do not print lines without the delimiter
+//
+// Example:
+//     $ cut -d : -f 1,7 /etc/passwd
+//     root:/bin/sh
+//     $ ls -l | cut -c 1-10
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	fieldList = flag.String("f", "", "print the fields of `list`")
+	charList  = flag.String("c", "", "print the characters of `list`")
+	byteList  = flag.String("b", "", "print the bytes of `list`")
+	delim     = flag.String("d", "\t", "the `delimiter` of fields")
+	onlyDelim = flag.Bool("s", false, "do not print lines without the delimiter")
+)
+
+// A span is the parts from lo to hi, from 1; hi of 0 is to the end.
+type span struct {
+	lo, hi int
+}
+
+// list is the spans of a LIST.
+type list []span
+
+// has returns whether part n, from 1, is in l.
+func (l list) has(n int) bool {
+	for _, s := range l {
+		if n >= s.lo && (s.hi == 0 || n <= s.hi) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList parses a LIST: N, N-M, N- and -M, with commas between.
+func parseList(s string) (list, error) {
+	var l list
+	for _, r := range strings.Split(s, ",") {
+		var sp span
+		lo, hi := r, r
+		if i := strings.IndexByte(r, '-'); i >= 0 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		var err error
+		if lo == "" {
+			sp.lo = 1
+		} else if sp.lo, err = strconv.Atoi(lo); err != nil || sp.lo < 1 {
+			return nil, fmt.Errorf("invalid range %q: counting is from 1", r)
+		}
+		if hi != "" {
+			if sp.hi, err = strconv.Atoi(hi); err != nil || sp.hi < sp.lo {
+				return nil, fmt.Errorf("invalid range %q", r)
+			}
+		} else if lo == "" {
+			return nil, fmt.Errorf("invalid range %q", r)
+		}
+		l = append(l, sp)
+	}
+	return l, nil
+}
+
+// A cutter cuts lines.
+type cutter struct {
+	l list
+	// mode is 'f', 'c' or 'b'.
+	mode      byte
+	delim     string
+	onlyDelim bool
+}
+
+// cut returns the parts of line, without its newline, that c prints, and
+// whether it is printed.
+func (c *cutter) cut(line string) (string, bool) {
+	switch c.mode {
+	case 'b':
+		var b []byte
+		for i := 0; i < len(line); i++ {
+			if c.l.has(i + 1) {
+				b = append(b, line[i])
+			}
+		}
+		return string(b), true
+	case 'c':
+		var b []byte
+		for i, n := 0, 1; i < len(line); n++ {
+			_, size := utf8.DecodeRuneInString(line[i:])
+			if c.l.has(n) {
+				b = append(b, line[i:i+size]...)
+			}
+			i += size
+		}
+		return string(b), true
+	}
+	if !strings.Contains(line, c.delim) {
+		return line, !c.onlyDelim
+	}
+	var out []string
+	for i, f := range strings.Split(line, c.delim) {
+		if c.l.has(i + 1) {
+			out = append(out, f)
+		}
+	}
+	return strings.Join(out, c.delim), true
+}
+
+// run cuts the lines of r to w.
+func (c *cutter) run(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if out, ok := c.cut(strings.TrimSuffix(line, "\n")); ok {
+				if _, err := io.WriteString(w, out+"\n"); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newCutter makes a cutter of the flags.
+func newCutter() (*cutter, error) {
+	c := &cutter{delim: *delim, onlyDelim: *onlyDelim}
+	var s string
+	for _, m := range []struct {
+		list string
+		mode byte
+	}{{*fieldList, 'f'}, {*charList, 'c'}, {*byteList, 'b'}} {
+		if m.list == "" {
+			continue
+		}
+		if c.mode != 0 {
+			return nil, fmt.Errorf("only one of -f, -c and -b")
+		}
+		s, c.mode = m.list, m.mode
+	}
+	if c.mode == 0 {
+		return nil, fmt.Errorf("one of -f, -c and -b is needed")
+	}
+	if c.delim == "" {
+		return nil, fmt.Errorf("the delimiter can not be empty")
+	}
+	var err error
+	c.l, err = parseList(s)
+	return c, err
+}
+
+func main() {
+	flag.Parse()
+	c, err := newCutter()
+	if err != nil {
+		log.Fatalf("cut: %v", err)
+	}
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for _, name := range files {
+		f := os.Stdin
+		if name != "-" {
+			if f, err = os.Open(name); err != nil {
+				w.Flush()
+				log.Fatalf("cut: %v", err)
+			}
+		}
+		err := c.run(w, f)
+		f.Close()
+		if err != nil {
+			w.Flush()
+			log.Fatalf("cut: %v", err)
+		}
+	}
+}
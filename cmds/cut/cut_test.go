@@ -0,0 +1,71 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want list
+	}{
+		{"1", list{{1, 1}}},
+		{"1,3", list{{1, 1}, {3, 3}}},
+		{"2-4", list{{2, 4}}},
+		{"3-", list{{3, 0}}},
+		{"-2,5-", list{{1, 2}, {5, 0}}},
+	} {
+		got, err := parseList(tt.s)
+		if err != nil {
+			t.Errorf("parseList(%q): %v", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseList(%q): got %v, want %v", tt.s, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "0", "x", "3-2", "-", "1,,2"} {
+		if got, err := parseList(s); err == nil {
+			t.Errorf("parseList(%q): got %v, want an error", s, got)
+		}
+	}
+}
+
+func TestCut(t *testing.T) {
+	const in = "root:x:0:0:root:/root:/bin/sh\nno delimiter\nαβγδ:e\n"
+	for _, tt := range []struct {
+		mode      byte
+		list      string
+		delim     string
+		onlyDelim bool
+		want      string
+	}{
+		{'f', "1,7", ":", false, "root:/bin/sh\nno delimiter\nαβγδ\n"},
+		{'f', "7,1", ":", false, "root:/bin/sh\nno delimiter\nαβγδ\n"},
+		{'f', "6-", ":", true, "/root:/bin/sh\n\n"},
+		{'f', "2", " ", false, "root:x:0:0:root:/root:/bin/sh\ndelimiter\nαβγδ:e\n"},
+		{'c', "2-3", "", false, "oo\no \nβγ\n"},
+		{'b', "-2", "", false, "ro\nno\nα\n"},
+	} {
+		l, err := parseList(tt.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &cutter{l: l, mode: tt.mode, delim: tt.delim, onlyDelim: tt.onlyDelim}
+		var b bytes.Buffer
+		if err := c.run(&b, strings.NewReader(in)); err != nil {
+			t.Errorf("-%c %s: %v", tt.mode, tt.list, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("-%c %s -d %q: got %q, want %q", tt.mode, tt.list, tt.delim, b.String(), tt.want)
+		}
+	}
+}
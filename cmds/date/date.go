@@ -2,13 +2,72 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Print the date.
+// Print the date, or set the clock.
 //
 // Synopsis:
-//     date [-u] [+format] | date [-u] [MMDDhhmm[CC]YY[.ss]]
+//     date [-u] [-d STRING] [+FORMAT]
+//     date [-u] -s STRING
+//     date [-u] MMDDhhmm[[CC]YY][.ss]
+//
+// Description:
+//     date prints the time, or the time STRING says with -d, in FORMAT.
+//     With -s, or a time in the old MMDDhhmm[[CC]YY][.ss] form, it sets
+//     the clock instead.
+//
+//     FORMAT is as for strftime, with GNU's additions:
+//         %a, %A: the short and long name of the day of the week
+//         %b, %h, %B: the short and long name of the month
+//         %c: the date and time, as %a %b %e %H:%M:%S %Y
+//         %C, %y, %Y: the century, the year in it, and the year
+//         %d, %e: the day of the month, padded with 0 or a space
+//         %D, %x: %m/%d/%y
+//         %F: %Y-%m-%d
+//         %g, %G: the year of the ISO week, without and with the century
+//         %H, %k: the hour, 00 to 23, padded with 0 or a space
+//         %I, %l: the hour, 01 to 12, padded with 0 or a space
+//         %j: the day of the year, 001 to 366
+//         %m, %M, %S: the month, minute and second
+//         %N: the nanoseconds
+//         %p, %P: AM or PM, and am or pm
+//         %r: %I:%M:%S %p
+//         %R: %H:%M
+//         %s: the seconds since 1970-01-01 00:00:00 UTC
+//         %T, %X: %H:%M:%S
+//         %u, %w: the day of the week, 1 to 7 from Monday, or 0 to 6 from
+//             Sunday
+//         %U, %W: the week of the year, from the first Sunday, or Monday
+//         %V: the ISO week of the year
+//         %z, %:z, %Z: the time zone, as -0700, -07:00, or its name
+//         %n, %t, %%: a newline, a tab and a %
+//     After the %, - does not pad numbers, _ pads them with spaces, 0
+//     pads them with 0, and ^ makes letters upper case.
+//
+//     STRING is a date, a time, or both, as in 2017-10-15 12:30:05,
+//     2017-10-15T12:30:05Z, 12:30, 10/15/2017 or Oct 15 2017, or @SECONDS
+//     since 1970; with none of them it is now. Then it may be moved by any
+//     of: N second, minute, hour, day, week, fortnight, month or year, or
+//     their plurals, N being negative, or the item followed by ago, to go
+//     back; next and last, as N of 1 and -1; today, yesterday and
+//     tomorrow.
+//
+// Options:
+//     -u: print and set the time in UTC
+//     -d: print the time STRING says, not now
+//     -s: set the clock to the time STRING says
+//
+// Example:
+//     $ date -d '2017-10-15 12:30 + 3 days' +%F
+//     2017-10-18
+//     $ date +%s
+//     1508070605
+//     $ date -d @1508070605 -u
+//     Sun Oct 15 12:30:05 UTC 2017
+//     $ date -d 'last month' '+%B %Y'
+//     September 2017
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"log"
@@ -20,35 +79,14 @@ import (
 	"time"
 )
 
-// default format map from format.go on time lib
-// Help to make format of date with posix compliant
-var fmtMap = map[string]string{
-	"%a": "Mon",
-	"%A": "Monday",
-	"%b": "Jan",
-	"%h": "Jan",
-	"%B": "January",
-	"%c": time.UnixDate,
-	"%d": "02",
-	"%e": "_2",
-	"%H": "15",
-	"%I": "03",
-	"%m": "1",
-	"%M": "04",
-	"%p": "PM",
-	"%S": "05",
-	"%y": "06",
-	"%Y": "2006",
-	"%z": "-0700",
-	"%Z": "MST",
-}
-
 var (
-	flags struct{ universal bool }
-	z     = time.Local
+	universal = flag.Bool("u", false, "Coordinated Universal Time (UTC)")
+	dateStr   = flag.String("d", "", "print the time `string` says, not now")
+	setStr    = flag.String("s", "", "set the clock to the time `string` says")
+	z         = time.Local
 )
 
-const cmd = "date [-u] [+format] | date [-u] [MMDDhhmm[CC]YY[.ss]]"
+const cmd = "date [-u] [-d string] [+format] | date [-u] -s string | date [-u] [MMDDhhmm[[CC]YY][.ss]]"
 
 func init() {
 	defUsage := flag.Usage
@@ -56,101 +94,285 @@ func init() {
 		os.Args[0] = cmd
 		defUsage()
 	}
-	flag.BoolVar(&flags.universal, "u", false, "Coordinated Universal Time (UTC)")
-	flag.Parse()
-	if flags.universal {
-		z = time.UTC
-	}
 }
 
-// regex search for +format POSIX patterns
-func formatParser(args string) []string {
-	pattern := regexp.MustCompile("%[a-zA-Z]")
-	match := pattern.FindAll([]byte(args), -1)
+// The names of the days and months, and the layouts of the conversions
+// which are as time.Format has them.
+var layouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+// The conversions which are other conversions.
+var compound = map[byte]string{
+	'c': "%a %b %e %H:%M:%S %Y",
+	'D': "%m/%d/%y",
+	'x': "%m/%d/%y",
+	'F': "%Y-%m-%d",
+	'r': "%I:%M:%S %p",
+	'R': "%H:%M",
+	'T': "%H:%M:%S",
+	'X': "%H:%M:%S",
+}
 
-	var results []string
-	for _, m := range match {
-		results = append(results, string(m[:]))
+// number returns the number conversion c of t, how wide it is, and what it
+// is padded with; ok is false if c is not one.
+func number(t time.Time, c byte) (n int64, width int, pad byte, ok bool) {
+	hour12 := t.Hour() % 12
+	if hour12 == 0 {
+		hour12 = 12
 	}
+	isoYear, isoWeek := t.ISOWeek()
+	yday, wday := t.YearDay()-1, int(t.Weekday())
+	v, width, pad := 0, 2, byte('0')
+	switch c {
+	case 'C':
+		v = t.Year() / 100
+	case 'd':
+		v = t.Day()
+	case 'e':
+		v, pad = t.Day(), ' '
+	case 'g':
+		v = isoYear % 100
+	case 'G':
+		v, width = isoYear, 4
+	case 'H':
+		v = t.Hour()
+	case 'I':
+		v = hour12
+	case 'j':
+		v, width = yday+1, 3
+	case 'k':
+		v, pad = t.Hour(), ' '
+	case 'l':
+		v, pad = hour12, ' '
+	case 'm':
+		v = int(t.Month())
+	case 'M':
+		v = t.Minute()
+	case 'N':
+		v, width = t.Nanosecond(), 9
+	case 's':
+		return t.Unix(), 1, '0', true
+	case 'S':
+		v = t.Second()
+	case 'u':
+		v, width = (wday+6)%7+1, 1
+	case 'U':
+		v = (yday + 7 - wday) / 7
+	case 'V':
+		v = isoWeek
+	case 'w':
+		v, width = wday, 1
+	case 'W':
+		v = (yday + 7 - (wday+6)%7) / 7
+	case 'y':
+		v = t.Year() % 100
+	case 'Y':
+		v, width = t.Year(), 4
+	default:
+		return 0, 0, 0, false
+	}
+	return int64(v), width, pad, true
+}
 
-	return results
+// strftime formats t as format says, as strftime(3) does.
+func strftime(t time.Time, format string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+		var pad byte
+		upper := false
+		for ; i+1 < len(format) && strings.IndexByte("-_0^", format[i]) >= 0; i++ {
+			if format[i] == '^' {
+				upper = true
+			} else {
+				pad = format[i]
+			}
+		}
+		c := format[i]
+		var s string
+		if n, width, p, ok := number(t, c); ok {
+			switch pad {
+			case '-':
+				width = 0
+			case '_':
+				p = ' '
+			case '0':
+				p = '0'
+			}
+			s = strconv.FormatInt(n, 10)
+			if len(s) < width {
+				s = strings.Repeat(string(p), width-len(s)) + s
+			}
+		} else if l, ok := layouts[c]; ok {
+			s = t.Format(l)
+		} else if f, ok := compound[c]; ok {
+			s = strftime(t, f)
+		} else {
+			switch {
+			case c == 'p':
+				s = t.Format("PM")
+			case c == 'P':
+				s = strings.ToLower(t.Format("PM"))
+			case c == 'n':
+				s = "\n"
+			case c == 't':
+				s = "\t"
+			case c == '%':
+				s = "%"
+			case c == ':' && i+1 < len(format) && format[i+1] == 'z':
+				i++
+				s = t.Format("-07:00")
+			default:
+				// Conversions which are not known are left as they are.
+				s = format[start : i+1]
+			}
+		}
+		if upper {
+			s = strings.ToUpper(s)
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// The layouts of the dates and times -d and -s know. Those without a date
+// are today; those without a time are at midnight.
+var parseLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"01/02/2006 15:04:05",
+	"01/02/2006",
+	time.UnixDate,
+	time.RubyDate,
+	time.ANSIC,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	"15:04:05.999999999",
+	"15:04",
 }
 
-// replace map for the format patterns according POSIX and GNU implementations
-func dateMap(format string) string {
-	d := time.Now().In(z)
-	var toReplace string
-	for _, match := range formatParser(format) {
-		translate, exists := fmtMap[match]
+var (
+	dayWords = regexp.MustCompile(`(?i)\b(now|today|yesterday|tomorrow)\b`)
+	relative = regexp.MustCompile(`(?i)(?:([+-]?)\s*(\d+)|\b(next|last))?\s*\b(second|sec|minute|min|hour|day|week|fortnight|month|year)s?\b(\s+ago\b)?`)
+)
+
+// parse parses s, as -d and -s take it, as a time in now's location.
+func parse(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "@") {
+		f, err := strconv.ParseFloat(s[1:], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).In(now.Location()), nil
+	}
+
+	days := 0
+	s = dayWords.ReplaceAllStringFunc(s, func(w string) string {
+		switch strings.ToLower(w) {
+		case "yesterday":
+			days--
+		case "tomorrow":
+			days++
+		}
+		return ""
+	})
+	type move struct {
+		n    int
+		unit string
+	}
+	var moves []move
+	var bad error
+	s = relative.ReplaceAllStringFunc(s, func(w string) string {
+		m := relative.FindStringSubmatch(w)
+		n := 1
 		switch {
-		case exists:
-			// Values defined by fmtMap
-			toReplace = d.Format(translate)
-		case match == "%C":
-			// Century (a year divided by 100 and truncated to an integer)
-			// as a decimal number [00,99].
-			toReplace = strconv.Itoa(d.Year() / 100)
-		case match == "%D":
-			// Date in the format mm/dd/yy.
-			toReplace = dateMap("%m/%d/%y")
-		case match == "%j":
-			// Day of the year as a decimal number [001,366]."
-			year, weekYear := d.ISOWeek()
-			firstWeekDay := time.Date(year, 1, 1, 1, 1, 1, 1, time.UTC).Weekday()
-			weekDay := d.Weekday()
-			dayYear := int(weekYear)*7 - (int(firstWeekDay) - 1) + int(weekDay)
-			toReplace = strconv.Itoa(dayYear)
-		case match == "%n":
-			// A <newline>.
-			toReplace = "\n"
-		case match == "%r":
-			// 12-hour clock time [01,12] using the AM/PM notation;
-			// in the POSIX locale, this shall be equivalent to %I : %M : %S %p.
-			toReplace = dateMap("%I:%M:%S %p")
-		case match == "%t":
-			// A <tab>.
-			toReplace = "\t"
-		case match == "%T":
-			toReplace = dateMap("%H:%M:%S")
-		case match == "%W":
-			// Week of the year (Sunday as the first day of the week)
-			// as a decimal number [00,53]. All days in a new year preceding
-			// the first Sunday shall be considered to be in week 0.
-			_, weekYear := d.ISOWeek()
-			weekDay := int(d.Weekday())
-			isNotSunday := 1
-			if weekDay == 0 {
-				isNotSunday = 0
+		case m[2] != "":
+			var err error
+			if n, err = strconv.Atoi(m[2]); err != nil {
+				bad = err
 			}
-			toReplace = strconv.Itoa(weekYear - (isNotSunday))
-		case match == "%w":
-			// Weekday as a decimal number [0,6] (0=Sunday).
-			toReplace = strconv.Itoa(int(d.Weekday()))
-		case match == "%V":
-			// Week of the year (Monday as the first day of the week)
-			// as a decimal number [01,53]. If the week containing January 1
-			// has four or more days in the new year, then it shall be
-			// considered week 1; otherwise, it shall be the last week
-			// of the previous year, and the next week shall be week 1.
-			_, weekYear := d.ISOWeek()
-			toReplace = strconv.Itoa(int(weekYear))
-		case match == "%x":
-			// Locale's appropriate date representation.
-			toReplace = dateMap("%m/%d/%y") // TODO: decision algorithm
-		case match == "%F":
-			// Date yyyy-mm-dd defined by GNU implementation
-			toReplace = dateMap("%Y-%m-%d")
-		case match == "%X":
-			// Locale's appropriate time representation.
-			toReplace = dateMap("%I:%M:%S %p") // TODO: decision algorithm
-		default:
-			continue
+			if m[1] == "-" {
+				n = -n
+			}
+		case strings.ToLower(m[3]) == "last":
+			n = -1
+		}
+		if m[5] != "" {
+			n = -n
 		}
+		moves = append(moves, move{n, strings.ToLower(m[4])})
+		return " "
+	})
+	if bad != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %v", s, bad)
+	}
 
-		format = strings.Replace(format, match, toReplace, 1)
-		// fmt.Printf("Iteration[%d]: %v\n", iter, format)
+	t := now
+	if s = strings.Join(strings.Fields(s), " "); s != "" {
+		var err error
+		for _, l := range parseLayouts {
+			if t, err = time.ParseInLocation(l, s, now.Location()); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		if t.Year() == 0 {
+			y, m, d := now.Date()
+			t = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		}
+	}
+
+	// Days and longer are of the calendar, so as not to be off by an
+	// hour across a change to or from summer time.
+	t = t.AddDate(0, 0, days)
+	for _, m := range moves {
+		switch m.unit {
+		case "second", "sec":
+			t = t.Add(time.Duration(m.n) * time.Second)
+		case "minute", "min":
+			t = t.Add(time.Duration(m.n) * time.Minute)
+		case "hour":
+			t = t.Add(time.Duration(m.n) * time.Hour)
+		case "day":
+			t = t.AddDate(0, 0, m.n)
+		case "week":
+			t = t.AddDate(0, 0, 7*m.n)
+		case "fortnight":
+			t = t.AddDate(0, 0, 14*m.n)
+		case "month":
+			t = t.AddDate(0, m.n, 0)
+		case "year":
+			t = t.AddDate(m.n, 0, 0)
+		}
 	}
-	return format
+	return t, nil
 }
 
 func ints(s string, i ...*int) error {
@@ -212,25 +434,55 @@ func date(z *time.Location) string {
 	return time.Now().In(z).Format(time.UnixDate)
 }
 
+// setClock sets the clock to t.
+func setClock(t time.Time) error {
+	tv := syscall.NsecToTimeval(t.UnixNano())
+	return syscall.Settimeofday(&tv)
+}
+
 func main() {
-	switch len(flag.Args()) {
-	case 0:
+	flag.Parse()
+	if *universal {
+		z = time.UTC
+	}
+	args := flag.Args()
+	format := ""
+	if len(args) > 0 && strings.HasPrefix(args[0], "+") {
+		format, args = args[0][1:], args[1:]
+	}
+	switch {
+	case len(args) > 1, len(args) == 1 && (*dateStr != "" || *setStr != ""), *dateStr != "" && *setStr != "":
+		flag.Usage()
+		os.Exit(1)
+	case *setStr != "":
+		t, err := parse(*setStr, time.Now().In(z))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := setClock(t); err != nil {
+			log.Fatalf("%v: %v", *setStr, err)
+		}
 		fmt.Printf("%v\n", date(z))
-	case 1:
-		argv0 := flag.Args()[0]
-		if argv0[0] == '+' {
-			fmt.Printf("%v\n", dateMap(argv0[1:]))
-		} else {
-			t, err := getTime(argv0)
-			if err != nil {
-				log.Fatalf("%v: %v", argv0, err)
-			}
-			tv := syscall.NsecToTimeval(t.UnixNano())
-			if err := syscall.Settimeofday(&tv); err != nil {
-				log.Fatalf("%v: %v", argv0, err)
-			}
+	case len(args) == 1:
+		t, err := getTime(args[0])
+		if err != nil {
+			log.Fatalf("%v: %v", args[0], err)
+		}
+		if err := setClock(t); err != nil {
+			log.Fatalf("%v: %v", args[0], err)
 		}
+	case *dateStr != "":
+		t, err := parse(*dateStr, time.Now().In(z))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if format == "" {
+			format = "%a %b %e %H:%M:%S %Z %Y"
+		}
+		fmt.Println(strftime(t, format))
+	case format != "":
+		fmt.Println(strftime(time.Now().In(z), format))
 	default:
-		flag.Usage()
+		fmt.Printf("%v\n", date(z))
 	}
 }
@@ -42,54 +42,83 @@ func TestDateUniversal(t *testing.T) {
 	}
 }
 
-func TestFormatParser(t *testing.T) {
-	test := "%d %w %x %D %% asdf qwer qwe s sd fqwer % qwer"
-	expected := []string{"%d", "%w", "%x", "%D"}
-	t.Log(":: Test of FormatParser greping the n flags")
-	for index, match := range formatParser(test) {
-		t.Logf(":: Parsed on iteration %d: %v", index, match)
-		if match != expected[index] {
-			t.Errorf("Parsing Error; Want %v, got %v\n", expected[index], match)
-		}
-	}
-}
-
 func TestDateMap(t *testing.T) {
-	t.Log(":: Test of DateMap formatting")
+	t.Log(":: Test of strftime formatting")
 	posixFormat := "%a %b %e %H:%M:%S %Z %Y"
-	test := dateMap(posixFormat)
-	expected := time.Now().Format(time.UnixDate)
+	now := time.Now()
+	test := strftime(now, posixFormat)
+	expected := now.Format(time.UnixDate)
 
 	if test != expected {
 		t.Errorf("Mismatch outputs; \nwant %v, \n got %v", expected, test)
 	}
 }
 
-func TestDateMapExamples(t *testing.T) {
-	type dateTest struct {
-		format  string // format flags
-		example string // correct example
+func TestStrftime(t *testing.T) {
+	pdt := time.FixedZone("PDT", -7*3600)
+	for _, tt := range []struct {
+		t      time.Time
+		format string
+		want   string
+	}{
+		{time.Date(1990, time.June, 26, 9, 58, 10, 0, pdt), "%a %b %e %H:%M:%S %Z %Y", "Tue Jun 26 09:58:10 PDT 1990"},
+		{time.Date(1991, time.November, 2, 13, 36, 16, 0, time.UTC), "DATE: %m/%d/%y%nTIME: %H:%M:%S", "DATE: 11/02/91\nTIME: 13:36:16"},
+		{time.Date(1991, time.November, 2, 13, 36, 32, 0, time.UTC), "TIME: %r", "TIME: 01:36:32 PM"},
+		{time.Date(2017, time.January, 1, 0, 5, 0, 0, time.UTC), "%A %B %d %j %u %w %U %W %V %G %g", "Sunday January 01 001 7 0 01 00 52 2016 16"},
+		{time.Date(2017, time.October, 9, 0, 5, 0, 0, time.UTC), "%j %u %w %U %W %V", "282 1 1 41 41 41"},
+		{time.Date(2017, time.October, 5, 7, 8, 9, 12345, pdt), "%e|%-d|%_H|%0e|%k|%l|%I|%P|%N", " 5|5| 7|05| 7| 7|07|am|000012345"},
+		{time.Date(2017, time.October, 5, 7, 8, 9, 0, pdt), "%F %T %z %:z %^a %^B", "2017-10-05 07:08:09 -0700 -07:00 THU OCTOBER"},
+		{time.Date(2017, time.October, 5, 7, 8, 9, 0, pdt), "%D %x %X %R %C %h", "10/05/17 10/05/17 07:08:09 07:08 20 Oct"},
+		{time.Date(2017, time.October, 15, 12, 30, 5, 0, time.UTC), "%s %c", "1508070605 Sun Oct 15 12:30:05 2017"},
+		{time.Date(2017, time.October, 15, 12, 30, 5, 0, time.UTC), "100%% %t%q %", "100% \t%q %"},
+	} {
+		if got := strftime(tt.t, tt.format); got != tt.want {
+			t.Errorf("strftime(%v, %q): got %q, want %q", tt.t, tt.format, got, tt.want)
+		}
 	}
+}
 
-	var tests = []dateTest{
-		{
-			"%a %b %e %H:%M:%S %Z %Y",
-			"Tue Jun 26 09:58:10 PDT 1990",
-		},
-		{
-			"DATE: %m/%d/%y%nTIME: %H:%M:%S",
-			"DATE: 11/02/91\nTIME: 13:36:16",
-		},
-		{
-			"TIME: %r",
-			"TIME: 01:36:32 PM",
-		},
+func TestParse(t *testing.T) {
+	now := time.Date(2017, time.October, 15, 12, 30, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"", now},
+		{"now", now},
+		{"@1508070605", now},
+		{"2017-10-15 12:30:05", now},
+		{"2017-10-15T12:30:05Z", now},
+		{"2017-01-02", time.Date(2017, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"01/02/2017 03:04:05", time.Date(2017, time.January, 2, 3, 4, 5, 0, time.UTC)},
+		{"Jan 2 2017", time.Date(2017, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"Sun Oct 15 12:30:05 UTC 2017", now},
+		{"08:15", time.Date(2017, time.October, 15, 8, 15, 0, 0, time.UTC)},
+		{"tomorrow", now.AddDate(0, 0, 1)},
+		{"yesterday 08:15", time.Date(2017, time.October, 14, 8, 15, 0, 0, time.UTC)},
+		{"2 days ago", now.AddDate(0, 0, -2)},
+		{"+3 hours", now.Add(3 * time.Hour)},
+		{"-90 min", now.Add(-90 * time.Minute)},
+		{"next week", now.AddDate(0, 0, 7)},
+		{"last month", now.AddDate(0, -1, 0)},
+		// As for GNU date, ago is only of the item it follows.
+		{"1 year 2 months ago", now.AddDate(1, -2, 0)},
+		{"2017-10-15 12:30 + 3 days", time.Date(2017, time.October, 18, 12, 30, 0, 0, time.UTC)},
+		{"2017-01-31 1 month", time.Date(2017, time.March, 3, 0, 0, 0, 0, time.UTC)},
+		{"1 fortnight 10 seconds", now.AddDate(0, 0, 14).Add(10 * time.Second)},
+	} {
+		got, err := parse(tt.s, now)
+		if err != nil {
+			t.Errorf("parse(%q): %v", tt.s, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parse(%q): got %v, want %v", tt.s, got, tt.want)
+		}
 	}
-
-	t.Log(":: Sequence of examples for dateMap")
-	for _, test := range tests {
-		t.Logf(" Format: \n%v\n", test.format)
-		t.Logf("Example: \n%v\n", test.example)
-		t.Logf(" Output: \n%v\n", dateMap(test.format))
+	for _, s := range []string{"@x", "soon", "2017-13-01", "3 parsecs"} {
+		if got, err := parse(s, now); err == nil {
+			t.Errorf("parse(%q): got %v, want an error", s, got)
+		}
 	}
 }
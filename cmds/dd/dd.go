@@ -8,7 +8,8 @@
 //     dd [OPTIONS...] [-inName FILE] [-outName FILE]
 //
 // Description:
-//     dd is modeled after dd(1).
+//     dd is modeled after dd(1). Numbers may end in c (1), w (2), b (512),
+//     kB (1000), K (1024), MB, M, GB or G.
 //
 // Options:
 //     -ibs n:   input block size (default=1)
@@ -16,14 +17,30 @@
 //     -bs n:    input and output block size (default=0)
 //     -skip n:  skip n ibs-sized input blocks before reading (default=0)
 //     -seek n:  seek n obs-sized output blocks before writing (default=0)
-//     -conv s:  Convert the file on a specific way, like notrunc
+//     -conv s:  comma separated list of conversions:
+//         lcase:    convert to lower case
+//         ucase:    convert to upper case
+//         notrunc:  do not truncate the output file
+//         sync:     pad short input blocks with NULs to ibs
+//         fsync:    flush the output file to disk at the end
+//         fdatasync: flush the output file's data to disk at the end
 //     -count n: copy only n ibs-sized input blocks
-//     -inName:  defaults to stdin
-//     -outName: defaults to stdout
+//     -iflag s: comma separated list of input flags:
+//         direct:   use O_DIRECT
+//     -oflag s: comma separated list of output flags:
+//         direct:   use O_DIRECT
+//         sync:     use O_SYNC
+//         dsync:    use O_DSYNC
+//     -if:      defaults to stdin
+//     -of:      defaults to stdout
 //     -status:  print transfer stats to stderr, can be one of:
 //         none:     do not display
 //         xfer:     print on completion (default)
 //         progress: print throughout transfer (GNU)
+//
+//     With O_DIRECT, block sizes and offsets should be multiples of the
+//     device's block size. A last short block is written without O_DIRECT.
+//     SIGUSR1 makes dd print the transfer stats so far.
 package main
 
 import (
@@ -35,20 +52,28 @@ import (
 	"log"
 	"math"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
 )
 
 var (
-	ibs     = flag.Int64("ibs", 512, "Default input block size")
-	obs     = flag.Int64("obs", 512, "Default output block size")
-	bs      = flag.Int64("bs", 0, "Default input and output block size")
-	skip    = flag.Int64("skip", 0, "skip N ibs-sized blocks before reading")
-	seek    = flag.Int64("seek", 0, "seek N obs-sized blocks before writing")
-	conv    = flag.String("conv", "none", "Convert the file on a specific way, like notrunc")
-	count   = flag.Int64("count", math.MaxInt64, "copy only N input blocks")
+	ibs     = sizeFlag("ibs", 512, "Default input block size")
+	obs     = sizeFlag("obs", 512, "Default output block size")
+	bs      = sizeFlag("bs", 0, "Default input and output block size")
+	skip    = sizeFlag("skip", 0, "skip N ibs-sized blocks before reading")
+	seek    = sizeFlag("seek", 0, "seek N obs-sized blocks before writing")
+	conv    = flag.String("conv", "none", "comma separated list of conversions: lcase, ucase, notrunc, sync, fsync, fdatasync")
+	iflag   = flag.String("iflag", "", "comma separated list of input flags: direct")
+	oflag   = flag.String("oflag", "", "comma separated list of output flags: direct, sync, dsync")
+	count   = sizeFlag("count", math.MaxInt64, "copy only N input blocks")
 	inName  = flag.String("if", "", "Input file")
 	outName = flag.String("of", "", "Output file")
 	status  = flag.String("status", "xfer", "display status of transfer (none|xfer|progress)")
@@ -56,28 +81,92 @@ var (
 	bytesWritten int64 // access atomically, must be global for correct alignedness
 )
 
+// sizeSuffixes are the multipliers of the suffixes numbers may have.
+var sizeSuffixes = []struct {
+	suffix string
+	mult   int64
+}{
+	{"kB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"c", 1},
+	{"w", 2},
+	{"b", 512},
+	{"K", 1 << 10},
+	{"k", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+}
+
+// sizeValue is a number, like 4k or 1M.
+type sizeValue int64
+
+func (s *sizeValue) String() string {
+	return fmt.Sprint(int64(*s))
+}
+
+func (s *sizeValue) Set(v string) error {
+	mult := int64(1)
+	for _, u := range sizeSuffixes {
+		if strings.HasSuffix(v, u.suffix) {
+			v, mult = strings.TrimSuffix(v, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(v, 0, 64)
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > math.MaxInt64/mult {
+		return fmt.Errorf("%s out of range", v)
+	}
+	*s = sizeValue(n * mult)
+	return nil
+}
+
+func sizeFlag(name string, value int64, usage string) *int64 {
+	p := new(int64)
+	*p = value
+	flag.Var((*sizeValue)(p), name, usage+"; may end in c, w, b, kB, K, MB, M, GB or G")
+	return p
+}
+
 // intermediateBuffer is a buffer that one can write to and read from.
 type intermediateBuffer interface {
 	io.ReaderFrom
 	io.WriterTo
 }
 
+// directAlign is the alignment of buffers, which O_DIRECT needs.
+const directAlign = 4096
+
+// alignedBuffer returns a buffer of n bytes starting at a multiple of
+// directAlign.
+func alignedBuffer(n int64) []byte {
+	b := make([]byte, n+directAlign)
+	off := (directAlign - int64(uintptr(unsafe.Pointer(&b[0]))%directAlign)) % directAlign
+	return b[off : off+n]
+}
+
 // chunkedBuffer is an intermediateBuffer with a specific size.
 type chunkedBuffer struct {
 	outChunk  int64
 	length    int64
 	data      []byte
 	transform func([]byte) []byte
+	// pad pads short chunks with NULs to the full size.
+	pad bool
 }
 
 // newChunkedBuffer returns an intermediateBuffer that stores inChunkSize-sized
 // chunks of data and writes them to writers in outChunkSize-sized chunks.
-func newChunkedBuffer(inChunkSize int64, outChunkSize int64, transform func([]byte) []byte) intermediateBuffer {
+func newChunkedBuffer(inChunkSize int64, outChunkSize int64, transform func([]byte) []byte, pad bool) intermediateBuffer {
 	return &chunkedBuffer{
 		outChunk:  outChunkSize,
 		length:    0,
-		data:      make([]byte, inChunkSize),
+		data:      alignedBuffer(inChunkSize),
 		transform: transform,
+		pad:       pad,
 	}
 }
 
@@ -90,6 +179,12 @@ func (cb *chunkedBuffer) ReadFrom(r io.Reader) (int64, error) {
 	if n == 0 && err == nil {
 		return 0, io.EOF
 	}
+	if n > 0 && cb.pad {
+		for i := n; i < len(cb.data); i++ {
+			cb.data[i] = 0
+		}
+		cb.length = int64(len(cb.data))
+	}
 	return int64(n), err
 }
 
@@ -154,7 +249,7 @@ func (bp bufferPool) Destroy() {
 	close(bp.c)
 }
 
-func parallelChunkedCopy(r io.Reader, w io.Writer, inBufSize, outBufSize int64, transform func([]byte) []byte) error {
+func parallelChunkedCopy(r io.Reader, w io.Writer, inBufSize, outBufSize int64, transform func([]byte) []byte, pad bool) error {
 	// Make the channels deep enough to hold a total of 1GiB of data.
 	depth := (1024 * 1024 * 1024) / inBufSize
 	// But keep it reasonable!
@@ -164,7 +259,7 @@ func parallelChunkedCopy(r io.Reader, w io.Writer, inBufSize, outBufSize int64,
 
 	readyBufs := make(chan intermediateBuffer, depth)
 	pool := newBufferPool(depth, func() intermediateBuffer {
-		return newChunkedBuffer(inBufSize, outBufSize, transform)
+		return newChunkedBuffer(inBufSize, outBufSize, transform, pad)
 	})
 	defer pool.Destroy()
 
@@ -280,8 +375,45 @@ func (s *sectionReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// directWriter writes to a file opened with O_DIRECT. Writes which are not
+// a multiple of the block size, which O_DIRECT can not do, turn it off.
+type directWriter struct {
+	*os.File
+}
+
+func (w directWriter) Write(b []byte) (int, error) {
+	if len(b)%512 != 0 {
+		fd := w.Fd()
+		fl, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+		if errno == 0 {
+			_, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFL, fl&^unix.O_DIRECT)
+		}
+		if errno != 0 {
+			return 0, errno
+		}
+	}
+	return w.File.Write(b)
+}
+
+// openFlags returns the flags to open a file with for the comma separated
+// list of flags s. allowed are the ones which may be in s.
+func openFlags(s string, allowed map[string]int) (int, error) {
+	var fl int
+	for _, f := range strings.Split(s, ",") {
+		if f == "" {
+			continue
+		}
+		v, ok := allowed[f]
+		if !ok {
+			return 0, fmt.Errorf("unknown flag %q", f)
+		}
+		fl |= v
+	}
+	return fl, nil
+}
+
 // inFile opens the input file and seeks to the right position.
-func inFile(name string, inputBytes int64, skip int64, count int64) (io.Reader, error) {
+func inFile(name string, inputBytes int64, skip int64, count int64, flags int) (io.Reader, error) {
 	maxRead := int64(math.MaxInt64)
 	if count != math.MaxInt64 {
 		maxRead = count * inputBytes
@@ -293,23 +425,27 @@ func inFile(name string, inputBytes int64, skip int64, count int64) (io.Reader,
 		return newStreamSectionReader(os.Stdin, inputBytes*skip, maxRead), nil
 	}
 
-	in, err := os.Open(name)
+	in, err := os.OpenFile(name, os.O_RDONLY|flags, 0)
 	if err != nil {
 		return nil, fmt.Errorf("error opening input file %q: %v", name, err)
 	}
 	return io.NewSectionReader(in, inputBytes*skip, maxRead), nil
 }
 
-// outFile opens the output file and seeks to the right position.
-func outFile(name string, outputBytes int64, seek int64) (io.Writer, error) {
-	var out io.WriteSeeker
-	var err error
-	if name == "" {
-		out = os.Stdout
-	} else {
-		if out, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666); err != nil {
+// outFile opens the output file and seeks to the right position. Unless
+// notrunc, a regular file is truncated there.
+func outFile(name string, outputBytes int64, seek int64, flags int, notrunc bool) (*os.File, error) {
+	out := os.Stdout
+	if name != "" {
+		var err error
+		if out, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|flags, 0666); err != nil {
 			return nil, fmt.Errorf("error opening output file %q: %v", name, err)
 		}
+		if fi, err := out.Stat(); err == nil && fi.Mode().IsRegular() && !notrunc {
+			if err := out.Truncate(seek * outputBytes); err != nil {
+				return nil, fmt.Errorf("error truncating output file: %v", err)
+			}
+		}
 	}
 	if seek*outputBytes != 0 {
 		if _, err := out.Seek(seek*outputBytes, io.SeekCurrent); err != nil {
@@ -324,6 +460,7 @@ type progressData struct {
 	start    time.Time
 	variable *int64 // must be aligned for atomic operations
 	quit     chan struct{}
+	done     chan struct{}
 }
 
 func progressBegin(mode string, variable *int64) (ProgressData *progressData) {
@@ -331,33 +468,44 @@ func progressBegin(mode string, variable *int64) (ProgressData *progressData) {
 		mode:     mode,
 		start:    time.Now(),
 		variable: variable,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	if p.mode == "progress" {
 		p.print()
-		// Print progress in a separate goroutine.
-		p.quit = make(chan struct{}, 1)
-		go func() {
+	}
+	// Print progress in a separate goroutine, every second with
+	// status=progress, and whenever we get SIGUSR1.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+	go func() {
+		defer close(p.done)
+		defer signal.Stop(sigs)
+		var tick <-chan time.Time
+		if p.mode == "progress" {
 			ticker := time.NewTicker(1 * time.Second)
 			defer ticker.Stop()
-			for {
-				select {
-				case <-ticker.C:
-					p.print()
-				case <-p.quit:
-					return
-				}
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-tick:
+				p.print()
+			case <-sigs:
+				p.print()
+				fmt.Fprint(os.Stderr, "\n")
+			case <-p.quit:
+				return
 			}
-		}()
-	}
+		}
+	}()
 	return p
 }
 
 func (p *progressData) end() {
-	if p.mode == "progress" {
-		// Properly synchronize goroutine.
-		p.quit <- struct{}{}
-		p.quit <- struct{}{}
-	}
+	// Properly synchronize goroutine.
+	close(p.quit)
+	<-p.done
 	if p.mode == "progress" || p.mode == "xfer" {
 		// Print grand total.
 		p.print()
@@ -386,7 +534,7 @@ func (p *progressData) print() {
 func usage() {
 	// If the conversions get more complex we can dump
 	// the convs map. For now, it's not really worth it.
-	log.Fatal(`Usage: dd [if=file] [of=file] [conv=lcase|ucase] [seek=#] [skip=#] [count=#] [bs=#] [ibs=#] [obs=#] [status=none|xfer|progress]
+	log.Fatal(`Usage: dd [if=file] [of=file] [conv=lcase|ucase|notrunc|sync|fsync|fdatasync,...] [iflag=direct] [oflag=direct|sync|dsync,...] [seek=#] [skip=#] [count=#] [bs=#] [ibs=#] [obs=#] [status=none|xfer|progress]
 		options may also be invoked Go-style as -opt value or -opt=value
 		bs, if specified, overrides ibs and obs`)
 }
@@ -420,13 +568,41 @@ func main() {
 	}
 
 	convs := map[string]func([]byte) []byte{
-		"none":  func(b []byte) []byte { return b },
 		"ucase": bytes.ToUpper,
 		"lcase": bytes.ToLower,
 	}
-	convert, ok := convs[*conv]
-	if !ok {
-		usage()
+	convert := func(b []byte) []byte { return b }
+	var notrunc, pad, fsync, fdatasync bool
+	for _, c := range strings.Split(*conv, ",") {
+		switch c {
+		case "none":
+		case "notrunc":
+			notrunc = true
+		case "sync":
+			pad = true
+		case "fsync":
+			fsync = true
+		case "fdatasync":
+			fdatasync = true
+		default:
+			f, ok := convs[c]
+			if !ok {
+				usage()
+			}
+			convert = f
+		}
+	}
+	inFlags, err := openFlags(*iflag, map[string]int{"direct": unix.O_DIRECT})
+	if err != nil {
+		log.Fatalf("iflag: %v", err)
+	}
+	outFlags, err := openFlags(*oflag, map[string]int{
+		"direct": unix.O_DIRECT,
+		"sync":   unix.O_SYNC,
+		"dsync":  unix.O_DSYNC,
+	})
+	if err != nil {
+		log.Fatalf("oflag: %v", err)
 	}
 
 	if *status != "none" && *status != "xfer" && *status != "progress" {
@@ -440,17 +616,30 @@ func main() {
 		*obs = *bs
 	}
 
-	in, err := inFile(*inName, *ibs, *skip, *count)
+	in, err := inFile(*inName, *ibs, *skip, *count, inFlags)
 	if err != nil {
 		log.Fatal(err)
 	}
-	out, err := outFile(*outName, *obs, *seek)
+	out, err := outFile(*outName, *obs, *seek, outFlags, notrunc)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := parallelChunkedCopy(in, out, *ibs, *obs, convert); err != nil {
+	var w io.Writer = out
+	if outFlags&unix.O_DIRECT != 0 {
+		w = directWriter{out}
+	}
+	if err := parallelChunkedCopy(in, w, *ibs, *obs, convert, pad); err != nil {
 		log.Fatal(err)
 	}
+	switch {
+	case fsync:
+		err = out.Sync()
+	case fdatasync:
+		err = unix.Fdatasync(int(out.Fd()))
+	}
+	if err != nil {
+		log.Fatalf("error syncing output file: %v", err)
+	}
 
 	progress.end()
 }
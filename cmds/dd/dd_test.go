@@ -6,8 +6,10 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -44,6 +46,16 @@ var tests = []struct {
 		flags:  []string{"bs=8"},
 		stdin:  "hello world.....", // len=16 is a multiple of 8
 		stdout: "hello world.....",
+	}, {
+		// Pad short blocks.
+		flags:  []string{"bs=8", "conv=sync"},
+		stdin:  "hello",
+		stdout: "hello\x00\x00\x00",
+	}, {
+		// Several conversions.
+		flags:  []string{"bs=8", "conv=ucase,sync"},
+		stdin:  "hello",
+		stdout: "HELLO\x00\x00\x00",
 	}, {
 		// Create a 64KiB zeroed file in 1KiB blocks
 		flags:  []string{"if=/dev/zero", "bs=1024", "count=64"},
@@ -59,6 +71,11 @@ var tests = []struct {
 		flags:  []string{"if=/dev/zero", "bs=65536", "count=1"},
 		stdin:  "",
 		stdout: strings.Repeat("\x00", 64*1024),
+	}, {
+		// Sizes with suffixes.
+		flags:  []string{"if=/dev/zero", "bs=64K", "count=1"},
+		stdin:  "",
+		stdout: strings.Repeat("\x00", 64*1024),
 	}, {
 		// Use skip and count.
 		flags:  []string{"skip=6", "bs=1", "count=5"},
@@ -95,6 +112,40 @@ func TestDd(t *testing.T) {
 	}
 }
 
+func TestOutputFile(t *testing.T) {
+	tmpDir, execPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	out := filepath.Join(tmpDir, "out")
+	for _, tt := range []struct {
+		flags []string
+		want  string
+	}{
+		// seek truncates the file after the blocks seeked over.
+		{[]string{"bs=2", "seek=1"}, "01ab"},
+		{[]string{"bs=2", "seek=1", "conv=notrunc"}, "01ab456789"},
+		{[]string{"bs=2", "seek=1", "conv=notrunc,fsync"}, "01ab456789"},
+		{[]string{"bs=2", "conv=fdatasync"}, "ab"},
+	} {
+		if err := ioutil.WriteFile(out, []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(execPath, append(tt.flags, "of="+out, "status=none")...)
+		cmd.Stdin = strings.NewReader("ab")
+		if o, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("dd %v: %v: %s", tt.flags, err, o)
+			continue
+		}
+		b, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("dd %v: got %q, want %q", tt.flags, b, tt.want)
+		}
+	}
+}
+
 // BenchmarkDd benchmarks the dd command. Each "op" unit is a 1MiB block.
 func BenchmarkDd(b *testing.B) {
 	tmpDir, execPath := testutil.CompileInTempDir(b)
@@ -7,9 +7,20 @@
 // Synopsis:
 //     dhclient [OPTIONS...]
 //
+// Description:
+//     dhclient configures the addresses, default route, DNS servers and
+//     search domain, and host name of the interfaces matching a regular
+//     expression, by default ^e.*, with DHCPv4 and DHCPv6. DHCPv4 leases
+//     are kept in the lease directory, and renewed the next time rather
+//     than asked for again.
+//
 // Options:
 //     -timeout:  lease timeout in seconds
+//     -retry:    number of attempts before giving up; -1 means forever
 //     -renewals: number of DHCP renewals before exiting
+//     -ipv4:     use DHCPv4
+//     -ipv6:     use DHCPv6
+//     -leasedir: directory to keep DHCPv4 leases in; "" means none
 //     -verbose:  verbose output
 package main
 
@@ -22,8 +33,10 @@ import (
 	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/d2g/dhcp4"
@@ -47,6 +60,7 @@ var (
 	ipv4         = flag.Bool("ipv4", true, "use IPV4")
 	ipv6         = flag.Bool("ipv6", true, "use IPV6")
 	test         = flag.Bool("test", false, "Test mode")
+	leaseDir     = flag.String("leasedir", "/var/lib/dhclient", "Directory to keep DHCPv4 leases in")
 	debug        = func(string, ...interface{}) {}
 )
 
@@ -88,8 +102,10 @@ func dhclient4(iface netlink.Link, numRenewals int, timeout time.Duration, retry
 		return fmt.Errorf("error: %v", err)
 	}
 
-	var packet dhcp4.Packet
-	needsRequest := true
+	// A lease we had before is renewed, rather than asking for a new one.
+	packet := readLease(iface)
+	saved := packet != nil
+	needsRequest := !saved
 	for i := 0; numRenewals < 0 || i < numRenewals+1; i++ {
 		debug("Start getting or renewing DHCPv4 lease")
 
@@ -103,23 +119,29 @@ func dhclient4(iface netlink.Link, numRenewals int, timeout time.Duration, retry
 				}
 			}
 
+			var p dhcp4.Packet
 			if needsRequest {
-				success, packet, err = client.Request()
+				success, p, err = client.Request()
 			} else {
-				success, packet, err = client.Renew(packet)
+				success, p, err = client.Renew(packet)
 			}
-			if err != nil {
-				if err0, ok := err.(net.Error); ok && err0.Timeout() {
-					log.Printf("%s: timeout contacting DHCP server", mac)
-				} else {
-					log.Printf("%s: error: %v", mac, err)
-				}
-			} else {
+			if err == nil && (success || !saved) {
+				packet = p
 				// Client needs renew after no matter what state it is now.
 				needsRequest = false
 				break
 			}
+			if err0, ok := err.(net.Error); ok && err0.Timeout() {
+				log.Printf("%s: timeout contacting DHCP server", mac)
+			} else if err != nil {
+				log.Printf("%s: error: %v", mac, err)
+			}
+			if saved {
+				debug("%s: could not renew the saved lease; requesting a new one", mac)
+				saved, needsRequest = false, true
+			}
 		}
+		saved = false
 
 		debug("Success on %s: %v\n", mac, success)
 		debug("Packet: %v\n", packet)
@@ -174,21 +196,19 @@ func dhclient4(iface netlink.Link, numRenewals int, timeout time.Duration, retry
 					}
 				}
 			}
-			if ip, ok := o[dhcp4.OptionDomainNameServer]; ok {
-				rc := ""
-				// multiples of 4 octets.
-				for i := 0; i < len(ip); i += 4 {
-					// Don't let broken servers cause us to die.
-					if len(ip[i:]) < 4 {
-						log.Printf("dhcp4.OptionDomainNameServer: short length for last adddress: %v", ip[i:])
-						continue
-					}
-					rc = fmt.Sprintf("%snameserver %s\n", rc, net.IP(ip[i:i+4]))
-				}
+			if rc := resolvConf(o); rc != "" {
 				if err := ioutil.WriteFile("/etc/resolv.conf", []byte(rc), 0644); err != nil {
 					return err
 				}
 			}
+			if h, ok := o[dhcp4.OptionHostName]; ok && len(h) > 0 {
+				if err := syscall.Sethostname(h); err != nil {
+					log.Printf("%s: setting host name %q: %v", iface.Attrs().Name, h, err)
+				}
+			}
+			if err := writeLease(iface, packet); err != nil {
+				log.Printf("%s: saving lease: %v", iface.Attrs().Name, err)
+			}
 		}
 		if binary.BigEndian.Uint16(packet.Secs()) == 0 {
 			debug("%v: server returned infinite lease.", iface.Attrs().Name)
@@ -202,6 +222,58 @@ func dhclient4(iface netlink.Link, numRenewals int, timeout time.Duration, retry
 	return nil
 }
 
+// resolvConf returns the contents of resolv.conf for the DNS servers and
+// domain name in o, or "" if there are no servers.
+func resolvConf(o dhcp4.Options) string {
+	ip, ok := o[dhcp4.OptionDomainNameServer]
+	if !ok {
+		return ""
+	}
+	rc := ""
+	// multiples of 4 octets.
+	for i := 0; i < len(ip); i += 4 {
+		// Don't let broken servers cause us to die.
+		if len(ip[i:]) < 4 {
+			log.Printf("dhcp4.OptionDomainNameServer: short length for last adddress: %v", ip[i:])
+			continue
+		}
+		rc = fmt.Sprintf("%snameserver %s\n", rc, net.IP(ip[i:i+4]))
+	}
+	if d, ok := o[dhcp4.OptionDomainName]; ok && len(d) > 0 {
+		rc += fmt.Sprintf("search %s\n", d)
+	}
+	return rc
+}
+
+func leaseFile(iface netlink.Link) string {
+	return filepath.Join(*leaseDir, iface.Attrs().Name+".lease")
+}
+
+// readLease returns the lease saved for iface, or nil if there is none.
+func readLease(iface netlink.Link) dhcp4.Packet {
+	if *leaseDir == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(leaseFile(iface))
+	// A lease is at least a header and the message type option.
+	if err != nil || len(b) < 244 {
+		return nil
+	}
+	debug("%s: read lease from %s", iface.Attrs().Name, leaseFile(iface))
+	return dhcp4.Packet(b)
+}
+
+// writeLease saves the lease p for iface.
+func writeLease(iface netlink.Link, p dhcp4.Packet) error {
+	if *leaseDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(*leaseDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(leaseFile(iface), p, 0644)
+}
+
 // dhcp6 support in go is hard to find. This function represents our best current
 // guess based on reading and testing.
 func dhclient6(iface netlink.Link, numRenewals int, timeout time.Duration, retry int) error {
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/d2g/dhcp4"
 	"github.com/u-root/u-root/pkg/testutil"
 )
 
@@ -41,3 +42,28 @@ func TestDhclient(t *testing.T) {
 		}
 	}
 }
+
+func TestResolvConf(t *testing.T) {
+	for _, tt := range []struct {
+		o    dhcp4.Options
+		want string
+	}{
+		{dhcp4.Options{}, ""},
+		{dhcp4.Options{dhcp4.OptionDomainName: []byte("example.com")}, ""},
+		{
+			dhcp4.Options{dhcp4.OptionDomainNameServer: []byte{8, 8, 8, 8, 8, 8, 4, 4, 1}},
+			"nameserver 8.8.8.8\nnameserver 8.8.4.4\n",
+		},
+		{
+			dhcp4.Options{
+				dhcp4.OptionDomainNameServer: []byte{10, 0, 0, 1},
+				dhcp4.OptionDomainName:       []byte("example.com"),
+			},
+			"nameserver 10.0.0.1\nsearch example.com\n",
+		},
+	} {
+		if got := resolvConf(tt.o); got != tt.want {
+			t.Errorf("resolvConf(%v): got %q, want %q", tt.o, got, tt.want)
+		}
+	}
+}
@@ -5,46 +5,190 @@
 // Read the system log.
 //
 // Synopsis:
-//     dmesg [-clear|-read-clear]
+//     dmesg [-clear|-read-clear] [-w] [-T] [-r] [-l LEVELS]
+//
+// Description:
+//     dmesg prints the messages in the kernel log buffer. With -w, it then
+//     waits for new messages and prints them as they come.
 //
 // Options:
 //     -clear: clear the log
-//     -read-clear: clear the log after printing
+//     -read-clear, -c: clear the log after printing
+//     -w: wait for new messages
+//     -T: print times as dates rather than seconds since boot
+//     -r: print the raw messages, with their <LEVEL> prefixes
+//     -l: print only messages of the comma-separated LEVELS: emerg, alert,
+//         crit, err, warn, notice, info, debug, or their numbers 0 to 7
+//
+// Example:
+//     dmesg -l err,warn
+//     dmesg -T -w
 package main
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 	"unsafe"
+
+	"golang.org/x/sys/unix"
 )
 
 const (
-	_SYSLOG_ACTION_READ       = 2
-	_SYSLOG_ACTION_READ_ALL   = 3
-	_SYSLOG_ACTION_READ_CLEAR = 4
-	_SYSLOG_ACTION_CLEAR      = 5
+	_SYSLOG_ACTION_READ        = 2
+	_SYSLOG_ACTION_READ_ALL    = 3
+	_SYSLOG_ACTION_READ_CLEAR  = 4
+	_SYSLOG_ACTION_CLEAR       = 5
+	_SYSLOG_ACTION_SIZE_UNREAD = 9
 )
 
 var (
 	clear     bool
 	readClear bool
+	follow    = flag.Bool("w", false, "Wait for new messages")
+	human     = flag.Bool("T", false, "Print times as dates")
+	raw       = flag.Bool("r", false, "Print raw messages")
+	levelList = flag.String("l", "", "Print only messages of these comma-separated `levels`")
 )
 
+// levelNames are the names of the log levels, in order.
+var levelNames = []string{"emerg", "alert", "crit", "err", "warn", "notice", "info", "debug"}
+
 func init() {
 	flag.BoolVar(&clear, "clear", false, "Clear the log")
 	flag.BoolVar(&readClear, "read-clear", false, "Clear the log after printing")
 	flag.BoolVar(&readClear, "c", false, "Clear the log after printing")
 }
 
+func syslog(action int, b []byte) (int, error) {
+	var p unsafe.Pointer
+	if len(b) > 0 {
+		p = unsafe.Pointer(&b[0])
+	}
+	n, _, err := syscall.Syscall(syscall.SYS_SYSLOG, uintptr(action), uintptr(p), uintptr(len(b)))
+	if err != 0 {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// parseLevels parses the argument of -l. It returns nil if s is empty.
+func parseLevels(s string) (map[int]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	levels := map[int]bool{}
+	for _, l := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(l)
+		if err != nil {
+			n = -1
+			for i, name := range levelNames {
+				if l == name {
+					n = i
+				}
+			}
+		}
+		if n < 0 || n >= len(levelNames) {
+			return nil, fmt.Errorf("unknown level %q", l)
+		}
+		levels[n] = true
+	}
+	return levels, nil
+}
+
+// A printer formats the lines of the log.
+type printer struct {
+	levels map[int]bool
+	raw    bool
+	// boot is set to the time of boot to print dates.
+	boot time.Time
+	// partial is the start of a line which has not been printed yet.
+	partial []byte
+}
+
+// line formats one line of the log. It returns false if the line is not
+// to be printed.
+func (p *printer) line(l string) (string, bool) {
+	level := -1
+	msg := l
+	if strings.HasPrefix(l, "<") {
+		if i := strings.IndexByte(l, '>'); i > 0 {
+			if n, err := strconv.Atoi(l[1:i]); err == nil {
+				// The facility is in the bits above the level.
+				level = n & 7
+				msg = l[i+1:]
+			}
+		}
+	}
+	prefix := l[:len(l)-len(msg)]
+	if p.levels != nil && !p.levels[level] {
+		return "", false
+	}
+	if !p.boot.IsZero() && strings.HasPrefix(msg, "[") {
+		if i := strings.IndexByte(msg, ']'); i > 0 {
+			if s, err := strconv.ParseFloat(strings.TrimSpace(msg[1:i]), 64); err == nil {
+				t := p.boot.Add(time.Duration(s * float64(time.Second)))
+				msg = "[" + t.Format(time.ANSIC) + "]" + msg[i+1:]
+			}
+		}
+	}
+	if p.raw {
+		return prefix + msg, true
+	}
+	return msg, true
+}
+
+// write prints the complete lines of b, and keeps the rest for next time.
+func (p *printer) write(b []byte) {
+	b = append(p.partial, b...)
+	var out bytes.Buffer
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			break
+		}
+		if l, ok := p.line(string(b[:i])); ok {
+			out.WriteString(l)
+			out.WriteByte('\n')
+		}
+		b = b[i+1:]
+	}
+	p.partial = append([]byte(nil), b...)
+	os.Stdout.Write(out.Bytes())
+}
+
+// bootTime returns when the system booted.
+func bootTime() (time.Time, error) {
+	var si unix.Sysinfo_t
+	if err := unix.Sysinfo(&si); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-time.Duration(si.Uptime) * time.Second), nil
+}
+
 func main() {
 	flag.Parse()
 	if clear && readClear {
 		log.Fatalf("cannot specify both -clear and -read-clear")
 	}
+	levels, err := parseLevels(*levelList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p := &printer{levels: levels, raw: *raw}
+	if *human {
+		if p.boot, err = bootTime(); err != nil {
+			log.Fatalf("can not find the time of boot: %v", err)
+		}
+	}
 
-	level := uintptr(_SYSLOG_ACTION_READ_ALL)
+	level := _SYSLOG_ACTION_READ_ALL
 	if clear {
 		level = _SYSLOG_ACTION_CLEAR
 	}
@@ -53,10 +197,40 @@ func main() {
 	}
 
 	b := make([]byte, 256*1024)
-	amt, _, err := syscall.Syscall(syscall.SYS_SYSLOG, level, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)))
-	if err != 0 {
+	if *follow {
+		// Messages not yet read by SYSLOG_ACTION_READ are in what
+		// READ_ALL prints, so they are skipped.
+		n, err := syslog(_SYSLOG_ACTION_SIZE_UNREAD, nil)
+		for err == nil && n > 0 {
+			if n > len(b) {
+				n = len(b)
+			}
+			var r int
+			if r, err = syslog(_SYSLOG_ACTION_READ, b[:n]); r == 0 {
+				break
+			}
+			n, err = syslog(_SYSLOG_ACTION_SIZE_UNREAD, nil)
+		}
+		if err != nil {
+			log.Fatalf("syslog failed: %v", err)
+		}
+	}
+
+	amt, err := syslog(level, b)
+	if err != nil {
 		log.Fatalf("syslog failed: %v", err)
 	}
+	p.write(b[:amt])
 
-	os.Stdout.Write(b[:amt])
+	for *follow {
+		// This waits until there is something to read.
+		amt, err := syslog(_SYSLOG_ACTION_READ, b)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			log.Fatalf("syslog failed: %v", err)
+		}
+		p.write(b[:amt])
+	}
 }
@@ -7,6 +7,7 @@ package main
 import (
 	"os/exec"
 	"testing"
+	"time"
 )
 
 // Test reading from the buffer.
@@ -21,3 +22,36 @@ func TestDmesg(t *testing.T) {
 		t.Fatalf("Nothing read from dmesg")
 	}
 }
+
+func TestLine(t *testing.T) {
+	levels, err := parseLevels("err,4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	boot := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		p    printer
+		in   string
+		out  string
+		show bool
+	}{
+		{printer{}, "<6>[    1.500000] hi", "[    1.500000] hi", true},
+		{printer{raw: true}, "<6>[    1.500000] hi", "<6>[    1.500000] hi", true},
+		{printer{levels: levels}, "<6>[    1.500000] hi", "", false},
+		{printer{levels: levels}, "<3>[    1.500000] oops", "[    1.500000] oops", true},
+		// Facility 1 (user), level 4.
+		{printer{levels: levels}, "<12>[    1.500000] user", "[    1.500000] user", true},
+		{printer{boot: boot}, "<6>[   60.000000] hi", "[Mon Jan  2 03:05:05 2017] hi", true},
+		{printer{}, "no prefix", "no prefix", true},
+	} {
+		out, show := tt.p.line(tt.in)
+		if out != tt.out || show != tt.show {
+			t.Errorf("line(%q): got %q, %v, want %q, %v", tt.in, out, show, tt.out, tt.show)
+		}
+	}
+	for _, l := range []string{"bogus", "8", "err,"} {
+		if _, err := parseLevels(l); err == nil {
+			t.Errorf("parseLevels(%q): got nil, want error", l)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/smbios"
+)
+
+// A field is a line dmidecode prints for a table.
+type field struct {
+	name, value string
+}
+
+// typeNames are the headings of the tables, by type.
+var typeNames = map[uint8]string{
+	0:   "BIOS Information",
+	1:   "System Information",
+	2:   "Base Board Information",
+	3:   "Chassis Information",
+	4:   "Processor Information",
+	5:   "Memory Controller Information",
+	6:   "Memory Module Information",
+	7:   "Cache Information",
+	8:   "Port Connector Information",
+	9:   "System Slot Information",
+	10:  "On Board Device Information",
+	11:  "OEM Strings",
+	12:  "System Configuration Options",
+	13:  "BIOS Language Information",
+	15:  "System Event Log",
+	16:  "Physical Memory Array",
+	17:  "Memory Device",
+	19:  "Memory Array Mapped Address",
+	20:  "Memory Device Mapped Address",
+	23:  "System Reset",
+	24:  "Hardware Security",
+	32:  "System Boot Information",
+	38:  "IPMI Device Information",
+	41:  "Onboard Device",
+	127: "End Of Table",
+}
+
+// enum returns the name of v in names, which start at 1, as they mostly do
+// in the specification.
+func enum(names []string, v uint8) string {
+	if v == 0 || int(v) > len(names) {
+		return fmt.Sprintf("<OUT OF SPEC> (0x%02X)", v)
+	}
+	return names[v-1]
+}
+
+var (
+	wakeUpTypes = []string{"Other", "Unknown", "APM Timer", "Modem Ring", "LAN Remote", "Power Switch", "PCI PME#", "AC Power Restored"}
+
+	chassisTypes = []string{"Other", "Unknown", "Desktop", "Low Profile Desktop", "Pizza Box", "Mini Tower", "Tower", "Portable",
+		"Laptop", "Notebook", "Hand Held", "Docking Station", "All In One", "Sub Notebook", "Space-saving", "Lunch Box",
+		"Main Server Chassis", "Expansion Chassis", "Sub Chassis", "Bus Expansion Chassis", "Peripheral Chassis",
+		"RAID Chassis", "Rack Mount Chassis", "Sealed-case PC", "Multi-system", "CompactPCI", "AdvancedTCA", "Blade",
+		"Blade Enclosing", "Tablet", "Convertible", "Detachable", "IoT Gateway", "Embedded PC", "Mini PC", "Stick PC"}
+
+	processorTypes = []string{"Other", "Unknown", "Central Processor", "Math Processor", "DSP Processor", "Video Processor"}
+
+	memoryLocations = []string{"Other", "Unknown", "System Board Or Motherboard", "ISA Add-on Card", "EISA Add-on Card",
+		"PCI Add-on Card", "MCA Add-on Card", "PCMCIA Add-on Card", "Proprietary Add-on Card", "NuBus"}
+
+	memoryUses = []string{"Other", "Unknown", "System Memory", "Video Memory", "Flash Memory", "Non-volatile RAM", "Cache Memory"}
+
+	formFactors = []string{"Other", "Unknown", "SIMM", "SIP", "Chip", "DIP", "ZIP", "Proprietary Card", "DIMM", "TSOP",
+		"Row Of Chips", "RIMM", "SODIMM", "SRIMM", "FB-DIMM", "Die"}
+
+	memoryTypes = []string{"Other", "Unknown", "DRAM", "EDRAM", "VRAM", "SRAM", "RAM", "ROM", "Flash", "EEPROM", "FEPROM",
+		"EPROM", "CDRAM", "3DRAM", "SDRAM", "SGRAM", "RDRAM", "DDR", "DDR2", "DDR2 FB-DIMM", "Reserved", "Reserved",
+		"Reserved", "DDR3", "FBD2", "DDR4", "LPDDR", "LPDDR2", "LPDDR3", "LPDDR4", "Logical non-volatile device", "HBM",
+		"HBM2", "DDR5", "LPDDR5"}
+)
+
+// processorFamilies names the common processor families; there are
+// hundreds.
+var processorFamilies = map[uint16]string{
+	0x01:  "Other",
+	0x02:  "Unknown",
+	0x0b:  "Pentium",
+	0x0f:  "Celeron",
+	0x18:  "Opteron",
+	0x28:  "Core Duo",
+	0x2c:  "Core M",
+	0x6b:  "Zen",
+	0xb3:  "Xeon",
+	0xbf:  "Core 2 Duo",
+	0xc6:  "Core i7",
+	0xcd:  "Core i5",
+	0xce:  "Core i3",
+	0xcf:  "Core i9",
+	0x118: "ARM",
+	0x119: "StrongARM",
+	0x200: "RISC-V RV32",
+	0x201: "RISC-V RV64",
+	0x258: "ARMv7",
+	0x259: "ARMv8",
+}
+
+// str returns the string numbered at off in t, or what dmidecode prints
+// for none.
+func str(t *smbios.Table, off int) string {
+	if s, ok := t.String(off); ok {
+		return strings.TrimSpace(s)
+	}
+	if n, ok := t.Byte(off); ok && n != 0 {
+		return "<BAD INDEX>"
+	}
+	return "Not Specified"
+}
+
+// uuid formats the UUID at off in t. From SMBIOS 2.6 on, its first three
+// fields are little endian, as they are in the EFI GUIDs it is like.
+func uuid(t *smbios.Table, off, ver int) (string, bool) {
+	if off+16 > len(t.Data) {
+		return "", false
+	}
+	u := t.Data[off : off+16]
+	switch {
+	case strings.Count(string(u), "\x00") == 16:
+		return "Not Present", true
+	case strings.Count(string(u), "\xff") == 16:
+		return "Not Settable", true
+	case ver >= 0x206:
+		return fmt.Sprintf("%02X%02X%02X%02X-%02X%02X-%02X%02X-%X-%X", u[3], u[2], u[1], u[0], u[5], u[4], u[7], u[6], u[8:10], u[10:]), true
+	}
+	return fmt.Sprintf("%X-%X-%X-%X-%X", u[:4], u[4:6], u[6:8], u[8:10], u[10:]), true
+}
+
+// size formats n bytes as dmidecode does, in the largest unit which is
+// a whole number of them.
+func size(n uint64) string {
+	u := 0
+	units := []string{"bytes", "kB", "MB", "GB", "TB", "PB", "EB"}
+	for n >= 1024 && n%1024 == 0 && u < len(units)-1 {
+		n /= 1024
+		u++
+	}
+	return fmt.Sprintf("%d %s", n, units[u])
+}
+
+// speed formats the speed at off in t, which is in unit, or 0 if it is not
+// known.
+func speed(t *smbios.Table, off int, unit string) (string, bool) {
+	w, ok := t.Word(off)
+	if !ok {
+		return "", false
+	}
+	if w == 0 {
+		return "Unknown", true
+	}
+	return fmt.Sprintf("%d %s", w, unit), true
+}
+
+// decode returns the fields of t, which is of SMBIOS version ver, as
+// major<<8 | minor, or nil for tables it does not know.
+func decode(t *smbios.Table, ver int) []field {
+	var f []field
+	add := func(name, value string, ok bool) {
+		if ok {
+			f = append(f, field{name, value})
+		}
+	}
+	switch t.Type {
+	case 0:
+		add("Vendor", str(t, 4), true)
+		add("Version", str(t, 5), true)
+		add("Release Date", str(t, 8), true)
+		// 0xff means it is 16MB or more, in Extended BIOS ROM Size.
+		if b, ok := t.Byte(9); ok && b != 0xff {
+			add("ROM Size", size((uint64(b)+1)*64<<10), true)
+		}
+		if major, ok := t.Byte(0x14); ok && major != 0xff {
+			minor, _ := t.Byte(0x15)
+			add("BIOS Revision", fmt.Sprintf("%d.%d", major, minor), true)
+		}
+		if major, ok := t.Byte(0x16); ok && major != 0xff {
+			minor, _ := t.Byte(0x17)
+			add("Firmware Revision", fmt.Sprintf("%d.%d", major, minor), true)
+		}
+	case 1:
+		add("Manufacturer", str(t, 4), true)
+		add("Product Name", str(t, 5), true)
+		add("Version", str(t, 6), true)
+		add("Serial Number", str(t, 7), true)
+		u, ok := uuid(t, 8, ver)
+		add("UUID", u, ok)
+		w, ok := t.Byte(0x18)
+		add("Wake-up Type", enum(wakeUpTypes, w), ok)
+		add("SKU Number", str(t, 0x19), len(t.Data) > 0x19)
+		add("Family", str(t, 0x1a), len(t.Data) > 0x1a)
+	case 2:
+		add("Manufacturer", str(t, 4), true)
+		add("Product Name", str(t, 5), true)
+		add("Version", str(t, 6), true)
+		add("Serial Number", str(t, 7), true)
+		add("Asset Tag", str(t, 8), len(t.Data) > 8)
+	case 3:
+		add("Manufacturer", str(t, 4), true)
+		if c, ok := t.Byte(5); ok {
+			// The top bit is whether there is a lock.
+			add("Type", enum(chassisTypes, c&0x7f), true)
+			lock := "Not Present"
+			if c&0x80 != 0 {
+				lock = "Present"
+			}
+			add("Lock", lock, true)
+		}
+		add("Version", str(t, 6), true)
+		add("Serial Number", str(t, 7), true)
+		add("Asset Tag", str(t, 8), true)
+	case 4:
+		add("Socket Designation", str(t, 4), true)
+		p, ok := t.Byte(5)
+		add("Type", enum(processorTypes, p), ok)
+		if fam, ok := t.Byte(6); ok {
+			f16 := uint16(fam)
+			// 0xfe means it is in Processor Family 2.
+			if w, ok := t.Word(0x28); ok && fam == 0xfe {
+				f16 = w
+			}
+			name, known := processorFamilies[f16]
+			if !known {
+				name = fmt.Sprintf("<OUT OF SPEC> (0x%02X)", f16)
+			}
+			add("Family", name, true)
+		}
+		add("Manufacturer", str(t, 7), true)
+		if len(t.Data) >= 0x10 {
+			var id []string
+			for _, b := range t.Data[8:0x10] {
+				id = append(id, fmt.Sprintf("%02X", b))
+			}
+			add("ID", strings.Join(id, " "), true)
+		}
+		add("Version", str(t, 0x10), len(t.Data) > 0x10)
+		s, ok := speed(t, 0x12, "MHz")
+		add("External Clock", s, ok)
+		s, ok = speed(t, 0x14, "MHz")
+		add("Max Speed", s, ok)
+		s, ok = speed(t, 0x16, "MHz")
+		add("Current Speed", s, ok)
+		if st, ok := t.Byte(0x18); ok {
+			if st&0x40 == 0 {
+				add("Status", "Unpopulated", true)
+			} else {
+				add("Status", "Populated, "+enum([]string{"Enabled", "Disabled By User", "Disabled By BIOS", "Idle"}, st&7), true)
+			}
+		}
+		add("Serial Number", str(t, 0x20), len(t.Data) > 0x20)
+		add("Asset Tag", str(t, 0x21), len(t.Data) > 0x21)
+		add("Part Number", str(t, 0x22), len(t.Data) > 0x22)
+		if c, ok := t.Byte(0x23); ok {
+			add("Core Count", fmt.Sprint(c), true)
+		}
+		if c, ok := t.Byte(0x24); ok {
+			add("Core Enabled", fmt.Sprint(c), true)
+		}
+		if c, ok := t.Byte(0x25); ok {
+			add("Thread Count", fmt.Sprint(c), true)
+		}
+	case 16:
+		l, ok := t.Byte(4)
+		add("Location", enum(memoryLocations, l), ok)
+		u, ok := t.Byte(5)
+		add("Use", enum(memoryUses, u), ok)
+		if c, ok := t.DWord(7); ok {
+			max := uint64(c) << 10
+			// It is in Extended Maximum Capacity, in bytes, if it does
+			// not fit.
+			if e, ok := t.QWord(0x0f); ok && c == 0x80000000 {
+				max = e
+			}
+			add("Maximum Capacity", size(max), true)
+		}
+		if n, ok := t.Word(0x0d); ok {
+			add("Number Of Devices", fmt.Sprint(n), true)
+		}
+	case 17:
+		for _, w := range []struct {
+			name string
+			off  int
+		}{{"Total Width", 8}, {"Data Width", 0x0a}} {
+			if v, ok := t.Word(w.off); ok {
+				s := "Unknown"
+				if v != 0xffff {
+					s = fmt.Sprintf("%d bits", v)
+				}
+				add(w.name, s, true)
+			}
+		}
+		if s, ok := t.Word(0x0c); ok {
+			switch {
+			case s == 0:
+				add("Size", "No Module Installed", true)
+			case s == 0xffff:
+				add("Size", "Unknown", true)
+			case s == 0x7fff:
+				// It is in Extended Size, in MB.
+				e, _ := t.DWord(0x1c)
+				add("Size", size(uint64(e&0x7fffffff)<<20), true)
+			case s&0x8000 != 0:
+				add("Size", size(uint64(s&0x7fff)<<10), true)
+			default:
+				add("Size", size(uint64(s)<<20), true)
+			}
+		}
+		ff, ok := t.Byte(0x0e)
+		add("Form Factor", enum(formFactors, ff), ok)
+		add("Locator", str(t, 0x10), len(t.Data) > 0x10)
+		add("Bank Locator", str(t, 0x11), len(t.Data) > 0x11)
+		mt, ok := t.Byte(0x12)
+		add("Type", enum(memoryTypes, mt), ok)
+		s, ok := speed(t, 0x15, "MT/s")
+		add("Speed", s, ok)
+		add("Manufacturer", str(t, 0x17), len(t.Data) > 0x17)
+		add("Serial Number", str(t, 0x18), len(t.Data) > 0x18)
+		add("Asset Tag", str(t, 0x19), len(t.Data) > 0x19)
+		add("Part Number", str(t, 0x1a), len(t.Data) > 0x1a)
+	}
+	return f
+}
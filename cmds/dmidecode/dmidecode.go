@@ -0,0 +1,189 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Dmidecode prints the SMBIOS tables.
+//
+// Synopsis:
+//     dmidecode [-t TYPE[,TYPE...]] [-s KEYWORD]
+//
+// Description:
+//     dmidecode prints the tables firmware describes the machine with, as
+//     Linux exports them in /sys/firmware/dmi/tables. It decodes the BIOS,
+//     system, base board, chassis, processor and memory tables, and prints
+//     only the headings of the others.
+//
+// Options:
+//     -t: only print tables of the TYPEs, which are numbers or one of bios,
+//         system, baseboard, chassis, processor, memory, cache, connector
+//         and slot
+//     -s: only print the value of KEYWORD, such as system-serial-number,
+//         one line for each table it is in
+//
+// Example:
+//     $ dmidecode -s system-uuid
+//     4C4C4544-0042-3510-8052-B3C04F563132
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/smbios"
+)
+
+var (
+	types   = flag.String("t", "", "only print tables of these types")
+	keyword = flag.String("s", "", "only print the value of this keyword")
+
+	// Tests change this.
+	sysTables = smbios.SysTables
+)
+
+// typeKeywords are the -t keywords, and the types they are for.
+var typeKeywords = map[string][]uint8{
+	"bios":      {0, 13},
+	"system":    {1, 12, 15, 23, 32},
+	"baseboard": {2, 10, 41},
+	"chassis":   {3},
+	"processor": {4},
+	"memory":    {5, 6, 16, 17},
+	"cache":     {7},
+	"connector": {8},
+	"slot":      {9},
+}
+
+// keywords are the -s keywords, and the fields they are.
+var keywords = map[string]struct {
+	typ   uint8
+	field string
+}{
+	"bios-vendor":             {0, "Vendor"},
+	"bios-version":            {0, "Version"},
+	"bios-release-date":       {0, "Release Date"},
+	"system-manufacturer":     {1, "Manufacturer"},
+	"system-product-name":     {1, "Product Name"},
+	"system-version":          {1, "Version"},
+	"system-serial-number":    {1, "Serial Number"},
+	"system-uuid":             {1, "UUID"},
+	"system-family":           {1, "Family"},
+	"baseboard-manufacturer":  {2, "Manufacturer"},
+	"baseboard-product-name":  {2, "Product Name"},
+	"baseboard-version":       {2, "Version"},
+	"baseboard-serial-number": {2, "Serial Number"},
+	"baseboard-asset-tag":     {2, "Asset Tag"},
+	"chassis-manufacturer":    {3, "Manufacturer"},
+	"chassis-type":            {3, "Type"},
+	"chassis-version":         {3, "Version"},
+	"chassis-serial-number":   {3, "Serial Number"},
+	"chassis-asset-tag":       {3, "Asset Tag"},
+	"processor-family":        {4, "Family"},
+	"processor-manufacturer":  {4, "Manufacturer"},
+	"processor-version":       {4, "Version"},
+	"processor-frequency":     {4, "Current Speed"},
+}
+
+// parseTypes returns the set of types in the -t list s, or nil for all of
+// them.
+func parseTypes(s string) (map[uint8]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	m := make(map[uint8]bool)
+	for _, t := range strings.Split(s, ",") {
+		if ts, ok := typeKeywords[t]; ok {
+			for _, t := range ts {
+				m[t] = true
+			}
+			continue
+		}
+		n, err := strconv.ParseUint(t, 0, 8)
+		if err != nil {
+			var k []string
+			for kw := range typeKeywords {
+				k = append(k, kw)
+			}
+			return nil, fmt.Errorf("bad type %q: want a number or one of %v", t, list(k))
+		}
+		m[uint8(n)] = true
+	}
+	return m, nil
+}
+
+// list returns the words in k, sorted, separated by commas.
+func list(k []string) string {
+	sort.Strings(k)
+	return strings.Join(k, ", ")
+}
+
+// dump prints the tables of the types in only, or all of them if it is
+// nil, as dmidecode does.
+func dump(w io.Writer, major, minor int, tables []*smbios.Table, only map[uint8]bool) {
+	fmt.Fprintf(w, "SMBIOS %d.%d present.\n", major, minor)
+	for _, t := range tables {
+		if only != nil && !only[t.Type] {
+			continue
+		}
+		fmt.Fprintf(w, "\nHandle 0x%04X, DMI type %d, %d bytes\n", t.Handle, t.Type, len(t.Data))
+		name, ok := typeNames[t.Type]
+		switch {
+		case ok:
+		case t.Type >= 128:
+			name = "OEM-specific Type"
+		default:
+			name = "Unknown Type"
+		}
+		fmt.Fprintln(w, name)
+		for _, f := range decode(t, major<<8|minor) {
+			fmt.Fprintf(w, "\t%s: %s\n", f.name, f.value)
+		}
+	}
+}
+
+// value prints the value of the -s keyword k in each table it is in.
+func value(w io.Writer, major, minor int, tables []*smbios.Table, k string) error {
+	kw, ok := keywords[k]
+	if !ok {
+		var ks []string
+		for kw := range keywords {
+			ks = append(ks, kw)
+		}
+		return fmt.Errorf("bad keyword %q: want one of %v", k, list(ks))
+	}
+	for _, t := range tables {
+		if t.Type != kw.typ {
+			continue
+		}
+		for _, f := range decode(t, major<<8|minor) {
+			if f.name == kw.field {
+				fmt.Fprintln(w, f.value)
+			}
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	only, err := parseTypes(*types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	major, minor, tables, err := smbios.Read(sysTables)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *keyword != "" {
+		if err := value(os.Stdout, major, minor, tables, *keyword); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	dump(os.Stdout, major, minor, tables, only)
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/smbios"
+)
+
+// table makes a table of type typ, with the formatted area data after its
+// header, and strings.
+func table(typ uint8, handle uint16, data []byte, strings ...string) []byte {
+	b := append([]byte{typ, byte(4 + len(data)), byte(handle), byte(handle >> 8)}, data...)
+	for _, s := range strings {
+		b = append(append(b, s...), 0)
+	}
+	if len(strings) == 0 {
+		b = append(b, 0)
+	}
+	return append(b, 0)
+}
+
+// fakeTables writes an SMBIOS 2.8 entry point and tables to a directory.
+func fakeTables(t *testing.T) string {
+	tmp, err := ioutil.TempDir("", "dmidecode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep := append([]byte("_SM_\x00\x1f\x02\x08"), make([]byte, 23)...)
+	var dmi []byte
+	// BIOS: vendor, version, start, release date, ROM size, 16 bytes
+	// of characteristics, BIOS and firmware revisions.
+	dmi = append(dmi, table(0, 0, append(append([]byte{1, 2, 0, 0xe8, 3, 0}, make([]byte, 10)...), 0, 1, 0xff, 0xff), "SeaBIOS", "1.11.0", "04/01/2014")...)
+	// System: manufacturer, product, version, serial, UUID, wake-up
+	// type, SKU and family.
+	uuid := []byte{0x44, 0x45, 0x4c, 0x4c, 0x42, 0x00, 0x10, 0x35, 0x80, 0x52, 0xb3, 0xc0, 0x4f, 0x56, 0x31, 0x32}
+	dmi = append(dmi, table(1, 0x100, append(append([]byte{1, 2, 0, 3}, uuid...), 6, 0, 0), "QEMU", "Standard PC", "SN123")...)
+	// Processor, 2.6 long: designation, type, family, manufacturer, ID,
+	// version, voltage, clocks, status, upgrade, caches, serial, asset
+	// tag, part number, counts.
+	cpu := []byte{1, 3, 0xcd, 2, 0xa9, 6, 3, 0, 0xff, 0xfb, 0xeb, 0xbf, 3, 0, 100, 0, 0x10, 0x0e, 0x98, 0x08, 0x41, 1,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 4, 4, 8, 0xfc, 0, 0, 0}
+	dmi = append(dmi, table(4, 0x400, cpu, "CPU 0", "Intel", "Intel(R) Core(TM) i5-3470")...)
+	// Memory device of 8 GB DDR3 DIMM at 1600 MT/s.
+	mem := []byte{0, 0x10, 0xfe, 0xff, 64, 0, 64, 0, 0, 0x20, 9, 0, 1, 2, 0x18, 0x80, 0, 0x40, 0x06, 3, 0, 0, 4}
+	dmi = append(dmi, table(17, 0x1100, mem, "DIMM 0", "BANK 0", "Samsung", "M378B1G73EB0")...)
+	dmi = append(dmi, table(0x80, 0x8000, []byte{1, 2})...)
+	dmi = append(dmi, table(127, 0xfeff, nil)...)
+	if err := ioutil.WriteFile(filepath.Join(tmp, "smbios_entry_point"), ep, 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "DMI"), dmi, 0444); err != nil {
+		t.Fatal(err)
+	}
+	return tmp
+}
+
+func TestDmidecode(t *testing.T) {
+	tmp := fakeTables(t)
+	defer os.RemoveAll(tmp)
+	major, minor, tables, err := smbios.Read(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		types string
+		want  string
+	}{
+		{"bios,1", `SMBIOS 2.8 present.
+
+Handle 0x0000, DMI type 0, 24 bytes
+BIOS Information
+	Vendor: SeaBIOS
+	Version: 1.11.0
+	Release Date: 04/01/2014
+	ROM Size: 64 kB
+	BIOS Revision: 0.1
+
+Handle 0x0100, DMI type 1, 27 bytes
+System Information
+	Manufacturer: QEMU
+	Product Name: Standard PC
+	Version: Not Specified
+	Serial Number: SN123
+	UUID: 4C4C4544-0042-3510-8052-B3C04F563132
+	Wake-up Type: Power Switch
+	SKU Number: Not Specified
+	Family: Not Specified
+`},
+		{"4", `SMBIOS 2.8 present.
+
+Handle 0x0400, DMI type 4, 42 bytes
+Processor Information
+	Socket Designation: CPU 0
+	Type: Central Processor
+	Family: Core i5
+	Manufacturer: Intel
+	ID: A9 06 03 00 FF FB EB BF
+	Version: Intel(R) Core(TM) i5-3470
+	External Clock: 100 MHz
+	Max Speed: 3600 MHz
+	Current Speed: 2200 MHz
+	Status: Populated, Enabled
+	Serial Number: Not Specified
+	Asset Tag: Not Specified
+	Part Number: Not Specified
+	Core Count: 4
+	Core Enabled: 4
+	Thread Count: 8
+`},
+		{"memory", `SMBIOS 2.8 present.
+
+Handle 0x1100, DMI type 17, 27 bytes
+Memory Device
+	Total Width: 64 bits
+	Data Width: 64 bits
+	Size: 8 GB
+	Form Factor: DIMM
+	Locator: DIMM 0
+	Bank Locator: BANK 0
+	Type: DDR3
+	Speed: 1600 MT/s
+	Manufacturer: Samsung
+	Serial Number: Not Specified
+	Asset Tag: Not Specified
+	Part Number: M378B1G73EB0
+`},
+		{"128,127", `SMBIOS 2.8 present.
+
+Handle 0x8000, DMI type 128, 6 bytes
+OEM-specific Type
+
+Handle 0xFEFF, DMI type 127, 4 bytes
+End Of Table
+`},
+	} {
+		only, err := parseTypes(tt.types)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		dump(&b, major, minor, tables, only)
+		if b.String() != tt.want {
+			t.Errorf("-t %v: got\n%s\nwant\n%s", tt.types, b.String(), tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		keyword, want string
+	}{
+		{"system-uuid", "4C4C4544-0042-3510-8052-B3C04F563132\n"},
+		{"bios-vendor", "SeaBIOS\n"},
+		{"processor-frequency", "2200 MHz\n"},
+		{"chassis-type", ""},
+	} {
+		var b bytes.Buffer
+		if err := value(&b, major, minor, tables, tt.keyword); err != nil {
+			t.Errorf("-s %v: %v", tt.keyword, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("-s %v: got %q, want %q", tt.keyword, b.String(), tt.want)
+		}
+	}
+	if err := value(ioutil.Discard, major, minor, tables, "bios-colour"); err == nil {
+		t.Errorf("-s bios-colour: got nil, want an error")
+	}
+	if _, err := parseTypes("bios,fan"); err == nil {
+		t.Errorf("-t bios,fan: got nil, want an error")
+	}
+}
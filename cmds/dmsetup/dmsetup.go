@@ -0,0 +1,278 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Dmsetup manages device-mapper devices.
+//
+// Synopsis:
+//     dmsetup create [-r] [-u UUID] [-table TABLE] NAME [FILE]
+//     dmsetup load [-r] [-table TABLE] NAME [FILE]
+//     dmsetup remove|suspend|resume|clear NAME
+//     dmsetup ls|version
+//     dmsetup info|status [NAME]
+//     dmsetup table [-showkeys] [NAME]
+//
+// Description:
+//     A table is a line for each target: START LENGTH TYPE PARAMS, in
+//     sectors, such as
+//         0 2097152 linear /dev/sda2 0
+//     It is TABLE, or read from FILE, or from stdin.
+//
+//     create creates the device NAME, /dev/mapper/NAME, with a table.
+//     load loads a table, which resume makes live; suspend makes I/O to
+//     the device wait, until it is resumed, so the table can be changed
+//     safely. clear clears a loaded table.
+//
+//     ls lists the devices, info and status print the state of them and
+//     of their targets, and table prints their tables. The keys of crypt
+//     targets are printed as zeros, unless -showkeys.
+//
+// Options:
+//     -r:        make the device read only
+//     -u:        the UUID of the device
+//     -table:    the table
+//     -showkeys: print the keys of crypt targets
+//
+// Example:
+//     $ dmsetup create -table "0 2097152 linear /dev/sda2 0" data
+//     $ dmsetup ls
+//     data	(253:0)
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/dmsetup"
+)
+
+var (
+	readOnly = flag.Bool("r", false, "make the device read only")
+	uuid     = flag.String("u", "", "the UUID of the device")
+	table    = flag.String("table", "", "the table")
+	showKeys = flag.Bool("showkeys", false, "print the keys of crypt targets")
+)
+
+// parseTable parses a table, skipping blank lines and comments.
+func parseTable(r io.Reader) ([]dmsetup.Target, error) {
+	var targets []dmsetup.Target
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		t, err := dmsetup.ParseTarget(l)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("the table is empty")
+	}
+	return targets, nil
+}
+
+// readTable reads the table of -table, or FILE, or stdin.
+func readTable(args []string) ([]dmsetup.Target, error) {
+	switch {
+	case *table != "":
+		return parseTable(strings.NewReader(*table))
+	case len(args) > 0:
+		f, err := os.Open(args[0])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseTable(f)
+	}
+	return parseTable(os.Stdin)
+}
+
+// hideKey replaces the key of a crypt target with zeros.
+func hideKey(t dmsetup.Target) dmsetup.Target {
+	f := strings.Fields(t.Params)
+	if t.Type != "crypt" || len(f) < 2 {
+		return t
+	}
+	if strings.HasPrefix(f[1], ":") {
+		// A key in the kernel keyring is only its description.
+		return t
+	}
+	f[1] = strings.Repeat("0", len(f[1]))
+	t.Params = strings.Join(f, " ")
+	return t
+}
+
+func printInfo(w io.Writer, i *dmsetup.Info) {
+	state := "ACTIVE"
+	if i.Suspended {
+		state = "SUSPENDED"
+	}
+	rw := "read-write"
+	if i.ReadOnly {
+		rw = "readonly"
+	}
+	tables := "None"
+	switch {
+	case i.Live && i.Inactive:
+		tables = "LIVE & INACTIVE"
+	case i.Live:
+		tables = "LIVE"
+	case i.Inactive:
+		tables = "INACTIVE"
+	}
+	fmt.Fprintf(w, "Name:              %s\n", i.Name)
+	fmt.Fprintf(w, "State:             %s\n", state)
+	fmt.Fprintf(w, "Read Ahead:        %s\n", rw)
+	fmt.Fprintf(w, "Tables present:    %s\n", tables)
+	fmt.Fprintf(w, "Open count:        %d\n", i.OpenCount)
+	fmt.Fprintf(w, "Event number:      %d\n", i.EventNr)
+	fmt.Fprintf(w, "Major, minor:      %d, %d\n", i.Major, i.Minor)
+	fmt.Fprintf(w, "Number of targets: %d\n", i.TargetCount)
+	if i.UUID != "" {
+		fmt.Fprintf(w, "UUID: %s\n", i.UUID)
+	}
+}
+
+// names returns the devices named in args, or all of them.
+func names(args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	devs, err := dmsetup.List()
+	if err != nil {
+		return nil, err
+	}
+	var n []string
+	for _, d := range devs {
+		n = append(n, d.Name)
+	}
+	return n, nil
+}
+
+// each calls f for each device of args, or all devices, with the name of
+// the device before what it prints, if there are more than one.
+func each(args []string, f func(name string) error) error {
+	n, err := names(args)
+	if err != nil {
+		return err
+	}
+	if len(n) == 0 {
+		fmt.Println("No devices found")
+	}
+	for i, name := range n {
+		if len(n) > 1 && i > 0 {
+			fmt.Println()
+		}
+		if err := f(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func run(cmd string, args []string) error {
+	need := func(n int) error {
+		if len(args) < n {
+			return fmt.Errorf("usage: dmsetup %s NAME", cmd)
+		}
+		return nil
+	}
+	switch cmd {
+	case "create", "load":
+		if err := need(1); err != nil {
+			return err
+		}
+		t, err := readTable(args[1:])
+		if err != nil {
+			return err
+		}
+		if cmd == "load" {
+			return dmsetup.Load(args[0], *readOnly, t)
+		}
+		return dmsetup.Create(args[0], *uuid, *readOnly, t)
+	case "remove", "suspend", "resume", "clear":
+		if err := need(1); err != nil {
+			return err
+		}
+		f := map[string]func(string) error{
+			"remove":  dmsetup.Remove,
+			"suspend": dmsetup.Suspend,
+			"resume":  dmsetup.Resume,
+			"clear":   dmsetup.Clear,
+		}[cmd]
+		return f(args[0])
+	case "ls":
+		devs, err := dmsetup.List()
+		if err != nil {
+			return err
+		}
+		if len(devs) == 0 {
+			fmt.Println("No devices found")
+		}
+		for _, d := range devs {
+			fmt.Printf("%s\t(%d:%d)\n", d.Name, d.Major, d.Minor)
+		}
+	case "version":
+		v, err := dmsetup.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Driver version:   %s\n", v)
+	case "info":
+		return each(args, func(name string) error {
+			i, err := dmsetup.Status(name)
+			if err != nil {
+				return err
+			}
+			printInfo(os.Stdout, i)
+			return nil
+		})
+	case "table", "status":
+		return each(args, func(name string) error {
+			get := dmsetup.Table
+			if cmd == "status" {
+				get = dmsetup.TargetStatus
+			}
+			targets, err := get(name)
+			if err != nil {
+				return err
+			}
+			for _, t := range targets {
+				if cmd == "table" && !*showKeys {
+					t = hideKey(t)
+				}
+				if len(args) != 1 {
+					fmt.Printf("%s: ", name)
+				}
+				fmt.Println(t)
+			}
+			return nil
+		})
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/dmsetup"
+)
+
+func TestParseTable(t *testing.T) {
+	targets, err := parseTable(strings.NewReader(`# the first disk, then the second
+0 100 linear /dev/sda 0
+
+100 50 linear /dev/sdb 2048
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[1].String() != "100 50 linear /dev/sdb 2048" {
+		t.Errorf("parseTable: got %v", targets)
+	}
+	for _, bad := range []string{"", "# nothing\n", "0 linear /dev/sda 0"} {
+		if _, err := parseTable(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseTable(%q): got nil, want error", bad)
+		}
+	}
+}
+
+func TestHideKey(t *testing.T) {
+	for _, tt := range []struct{ in, want dmsetup.Target }{
+		{
+			dmsetup.Target{Length: 8, Type: "crypt", Params: "aes-xts-plain64 0123abcd 0 8:2 4096"},
+			dmsetup.Target{Length: 8, Type: "crypt", Params: "aes-xts-plain64 00000000 0 8:2 4096"},
+		},
+		{
+			dmsetup.Target{Length: 8, Type: "crypt", Params: "aes-xts-plain64 :64:logon:cryptsetup:x 0 8:2 4096"},
+			dmsetup.Target{Length: 8, Type: "crypt", Params: "aes-xts-plain64 :64:logon:cryptsetup:x 0 8:2 4096"},
+		},
+		{
+			dmsetup.Target{Length: 8, Type: "linear", Params: "8:0 0123"},
+			dmsetup.Target{Length: 8, Type: "linear", Params: "8:0 0123"},
+		},
+	} {
+		if got := hideKey(tt.in); got != tt.want {
+			t.Errorf("hideKey(%v): got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPrintInfo(t *testing.T) {
+	var b bytes.Buffer
+	printInfo(&b, &dmsetup.Info{Name: "root", UUID: "CRYPT-LUKS2-x-root", Major: 253, Minor: 0, OpenCount: 1,
+		TargetCount: 1, Live: true})
+	want := `Name:              root
+State:             ACTIVE
+Read Ahead:        read-write
+Tables present:    LIVE
+Open count:        1
+Event number:      0
+Major, minor:      253, 0
+Number of targets: 1
+UUID: CRYPT-LUKS2-x-root
+`
+	if b.String() != want {
+		t.Errorf("printInfo: got\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -2,22 +2,249 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Options:
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+var (
+	// input is where the text of the a, c and i commands comes from.
+	input interface {
+		Scan() bool
+		Text() string
+	}
+	// fileName is the file e, r and w use if they are not given one.
+	fileName string
+	// lastSearch is the last pattern of a /RE/ or ?RE? address.
+	lastSearch string
+)
+
+// delimited returns s up to the first delim which is not escaped, and
+// what follows it. If there is no delim, it returns all of s.
+func delimited(s string, delim byte) (string, string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// line returns line n of f, with its newline.
+func line(f Editor, n int) string {
+	var b bytes.Buffer
+	f.Write(&b, n, n+1)
+	return b.String()
+}
+
+// lastLine returns the number of the last line of f.
+func lastLine(f Editor) int {
+	_, l := f.Range()
+	return l
+}
+
+// search returns the next line after dot, or with backward the last one
+// before it, which matches pat. It wraps around the end of the file. An
+// empty pat is the last one searched for.
+func search(f Editor, pat string, backward bool) (int, error) {
+	if pat == "" {
+		pat = lastSearch
+	}
+	if pat == "" {
+		return -1, fmt.Errorf("no previous pattern")
+	}
+	r, err := regexp.Compile(pat)
+	if err != nil {
+		return -1, err
+	}
+	lastSearch = pat
+	last := lastLine(f)
+	step := 1
+	if backward {
+		step = -1
+	}
+	for i, n := 0, f.Dot(); i < last; i++ {
+		n += step
+		if n > last {
+			n = 1
+		}
+		if n < 1 {
+			n = last
+		}
+		if r.MatchString(strings.TrimSuffix(line(f, n), "\n")) {
+			return n, nil
+		}
+	}
+	return -1, fmt.Errorf("no match")
+}
+
+// address parses the address at the start of l: ., $, a line number,
+// /RE/ or ?RE?, followed by any number of +N or -N. It returns the line
+// and the rest of l, or -1 if l does not start with an address.
+func address(f Editor, l string) (int, string, error) {
+	n := -1
+	switch {
+	case l == "":
+		return n, l, nil
+	case l[0] == '.':
+		n, l = f.Dot(), l[1:]
+	case l[0] == '$':
+		n, l = lastLine(f), l[1:]
+	case l[0] == '/' || l[0] == '?':
+		backward := l[0] == '?'
+		var pat string
+		var err error
+		pat, l = delimited(l[1:], l[0])
+		if n, err = search(f, pat, backward); err != nil {
+			return -1, l, err
+		}
+	case num.MatchString(l):
+		s := num.FindString(l)
+		n, _ = strconv.Atoi(s)
+		l = l[len(s):]
+	}
+	for len(l) > 0 && (l[0] == '+' || l[0] == '-') {
+		if n < 0 {
+			n = f.Dot()
+		}
+		sign := l[0]
+		l = l[1:]
+		off := 1
+		if s := num.FindString(l); s != "" {
+			off, _ = strconv.Atoi(s)
+			l = l[len(s):]
+		}
+		if sign == '-' {
+			off = -off
+		}
+		n += off
+	}
+	return n, l, nil
+}
+
+// readText reads the text of a, c and i from input, up to a line which is
+// just a period. It returns the text and how many lines are in it.
+func readText() ([]byte, int) {
+	var b bytes.Buffer
+	n := 0
+	for input != nil && input.Scan() {
+		l := input.Text()
+		if l == "." {
+			break
+		}
+		b.WriteString(l + "\n")
+		n++
+	}
+	return b.Bytes(), n
+}
+
+// insert puts text before line at of f. If it goes at the end, and the
+// file does not end in a newline, one is added first.
+func insert(f Editor, text []byte, at int) {
+	if last := lastLine(f); at > last && last > 0 && !strings.HasSuffix(line(f, last), "\n") {
+		text = append([]byte("\n"), text...)
+	}
+	f.Replace(text, at, at)
+	if len(text) > 0 {
+		f.Dirty(true)
+	}
+}
+
+// lines returns the lines from start to end, inclusive, with a newline at
+// the end of each.
+func lines(f Editor, start, end int) []byte {
+	var b bytes.Buffer
+	f.Write(&b, start, end+1)
+	if b.Len() > 0 && !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// count prints how many bytes were read or written, unless -s says not to.
+func count(n int) {
+	if !*suppress {
+		fmt.Println(n)
+	}
+}
+
+// writeFile writes the lines from start to end, inclusive, to the file n,
+// or with appending, to its end.
+func writeFile(f Editor, n string, start, end int, appending bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appending {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	out, err := os.OpenFile(n, flags, 0666)
+	if err != nil {
+		return err
+	}
+	amt, err := f.Write(out, start, end+1)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	count(amt)
+	return nil
+}
+
+// global runs cmd on each line from start to end, inclusive, which matches
+// the pattern at the start of a or, with invert, does not.
+func global(f Editor, a string, start, end int, invert bool) error {
+	if a == "" {
+		return fmt.Errorf("no pattern")
+	}
+	pat, cmd := delimited(a[1:], a[0])
+	if pat == "" {
+		pat = lastSearch
+	}
+	r, err := regexp.Compile(pat)
+	if err != nil {
+		return err
+	}
+	lastSearch = pat
+	if cmd == "" {
+		cmd = "p"
+	}
+	var marked []int
+	for n := start; n <= end; n++ {
+		if r.MatchString(strings.TrimSuffix(line(f, n), "\n")) != invert {
+			marked = append(marked, n)
+		}
+	}
+	// Commands which add or delete lines move the ones after them.
+	moved := 0
+	for _, n := range marked {
+		before := lastLine(f)
+		f.Move(n + moved)
+		if err := DoCommand(f, cmd); err != nil {
+			return err
+		}
+		moved += lastLine(f) - before
+	}
+	return nil
+}
+
+// Command runs the command c on the lines from startLine up to, but not
+// including, endLine.
 func Command(f Editor, c string, startLine, endLine int) error {
 	var err error
+	last := endLine - 1
 	if len(c) == 0 {
-		_, err = f.Write(os.Stdout, f.Dot(), f.Dot())
-		f.Move(endLine + 1)
+		f.Move(last)
+		_, err = f.Write(os.Stdout, last, endLine)
 		return err
 	}
 
@@ -27,113 +254,231 @@ func Command(f Editor, c string, startLine, endLine int) error {
 	case 'q', 'e':
 		if f.IsDirty() {
 			f.Dirty(false)
-			return fmt.Errorf("f was dirty, no longer is, try again")
+			return fmt.Errorf("warning: file modified, try again")
 		}
 	}
 	switch c[0] {
+	case 'a':
+		text, n := readText()
+		insert(f, text, endLine)
+		f.Move(last + n)
+	case 'i':
+		if startLine < 1 {
+			startLine = 1
+		}
+		text, n := readText()
+		insert(f, text, startLine)
+		f.Move(startLine + n - 1)
+	case 'c':
+		text, n := readText()
+		f.Replace(text, startLine, endLine)
+		f.Dirty(true)
+		f.Move(startLine + n - 1)
 	case 'd':
 		f.Replace([]byte{}, startLine, endLine)
-	case 'q':
-		os.Exit(1)
-	case 'e':
-		startLine, endLine = f.Range()
-		startLine = 0
+		f.Dirty(true)
+		f.Move(startLine)
+	case 'j':
+		if startLine == last {
+			break
+		}
+		text := bytes.Replace(lines(f, startLine, last), []byte("\n"), nil, -1)
+		f.Replace(append(text, '\n'), startLine, endLine)
+		f.Dirty(true)
+		f.Move(startLine)
+	case 'm', 't':
+		var to int
+		if to, _, err = address(f, strings.TrimLeft(a, " \t")); err != nil {
+			return err
+		}
+		if to < 0 || to > lastLine(f) || (c[0] == 'm' && to >= startLine && to < last) {
+			return fmt.Errorf("invalid destination")
+		}
+		text := lines(f, startLine, last)
+		n := last - startLine + 1
+		if c[0] == 'm' {
+			f.Replace([]byte{}, startLine, endLine)
+			if to >= last {
+				to -= n
+			}
+		}
+		insert(f, text, to+1)
+		f.Move(to + n)
+	case 'q', 'Q':
+		os.Exit(0)
+	case 'e', 'E':
+		// Lines count from 1 and bytes from 0, and endLine is one
+		// past the end of either.
+		lo, hi := f.Range()
+		startLine, endLine = 0, hi+lo
 		fallthrough
 	case 'r':
 		fname := strings.TrimLeft(a, " \t")
+		if fname == "" {
+			fname = fileName
+		}
+		if fileName == "" || c[0] != 'r' {
+			fileName = fname
+		}
 		debug("read %v @ %v, %v", f, startLine, endLine)
 		var r io.Reader
 		r, err = os.Open(fname)
 		debug("%v: r is %v, err %v", fname, r, err)
-		if err == nil {
-			_, err = f.Read(r, startLine, endLine)
+		if err != nil {
+			return err
+		}
+		before := lastLine(f)
+		var n int
+		if n, err = f.Read(r, startLine, endLine); err != nil {
+			return err
+		}
+		count(n)
+		if c[0] == 'r' {
+			f.Dirty(f.IsDirty() || n > 0)
+			f.Move(startLine + lastLine(f) - before)
+		} else {
+			f.Dirty(false)
+			f.Move(lastLine(f))
+		}
+	case 'f':
+		if fname := strings.TrimLeft(a, " \t"); fname != "" {
+			fileName = fname
 		}
+		fmt.Println(fileName)
+	case 'g', 'v':
+		return global(f, a, startLine, last, c[0] == 'v')
 	case 's':
-		o := strings.SplitN(a[1:], a[0:1], 3)
-		if o[1] == "" {
-			o[1] = "" //f.pat
+		if a == "" {
+			return fmt.Errorf("no pattern")
+		}
+		re, rest := delimited(a[1:], a[0])
+		repl, opts := delimited(rest, a[0])
+		debug("s: re %q, replacement %q, options %q", re, repl, opts)
+		err = f.Sub(re, repl, opts, startLine, endLine)
+	case 'w', 'W':
+		quit := strings.HasPrefix(a, "q")
+		if quit {
+			a = a[1:]
 		}
-		debug("after split o is %v", o)
-		err = f.Sub(o[0], o[1], o[2], startLine, endLine)
-	case 'w':
 		fname := strings.TrimLeft(a, " \t")
-		debug("NOT WRITINGT TO %v", fname)
-		_, err = f.Write(os.Stdout, startLine, endLine)
+		if fname == "" {
+			fname = fileName
+		}
+		if fname == "" {
+			return fmt.Errorf("no current filename")
+		}
+		if fileName == "" {
+			fileName = fname
+		}
+		if err := writeFile(f, fname, startLine, last, c[0] == 'W'); err != nil {
+			return err
+		}
+		if start, end := f.Range(); startLine <= start && last >= end {
+			f.Dirty(false)
+		}
+		if quit {
+			os.Exit(0)
+		}
+	case 'n':
+		for n := startLine; n <= last; n++ {
+			fmt.Printf("%d\t%s", n, lines(f, n, n))
+		}
+		f.Move(last)
 	case 'p':
-		fmt.Printf("what the shit")
 		_, err = f.Print(os.Stdout, startLine, endLine)
-		fmt.Printf("shiw %v", err)
+		f.Move(last)
+	case '=':
+		fmt.Println(last)
 	default:
 		err = fmt.Errorf("%c: unknown command", c[0])
 	}
+	if dot, end := f.Dot(), lastLine(f); dot > end {
+		f.Move(end)
+	}
 	return err
 }
 
+// DoCommand parses the addresses at the start of l, and runs the command
+// which follows them. The format is a regular language:
+// [start][,end]command[rest of line]
 func DoCommand(f Editor, l string) error {
-	var err error
-	var startLine, endLine int
-	startLine = f.Dot()
 	if len(l) == 0 {
-		_, err = f.Write(os.Stdout, f.Dot(), f.Dot())
-		f.Move(f.Dot() + 1)
+		// A newline prints the next line.
+		n := f.Dot() + 1
+		if n > lastLine(f) {
+			return fmt.Errorf("invalid address")
+		}
+		f.Move(n)
+		_, err := f.Write(os.Stdout, n, n+1)
 		return err
 	}
-	switch {
-	case l[0] == '.':
-		debug(".\n")
-		startLine = f.Dot()
-		l = l[1:]
-	case l[0] == '$':
-		debug("$\n")
-		_, endLine = f.Range()
-		f.Move(endLine)
-		startLine = f.Dot()
+	startLine, l, err := address(f, l)
+	if err != nil {
+		return err
+	}
+	endLine := startLine
+	if len(l) > 0 && (l[0] == ',' || l[0] == ';') {
+		sep := l[0]
 		l = l[1:]
-	case startsearch.FindString(l) != "":
-		pat := startsearch.FindString(l)
-		l = l[len(pat):]
-		debug("/\n")
-		fail("Pattern search: not yet")
-	case num.FindString(l) != "":
-		debug("num\n")
-		n := num.FindString(l)
-		if startLine, err = strconv.Atoi(n); err != nil {
+		if startLine < 0 {
+			// , alone is 1,$ and ; alone is .,$
+			startLine, endLine = 1, lastLine(f)
+			if sep == ';' {
+				startLine = f.Dot()
+			}
+		}
+		if sep == ';' {
+			f.Move(startLine)
+		}
+		var n int
+		if n, l, err = address(f, l); err != nil {
 			return err
 		}
-		f.Move(startLine)
-		l = l[len(n):]
+		if n >= 0 {
+			endLine = n
+		}
 	}
-	debug("cmd before endsearch is %v", l)
-	endLine = f.Dot()
-	if len(l) > 0 && l[0] == ',' {
-		l = l[1:]
-		if len(l) < 1 {
-			return fmt.Errorf("line ended at a ,?")
-		}
-		switch {
-		case l[0] == '.':
-			debug(".\n")
-			endLine = f.Dot()
-			l = l[1:]
-		case l[0] == '$':
-			debug("$\n")
-			_, endLine = f.Range()
-			l = l[1:]
-		case startsearch.FindString(l) != "":
-			debug("/\n")
-			fail("Pattern search: not yet")
-		case num.FindString(l) != "":
-			debug("num\n")
-			n := num.FindString(l)
-			if endLine, err = strconv.Atoi(n); err != nil {
-				return err
-			}
-			l = l[len(n):]
+	debug("addresses [%d, %d], command %q", startLine, endLine, l)
+
+	cmd := byte(0)
+	if len(l) > 0 {
+		cmd = l[0]
+	}
+	if startLine < 0 {
+		switch cmd {
+		case 'w', 'W', 'g', 'v':
+			startLine, endLine = 1, lastLine(f)
+		case 'r', '=':
+			startLine = lastLine(f)
+			endLine = startLine
+		case 'j':
+			startLine, endLine = f.Dot(), f.Dot()+1
+		default:
+			startLine, endLine = f.Dot(), f.Dot()
 		}
 	}
-	endLine++
-	debug("l before call to f.Command() is %v", l)
-	err = Command(f, l, startLine, endLine)
-	debug("Comand is done: f is %v", f)
-	return err
+	lo, hi := f.Range()
+	switch cmd {
+	case 'e', 'E', 'f', 'q', 'Q':
+	case 'w', 'W':
+		// An empty file can be written.
+		if hi == 0 {
+			break
+		}
+		fallthrough
+	default:
+		if startLine > endLine || startLine < lo || endLine > hi {
+			return fmt.Errorf("invalid address")
+		}
+	case 'a', 'i', 'r':
+		// These take a point, and 0 is before the first line.
+		if startLine > hi || startLine < 0 {
+			return fmt.Errorf("invalid address")
+		}
+	}
+	if cmd == 's' {
+		// Substitutions leave dot at the first line.
+		f.Move(startLine)
+	}
+	return Command(f, l, startLine, endLine+1)
 }
@@ -5,16 +5,57 @@
 // Ed is a simple line-oriented editor
 //
 // Synopsis:
-//     dd
+//     ed [-s] [-p PROMPT] [-t text|bin] [FILE]
 //
 // Description:
+//     ed reads FILE, if there is one, and then commands from stdin, one
+//     per line, in the form [ADDRESS[,ADDRESS]]COMMAND[ARGS]. An ADDRESS
+//     is ., the current line; $, the last; a line number; /RE/, the next
+//     line RE matches; or ?RE?, the last one before; with any number of
+//     +N or -N after it. , alone is 1,$ and ; is .,$. A line with only
+//     an address prints that line.
+//
+//     The commands are:
+//         a, i: append text after, or insert it before, the line; the text
+//               is the lines which follow, up to one which is a period
+//         c: change the lines to the text which follows
+//         d: delete the lines
+//         j: join the lines
+//         m ADDRESS, t ADDRESS: move, or copy, the lines after ADDRESS
+//         p, n: print the lines, with n with their numbers
+//         s/RE/REPLACEMENT/[g|N][p]: replace the first, all, or the Nth
+//               match of RE in each line; & in REPLACEMENT is the match
+//               and \1 to \9 its groups
+//         g/RE/COMMAND, v/RE/COMMAND: run COMMAND, p by default, on each
+//               line which does, or does not, match RE
+//         e [FILE], E [FILE]: edit FILE; E even if the text is modified
+//         r [FILE]: read FILE in after the line
+//         w [FILE], W [FILE]: write, or append, the lines, all by default,
+//               to FILE; wq writes and quits
+//         f [FILE]: set the name of the file, and print it
+//         =: print the line number, of $ by default
+//         q, Q: quit; q warns first if the text is modified
+//
+//     REs are in the syntax of Go, e.g. s/(a+)b/\1/ rather than
+//     s/\(a\+\)b/\1/.
 //
 // Options:
+//     -s: do not print the size of files read and written
+//     -p: print PROMPT before each command
+//     -t: type of file: text, or bin, with byte offsets for addresses
+//     -d: debug
+//
+// Example:
+//     ed /etc/fstab
+//     ,s/ro/rw/
+//     w
+//     q
 package main
 
 import (
 	"bufio"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -24,18 +65,17 @@ import (
 type editorArg func(Editor) error
 
 var (
-	d                  = flag.Bool("d", false, "debug")
-	debug              = func(s string, i ...interface{}) {}
-	fail               = log.Printf
-	f           Editor = &file{}
-	num                = regexp.MustCompile("^[0-9][0-9]*")
-	startsearch        = regexp.MustCompile("^/[^/]/")
-	endsearch          = regexp.MustCompile("^,/[^/]/")
-	editors            = map[string]func(...editorArg) (Editor, error){
+	d              = flag.Bool("d", false, "debug")
+	debug          = func(s string, i ...interface{}) {}
+	f       Editor = &file{}
+	num            = regexp.MustCompile("^[0-9][0-9]*")
+	editors        = map[string]func(...editorArg) (Editor, error){
 		"text": NewTextEditor,
 		"bin":  NewBinEditor,
 	}
 	fileType = flag.String("t", "text", "type of file")
+	suppress = flag.Bool("s", false, "do not print the size of files read and written")
+	prompt   = flag.String("p", "", "print `prompt` before each command")
 )
 
 func readerio(r io.Reader) editorArg {
@@ -51,9 +91,12 @@ func readFile(n string) editorArg {
 		if err != nil {
 			return err
 		}
-		if _, err := f.Read(r, 0, 0); err != nil {
+		defer r.Close()
+		amt, err := f.Read(r, 0, 0)
+		if err != nil {
 			return err
 		}
+		count(amt)
 		return nil
 	}
 }
@@ -73,10 +116,17 @@ func main() {
 	e, ok := editors[*fileType]
 	if !ok {
 		flag.Usage()
+		os.Exit(1)
 	}
 
 	if len(flag.Args()) == 1 {
-		args = append(args, readFile(flag.Args()[0]))
+		fileName = flag.Args()[0]
+		// A new file is only created when it is written.
+		if _, err := os.Stat(fileName); err == nil {
+			args = append(args, readFile(fileName))
+		} else {
+			log.Print(err)
+		}
 	}
 
 	ed, err := e(args...)
@@ -88,10 +138,15 @@ func main() {
 	// The format is a regular language.
 	// [start][,end]command[rest of line]
 	s := bufio.NewScanner(os.Stdin)
+	input = s
 
-	for s.Scan() {
+	for {
+		fmt.Print(*prompt)
+		if !s.Scan() {
+			break
+		}
 		if err := DoCommand(ed, s.Text()); err != nil {
-			log.Printf(err.Error())
+			log.Print(err)
 		}
 	}
 }
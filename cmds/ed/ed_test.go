@@ -13,10 +13,12 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -313,3 +315,147 @@ func TestFileSCommand(t *testing.T) {
 	}
 
 }
+
+func TestAddress(t *testing.T) {
+	var test = []struct {
+		a    string
+		dot  int
+		n    int
+		rest string
+	}{
+		{a: "p", dot: 2, n: -1, rest: "p"},
+		{a: ".p", dot: 2, n: 2, rest: "p"},
+		{a: "$", dot: 2, n: 6},
+		{a: "4d", dot: 2, n: 4, rest: "d"},
+		{a: "+", dot: 2, n: 3},
+		{a: "-2p", dot: 3, n: 1, rest: "p"},
+		{a: "$-1", dot: 1, n: 5},
+		{a: "/c/p", dot: 1, n: 3, rest: "p"},
+		// Searches wrap around.
+		{a: "/a", dot: 5, n: 1},
+		{a: "?b?", dot: 5, n: 2},
+		{a: "//", dot: 3, n: 5},
+		{a: "/c/+1", dot: 1, n: 4},
+	}
+
+	for _, v := range test {
+		f := &file{data: []byte("a\nb\nc\na\nb\nc\n")}
+		f.fixLines()
+		f.Move(v.dot)
+		n, rest, err := address(f, v.a)
+		if err != nil {
+			t.Errorf("address(%q): want nil, got %v", v.a, err)
+			continue
+		}
+		if n != v.n || rest != v.rest {
+			t.Errorf("address(%q) at %d: want %d, %q, got %d, %q", v.a, v.dot, v.n, v.rest, n, rest)
+		}
+	}
+}
+
+func TestEditCommands(t *testing.T) {
+	var test = []struct {
+		c     string
+		input string
+		d     string
+		fo    string
+		dot   int
+	}{
+		{c: "$a", input: "x\ny\n.\n", d: "a\nb\nc\n", fo: "a\nb\nc\nx\ny\n", dot: 5},
+		{c: "0a", input: "x\n.\n", d: "a\nb\nc\n", fo: "x\na\nb\nc\n", dot: 1},
+		{c: "$a", input: "x\n.\n", d: "a\nb", fo: "a\nb\nx\n", dot: 3},
+		{c: "2i", input: "x\n.\n", d: "a\nb\nc\n", fo: "a\nx\nb\nc\n", dot: 2},
+		{c: "2,3c", input: "x\n.\n", d: "a\nb\nc\n", fo: "a\nx\n", dot: 2},
+		{c: "a", input: "x\n.\n", d: "", fo: "x\n", dot: 1},
+		{c: ",j", d: "a\nb\nc\n", fo: "abc\n", dot: 1},
+		{c: "1m$", d: "a\nb\nc\n", fo: "b\nc\na\n", dot: 3},
+		{c: "2,3m0", d: "a\nb\nc\n", fo: "b\nc\na\n", dot: 2},
+		{c: "1t1", d: "a\nb\nc\n", fo: "a\na\nb\nc\n", dot: 2},
+		{c: "g/a/d", d: "a\nb\na\nc\n", fo: "b\nc\n", dot: 2},
+		{c: "v/a/s/$/!/", d: "a\nb\na\nc\n", fo: "a\nb!\na\nc!\n", dot: 4},
+	}
+
+	for _, v := range test {
+		f, err := NewTextEditor(readerio(bytes.NewBufferString(v.d)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		input = bufio.NewScanner(bytes.NewBufferString(v.input))
+		if err := DoCommand(f, v.c); err != nil {
+			t.Errorf("%q: want nil, got %v", v.c, err)
+			continue
+		}
+		if got := string(f.(*file).data); got != v.fo {
+			t.Errorf("%q on %q: want %q, got %q", v.c, v.d, v.fo, got)
+		}
+		if f.Dot() != v.dot {
+			t.Errorf("%q on %q: want dot %d, got %d", v.c, v.d, v.dot, f.Dot())
+		}
+	}
+}
+
+func TestSubReplacement(t *testing.T) {
+	var test = []struct {
+		c   string
+		d   string
+		err string
+		fo  string
+	}{
+		{c: "s/b+/<&>/", d: "abbcb\n", fo: "a<bb>cb\n"},
+		{c: "s/b/x/g", d: "abbcb\n", fo: "axxcx\n"},
+		{c: "s/b/x/2", d: "abbcb\n", fo: "abxcb\n"},
+		{c: "s/b/x/2g", d: "abbcb\n", fo: "abxcx\n"},
+		{c: "s/(a)(b)/\\2\\1/", d: "abbcb\n", fo: "babcb\n"},
+		{c: "s/b/\\&$1/", d: "abc\n", fo: "a&$1c\n"},
+		{c: "s,/,|,g", d: "/a/b\n", fo: "|a|b\n"},
+		{c: "s/$/!/", d: "abc\n", fo: "abc!\n"},
+		{c: "s/x/y/", d: "abc\n", err: "no match", fo: "abc\n"},
+	}
+
+	for _, v := range test {
+		f, err := NewTextEditor(readerio(bytes.NewBufferString(v.d)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = DoCommand(f, v.c)
+		if (err != nil || v.err != "") && (err == nil || err.Error() != v.err) {
+			t.Errorf("%q: want err %q, got %v", v.c, v.err, err)
+		}
+		if got := string(f.(*file).data); got != v.fo {
+			t.Errorf("%q on %q: want %q, got %q", v.c, v.d, v.fo, got)
+		}
+	}
+}
+
+func TestWriteCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	n := filepath.Join(dir, "f")
+	*suppress = true
+	fileName = ""
+	defer func() { *suppress = false }()
+
+	f, err := NewTextEditor(readerio(bytes.NewBufferString("a\nb\nc\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Dirty(true)
+	for _, c := range []string{"w " + n, "2W", "1,2W " + n} {
+		if err := DoCommand(f, c); err != nil {
+			t.Fatalf("%q: want nil, got %v", c, err)
+		}
+	}
+	if f.IsDirty() {
+		t.Errorf("dirty after writing all the file: want false, got true")
+	}
+	b, err := ioutil.ReadFile(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\nb\nc\nb\na\nb\n"; string(b) != want {
+		t.Errorf("written file: want %q, got %q", want, b)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"strconv"
 )
 
 // start and end are used like slices.
@@ -116,7 +117,7 @@ func (f *file) SliceX(startLine, endLine int) (int, int) {
 		endLine = startLine
 	}
 	if endLine != startLine {
-		if endLine >= len(f.lines) {
+		if endLine > len(f.lines) {
 			//f.end = len(f.lines)
 			end = len(f.data)
 			return start, end
@@ -203,9 +204,40 @@ func (f *file) WriteFile(n string, startLine, endLine int) (int, error) {
 	return f.Write(out, startLine, endLine)
 }
 
-// Sub replaces the regexp with a different one.
+// template turns a replacement of s, where & is the match and \1 to \9
+// are its groups, into a template for Regexp.Expand.
+func template(s string) []byte {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			b.WriteString("$$")
+		case c == '&':
+			b.WriteString("${0}")
+		case c == '\\' && i+1 < len(s):
+			i++
+			if c := s[i]; c >= '0' && c <= '9' {
+				fmt.Fprintf(&b, "${%c}", c)
+			} else {
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.Bytes()
+}
+
+// Sub replaces matches of the regexp re with n. n may refer to the match
+// with & and to its groups with \1 to \9. The options in opt are g to
+// replace every match in a line, rather than the first, a number N to
+// replace the Nth, and p to print the lines afterwards. An empty re is
+// the last one used.
 func (f *file) Sub(re, n, opt string, startLine, endLine int) error {
 	debug("Sub re %s n %s opt %s\n", re, n, opt)
+	if re == "" {
+		re = f.pat
+	}
 	if re == "" {
 		return fmt.Errorf("Empty RE")
 	}
@@ -213,10 +245,26 @@ func (f *file) Sub(re, n, opt string, startLine, endLine int) error {
 	if err != nil {
 		return err
 	}
-	var opts = make(map[byte]bool)
-	for i := range opt {
-		opts[opt[i]] = true
+	f.pat = re
+	var (
+		global, print bool
+		nth           = 1
+	)
+	for i := 0; i < len(opt); i++ {
+		switch c := opt[i]; {
+		case c == 'g':
+			global = true
+		case c == 'p':
+			print = true
+		case c >= '1' && c <= '9':
+			j := i + len(num.FindString(opt[i:]))
+			nth, _ = strconv.Atoi(opt[i:j])
+			i = j - 1
+		default:
+			return fmt.Errorf("unknown option %q", c)
+		}
 	}
+	tmpl := template(n)
 
 	o, start, end := f.Slice(startLine, endLine)
 	debug("Slice from [%v,%v] is [%v, %v] %v", startLine, endLine, start, end, string(o))
@@ -231,20 +279,34 @@ func (f *file) Sub(re, n, opt string, startLine, endLine int) error {
 	if b == nil {
 		return nil
 	}
+	var replaced bool
 	for i := range b {
-		var replaced bool
 		debug("Sub: before b[i] is %v", b[i])
-		b[i] = r.ReplaceAllFunc(b[i], func(o []byte) []byte {
-			debug("Sub: func called with %v", o)
-			if opts['g'] || !replaced {
-				f.dirty = true
-				replaced = true
-				return []byte(n)
+		// The newline is not part of what is matched, so that $ is
+		// the end of the line.
+		l := bytes.TrimSuffix(b[i], []byte("\n"))
+		var out []byte
+		prev := 0
+		for j, m := range r.FindAllSubmatchIndex(l, -1) {
+			if global && j+1 < nth || !global && j+1 != nth {
+				continue
 			}
-			return o
-		})
+			out = append(out, l[prev:m[0]]...)
+			out = r.Expand(out, tmpl, l, m)
+			prev = m[1]
+			replaced = true
+		}
+		out = append(out, l[prev:]...)
+		if len(l) < len(b[i]) {
+			out = append(out, '\n')
+		}
+		b[i] = out
 		debug("Sub: after b[i] is %v", b[i])
 	}
+	if !replaced {
+		return fmt.Errorf("no match")
+	}
+	f.dirty = true
 
 	debug("replaced o %v with n %v\n", o, b)
 	var repl = make([]byte, start)
@@ -255,7 +317,7 @@ func (f *file) Sub(re, n, opt string, startLine, endLine int) error {
 
 	f.data = append(repl, f.data[end:]...)
 	f.fixLines()
-	if opts['p'] {
+	if print {
 		_, err = f.Write(os.Stdout, startLine, endLine)
 	}
 	return err
@@ -296,7 +358,7 @@ func (f *file) Equal(e Editor) error {
 		}
 	}
 	if errors != "" {
-		return fmt.Errorf(errors)
+		return fmt.Errorf("%s", errors)
 	}
 	return nil
 }
@@ -317,6 +379,7 @@ func NewTextEditor(a ...editorArg) (Editor, error) {
 			return nil, err
 		}
 	}
-	f.dot = 1
+	// dot starts at the last line, or 0 if there are none.
+	f.dot = len(f.lines)
 	return f, nil
 }
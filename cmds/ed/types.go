@@ -2,18 +2,12 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Ed is a simple line-oriented editor
-//
-// Synopsis:
-//     dd
-//
-// Description:
-//
-// Options:
 package main
 
 import "io"
 
+// An Editor is the text being edited. Its lines, or for binary files
+// bytes, are numbered from where Range starts.
 type Editor interface {
 	Dot() int
 	Move(int)
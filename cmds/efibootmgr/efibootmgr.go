@@ -0,0 +1,358 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Efibootmgr manages UEFI boot entries.
+//
+// Synopsis:
+//     efibootmgr [-v] [-c [-d DISK] [-p PART] [-l LOADER] [-L LABEL] [ARGS...]]
+//                [-b XXXX [-B|-a|-A]] [-o XXXX,YYYY...] [-n XXXX|-N] [-t SECONDS]
+//
+// Description:
+//     efibootmgr changes the boot entries, Boot#### variables, and the
+//     order they are tried in, BootOrder, then prints them, through
+//     efivarfs. With no options, it only prints them.
+//
+// Options:
+//     -c: create a boot entry for LOADER, on partition PART of DISK, which
+//         must be GPT, with ARGS as its command line, and put it first in
+//         BootOrder
+//     -d: the disk (default /dev/sda)
+//     -p: the partition (default 1)
+//     -l: the loader (default \EFI\BOOT\BOOTX64.EFI)
+//     -L: the label (default Linux)
+//     -b: the boot entry to create or change, in hex
+//     -B: delete it, and take it out of BootOrder
+//     -a: make it active
+//     -A: make it inactive; the firmware does not boot it
+//     -o: set BootOrder
+//     -n: set BootNext, the entry to boot the next time only
+//     -N: delete BootNext
+//     -t: set the timeout of the boot menu
+//     -v: print the device paths and command lines of the entries too
+//
+// Example:
+//     $ efibootmgr -c -d /dev/sda -p 1 -l /vmlinuz -L u-root console=ttyS0
+//     BootCurrent: 0000
+//     BootOrder: 0001,0000
+//     Boot0000* UiApp
+//     Boot0001* u-root
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/u-root/u-root/pkg/efivar"
+	"github.com/u-root/u-root/pkg/gpt"
+)
+
+var (
+	create     = flag.Bool("c", false, "create a boot entry")
+	disk       = flag.String("d", "/dev/sda", "the disk of the loader")
+	part       = flag.Int("p", 1, "the partition of the loader")
+	loader     = flag.String("l", `\EFI\BOOT\BOOTX64.EFI`, "the loader")
+	label      = flag.String("L", "Linux", "the label of the boot entry")
+	bootNum    = flag.String("b", "", "the boot entry to create or change")
+	remove     = flag.Bool("B", false, "delete the boot entry")
+	active     = flag.Bool("a", false, "make the boot entry active")
+	inactive   = flag.Bool("A", false, "make the boot entry inactive")
+	bootOrder  = flag.String("o", "", "set BootOrder")
+	bootNext   = flag.String("n", "", "set BootNext")
+	deleteNext = flag.Bool("N", false, "delete BootNext")
+	timeout    = flag.Int("t", -1, "set the boot menu timeout, in seconds")
+	verbose    = flag.Bool("v", false, "print device paths and command lines")
+)
+
+// parseNum parses the hex boot entry number s.
+func parseNum(s string) (uint16, error) {
+	n, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("bad boot entry %q: want up to 4 hex digits", s)
+	}
+	return uint16(n), nil
+}
+
+// readUint16s returns the variable name as a list of 16 bit numbers, or
+// nil if there is no such variable.
+func readUint16s(name string) []uint16 {
+	v, err := efivar.Read(name, efivar.GlobalGUID)
+	if err != nil {
+		return nil
+	}
+	return efivar.ParseBootOrder(v.Data)
+}
+
+// writeUint16s writes the list of 16 bit numbers ns to variable name.
+func writeUint16s(name string, ns []uint16) error {
+	return efivar.Write(&efivar.Var{Name: name, GUID: efivar.GlobalGUID, Attr: efivar.DefaultAttr, Data: efivar.BootOrder(ns)})
+}
+
+// entries returns the numbers of the Boot#### variables.
+func entries() ([]uint16, error) {
+	vars, err := efivar.List()
+	if err != nil {
+		return nil, err
+	}
+	var ns []uint16
+	for _, v := range vars {
+		if v.GUID != efivar.GlobalGUID || len(v.Name) != 8 || !strings.HasPrefix(v.Name, "Boot") {
+			continue
+		}
+		if n, err := parseNum(v.Name[4:]); err == nil {
+			ns = append(ns, n)
+		}
+	}
+	return ns, nil
+}
+
+// loaderPath returns the device path of loader on partition part of disk.
+func loaderPath(disk string, part int, loader string) ([]byte, error) {
+	f, err := os.Open(disk)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	g, err := gpt.Table(f, gpt.HeaderOff)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", disk, err)
+	}
+	if part < 1 || part > len(g.Parts) || g.Parts[part-1].PartGUID == (uuid.UUID{}) {
+		return nil, fmt.Errorf("%v has no partition %d", disk, part)
+	}
+	p := g.Parts[part-1]
+	path := efivar.HardDrivePath(uint32(part), p.FirstLBA, p.LastLBA-p.FirstLBA+1, p.UniqueGUID)
+	path = append(path, efivar.FilePath(loader)...)
+	return append(path, efivar.EndPath...), nil
+}
+
+// createEntry writes boot entry n, or the first free one if n is nil,
+// which boots path with args, and puts it first in BootOrder.
+func createEntry(n *uint16, path []byte, label string, args []string) (uint16, error) {
+	if n == nil {
+		used, err := entries()
+		if err != nil {
+			return 0, err
+		}
+		m := make(map[uint16]bool)
+		for _, u := range used {
+			m[u] = true
+		}
+		var free uint16
+		for m[free] {
+			free++
+		}
+		n = &free
+	}
+	o := &efivar.LoadOption{Attr: efivar.LoadOptionActive, Description: label, FilePath: path}
+	if len(args) > 0 {
+		// The Linux EFI stub wants its command line in UCS-2.
+		o.OptionalData = efivar.UCS2(strings.Join(args, " "))
+	}
+	if err := efivar.Write(&efivar.Var{Name: efivar.BootName(*n), GUID: efivar.GlobalGUID, Attr: efivar.DefaultAttr, Data: o.Bytes()}); err != nil {
+		return 0, err
+	}
+	order := []uint16{*n}
+	for _, o := range readUint16s("BootOrder") {
+		if o != *n {
+			order = append(order, o)
+		}
+	}
+	return *n, writeUint16s("BootOrder", order)
+}
+
+// deleteEntry deletes boot entry n, and takes it out of BootOrder.
+func deleteEntry(n uint16) error {
+	if err := efivar.Delete(efivar.BootName(n), efivar.GlobalGUID); err != nil {
+		return err
+	}
+	old := readUint16s("BootOrder")
+	if old == nil {
+		return nil
+	}
+	var order []uint16
+	for _, o := range old {
+		if o != n {
+			order = append(order, o)
+		}
+	}
+	return writeUint16s("BootOrder", order)
+}
+
+// setActive makes boot entry n active, or inactive.
+func setActive(n uint16, on bool) error {
+	v, err := efivar.Read(efivar.BootName(n), efivar.GlobalGUID)
+	if err != nil {
+		return err
+	}
+	o, err := efivar.ParseLoadOption(v.Data)
+	if err != nil {
+		return err
+	}
+	if on {
+		o.Attr |= efivar.LoadOptionActive
+	} else {
+		o.Attr &^= efivar.LoadOptionActive
+	}
+	v.Data = o.Bytes()
+	return efivar.Write(v)
+}
+
+// parseOrder parses the -o list s of hex boot entry numbers.
+func parseOrder(s string) ([]uint16, error) {
+	var order []uint16
+	for _, f := range strings.Split(s, ",") {
+		n, err := parseNum(f)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, n)
+	}
+	return order, nil
+}
+
+// formatOrder formats order as efibootmgr does: 0001,0000.
+func formatOrder(order []uint16) string {
+	var s []string
+	for _, n := range order {
+		s = append(s, fmt.Sprintf("%04X", n))
+	}
+	return strings.Join(s, ",")
+}
+
+// optionalData formats the optional data of a boot entry: as a string, if
+// it is one, else in hex.
+func optionalData(b []byte) string {
+	s, n := efivar.ParseUCS2(b)
+	if n != len(b) {
+		return fmt.Sprintf("%x", b)
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return fmt.Sprintf("%x", b)
+		}
+	}
+	return s
+}
+
+// show prints the boot variables, as efibootmgr does.
+func show(w io.Writer, verbose bool) error {
+	if c := readUint16s("BootCurrent"); len(c) > 0 {
+		fmt.Fprintf(w, "BootCurrent: %04X\n", c[0])
+	}
+	if t := readUint16s("Timeout"); len(t) > 0 {
+		fmt.Fprintf(w, "Timeout: %d seconds\n", t[0])
+	}
+	if n := readUint16s("BootNext"); len(n) > 0 {
+		fmt.Fprintf(w, "BootNext: %04X\n", n[0])
+	}
+	if o := readUint16s("BootOrder"); o != nil {
+		fmt.Fprintf(w, "BootOrder: %s\n", formatOrder(o))
+	}
+	ns, err := entries()
+	if err != nil {
+		return err
+	}
+	for _, n := range ns {
+		v, err := efivar.Read(efivar.BootName(n), efivar.GlobalGUID)
+		if err != nil {
+			return err
+		}
+		o, err := efivar.ParseLoadOption(v.Data)
+		if err != nil {
+			fmt.Fprintf(w, "%s: %v\n", efivar.BootName(n), err)
+			continue
+		}
+		a := " "
+		if o.Attr&efivar.LoadOptionActive != 0 {
+			a = "*"
+		}
+		fmt.Fprintf(w, "%s%s %s", efivar.BootName(n), a, o.Description)
+		if verbose {
+			if p := efivar.FormatDevicePath(o.FilePath); p != "" {
+				fmt.Fprintf(w, "\t%s", p)
+			}
+			if len(o.OptionalData) > 0 {
+				fmt.Fprintf(w, " %s", optionalData(o.OptionalData))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// run does what the flags say.
+func run(args []string) error {
+	var n *uint16
+	if *bootNum != "" {
+		b, err := parseNum(*bootNum)
+		if err != nil {
+			return err
+		}
+		n = &b
+	}
+	if (*remove || *active || *inactive) && n == nil {
+		return fmt.Errorf("-B, -a and -A need a boot entry, -b")
+	}
+	switch {
+	case *create:
+		path, err := loaderPath(*disk, *part, *loader)
+		if err != nil {
+			return err
+		}
+		if _, err := createEntry(n, path, *label, args); err != nil {
+			return err
+		}
+	case *remove:
+		if err := deleteEntry(*n); err != nil {
+			return err
+		}
+	case *active || *inactive:
+		if err := setActive(*n, *active); err != nil {
+			return err
+		}
+	}
+	if *bootOrder != "" {
+		order, err := parseOrder(*bootOrder)
+		if err != nil {
+			return err
+		}
+		if err := writeUint16s("BootOrder", order); err != nil {
+			return err
+		}
+	}
+	if *bootNext != "" {
+		next, err := parseNum(*bootNext)
+		if err != nil {
+			return err
+		}
+		if err := writeUint16s("BootNext", []uint16{next}); err != nil {
+			return err
+		}
+	}
+	if *deleteNext {
+		if err := efivar.Delete("BootNext", efivar.GlobalGUID); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if *timeout >= 0 {
+		if err := writeUint16s("Timeout", []uint16{uint16(*timeout)}); err != nil {
+			return err
+		}
+	}
+	return show(os.Stdout, *verbose)
+}
+
+func main() {
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
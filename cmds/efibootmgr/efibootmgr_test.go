@@ -0,0 +1,139 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/efivar"
+	"github.com/u-root/u-root/pkg/gpt"
+)
+
+// fakeDisk writes a 64MiB disk image with an EFI system partition.
+func fakeDisk(t *testing.T, name string) *gpt.GPT {
+	const blocks = 64 << 20 / gpt.BlockSize
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(blocks * gpt.BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gpt.Create(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddPart(gpt.PartTypes["efi"], 0, 0, "EFI"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gpt.WriteProtectiveMBR(f, blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := gpt.Write(f, g); err != nil {
+		t.Fatal(err)
+	}
+	if err := gpt.Write(f, gpt.Backup(g)); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestEntries(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "efibootmgr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	efivar.Dir = filepath.Join(tmp, "efivars")
+	if err := os.Mkdir(efivar.Dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	disk := filepath.Join(tmp, "disk")
+	g := fakeDisk(t, disk)
+
+	// The firmware made an entry for its setup.
+	ui := &efivar.LoadOption{Attr: efivar.LoadOptionActive, Description: "UiApp", FilePath: efivar.EndPath}
+	if err := efivar.Write(&efivar.Var{Name: "Boot0000", GUID: efivar.GlobalGUID, Attr: efivar.DefaultAttr, Data: ui.Bytes()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"BootOrder", "BootCurrent"} {
+		if err := writeUint16s(v, []uint16{0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := loaderPath(disk, 1, "/EFI/linux/vmlinuz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := createEntry(nil, path, "u-root", []string{"console=ttyS0", "quiet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("createEntry: got Boot%04X, want Boot0001", n)
+	}
+	if _, err := loaderPath(disk, 2, "/EFI/linux/vmlinuz"); err == nil {
+		t.Errorf("loaderPath of partition 2: got nil, want an error")
+	}
+
+	p := g.Parts[0]
+	var b bytes.Buffer
+	if err := show(&b, true); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`BootCurrent: 0000
+BootOrder: 0001,0000
+Boot0000* UiApp
+Boot0001* u-root	HD(1,GPT,%s,0x800,%#x)/File(\EFI\linux\vmlinuz) console=ttyS0 quiet
+`, efivar.GUIDString(p.UniqueGUID[:]), p.LastLBA-p.FirstLBA+1)
+	if b.String() != want {
+		t.Errorf("show: got\n%s\nwant\n%s", b.String(), want)
+	}
+
+	if err := setActive(0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteEntry(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeUint16s("BootNext", []uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := show(&b, false); err != nil {
+		t.Fatal(err)
+	}
+	want = `BootCurrent: 0000
+BootNext: 0000
+BootOrder: 0000
+Boot0000  UiApp
+`
+	if b.String() != want {
+		t.Errorf("show: got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	order, err := parseOrder("0001,a,FFFF")
+	if want := []uint16{1, 0xa, 0xffff}; err != nil || !reflect.DeepEqual(order, want) {
+		t.Errorf("parseOrder: got %v, %v, want %v", order, err, want)
+	}
+	if s := formatOrder(order); s != "0001,000A,FFFF" {
+		t.Errorf("formatOrder(%v): got %q, want 0001,000A,FFFF", order, s)
+	}
+	for _, bad := range []string{"", "1,,2", "10000", "xyz"} {
+		if _, err := parseOrder(bad); err == nil {
+			t.Errorf("parseOrder(%q): got nil, want an error", bad)
+		}
+	}
+}
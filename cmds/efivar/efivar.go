@@ -0,0 +1,135 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Efivar lists, prints and writes UEFI variables.
+//
+// Synopsis:
+//     efivar -l
+//     efivar -p -n GUID-NAME
+//     efivar -w -n GUID-NAME -f FILE [-a ATTRIBUTES]
+//
+// Description:
+//     efivar reads and writes UEFI variables through efivarfs, which must
+//     be mounted on /sys/firmware/efi/efivars. Variables are named by their
+//     vendor GUID and their name, e.g.
+//     8be4df61-93ca-11d2-aa0d-00e098032b8c-BootOrder.
+//
+// Options:
+//     -l: list the variables
+//     -p: print the attributes and value of variable -n
+//     -w: write variable -n, creating it if need be, with the contents of
+//         -f; it keeps the attributes it had, unless there is -a
+//     -n: the variable
+//     -f: the file to write the variable with, or - for stdin
+//     -a: the attributes to write the variable with, as a number; the
+//         default is 7, non-volatile and accessible at boot and run time
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/u-root/u-root/pkg/efivar"
+)
+
+var (
+	list  = flag.Bool("l", false, "list the variables")
+	dump  = flag.Bool("p", false, "print a variable")
+	write = flag.Bool("w", false, "write a variable")
+	name  = flag.String("n", "", "the variable, GUID-NAME")
+	file  = flag.String("f", "", "the file to write the variable with")
+	attr  = flag.String("a", "", "the attributes to write the variable with")
+)
+
+// parseName splits the GUID-NAME s.
+func parseName(s string) (name, guid string, err error) {
+	// GUIDs are 36 characters, with dashes in them.
+	if len(s) < 38 || s[36] != '-' {
+		return "", "", fmt.Errorf("variable %q is not GUID-NAME", s)
+	}
+	return s[37:], s[:36], nil
+}
+
+// listVars prints the names of the variables.
+func listVars(w io.Writer) error {
+	vars, err := efivar.List()
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s-%s\n", v.GUID, v.Name)
+	}
+	return nil
+}
+
+// printVar prints the variable s, GUID-NAME, as efivar -p does.
+func printVar(w io.Writer, s string) error {
+	name, guid, err := parseName(s)
+	if err != nil {
+		return err
+	}
+	v, err := efivar.Read(name, guid)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "GUID: %s\nName: %q\nAttributes:\n", v.GUID, v.Name)
+	for _, a := range efivar.AttrNames(v.Attr) {
+		fmt.Fprintf(w, "\t%s\n", a)
+	}
+	fmt.Fprintf(w, "Value:\n%s", hex.Dump(v.Data))
+	return nil
+}
+
+// writeVar writes the variable s, GUID-NAME, with data. If attrs is "",
+// it keeps the attributes it has.
+func writeVar(s string, data []byte, attrs string) error {
+	name, guid, err := parseName(s)
+	if err != nil {
+		return err
+	}
+	v := &efivar.Var{Name: name, GUID: guid, Attr: efivar.DefaultAttr, Data: data}
+	if attrs != "" {
+		a, err := strconv.ParseUint(attrs, 0, 32)
+		if err != nil {
+			return fmt.Errorf("bad attributes %q: %v", attrs, err)
+		}
+		v.Attr = uint32(a)
+	} else if old, err := efivar.Read(name, guid); err == nil {
+		v.Attr = old.Attr
+	}
+	return efivar.Write(v)
+}
+
+func main() {
+	flag.Parse()
+	var err error
+	switch {
+	case *list:
+		err = listVars(os.Stdout)
+	case *dump && *name != "":
+		err = printVar(os.Stdout, *name)
+	case *write && *name != "" && *file != "":
+		var data []byte
+		if *file == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(*file)
+		}
+		if err == nil {
+			err = writeVar(*name, data, *attr)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
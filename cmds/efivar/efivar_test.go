@@ -0,0 +1,83 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/efivar"
+)
+
+const bootOrder = efivar.GlobalGUID + "-BootOrder"
+
+func TestEfivar(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "efivar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	efivar.Dir = tmp
+
+	if err := writeVar(bootOrder, []byte{1, 0, 2, 0}, ""); err != nil {
+		t.Fatal(err)
+	}
+	// Writes keep the attributes, unless they are given.
+	if err := writeVar(efivar.GlobalGUID+"-Test", []byte("x"), "0x3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeVar(efivar.GlobalGUID+"-Test", []byte("hello"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := listVars(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := bootOrder + "\n" + efivar.GlobalGUID + "-Test\n"; b.String() != want {
+		t.Errorf("-l: got %q, want %q", b.String(), want)
+	}
+
+	for _, tt := range []struct {
+		name, want string
+	}{
+		{bootOrder, `GUID: 8be4df61-93ca-11d2-aa0d-00e098032b8c
+Name: "BootOrder"
+Attributes:
+	Non-Volatile
+	Boot Service Access
+	Runtime Service Access
+Value:
+00000000  01 00 02 00                                       |....|
+`},
+		{efivar.GlobalGUID + "-Test", `GUID: 8be4df61-93ca-11d2-aa0d-00e098032b8c
+Name: "Test"
+Attributes:
+	Non-Volatile
+	Boot Service Access
+Value:
+00000000  68 65 6c 6c 6f                                    |hello|
+`},
+	} {
+		b.Reset()
+		if err := printVar(&b, tt.name); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("-p -n %v: got\n%s\nwant\n%s", tt.name, b.String(), tt.want)
+		}
+	}
+
+	for _, bad := range []string{"BootOrder", "BootOrder-" + efivar.GlobalGUID} {
+		if err := printVar(ioutil.Discard, bad); err == nil {
+			t.Errorf("-p -n %v: got nil, want an error", bad)
+		}
+	}
+	if err := writeVar(bootOrder, nil, "x"); err == nil {
+		t.Errorf("-w -a x: got nil, want an error")
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A pred is a compiled expression. It is called for each file found.
+type pred func(path string, fi os.FileInfo) bool
+
+// parser turns the words of an expression into a pred.
+type parser struct {
+	args []string
+	// action is set if the expression prints or runs something, so
+	// the default -print is not wanted.
+	action bool
+	now    time.Time
+	out    io.Writer
+}
+
+// next returns the next word, or "" if there are none left.
+func (p *parser) next() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	a := p.args[0]
+	p.args = p.args[1:]
+	return a
+}
+
+func (p *parser) peek() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	return p.args[0]
+}
+
+// arg returns the argument of the primary op.
+func (p *parser) arg(op string) (string, error) {
+	if len(p.args) == 0 {
+		return "", fmt.Errorf("missing argument to %v", op)
+	}
+	return p.next(), nil
+}
+
+// parse parses the whole expression. An empty one is true.
+func (p *parser) parse() (pred, error) {
+	if len(p.args) == 0 {
+		return func(string, os.FileInfo) bool { return true }, nil
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.args) > 0 {
+		return nil, fmt.Errorf("unexpected %v", p.peek())
+	}
+	return e, nil
+}
+
+func (p *parser) or() (pred, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = func(l, r pred) pred {
+			return func(path string, fi os.FileInfo) bool {
+				return l(path, fi) || r(path, fi)
+			}
+		}(l, r)
+	}
+	return l, nil
+}
+
+// and parses terms joined by -a, or by nothing at all.
+func (p *parser) and() (pred, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", "-o", "-or", ")":
+			return l, nil
+		case "-a", "-and":
+			p.next()
+		}
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = func(l, r pred) pred {
+			return func(path string, fi os.FileInfo) bool {
+				return l(path, fi) && r(path, fi)
+			}
+		}(l, r)
+	}
+}
+
+func (p *parser) not() (pred, error) {
+	switch p.peek() {
+	case "!", "-not":
+		p.next()
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(path string, fi os.FileInfo) bool {
+			return !e(path, fi)
+		}, nil
+	case "(":
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return e, nil
+	}
+	return p.primary()
+}
+
+// fileTypes maps the letters of -type to file modes.
+var fileTypes = map[string]os.FileMode{
+	"f": 0,
+	"d": os.ModeDir,
+	"l": os.ModeSymlink,
+	"p": os.ModeNamedPipe,
+	"s": os.ModeSocket,
+	"c": os.ModeDevice | os.ModeCharDevice,
+	"b": os.ModeDevice,
+}
+
+// sizeUnits maps the suffixes of -size to their sizes in bytes.
+var sizeUnits = map[byte]int64{
+	'c': 1,
+	'w': 2,
+	'b': 512,
+	'k': 1024,
+	'M': 1024 * 1024,
+	'G': 1024 * 1024 * 1024,
+}
+
+// number parses a -mtime or -size argument, [+|-]N. It returns -1, 0 or
+// 1 for less than, equal to or more than N.
+func number(s string) (int, int64, error) {
+	cmp := 0
+	switch {
+	case strings.HasPrefix(s, "+"):
+		cmp, s = 1, s[1:]
+	case strings.HasPrefix(s, "-"):
+		cmp, s = -1, s[1:]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, 0, fmt.Errorf("invalid number %q", s)
+	}
+	return cmp, n, nil
+}
+
+func compare(cmp int, got, want int64) bool {
+	switch cmp {
+	case 1:
+		return got > want
+	case -1:
+		return got < want
+	}
+	return got == want
+}
+
+func (p *parser) primary() (pred, error) {
+	op := p.next()
+	switch op {
+	case "-print", "-print0":
+		p.action = true
+		end := "\n"
+		if op == "-print0" {
+			end = "\x00"
+		}
+		return func(path string, fi os.FileInfo) bool {
+			fmt.Fprint(p.out, path, end)
+			return true
+		}, nil
+
+	case "-name":
+		pat, err := p.arg(op)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("-name %v: %v", pat, err)
+		}
+		return func(path string, fi os.FileInfo) bool {
+			ok, _ := filepath.Match(pat, filepath.Base(path))
+			return ok
+		}, nil
+
+	case "-type":
+		t, err := p.arg(op)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := fileTypes[t]
+		if !ok {
+			return nil, fmt.Errorf("-type %v: unknown type", t)
+		}
+		return func(path string, fi os.FileInfo) bool {
+			return fi.Mode()&os.ModeType == m
+		}, nil
+
+	case "-mtime":
+		a, err := p.arg(op)
+		if err != nil {
+			return nil, err
+		}
+		cmp, n, err := number(a)
+		if err != nil {
+			return nil, fmt.Errorf("-mtime: %v", err)
+		}
+		return func(path string, fi os.FileInfo) bool {
+			days := int64(p.now.Sub(fi.ModTime()) / (24 * time.Hour))
+			return compare(cmp, days, n)
+		}, nil
+
+	case "-size":
+		a, err := p.arg(op)
+		if err != nil || a == "" {
+			return nil, fmt.Errorf("missing argument to %v", op)
+		}
+		unit := int64(512)
+		if u, ok := sizeUnits[a[len(a)-1]]; ok && len(a) > 1 {
+			unit, a = u, a[:len(a)-1]
+		}
+		cmp, n, err := number(a)
+		if err != nil {
+			return nil, fmt.Errorf("-size: %v", err)
+		}
+		return func(path string, fi os.FileInfo) bool {
+			// Sizes are rounded up to whole units.
+			return compare(cmp, (fi.Size()+unit-1)/unit, n)
+		}, nil
+
+	case "-exec":
+		p.action = true
+		var argv []string
+		for {
+			if len(p.args) == 0 {
+				return nil, fmt.Errorf("-exec: missing ;")
+			}
+			a := p.next()
+			if a == ";" {
+				break
+			}
+			argv = append(argv, a)
+		}
+		if len(argv) == 0 {
+			return nil, fmt.Errorf("-exec: missing command")
+		}
+		return func(path string, fi os.FileInfo) bool {
+			a := make([]string, len(argv))
+			for i, s := range argv {
+				a[i] = strings.Replace(s, "{}", path, -1)
+			}
+			c := exec.Command(a[0], a[1:]...)
+			c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+			return c.Run() == nil
+		}, nil
+
+	case "":
+		return nil, fmt.Errorf("missing expression")
+	}
+	return nil, fmt.Errorf("unknown primary %v", op)
+}
+
+// compile parses the expression in args. Unless it has an action in it,
+// files for which it is true are printed.
+func compile(args []string, out io.Writer) (pred, error) {
+	p := &parser{args: args, now: time.Now(), out: out}
+	e, err := p.parse()
+	if err != nil || p.action {
+		return e, err
+	}
+	return func(path string, fi os.FileInfo) bool {
+		if e(path, fi) {
+			fmt.Fprintln(out, path)
+			return true
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Find files.
+//
+// Synopsis:
+//     find [PATH]... [EXPRESSION]
+//
+// Description:
+//     Each PATH, or . if there is none, and all files below it are checked
+//     against EXPRESSION. Files for which it is true are printed, unless it
+//     has -print, -print0 or -exec in it. Symbolic links are not followed.
+//
+//     EXPRESSION is made of primaries, which may be combined with
+//     ( EXPR ), ! EXPR, EXPR -a EXPR and EXPR -o EXPR. -a binds tighter
+//     than -o, and may be left out.
+//
+// Primaries:
+//     -name PATTERN: the base name of the file matches the shell PATTERN
+//     -type C: the file is of type C: f, d, l, p, s, c or b
+//     -mtime [+-]N: the file was modified N days ago, more than N days ago
+//         with +, or less than N days ago with -
+//     -size [+-]N[cwbkMG]: the file is N units big, rounded up; units are
+//         bytes, 2-byte words, 512-byte blocks (the default), KiB, MiB or GiB
+//     -exec CMD ARGS... ;: run CMD with {} in ARGS replaced by the file
+//         name; true if it succeeds
+//     -print: print the file name and a newline
+//     -print0: print the file name and a NUL
+//
+// Example:
+//     find /lib/modules -name '*.ko' -print0 | xargs -0 ls -l
+//     find /tmp -type f -mtime +7 -exec rm {} ;
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isExpr reports whether a, the first argument which is not a path,
+// starts the expression.
+func isExpr(a string) bool {
+	return strings.HasPrefix(a, "-") || a == "!" || a == "("
+}
+
+func main() {
+	args := os.Args[1:]
+	var paths []string
+	for len(args) > 0 && !isExpr(args[0]) {
+		paths, args = append(paths, args[0]), args[1:]
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	e, err := compile(args, os.Stdout)
+	if err != nil {
+		log.Fatalf("find: %v", err)
+	}
+
+	status := 0
+	for _, p := range paths {
+		filepath.Walk(p, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				log.Printf("find: %v", err)
+				status = 1
+				return nil
+			}
+			e(path, fi)
+			return nil
+		})
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestFind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "a/b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for n, size := range map[string]int{"a/x.ko": 3, "a/b/y.ko": 3000, "a/old": 0} {
+		if err := ioutil.WriteFile(filepath.Join(tmpDir, n), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(tmpDir, "a/old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("x.ko", filepath.Join(tmpDir, "a/l")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		expr []string
+		out  string
+	}{
+		{nil, ".\na\na/b\na/b/y.ko\na/l\na/old\na/x.ko\n"},
+		{[]string{"-name", "*.ko"}, "a/b/y.ko\na/x.ko\n"},
+		{[]string{"-type", "d"}, ".\na\na/b\n"},
+		{[]string{"-type", "l", "-o", "-mtime", "+7"}, "a/l\na/old\n"},
+		{[]string{"-type", "f", "-mtime", "-1"}, "a/b/y.ko\na/x.ko\n"},
+		{[]string{"-type", "f", "-size", "+2"}, "a/b/y.ko\n"},
+		{[]string{"-size", "3c", "-a", "-type", "f"}, "a/x.ko\n"},
+		{[]string{"-size", "-1k", "-type", "f"}, "a/old\n"},
+		{[]string{"!", "(", "-type", "d", "-o", "-name", "*.ko", ")", "-print0"}, "a/l\x00a/old\x00"},
+		{[]string{"-name", "*.ko", "-print", "-print"}, "a/b/y.ko\na/b/y.ko\na/x.ko\na/x.ko\n"},
+		{[]string{"-name", "x*", "-exec", "true", "{}", ";", "-print"}, "a/x.ko\n"},
+		{[]string{"-name", "x*", "-exec", "false", ";", "-o", "-name", "l", "-print"}, "a/l\n"},
+	} {
+		var b bytes.Buffer
+		e, err := compile(tt.expr, &b)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		filepath.Walk(tmpDir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(tmpDir, path)
+			e(rel, fi)
+			return nil
+		})
+		if b.String() != tt.out {
+			t.Errorf("%q: got %q, want %q", tt.expr, b.String(), tt.out)
+		}
+	}
+}
+
+func TestBadExpr(t *testing.T) {
+	for _, expr := range [][]string{
+		{"-name"},
+		{"-type", "x"},
+		{"-size", "+k"},
+		{"-mtime", "a"},
+		{"-exec", "ls"},
+		{"(", "-print"},
+		{"-print", ")"},
+		{"-o"},
+		{"-bogus"},
+	} {
+		if _, err := compile(expr, ioutil.Discard); err == nil {
+			t.Errorf("%q: got nil, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Flashrom-lite reads, verifies and writes the firmware flash, as flashrom
+// does, through the kernel's MTD devices or spidev.
+//
+// Synopsis:
+//     flashrom-lite -p PROGRAMMER [-l LAYOUT -i REGION[,REGION...]] [-r|-w|-v FILE] [-E]
+//
+// Description:
+//     The programmer is the way to the flash chip:
+//         linux_mtd[:dev=N]                   /dev/mtdN, by default /dev/mtd0
+//         linux_spi:dev=DEV[,spispeed=KHZ]    the chip on the spidev device
+//                                             DEV, such as /dev/spidev0.0
+//     With no -r, -w, -v or -E, flashrom-lite prints the size of the chip.
+//
+//     The file is an image of the whole chip. Writing only erases and
+//     writes the blocks which change, then verifies them.
+//
+// Options:
+//     -p: the programmer
+//     -r: read the chip into FILE
+//     -w: write FILE to the chip
+//     -v: verify the chip is FILE
+//     -E: erase the chip
+//     -l: a layout: a line for each region of the chip, START:END NAME,
+//         with START and END in hex
+//     -i: only read, write, verify or erase these regions of the layout
+//
+// Example:
+//     $ flashrom-lite -p linux_mtd -l layout -i bios -w coreboot.rom
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/flash"
+)
+
+var (
+	programmer = flag.String("p", "", "the programmer: linux_mtd[:dev=N] or linux_spi:dev=DEV[,spispeed=KHZ]")
+	read       = flag.String("r", "", "read the chip into this file")
+	write      = flag.String("w", "", "write this file to the chip")
+	verify     = flag.String("v", "", "verify the chip is this file")
+	erase      = flag.Bool("E", false, "erase the chip")
+	layout     = flag.String("l", "", "the layout file")
+	include    = flag.String("i", "", "only these regions of the layout, separated by commas")
+)
+
+// open opens the flash chip of programmer p.
+func open(p string) (flash.Flash, error) {
+	name, params := p, ""
+	if i := strings.Index(p, ":"); i >= 0 {
+		name, params = p[:i], p[i+1:]
+	}
+	opts := map[string]string{}
+	for _, kv := range strings.Split(params, ",") {
+		if kv == "" {
+			continue
+		}
+		f := strings.SplitN(kv, "=", 2)
+		if len(f) != 2 {
+			return nil, fmt.Errorf("programmer parameter %q is not NAME=VALUE", kv)
+		}
+		opts[f[0]] = f[1]
+	}
+	switch name {
+	case "linux_mtd":
+		dev := "0"
+		if d, ok := opts["dev"]; ok {
+			dev = d
+		}
+		if _, err := strconv.ParseUint(dev, 10, 32); err != nil {
+			return nil, fmt.Errorf("linux_mtd: dev %q is not a number", dev)
+		}
+		return flash.OpenMTD("/dev/mtd" + dev)
+	case "linux_spi":
+		dev, ok := opts["dev"]
+		if !ok {
+			return nil, fmt.Errorf("linux_spi: no dev")
+		}
+		var khz uint64
+		if s, ok := opts["spispeed"]; ok {
+			var err error
+			if khz, err = strconv.ParseUint(s, 10, 32); err != nil {
+				return nil, fmt.Errorf("linux_spi: spispeed: %v", err)
+			}
+		}
+		return flash.OpenSPI(dev, uint32(khz*1000))
+	}
+	return nil, fmt.Errorf("unknown programmer %q: it is linux_mtd or linux_spi", name)
+}
+
+// regions returns the regions named in include, in the layout l, or the
+// whole chip of size if none are.
+func regions(l flash.Layout, include string, size int64) ([]flash.Region, error) {
+	if include == "" {
+		return []flash.Region{{Name: "all", Start: 0, End: size - 1}}, nil
+	}
+	var rs []flash.Region
+	for _, n := range strings.Split(include, ",") {
+		r, err := l.Find(n)
+		if err != nil {
+			return nil, err
+		}
+		if r.End >= size {
+			return nil, fmt.Errorf("region %v ends at %#x, past the end of the %#x byte chip", r.Name, r.End, size)
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// readImage reads the regions rs of f into an image of the whole chip.
+// What is not in them is 0xff, as erased flash is.
+func readImage(f flash.Flash, rs []flash.Region) ([]byte, error) {
+	img := bytes.Repeat([]byte{0xff}, int(f.Size()))
+	for _, r := range rs {
+		b, err := flash.Read(f, r.Start, r.Len())
+		if err != nil {
+			return nil, err
+		}
+		copy(img[r.Start:], b)
+	}
+	return img, nil
+}
+
+// checkImage returns an error if img is not an image of the whole of f.
+func checkImage(f flash.Flash, img []byte) error {
+	if int64(len(img)) != f.Size() {
+		return fmt.Errorf("image is %#x bytes, but the chip is %#x", len(img), f.Size())
+	}
+	return nil
+}
+
+// writeImage writes the regions rs of img to f.
+func writeImage(f flash.Flash, rs []flash.Region, img []byte) error {
+	if err := checkImage(f, img); err != nil {
+		return err
+	}
+	for _, r := range rs {
+		if err := flash.Write(f, r.Start, img[r.Start:r.End+1]); err != nil {
+			return fmt.Errorf("region %v: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// verifyImage verifies the regions rs of f are img.
+func verifyImage(f flash.Flash, rs []flash.Region, img []byte) error {
+	if err := checkImage(f, img); err != nil {
+		return err
+	}
+	for _, r := range rs {
+		if err := flash.Verify(f, r.Start, img[r.Start:r.End+1]); err != nil {
+			return fmt.Errorf("region %v: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// eraseRegions erases the regions rs of f.
+func eraseRegions(f flash.Flash, rs []flash.Region) error {
+	for _, r := range rs {
+		if err := flash.Erase(f, r.Start, r.Len()); err != nil {
+			return fmt.Errorf("region %v: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func run(f flash.Flash, rs []flash.Region) error {
+	switch {
+	case *read != "":
+		img, err := readImage(f, rs)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(*read, img, 0644)
+	case *write != "":
+		img, err := ioutil.ReadFile(*write)
+		if err != nil {
+			return err
+		}
+		return writeImage(f, rs, img)
+	case *verify != "":
+		img, err := ioutil.ReadFile(*verify)
+		if err != nil {
+			return err
+		}
+		return verifyImage(f, rs, img)
+	case *erase:
+		return eraseRegions(f, rs)
+	}
+	fmt.Printf("%#x bytes, erased in blocks of %#x\n", f.Size(), f.EraseSize())
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if *programmer == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *include != "" && *layout == "" {
+		log.Fatal("-i needs a layout, -l")
+	}
+	var l flash.Layout
+	if *layout != "" {
+		lf, err := os.Open(*layout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		l, err = flash.ParseLayout(lf)
+		lf.Close()
+		if err != nil {
+			log.Fatalf("%v: %v", *layout, err)
+		}
+	}
+	f, err := open(*programmer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	rs, err := regions(l, *include, f.Size())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := run(f, rs); err != nil {
+		log.Fatal(err)
+	}
+}
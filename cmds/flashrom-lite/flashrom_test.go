@@ -0,0 +1,120 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/flash"
+)
+
+// memFlash is a flash.Flash in memory.
+type memFlash []byte
+
+func (m memFlash) ReadAt(b []byte, off int64) (int, error) { return copy(b, m[off:]), nil }
+func (m memFlash) Size() int64                             { return int64(len(m)) }
+func (m memFlash) EraseSize() int64                        { return 16 }
+func (m memFlash) Close() error                            { return nil }
+
+func (m memFlash) WriteAt(b []byte, off int64) (int, error) {
+	for i, c := range b {
+		m[off+int64(i)] &= c
+	}
+	return len(b), nil
+}
+
+func (m memFlash) Erase(off, n int64) error {
+	copy(m[off:off+n], bytes.Repeat([]byte{0xff}, int(n)))
+	return nil
+}
+
+func region(name string, start, end int64) flash.Region {
+	return flash.Region{Name: name, Start: start, End: end}
+}
+
+func TestRegions(t *testing.T) {
+	l := flash.Layout{region("fd", 0, 0xf), region("bios", 0x20, 0x3f), region("big", 0, 0x40)}
+	for _, tt := range []struct {
+		include string
+		want    []flash.Region
+		err     string
+	}{
+		{"", []flash.Region{region("all", 0, 0x3f)}, ""},
+		{"bios,fd", []flash.Region{region("bios", 0x20, 0x3f), region("fd", 0, 0xf)}, ""},
+		{"me", nil, `no region "me"`},
+		{"big", nil, "past the end"},
+	} {
+		rs, err := regions(l, tt.include, 0x40)
+		if !reflect.DeepEqual(rs, tt.want) || (err == nil) != (tt.err == "") || err != nil && !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("regions(%q): got %v, %v, want %v, %q", tt.include, rs, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestImage(t *testing.T) {
+	m := make(memFlash, 0x40)
+	for i := range m {
+		m[i] = byte(i)
+	}
+	rs := []flash.Region{region("fd", 0, 0xf), region("bios", 0x20, 0x3f)}
+
+	img, err := readImage(m, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte(nil), m...)
+	copy(want[0x10:0x20], bytes.Repeat([]byte{0xff}, 0x10))
+	if !bytes.Equal(img, want) {
+		t.Errorf("readImage: got % x, want % x", img, want)
+	}
+	if err := verifyImage(m, rs, img); err != nil {
+		t.Errorf("verifyImage: got %v, want nil", err)
+	}
+	if err := verifyImage(m, []flash.Region{region("me", 0x10, 0x1f)}, img); err == nil {
+		t.Errorf("verifyImage of a region not read: got nil, want error")
+	}
+
+	// Writing the bios region leaves the rest alone.
+	for i := range img {
+		img[i] = 0xaa
+	}
+	if err := writeImage(m, rs[1:], img); err != nil {
+		t.Fatal(err)
+	}
+	copy(want[0x10:0x20], m[0x10:0x20])
+	copy(want[0x20:], img[0x20:])
+	if !bytes.Equal(m, want) {
+		t.Errorf("writeImage: got % x, want % x", []byte(m), want)
+	}
+	if err := writeImage(m, rs, img[:0x20]); err == nil {
+		t.Errorf("writeImage of half an image: got nil, want error")
+	}
+
+	if err := eraseRegions(m, rs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m[:0x10], bytes.Repeat([]byte{0xff}, 0x10)) || m[0x10] != 0x10 {
+		t.Errorf("eraseRegions: got % x, want fd erased", []byte(m))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, tt := range []struct {
+		p, err string
+	}{
+		{"internal", "unknown programmer"},
+		{"linux_mtd:dev=x", "not a number"},
+		{"linux_mtd:dev", "not NAME=VALUE"},
+		{"linux_spi", "no dev"},
+		{"linux_spi:dev=/dev/spidev0.0,spispeed=fast", "spispeed"},
+	} {
+		if _, err := open(tt.p); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("open(%q): got %v, want %q", tt.p, err, tt.err)
+		}
+	}
+}
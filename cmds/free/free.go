@@ -0,0 +1,107 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Free prints how much memory and swap is used and free.
+//
+// Synopsis:
+//     free [-b|-k|-m|-g|-h] [-s SECONDS [-c COUNT]]
+//
+// Description:
+//     free prints the total, used, free, shared, buffer and cache, and
+//     available memory, and the total, used and free swap, from
+//     /proc/meminfo, in KiB by default.
+//
+// Options:
+//     -b: print bytes
+//     -k: print KiB
+//     -m: print MiB
+//     -g: print GiB
+//     -h: print each in the largest unit it is at least one of, e.g. 1.5Gi
+//     -s: print again every SECONDS
+//     -c: print COUNT times, with -s
+//
+// Example:
+//     $ free -h
+//                   total        used        free      shared  buff/cache   available
+//     Mem:          5.9Gi       361Mi       3.7Gi       9.0Mi       1.8Gi       5.2Gi
+//     Swap:            0B          0B          0B
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/u-root/u-root/pkg/meminfo"
+)
+
+var (
+	inBytes = flag.Bool("b", false, "print bytes")
+	inKiB   = flag.Bool("k", false, "print KiB")
+	inMiB   = flag.Bool("m", false, "print MiB")
+	inGiB   = flag.Bool("g", false, "print GiB")
+	human   = flag.Bool("h", false, "print sizes in the largest unit they are at least one of")
+	seconds = flag.Float64("s", 0, "print again every `seconds`")
+	count   = flag.Int("c", 0, "print `count` times, with -s")
+)
+
+// unit returns the unit of -b, -k, -m and -g, or "" for -h, or an error if
+// there is more than one.
+func unit() (uint64, error) {
+	var (
+		u uint64 = 1024
+		n int
+	)
+	for _, f := range []struct {
+		set  bool
+		unit uint64
+	}{{*inBytes, 1}, {*inKiB, 1024}, {*inMiB, 1 << 20}, {*inGiB, 1 << 30}, {*human, 0}} {
+		if f.set {
+			u = f.unit
+			n++
+		}
+	}
+	if n > 1 {
+		return 0, fmt.Errorf("only one of -b, -k, -m, -g and -h")
+	}
+	return u, nil
+}
+
+// free prints m in unit, or for -h, a unit of 0, in human units.
+func free(w io.Writer, m meminfo.Meminfo, unit uint64) {
+	f := func(b uint64) string {
+		if unit == 0 {
+			return meminfo.Human(b)
+		}
+		return fmt.Sprint(b / unit)
+	}
+	swapUsed := m["SwapTotal"] - m["SwapFree"]
+	fmt.Fprintf(w, "%-7s%12s%12s%12s%12s%12s%12s\n", "", "total", "used", "free", "shared", "buff/cache", "available")
+	fmt.Fprintf(w, "%-7s%12s%12s%12s%12s%12s%12s\n", "Mem:", f(m["MemTotal"]), f(m.Used()), f(m["MemFree"]), f(m["Shmem"]), f(m.BuffCache()), f(m.Available()))
+	fmt.Fprintf(w, "%-7s%12s%12s%12s\n", "Swap:", f(m["SwapTotal"]), f(swapUsed), f(m["SwapFree"]))
+}
+
+func main() {
+	flag.Parse()
+	u, err := unit()
+	if err != nil || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	for i := 1; ; i++ {
+		m, err := meminfo.Read()
+		if err != nil {
+			log.Fatal(err)
+		}
+		free(os.Stdout, m, u)
+		if *seconds <= 0 || i == *count {
+			return
+		}
+		fmt.Println()
+		time.Sleep(time.Duration(*seconds * float64(time.Second)))
+	}
+}
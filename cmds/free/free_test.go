@@ -0,0 +1,63 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/meminfo"
+)
+
+func TestFree(t *testing.T) {
+	m := meminfo.Meminfo{
+		"MemTotal":     6 << 30,
+		"MemFree":      3 << 30,
+		"MemAvailable": 5 << 30,
+		"Buffers":      256 << 20,
+		"Cached":       768 << 20,
+		"Shmem":        9 << 20,
+		"SwapTotal":    1 << 30,
+		"SwapFree":     1<<30 - 512<<10,
+	}
+	for _, tt := range []struct {
+		unit uint64
+		want string
+	}{
+		{1024, `              total        used        free      shared  buff/cache   available
+Mem:        6291456     2097152     3145728        9216     1048576     5242880
+Swap:       1048576         512     1048064
+`},
+		{1 << 20, `              total        used        free      shared  buff/cache   available
+Mem:           6144        2048        3072           9        1024        5120
+Swap:          1024           0        1023
+`},
+		{0, `              total        used        free      shared  buff/cache   available
+Mem:          6.0Gi       2.0Gi       3.0Gi       9.0Mi       1.0Gi       5.0Gi
+Swap:         1.0Gi       512Ki       1.0Gi
+`},
+	} {
+		var b bytes.Buffer
+		free(&b, m, tt.unit)
+		if b.String() != tt.want {
+			t.Errorf("free in %d: got\n%s\nwant\n%s", tt.unit, b.String(), tt.want)
+		}
+	}
+}
+
+func TestUnit(t *testing.T) {
+	defer func() { *inMiB, *human = false, false }()
+	if u, err := unit(); err != nil || u != 1024 {
+		t.Errorf("unit(): got %d, %v, want 1024, nil", u, err)
+	}
+	*inMiB = true
+	if u, err := unit(); err != nil || u != 1<<20 {
+		t.Errorf("unit() with -m: got %d, %v, want %d, nil", u, err, 1<<20)
+	}
+	*human = true
+	if _, err := unit(); err == nil {
+		t.Errorf("unit() with -m and -h: got nil, want an error")
+	}
+}
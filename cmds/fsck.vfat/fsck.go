@@ -0,0 +1,115 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Fsck.vfat checks FAT12, FAT16 and FAT32 file systems.
+//
+// Synopsis:
+//     fsck.vfat [-a|-p|-n] [-v] DEV
+//
+// Description:
+//     fsck.vfat checks the boot sector of the file system on DEV, that its
+//     FATs are the same, and that the chains of clusters of its files and
+//     directories are whole, do not cross, and are as long as the files;
+//     and it counts the clusters which are used but in no chain. It only
+//     reports what is wrong, except that, with -a, it clears the bits which
+//     say the file system was not unmounted cleanly.
+//
+//     It exits as the other fscks do: 0 if nothing is wrong, 1 if what was
+//     wrong was fixed, 4 if something wrong is left, and 8 if it could not
+//     check the file system.
+//
+// Options:
+//     -a: fix what can be fixed
+//     -p: the same as -a
+//     -n: fix nothing, which is the default
+//     -v: say so when nothing is wrong
+//
+// Example:
+//     $ fsck.vfat -a /dev/sda1
+//     /dev/sda1: fixed: the boot sector says the file system was not unmounted cleanly
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/fat"
+)
+
+var fix, noFix, verbose bool
+
+func init() {
+	flag.BoolVar(&fix, "a", false, "fix what can be fixed")
+	flag.BoolVar(&fix, "p", false, "the same as -a")
+	flag.BoolVar(&noFix, "n", false, "fix nothing")
+	flag.BoolVar(&verbose, "v", false, "say when nothing is wrong")
+}
+
+// The exit statuses of fsck.
+const (
+	exitOK      = 0
+	exitFixed   = 1
+	exitErrors  = 4
+	exitFailure = 8
+)
+
+// fsck checks the file system on dev, and returns the exit status.
+func fsck(w io.Writer, dev string, repair bool) int {
+	mode := os.O_RDONLY
+	if repair {
+		mode = os.O_RDWR
+	}
+	f, err := os.OpenFile(dev, mode, 0)
+	if err != nil {
+		log.Print(err)
+		return exitFailure
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		log.Print(err)
+		return exitFailure
+	}
+	var fw io.WriterAt
+	if repair {
+		fw = f
+	}
+	r, err := fat.Check(f, fw, size)
+	if err != nil {
+		log.Printf("%v: %v", dev, err)
+		return exitFailure
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "%v: %v\n", dev, e)
+	}
+	for _, e := range r.Fixed {
+		fmt.Fprintf(w, "%v: fixed: %v\n", dev, e)
+	}
+	switch {
+	case len(r.Errors) > 0:
+		return exitErrors
+	case len(r.Fixed) > 0:
+		if err := f.Sync(); err != nil {
+			log.Print(err)
+			return exitFailure
+		}
+		return exitFixed
+	}
+	if verbose {
+		fmt.Fprintf(w, "%v: clean\n", dev)
+	}
+	return exitOK
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(exitFailure)
+	}
+	os.Exit(fsck(os.Stdout, flag.Arg(0), fix && !noFix))
+}
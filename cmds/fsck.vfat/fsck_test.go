@@ -0,0 +1,58 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/fat"
+)
+
+func TestFsck(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "fsck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dev := filepath.Join(tmp, "dev")
+	f, err := os.Create(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(64 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := fat.Format(f, 64<<20, fat.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	// Mark it mounted, as Linux does.
+	if _, err := f.WriteAt([]byte{1}, 65); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	const dirty = "the boot sector says the file system was not unmounted cleanly"
+	for _, tt := range []struct {
+		repair bool
+		status int
+		out    string
+	}{
+		{false, exitErrors, dev + ": " + dirty + "\n"},
+		{true, exitFixed, dev + ": fixed: " + dirty + "\n"},
+		{false, exitOK, ""},
+	} {
+		var b bytes.Buffer
+		if got := fsck(&b, dev, tt.repair); got != tt.status || b.String() != tt.out {
+			t.Errorf("fsck(%v): got %d, %q, want %d, %q", tt.repair, got, b.String(), tt.status, tt.out)
+		}
+	}
+	if got := fsck(ioutil.Discard, filepath.Join(tmp, "missing"), false); got != exitFailure {
+		t.Errorf("fsck of a missing device: got %d, want %d", got, exitFailure)
+	}
+}
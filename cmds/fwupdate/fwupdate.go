@@ -0,0 +1,320 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Fwupdate updates an A/B pair of partitions, and rolls them back.
+//
+// Synopsis:
+//     fwupdate [-d DISK] [-a PART] [-b PART] [status]
+//     fwupdate [-d DISK] [-a PART] [-b PART] [-k KEY] [-t TRIES] apply IMAGE
+//     fwupdate [-d DISK] [-a PART] [-b PART] boot|good|rollback
+//
+// Description:
+//     Two GPT partitions of DISK, slots A and B, hold the firmware or OS,
+//     and the one which is not booted is updated. Which to boot is in the
+//     attributes of the partitions, as ChromeOS keeps it: a priority, 0 to
+//     15, a count of tries left, and whether the slot booted successfully.
+//     The slot with the highest priority which succeeded or has tries
+//     left is booted.
+//
+//     status prints the slots; the one which boots next has a *.
+//
+//     apply checks IMAGE.sig is KEY's ed25519 signature of the SHA-256 of
+//     IMAGE, writes IMAGE to the slot which is not booted, checks the
+//     hash of what was written, then makes it the slot to boot, with TRIES
+//     tries.
+//
+//     boot is for the boot loader: it prints the partition to boot, and
+//     takes a try from it if it has not booted successfully yet. A slot
+//     with no tries left is given priority 0, so the other is booted: that
+//     is the roll back if an update does not boot.
+//
+//     good marks the slot booted as booted successfully, once it is up.
+//
+//     rollback makes the other slot the one to boot, if it booted
+//     successfully.
+//
+// Options:
+//     -d: the disk (default /dev/sda)
+//     -a: the partition of slot A (default 2)
+//     -b: the partition of slot B (default 3)
+//     -k: the ed25519 public key (default /etc/fwupdate.pub)
+//     -t: the tries of the new slot (default 3)
+//
+// Example:
+//     $ fwupdate apply image
+//     $ fwupdate
+//     slot part priority tries successful
+//     B*      3        2     3 no
+//     A       2        1     0 yes
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/gpt"
+	"golang.org/x/crypto/ed25519"
+)
+
+var (
+	disk  = flag.String("d", "/dev/sda", "the disk")
+	partA = flag.Int("a", 2, "the partition of slot A")
+	partB = flag.Int("b", 3, "the partition of slot B")
+	key   = flag.String("k", "/etc/fwupdate.pub", "the ed25519 public key")
+	tries = flag.Uint("t", 3, "the tries of the new slot")
+)
+
+// The bits of the partition attributes which ChromeOS boots by.
+const (
+	priorityShift = 48
+	triesShift    = 52
+	successful    = 1 << 56
+)
+
+// A slot is one of the A/B pair of partitions.
+type slot struct {
+	name string
+	part int
+	p    *gpt.Part
+}
+
+func (s *slot) priority() uint {
+	return uint(s.p.Attribute>>priorityShift) & 0xf
+}
+
+func (s *slot) tries() uint {
+	return uint(s.p.Attribute>>triesShift) & 0xf
+}
+
+func (s *slot) successful() bool {
+	return s.p.Attribute&successful != 0
+}
+
+// set sets the priority, tries and successful bit of s.
+func (s *slot) set(priority, tries uint, ok bool) {
+	a := s.p.Attribute &^ (0x1ff << priorityShift)
+	a |= gpt.PartAttr(priority&0xf)<<priorityShift | gpt.PartAttr(tries&0xf)<<triesShift
+	if ok {
+		a |= successful
+	}
+	s.p.Attribute = a
+}
+
+// bootable returns whether s can be booted.
+func (s *slot) bootable() bool {
+	return s.priority() > 0 && (s.successful() || s.tries() > 0)
+}
+
+// slots returns slots A and B of g.
+func slots(g *gpt.GPT, a, b int) ([2]*slot, error) {
+	var ss [2]*slot
+	for i, n := range []int{a, b} {
+		if n < 1 || n > len(g.Parts) || g.Parts[n-1].FirstLBA == 0 {
+			return ss, fmt.Errorf("no partition %d", n)
+		}
+		ss[i] = &slot{name: "AB"[i : i+1], part: n, p: &g.Parts[n-1]}
+	}
+	return ss, nil
+}
+
+// current returns the slot which boots, or is booted: the one with the
+// highest priority, and the other. If neither has a priority, it is nil.
+func current(ss [2]*slot) (*slot, *slot) {
+	if ss[1].priority() > ss[0].priority() {
+		return ss[1], ss[0]
+	}
+	if ss[0].priority() > 0 {
+		return ss[0], ss[1]
+	}
+	return nil, ss[0]
+}
+
+// next returns the slot to boot next, or nil if neither can be.
+func next(ss [2]*slot) *slot {
+	var n *slot
+	for _, s := range ss {
+		if s.bootable() && (n == nil || s.priority() > n.priority()) {
+			n = s
+		}
+	}
+	return n
+}
+
+func status(w io.Writer, ss [2]*slot) {
+	n := next(ss)
+	fmt.Fprintf(w, "slot part priority tries successful\n")
+	for _, s := range ss {
+		mark, ok := " ", "no"
+		if s == n {
+			mark = "*"
+		}
+		if s.successful() {
+			ok = "yes"
+		}
+		fmt.Fprintf(w, "%s%s   %4d %8d %5d %s\n", s.name, mark, s.part, s.priority(), s.tries(), ok)
+	}
+}
+
+// verify checks sig is key's signature of the SHA-256 of image.
+func verify(key, image, sig []byte) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("key is %d bytes, not %d", len(key), ed25519.PublicKeySize)
+	}
+	h := sha256.Sum256(image)
+	if !ed25519.Verify(key, h[:], sig) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+// apply writes image to the slot which is not booted, checks it, and
+// makes it the one to boot, with tries tries.
+func apply(f io.ReaderAt, w io.WriterAt, ss [2]*slot, image []byte, tries uint) error {
+	cur, other := current(ss)
+	size := int64(other.p.LastLBA-other.p.FirstLBA+1) * gpt.BlockSize
+	if int64(len(image)) > size {
+		return fmt.Errorf("image is %d bytes, but slot %v is %d", len(image), other.name, size)
+	}
+	off := int64(other.p.FirstLBA) * gpt.BlockSize
+	if _, err := w.WriteAt(image, off); err != nil {
+		return fmt.Errorf("writing slot %v: %v", other.name, err)
+	}
+	got := make([]byte, len(image))
+	if _, err := f.ReadAt(got, off); err != nil {
+		return fmt.Errorf("reading slot %v back: %v", other.name, err)
+	}
+	if want, h := sha256.Sum256(image), sha256.Sum256(got); !bytes.Equal(h[:], want[:]) {
+		return fmt.Errorf("slot %v: SHA-256 is %x, want %x", other.name, h, want)
+	}
+	if cur != nil {
+		cur.set(1, cur.tries(), cur.successful())
+	}
+	other.set(2, tries, false)
+	return nil
+}
+
+// boot returns the slot to boot, and takes a try from it.
+func boot(ss [2]*slot) (*slot, error) {
+	for _, s := range ss {
+		if !s.bootable() {
+			s.set(0, s.tries(), s.successful())
+		}
+	}
+	n := next(ss)
+	if n == nil {
+		return nil, errors.New("no slot can be booted")
+	}
+	if !n.successful() {
+		n.set(n.priority(), n.tries()-1, false)
+	}
+	return n, nil
+}
+
+// good marks the slot booted as booted successfully.
+func good(ss [2]*slot) (*slot, error) {
+	cur, _ := current(ss)
+	if cur == nil {
+		return nil, errors.New("no slot was booted")
+	}
+	cur.set(cur.priority(), 0, true)
+	return cur, nil
+}
+
+// rollback makes the other slot the one to boot.
+func rollback(ss [2]*slot) (*slot, error) {
+	cur, other := current(ss)
+	if cur == nil || !other.successful() {
+		return nil, fmt.Errorf("slot %v has not booted successfully, so can not be rolled back to", other.name)
+	}
+	cur.set(0, cur.tries(), cur.successful())
+	if other.priority() == 0 {
+		other.set(1, other.tries(), true)
+	}
+	return other, nil
+}
+
+// run does cmd to the slots on f.
+func run(f *os.File, cmd string, args []string) error {
+	g, err := gpt.Table(f, gpt.HeaderOff)
+	if err != nil {
+		return err
+	}
+	ss, err := slots(g, *partA, *partB)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "status":
+		status(os.Stdout, ss)
+		return nil
+	case "apply":
+		if len(args) != 1 {
+			return errors.New("usage: fwupdate apply IMAGE")
+		}
+		k, err := ioutil.ReadFile(*key)
+		if err != nil {
+			return err
+		}
+		image, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		sig, err := ioutil.ReadFile(args[0] + ".sig")
+		if err != nil {
+			return err
+		}
+		if err := verify(k, image, sig); err != nil {
+			return fmt.Errorf("%v: %v", args[0], err)
+		}
+		if err := apply(f, f, ss, image, *tries); err != nil {
+			return err
+		}
+	case "boot":
+		s, err := boot(ss)
+		if err != nil {
+			return err
+		}
+		fmt.Println(s.part)
+	case "good":
+		if _, err := good(ss); err != nil {
+			return err
+		}
+	case "rollback":
+		if _, err := rollback(ss); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	if err := gpt.Write(f, g); err != nil {
+		return err
+	}
+	if err := gpt.Write(f, gpt.Backup(g)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func main() {
+	flag.Parse()
+	cmd, args := "status", flag.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	f, err := os.OpenFile(*disk, os.O_RDWR, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := run(f, cmd, args); err != nil {
+		log.Fatal(err)
+	}
+}
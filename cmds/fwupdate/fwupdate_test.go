@@ -0,0 +1,157 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/gpt"
+	"golang.org/x/crypto/ed25519"
+)
+
+// fakeDisk writes a 4MiB disk image with an EFI system partition, then
+// slots A and B.
+func fakeDisk(t *testing.T, name string) {
+	const blocks = 4 << 20 / gpt.BlockSize
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(blocks * gpt.BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gpt.Create(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range []struct {
+		t           string
+		first, last uint64
+	}{{"efi", 2048, 4095}, {"linux", 4096, 5119}, {"linux", 5120, 6143}} {
+		if _, err := g.AddPart(gpt.PartTypes[p.t], p.first, p.last, "EAB"[i:i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A is installed, and has booted.
+	g.Parts[1].Attribute = 1<<priorityShift | successful
+	if err := gpt.Write(f, g); err != nil {
+		t.Fatal(err)
+	}
+	if err := gpt.Write(f, gpt.Backup(g)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// do runs cmd on disk, and returns the slots after it.
+func do(t *testing.T, disk, cmd string, args ...string) ([2]*slot, error) {
+	f, err := os.OpenFile(disk, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = run(f, cmd, args)
+	g, _, gerr := gpt.New(f)
+	if gerr != nil {
+		t.Fatalf("after %v: %v", cmd, gerr)
+	}
+	ss, serr := slots(g, 2, 3)
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	return ss, err
+}
+
+// check checks the priority, tries and successful bit of a slot.
+func check(t *testing.T, when string, s *slot, priority, tries uint, ok bool) {
+	if s.priority() != priority || s.tries() != tries || s.successful() != ok {
+		t.Errorf("%v: slot %v is priority %d, tries %d, successful %v, want %d, %d, %v", when, s.name,
+			s.priority(), s.tries(), s.successful(), priority, tries, ok)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "fwupdate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	disk := filepath.Join(tmp, "disk")
+	fakeDisk(t, disk)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*key = filepath.Join(tmp, "key.pub")
+	if err := ioutil.WriteFile(*key, pub, 0644); err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.Join(tmp, "image")
+	img := bytes.Repeat([]byte("u-root"), 1000)
+	if err := ioutil.WriteFile(image, img, 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(img)
+	if err := ioutil.WriteFile(image+".sig", ed25519.Sign(priv, h[:]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := do(t, disk, "apply", image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, "apply", ss[0], 1, 0, true)
+	check(t, "apply", ss[1], 2, 3, false)
+	b, err := ioutil.ReadFile(disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[5120*gpt.BlockSize:][:len(img)], img) {
+		t.Errorf("apply: image is not in slot B")
+	}
+
+	// B fails to boot 3 times, so A is booted.
+	for i := 0; i < 4; i++ {
+		if ss, err = do(t, disk, "boot"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check(t, "boot", ss[0], 1, 0, true)
+	check(t, "boot", ss[1], 0, 0, false)
+
+	// Then it works.
+	if _, err = do(t, disk, "apply", image); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = do(t, disk, "boot"); err != nil {
+		t.Fatal(err)
+	}
+	if ss, err = do(t, disk, "good"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, "good", ss[0], 1, 0, true)
+	check(t, "good", ss[1], 2, 0, true)
+
+	if ss, err = do(t, disk, "rollback"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, "rollback", ss[0], 1, 0, true)
+	check(t, "rollback", ss[1], 0, 0, true)
+
+	// A bad signature changes nothing.
+	if err := ioutil.WriteFile(image, append(img, '!'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ss, err = do(t, disk, "apply", image); err == nil {
+		t.Errorf("apply with a bad signature: got nil, want error")
+	}
+	check(t, "bad apply", ss[1], 0, 0, true)
+}
@@ -0,0 +1,205 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Open a terminal and start a shell, or a login program, on it.
+//
+// Synopsis:
+//     getty [-l LOGIN] [-shell SHELL] [-issue FILE] TTY [BAUD [TERM]]
+//
+// Description:
+//     getty makes TTY, e.g. ttyS0 or /dev/ttyS0, its controlling terminal
+//     and stdin, stdout and stderr. It sets its speed to BAUD and puts it
+//     in cooked mode, ignoring the modem lines, then prints the issue
+//     file and runs SHELL.
+//
+//     With -l, it asks for a user name and runs LOGIN with it instead.
+//
+//     These escapes in the issue file are replaced: \n by the host name,
+//     \l by the name of the tty, \s, \r, \v and \m by the system name,
+//     release, version and machine, \d by the date and \t by the time.
+//
+//     Init runs getty, and runs it again each time it exits, on the ttys
+//     in uroot.getty=TTY[:BAUD][,TTY[:BAUD]...] on the kernel command
+//     line.
+//
+// Options:
+//     -l: login program, which is given the user name
+//     -shell: shell to run without -l
+//     -issue: issue file
+//
+// Example:
+//     getty ttyS0 115200 vt100
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	login = flag.String("l", "", "login `program`, given the user name")
+	shell = flag.String("shell", "/buildbin/rush", "shell to run without -l")
+	issue = flag.String("issue", "/etc/issue", "issue `file`")
+)
+
+// expandIssue replaces the escapes \C of s by vals[C]. \\ is a backslash;
+// other escapes are left.
+func expandIssue(s string, vals map[byte]string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if v, ok := vals[s[i]]; ok {
+			b.WriteString(v)
+		} else if s[i] == '\\' {
+			b.WriteByte('\\')
+		} else {
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func utsString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// issueValues returns the values of the escapes of the issue file, for
+// the tty.
+func issueValues(tty string) map[byte]string {
+	now := time.Now()
+	vals := map[byte]string{
+		'l': filepath.Base(tty),
+		'd': now.Format("Mon Jan 2 2006"),
+		't': now.Format("15:04:05"),
+	}
+	var u unix.Utsname
+	if err := unix.Uname(&u); err == nil {
+		vals['s'] = utsString(u.Sysname[:])
+		vals['n'] = utsString(u.Nodename[:])
+		vals['r'] = utsString(u.Release[:])
+		vals['v'] = utsString(u.Version[:])
+		vals['m'] = utsString(u.Machine[:])
+	}
+	return vals
+}
+
+// openTTY makes the tty the controlling terminal, and stdin, stdout and
+// stderr, of a new session.
+func openTTY(tty string) error {
+	// This fails if we already lead a session, which is fine.
+	syscall.Setsid()
+	// Without O_NONBLOCK, the open would wait for the carrier.
+	fd, err := unix.Open(tty, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return fmt.Errorf("%v: %v", tty, err)
+	}
+	if err := unix.SetNonblock(fd, false); err != nil {
+		return err
+	}
+	if err := unix.IoctlSetInt(fd, unix.TIOCSCTTY, 1); err != nil {
+		return fmt.Errorf("%v: can not make it the controlling tty: %v", tty, err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := unix.Dup3(fd, i, 0); err != nil {
+			return err
+		}
+	}
+	if fd > 2 {
+		unix.Close(fd)
+	}
+	return nil
+}
+
+// setTTY sets the speed of stdin, and cooked mode. clocal ignores the
+// modem lines, which many serial consoles do not have.
+func setTTY(baud string) error {
+	t, err := termios.GTTY(0)
+	if err != nil {
+		return err
+	}
+	opts := []string{"cooked", "cread", "clocal", "hupcl"}
+	if baud != "" {
+		opts = append(opts, "speed", baud)
+	}
+	if err := t.SetOpts(opts); err != nil {
+		return err
+	}
+	_, err = t.STTY(0)
+	return err
+}
+
+// readName asks for the name of the user, until one is given.
+func readName(host string) (string, error) {
+	r := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("%s login: ", host)
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if n := strings.TrimSpace(l); n != "" {
+			return n, nil
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 3 {
+		log.Fatalf("Usage: getty [-l LOGIN] [-shell SHELL] [-issue FILE] TTY [BAUD [TERM]]")
+	}
+	tty := flag.Arg(0)
+	if !strings.HasPrefix(tty, "/") {
+		tty = filepath.Join("/dev", tty)
+	}
+	baud, term := flag.Arg(1), flag.Arg(2)
+	if term == "" {
+		term = "vt100"
+	}
+
+	if err := openTTY(tty); err != nil {
+		log.Fatalf("getty: %v", err)
+	}
+	if err := setTTY(baud); err != nil {
+		log.Fatalf("getty: %v: %v", tty, err)
+	}
+
+	vals := issueValues(tty)
+	if b, err := ioutil.ReadFile(*issue); err == nil {
+		fmt.Print(expandIssue(string(b), vals))
+	}
+
+	argv := []string{*shell}
+	if *login != "" {
+		n, err := readName(vals['n'])
+		if err != nil {
+			log.Fatalf("getty: %v", err)
+		}
+		argv = []string{*login, n}
+	}
+	env := append(os.Environ(), "TERM="+term)
+	if err := syscall.Exec(argv[0], argv, env); err != nil {
+		log.Fatalf("getty: %v: %v", argv[0], err)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestExpandIssue(t *testing.T) {
+	vals := map[byte]string{'n': "box", 'l': "ttyS0", 'r': "4.14.0"}
+	for _, tt := range []struct {
+		s, want string
+	}{
+		{"Welcome\n", "Welcome\n"},
+		{"\\n on \\l\n", "box on ttyS0\n"},
+		{"Linux \\r \\x", "Linux 4.14.0 \\x"},
+		{"a \\\\n b\\", "a \\n b\\"},
+	} {
+		if got := expandIssue(tt.s, vals); got != tt.want {
+			t.Errorf("expandIssue(%q): got %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
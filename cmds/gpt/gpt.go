@@ -6,12 +6,33 @@
 //
 // Synopsis:
 //     gpt [-w] file
+//     gpt -create file
+//     gpt -fix file
+//     gpt -add TYPE:FIRST:LAST[:NAME] file
 //
 // Description:
 //     For -w, it reads a JSON formatted GPT from stdin, and writes 'file'
 //     which is usually a device. It writes both primary and secondary headers.
 //
-//     Otherwise it just writes the headers to stdout in JSON format.
+//     -create writes a new, empty GPT and a protective MBR.
+//
+//     -fix rewrites both GPTs from the primary, or from the backup if the
+//     primary is bad, with the backup at the end of the disk. This fixes
+//     a backup which differs from the primary, or which is not at the end
+//     because an image was written to a bigger disk.
+//
+//     -add adds a partition. TYPE is efi, bios, linux, swap, raid, lvm or a
+//     type GUID. FIRST and LAST are blocks; if FIRST is 0, the partition
+//     starts at the next free MiB, and if LAST is 0, it ends at the end of
+//     the disk. The partition's number is printed.
+//
+//     Otherwise it just writes the headers to stdout in JSON format. If
+//     they are not valid, it says why and exits with status 1.
+//
+// Example:
+//     gpt -create /dev/sda
+//     gpt -add efi:0:264191:EFI /dev/sda
+//     gpt -add linux:0:0:root /dev/sda
 package main
 
 import (
@@ -20,14 +41,20 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/u-root/u-root/pkg/gpt"
 )
 
 const cmd = "gpt [options] file"
 
 var (
-	write = flag.Bool("w", false, "Write GPT to file")
+	write  = flag.Bool("w", false, "Write GPT to file")
+	create = flag.Bool("create", false, "Write a new GPT to file")
+	fix    = flag.Bool("fix", false, "Rewrite both GPTs, with the backup at the end of file")
+	add    = flag.String("add", "", "Add a partition, `TYPE:FIRST:LAST[:NAME]`")
 )
 
 func init() {
@@ -39,6 +66,93 @@ func init() {
 	}
 }
 
+// writeGPT writes g and its backup to f.
+func writeGPT(f *os.File, g *gpt.GPT) error {
+	if err := gpt.Write(f, g); err != nil {
+		return err
+	}
+	if err := gpt.Write(f, gpt.Backup(g)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// parsePart parses the TYPE:FIRST:LAST[:NAME] of -add.
+func parsePart(s string) (t uuid.UUID, first, last uint64, name string, err error) {
+	f := strings.SplitN(s, ":", 4)
+	if len(f) < 3 {
+		return t, 0, 0, "", fmt.Errorf("%q is not TYPE:FIRST:LAST[:NAME]", s)
+	}
+	if t, err = gpt.ParsePartType(f[0]); err != nil {
+		return t, 0, 0, "", fmt.Errorf("type %q: %v", f[0], err)
+	}
+	if first, err = strconv.ParseUint(f[1], 0, 64); err != nil {
+		return t, 0, 0, "", err
+	}
+	if last, err = strconv.ParseUint(f[2], 0, 64); err != nil {
+		return t, 0, 0, "", err
+	}
+	if len(f) == 4 {
+		name = f[3]
+	}
+	return t, first, last, name, nil
+}
+
+// edit does -create, -fix and -add.
+func edit(f *os.File) error {
+	// Seeking to the end gives the size of devices as well as files.
+	size, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		return err
+	}
+	blocks := uint64(size) / gpt.BlockSize
+
+	switch {
+	case *create:
+		g, err := gpt.Create(blocks)
+		if err != nil {
+			return err
+		}
+		if err := gpt.WriteProtectiveMBR(f, blocks); err != nil {
+			return err
+		}
+		return writeGPT(f, g)
+
+	case *fix:
+		g, _, err := gpt.New(f)
+		if g == nil {
+			// The primary is bad, so try the backup at the end.
+			b, berr := gpt.Table(f, int64(blocks-1)*gpt.BlockSize)
+			if berr != nil {
+				return fmt.Errorf("no good primary (%v) or backup (%v) GPT", err, berr)
+			}
+			g = gpt.Primary(b)
+		}
+		if err := g.Place(blocks); err != nil {
+			return err
+		}
+		return writeGPT(f, g)
+	}
+
+	t, first, last, name, err := parsePart(*add)
+	if err != nil {
+		return err
+	}
+	g, _, err := gpt.New(f)
+	if err != nil {
+		return fmt.Errorf("%v; try -fix", err)
+	}
+	p, err := g.AddPart(t, first, last, name)
+	if err != nil {
+		return err
+	}
+	if err := writeGPT(f, g); err != nil {
+		return err
+	}
+	fmt.Println(p)
+	return nil
+}
+
 func main() {
 	flag.Parse()
 	if flag.NArg() != 1 {
@@ -46,7 +160,7 @@ func main() {
 	}
 
 	m := os.O_RDONLY
-	if *write {
+	if *write || *create || *fix || *add != "" {
 		m = os.O_RDWR
 	}
 
@@ -56,8 +170,12 @@ func main() {
 		log.Fatal(err)
 	}
 
-	switch *write {
-	case true:
+	switch {
+	case *create, *fix, *add != "":
+		if err := edit(f); err != nil {
+			log.Fatalf("%v: %v", n, err)
+		}
+	case *write:
 		var g = make([]gpt.GPT, 2)
 		if err := json.NewDecoder(os.Stdin).Decode(&g); err != nil {
 			log.Fatalf("Reading in JSON: %v", err)
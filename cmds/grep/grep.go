@@ -5,38 +5,66 @@
 // Concurrent, parallel grep.
 //
 // Synopsis:
-//     grep [-vrlq] [FILE]...
+//     grep [-vrlqinc] REGEXP [FILE]...
 //
 // Description:
 //     It has to deal with the EMFILE limit. To do so we have one chan that is
 //     bounded. From args, we use filepath.Walk to generate a chan of names.
-//     From that, we create a chan of grepCommands. From that, we create a chan
-//     of grepResults. The grepResults contain matches or not-matches only. If
-//     we are in -l mode, the goprocs handling the grep bail out as soon as the
-//     condition is met. This grep is about 2x faster than GNU grep for simple
-//     non-recursive greps and slower as soon as filepath. Walk enters the
-//     picture. Let's fix this.
+//     From that, we create a chan of grepCommands. Each grepCommand gets its
+//     chan of grepResults before its grep starts, and the chans are read in
+//     the order of the files, so the output is in file name order even
+//     though the greps run in parallel. If we are in -l mode, the goprocs
+//     handling the grep bail out as soon as the condition is met.
+//
+//     With -r and no FILE, the current directory is searched. A file with a
+//     NUL byte near its start is binary: only whether it matches is
+//     printed. When stdout is a terminal, matches are printed in color.
+//
+//     The exit status is 0 if a line was selected, 1 if none was, and 2 if
+//     there was an error.
 //
 // Options:
 //     -v: print only non-matching lines
 //     -r: recursive
 //     -l: list only files
 //     -q: don't print matches; exit on first match
+//     -i: ignore case
+//     -n: print line numbers
+//     -c: print only the number of matching lines
 package main
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+
+	"github.com/u-root/u-root/pkg/termios"
 )
 
+// ANSI escapes for colored output.
+const (
+	colorMatch = "\033[01;31m"
+	colorName  = "\033[35m"
+	colorSep   = "\033[36m"
+	colorLine  = "\033[32m"
+	colorEnd   = "\033[m"
+)
+
+// binaryPeek is how far into a file we look for a NUL.
+const binaryPeek = 1024
+
 type grepResult struct {
-	match bool
-	c     *grepCommand
-	line  *string
+	c      *grepCommand
+	line   string
+	lineno int
+	// count is the number of selected lines, sent once when counting.
+	count int
+	// binary is set if c is binary and has a selected line.
+	binary bool
 }
 
 type grepCommand struct {
@@ -49,132 +77,223 @@ type oneGrep struct {
 }
 
 var (
-	match       = flag.Bool("v", true, "Print only non-matching lines")
+	invert      = flag.Bool("v", false, "Print only non-matching lines")
 	recursive   = flag.Bool("r", false, "recursive")
 	noshowmatch = flag.Bool("l", false, "list only files")
-	showname    = false
 	quiet       = flag.Bool("q", false, "Don't print matches; exit on first match")
-	allGrep     = make(chan *oneGrep)
-	nGrep       = 0
+	ignoreCase  = flag.Bool("i", false, "Ignore case")
+	number      = flag.Bool("n", false, "Print line numbers")
+	count       = flag.Bool("c", false, "Print only the number of matching lines")
+	showname    = false
+	color       = false
+	// allGrep is in file order. Its size bounds the number of open files.
+	allGrep = make(chan *oneGrep, 128)
+	out     = bufio.NewWriter(os.Stdout)
 )
 
+// isBinary reports whether the start of r has a NUL in it.
+func isBinary(r *bufio.Reader) bool {
+	b, _ := r.Peek(binaryPeek)
+	return bytes.IndexByte(b, 0) >= 0
+}
+
 // grep reads data from the os.File embedded in grepCommand.
-// It creates a chan of grepResults and pushes a pointer to it into allGrep.
-// It matches each line against the re and pushes the matching result
-// into the chan.
-// Bug: this chan should be created by the caller and passed in
-// to preserve file name order. Oops.
+// It matches each line against the re and pushes the selected lines
+// into res, which it closes when done.
 // If we are only looking for a match, we exit as soon as the condition is met.
-// "match" means result of re.Match == match flag.
-func grep(f *grepCommand, re *regexp.Regexp) {
-	nGrep++
+// A line is selected if re.Match differs from the -v flag.
+func grep(f *grepCommand, re *regexp.Regexp, res chan<- *grepResult) {
+	defer close(res)
+	defer f.Close()
 	r := bufio.NewReader(f)
-	res := make(chan *grepResult, 1)
-	allGrep <- &oneGrep{res}
-	for {
-		if i, err := r.ReadString('\n'); err == nil {
-			m := re.Match([]byte(i))
-			if m == *match {
-				res <- &grepResult{re.Match([]byte(i)), f, &i}
-				if *noshowmatch {
-					break
-				}
-			}
-		} else {
+	binary := isBinary(r)
+	n := 0
+	for lineno := 1; ; lineno++ {
+		i, err := r.ReadString('\n')
+		if len(i) == 0 && err != nil {
 			break
 		}
+		if re.MatchString(i) == *invert {
+			continue
+		}
+		n++
+		switch {
+		case *noshowmatch, *quiet:
+			res <- &grepResult{c: f, count: n}
+			return
+		case *count:
+		case binary:
+			res <- &grepResult{c: f, binary: true}
+			return
+		default:
+			res <- &grepResult{c: f, line: i, lineno: lineno}
+		}
+	}
+	if *count {
+		res <- &grepResult{c: f, count: n}
 	}
-	close(res)
-	f.Close()
 }
 
-func printmatch(r *grepResult) {
+// paint wraps s in color, if color is on.
+func paint(s, c string) string {
+	if !color {
+		return s
+	}
+	return c + s + colorEnd
+}
+
+// highlight colors the matches of re in the line s.
+func highlight(s string, re *regexp.Regexp) string {
+	if !color || *invert {
+		return s
+	}
+	var b bytes.Buffer
+	last := 0
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(paint(s[m[0]:m[1]], colorMatch))
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func printmatch(r *grepResult, re *regexp.Regexp) {
 	var prefix string
 	if showname {
-		fmt.Printf("%v", r.c.name)
-		prefix = ":"
+		prefix = paint(r.c.name, colorName) + paint(":", colorSep)
 	}
-	if *noshowmatch {
-		return
+	switch {
+	case *noshowmatch:
+		fmt.Fprintln(out, paint(r.c.name, colorName))
+	case *count:
+		fmt.Fprintf(out, "%v%d\n", prefix, r.count)
+	case r.binary:
+		fmt.Fprintf(out, "Binary file %v matches\n", r.c.name)
+	default:
+		if *number {
+			prefix += paint(fmt.Sprint(r.lineno), colorLine) + paint(":", colorSep)
+		}
+		line := r.line
+		if line[len(line)-1] == '\n' {
+			line = line[:len(line)-1]
+		}
+		fmt.Fprintf(out, "%v%v\n", prefix, highlight(line, re))
 	}
-	if r.match == *match {
-		fmt.Printf("%v%v", prefix, *r.line)
+}
+
+// walk sends an open grepCommand for each file in names, and those
+// below them with -r, to files. It reports whether there was an error.
+func walk(names []string, files chan<- *grepCommand) bool {
+	failed := false
+	for _, v := range names {
+		// we could parallelize the open part but people might want
+		// things to be in order.
+		filepath.Walk(v, func(name string, fi os.FileInfo, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "grep: %v\n", err)
+				failed = true
+				return nil
+			}
+			if fi.IsDir() {
+				if !*recursive {
+					fmt.Fprintf(os.Stderr, "grep: %v: Is a directory\n", name)
+					failed = true
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// Reading a FIFO or device found on the way down could
+			// block forever.
+			if !fi.Mode().IsRegular() && name != v {
+				return nil
+			}
+			fp, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "grep: %v\n", err)
+				failed = true
+				return nil
+			}
+			files <- &grepCommand{name, fp}
+			return nil
+		})
 	}
+	close(files)
+	return failed
 }
 
 func main() {
-	r := ".*"
 	flag.Parse()
 	a := flag.Args()
-	if len(a) > 0 {
-		r = a[0]
+	if len(a) == 0 {
+		fmt.Fprintf(os.Stderr, "usage: grep [-vrlqinc] REGEXP [FILE]...\n")
+		os.Exit(2)
+	}
+	r := a[0]
+	if *ignoreCase {
+		r = "(?i)" + r
 	}
-	re := regexp.MustCompile(r)
+	re, err := regexp.Compile(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "grep: %v\n", err)
+		os.Exit(2)
+	}
+	if _, err := termios.GetTermios(os.Stdout.Fd()); err == nil {
+		color = true
+	}
+
+	names := a[1:]
+	if len(names) == 0 && *recursive {
+		names = []string{"."}
+	}
+	showname = len(names) > 1 || *recursive
+
+	files := make(chan *grepCommand)
+	failed := make(chan bool, 1)
 	// very special case, just stdin ...
-	if len(a) < 2 {
-		go grep(&grepCommand{"<stdin>", os.Stdin}, re)
-	} else {
-		showname = len(a[1:]) > 1
-		// generate a chan of file names, bounded by the size of the chan. This in turn
-		// throttles the opens.
-		treenames := make(chan string, 128)
+	if len(names) == 0 {
 		go func() {
-			for _, v := range a[1:] {
-				// we could parallelize the open part but people might want
-				// things to be in order. I don't care but who knows.
-				// just ignore the errors. If there is not a single one that works,
-				// then all the sizes will be 0 and we'll just fall through.
-				filepath.Walk(v, func(name string, fi os.FileInfo, err error) error {
-					if fi.IsDir() && !*recursive {
-						fmt.Fprintf(os.Stderr, "grep: %v: Is a directory\n", name)
-						return filepath.SkipDir
-					}
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
-						return err
-					}
-					treenames <- name
-					return nil
-				})
-			}
-			close(treenames)
+			files <- &grepCommand{"(standard input)", os.Stdin}
+			close(files)
+			failed <- false
 		}()
-
-		files := make(chan *grepCommand)
-		// convert the file names to a stream of os.File
+	} else {
 		go func() {
-			for i := range treenames {
-				fp, err := os.Open(i)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "can't open %s: %v\n", i, err)
-					continue
-				}
-				files <- &grepCommand{i, fp}
-			}
-			close(files)
+			failed <- walk(names, files)
 		}()
-		// now kick off the greps
-		// bug: file name order is not preserved here. Darn.
-
+	}
+	// now kick off the greps, each with its results chan in allGrep
+	// before it starts, so the order of the files is kept.
+	go func() {
 		for f := range files {
-			go grep(f, re)
+			res := make(chan *grepResult, 1)
+			allGrep <- &oneGrep{res}
+			go grep(f, re, res)
 		}
-	}
+		close(allGrep)
+	}()
 
+	matched := false
 	for c := range allGrep {
 		for r := range c.c {
+			if r.count > 0 || !*count {
+				matched = true
+			}
 			// exit on first match.
-			if *quiet {
+			if *quiet && matched {
 				os.Exit(0)
 			}
-			printmatch(r)
-		}
-		nGrep--
-		if nGrep == 0 {
-			break
+			printmatch(r, re)
 		}
 	}
-	if *quiet {
+	out.Flush()
+	switch {
+	case <-failed:
+		os.Exit(2)
+	case !matched:
 		os.Exit(1)
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 )
@@ -28,6 +29,13 @@ func TestGrep(t *testing.T) {
 		{"hix\n", "hix\n", 0, []string{"."}},
 		{"hix\n", "", 0, []string{"-q", "."}},
 		{"hix\n", "", 1, []string{"-q", "hox"}},
+		{"hix\nhox\n", "hox\n", 0, []string{"-v", "hix"}},
+		{"hix\nHIX\n", "hix\nHIX\n", 0, []string{"-i", "hix"}},
+		{"a\nb\na\n", "1:a\n3:a\n", 0, []string{"-n", "a"}},
+		{"a\nb\na\n", "2\n", 0, []string{"-c", "a"}},
+		{"a\nb\na\n", "0\n", 1, []string{"-c", "c"}},
+		{"a\nb", "b\n", 0, []string{"b"}},
+		{"a\x00\nb\n", "Binary file (standard input) matches\n", 0, []string{"a"}},
 	}
 
 	tmpDir, err := ioutil.TempDir("", "TestGrep")
@@ -66,3 +74,55 @@ func TestGrep(t *testing.T) {
 		t.Logf("Grep %v < %v: %v", v.a, v.i, v.o)
 	}
 }
+
+func TestGrepFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestGrepFiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	for _, f := range []struct {
+		name, data string
+	}{
+		{"a", "one\ntwo\n"},
+		{"d/b", "three\none\n"},
+		{"d/e/c", "four\n"},
+	} {
+		p := filepath.Join(tmpDir, "data", f.name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(f.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testgreppath := filepath.Join(tmpDir, "testgrep.exe")
+	out, err := exec.Command("go", "build", "-o", testgreppath, ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go build -o %v cmds/grep: %v\n%s", testgreppath, err, string(out))
+	}
+
+	var tab = []struct {
+		o string
+		s int
+		a []string
+	}{
+		{"a:one\nd/b:one\n", 0, []string{"-r", "one", "a", "d"}},
+		{"a\nd/b\n", 0, []string{"-r", "-l", "one", "."}},
+		{"d/b:2:one\n", 0, []string{"-r", "-n", "one", "d"}},
+		{"a:1\nd/e/c:0\n", 0, []string{"-c", "two", "a", "d/e/c"}},
+		{"", 2, []string{"one", "d"}},
+		{"", 2, []string{"(", "a"}},
+	}
+	for _, v := range tab {
+		c := exec.Command(testgreppath, v.a...)
+		c.Dir = filepath.Join(tmpDir, "data")
+		o, _ := c.Output()
+		s := c.ProcessState.Sys().(syscall.WaitStatus).ExitStatus()
+		// -r . names the files ./a and so on.
+		if got := strings.Replace(string(o), "./", "", -1); got != v.o || s != v.s {
+			t.Errorf("Grep %v: got %q (exit %v), want %q (exit %v)", v.a, got, s, v.o, v.s)
+		}
+	}
+}
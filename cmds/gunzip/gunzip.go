@@ -0,0 +1,48 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Decompress gzip files.
+//
+// Synopsis:
+//     gunzip [-cfk] [FILES...]
+//
+// Description:
+//     gunzip decompresses each FILE.gz to FILE, or FILE.tgz to FILE.tar, and
+//     removes it. With no FILES, or for -, stdin is decompressed to stdout.
+//     Files of several gzip streams one after the other are decompressed
+//     as one.
+//
+// Example:
+//     gunzip initramfs.cpio.gz
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/gzip"
+)
+
+var opts = gzip.Options{Decompress: true}
+
+func init() {
+	opts.RegisterFlags(flag.CommandLine)
+}
+
+func main() {
+	flag.Parse()
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	status := 0
+	for _, f := range files {
+		if err := opts.File(f); err != nil {
+			log.Print(err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Compress files with gzip.
+//
+// Synopsis:
+//     gzip [-cdfk] [-1...-9] [FILES...]
+//
+// Description:
+//     gzip compresses each FILE to FILE.gz, and removes FILE. With no FILES,
+//     or for -, stdin is compressed to stdout.
+//
+// Example:
+//     gzip -9 -k initramfs.cpio
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/gzip"
+)
+
+var opts = gzip.Options{}
+
+func init() {
+	opts.RegisterFlags(flag.CommandLine)
+}
+
+func main() {
+	flag.Parse()
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	status := 0
+	for _, f := range files {
+		if err := opts.File(f); err != nil {
+			log.Print(err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
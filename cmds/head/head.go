@@ -0,0 +1,163 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Print the first lines of files.
+//
+// Synopsis:
+//     head [-n [-]N | -c [-]N] [-q|-v] [FILE...]
+//
+// Description:
+//     head prints the first 10 lines of each FILE, or of stdin if there is
+//     none or FILE is -. With more than one FILE, each is under a header of
+//     its name, ==> FILE <==. head -N is head -n N.
+//
+// Options:
+//     -n: print the first N lines; with -N, all but the last N
+//     -c: print the first N bytes; with -N, all but the last N
+//     -q: never print headers
+//     -v: always print headers
+//
+// Example:
+//     $ head -n 1 /proc/cpuinfo
+//     processor	: 0
+//     $ dmesg | head -n -5
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+var (
+	lines   = flag.Int64("n", 10, "print the first `N` lines; with -N, all but the last N")
+	bytesN  = flag.Int64("c", 0, "print the first `N` bytes; with -N, all but the last N")
+	quiet   = flag.Bool("q", false, "never print headers")
+	verbose = flag.Bool("v", false, "always print headers")
+)
+
+// lastOffset returns the offset in b of the last n lines, or if inBytes is
+// set, bytes. A newline at the end does not start a line.
+func lastOffset(b []byte, n int64, inBytes bool) int {
+	if inBytes {
+		if n >= int64(len(b)) {
+			return 0
+		}
+		return len(b) - int(n)
+	}
+	if n == 0 {
+		return len(b)
+	}
+	for i := len(b) - 2; i >= 0; i-- {
+		if b[i] == '\n' {
+			if n--; n == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// head copies the first n lines of r to w, or if inBytes is set, bytes; if
+// n is negative, all but the last -n.
+func head(w io.Writer, r io.Reader, n int64, inBytes bool) error {
+	if n < 0 {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b[:lastOffset(b, -n, inBytes)])
+		return err
+	}
+	if inBytes {
+		_, err := io.CopyN(w, r, n)
+		if err == io.EOF {
+			err = nil
+		}
+		return err
+	}
+	br := bufio.NewReader(r)
+	for ; n > 0; n-- {
+		l, err := br.ReadBytes('\n')
+		if _, werr := w.Write(l); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// args turns the old -N form into -n N.
+func args(a []string) []string {
+	if len(a) > 1 && len(a[1]) > 1 && a[1][0] == '-' && a[1][1] >= '0' && a[1][1] <= '9' {
+		return append([]string{a[0], "-n", a[1][1:]}, a[2:]...)
+	}
+	return a
+}
+
+// headFile prints the head of the file name, or stdin for -, under a
+// header if there are headers.
+func headFile(w io.Writer, name string, n int64, inBytes, header, first bool) error {
+	f := os.Stdin
+	if name == "-" {
+		name = "standard input"
+	} else {
+		var err error
+		if f, err = os.Open(name); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	if header {
+		if !first {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "==> %s <==\n", name)
+	}
+	if err := head(w, f, n, inBytes); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func run(w io.Writer, files []string, n int64, inBytes bool) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	headers := !*quiet && (*verbose || len(files) > 1)
+	var failed error
+	for i, name := range files {
+		if err := headFile(w, name, n, inBytes, headers, i == 0); err != nil {
+			log.Print(err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+func main() {
+	os.Args = args(os.Args)
+	flag.Parse()
+	n, inBytes := *lines, false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "c" {
+			n, inBytes = *bytesN, true
+		}
+	})
+	w := bufio.NewWriter(os.Stdout)
+	err := run(w, flag.Args(), n, inBytes)
+	w.Flush()
+	if err != nil {
+		os.Exit(1)
+	}
+}
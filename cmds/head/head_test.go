@@ -0,0 +1,81 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHead(t *testing.T) {
+	const in = "1\n2\n3\n4\n5\n"
+	for _, tt := range []struct {
+		n       int64
+		inBytes bool
+		in      string
+		want    string
+	}{
+		{2, false, in, "1\n2\n"},
+		{0, false, in, ""},
+		{10, false, in, in},
+		{2, false, "1\n2", "1\n2"},
+		{-2, false, in, "1\n2\n3\n"},
+		{-2, false, "1\n2\n3", "1\n"},
+		{-10, false, in, ""},
+		{3, true, in, "1\n2"},
+		{100, true, in, in},
+		{-3, true, in, "1\n2\n3\n4"},
+		{-100, true, in, ""},
+	} {
+		var b bytes.Buffer
+		if err := head(&b, strings.NewReader(tt.in), tt.n, tt.inBytes); err != nil {
+			t.Errorf("head(%q, %d, %v): %v", tt.in, tt.n, tt.inBytes, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("head(%q, %d, %v): got %q, want %q", tt.in, tt.n, tt.inBytes, b.String(), tt.want)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"head", "-5", "f"}, []string{"head", "-n", "5", "f"}},
+		{[]string{"head", "-n", "5"}, []string{"head", "-n", "5"}},
+		{[]string{"head", "-"}, []string{"head", "-"}},
+		{[]string{"head"}, []string{"head"}},
+	} {
+		if got := args(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("args(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "head")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	ioutil.WriteFile(a, []byte("a1\na2\n"), 0644)
+	ioutil.WriteFile(b, []byte("b1\nb2\n"), 0644)
+
+	var out bytes.Buffer
+	if err := run(&out, []string{a, b}, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "==> " + a + " <==\na1\n\n==> " + b + " <==\nb1\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Hwclock reads and sets the battery backed real time clock, the RTC.
+//
+// Synopsis:
+//     hwclock [--show|--hctosys|--systohc] [--utc|--localtime] [--rtc DEV]
+//
+// Description:
+//     hwclock prints the time of the RTC, or with --hctosys sets the
+//     system clock from it, or with --systohc sets it from the system
+//     clock.
+//
+//     The RTC is in UTC unless --localtime is given, or /etc/adjtime says
+//     LOCAL on its third line, as it does on machines which also boot
+//     Windows. Local time is from TZ, or /etc/localtime.
+//
+//     Init sets the system clock from the RTC at boot, unless
+//     uroot.nortc is on the kernel command line, so machines with no
+//     network do not start in 1970; uroot.rtc=local says the RTC is in
+//     local time, which needs TZ or /etc/localtime in the image too.
+//
+// Options:
+//     --show, -r:      print the time of the RTC (the default)
+//     --hctosys, -s:   set the system clock from the RTC
+//     --systohc, -w:   set the RTC from the system clock
+//     --utc, -u:       the RTC is in UTC
+//     --localtime, -l: the RTC is in local time
+//     --rtc, -f:       the RTC device; the default is /dev/rtc, or /dev/rtc0
+//
+// Example:
+//     $ hwclock
+//     2017-10-15 12:30:05+00:00
+//     $ ntpdate && hwclock --systohc
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/u-root/u-root/pkg/ntp"
+	"github.com/u-root/u-root/pkg/rtc"
+)
+
+var (
+	show, hctosys, systohc, utc, localtime bool
+	dev                                    string
+)
+
+func init() {
+	for _, f := range []struct {
+		v           *bool
+		long, short string
+		usage       string
+	}{
+		{&show, "show", "r", "print the time of the RTC"},
+		{&hctosys, "hctosys", "s", "set the system clock from the RTC"},
+		{&systohc, "systohc", "w", "set the RTC from the system clock"},
+		{&utc, "utc", "u", "the RTC is in UTC"},
+		{&localtime, "localtime", "l", "the RTC is in local time"},
+	} {
+		flag.BoolVar(f.v, f.long, false, f.usage)
+		flag.BoolVar(f.v, f.short, false, f.usage)
+	}
+	flag.StringVar(&dev, "rtc", "", "the RTC `device`")
+	flag.StringVar(&dev, "f", "", "the RTC `device`")
+}
+
+// location returns the location the RTC is in: from --utc or --localtime,
+// else from adjtime, which says whether it is local.
+func location(utc, localtime bool, adjtime func() bool) (*time.Location, error) {
+	switch {
+	case utc && localtime:
+		return nil, fmt.Errorf("only one of --utc and --localtime")
+	case utc:
+		return time.UTC, nil
+	case localtime:
+		return time.Local, nil
+	case adjtime():
+		return time.Local, nil
+	}
+	return time.UTC, nil
+}
+
+// format prints t as hwclock does, in local time.
+func format(w io.Writer, t time.Time) {
+	fmt.Fprintln(w, t.In(time.Local).Format("2006-01-02 15:04:05-07:00"))
+}
+
+// nextSecond waits until the start of the next second, and returns it. The
+// RTC only has whole seconds, and starts the second it is set to when it
+// is set, so setting it at the start of one keeps it closest.
+func nextSecond() time.Time {
+	t := time.Now().Truncate(time.Second).Add(time.Second)
+	time.Sleep(time.Until(t))
+	return t
+}
+
+func run(w io.Writer) error {
+	n := 0
+	for _, b := range []bool{show, hctosys, systohc} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one of --show, --hctosys and --systohc")
+	}
+	loc, err := location(utc, localtime, rtc.Local)
+	if err != nil {
+		return err
+	}
+	r, err := rtc.Open(dev)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if systohc {
+		return r.Set(nextSecond(), loc)
+	}
+	t, err := r.Read(loc)
+	if err != nil {
+		return err
+	}
+	if hctosys {
+		return ntp.SetTime(t)
+	}
+	format(w, t)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := run(os.Stdout); err != nil {
+		log.Fatalf("hwclock: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLocation(t *testing.T) {
+	for _, tt := range []struct {
+		utc, localtime, adjtime bool
+		want                    *time.Location
+		err                     bool
+	}{
+		{want: time.UTC},
+		{adjtime: true, want: time.Local},
+		{utc: true, adjtime: true, want: time.UTC},
+		{localtime: true, want: time.Local},
+		{utc: true, localtime: true, err: true},
+	} {
+		got, err := location(tt.utc, tt.localtime, func() bool { return tt.adjtime })
+		if (err != nil) != tt.err {
+			t.Errorf("location(%v, %v, %v): got error %v, want error %v", tt.utc, tt.localtime, tt.adjtime, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("location(%v, %v, %v): got %v, want %v", tt.utc, tt.localtime, tt.adjtime, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	local := time.Local
+	defer func() { time.Local = local }()
+	time.Local = time.FixedZone("CEST", 2*3600)
+
+	var b bytes.Buffer
+	format(&b, time.Date(2017, time.October, 15, 12, 30, 5, 0, time.UTC))
+	if got, want := b.String(), "2017-10-15 14:30:05+02:00\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -13,7 +13,8 @@ import (
 	"os/exec"
 	"runtime"
 	"sort"
-	"strings"
+
+	"github.com/u-root/u-root/pkg/cmdline"
 )
 
 // Commands are built approximately in order from smallest to largest length of
@@ -73,21 +74,6 @@ func startBgBuild() {
 	close(cmds)
 }
 
-func cmdlineContainsFlag(flag string) bool {
-	cmdline, err := ioutil.ReadFile("/proc/cmdline")
-	if err != nil {
-		// Procfs not mounted?
-		return false
-	}
-	args := strings.Split(string(cmdline), " ")
-	for _, a := range args {
-		if a == flag {
-			return true
-		}
-	}
-	return false
-}
-
 func isBgBuildEnabled() bool {
-	return !cmdlineContainsFlag("uroot.nobgbuild")
+	return !cmdline.ContainsFlag("uroot.nobgbuild")
 }
@@ -0,0 +1,37 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+// fstab is what the fstab stage mounts.
+var fstab = "/etc/fstab"
+
+func init() {
+	addStage(fstabLevel, stageFunc{"fstab", mountFstab})
+}
+
+// mountFstab mounts the file systems of the image's /etc/fstab, as
+// mountall would, once the modules for the disks are loaded and the
+// fsck commands are in /buildbin.
+func mountFstab() error {
+	f, err := os.Open(fstab)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := mount.ParseFstab(f)
+	if err != nil {
+		return err
+	}
+	return mount.MountAll(entries, mount.ExecFsck)
+}
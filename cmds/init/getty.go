@@ -0,0 +1,51 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+// gettyPath is the getty command run on the ttys of uroot.getty=.
+const gettyPath = "/buildbin/getty"
+
+func init() {
+	addStage(gettyLevel, stageFunc{"getty", startGettys})
+}
+
+// startGettys runs getty on each TTY[:BAUD] of the comma-separated
+// uroot.getty=, e.g. uroot.getty=ttyS0:115200, and runs it again when it
+// exits, so that boards without a screen can be logged in to over serial
+// lines.
+func startGettys() error {
+	v, _ := cmdline.Value("uroot.getty")
+	if v == "" {
+		return nil
+	}
+	for _, t := range strings.Split(v, ",") {
+		go respawn(strings.Split(t, ":")...)
+	}
+	return nil
+}
+
+// respawn runs getty with args for ever.
+func respawn(args ...string) {
+	for {
+		start := time.Now()
+		c := exec.Command(gettyPath, args...)
+		if err := c.Run(); err != nil {
+			log.Printf("init: getty %v: %v", args, err)
+		}
+		// Do not spin if it can not start.
+		if time.Since(start) < time.Second {
+			time.Sleep(5 * time.Second)
+		}
+	}
+}
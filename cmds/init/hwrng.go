@@ -0,0 +1,60 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+const (
+	hwrng = "/dev/hwrng"
+	// hwrngBytes are read from hwrng every hwrngInterval.
+	hwrngBytes    = 512
+	hwrngInterval = 30 * time.Second
+)
+
+func init() {
+	addStage(hwrngLevel, stageFunc{"hwrng", startHwrng})
+}
+
+// startHwrng mixes what the hardware random number generator, if there is
+// one, gives into the kernel's pool, unless uroot.nohwrng is on the kernel
+// command line. Just after boot there is little else to mix in, and
+// dhclient, for one, can not start without random numbers.
+func startHwrng() error {
+	if cmdline.ContainsFlag("uroot.nohwrng") {
+		return nil
+	}
+	in, err := os.Open(hwrng)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile("/dev/random", os.O_WRONLY, 0)
+	if err != nil {
+		in.Close()
+		return err
+	}
+	go func() {
+		defer in.Close()
+		defer out.Close()
+		b := make([]byte, hwrngBytes)
+		for {
+			n, err := in.Read(b)
+			if err != nil {
+				debug("init: %v: %v", hwrng, err)
+				return
+			}
+			out.Write(b[:n])
+			time.Sleep(hwrngInterval)
+		}
+	}()
+	return nil
+}
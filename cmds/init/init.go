@@ -22,6 +22,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/u-root/u-root/pkg/cmdline"
 	"github.com/u-root/u-root/pkg/uroot/util"
 )
 
@@ -32,17 +33,68 @@ var (
 )
 
 func main() {
-	a := []string{"build"}
 	flag.Parse()
 	log.Printf("Welcome to u-root")
-	util.Rootfs()
+	if *verbose {
+		debug = log.Printf
+	}
+
+	runStages()
+
+	log.Printf("init: All commands exited")
+	log.Printf("init: Syncing filesystems")
+	syscall.Sync()
+	log.Printf("init: Exiting...")
+}
 
+func init() {
+	addStage(mountLevel, stageFunc{"rootfs", func() error {
+		util.Rootfs()
+		return nil
+	}})
+	addStage(mountLevel, stageFunc{"initflags", initFlags})
+	addStage(setupLevel, stageFunc{"buildbin", buildbin})
+	addStage(setupLevel, stageFunc{"loglevel", logLevel})
+	addStage(setupLevel, stageFunc{"env", env})
+	addStage(setupLevel, stageFunc{"bgbuild", func() error {
+		if isBgBuildEnabled() {
+			go startBgBuild()
+		}
+		return nil
+	}})
+}
+
+// initFlags sets the flags of uroot.initflags on the kernel command line,
+// -NAME or -NAME=VALUE, e.g. uroot.initflags="-v -test=true", as the kernel
+// only passes init those it does not know. /proc is not there until the
+// rootfs stage.
+func initFlags() error {
+	f, ok := cmdline.Value("uroot.initflags")
+	if !ok {
+		return nil
+	}
+	// A bad flag must not make init exit, as flag.Parse would.
+	for _, a := range cmdline.Fields(f) {
+		nv := strings.SplitN(strings.TrimLeft(a, "-"), "=", 2)
+		if len(nv) < 2 {
+			nv = append(nv, "true")
+		}
+		if err := flag.Set(nv[0], nv[1]); err != nil {
+			return fmt.Errorf("%v: %v", a, err)
+		}
+	}
 	if *verbose {
 		debug = log.Printf
-		a = append(a, "-x")
 	}
+	return nil
+}
 
-	// populate buildbin
+// buildbin populates /buildbin and builds installcommand.
+func buildbin() error {
+	a := []string{"build"}
+	if *verbose {
+		a = append(a, "-x")
+	}
 
 	// In earlier versions we just had src/cmds. Due to the Go rules it seems we need to
 	// embed the URL of the repo everywhere. Yuck.
@@ -73,32 +125,31 @@ func main() {
 	os.Setenv("GOBIN", "/buildbin")
 	a = append(a, "-o", "/buildbin/installcommand", filepath.Join(util.CmdsPath, "installcommand"))
 	cmd := exec.Command("go", a...)
-	installenvs := envs
-	installenvs = append(envs, "GOBIN=/buildbin")
-	cmd.Env = installenvs
+	cmd.Env = append(envs, "GOBIN=/buildbin")
 	cmd.Dir = "/"
 
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
 	debug("Run %v", cmd)
-	if err := cmd.Run(); err != nil {
-		log.Printf("%v\n", err)
-	}
+	return cmd.Run()
+}
 
-	// Before entering an interactive shell, decrease the loglevel because
-	// spamming non-critical logs onto the shell frustrates users. The logs
-	// are still accessible through dmesg.
+// logLevel decreases the console log level. Spamming non-critical logs onto
+// the shell frustrates users. The logs are still accessible through dmesg.
+func logLevel() error {
 	const sysLogActionConsoleLevel = 8
 	const kernNotice = 5 // Only messages more severe than "notice" are printed.
 	if _, _, err := syscall.Syscall(syscall.SYS_SYSLOG, sysLogActionConsoleLevel, 0, kernNotice); err != 0 {
-		log.Print("Could not set log level")
+		return fmt.Errorf("could not set log level: %v", err)
 	}
+	return nil
+}
 
-	// install /env.
+// env installs /env and /etc/profile.d/uroot.sh.
+func env() error {
 	os.Setenv("GOBIN", "/ubin")
-	envs = append(envs, "GOBIN=/ubin")
-	for _, e := range envs {
+	for _, e := range os.Environ() {
 		nv := strings.SplitN(e, "=", 2)
 		if len(nv) < 2 {
 			nv = append(nv, "")
@@ -143,50 +194,8 @@ func main() {
 	// Only bother doing this is /etc/profile.d exists and is a directory.
 	if fi, err := os.Stat("/etc/profile.d"); err == nil && fi.IsDir() {
 		if err := ioutil.WriteFile("/etc/profile.d/uroot.sh", []byte(profile), 0644); err != nil {
-			log.Printf("Trying to write uroot profile failed: %v", err)
+			return fmt.Errorf("trying to write uroot profile failed: %v", err)
 		}
 	}
-
-	// Start background build.
-	if isBgBuildEnabled() {
-		go startBgBuild()
-	}
-
-	// There may be an inito if we are building on
-	// an existing initramfs. So, first, try to
-	// run inito and then run our shell
-	// inito is always first and we set default flags for it.
-	cloneFlags := uintptr(syscall.CLONE_NEWPID)
-	cmdList := []string{"/inito", "/buildbin/uinit", "/buildbin/rush"}
-	noCmdFound := true
-	for _, v := range cmdList {
-		if _, err := os.Stat(v); !os.IsNotExist(err) {
-			noCmdFound = false
-			cmd = exec.Command(v)
-			cmd.Env = envs
-			cmd.Stdin = os.Stdin
-			cmd.Stderr = os.Stderr
-			cmd.Stdout = os.Stdout
-			if *test {
-				cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: cloneFlags}
-			} else {
-				cmd.SysProcAttr = &syscall.SysProcAttr{Setctty: true, Setsid: true, Cloneflags: cloneFlags}
-			}
-			debug("Run %v", cmd)
-			if err := cmd.Run(); err != nil {
-				log.Print(err)
-			}
-		}
-		// only the first init needs its own PID space.
-		cloneFlags = 0
-	}
-
-	if noCmdFound {
-		log.Printf("init: No suitable executable found in %+v", cmdList)
-	}
-
-	log.Printf("init: All commands exited")
-	log.Printf("init: Syncing filesystems")
-	syscall.Sync()
-	log.Printf("init: Exiting...")
+	return nil
 }
@@ -0,0 +1,46 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/u-root/u-root/pkg/measurement"
+)
+
+func init() {
+	addStage(measureLevel, stageFunc{"measure", measure})
+}
+
+// measure measures the kernel command line, which has the uinit and init
+// flags, and the programs uinit runs, into the TPM before they run, if
+// there is a TPM. kexec measures the kernel and initramfs it boots.
+func measure() error {
+	m, err := measurement.Open()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	c, err := ioutil.ReadFile("/proc/cmdline")
+	if err != nil {
+		return err
+	}
+	if err := m.Data("/proc/cmdline", c); err != nil {
+		return err
+	}
+	for _, p := range uinitCmds() {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			continue
+		}
+		if err := m.File(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
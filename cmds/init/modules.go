@@ -0,0 +1,77 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+)
+
+// modulesFile lists the kernel modules to load, one per line: the path of
+// the .ko file, or the name of a module in /lib/modules/RELEASE, then its
+// options. Modules named are loaded with those they depend on, as by
+// modprobe. Lines starting with # are comments.
+const modulesFile = "/etc/modules"
+
+func init() {
+	addStage(moduleLevel, stageFunc{"modules", loadModules})
+}
+
+func loadModules() error {
+	f, err := os.Open(modulesFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Only images with modules.dep can load modules by name.
+	var mods *kmodule.Modules
+	if d, err := kmodule.ModuleDir(); err == nil {
+		mods, _ = kmodule.Open(d)
+	}
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		m := strings.SplitN(l, " ", 2)
+		opts := ""
+		if len(m) > 1 {
+			opts = strings.TrimSpace(m[1])
+		}
+		// One module failing to load should not stop the others.
+		if err := loadModule(mods, m[0], opts); err != nil {
+			log.Printf("init: %v", err)
+		}
+	}
+	return s.Err()
+}
+
+func loadModule(mods *kmodule.Modules, name, opts string) error {
+	var err error
+	switch {
+	case strings.ContainsRune(name, '/'):
+		err = kmodule.Load(name, opts, 0)
+	case mods == nil:
+		err = fmt.Errorf("%v: no modules.dep to find it in", name)
+	default:
+		err = mods.Probe(name, opts)
+	}
+	if err != nil {
+		return err
+	}
+	debug("init: loaded %v", name)
+	return nil
+}
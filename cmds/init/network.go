@@ -0,0 +1,23 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/vishvananda/netlink"
+)
+
+func init() {
+	addStage(networkLevel, stageFunc{"network", network})
+}
+
+// network brings up the loopback interface. Other interfaces are left to
+// dhclient or ip, or to a stage of the image.
+func network() error {
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(lo)
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+	"github.com/u-root/u-root/pkg/ntp"
+)
+
+func init() {
+	addStage(ntpLevel, stageFunc{"ntp", setClock})
+}
+
+// setClock sets the clock from the first of the comma-separated servers of
+// uroot.ntp= which answers. Machines without a battery backed clock boot
+// in 1970, when no certificate is valid yet.
+func setClock() error {
+	v, _ := cmdline.Value("uroot.ntp")
+	if v == "" {
+		return nil
+	}
+	for _, s := range strings.Split(v, ",") {
+		r, err := ntp.Query(s, 5*time.Second)
+		if err != nil {
+			log.Printf("init: ntp: %v", err)
+			continue
+		}
+		debug("init: ntp: %v offset %v", r.Server, r.Offset)
+		return ntp.Adjust(r.Offset)
+	}
+	return fmt.Errorf("no server of %v answered", v)
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+	"github.com/u-root/u-root/pkg/ntp"
+	"github.com/u-root/u-root/pkg/rtc"
+)
+
+func init() {
+	addStage(rtcLevel, stageFunc{"rtc", setClockFromRTC})
+}
+
+// setClockFromRTC sets the clock from the RTC, if there is one, unless
+// uroot.nortc is on the kernel command line. The RTC is in UTC, or with
+// uroot.rtc=local, in local time. Not every kernel does this itself, and
+// without it a machine with no network to ask for the time is in 1970.
+//
+// Local time needs a time zone: /etc/localtime, or TZ, which the kernel
+// passes on from its command line, e.g. TZ=Europe/Berlin, and the zoneinfo
+// for it in the image. Without one, local time is UTC.
+func setClockFromRTC() error {
+	if cmdline.ContainsFlag("uroot.nortc") {
+		return nil
+	}
+	r, err := rtc.Open("")
+	if err == rtc.ErrNoRTC {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	loc := time.UTC
+	if v, _ := cmdline.Value("uroot.rtc"); v == "local" {
+		loc = time.Local
+		if name, off := time.Now().In(loc).Zone(); name == "UTC" && off == 0 {
+			log.Printf("init: rtc: uroot.rtc=local, but there is no time zone, so the RTC is read as UTC")
+		}
+	}
+	t, err := r.Read(loc)
+	if err != nil {
+		return err
+	}
+	debug("init: rtc: %v", t)
+	return ntp.SetTime(t)
+}
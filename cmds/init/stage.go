@@ -0,0 +1,91 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Init runs as a series of stages: kernel mounts, module loading, network
+// bring-up, setting up the build environment, then the hand-off to /inito,
+// uinit or rush. An image which needs more steps, e.g. for its board, adds
+// a file to this directory whose init function calls addStage, rather than
+// changing init.go.
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+// Levels of the stages init comes with. A stage added at level 25 runs
+// after the modules are loaded and before the network is up. The network
+// stage only brings up lo; an image which sets up more of it at
+// networkLevel can have the clock set at ntpLevel. /etc/fstab is mounted
+// once /buildbin is set up, so that fsck.ext and fsck.vfat are there to
+// check its file systems. The boot state is measured before gettys or
+// uinit give anyone access to the machine.
+const (
+	mountLevel   = 10
+	moduleLevel  = 20
+	rtcLevel     = 21
+	hwrngLevel   = 22
+	sysctlLevel  = 25
+	networkLevel = 30
+	ntpLevel     = 35
+	setupLevel   = 40
+	fstabLevel   = 45
+	measureLevel = 85
+	gettyLevel   = 90
+	uinitLevel   = 100
+)
+
+// A stage is one step of booting.
+type stage interface {
+	// Name is used in log messages.
+	Name() string
+	// Run does the step. If it fails, the error is logged and init
+	// goes on with the next stage.
+	Run() error
+}
+
+// stageFunc is a stage which calls a function.
+type stageFunc struct {
+	name string
+	run  func() error
+}
+
+func (s stageFunc) Name() string {
+	return s.name
+}
+
+func (s stageFunc) Run() error {
+	return s.run()
+}
+
+type levelStage struct {
+	level int
+	stage
+}
+
+var stages []levelStage
+
+// addStage adds s to the stages run at level. Stages at the same level run
+// in the order they were added.
+func addStage(level int, s stage) {
+	stages = append(stages, levelStage{level, s})
+}
+
+// sortStages sorts the stages in order of their level.
+func sortStages() {
+	sort.SliceStable(stages, func(i, j int) bool {
+		return stages[i].level < stages[j].level
+	})
+}
+
+// runStages runs all stages in order of their level.
+func runStages() {
+	sortStages()
+	for _, s := range stages {
+		debug("init: stage %v", s.Name())
+		if err := s.Run(); err != nil {
+			log.Printf("init: %v: %v", s.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestStageOrder pins the order of the stages init comes with; in
+// particular, /etc/fstab is mounted once /buildbin has fsck in it, and
+// the boot state is measured before gettys and uinit run.
+func TestStageOrder(t *testing.T) {
+	sortStages()
+	var got []string
+	for _, s := range stages {
+		got = append(got, s.Name())
+	}
+	want := []string{
+		"rootfs", "initflags",
+		"modules",
+		"rtc",
+		"hwrng",
+		"sysctl",
+		"network",
+		"ntp",
+		"buildbin", "loglevel", "env", "bgbuild",
+		"fstab",
+		"measure",
+		"getty",
+		"uinit",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stages: got %q, want %q", got, want)
+	}
+}
+
+func TestStagesAreStable(t *testing.T) {
+	saved := stages
+	defer func() { stages = saved }()
+	stages = nil
+	for _, s := range []struct {
+		level int
+		name  string
+	}{
+		{uinitLevel, "c"},
+		{measureLevel, "a"},
+		{gettyLevel, "b"},
+		{measureLevel, "a2"},
+	} {
+		addStage(s.level, stageFunc{name: s.name})
+	}
+	sortStages()
+	var got []string
+	for _, s := range stages {
+		got = append(got, s.Name())
+	}
+	if want := []string{"a", "a2", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stages: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/u-root/u-root/pkg/sysctl"
+)
+
+// sysctlFiles are loaded in order, so that a file in /etc/sysctl.d can
+// override /etc/sysctl.conf.
+var sysctlFiles = []string{"/etc/sysctl.conf", "/etc/sysctl.d/*.conf"}
+
+func init() {
+	addStage(sysctlLevel, stageFunc{"sysctl", loadSysctl})
+}
+
+// loadSysctl sets the kernel parameters of the image, once the modules
+// whose parameters they may be are loaded.
+func loadSysctl() error {
+	for _, pat := range sysctlFiles {
+		files, err := filepath.Glob(pat)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if _, err := sysctl.Load(f); err != nil && !os.IsNotExist(err) {
+				log.Printf("init: %v", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+// defaultUinit is run if uroot.uinit= is not on the kernel command line.
+const defaultUinit = "/buildbin/uinit"
+
+func init() {
+	addStage(uinitLevel, stageFunc{"uinit", uinit})
+}
+
+// uinitCmds returns the user's programs: /inito, then uinit, then rush.
+func uinitCmds() []string {
+	u, _ := cmdline.Value("uroot.uinit")
+	if u == "" {
+		u = defaultUinit
+	}
+	return []string{"/inito", u, "/buildbin/rush"}
+}
+
+// uinit hands off to the user's programs, the ones of uinitCmds there are.
+// There may be an inito if we are building on an existing initramfs.
+// inito is always first and we set default flags for it.
+func uinit() error {
+	cloneFlags := uintptr(syscall.CLONE_NEWPID)
+	cmdList := uinitCmds()
+	noCmdFound := true
+	for _, v := range cmdList {
+		if _, err := os.Stat(v); !os.IsNotExist(err) {
+			noCmdFound = false
+			cmd := exec.Command(v)
+			cmd.Stdin = os.Stdin
+			cmd.Stderr = os.Stderr
+			cmd.Stdout = os.Stdout
+			if *test {
+				cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: cloneFlags}
+			} else {
+				cmd.SysProcAttr = &syscall.SysProcAttr{Setctty: true, Setsid: true, Cloneflags: cloneFlags}
+			}
+			debug("Run %v", cmd)
+			if err := cmd.Run(); err != nil {
+				log.Print(err)
+			}
+		}
+		// only the first init needs its own PID space.
+		cloneFlags = 0
+	}
+
+	if noCmdFound {
+		return fmt.Errorf("no suitable executable found in %+v", cmdList)
+	}
+	return nil
+}
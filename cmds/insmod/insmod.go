@@ -8,7 +8,9 @@
 //	insmod [filename] [module options...]
 //
 // Description:
-//	insmod is a clone of insmod(8)
+//	insmod is a clone of insmod(8). The module may be compressed with
+//	gzip, xz or zstd. It does not load the modules it depends on; use
+//	modprobe for that.
 package main
 
 import (
@@ -30,12 +32,7 @@ func main() {
 	// Everything else is module options
 	options := strings.Join(os.Args[2:], " ")
 
-	f, err := os.Open(filename)
-	if err != nil {
-		log.Fatalf("could not open %q: %v", filename, err)
-	}
-
-	if err := kmodule.FileInit(f, options, 0); err != nil {
+	if err := kmodule.Load(filename, options, 0); err != nil {
 		log.Fatalf("insmod: could not load %q: %v", filename, err)
 	}
 }
@@ -0,0 +1,322 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Install copies files and sets their mode and owner.
+//
+// Synopsis:
+//     install [-cDpTv] [-m MODE] [-o OWNER] [-g GROUP] SOURCE DEST
+//     install [-cDpv] [-m MODE] [-o OWNER] [-g GROUP] SOURCE... DIR
+//     install [-cDpv] [-m MODE] [-o OWNER] [-g GROUP] -t DIR SOURCE...
+//     install -d [-v] [-m MODE] [-o OWNER] [-g GROUP] DIR...
+//
+// Description:
+//     install copies each SOURCE to DEST, or into DIR, and sets the mode
+//     of the copy to MODE, 0755 by default, and its owner and group to
+//     OWNER and GROUP, which are names or IDs. A DEST which is there is
+//     replaced, not written over, so that a running program can be.
+//
+//     With -d, install makes each DIR, and its parents, and sets the mode
+//     and owner of DIR.
+//
+//     Flags may be combined, as in install -Dm644 SOURCE DEST.
+//
+// Options:
+//     -c: ignored
+//     -d: make directories
+//     -D: make the parents of DEST, or DIR
+//     -g: set the group to GROUP
+//     -m: set the mode to MODE, in octal
+//     -o: set the owner to OWNER
+//     -p: set the access and modify times of DEST to those of SOURCE
+//     -t: copy the SOURCEs into DIR
+//     -T: DEST is a file, even if it is a directory
+//     -v: print the name of each file and directory made
+//
+// Example:
+//     $ install -Dm644 -o root -g root motd /etc/motd
+//     $ install -d -m 0700 /root/.ssh
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	mode      = flag.String("m", "0755", "set the mode to `mode`, in octal")
+	owner     = flag.String("o", "", "set the owner to `owner`")
+	group     = flag.String("g", "", "set the group to `group`")
+	dirs      = flag.Bool("d", false, "make directories")
+	parents   = flag.Bool("D", false, "make the parents of the destination")
+	targetDir = flag.String("t", "", "copy the sources into `dir`")
+	noTarget  = flag.Bool("T", false, "the destination is a file, even if it is a directory")
+	preserve  = flag.Bool("p", false, "set the times of the destination to those of the source")
+	verbose   = flag.Bool("v", false, "print the name of each file and directory made")
+)
+
+func init() {
+	flag.Bool("c", false, "ignored")
+}
+
+// An installer installs files and directories.
+type installer struct {
+	mode uint32
+	// uid and gid are -1 to leave them as they are.
+	uid, gid int
+	parents  bool
+	preserve bool
+	verbose  io.Writer
+}
+
+// parseMode parses an octal mode, such as 644, or 04755.
+func parseMode(s string) (uint32, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return uint32(m), nil
+}
+
+// lookupID returns the ID of the user or group name, which may be an ID,
+// or -1 for "".
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if name == "" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	s, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s)
+}
+
+func lookupUser(name string) (string, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid user %q", name)
+	}
+	return u.Uid, nil
+}
+
+func lookupGroup(name string) (string, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid group %q", name)
+	}
+	return g.Gid, nil
+}
+
+// attrs sets the owner, group and mode of name. The owner is set first,
+// as chown clears the set-ID bits.
+func (in *installer) attrs(name string) error {
+	if in.uid >= 0 || in.gid >= 0 {
+		if err := os.Chown(name, in.uid, in.gid); err != nil {
+			return err
+		}
+	}
+	if err := syscall.Chmod(name, in.mode); err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	return nil
+}
+
+// mkdirAll makes dir and its parents which are not there, printing
+// those it makes.
+func (in *installer) mkdirAll(dir string) error {
+	if fi, err := os.Stat(dir); err == nil {
+		if !fi.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: syscall.ENOTDIR}
+		}
+		return nil
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := in.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	if in.verbose != nil {
+		fmt.Fprintf(in.verbose, "install: creating directory '%s'\n", dir)
+	}
+	return nil
+}
+
+// dir makes dir, and its parents, and sets its attributes.
+func (in *installer) dir(dir string) error {
+	if err := in.mkdirAll(dir); err != nil {
+		return err
+	}
+	return in.attrs(dir)
+}
+
+// file copies src to dst, and sets its attributes.
+func (in *installer) file(src, dst string) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sf.Close()
+	sfi, err := sf.Stat()
+	if err != nil {
+		return err
+	}
+	if sfi.IsDir() {
+		return fmt.Errorf("omitting directory '%s'", src)
+	}
+	if in.parents {
+		if err := in.mkdirAll(filepath.Dir(dst)); err != nil {
+			return err
+		}
+	}
+
+	// dst is removed, rather than written over, so that it can be a
+	// program which is running, but not when it is src.
+	if dfi, err := os.Stat(dst); err == nil {
+		if os.SameFile(sfi, dfi) {
+			return fmt.Errorf("'%s' and '%s' are the same file", src, dst)
+		}
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	df, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(df, sf)
+	if cerr := df.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = in.attrs(dst)
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	if in.preserve {
+		atime := sfi.ModTime()
+		if st, ok := sfi.Sys().(*syscall.Stat_t); ok {
+			atime = time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+		}
+		if err := os.Chtimes(dst, atime, sfi.ModTime()); err != nil {
+			return err
+		}
+	}
+	if in.verbose != nil {
+		fmt.Fprintf(in.verbose, "'%s' -> '%s'\n", src, dst)
+	}
+	return nil
+}
+
+// into copies srcs into dir.
+func (in *installer) into(dir string, srcs []string) int {
+	status := 0
+	if in.parents {
+		if err := in.mkdirAll(dir); err != nil {
+			log.Printf("install: %v", err)
+			return 1
+		}
+	}
+	for _, src := range srcs {
+		if err := in.file(src, filepath.Join(dir, filepath.Base(src))); err != nil {
+			log.Printf("install: %v", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// args splits combined flags, such as -Dm644, into flags as flag takes
+// them, -D -m 644.
+func args(a []string) []string {
+	out := []string{a[0]}
+	for i := 1; i < len(a); i++ {
+		s := a[i]
+		if s == "--" || len(s) < 2 || s[0] != '-' {
+			return append(out, a[i:]...)
+		}
+		for j := 1; j < len(s); j++ {
+			out = append(out, "-"+s[j:j+1])
+			if strings.IndexByte("mogt", s[j]) < 0 {
+				continue
+			}
+			if j+1 < len(s) {
+				out = append(out, s[j+1:])
+			} else if i+1 < len(a) {
+				i++
+				out = append(out, a[i])
+			}
+			break
+		}
+	}
+	return out
+}
+
+func main() {
+	os.Args = args(os.Args)
+	flag.Parse()
+	args := flag.Args()
+
+	m, err := parseMode(*mode)
+	if err != nil {
+		log.Fatalf("install: %v", err)
+	}
+	uid, err := lookupID(*owner, lookupUser)
+	if err != nil {
+		log.Fatalf("install: %v", err)
+	}
+	gid, err := lookupID(*group, lookupGroup)
+	if err != nil {
+		log.Fatalf("install: %v", err)
+	}
+	in := &installer{mode: m, uid: uid, gid: gid, parents: *parents, preserve: *preserve}
+	if *verbose {
+		in.verbose = os.Stdout
+	}
+
+	switch {
+	case *dirs:
+		status := 0
+		for _, d := range args {
+			if err := in.dir(d); err != nil {
+				log.Printf("install: %v", err)
+				status = 1
+			}
+		}
+		os.Exit(status)
+	case *targetDir != "":
+		os.Exit(in.into(*targetDir, args))
+	case len(args) < 2:
+		log.Printf("install: missing file operand")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	srcs, dst := args[:len(args)-1], args[len(args)-1]
+	if fi, err := os.Stat(dst); !*noTarget && err == nil && fi.IsDir() {
+		os.Exit(in.into(dst, srcs))
+	}
+	if len(srcs) > 1 {
+		log.Fatalf("install: target '%s' is not a directory", dst)
+	}
+	if err := in.file(srcs[0], dst); err != nil {
+		log.Fatalf("install: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want uint32
+		ok   bool
+	}{
+		{"644", 0644, true},
+		{"0755", 0755, true},
+		{"4755", 04755, true},
+		{"10000", 0, false},
+		{"u+x", 0, false},
+		{"8", 0, false},
+	} {
+		got, err := parseMode(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseMode(%q): got %o, %v, want %o, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLookupID(t *testing.T) {
+	if id, err := lookupID("", lookupUser); id != -1 || err != nil {
+		t.Errorf("lookupID(\"\"): got %d, %v, want -1, nil", id, err)
+	}
+	if id, err := lookupID("1234", lookupUser); id != 1234 || err != nil {
+		t.Errorf("lookupID(1234): got %d, %v, want 1234, nil", id, err)
+	}
+	if _, err := lookupID("no such user", lookupUser); err == nil {
+		t.Errorf("lookupID(no such user): got nil, want an error")
+	}
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	want, _ := strconv.Atoi(u.Uid)
+	if id, err := lookupID(u.Username, lookupUser); id != want || err != nil {
+		t.Errorf("lookupID(%q): got %d, %v, want %d, nil", u.Username, id, err, want)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	d, err := ioutil.TempDir("", "install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	src := filepath.Join(d, "src")
+	if err := ioutil.WriteFile(src, []byte("#!/bin/sh\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Unix(1500000000, 0)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	in := &installer{mode: 0750, uid: -1, gid: -1, parents: true, preserve: true, verbose: &out}
+	dst := filepath.Join(d, "a", "b", "dst")
+	if err := in.file(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0750 {
+		t.Errorf("mode of %q: got %v, want %v", dst, fi.Mode(), os.FileMode(0750))
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("modify time of %q: got %v, want %v", dst, fi.ModTime(), mtime)
+	}
+	if b, err := ioutil.ReadFile(dst); err != nil || string(b) != "#!/bin/sh\n" {
+		t.Errorf("contents of %q: got %q, %v, want %q, nil", dst, b, err, "#!/bin/sh\n")
+	}
+	want := "install: creating directory '" + filepath.Join(d, "a") + "'\n" +
+		"install: creating directory '" + filepath.Join(d, "a", "b") + "'\n" +
+		"'" + src + "' -> '" + dst + "'\n"
+	if out.String() != want {
+		t.Errorf("verbose: got %q, want %q", out.String(), want)
+	}
+
+	// A second install replaces dst, rather than writing over it; a
+	// link to it keeps the old one.
+	old := filepath.Join(d, "old")
+	if err := os.Link(dst, old); err != nil {
+		t.Fatal(err)
+	}
+	in = &installer{mode: 0644, uid: -1, gid: -1}
+	if err := in.file(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before, err := os.Stat(old); err != nil || os.SameFile(before, after) {
+		t.Errorf("%q was written over, not replaced", dst)
+	}
+	if after.Mode() != 0644 {
+		t.Errorf("mode of %q: got %v, want %v", dst, after.Mode(), os.FileMode(0644))
+	}
+
+	if err := in.file(dst, dst); err == nil {
+		t.Errorf("installing %q on itself: got nil, want an error", dst)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("installing %q on itself removed it: %v", dst, err)
+	}
+	if err := in.file(d, filepath.Join(d, "x")); err == nil {
+		t.Errorf("installing the directory %q: got nil, want an error", d)
+	}
+	in.parents = false
+	if err := in.file(src, filepath.Join(d, "c", "dst")); !os.IsNotExist(err) {
+		t.Errorf("installing without -D into a missing directory: got %v, want a does not exist error", err)
+	}
+}
+
+func TestInto(t *testing.T) {
+	d, err := ioutil.TempDir("", "install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	var srcs []string
+	for _, n := range []string{"a", "b"} {
+		src := filepath.Join(d, n)
+		if err := ioutil.WriteFile(src, []byte(n), 0600); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, src)
+	}
+	in := &installer{mode: 0755, uid: -1, gid: -1, parents: true}
+	dir := filepath.Join(d, "bin")
+	if status := in.into(dir, srcs); status != 0 {
+		t.Fatalf("into(%q): got %d, want 0", dir, status)
+	}
+	for _, n := range []string{"a", "b"} {
+		if b, err := ioutil.ReadFile(filepath.Join(dir, n)); err != nil || string(b) != n {
+			t.Errorf("contents of %q: got %q, %v, want %q, nil", n, b, err, n)
+		}
+	}
+
+	in.mode = 0700
+	sub := filepath.Join(d, "x", "y")
+	if err := in.dir(sub); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{filepath.Join(d, "x"), os.ModeDir | 0755},
+		{sub, os.ModeDir | 0700},
+	} {
+		if fi, err := os.Stat(tt.name); err != nil || fi.Mode() != tt.mode {
+			t.Errorf("mode of %q: got %v, %v, want %v", tt.name, fi.Mode(), err, tt.mode)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"install", "-Dm644", "a", "b"}, []string{"install", "-D", "-m", "644", "a", "b"}},
+		{[]string{"install", "-dm", "0700", "dir"}, []string{"install", "-d", "-m", "0700", "dir"}},
+		{[]string{"install", "-oroot", "-g", "wheel", "-vt", "bin", "a"}, []string{"install", "-o", "root", "-g", "wheel", "-v", "-t", "bin", "a"}},
+		{[]string{"install", "a", "-b"}, []string{"install", "a", "-b"}},
+	} {
+		if got := args(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("args(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -14,7 +14,9 @@ package main
 //     u-root commands are lazily compiled. Uncompiled commands in the /bin
 //     directory are symbolic links to installcommand. When executed through
 //     the symbolic link, installcommand will build the command from source and
-//     exec it.
+//     exec it. The command is built into /ubin, which is before /buildbin in
+//     the PATH, so it is built once; if it is run again while it is being
+//     built, installcommand waits for the build rather than starting another.
 //
 //     The second form allows commands to be installed and exec'ed without a
 //     symbolic link. In this form additional arguments such as `-v` and
@@ -156,6 +158,21 @@ func main() {
 		run(destFile, form)
 	}
 
+	// A command may be run twice before it is built, e.g. from the shell
+	// while bgbuild builds it. The second waits for the first to build it,
+	// rather than building it again.
+	if l, err := lock(filepath.Join(destDir, "."+form.cmdName+".lock")); err != nil {
+		log.Printf("Can not lock %v, building anyway: %v", form.cmdName, err)
+	} else {
+		defer l.Close()
+		if _, err := os.Stat(destFile); err == nil {
+			if form.onlybuild {
+				os.Exit(0)
+			}
+			run(destFile, form)
+		}
+	}
+
 	// If we are here, things did not go so well. We have to build
 	// the command.  Which means we have to find the source.  Now
 	// that we can add new commands, we need to find out where the
@@ -203,12 +220,15 @@ func main() {
 	}
 	cmd := exec.Command("go", append(a, r)...)
 
+	// The command goes in destDir, whatever GOBIN the shell has.
+	cmd.Env = append(os.Environ(), "GOBIN="+destDir)
+
 	// Set GOGC if unset. The best value is determined empirically and
 	// depends on the machine and Go version. For the workload of compiling
 	// a small Go program, values larger than the default perform better.
 	// See: /scripts/build_perf.sh
 	if _, ok := os.LookupEnv("GOGC"); !ok {
-		cmd.Env = append(os.Environ(), "GOGC=400")
+		cmd.Env = append(cmd.Env, "GOGC=400")
 	}
 
 	cmd.Dir = "/"
@@ -13,3 +13,18 @@ import (
 func exitWithStatus(err *exec.ExitError) {
 	os.Exit(err.Sys().(syscall.WaitStatus).ExitStatus())
 }
+
+// lock waits for, and takes, an exclusive lock on the file n, which is
+// created if need be. The lock is held until the file is closed, or the
+// process execs or exits.
+func lock(n string) (*os.File, error) {
+	f, err := os.OpenFile(n, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -2,105 +2,115 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// io lets you do IO operations.
+// io reads and writes I/O ports and physical memory.
 //
 // Synopsis:
-//     io [inb|inw|inl] address
-//     io [outb|outw|outl] address value
+//     io (inb|inw|inl) PORT...
+//     io (outb|outw|outl) PORT VALUE...
+//     io (rb|rw|rl|rq) ADDRESS...
+//     io (wb|ww|wl|wq) ADDRESS VALUE...
 //
 // Description:
-//     io will let you do IO instructions on various architectures that support it.
+//     io does I/O operations, one after the other, for as many as there
+//     are. in and out read and write bytes, words and longs of I/O ports,
+//     through /dev/port. r and w read and write bytes, words, longs and
+//     quads of physical memory, such as the registers of devices, through
+//     /dev/mem, which is mapped uncached; ADDRESS must be a multiple of
+//     the size. A quad is written as two longs on 32 bit machines.
 //
+//     What is read is printed, in hex.
+//
+// Example:
+//     $ io outb 0x70 0x0a inb 0x71
+//     0x26
+//     $ io rl 0xfed00000
+//     0x0429b17f
 package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
 )
 
-const usage = `io [inb|inw|inl] address
-io [outb|outw|outl] address value`
+const usage = `io (inb|inw|inl) PORT...
+io (outb|outw|outl) PORT VALUE...
+io (rb|rw|rl|rq) ADDRESS...
+io (wb|ww|wl|wq) ADDRESS VALUE...`
 
-type iod struct {
-	nargs    int
-	addrbits int // not all addresses are multiples of 8 in size.
-	val      interface{}
-	valbits  int // not all value bits are multiples of 8 in size.
-	format   string
+// An op is an I/O operation.
+type op struct {
+	// port is whether it is of an I/O port, not of memory.
+	port  bool
+	bits  int
+	write bool
 }
 
-var (
-	ios = map[string]iod{
-		"inb":  {2, 16, &b, 8, "%#02x"},
-		"inw":  {2, 16, &w, 16, "%#04x"},
-		"inl":  {2, 16, &l, 32, "%#08x"},
-		"outb": {3, 16, b, 8, ""},
-		"outw": {3, 16, w, 16, ""},
-		"outl": {3, 16, l, 32, ""},
-	}
-	b    byte
-	w    uint16
-	l    uint32
-	addr uint64
-)
-
-func main() {
-	var err error
-	a := os.Args[1:]
-
-	if len(a) == 0 {
-		log.Fatal(usage)
-	}
-
-	i, ok := ios[a[0]]
-	if !ok || len(a) != i.nargs {
-		log.Fatal(usage)
-	}
+var ops = map[string]op{
+	"inb":  {true, 8, false},
+	"inw":  {true, 16, false},
+	"inl":  {true, 32, false},
+	"outb": {true, 8, true},
+	"outw": {true, 16, true},
+	"outl": {true, 32, true},
+	"rb":   {false, 8, false},
+	"rw":   {false, 16, false},
+	"rl":   {false, 32, false},
+	"rq":   {false, 64, false},
+	"wb":   {false, 8, true},
+	"ww":   {false, 16, true},
+	"wl":   {false, 32, true},
+	"wq":   {false, 64, true},
+}
 
-	addr, err := strconv.ParseUint(a[1], 0, i.addrbits)
-	if err != nil {
-		log.Fatalf("Parsing address for %d bits: %v %v", i.addrbits, a[1], err)
+// run does the operations of args, printing what is read to w.
+func run(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage:\n%s", usage)
 	}
-
-	switch a[0] {
-	case "inb", "inw", "inl":
-		err = in(addr, i.val)
-	case "outb", "outw", "outl":
+	for len(args) > 0 {
+		o, ok := ops[args[0]]
+		n := 2
+		if o.write {
+			n = 3
+		}
+		if !ok || len(args) < n {
+			return fmt.Errorf("usage:\n%s", usage)
+		}
+		addrBits := 64
+		if o.port {
+			addrBits = 16
+		}
+		addr, err := strconv.ParseUint(args[1], 0, addrBits)
+		if err != nil {
+			return fmt.Errorf("%v: address: %v", args[0], err)
+		}
 		var v uint64
-		v, err = strconv.ParseUint(a[2], 0, i.valbits)
+		if o.write {
+			if v, err = strconv.ParseUint(args[2], 0, o.bits); err != nil {
+				return fmt.Errorf("%v: value: %v", args[0], err)
+			}
+		}
+		if o.port {
+			v, err = portIO(addr, o.bits, o.write, v)
+		} else {
+			v, err = memIO(addr, o.bits, o.write, v)
+		}
 		if err != nil {
-			log.Fatalf("%v: %v", a, err)
+			return fmt.Errorf("%v %#x: %v", args[0], addr, err)
 		}
-		switch t := i.val.(type) {
-		case uint8:
-			t = uint8(v)
-		case uint16:
-			t = uint16(v)
-		case uint32:
-			t = uint32(v)
-		default:
-			log.Fatalf("Can't handle %T for %v command", t, a[0])
+		if !o.write {
+			fmt.Fprintf(w, "0x%0*x\n", o.bits/4, v)
 		}
-		err = out(addr, i.val)
-	default:
-		log.Fatalf(usage)
+		args = args[n:]
 	}
+	return nil
+}
 
-	if err != nil {
-		log.Fatalf("%v: %v", a, err)
-	}
-
-	if i.format != "" {
-		switch i.val.(type) {
-		case *uint8:
-			fmt.Printf(i.format, *i.val.(*uint8))
-		case *uint16:
-			fmt.Printf(i.format, *i.val.(*uint16))
-		case *uint32:
-			fmt.Printf(i.format, *i.val.(*uint32))
-		}
-
+func main() {
+	if err := run(os.Stdout, os.Args[1:]); err != nil {
+		log.Fatal(err)
 	}
 }
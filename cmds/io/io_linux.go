@@ -9,30 +9,79 @@ import (
 	"encoding/binary"
 	"fmt"
 	"os"
+	"syscall"
+	"unsafe"
 )
 
-func doio(a16 uint64, f func(*os.File) error) error {
-	port, err := os.OpenFile("/dev/port", os.O_RDWR, 0)
+// The devices of I/O ports and of physical memory. Tests change them.
+var (
+	portDev = "/dev/port"
+	memDev  = "/dev/mem"
+)
+
+// portIO reads or writes the I/O port addr, which are the bytes of
+// /dev/port at addr.
+func portIO(addr uint64, bits int, write bool, v uint64) (uint64, error) {
+	port, err := os.OpenFile(portDev, os.O_RDWR, 0)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer port.Close()
-	_, err = port.Seek(int64(a16), 0)
-	if err != nil {
-		return fmt.Errorf("in: bad address %v: %v", a16, err)
+	var b [8]byte
+	if write {
+		binary.LittleEndian.PutUint64(b[:], v)
+		_, err = port.WriteAt(b[:bits/8], int64(addr))
+		return 0, err
 	}
-	return f(port)
-}
-
-func in(a16 uint64, data interface{}) error {
-	return doio(a16, func(port *os.File) error {
-		return binary.Read(port, binary.LittleEndian, data)
-	})
+	if _, err := port.ReadAt(b[:bits/8], int64(addr)); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
 }
 
-func out(a16 uint64, data interface{}) error {
-	return doio(a16, func(port *os.File) error {
-		return binary.Write(port, binary.LittleEndian, data)
-	})
-
+// memIO reads or writes the physical memory at addr, by mapping the page it
+// is in, with one access of the size. Reading or writing /dev/mem would
+// copy it a byte at a time, which the registers of devices often do not
+// like.
+func memIO(addr uint64, bits int, write bool, v uint64) (uint64, error) {
+	if addr%uint64(bits/8) != 0 {
+		return 0, fmt.Errorf("address is not a multiple of %d", bits/8)
+	}
+	mode, prot := os.O_RDONLY, syscall.PROT_READ
+	if write {
+		mode, prot = os.O_RDWR, syscall.PROT_READ|syscall.PROT_WRITE
+	}
+	// O_SYNC makes the mapping uncached.
+	mem, err := os.OpenFile(memDev, mode|os.O_SYNC, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer mem.Close()
+	page := uint64(os.Getpagesize())
+	off := addr &^ (page - 1)
+	m, err := syscall.Mmap(int(mem.Fd()), int64(off), int(page), prot, syscall.MAP_SHARED)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.Munmap(m)
+	p := unsafe.Pointer(&m[addr-off])
+	switch {
+	case bits == 8 && write:
+		*(*uint8)(p) = uint8(v)
+	case bits == 8:
+		v = uint64(*(*uint8)(p))
+	case bits == 16 && write:
+		*(*uint16)(p) = uint16(v)
+	case bits == 16:
+		v = uint64(*(*uint16)(p))
+	case bits == 32 && write:
+		*(*uint32)(p) = uint32(v)
+	case bits == 32:
+		v = uint64(*(*uint32)(p))
+	case write:
+		*(*uint64)(p) = v
+	default:
+		v = *(*uint64)(p)
+	}
+	return v, nil
 }
@@ -0,0 +1,59 @@
+// Copyright 2012-2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIO(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	portDev, memDev = filepath.Join(tmp, "port"), filepath.Join(tmp, "mem")
+	if err := ioutil.WriteFile(portDev, make([]byte, 0x10000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(memDev, make([]byte, 2*os.Getpagesize()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		args string
+		want string
+	}{
+		{"outw 0x80 0x1234 inw 0x80 inb 0x80 inb 0x81 inl 0x80", "0x1234\n0x34\n0x12\n0x00001234\n"},
+		{"outl 0xfffc 0xdeadbeef inl 0xfffc", "0xdeadbeef\n"},
+		{"wq 0x1008 0x0123456789abcdef rq 0x1008 rl 0x100c rw 0x1008 rb 0x100f", "0x0123456789abcdef\n0x01234567\n0xcdef\n0x01\n"},
+		{"wb 0x1001 0xff rl 0x1000", "0x0000ff00\n"},
+	} {
+		var b bytes.Buffer
+		if err := run(&b, strings.Fields(tt.args)); err != nil || b.String() != tt.want {
+			t.Errorf("io %v: got %q, %v, want %q, nil", tt.args, b.String(), err, tt.want)
+		}
+	}
+
+	for _, args := range []string{
+		"",
+		"inb",
+		"outb 0x80",
+		"inx 0x80",
+		"inb 0x10000",
+		"outb 0x80 0x100",
+		"rl 0x1002",
+		"inb 0x80 outb",
+	} {
+		if err := run(ioutil.Discard, strings.Fields(args)); err == nil {
+			t.Errorf("io %v: got nil, want error", args)
+		}
+	}
+}
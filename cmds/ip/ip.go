@@ -2,16 +2,35 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Show and change interfaces, addresses and routes.
+//
+// Synopsis:
+//     ip addr [show [[dev] DEVICE]]
+//     ip addr {add|del} CIDR [dev] DEVICE
+//     ip link [show [[dev] DEVICE]]
+//     ip link set [dev] DEVICE [up|down] [mtu MTU] [name NAME] [address MAC]
+//     ip route [show]
+//     ip route {add|del} {default|CIDR} [via GATEWAY] [dev DEVICE]
+//
+// Description:
+//     ip talks to the kernel over netlink. Commands and keywords may be
+//     shortened to any unique prefix.
+//
+// Example:
+//     ip link set eth0 up
+//     ip addr add 10.0.2.15/24 dev eth0
+//     ip route add default via 10.0.2.2 dev eth0
 package main
 
 import (
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	l "log"
 	"math"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/vishvananda/netlink"
@@ -91,10 +110,17 @@ func dev() netlink.Link {
 	return iface
 }
 
+// showLinks shows all the interfaces or, if there is a device name left
+// in the arguments, just that one.
 func showLinks(w io.Writer, withAddresses bool) {
-	ifaces, err := netlink.LinkList()
-	if err != nil {
-		log.Fatalf("Can't enumerate interfaces? %v", err)
+	var ifaces []netlink.Link
+	if cursor < len(arg)-1 {
+		ifaces = []netlink.Link{dev()}
+	} else {
+		var err error
+		if ifaces, err = netlink.LinkList(); err != nil {
+			log.Fatalf("Can't enumerate interfaces? %v", err)
+		}
 	}
 
 	for _, v := range ifaces {
@@ -159,11 +185,14 @@ func addrip() {
 		return
 	}
 	cursor++
-	whatIWant = []string{"add", "del"}
+	whatIWant = []string{"add", "del", "show"}
 	cmd := arg[cursor]
 
 	c := one(cmd, whatIWant)
 	switch c {
+	case "show":
+		showLinks(os.Stdout, true)
+		return
 	case "add", "del":
 		cursor++
 		whatIWant = []string{"CIDR format address"}
@@ -192,28 +221,51 @@ func addrip() {
 }
 
 func linkshow() {
-	cursor++
 	whatIWant = []string{"<nothing>", "<device name>"}
-	if len(arg[cursor:]) == 0 {
-		showLinks(os.Stdout, false)
-	}
+	showLinks(os.Stdout, false)
 }
 
+// linkset does each of the settings which follow the device name, in order.
 func linkset() {
 	iface := dev()
-	cursor++
-	whatIWant = []string{"up", "down"}
-	switch one(arg[cursor], whatIWant) {
-	case "up":
-		if err := netlink.LinkSetUp(iface); err != nil {
-			log.Fatalf("%v can't make it up: %v", iface, err)
+	name := iface.Attrs().Name
+	for cursor < len(arg)-1 {
+		cursor++
+		whatIWant = []string{"up", "down", "mtu", "name", "address"}
+		var err error
+		switch one(arg[cursor], whatIWant) {
+		case "up":
+			err = netlink.LinkSetUp(iface)
+		case "down":
+			err = netlink.LinkSetDown(iface)
+		case "mtu":
+			cursor++
+			whatIWant = []string{"MTU"}
+			mtu, e := strconv.Atoi(arg[cursor])
+			if e != nil {
+				usage()
+			}
+			err = netlink.LinkSetMTU(iface, mtu)
+		case "name":
+			cursor++
+			whatIWant = []string{"new device name"}
+			if err = netlink.LinkSetName(iface, arg[cursor]); err == nil {
+				name = arg[cursor]
+			}
+		case "address":
+			cursor++
+			whatIWant = []string{"MAC address"}
+			mac, e := net.ParseMAC(arg[cursor])
+			if e != nil {
+				usage()
+			}
+			err = netlink.LinkSetHardwareAddr(iface, mac)
+		default:
+			usage()
 		}
-	case "down":
-		if err := netlink.LinkSetDown(iface); err != nil {
-			log.Fatalf("%v can't make it down: %v", iface, err)
+		if err != nil {
+			log.Fatalf("%v: can't set %v: %v", name, arg[cursor], err)
 		}
-	default:
-		usage()
 	}
 }
 
@@ -238,61 +290,86 @@ func link() {
 	return
 }
 
+// routeString formats r the way ip route show does. name is the name of
+// the device of the route.
+func routeString(r netlink.Route, name string) string {
+	s := "default"
+	if r.Dst != nil {
+		s = r.Dst.String()
+	}
+	if r.Gw != nil {
+		s += " via " + r.Gw.String()
+	}
+	if name != "" {
+		s += " dev " + name
+	}
+	if r.Scope != netlink.SCOPE_UNIVERSE {
+		s += " scope " + addrScopes[r.Scope]
+	}
+	if r.Src != nil {
+		s += " src " + r.Src.String()
+	}
+	return s
+}
+
 func routeshow() {
-	if b, err := ioutil.ReadFile("/proc/net/route"); err == nil {
-		log.Printf("%s", string(b))
-	} else {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
 		log.Fatalf("Route show failed: %v", err)
 	}
+	for _, r := range routes {
+		var name string
+		if l, err := netlink.LinkByIndex(r.LinkIndex); err == nil {
+			name = l.Attrs().Name
+		}
+		fmt.Println(routeString(r, name))
+	}
 }
 
-func nodespec() string {
+func nodespec() *net.IPNet {
 	cursor++
 	whatIWant = []string{"default", "CIDR"}
-	return arg[cursor]
-}
-
-func nexthop() (string, *netlink.Addr) {
-	cursor++
-	whatIWant = []string{"via"}
-	if arg[cursor] != "via" {
-		usage()
+	if arg[cursor] == "default" {
+		return nil
 	}
-	nh := arg[cursor]
-	cursor++
-	whatIWant = []string{"Gateway CIDR"}
-	addr, err := netlink.ParseAddr(arg[cursor])
+	_, dst, err := net.ParseCIDR(arg[cursor])
 	if err != nil {
-		log.Fatalf("Gateway CIDR: %v", err)
+		usage()
 	}
-	return nh, addr
+	return dst
 }
 
-func routeadddefault() {
-	nh, nhval := nexthop()
-	// TODO: NHFLAGS.
-	l := dev()
-	switch nh {
-	case "via":
-		log.Printf("Add default route %v via %v", nhval, l)
-		r := &netlink.Route{LinkIndex: l.Attrs().Index, Gw: nhval.IPNet.IP}
-		if err := netlink.RouteAdd(r); err != nil {
-			log.Fatalf("Add default route: %v", err)
-		}
-
-	default:
+// gateway takes an address with or without a prefix length.
+func gateway() net.IP {
+	cursor++
+	whatIWant = []string{"Gateway address"}
+	if ip, _, err := net.ParseCIDR(arg[cursor]); err == nil {
+		return ip
+	}
+	ip := net.ParseIP(arg[cursor])
+	if ip == nil {
 		usage()
 	}
+	return ip
 }
 
-func routeadd() {
-	ns := nodespec()
-	switch ns {
-	case "default":
-		routeadddefault()
-	default:
-		usage()
+// routespec parses the route given to route add and del.
+func routespec() *netlink.Route {
+	r := &netlink.Route{Dst: nodespec()}
+	for cursor < len(arg)-1 {
+		cursor++
+		whatIWant = []string{"via", "dev"}
+		switch arg[cursor] {
+		case "via":
+			r.Gw = gateway()
+		case "dev":
+			cursor--
+			r.LinkIndex = dev().Attrs().Index
+		default:
+			usage()
+		}
 	}
+	return r
 }
 
 func route() {
@@ -302,12 +379,18 @@ func route() {
 		return
 	}
 
-	whatIWant = []string{"show", "add"}
+	whatIWant = []string{"show", "add", "del"}
 	switch one(arg[cursor], whatIWant) {
 	case "show":
 		routeshow()
 	case "add":
-		routeadd()
+		if err := netlink.RouteAdd(routespec()); err != nil {
+			log.Fatalf("Route add failed: %v", err)
+		}
+	case "del":
+		if err := netlink.RouteDel(routespec()); err != nil {
+			log.Fatalf("Route del failed: %v", err)
+		}
 	default:
 		usage()
 	}
@@ -0,0 +1,32 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestRouteString(t *testing.T) {
+	_, dst, err := net.ParseCIDR("10.0.2.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		r    netlink.Route
+		name string
+		want string
+	}{
+		{netlink.Route{Gw: net.IPv4(10, 0, 2, 2)}, "eth0", "default via 10.0.2.2 dev eth0"},
+		{netlink.Route{Dst: dst, Scope: netlink.SCOPE_LINK, Src: net.IPv4(10, 0, 2, 15)}, "eth0", "10.0.2.0/24 dev eth0 scope link src 10.0.2.15"},
+		{netlink.Route{Dst: dst}, "", "10.0.2.0/24"},
+	} {
+		if got := routeString(tt.r, tt.name); got != tt.want {
+			t.Errorf("routeString(%v, %q): got %q, want %q", tt.r, tt.name, got, tt.want)
+		}
+	}
+}
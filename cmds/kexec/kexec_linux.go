@@ -10,6 +10,9 @@
 // Description:
 //		 Loads a kernel for later execution.
 //
+//     If there is a TPM, the kernel, initramfs and command line are measured
+//     into it before they are loaded.
+//
 // Options:
 //     --cmdline=STRING:       command line for kernel
 //     --command-line=STRING:  command line for kernel
@@ -30,6 +33,7 @@ import (
 	"os"
 
 	"github.com/u-root/u-root/pkg/kexec"
+	"github.com/u-root/u-root/pkg/measurement"
 )
 
 type options struct {
@@ -59,6 +63,28 @@ func registerFlags(f *flag.FlagSet) *options {
 	return o
 }
 
+// measure measures the kernel, initramfs and command line into the TPM, if
+// there is one.
+func measure(kernel, initramfs, cmdline string) error {
+	m, err := measurement.Open()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	for _, f := range []string{kernel, initramfs} {
+		if f == "" {
+			continue
+		}
+		if err := m.File(f); err != nil {
+			return err
+		}
+	}
+	return m.Data("kernel command line", []byte(cmdline))
+}
+
 func main() {
 	opts := registerFlags(flag.CommandLine)
 	flag.Parse()
@@ -97,6 +123,17 @@ func main() {
 		}
 		defer kernel.Close()
 
+		// kexec_file_load checks the kernel too, but its errors
+		// are less helpful.
+		if b, err := kexec.ParseBzImage(kernel); err == nil {
+			if b.KernelVersion != "" {
+				log.Printf("Kernel version %s", b.KernelVersion)
+			}
+			if err := b.CheckCmdline(cmdline); err != nil {
+				log.Fatalf("%s: %v", kernelpath, err)
+			}
+		}
+
 		var ramfs *os.File
 		if opts.initramfs != "" {
 			ramfs, err = os.OpenFile(opts.initramfs, os.O_RDONLY, 0)
@@ -106,6 +143,10 @@ func main() {
 			defer ramfs.Close()
 		}
 
+		if err := measure(kernelpath, opts.initramfs, cmdline); err != nil {
+			log.Fatalf("%v", err)
+		}
+
 		if err := kexec.FileLoad(kernel, ramfs, cmdline); err != nil {
 			log.Fatalf("%v", err)
 		}
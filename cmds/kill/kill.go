@@ -8,6 +8,10 @@
 //     kill -l
 //     kill [<-s | --signal | -> <isgname|signum>] pid [pid...]
 //
+// Description:
+//     Signal names may be given with or without SIG, in any case, e.g.
+//     -SIGHUP, -HUP or -hup. To signal processes by name, use pkill.
+//
 // Options:
 //     -l:                       list the signal names
 //     -name, --signal name, -s: name is the message to send. On some systems
@@ -20,6 +24,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 const eUsage = "Usage: kill -l | kill [<-s | --signal | -> <signame|signum>] pid [pid...]"
@@ -34,7 +39,20 @@ func die(msg string, args ...interface{}) {
 	os.Exit(1)
 }
 
+// signal looks up the signal named s.
+func signal(s string) (os.Signal, bool) {
+	u := strings.ToUpper(s)
+	if sig, ok := signums[u]; ok {
+		return sig, true
+	}
+	sig, ok := signums["SIG"+u]
+	return sig, ok
+}
+
 func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
 	op := os.Args[1]
 	pids := os.Args[2:]
 	if op[0] != '-' {
@@ -51,7 +69,7 @@ func main() {
 	// Also, note, the -l has no meaning on Plan 9 or Harvey
 	// since signals on those systems are arbitrary strings.
 
-	if op[0:2] == "-l" {
+	if op == "-l" {
 		if len(os.Args) > 2 {
 			usage()
 		}
@@ -73,7 +91,7 @@ func main() {
 		op = op[1:]
 	}
 
-	s, ok := signums[op]
+	s, ok := signal(op)
 	if !ok {
 		die("%v is not a valid signal", op)
 	}
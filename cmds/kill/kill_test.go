@@ -80,6 +80,9 @@ func TestBadInvocations(t *testing.T) {
 			{a: []string{"--signal"}, err: eUsage + "\n"},
 			{a: []string{"--signal", "a"}, err: "a is not a valid signal\n"},
 			{a: []string{"-1", "a"}, err: "Some processes could not be killed: [a: arguments must be process or job IDS]\n"},
+			{a: []string{"-hup", "a"}, err: "Some processes could not be killed: [a: arguments must be process or job IDS]\n"},
+			{a: []string{"-SIGFOO", "1"}, err: "SIGFOO is not a valid signal\n"},
+			{a: []string{}, err: eUsage + "\n"},
 		}
 	)
 
@@ -205,7 +205,7 @@ var (
 
 func siglist() (s string) {
 	for i, sig := range signames {
-		s = s + fmt.Sprintf("%d: %v\n", i, sig)
+		s = s + fmt.Sprintf("%d: %v\n", i+1, sig)
 	}
 	return
 }
@@ -0,0 +1,99 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Ldd prints the shared libraries which programs need.
+//
+// Synopsis:
+//     ldd [-v] FILE...
+//
+// Description:
+//     ldd prints, for each FILE, the shared libraries it needs, directly
+//     or not, and where they are found, in the form NAME => PATH; and its
+//     program interpreter. It does not run the program, or its
+//     interpreter, so it works on programs for other architectures too.
+//     Libraries which can not be found are printed as NAME => not found,
+//     and ldd exits 1. It is handy to find out why a program copied
+//     into the initramfs does not start.
+//
+//     Libraries are looked for as ld.so does, in the DT_RPATH of the
+//     program, $LD_LIBRARY_PATH, its DT_RUNPATH, the directories of
+//     /etc/ld.so.conf, and /lib and /usr/lib.
+//
+// Options:
+//     -v: also print which file needs each library
+//
+// Example:
+//     ldd /bin/date
+//         /lib64/ld-linux-x86-64.so.2
+//         libc.so.6 => /lib/x86_64-linux-gnu/libc.so.6
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/ldd"
+)
+
+var verbose = flag.Bool("v", false, "Print which file needs each library")
+
+// list prints the dependencies of name to w, and returns whether they were
+// all found.
+func list(w io.Writer, name string, verbose bool) (bool, error) {
+	deps, err := ldd.Deps(name)
+	if _, ok := err.(*elf.FormatError); ok {
+		fmt.Fprintf(w, "\tnot a dynamic executable\n")
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(deps) == 0 {
+		fmt.Fprintf(w, "\tstatically linked\n")
+		return true, nil
+	}
+	ok := true
+	for _, d := range deps {
+		switch {
+		case d.Path == "":
+			fmt.Fprintf(w, "\t%s => not found", d.Name)
+			ok = false
+		case d.Name == d.Path:
+			fmt.Fprintf(w, "\t%s", d.Path)
+		default:
+			fmt.Fprintf(w, "\t%s => %s", d.Name, d.Path)
+		}
+		if verbose {
+			fmt.Fprintf(w, " (needed by %s)", d.By)
+		}
+		fmt.Fprintln(w)
+	}
+	return ok, nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("ldd: missing file arguments")
+	}
+	status := 0
+	for _, n := range flag.Args() {
+		if flag.NArg() > 1 {
+			fmt.Printf("%s:\n", n)
+		}
+		ok, err := list(os.Stdout, n, *verbose)
+		if err != nil {
+			log.Printf("ldd: %v", err)
+			status = 1
+		}
+		if !ok {
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
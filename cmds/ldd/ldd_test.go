@@ -0,0 +1,43 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	f, err := ioutil.TempFile("", "ldd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("#!/bin/sh\n")
+	f.Close()
+
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"/bin/date", "\tlibc.so.6 => /"},
+		{f.Name(), "\tnot a dynamic executable\n"},
+	} {
+		var b bytes.Buffer
+		ok, err := list(&b, tt.name, false)
+		if err != nil || !ok {
+			t.Errorf("list(%q): got %v, %v, want true, nil", tt.name, ok, err)
+		}
+		if !strings.Contains(b.String(), tt.want) {
+			t.Errorf("list(%q): got %q, want it to have %q", tt.name, b.String(), tt.want)
+		}
+	}
+	if _, err := list(&bytes.Buffer{}, "/no/such/file", false); err == nil {
+		t.Errorf("list(%q): got nil, want an error", "/no/such/file")
+	}
+}
@@ -5,12 +5,16 @@
 // Ln makes links to files.
 //
 // Synopsis:
-//     ln [-svfTiLPrt] TARGET LINK
+//     ln [-svfnTiLPr] [-t DIR] TARGET... [LINK]
+//
+// Description:
+//     Flags may be combined, as in ln -sf TARGET LINK.
 //
 // Options:
 //     -s: make symbolic links instead of hard links
 //     -v: print name of each linked file
 //     -f: remove destination files
+//     -n: treat linkname operand as a non-dir if it is a symlink to a dir
 //     -T: treat linkname operand as a non-dir always
 //     -i: prompt if the user wants overwrite
 //     -L: dereference targets if are symbolic links
@@ -41,6 +45,7 @@ type config struct {
 	logical  bool
 	physical bool
 	relative bool
+	noderef  bool
 	dirtgt   string
 }
 
@@ -69,7 +74,11 @@ func (conf *config) evalArgs(args []string) (targets []string, linkName string)
 	targets = args[:len(args)-1]
 	lastArg := args[len(args)-1]
 
-	if lf, err := os.Stat(lastArg); !conf.nondir && err == nil && lf.IsDir() {
+	stat := os.Stat
+	if conf.noderef {
+		stat = os.Lstat
+	}
+	if lf, err := stat(lastArg); !conf.nondir && err == nil && lf.IsDir() {
 		conf.dirtgt = lastArg
 	} else {
 		linkName = lastArg
@@ -81,16 +90,16 @@ func (conf *config) evalArgs(args []string) (targets []string, linkName string)
 // relLink get the relative link path between
 // a target and linkName fpath
 // between a linkName operand and the target
-// HMM, i don't have sure if that works well...
 func relLink(target, linkName string) (string, error) {
-	base := filepath.Dir(linkName)
-	if newTarget, err := filepath.Rel(base, target); err == nil {
-		return newTarget, nil
-	} else if absLink, err := filepath.Abs(linkName); err == nil {
-		return filepath.Rel(absLink, target)
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
 	}
-
-	return "", nil
+	absLink, err := filepath.Abs(linkName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(filepath.Dir(absLink), absTarget)
 }
 
 // inferLinkname infers the linkName if don't passed ("")
@@ -127,6 +136,10 @@ func (conf config) dereferTarget(target string, linkFunc *func(string, string) e
 func (conf config) ln(args []string) error {
 	var remove bool
 
+	if conf.relative && !conf.symlink {
+		return fmt.Errorf("cannot do -r without -s")
+	}
+
 	linkFunc := os.Link
 	if conf.symlink {
 		linkFunc = os.Symlink
@@ -168,10 +181,6 @@ func (conf config) ln(args []string) error {
 			}
 		}
 
-		if conf.relative && !conf.symlink {
-			return fmt.Errorf("cannot do -r without -s")
-		}
-
 		// make relative paths with symlinks
 		if conf.relative {
 			if relTarget, err := relLink(target, linkName); err != nil {
@@ -207,23 +216,52 @@ func (conf config) ln(args []string) error {
 	return nil
 }
 
+// args splits combined flags, such as -sf, into flags as flag takes
+// them, -s -f. The last may be -t, with its DIR attached, or next.
+func args(a []string) []string {
+	out := []string{a[0]}
+	for i := 1; i < len(a); i++ {
+		s := a[i]
+		if s == "--" || len(s) < 2 || s[0] != '-' {
+			return append(out, a[i:]...)
+		}
+		for j := 1; j < len(s); j++ {
+			out = append(out, "-"+s[j:j+1])
+			if s[j] != 't' {
+				continue
+			}
+			if j+1 < len(s) {
+				out = append(out, s[j+1:])
+			} else if i+1 < len(a) {
+				i++
+				out = append(out, a[i])
+			}
+			break
+		}
+	}
+	return out
+}
+
 func main() {
 	var conf config
 	flag.BoolVar(&conf.symlink, "s", false, "make symbolic links instead of hard links")
 	flag.BoolVar(&conf.verbose, "v", false, "print name of each linked file")
 	flag.BoolVar(&conf.force, "f", false, "remove destination files")
+	flag.BoolVar(&conf.noderef, "n", false, "treat linkname operand as a non-dir if it is a symlink to a dir")
 	flag.BoolVar(&conf.nondir, "T", false, "treat linkname operand as a non-dir always")
 	flag.BoolVar(&conf.prompt, "i", false, "prompt if the user wants overwrite")
 	flag.BoolVar(&conf.logical, "L", false, "dereference targets if are symbolic links")
 	flag.BoolVar(&conf.physical, "P", false, "make hard links directly to symbolic links")
 	flag.BoolVar(&conf.relative, "r", false, "create symlinks relative to link location")
 	flag.StringVar(&conf.dirtgt, "t", "", "specify the directory to put the links")
+	os.Args = args(os.Args)
 	flag.Parse()
 
 	args := flag.Args()
 	if len(args) == 0 {
 		log.Printf("ln: missing file operand")
 		flag.Usage()
+		os.Exit(1)
 	}
 
 	if err := conf.ln(args); err != nil {
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -145,6 +146,13 @@ func loadTests() []test {
 			},
 			"ln -i -f a overwrite",
 		},
+		{
+			config{symlink: true, force: true},
+			[]string{"a", "b"},
+			[]result{{symlink: true, name: "b", linksTo: "a"}},
+			[]create{{name: "a"}, {name: "b"}},
+			"ln -sf a b",
+		},
 	}
 }
 
@@ -280,3 +288,69 @@ func TestLn(t *testing.T) {
 		os.Chdir("..")
 	}
 }
+
+// TestNoDeref tests that with -n, a symlink to a dir is replaced rather
+// than linked into, as ln -sfn does.
+func TestNoDeref(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestNoDeref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	for _, dir := range []string{"old", "new"} {
+		if err := os.Mkdir(filepath.Join(d, dir), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(d, "current")
+	if err := os.Symlink("old", link); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config{symlink: true, force: true, noderef: true}
+	if err := conf.ln([]string{"new", link}); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := os.Readlink(link); err != nil || s != "new" {
+		t.Errorf("readlink %q: got %q, %v, want \"new\", nil", link, s, err)
+	}
+	if _, err := os.Lstat(filepath.Join(d, "old", "new")); !os.IsNotExist(err) {
+		t.Errorf("ln -sfn linked into the old dir")
+	}
+}
+
+func TestRelLink(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		target, linkName, want string
+	}{
+		{"cp", "folder/cp", "../cp"},
+		{"bin/cp", "cp", "bin/cp"},
+		{filepath.Join(wd, "bin/cp"), "sbin/cp", "../bin/cp"},
+		{"/usr/bin/cp", "/usr/local/bin/cp", "../../bin/cp"},
+	} {
+		got, err := relLink(tt.target, tt.linkName)
+		if err != nil || got != tt.want {
+			t.Errorf("relLink(%q, %q): got %q, %v, want %q, nil", tt.target, tt.linkName, got, err, tt.want)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"ln", "-sf", "a", "b"}, []string{"ln", "-s", "-f", "a", "b"}},
+		{[]string{"ln", "-s", "-vt", "dir", "a"}, []string{"ln", "-s", "-v", "-t", "dir", "a"}},
+		{[]string{"ln", "-srtdir", "a"}, []string{"ln", "-s", "-r", "-t", "dir", "a"}},
+		{[]string{"ln", "a", "-b"}, []string{"ln", "a", "-b"}},
+		{[]string{"ln", "--", "-a", "b"}, []string{"ln", "--", "-a", "b"}},
+	} {
+		if got := args(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("args(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -5,12 +5,28 @@
 // Setup loop devices.
 //
 // Synopsis:
-//     losetup [-Ad] FILE
-//     losetup [-Ad] DEV FILE
+//     losetup [-a]
+//     losetup -f
+//     losetup [-Pr] -f FILE
+//     losetup [-Pr] DEV FILE
+//     losetup -d DEV...
+//
+// Description:
+//     With no arguments, or -a, losetup lists the loop devices which are
+//     attached to files. -f alone prints the name of a free device; with
+//     FILE, it attaches FILE to a free device and prints its name.
 //
 // Options:
-//     -A: pick any device
-//     -d: detach the device
+//     -a: list the devices in use
+//     -f: pick a free device
+//     -A: the same as -f
+//     -d: detach the devices
+//     -P: scan the device for partitions, e.g. /dev/loop0p1
+//     -r: attach read only
+//
+// Example:
+//     dev=`losetup -P -f disk.img`
+//     mount ${dev}p1 /mnt
 package main
 
 import (
@@ -18,87 +34,84 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"syscall"
-)
 
-const (
-	/*
-	 * IOCTL commands --- we will commandeer 0x4C ('L')
-	 */
-	LOOP_SET_CAPACITY = 0x4C07
-	LOOP_CHANGE_FD    = 0x4C06
-	LOOP_GET_STATUS64 = 0x4C05
-	LOOP_SET_STATUS64 = 0x4C04
-	LOOP_GET_STATUS   = 0x4C03
-	LOOP_SET_STATUS   = 0x4C02
-	LOOP_CLR_FD       = 0x4C01
-	LOOP_SET_FD       = 0x4C00
-	LO_NAME_SIZE      = 64
-	LO_KEY_SIZE       = 32
-	/* /dev/loop-control interface */
-	LOOP_CTL_ADD      = 0x4C80
-	LOOP_CTL_REMOVE   = 0x4C81
-	LOOP_CTL_GET_FREE = 0x4C82
-	SYS_ioctl         = 16
+	"github.com/u-root/u-root/pkg/mount/loop"
 )
 
 var (
-	anyLoop = flag.Bool("A", true, "Pick any device")
-	detach  = flag.Bool("d", false, "Detach the device")
-	l       = log.New(os.Stdout, "tcz: ", 0)
+	list     = flag.Bool("a", false, "List the devices in use")
+	free     = flag.Bool("f", false, "Pick a free device")
+	anyLoop  = flag.Bool("A", false, "Pick a free device; the same as -f")
+	detach   = flag.Bool("d", false, "Detach the devices")
+	partScan = flag.Bool("P", false, "Scan the device for partitions")
+	readOnly = flag.Bool("r", false, "Attach read only")
 )
 
-// consider making this a goroutine which pushes the string down the channel.
-func findloop() (name string, err error) {
-	cfd, err := syscall.Open("/dev/loop-control", syscall.O_RDWR, 0)
-	if err != nil {
-		log.Fatalf("/dev/loop-control: %v", err)
-	}
-	defer syscall.Close(cfd)
-	a, b, errno := syscall.Syscall(SYS_ioctl, uintptr(cfd), LOOP_CTL_GET_FREE, 0)
-	if errno != 0 {
-		log.Fatalf("ioctl: %v\n", err)
-	}
-	log.Printf("a %v b %v err %v\n", a, b, err)
-	name = fmt.Sprintf("/dev/loop%d", a)
-	return name, nil
+func usage() {
+	log.Fatalf("Usage: losetup [-a] | -f | [-Pr] -f FILE | [-Pr] DEV FILE | -d DEV...")
 }
 
 func main() {
 	flag.Parse()
 	args := flag.Args()
-	if *detach {
-		l.Fatalf("detach: not yet")
-		os.Exit(1)
-	}
-	var file, dev string
-	var err error
-	if len(args) == 1 {
-		dev, err = findloop()
+	*free = *free || *anyLoop
+
+	switch {
+	case *detach:
+		if len(args) == 0 {
+			usage()
+		}
+		status := 0
+		for _, d := range args {
+			if err := loop.Detach(d); err != nil {
+				log.Print(err)
+				status = 1
+			}
+		}
+		os.Exit(status)
+
+	case *list || len(args) == 0 && !*free:
+		if len(args) != 0 {
+			usage()
+		}
+		devs, err := loop.List()
 		if err != nil {
-			l.Fatalf("can't find a loop: %v\n", err)
-			os.Exit(1)
+			log.Fatal(err)
+		}
+		for _, d := range devs {
+			fmt.Printf("%s: %s\n", d.Name, d.File)
+		}
+		return
+	}
+
+	var dev, file string
+	switch {
+	case *free && len(args) <= 1:
+		var err error
+		if dev, err = loop.FindDevice(); err != nil {
+			log.Fatal(err)
+		}
+		if len(args) == 0 {
+			fmt.Println(dev)
+			return
 		}
 		file = args[0]
-	} else if len(args) == 2 {
-		dev = args[0]
-		file = args[1]
-	} else {
-		l.Fatalf("usage\n")
-		os.Exit(1)
+	case !*free && len(args) == 2:
+		dev, file = args[0], args[1]
+	default:
+		usage()
 	}
 
-	ffd, err := syscall.Open(file, syscall.O_RDONLY, 0)
-	if err != nil {
-		l.Fatalf("file: %v, %v\n", file, err)
+	var flags uint32
+	if *partScan {
+		flags |= loop.FlagsPartScan
 	}
-	lfd, err := syscall.Open(dev, syscall.O_RDONLY, 0)
+	l, err := loop.Attach(dev, file, *readOnly, flags)
 	if err != nil {
-		l.Fatalf("dev: %v, %v\n", dev, err)
+		log.Fatal(err)
 	}
-	l.Printf("ffd %v lfd %v\n", ffd, lfd)
-	a, b, errno := syscall.Syscall(SYS_ioctl, uintptr(lfd), LOOP_SET_FD, uintptr(ffd))
-	if errno != 0 {
-		l.Fatalf("loop set fd ioctl: %v, %v, %v\n", a, b, errno)
+	l.Close()
+	if *free {
+		fmt.Println(dev)
 	}
 }
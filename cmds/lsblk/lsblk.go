@@ -0,0 +1,258 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Lsblk lists block devices.
+//
+// Synopsis:
+//     lsblk [-a] [-b] [-f] [DEV...]
+//
+// Description:
+//     lsblk lists the block devices in /sys/block, or the DEVs, with their
+//     partitions under them. For each it prints its name, device number,
+//     whether it is removable, its size, whether it is read only, its type
+//     and where it is mounted.
+//
+// Options:
+//     -a: list empty devices too
+//     -b: print sizes in bytes
+//     -f: print the type, label and UUID of the file systems instead
+//
+// Example:
+//     $ lsblk
+//     NAME   MAJ:MIN RM SIZE RO TYPE MOUNTPOINT
+//     vda    253:0   0  20G  0  disk
+//     ├─vda1 253:1   0  19G  0  part /
+//     └─vda2 253:2   0  1G   0  part [SWAP]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+var (
+	all     = flag.Bool("a", false, "list empty devices too")
+	inBytes = flag.Bool("b", false, "print sizes in bytes")
+	fsInfo  = flag.Bool("f", false, "print file system types, labels and UUIDs")
+
+	// Tests change these.
+	sysBlock   = "/sys/block"
+	devDir     = "/dev"
+	procMounts = "/proc/self/mounts"
+	procSwaps  = "/proc/swaps"
+)
+
+// A device is a disk, or a partition of one.
+type device struct {
+	name   string
+	majMin string
+	rm     string
+	size   uint64
+	ro     string
+	typ    string
+	// mount is where it is mounted, or [SWAP].
+	mount string
+	fs    *mount.FSInfo
+	parts []*device
+}
+
+// attr returns the contents of the sysfs file name in dir, or "".
+func attr(dir, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readDevice reads what sysfs has of the device at dir.
+func readDevice(dir, typ string, mounts map[string]string) *device {
+	d := &device{
+		name:   filepath.Base(dir),
+		majMin: attr(dir, "dev"),
+		rm:     attr(dir, "removable"),
+		ro:     attr(dir, "ro"),
+		typ:    typ,
+	}
+	if d.rm == "" {
+		// Partitions are as removable as their disk.
+		d.rm = attr(filepath.Dir(dir), "removable")
+	}
+	// size is in 512 byte sectors, whatever the device's sector size.
+	if s, err := strconv.ParseUint(attr(dir, "size"), 10, 64); err == nil {
+		d.size = s * 512
+	}
+	dev := filepath.Join(devDir, d.name)
+	d.mount = mounts[dev]
+	if *fsInfo {
+		d.fs, _ = mount.ProbeFile(dev)
+	}
+	return d
+}
+
+// diskType returns the type of the disk at dir.
+func diskType(dir string) string {
+	name := filepath.Base(dir)
+	switch {
+	case strings.HasPrefix(name, "loop"):
+		return "loop"
+	case strings.HasPrefix(name, "sr"):
+		return "rom"
+	case strings.HasPrefix(name, "dm-"):
+		return "dm"
+	case attr(dir, "md/level") != "":
+		return attr(dir, "md/level")
+	}
+	return "disk"
+}
+
+// devices returns the disks in sysBlock, with their partitions, or only
+// those in names.
+func devices(names []string) ([]*device, error) {
+	mounts, err := readMounts()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		fis, err := ioutil.ReadDir(sysBlock)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+	}
+	var disks []*device
+	for _, n := range names {
+		dir := filepath.Join(sysBlock, filepath.Base(n))
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("%v: not a block device", n)
+		}
+		d := readDevice(dir, diskType(dir), mounts)
+		if d.size == 0 && !*all {
+			continue
+		}
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			p := filepath.Join(dir, fi.Name())
+			if attr(p, "partition") != "" {
+				d.parts = append(d.parts, readDevice(p, "part", mounts))
+			}
+		}
+		// sda10 comes after sda9.
+		sort.Slice(d.parts, func(i, j int) bool {
+			a, _ := strconv.Atoi(attr(filepath.Join(dir, d.parts[i].name), "partition"))
+			b, _ := strconv.Atoi(attr(filepath.Join(dir, d.parts[j].name), "partition"))
+			return a < b
+		})
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+// readMounts returns where each device is mounted, or [SWAP] if it is
+// used for swap.
+func readMounts() (map[string]string, error) {
+	m := make(map[string]string)
+	for _, f := range []string{procMounts, procSwaps} {
+		r, err := os.Open(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			fs := strings.Fields(s.Text())
+			switch {
+			case len(fs) < 2 || !strings.HasPrefix(fs[0], "/"):
+			case f == procSwaps:
+				m[fs[0]] = "[SWAP]"
+			case m[fs[0]] == "":
+				// The first is the one people expect.
+				m[fs[0]] = fs[1]
+			}
+		}
+		r.Close()
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// humanSize returns size in the largest unit, in powers of 1024, it is at
+// least one of, with one decimal if it is not a whole number of them.
+func humanSize(size uint64) string {
+	if *inBytes {
+		return strconv.FormatUint(size, 10)
+	}
+	const units = "BKMGTPE"
+	f, u := float64(size), 0
+	for f >= 1024 && u < len(units)-1 {
+		f /= 1024
+		u++
+	}
+	s := strconv.FormatFloat(f, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0") + units[u:u+1]
+}
+
+// list prints the disks and their partitions as a tree.
+func list(w io.Writer, disks []*device) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	if *fsInfo {
+		fmt.Fprintln(tw, "NAME\tFSTYPE\tLABEL\tUUID\tMOUNTPOINT")
+	} else {
+		fmt.Fprintln(tw, "NAME\tMAJ:MIN\tRM\tSIZE\tRO\tTYPE\tMOUNTPOINT")
+	}
+	line := func(prefix string, d *device) {
+		if *fsInfo {
+			var fs mount.FSInfo
+			if d.fs != nil {
+				fs = *d.fs
+			}
+			fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\n", prefix, d.name, fs.Type, fs.Label, fs.UUID, d.mount)
+			return
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\n", prefix, d.name, d.majMin, d.rm, humanSize(d.size), d.ro, d.typ, d.mount)
+	}
+	for _, d := range disks {
+		line("", d)
+		for i, p := range d.parts {
+			prefix := "├─"
+			if i == len(d.parts)-1 {
+				prefix = "└─"
+			}
+			line(prefix, p)
+		}
+	}
+	return tw.Flush()
+}
+
+func main() {
+	flag.Parse()
+	disks, err := devices(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := list(os.Stdout, disks); err != nil {
+		log.Fatal(err)
+	}
+}
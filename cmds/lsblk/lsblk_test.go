@@ -0,0 +1,116 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHumanSize(t *testing.T) {
+	for _, tt := range []struct {
+		size uint64
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1024, "1K"},
+		{1536, "1.5K"},
+		{20 << 30, "20G"},
+		{512110190592, "476.9G"},
+	} {
+		if got := humanSize(tt.size); got != tt.want {
+			t.Errorf("humanSize(%d): got %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestLsblk(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "lsblk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer func(s, m, sw string) { sysBlock, procMounts, procSwaps = s, m, sw }(sysBlock, procMounts, procSwaps)
+	sysBlock = filepath.Join(tmp, "block")
+	procMounts, procSwaps = filepath.Join(tmp, "mounts"), filepath.Join(tmp, "swaps")
+
+	for f, v := range map[string]string{
+		"vda/dev":              "253:0",
+		"vda/size":             "41943040",
+		"vda/removable":        "0",
+		"vda/ro":               "0",
+		"vda/vda1/dev":         "253:1",
+		"vda/vda1/size":        "39845888",
+		"vda/vda1/ro":          "0",
+		"vda/vda1/partition":   "1",
+		"vda/vda10/dev":        "253:10",
+		"vda/vda10/size":       "2097152",
+		"vda/vda10/ro":         "0",
+		"vda/vda10/partition":  "10",
+		"vda/vda2/dev":         "253:2",
+		"vda/vda2/size":        "2048",
+		"vda/vda2/ro":          "1",
+		"vda/vda2/partition":   "2",
+		"vda/queue/rotational": "1",
+		"loop0/dev":            "7:0",
+		"loop0/size":           "0",
+		"loop0/removable":      "0",
+		"loop0/ro":             "0",
+		"../mounts":            "/dev/vda1 / ext4 rw 0 0\n/dev/vda1 /mnt ext4 rw 0 0\nproc /proc proc rw 0 0\n",
+		"../swaps":             "Filename Type Size Used Priority\n/dev/vda10 partition 1048572 0 -2\n",
+	} {
+		p := filepath.Join(sysBlock, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(v+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		all   bool
+		names []string
+		want  string
+	}{
+		{false, nil, `
+NAME    MAJ:MIN RM SIZE RO TYPE MOUNTPOINT
+vda     253:0   0  20G  0  disk
+├─vda1  253:1   0  19G  0  part /
+├─vda2  253:2   0  1M   1  part
+└─vda10 253:10  0  1G   0  part [SWAP]
+`},
+		{true, []string{"/dev/loop0"}, `
+NAME  MAJ:MIN RM SIZE RO TYPE MOUNTPOINT
+loop0 7:0     0  0B   0  loop
+`},
+	} {
+		*all = tt.all
+		disks, err := devices(tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := list(&b, disks); err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, l := range strings.Split(b.String(), "\n") {
+			lines = append(lines, strings.TrimRight(l, " "))
+		}
+		if got := "\n" + strings.Join(lines, "\n"); got != tt.want {
+			t.Errorf("lsblk %v: got %s, want %s", tt.names, got, tt.want)
+		}
+	}
+
+	if _, err := devices([]string{"sdz"}); err == nil {
+		t.Errorf("lsblk sdz: got nil, want an error")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Lspci lists PCI devices.
+//
+// Synopsis:
+//     lspci [-n|-nn] [-k] [-v|-vv]
+//
+// Description:
+//     lspci lists the devices in /sys/bus/pci/devices: their address, class,
+//     vendor, device and revision, with the names of the class, vendor and
+//     device if they are known.
+//
+// Options:
+//     -n:  print the class, vendor and device numbers instead of names
+//     -nn: print both the names and the numbers
+//     -k:  print the driver bound to each device, and the modules which
+//          could drive it; a device without a driver is not working
+//     -v:  print the IRQ too, and what -k does
+//     -vv: dump the configuration space too, and what -v does
+//
+// Example:
+//     $ lspci -nn -k
+//     00:03.0 Ethernet controller [0200]: Intel Corporation 82540EM Gigabit Ethernet Controller [8086:100e] (rev 03)
+//             Kernel driver in use: e1000
+//             Kernel modules: e1000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+	"github.com/u-root/u-root/pkg/pci"
+)
+
+var (
+	numbers  = flag.Bool("n", false, "print numbers instead of names")
+	both     = flag.Bool("nn", false, "print both names and numbers")
+	kernel   = flag.Bool("k", false, "print drivers and modules")
+	verbose  = flag.Bool("v", false, "print the IRQ, drivers and modules")
+	vverbose = flag.Bool("vv", false, "print the configuration space too")
+)
+
+// lspci prints a line for each device, and, if details is set, the
+// lines it returns for it under it.
+func lspci(w io.Writer, devs pci.Devices, ids map[string]pci.Vendor, details func(*pci.PCI) []string) {
+	for _, d := range devs {
+		// Domain 0 is all most machines have, so it is left out.
+		addr := strings.TrimPrefix(d.Addr, "0000:")
+		class := d.Class
+		if len(class) > 4 {
+			class = class[:4]
+		}
+		rev := ""
+		if d.Revision != "" && d.Revision != "00" {
+			rev = fmt.Sprintf(" (rev %s)", d.Revision)
+		}
+		if *numbers && !*both {
+			fmt.Fprintf(w, "%s %s: %s:%s%s\n", addr, class, d.Vendor, d.Device, rev)
+		} else {
+			vendor, device := pci.Lookup(ids, d.Vendor, d.Device)
+			if *both {
+				fmt.Fprintf(w, "%s %s [%s]: %s %s [%s:%s]%s\n", addr, pci.ClassName(d.Class), class, vendor, device, d.Vendor, d.Device, rev)
+			} else {
+				fmt.Fprintf(w, "%s %s: %s %s%s\n", addr, pci.ClassName(d.Class), vendor, device, rev)
+			}
+		}
+		if details == nil {
+			continue
+		}
+		for _, l := range details(d) {
+			fmt.Fprintf(w, "\t%s\n", l)
+		}
+	}
+}
+
+// attr returns the contents of the sysfs file name of d, or "".
+func attr(d *pci.PCI, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(d.FullPath, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// hexDump formats b as lspci -x does: 16 bytes a line, after their offset.
+func hexDump(b []byte) []string {
+	var lines []string
+	for off := 0; off < len(b); off += 16 {
+		end := off + 16
+		if end > len(b) {
+			end = len(b)
+		}
+		l := fmt.Sprintf("%02x:", off)
+		for _, c := range b[off:end] {
+			l += fmt.Sprintf(" %02x", c)
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// details returns the lines -k, -v and -vv print for a device.
+func details(mods *kmodule.Modules) func(*pci.PCI) []string {
+	return func(d *pci.PCI) []string {
+		var lines []string
+		if *verbose || *vverbose {
+			if irq := attr(d, "irq"); irq != "" && irq != "0" {
+				lines = append(lines, "IRQ "+irq)
+			}
+		}
+		if d.Driver != "" {
+			lines = append(lines, "Kernel driver in use: "+d.Driver)
+		}
+		if mods != nil {
+			if names, err := mods.Lookup(attr(d, "modalias")); err == nil {
+				lines = append(lines, "Kernel modules: "+strings.Join(names, ", "))
+			}
+		}
+		if *vverbose {
+			c, err := d.ReadConfig()
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("Config space: %v", err))
+			}
+			lines = append(lines, hexDump(c)...)
+		}
+		return lines
+	}
+}
+
+func main() {
+	flag.Parse()
+	r, err := pci.NewBusReader()
+	if err != nil {
+		log.Fatal(err)
+	}
+	devs, err := r.Read()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var ids map[string]pci.Vendor
+	if !*numbers || *both {
+		if ids, err = pci.NewIDs(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var f func(*pci.PCI) []string
+	if *kernel || *verbose || *vverbose {
+		var mods *kmodule.Modules
+		// Only images with modules.alias know which modules there are.
+		if dir, err := kmodule.ModuleDir(); err == nil {
+			mods, _ = kmodule.Open(dir)
+		}
+		f = details(mods)
+	}
+	lspci(os.Stdout, devs, ids, f)
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/pci"
+)
+
+func TestLspci(t *testing.T) {
+	devs := pci.Devices{
+		{Addr: "0000:00:03.0", Vendor: "8086", Device: "100e", Class: "020000", Revision: "03", Driver: "e1000"},
+		{Addr: "0001:00:00.0", Vendor: "abcd", Device: "0001", Class: "060000", Revision: "00"},
+	}
+	ids := map[string]pci.Vendor{
+		"8086": {Name: "Intel Corporation", Devices: map[string]pci.Device{"100e": "82540EM Gigabit Ethernet Controller"}},
+	}
+	driver := func(d *pci.PCI) []string {
+		if d.Driver == "" {
+			return nil
+		}
+		return []string{"Kernel driver in use: " + d.Driver}
+	}
+	defer func() { *numbers, *both = false, false }()
+	for _, tt := range []struct {
+		numbers, both bool
+		details       func(*pci.PCI) []string
+		want          string
+	}{
+		{false, false, nil, `00:03.0 Ethernet controller: Intel Corporation 82540EM Gigabit Ethernet Controller (rev 03)
+0001:00:00.0 Host bridge: abcd 0001
+`},
+		{true, false, nil, `00:03.0 0200: 8086:100e (rev 03)
+0001:00:00.0 0600: abcd:0001
+`},
+		{false, true, driver, `00:03.0 Ethernet controller [0200]: Intel Corporation 82540EM Gigabit Ethernet Controller [8086:100e] (rev 03)
+	Kernel driver in use: e1000
+0001:00:00.0 Host bridge [0600]: abcd 0001 [abcd:0001]
+`},
+	} {
+		*numbers, *both = tt.numbers, tt.both
+		var b bytes.Buffer
+		lspci(&b, devs, ids, tt.details)
+		if b.String() != tt.want {
+			t.Errorf("lspci -n=%v -nn=%v: got\n%s\nwant\n%s", tt.numbers, tt.both, b.String(), tt.want)
+		}
+	}
+}
+
+func TestHexDump(t *testing.T) {
+	b := make([]byte, 20)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	want := []string{
+		"00: 00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f",
+		"10: 10 11 12 13",
+	}
+	if got := hexDump(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("hexDump: got %q, want %q", got, want)
+	}
+}
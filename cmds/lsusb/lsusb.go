@@ -0,0 +1,398 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Lsusb lists USB devices.
+//
+// Synopsis:
+//     lsusb [-t] [-v] [-s [BUS:][DEV]] [-d VENDOR:[PRODUCT]]
+//
+// Description:
+//     lsusb lists the devices in /sys/bus/usb/devices: their bus, device
+//     number, vendor and product IDs, and the manufacturer and product the
+//     device names itself.
+//
+// Options:
+//     -t: print the devices as a tree of hubs and ports, with the class and
+//         driver of each interface; an interface without a driver is not
+//         working
+//     -v: print the device descriptor too, as read from usbfs
+//     -s: only list the devices on BUS, with device number DEV, in decimal
+//     -d: only list the devices with VENDOR and PRODUCT IDs, in hex
+//
+// Example:
+//     $ lsusb
+//     Bus 001 Device 001: ID 1d6b:0002 Linux 4.14.0 xhci-hcd xHCI Host Controller
+//     Bus 001 Device 002: ID 0bda:8153 Realtek USB 10/100/1000 LAN
+//     $ lsusb -t
+//     /:  Bus 01.Port 1: Dev 1, Class=root_hub, Driver=xhci_hcd/4p, 480M
+//         |__ Port 2: Dev 2, If 0, Class=Vendor Specific Class, Driver=r8152, 5000M
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	tree    = flag.Bool("t", false, "print the devices as a tree")
+	verbose = flag.Bool("v", false, "print the device descriptors")
+	slot    = flag.String("s", "", "only list devices on [BUS:][DEV]")
+	id      = flag.String("d", "", "only list devices with VENDOR:[PRODUCT]")
+
+	// Tests change these.
+	sysUSB = "/sys/bus/usb/devices"
+	usbfs  = "/dev/bus/usb"
+)
+
+// classNames are the names of the USB classes, by their hex codes.
+var classNames = map[string]string{
+	"00": ">ifc",
+	"01": "Audio",
+	"02": "Communications",
+	"03": "Human Interface Device",
+	"05": "Physical Interface Device",
+	"06": "Imaging",
+	"07": "Printer",
+	"08": "Mass Storage",
+	"09": "Hub",
+	"0a": "CDC Data",
+	"0b": "Chip/SmartCard",
+	"0d": "Content Security",
+	"0e": "Video",
+	"0f": "Personal Healthcare",
+	"10": "Audio/Video",
+	"dc": "Diagnostic",
+	"e0": "Wireless",
+	"ef": "Miscellaneous Device",
+	"fe": "Application Specific Interface",
+	"ff": "Vendor Specific Class",
+}
+
+// vendorNames name some vendors whose devices often do not name
+// themselves, such as root hubs.
+var vendorNames = map[string]string{
+	"1d6b": "Linux Foundation",
+	"8087": "Intel Corp.",
+	"8086": "Intel Corp.",
+	"0bda": "Realtek Semiconductor Corp.",
+	"0b95": "ASIX Electronics Corp.",
+	"0781": "SanDisk Corp.",
+	"0951": "Kingston Technology",
+	"046d": "Logitech, Inc.",
+	"05e3": "Genesys Logic, Inc.",
+	"2109": "VIA Labs, Inc.",
+	"0424": "Microchip Technology, Inc.",
+	"0627": "Adomax Technology Co., Ltd",
+}
+
+// className returns the name of the USB class with hex code c.
+func className(c string) string {
+	if n, ok := classNames[c]; ok {
+		return n
+	}
+	return "Class " + c
+}
+
+// An iface is an interface of a device.
+type iface struct {
+	num    int
+	class  string
+	driver string
+}
+
+// A device is a USB device, or the root hub of a bus.
+type device struct {
+	// name is its name in sysfs, such as usb1, 1-2 or 1-2.4.
+	name     string
+	dir      string
+	bus, dev int
+	vendor   string
+	product  string
+	// desc is the manufacturer and product the device names itself, or
+	// the name of its vendor.
+	desc     string
+	class    string
+	speed    string
+	ifaces   []iface
+	children []*device
+}
+
+// attr returns the contents of the sysfs file name in dir, or "".
+func attr(dir, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// driver returns the name of the driver bound to the device at dir, or "".
+func driver(dir string) string {
+	l, err := os.Readlink(filepath.Join(dir, "driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(l)
+}
+
+// isRoot returns whether name is that of a root hub.
+func isRoot(name string) bool {
+	return strings.HasPrefix(name, "usb")
+}
+
+// parent returns the name of the hub the device name is plugged into.
+func parent(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return "usb" + name[:strings.Index(name, "-")]
+}
+
+// port returns the port of its hub the device name is plugged into, or 1
+// for a root hub.
+func port(name string) int {
+	if isRoot(name) {
+		return 1
+	}
+	p, _ := strconv.Atoi(name[strings.LastIndexAny(name, "-.")+1:])
+	return p
+}
+
+// readDevices reads the devices in sysUSB.
+func readDevices() ([]*device, error) {
+	fis, err := ioutil.ReadDir(sysUSB)
+	if err != nil {
+		return nil, err
+	}
+	devs := make(map[string]*device)
+	var ifaces []string
+	for _, fi := range fis {
+		n := fi.Name()
+		if strings.Contains(n, ":") {
+			ifaces = append(ifaces, n)
+			continue
+		}
+		dir := filepath.Join(sysUSB, n)
+		d := &device{
+			name:    n,
+			dir:     dir,
+			vendor:  attr(dir, "idVendor"),
+			product: attr(dir, "idProduct"),
+			desc:    strings.TrimSpace(attr(dir, "manufacturer") + " " + attr(dir, "product")),
+			class:   attr(dir, "bDeviceClass"),
+			speed:   attr(dir, "speed"),
+		}
+		if d.desc == "" {
+			d.desc = vendorNames[d.vendor]
+		}
+		d.bus, _ = strconv.Atoi(attr(dir, "busnum"))
+		d.dev, _ = strconv.Atoi(attr(dir, "devnum"))
+		devs[n] = d
+	}
+	// Interfaces are named DEVICE:CONFIG.INTERFACE, but the root hub of
+	// bus N is N-0 in them.
+	for _, n := range ifaces {
+		dn := n[:strings.Index(n, ":")]
+		if strings.HasSuffix(dn, "-0") {
+			dn = "usb" + strings.TrimSuffix(dn, "-0")
+		}
+		d, ok := devs[dn]
+		if !ok {
+			continue
+		}
+		dir := filepath.Join(sysUSB, n)
+		num, _ := strconv.ParseInt(attr(dir, "bInterfaceNumber"), 16, 32)
+		d.ifaces = append(d.ifaces, iface{num: int(num), class: attr(dir, "bInterfaceClass"), driver: driver(dir)})
+	}
+	var all []*device
+	for _, d := range devs {
+		sort.Slice(d.ifaces, func(i, j int) bool { return d.ifaces[i].num < d.ifaces[j].num })
+		all = append(all, d)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].bus != all[j].bus {
+			return all[i].bus < all[j].bus
+		}
+		return all[i].dev < all[j].dev
+	})
+	return all, nil
+}
+
+// match returns whether d is one of those -s and -d select.
+func match(d *device, slot, id string) (bool, error) {
+	if slot != "" {
+		bus, dev := "", slot
+		if i := strings.Index(slot, ":"); i >= 0 {
+			bus, dev = slot[:i], slot[i+1:]
+		}
+		for _, f := range []struct {
+			s string
+			v int
+		}{{bus, d.bus}, {dev, d.dev}} {
+			if f.s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(f.s)
+			if err != nil {
+				return false, fmt.Errorf("bad -s %q: %v", slot, err)
+			}
+			if n != f.v {
+				return false, nil
+			}
+		}
+	}
+	if id != "" {
+		i := strings.Index(id, ":")
+		if i < 0 {
+			return false, fmt.Errorf("bad -d %q: want VENDOR:[PRODUCT]", id)
+		}
+		if !strings.EqualFold(id[:i], d.vendor) || (id[i+1:] != "" && !strings.EqualFold(id[i+1:], d.product)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// list prints a line for each device.
+func list(w io.Writer, devs []*device) error {
+	for _, d := range devs {
+		ok, err := match(d, *slot, *id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "Bus %03d Device %03d: ID %s:%s %s\n", d.bus, d.dev, d.vendor, d.product, d.desc)
+		if !*verbose {
+			continue
+		}
+		b, err := descriptor(d)
+		if err != nil {
+			fmt.Fprintf(w, "  Device Descriptor: %v\n", err)
+			continue
+		}
+		for _, l := range decode(b) {
+			fmt.Fprintf(w, "  %s\n", l)
+		}
+	}
+	return nil
+}
+
+// descriptor returns the device descriptor of d, from usbfs, or, if usbfs
+// is not mounted, from sysfs, which has the same.
+func descriptor(d *device) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(usbfs, fmt.Sprintf("%03d/%03d", d.bus, d.dev)))
+	if err != nil {
+		b, err = ioutil.ReadFile(filepath.Join(d.dir, "descriptors"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 18 {
+		return nil, fmt.Errorf("descriptor is %d bytes, want 18", len(b))
+	}
+	return b[:18], nil
+}
+
+// bcd formats the binary coded decimal version v as lsusb does.
+func bcd(v uint16) string {
+	return fmt.Sprintf("%x.%02x", v>>8, v&0xff)
+}
+
+// decode returns the fields of the 18 byte device descriptor b.
+func decode(b []byte) []string {
+	u16 := func(off int) uint16 { return binary.LittleEndian.Uint16(b[off:]) }
+	class := fmt.Sprintf("%02x", b[4])
+	return []string{
+		"Device Descriptor:",
+		fmt.Sprintf("  bcdUSB             %5s", bcd(u16(2))),
+		fmt.Sprintf("  bDeviceClass       %5d %s", b[4], className(class)),
+		fmt.Sprintf("  bDeviceSubClass    %5d", b[5]),
+		fmt.Sprintf("  bDeviceProtocol    %5d", b[6]),
+		fmt.Sprintf("  bMaxPacketSize0    %5d", b[7]),
+		fmt.Sprintf("  idVendor          0x%04x", u16(8)),
+		fmt.Sprintf("  idProduct         0x%04x", u16(10)),
+		fmt.Sprintf("  bcdDevice          %5s", bcd(u16(12))),
+		fmt.Sprintf("  bNumConfigurations %5d", b[17]),
+	}
+}
+
+// printTree prints the devices as lsusb -t does: each interface of each
+// device, under the hub it is plugged into.
+func printTree(w io.Writer, devs []*device) {
+	byName := make(map[string]*device)
+	for _, d := range devs {
+		byName[d.name] = d
+	}
+	var roots []*device
+	for _, d := range devs {
+		if isRoot(d.name) {
+			roots = append(roots, d)
+		} else if p, ok := byName[parent(d.name)]; ok {
+			p.children = append(p.children, d)
+		}
+	}
+	var walk func(d *device, depth int)
+	walk = func(d *device, depth int) {
+		ports := ""
+		if n := attr(d.dir, "maxchild"); n != "" && n != "0" {
+			ports = "/" + n + "p"
+		}
+		if isRoot(d.name) {
+			// The driver of a root hub is that of its host controller,
+			// the directory its sysfs link points into.
+			drv := ""
+			if dir, err := filepath.EvalSymlinks(d.dir); err == nil {
+				drv = driver(filepath.Dir(dir))
+			}
+			fmt.Fprintf(w, "/:  Bus %02d.Port %d: Dev %d, Class=root_hub, Driver=%s%s, %sM\n", d.bus, port(d.name), d.dev, drv, ports, d.speed)
+		} else {
+			printIfaces(w, d, depth, ports)
+		}
+		sort.Slice(d.children, func(i, j int) bool { return port(d.children[i].name) < port(d.children[j].name) })
+		for _, c := range d.children {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+}
+
+// printIfaces prints a line for each interface of d, depth hubs down.
+func printIfaces(w io.Writer, d *device, depth int, ports string) {
+	indent := strings.Repeat("    ", depth)
+	for _, i := range d.ifaces {
+		drv := i.driver
+		if i.class == "09" {
+			drv += ports
+		}
+		fmt.Fprintf(w, "%s|__ Port %d: Dev %d, If %d, Class=%s, Driver=%s, %sM\n", indent, port(d.name), d.dev, i.num, className(i.class), drv, d.speed)
+	}
+}
+
+func main() {
+	flag.Parse()
+	devs, err := readDevices()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *tree {
+		printTree(os.Stdout, devs)
+		return
+	}
+	if err := list(os.Stdout, devs); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSys makes a sysfs with a root hub, a hub in its port 1, and a
+// network adapter in port 2 of that.
+func fakeSys(t *testing.T) string {
+	tmp, err := ioutil.TempDir("", "lsusb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := filepath.Join(tmp, "devices/pci0000:00/0000:00:14.0")
+	drivers := filepath.Join(tmp, "bus/drivers")
+	files := map[string]map[string]string{
+		"usb1":      {"busnum": "1", "devnum": "1", "idVendor": "1d6b", "idProduct": "0002", "bDeviceClass": "09", "speed": "480", "maxchild": "4"},
+		"1-0:1.0":   {"bInterfaceNumber": "00", "bInterfaceClass": "09"},
+		"1-1":       {"busnum": "1", "devnum": "2", "idVendor": "05e3", "idProduct": "0610", "product": "USB2.0 Hub", "bDeviceClass": "09", "speed": "480", "maxchild": "4"},
+		"1-1:1.0":   {"bInterfaceNumber": "00", "bInterfaceClass": "09"},
+		"1-1.2":     {"busnum": "1", "devnum": "3", "idVendor": "0bda", "idProduct": "8153", "manufacturer": "Realtek", "product": "USB 10/100/1000 LAN", "bDeviceClass": "00", "speed": "480"},
+		"1-1.2:1.0": {"bInterfaceNumber": "00", "bInterfaceClass": "ff"},
+		"1-1.2:1.1": {"bInterfaceNumber": "01", "bInterfaceClass": "0a"},
+	}
+	bound := map[string]string{"1-0:1.0": "hub", "1-1:1.0": "hub", "1-1.2:1.0": "r8152"}
+	dir := func(n string) string {
+		if n == "usb1" || n == "1-0:1.0" {
+			return filepath.Join(hc, n)
+		}
+		return filepath.Join(tmp, "devices", n)
+	}
+	if err := os.MkdirAll(filepath.Join(tmp, "bus/usb/devices"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for n, fs := range files {
+		d := dir(n)
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for f, v := range fs {
+			if err := ioutil.WriteFile(filepath.Join(d, f), []byte(v+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if drv, ok := bound[n]; ok {
+			if err := os.Symlink(filepath.Join(drivers, drv), filepath.Join(d, "driver")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Symlink(d, filepath.Join(tmp, "bus/usb/devices", n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(drivers, "xhci_hcd"), filepath.Join(hc, "driver")); err != nil {
+		t.Fatal(err)
+	}
+	// The network adapter has a device descriptor.
+	desc := []byte{18, 1, 0x10, 0x02, 0, 0, 0, 64, 0xda, 0x0b, 0x53, 0x81, 0x00, 0x30, 1, 2, 3, 1}
+	if err := ioutil.WriteFile(filepath.Join(dir("1-1.2"), "descriptors"), desc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tmp
+}
+
+func TestLsusb(t *testing.T) {
+	tmp := fakeSys(t)
+	defer os.RemoveAll(tmp)
+	sysUSB, usbfs = filepath.Join(tmp, "bus/usb/devices"), filepath.Join(tmp, "nousbfs")
+	devs, err := readDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { *slot, *id, *verbose = "", "", false }()
+
+	for _, tt := range []struct {
+		slot, id string
+		verbose  bool
+		want     string
+	}{
+		{"", "", false, `Bus 001 Device 001: ID 1d6b:0002 Linux Foundation
+Bus 001 Device 002: ID 05e3:0610 USB2.0 Hub
+Bus 001 Device 003: ID 0bda:8153 Realtek USB 10/100/1000 LAN
+`},
+		{"1:2", "", false, "Bus 001 Device 002: ID 05e3:0610 USB2.0 Hub\n"},
+		{"3", "", false, "Bus 001 Device 003: ID 0bda:8153 Realtek USB 10/100/1000 LAN\n"},
+		{"", "1D6B:", false, "Bus 001 Device 001: ID 1d6b:0002 Linux Foundation\n"},
+		{"", "0bda:8152", false, ""},
+		{"", "0bda:8153", true, `Bus 001 Device 003: ID 0bda:8153 Realtek USB 10/100/1000 LAN
+  Device Descriptor:
+    bcdUSB              2.10
+    bDeviceClass           0 >ifc
+    bDeviceSubClass        0
+    bDeviceProtocol        0
+    bMaxPacketSize0       64
+    idVendor          0x0bda
+    idProduct         0x8153
+    bcdDevice          30.00
+    bNumConfigurations     1
+`},
+	} {
+		*slot, *id, *verbose = tt.slot, tt.id, tt.verbose
+		var b bytes.Buffer
+		if err := list(&b, devs); err != nil {
+			t.Errorf("-s %q -d %q: %v", tt.slot, tt.id, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("-s %q -d %q: got\n%s\nwant\n%s", tt.slot, tt.id, b.String(), tt.want)
+		}
+	}
+
+	for _, bad := range []struct{ slot, id string }{{"x", ""}, {"", "0bda"}} {
+		*slot, *id = bad.slot, bad.id
+		if err := list(ioutil.Discard, devs); err == nil {
+			t.Errorf("-s %q -d %q: got nil, want an error", bad.slot, bad.id)
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	tmp := fakeSys(t)
+	defer os.RemoveAll(tmp)
+	sysUSB = filepath.Join(tmp, "bus/usb/devices")
+	devs, err := readDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	printTree(&b, devs)
+	want := `/:  Bus 01.Port 1: Dev 1, Class=root_hub, Driver=xhci_hcd/4p, 480M
+    |__ Port 1: Dev 2, If 0, Class=Hub, Driver=hub/4p, 480M
+        |__ Port 2: Dev 3, If 0, Class=Vendor Specific Class, Driver=r8152, 480M
+        |__ Port 2: Dev 3, If 1, Class=CDC Data, Driver=, 480M
+`
+	if b.String() != want {
+		t.Errorf("tree: got\n%s\nwant\n%s", b.String(), want)
+	}
+}
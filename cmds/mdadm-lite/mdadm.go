@@ -0,0 +1,299 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Mdadm-lite assembles Linux software RAID, md, arrays, as mdadm does.
+//
+// Synopsis:
+//     mdadm-lite --assemble --scan [--run]
+//     mdadm-lite --assemble [--run] /dev/mdN DEV...
+//     mdadm-lite --examine DEV...
+//     mdadm-lite --stop /dev/mdN
+//
+// Description:
+//     --assemble assembles the array of the DEVs as /dev/mdN, and runs it.
+//     With --scan, it reads the superblocks of all the block devices, and
+//     assembles all the arrays it finds. An array with a 0.90 superblock
+//     is assembled as the md device it was made as; one with a 1.x
+//     superblock is too, if its name is a number, else it is the highest
+//     free one from /dev/md127 down, with /dev/md/NAME a link to it.
+//
+//     Devices which are out of date, or faulty, are left out. An array
+//     with disks missing is only run with --run, so that an array is not
+//     run degraded just because a disk is slow to show up.
+//
+//     --examine prints the superblocks of the DEVs, and --stop stops an
+//     array.
+//
+// Options:
+//     --assemble, -A: assemble arrays
+//     --scan, -s:     assemble the arrays of all the block devices
+//     --run, -R:      run arrays with disks missing
+//     --examine, -E:  print the superblocks of devices
+//     --stop, -S:     stop an array
+//
+// Example:
+//     $ mdadm-lite --assemble --scan
+//     /dev/md127 has been started with 2 drives (out of 2)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/md"
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+var assemble, scan, run, examine, stop bool
+
+func init() {
+	for _, f := range []struct {
+		v           *bool
+		long, short string
+		usage       string
+	}{
+		{&assemble, "assemble", "A", "assemble arrays"},
+		{&scan, "scan", "s", "assemble the arrays of all the block devices"},
+		{&run, "run", "R", "run arrays with disks missing"},
+		{&examine, "examine", "E", "print the superblocks of devices"},
+		{&stop, "stop", "S", "stop an array"},
+	} {
+		flag.BoolVar(f.v, f.long, false, f.usage)
+		flag.BoolVar(f.v, f.short, false, f.usage)
+	}
+}
+
+// candidates returns the devices which may be in arrays: not md devices,
+// and not disks with partitions, since the superblock at the end of the
+// last partition is at the end of the disk too.
+func candidates(devs []string) []string {
+	var c []string
+	for _, d := range devs {
+		if strings.HasPrefix(filepath.Base(d), "md") {
+			continue
+		}
+		partitioned := false
+		for _, p := range devs {
+			n := strings.TrimPrefix(strings.TrimPrefix(p, d), "p")
+			if _, err := strconv.Atoi(n); p != d && strings.HasPrefix(p, d) && err == nil {
+				partitioned = true
+				break
+			}
+		}
+		if !partitioned {
+			c = append(c, d)
+		}
+	}
+	return c
+}
+
+// members returns the devs which have md superblocks.
+func members(devs []string) []md.Member {
+	var m []md.Member
+	for _, d := range devs {
+		if s, err := md.Examine(d); err == nil {
+			m = append(m, md.Member{Device: d, Superblock: s})
+		}
+	}
+	return m
+}
+
+// name returns the name of the array with s, which is after the host in
+// 1.x superblocks.
+func name(s *md.Superblock) string {
+	n := s.Name
+	if i := strings.Index(n, ":"); i >= 0 {
+		n = n[i+1:]
+	}
+	return n
+}
+
+// minor returns which md device the array with s is, and whether it is the
+// one it wants to be.
+func minor(s *md.Superblock) (int, bool) {
+	if s.PreferredMinor >= 0 {
+		return s.PreferredMinor, true
+	}
+	if n, err := strconv.Atoi(name(s)); err == nil && n >= 0 {
+		return n, true
+	}
+	return 0, false
+}
+
+// describe says how many drives of a there are.
+func describe(a *md.Array) string {
+	spares := len(a.Members) - a.Active()
+	d := fmt.Sprintf("%d drive", a.Active())
+	if a.Active() != 1 {
+		d += "s"
+	}
+	d += fmt.Sprintf(" (out of %d)", a.Super.RaidDisks)
+	if spares > 0 {
+		d += fmt.Sprintf(" and %d spare", spares)
+		if spares != 1 {
+			d += "s"
+		}
+	}
+	return d
+}
+
+// check returns an error if a should not be run.
+func check(dev string, a *md.Array) error {
+	if !a.Runnable() {
+		return fmt.Errorf("%v cannot be started: it has %s, which is not enough", dev, describe(a))
+	}
+	if a.Degraded() && !run {
+		return fmt.Errorf("not starting %v with %s: use --run to start it degraded", dev, describe(a))
+	}
+	return nil
+}
+
+// start assembles a as mdN and runs it.
+func start(w io.Writer, minor int, a *md.Array) error {
+	dev := filepath.Join(md.Dev, fmt.Sprintf("md%d", minor))
+	if err := check(dev, a); err != nil {
+		return err
+	}
+	for _, m := range a.Stale {
+		fmt.Fprintf(w, "%v is out of date or faulty, so is left out of %v\n", m.Device, dev)
+	}
+	if err := md.Assemble(minor, a); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%v has been started with %s\n", dev, describe(a))
+	return nil
+}
+
+// assembleScan assembles the arrays of all the block devices.
+func assembleScan(w io.Writer) error {
+	devs, err := mount.BlockDevices()
+	if err != nil {
+		return err
+	}
+	arrays := md.Group(members(candidates(devs)))
+	if len(arrays) == 0 {
+		return fmt.Errorf("no arrays found")
+	}
+	var failed bool
+	for _, a := range arrays {
+		m, ok := minor(a.Super)
+		if !ok {
+			if m, err = md.FreeMinor(); err != nil {
+				return err
+			}
+		}
+		if md.State(m) != "clear" {
+			fmt.Fprintf(w, "md%d is already active\n", m)
+			continue
+		}
+		if err := start(w, m, a); err != nil {
+			log.Print(err)
+			failed = true
+			continue
+		}
+		if n := name(a.Super); !ok && n != "" {
+			link := filepath.Join(md.Dev, "md", n)
+			os.MkdirAll(filepath.Dir(link), 0755)
+			os.Remove(link)
+			if err := os.Symlink(fmt.Sprintf("../md%d", m), link); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+	if failed {
+		return fmt.Errorf("not all arrays were started")
+	}
+	return nil
+}
+
+// printSuperblock prints the superblock of the device dev.
+func printSuperblock(w io.Writer, dev string, s *md.Superblock) {
+	role := strconv.Itoa(s.Role)
+	switch s.Role {
+	case md.RoleSpare:
+		role = "spare"
+	case md.RoleFaulty:
+		role = "faulty"
+	}
+	fmt.Fprintf(w, "%s:\n", dev)
+	fmt.Fprintf(w, "          Version : %s\n", s.Version())
+	fmt.Fprintf(w, "       Array UUID : %s\n", s.UUIDString())
+	if s.Name != "" {
+		fmt.Fprintf(w, "             Name : %s\n", s.Name)
+	}
+	if s.PreferredMinor >= 0 {
+		fmt.Fprintf(w, "  Preferred Minor : %d\n", s.PreferredMinor)
+	}
+	fmt.Fprintf(w, "       Raid Level : %s\n", s.LevelString())
+	fmt.Fprintf(w, "     Raid Devices : %d\n", s.RaidDisks)
+	fmt.Fprintf(w, "    Used Dev Size : %d KiB\n", s.Size/1024)
+	if s.ChunkSize != 0 {
+		fmt.Fprintf(w, "       Chunk Size : %dK\n", s.ChunkSize/1024)
+	}
+	fmt.Fprintf(w, "           Events : %d\n", s.Events)
+	fmt.Fprintf(w, "      Device Role : %s\n", role)
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	var err error
+	switch {
+	case examine:
+		if len(args) == 0 {
+			log.Fatal("usage: mdadm-lite --examine DEV...")
+		}
+		for _, d := range args {
+			s, err := md.Examine(d)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printSuperblock(os.Stdout, d, s)
+		}
+	case stop:
+		if len(args) != 1 {
+			log.Fatal("usage: mdadm-lite --stop /dev/mdN")
+		}
+		var m int
+		if m, err = md.Minor(args[0]); err == nil {
+			err = md.Stop(m)
+		}
+	case assemble && scan:
+		err = assembleScan(os.Stdout)
+	case assemble:
+		if len(args) < 2 {
+			log.Fatal("usage: mdadm-lite --assemble /dev/mdN DEV...")
+		}
+		var m int
+		if m, err = md.Minor(args[0]); err != nil {
+			break
+		}
+		var ms []md.Member
+		for _, d := range args[1:] {
+			s, err := md.Examine(d)
+			if err != nil {
+				log.Fatal(err)
+			}
+			ms = append(ms, md.Member{Device: d, Superblock: s})
+		}
+		switch arrays := md.Group(ms); {
+		case len(arrays) > 1:
+			err = fmt.Errorf("%v are in %d arrays, not one", args[1:], len(arrays))
+		default:
+			err = start(os.Stdout, m, arrays[0])
+		}
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/md"
+)
+
+func TestCandidates(t *testing.T) {
+	got := candidates([]string{"/dev/md0", "/dev/nvme0n1", "/dev/nvme0n1p1", "/dev/sda", "/dev/sda1", "/dev/sda2", "/dev/sdb", "/dev/sdb1", "/dev/sdc"})
+	want := []string{"/dev/nvme0n1p1", "/dev/sda1", "/dev/sda2", "/dev/sdb1", "/dev/sdc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates: got %v, want %v", got, want)
+	}
+}
+
+func TestMinor(t *testing.T) {
+	for _, tt := range []struct {
+		s     md.Superblock
+		minor int
+		ok    bool
+	}{
+		{md.Superblock{PreferredMinor: 3}, 3, true},
+		{md.Superblock{PreferredMinor: -1, Name: "host:1"}, 1, true},
+		{md.Superblock{PreferredMinor: -1, Name: "host:root"}, 0, false},
+		{md.Superblock{PreferredMinor: -1}, 0, false},
+	} {
+		if m, ok := minor(&tt.s); m != tt.minor || ok != tt.ok {
+			t.Errorf("minor(%+v): got %v, %v, want %v, %v", tt.s, m, ok, tt.minor, tt.ok)
+		}
+	}
+}
+
+// array makes a RAID level array of disks, with the devices of roles.
+func array(level, disks int, roles ...int) *md.Array {
+	a := &md.Array{Super: &md.Superblock{Level: level, RaidDisks: disks}}
+	for _, r := range roles {
+		a.Members = append(a.Members, md.Member{Device: "", Superblock: &md.Superblock{Role: r}})
+	}
+	return a
+}
+
+func TestCheck(t *testing.T) {
+	for _, tt := range []struct {
+		a    *md.Array
+		run  bool
+		desc string
+		ok   bool
+	}{
+		{array(1, 2, 0, 1), false, "2 drives (out of 2)", true},
+		{array(1, 2, 0, 1, md.RoleSpare, md.RoleSpare), false, "2 drives (out of 2) and 2 spares", true},
+		{array(1, 2, 1, md.RoleSpare), false, "1 drive (out of 2) and 1 spare", false},
+		{array(1, 2, 1), true, "1 drive (out of 2)", true},
+		{array(0, 2, 1), true, "1 drive (out of 2)", false},
+	} {
+		run = tt.run
+		if d := describe(tt.a); d != tt.desc {
+			t.Errorf("describe: got %q, want %q", d, tt.desc)
+		}
+		if err := check("/dev/md0", tt.a); (err == nil) != tt.ok {
+			t.Errorf("check %v, run %v: got %v, want ok %v", tt.desc, tt.run, err, tt.ok)
+		}
+	}
+}
+
+func TestPrintSuperblock(t *testing.T) {
+	var b bytes.Buffer
+	printSuperblock(&b, "/dev/sda1", &md.Superblock{Major: 1, Minor: 2, UUID: [16]byte{0xde, 0xad, 0xbe, 0xef},
+		Name: "host:root", PreferredMinor: -1, Level: 1, RaidDisks: 2, Size: 1 << 30, Events: 42, Role: md.RoleSpare})
+	want := `/dev/sda1:
+          Version : 1.2
+       Array UUID : deadbeef:00000000:00000000:00000000
+             Name : host:root
+       Raid Level : raid1
+     Raid Devices : 2
+    Used Dev Size : 1048576 KiB
+           Events : 42
+      Device Role : spare
+`
+	if b.String() != want {
+		t.Errorf("printSuperblock: got\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Mdev makes and removes device files as devices come and go.
+//
+// Synopsis:
+//     mdev [-s] [-n] [-c CONF] [-dev DIR] [-sys DIR]
+//
+// Description:
+//     mdev listens for the uevents the kernel sends when devices are added
+//     and removed. For a device with a device number it makes, or removes,
+//     the device file named by its DEVNAME in /dev, as the rules in CONF
+//     say. For one with a MODALIAS it loads the module for it, as modprobe
+//     would. So /dev need not be a devtmpfs, and the modules an image
+//     needs are loaded as the hardware is found. Once it is listening, it
+//     has the kernel send uevents for the devices already there.
+//
+//     Each line of CONF is a rule:
+//         REGEXP UID:GID MODE [>LINK | !]
+//     The first rule whose REGEXP matches all of DEVNAME says the owner and
+//     mode of the device file; without one it is 0:0 0660. >LINK makes a
+//     symlink to it, named by LINK, relative to /dev, in which $VAR is the
+//     uevent's VAR, or SERIAL, the serial number sysfs has for the device
+//     or a parent; the link is only made if all of them are known. ! makes
+//     no device file.
+//
+// Options:
+//     -s: only make the device files of the devices there now, and exit
+//     -n: do not load modules
+//     -c: the rules, /etc/mdev.conf by default; it need not exist
+//     -dev: the device directory
+//     -sys: where sysfs is mounted
+//
+// Example:
+//     $ cat /etc/mdev.conf
+//     # Disks are for group disk, and are found by serial number.
+//     sd[a-z]+ 0:6 0660 >disk/by-serial/$SERIAL
+//     null|zero|random|urandom|tty 0:0 0666
+//     $ mdev &
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	scanOnly  = flag.Bool("s", false, "only make the device files of the devices there now")
+	noModules = flag.Bool("n", false, "do not load modules")
+	conf      = flag.String("c", "/etc/mdev.conf", "rules `file`")
+	devDir    = flag.String("dev", "/dev", "device `directory`")
+	sysDir    = flag.String("sys", "/sys", "sysfs `directory`")
+)
+
+type mdev struct {
+	dev   string
+	sys   string
+	rules []rule
+	// mods is nil if modules are not loaded.
+	mods *kmodule.Modules
+	// links are the links made to each device file.
+	links map[string][]string
+}
+
+// serial returns the serial number sysfs has for the device at devpath,
+// or the closest parent with one, or "".
+func (m *mdev) serial(devpath string) string {
+	for d := filepath.Join(m.sys, devpath); strings.HasPrefix(d, m.sys+"/"); d = filepath.Dir(d) {
+		if b, err := ioutil.ReadFile(filepath.Join(d, "serial")); err == nil {
+			if s := strings.TrimSpace(string(b)); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// add makes the device file of u, and its link, as the rules say.
+func (m *mdev) add(u uevent, name string) error {
+	r := match(m.rules, name)
+	if r.skip {
+		return nil
+	}
+	var major, minor uint32
+	if _, err := fmt.Sscan(u["MAJOR"]+" "+u["MINOR"], &major, &minor); err != nil {
+		return fmt.Errorf("%v: bad device number %q:%q", name, u["MAJOR"], u["MINOR"])
+	}
+	mode := r.mode | syscall.S_IFCHR
+	if u["SUBSYSTEM"] == "block" {
+		mode = r.mode | syscall.S_IFBLK
+	}
+	p := filepath.Join(m.dev, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	// It may be there with another number, from a device which went
+	// before we heard.
+	os.Remove(p)
+	if err := unix.Mknod(p, mode, int(unix.Mkdev(major, minor))); err != nil {
+		return err
+	}
+	if err := os.Chown(p, r.uid, r.gid); err != nil {
+		return err
+	}
+	if r.link == "" {
+		return nil
+	}
+	if u["SERIAL"] == "" {
+		u["SERIAL"] = m.serial(u["DEVPATH"])
+	}
+	l := expand(r.link, u)
+	if l == "" {
+		return nil
+	}
+	lp := filepath.Join(m.dev, l)
+	if err := os.MkdirAll(filepath.Dir(lp), 0755); err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(lp), p)
+	if err != nil {
+		return err
+	}
+	os.Remove(lp)
+	if err := os.Symlink(target, lp); err != nil {
+		return err
+	}
+	m.links[name] = append(m.links[name], lp)
+	return nil
+}
+
+// remove removes the device file name and its links.
+func (m *mdev) remove(name string) error {
+	for _, l := range m.links[name] {
+		os.Remove(l)
+	}
+	delete(m.links, name)
+	if err := os.Remove(filepath.Join(m.dev, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// handle does what the uevent u calls for.
+func (m *mdev) handle(u uevent) error {
+	if u["ACTION"] == "add" && u["MODALIAS"] != "" && m.mods != nil {
+		// Most devices have no module, or one built in.
+		if err := m.mods.Probe(u["MODALIAS"], ""); err != nil && !kmodule.IsNotFound(err) {
+			log.Printf("mdev: module for %v: %v", u["MODALIAS"], err)
+		}
+	}
+	name := u["DEVNAME"]
+	if name == "" || u["MAJOR"] == "" {
+		return nil
+	}
+	// It must not get out of the device directory.
+	if name = filepath.Clean("/" + name)[1:]; name == "" {
+		return nil
+	}
+	switch u["ACTION"] {
+	case "add":
+		return m.add(u, name)
+	case "remove":
+		return m.remove(name)
+	}
+	return nil
+}
+
+func newMdev(dev, sys, conf string, modules bool) (*mdev, error) {
+	m := &mdev{dev: dev, sys: sys, links: make(map[string][]string)}
+	f, err := os.Open(conf)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		defer f.Close()
+		if m.rules, err = parseRules(f); err != nil {
+			return nil, fmt.Errorf("%v: %v", conf, err)
+		}
+	}
+	if modules {
+		// Only images with modules.dep can load modules.
+		if d, err := kmodule.ModuleDir(); err == nil {
+			m.mods, _ = kmodule.Open(d)
+		}
+	}
+	return m, nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	m, err := newMdev(*devDir, *sysDir, *conf, !*noModules && !*scanOnly)
+	if err != nil {
+		log.Fatalf("mdev: %v", err)
+	}
+	// The rules say what the mode is.
+	unix.Umask(0)
+
+	if *scanOnly {
+		us, err := scan(m.sys)
+		if err != nil {
+			log.Fatalf("mdev: %v", err)
+		}
+		for _, u := range us {
+			if err := m.handle(u); err != nil {
+				log.Printf("mdev: %v", err)
+			}
+		}
+		return
+	}
+
+	fd, err := listen()
+	if err != nil {
+		log.Fatalf("mdev: listening for uevents: %v", err)
+	}
+	go func() {
+		if err := trigger(m.sys); err != nil {
+			log.Printf("mdev: %v", err)
+		}
+	}()
+	for {
+		u, err := readUevent(fd)
+		if err == syscall.ENOBUFS {
+			// We missed some; there is no getting them back.
+			log.Printf("mdev: %v", err)
+			continue
+		}
+		if err != nil {
+			log.Fatalf("mdev: %v", err)
+		}
+		if err := m.handle(u); err != nil {
+			log.Printf("mdev: %v", err)
+		}
+	}
+}
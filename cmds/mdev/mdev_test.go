@@ -0,0 +1,216 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseUevent(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want uevent
+	}{
+		{
+			"add@/devices/virtual/mem/null\x00ACTION=add\x00DEVPATH=/devices/virtual/mem/null\x00SUBSYSTEM=mem\x00MAJOR=1\x00MINOR=3\x00DEVNAME=null\x00SEQNUM=1\x00",
+			uevent{"ACTION": "add", "DEVPATH": "/devices/virtual/mem/null", "SUBSYSTEM": "mem", "MAJOR": "1", "MINOR": "3", "DEVNAME": "null", "SEQNUM": "1"},
+		},
+		{
+			"bind@/devices/pci0000:00\x00ACTION=bind\x00DEVPATH=/devices/pci0000:00\x00MODALIAS=pci:v8086\x00",
+			uevent{"ACTION": "bind", "DEVPATH": "/devices/pci0000:00", "MODALIAS": "pci:v8086"},
+		},
+		{"libudev\x00\xfe\xed", nil},
+		{"add@/x\x00SUBSYSTEM=mem\x00", nil},
+	} {
+		got, err := parseUevent([]byte(tt.in))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseUevent(%q): got %v, want %v", tt.in, got, tt.want)
+		}
+		if (err != nil) != (tt.want == nil) {
+			t.Errorf("parseUevent(%q): got error %v", tt.in, err)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(strings.NewReader(`
+# Comment.
+sd[a-z]+ 0:6 0660 >disk/by-serial/$SERIAL
+null|zero 0:0 666
+watchdog 0:0 0600 !
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		want rule
+	}{
+		{"sda", rule{gid: 6, mode: 0660, link: "disk/by-serial/$SERIAL"}},
+		{"sda1", defaultRule},
+		{"zero", rule{mode: 0666}},
+		{"nullx", defaultRule},
+		{"watchdog", rule{mode: 0600, skip: true}},
+	} {
+		got := match(rules, tt.name)
+		got.re = nil
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("match(%q): got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"sda 0:6",
+		"sda 0 0660",
+		"sda x:0 0660",
+		"sda 0:0 0999",
+		"sda 0:0 0660 disk",
+		"sd( 0:0 0660",
+	} {
+		if _, err := parseRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseRules(%q): got nil, want an error", bad)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	u := uevent{"SERIAL": "S1", "DEVNAME": "sda"}
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"disk/$SERIAL", "disk/S1"},
+		{"disk/${DEVNAME}-$SERIAL", "disk/sda-S1"},
+		{"disk/$MISSING", ""},
+		{"plain", "plain"},
+	} {
+		if got := expand(tt.in, u); got != tt.want {
+			t.Errorf("expand(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("mknod needs root")
+	}
+	tmp, err := ioutil.TempDir("", "mdev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dev, sys := filepath.Join(tmp, "dev"), filepath.Join(tmp, "sys")
+	conf := filepath.Join(tmp, "mdev.conf")
+
+	// sda's serial number is its parent's, as for a USB disk.
+	disk := filepath.Join(sys, "devices/usb1/1-1/host0/block/sda")
+	if err := os.MkdirAll(disk, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sys, "devices/usb1/1-1/serial"), []byte("ABC123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(conf, []byte("sd[a-z]+ 0:6 0640 >disk/by-serial/$SERIAL\nbus/.* 0:0 0664\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := newMdev(dev, sys, conf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Umask(unix.Umask(0))
+
+	sda := uevent{"ACTION": "add", "DEVPATH": "/devices/usb1/1-1/host0/block/sda", "SUBSYSTEM": "block", "MAJOR": "8", "MINOR": "0", "DEVNAME": "sda"}
+	usb := uevent{"ACTION": "add", "DEVPATH": "/devices/usb1/1-1", "SUBSYSTEM": "usb", "MAJOR": "189", "MINOR": "1", "DEVNAME": "bus/usb/001/002"}
+	for _, u := range []uevent{sda, usb} {
+		if err := m.handle(u); err != nil {
+			t.Fatalf("handle(%v): %v", u, err)
+		}
+	}
+	for _, tt := range []struct {
+		name         string
+		mode         uint32
+		gid          uint32
+		major, minor uint32
+	}{
+		{"sda", syscall.S_IFBLK | 0640, 6, 8, 0},
+		{"bus/usb/001/002", syscall.S_IFCHR | 0664, 0, 189, 1},
+	} {
+		var st syscall.Stat_t
+		p := filepath.Join(dev, tt.name)
+		if err := syscall.Lstat(p, &st); err != nil {
+			t.Errorf("%v: %v", p, err)
+			continue
+		}
+		if st.Mode != tt.mode || st.Gid != tt.gid {
+			t.Errorf("%v: got mode %#o gid %d, want %#o gid %d", p, st.Mode, st.Gid, tt.mode, tt.gid)
+		}
+		if maj, min := unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev)); maj != tt.major || min != tt.minor {
+			t.Errorf("%v: got device %d:%d, want %d:%d", p, maj, min, tt.major, tt.minor)
+		}
+	}
+	link := filepath.Join(dev, "disk/by-serial/ABC123")
+	if l, err := os.Readlink(link); err != nil || l != "../../sda" {
+		t.Errorf("Readlink(%v): got %q, %v, want ../../sda", link, l, err)
+	}
+
+	sda["ACTION"] = "remove"
+	if err := m.handle(sda); err != nil {
+		t.Fatalf("handle(%v): %v", sda, err)
+	}
+	for _, p := range []string{filepath.Join(dev, "sda"), link} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Errorf("%v: got %v, want it removed", p, err)
+		}
+	}
+
+	// DEVNAME can not get out of dev.
+	escape := uevent{"ACTION": "add", "DEVPATH": "/x", "MAJOR": "1", "MINOR": "3", "DEVNAME": "../../escaped"}
+	if err := m.handle(escape); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(tmp, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("DEVNAME ../../escaped: got a file outside %v", dev)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "mdev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	null := filepath.Join(tmp, "devices/virtual/mem/null")
+	for _, d := range []string{null, filepath.Join(tmp, "class/mem"), filepath.Join(tmp, "dev/char")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(null, "uevent"), []byte("MAJOR=1\nMINOR=3\nDEVNAME=null\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../class/mem", filepath.Join(null, "subsystem")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../devices/virtual/mem/null", filepath.Join(tmp, "dev/char/1:3")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := scan(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uevent{{"ACTION": "add", "DEVPATH": "/devices/virtual/mem/null", "SUBSYSTEM": "mem", "MAJOR": "1", "MINOR": "3", "DEVNAME": "null"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scan(%q): got %v, want %v", tmp, got, want)
+	}
+}
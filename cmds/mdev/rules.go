@@ -0,0 +1,110 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A rule says how to make the device files whose names match re.
+type rule struct {
+	re   *regexp.Regexp
+	uid  int
+	gid  int
+	mode uint32
+	// link is the name, relative to the device directory, of a symlink
+	// to the device file, with $VARs from the uevent; or "".
+	link string
+	// skip is true if no device file is to be made.
+	skip bool
+}
+
+// defaultRule is for devices no rule matches.
+var defaultRule = rule{mode: 0660}
+
+// parseRules parses rules, one per line, as
+//     REGEXP UID:GID MODE [>LINK | !]
+// Blank lines and those which start with # are ignored.
+func parseRules(r io.Reader) ([]rule, error) {
+	var rules []rule
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		if len(f) != 3 && len(f) != 4 {
+			return nil, fmt.Errorf("line %d: want REGEXP UID:GID MODE [>LINK | !], got %q", n, s.Text())
+		}
+		var (
+			ru  rule
+			err error
+		)
+		if ru.re, err = regexp.Compile("^(" + f[0] + ")$"); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		ids := strings.SplitN(f[1], ":", 2)
+		if len(ids) != 2 {
+			return nil, fmt.Errorf("line %d: %q is not UID:GID", n, f[1])
+		}
+		if ru.uid, err = strconv.Atoi(ids[0]); err != nil {
+			return nil, fmt.Errorf("line %d: uid: %v", n, err)
+		}
+		if ru.gid, err = strconv.Atoi(ids[1]); err != nil {
+			return nil, fmt.Errorf("line %d: gid: %v", n, err)
+		}
+		m, err := strconv.ParseUint(f[2], 8, 32)
+		if err != nil || m&^07777 != 0 {
+			return nil, fmt.Errorf("line %d: invalid mode %q", n, f[2])
+		}
+		ru.mode = uint32(m)
+		if len(f) == 4 {
+			switch {
+			case f[3] == "!":
+				ru.skip = true
+			case strings.HasPrefix(f[3], ">") && len(f[3]) > 1:
+				ru.link = f[3][1:]
+			default:
+				return nil, fmt.Errorf("line %d: %q is not >LINK or !", n, f[3])
+			}
+		}
+		rules = append(rules, ru)
+	}
+	return rules, s.Err()
+}
+
+// match returns the first of rules which matches the device file name, or
+// defaultRule.
+func match(rules []rule, name string) rule {
+	for _, r := range rules {
+		if r.re.MatchString(name) {
+			return r
+		}
+	}
+	return defaultRule
+}
+
+// expand replaces $VAR and ${VAR} in link with the values in u. It returns
+// "" if any of them is empty, as a link named by part of a name would be
+// misleading.
+func expand(link string, u uevent) string {
+	missing := false
+	s := os.Expand(link, func(v string) string {
+		if u[v] == "" {
+			missing = true
+		}
+		return u[v]
+	})
+	if missing {
+		return ""
+	}
+	return s
+}
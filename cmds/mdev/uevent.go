@@ -0,0 +1,145 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// A uevent is what the kernel says of a device which came or went:
+// ACTION, DEVPATH, SUBSYSTEM, and, for those with a device file, MAJOR,
+// MINOR and DEVNAME, among others.
+type uevent map[string]string
+
+// parseUevent parses a uevent from the kernel: ACTION@DEVPATH, then
+// KEY=VALUE strings, each ending in a NUL.
+func parseUevent(b []byte) (uevent, error) {
+	f := bytes.Split(bytes.TrimRight(b, "\x00"), []byte{0})
+	if !bytes.Contains(f[0], []byte("@")) {
+		// E.g. from libudev, which we do not listen for.
+		return nil, fmt.Errorf("not a kernel uevent: %q", f[0])
+	}
+	u := make(uevent)
+	for _, kv := range f[1:] {
+		if i := bytes.IndexByte(kv, '='); i > 0 {
+			u[string(kv[:i])] = string(kv[i+1:])
+		}
+	}
+	if u["ACTION"] == "" || u["DEVPATH"] == "" {
+		return nil, fmt.Errorf("uevent %q has no ACTION or DEVPATH", f[0])
+	}
+	return u, nil
+}
+
+// listen returns a socket on which the kernel sends uevents.
+func listen() (int, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return -1, err
+	}
+	// Plugging in a hub makes a burst of them.
+	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUFFORCE, 1<<20); err != nil {
+		syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, 1<<20)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// readUevent reads the next uevent from fd, the socket from listen.
+func readUevent(fd int) (uevent, error) {
+	b := make([]byte, 8192)
+	for {
+		n, from, err := syscall.Recvfrom(fd, b, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Only the kernel, port 0, is to be believed.
+		if nl, ok := from.(*syscall.SockaddrNetlink); !ok || nl.Pid != 0 {
+			continue
+		}
+		if u, err := parseUevent(b[:n]); err == nil {
+			return u, nil
+		}
+	}
+}
+
+// readDevice returns an add uevent for the device at dir, a directory of
+// sys/devices, from its uevent file, which only has the KEY=VALUE lines.
+func readDevice(sys, dir string) (uevent, error) {
+	f, err := os.Open(filepath.Join(dir, "uevent"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rel, err := filepath.Rel(sys, dir)
+	if err != nil {
+		return nil, err
+	}
+	u := uevent{"ACTION": "add", "DEVPATH": "/" + filepath.ToSlash(rel)}
+	if s, err := os.Readlink(filepath.Join(dir, "subsystem")); err == nil {
+		u["SUBSYSTEM"] = filepath.Base(s)
+	}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if kv := strings.SplitN(s.Text(), "=", 2); len(kv) == 2 {
+			u[kv[0]] = kv[1]
+		}
+	}
+	return u, s.Err()
+}
+
+// scan returns an add uevent for each device in sys with a device number,
+// as linked to from sys/dev/block and sys/dev/char.
+func scan(sys string) ([]uevent, error) {
+	var us []uevent
+	for _, class := range []string{"block", "char"} {
+		links, err := filepath.Glob(filepath.Join(sys, "dev", class, "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			dir, err := filepath.EvalSymlinks(l)
+			if err != nil {
+				continue
+			}
+			u, err := readDevice(sys, dir)
+			if err != nil {
+				continue
+			}
+			if class == "block" {
+				// SUBSYSTEM is how handle knows a block device.
+				u["SUBSYSTEM"] = "block"
+			}
+			us = append(us, u)
+		}
+	}
+	return us, nil
+}
+
+// trigger has the kernel send an add uevent for every device in sys, so
+// those there before we listened get their modules and device files too.
+func trigger(sys string) error {
+	return filepath.Walk(filepath.Join(sys, "devices"), func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.Name() != "uevent" || !fi.Mode().IsRegular() {
+			return nil
+		}
+		// Some devices can not be triggered; that is fine.
+		ioutil.WriteFile(p, []byte("add"), 0)
+		return nil
+	})
+}
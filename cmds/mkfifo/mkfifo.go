@@ -0,0 +1,60 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Mkfifo creates named pipes.
+//
+// Synopsis:
+//     mkfifo [-m MODE] NAME...
+//
+// Description:
+//     Creates a named pipe, or FIFO, for each NAME.
+//
+// Options:
+//     -m: octal permissions of the pipes, 0666 less the umask by default;
+//         the umask is not applied to MODE
+//
+// Example:
+//     mkfifo -m 600 /tmp/pipe
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const defaultPerms = 0666
+
+var perms = flag.String("m", "", "octal permissions `mode` of the pipes")
+
+func mkfifo(names []string) error {
+	mode := uint32(defaultPerms)
+	if *perms != "" {
+		m, err := strconv.ParseUint(*perms, 8, 32)
+		if err != nil || m&^07777 != 0 {
+			return fmt.Errorf("invalid mode %q", *perms)
+		}
+		mode = uint32(m)
+		defer unix.Umask(unix.Umask(0))
+	}
+	for _, n := range names {
+		if err := unix.Mkfifo(n, mode); err != nil {
+			return fmt.Errorf("%v: %v", n, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("Usage: mkfifo [-m MODE] NAME...")
+	}
+	if err := mkfifo(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
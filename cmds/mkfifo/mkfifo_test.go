@@ -0,0 +1,45 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestMkfifo(t *testing.T) {
+	tempDir, mkfifoPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tempDir)
+
+	a, b := filepath.Join(tempDir, "a"), filepath.Join(tempDir, "b")
+	if out, err := exec.Command(mkfifoPath, "-m", "0640", a, b).CombinedOutput(); err != nil {
+		t.Fatalf("mkfifo -m 0640 %v %v: %v, %s", a, b, err, out)
+	}
+	for _, n := range []string{a, b} {
+		fi, err := os.Stat(n)
+		if err != nil {
+			t.Errorf("%v: %v", n, err)
+			continue
+		}
+		if got, want := fi.Mode(), os.ModeNamedPipe|0640; got != want {
+			t.Errorf("%v: got mode %v, want %v", n, got, want)
+		}
+	}
+
+	// It is there already.
+	if err := exec.Command(mkfifoPath, a).Run(); err == nil {
+		t.Errorf("mkfifo %v: got nil, want an error", a)
+	}
+	if err := exec.Command(mkfifoPath).Run(); err == nil {
+		t.Errorf("mkfifo with no names: got nil, want an error")
+	}
+	if err := exec.Command(mkfifoPath, "-m", "8", filepath.Join(tempDir, "c")).Run(); err == nil {
+		t.Errorf("mkfifo -m 8: got nil, want an error")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Make a file system.
+//
+// Synopsis:
+//     mkfs -t vfat [-n LABEL] [-s SECTORS] DEV
+//     mkfs -t TYPE [ARGS...] DEV
+//
+// Description:
+//     mkfs makes vfat (FAT32) file systems itself, on the whole of DEV,
+//     which may be a device or a file. For any other TYPE, e.g. ext4, it
+//     runs mkfs.TYPE from PATH with ARGS and DEV; -t TYPE must then come
+//     first.
+//
+// Options:
+//     -t: file system type
+//     -n: volume label
+//     -s: sectors per cluster; by default it depends on the size of DEV
+//
+// Example:
+//     mkfs -t vfat -n EFI /dev/sda1
+//     mkfs -t ext4 -L root /dev/sda2
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/fat"
+)
+
+var (
+	fsType            = flag.String("t", "", "File system type")
+	label             = flag.String("n", "", "Volume label")
+	sectorsPerCluster = flag.Int("s", 0, "Sectors per cluster")
+)
+
+func usage() {
+	log.Fatalf("Usage: mkfs -t vfat [-n LABEL] [-s SECTORS] DEV | mkfs -t TYPE [ARGS...] DEV")
+}
+
+func vfat(name string) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Seeking to the end gives the size of devices as well as files.
+	size, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		return err
+	}
+	o := fat.Options{
+		Label:             *label,
+		ID:                uint32(time.Now().Unix()),
+		SectorsPerCluster: *sectorsPerCluster,
+	}
+	if err := fat.Format(f, size, o); err == fat.ErrFewClusters {
+		log.Printf("%s: %v", name, err)
+	} else if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// isFAT says if t is a name for the file systems mkfs makes itself.
+func isFAT(t string) bool {
+	return t == "vfat" || t == "fat" || t == "msdos"
+}
+
+// other runs mkfs.t with args.
+func other(t string, args []string) {
+	cmd := "mkfs." + t
+	p, err := exec.LookPath(cmd)
+	if err != nil {
+		log.Fatalf("%s file systems need %s: %v", t, cmd, err)
+	}
+	err = syscall.Exec(p, append([]string{cmd}, args...), os.Environ())
+	log.Fatalf("%s: %v", p, err)
+}
+
+func main() {
+	// The arguments for other file systems are theirs, not ours.
+	if len(os.Args) > 2 && os.Args[1] == "-t" && !isFAT(os.Args[2]) {
+		other(os.Args[2], os.Args[3:])
+	}
+	flag.Parse()
+	if !isFAT(*fsType) || flag.NArg() != 1 {
+		usage()
+	}
+	if err := vfat(flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
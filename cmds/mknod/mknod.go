@@ -2,15 +2,22 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Unmount a filesystem at the specified path.
+// Mknod creates a special file.
 //
 // Synopsis:
-//     mknod PATH TYPE [MAJOR MINOR]
+//     mknod [-m MODE] PATH TYPE [MAJOR MINOR]
 //
 // Description:
 //     Creates a special file at PATH of the given TYPE. If TYPE is b, c or u,
 //     the MAJOR and MINOR number must be specified. If the TYPE is p, they
 //     must not be specified.
+//
+// Options:
+//     -m: octal permissions of the file, 0660 by default; the umask is not
+//         applied
+//
+// Example:
+//     mknod -m 666 /dev/null c 1 3
 package main
 
 import "log"
@@ -15,19 +15,33 @@ import (
 
 const defaultPerms = 0660
 
+var perms = flag.String("m", "", "octal permissions `mode` of the file")
+
 func parseDevices(args []string, devtype string) (int, error) {
 	if len(args) != 4 {
 		return 0, fmt.Errorf("device type %v requires a major and minor number", devtype)
 	}
-	major, err := strconv.ParseUint(args[2], 10, 8)
+	major, err := strconv.ParseUint(args[2], 10, 32)
 	if err != nil {
 		return 0, err
 	}
-	minor, err := strconv.ParseUint(args[3], 10, 8)
+	minor, err := strconv.ParseUint(args[3], 10, 32)
 	if err != nil {
 		return 0, err
 	}
-	return int((major << 8) | minor), nil
+	return int(unix.Mkdev(uint32(major), uint32(minor))), nil
+}
+
+// parsePerms returns the permissions of -m, or defaultPerms.
+func parsePerms() (uint32, error) {
+	if *perms == "" {
+		return defaultPerms, nil
+	}
+	m, err := strconv.ParseUint(*perms, 8, 32)
+	if err != nil || m&^07777 != 0 {
+		return 0, fmt.Errorf("invalid mode %q", *perms)
+	}
+	return uint32(m), nil
 }
 
 func mknod() error {
@@ -39,10 +53,10 @@ func mknod() error {
 	path := a[0]
 	devtype := a[1]
 
-	var err error
-	var mode uint32
-
-	mode = defaultPerms
+	mode, err := parsePerms()
+	if err != nil {
+		return err
+	}
 	var dev int
 
 	switch devtype {
@@ -70,6 +84,10 @@ func mknod() error {
 		return fmt.Errorf("device type not recognized: %v", devtype)
 	}
 
+	// Like mknod(1), -m is not subject to the umask.
+	if *perms != "" {
+		defer unix.Umask(unix.Umask(0))
+	}
 	if err := unix.Mknod(path, mode, dev); err != nil {
 		return fmt.Errorf("mknod :%s: mode %x: %v", path, mode, err)
 	}
@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/u-root/u-root/pkg/testutil"
+	"golang.org/x/sys/unix"
 )
 
 var (
@@ -98,3 +99,57 @@ func TestMknodBlock(t *testing.T) {
 	//TODO(ganshun): implement block test
 	t.Skip("Unimplemented test, need root")
 }
+
+func TestMknodMode(t *testing.T) {
+	tempDir, mknodPath := testutil.CompileInTempDir(t)
+
+	if remove {
+		defer os.RemoveAll(tempDir)
+	}
+
+	pipepath := filepath.Join(tempDir, "testpipe")
+	c := exec.Command(mknodPath, "-m", "0604", pipepath, "p")
+	if _, e, err := run(c); err != nil {
+		t.Fatalf("mknod -m 0604 %v p: %v, %s", pipepath, err, e)
+	}
+	fi, err := os.Stat(pipepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode(), os.ModeNamedPipe|0604; got != want {
+		t.Errorf("mknod -m 0604: got mode %v, want %v", got, want)
+	}
+
+	c = exec.Command(mknodPath, "-m", "999", filepath.Join(tempDir, "bad"), "p")
+	if _, e, _ := run(c); e[20:] != "invalid mode \"999\"\n" {
+		t.Errorf("mknod -m 999: got %q, want invalid mode", e[20:])
+	}
+}
+
+func TestMknodChar(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("mknod of a device needs root")
+	}
+	tempDir, mknodPath := testutil.CompileInTempDir(t)
+
+	if remove {
+		defer os.RemoveAll(tempDir)
+	}
+
+	// Majors and minors are more than 8 bits.
+	devpath := filepath.Join(tempDir, "testdev")
+	c := exec.Command(mknodPath, devpath, "c", "300", "1000")
+	if _, e, err := run(c); err != nil {
+		t.Fatalf("mknod %v c 300 1000: %v, %s", devpath, err, e)
+	}
+	var st unix.Stat_t
+	if err := unix.Lstat(devpath, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFCHR {
+		t.Errorf("mknod c: got mode %#o, want a character device", st.Mode)
+	}
+	if maj, min := unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev)); maj != 300 || min != 1000 {
+		t.Errorf("mknod c 300 1000: got %d:%d, want 300:1000", maj, min)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Mktemp makes a temporary file or directory, and prints its name.
+//
+// Synopsis:
+//     mktemp [-dqtu] [-p DIR] [-suffix SUFFIX] [TEMPLATE]
+//
+// Description:
+//     mktemp makes a file, which only its owner can read and write, or
+//     with -d, a directory, which only its owner can use, named TEMPLATE,
+//     tmp.XXXXXXXXXX by default, with the Xs at its end replaced by random
+//     letters and digits. There must be at least 3.
+//
+//     Without a TEMPLATE, or with -p or -t, it is made in DIR, or $TMPDIR,
+//     or /tmp. Otherwise, TEMPLATE is a name like any other.
+//
+//     Flags may be combined, as in mktemp -dt build.XXXXXX.
+//
+// Options:
+//     -d: make a directory, not a file
+//     -p: make it in DIR
+//     -q: do not print errors
+//     -t: make it in DIR, or $TMPDIR, or /tmp
+//     -u: do not make anything; just print a name
+//     -suffix: end the name with SUFFIX, after the Xs
+//
+// Example:
+//     $ dir=$(mktemp -d)
+//     $ mktemp -p /run lock.XXXX
+//     /run/lock.Lk0e
+//     $ mktemp --suffix=.json
+//     /tmp/tmp.4gC9QeZ0wA.json
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultTemplate = "tmp.XXXXXXXXXX"
+	letters         = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	// attempts is how many names mktemp tries before it gives up.
+	attempts = 1000
+)
+
+var (
+	dir    = flag.Bool("d", false, "make a directory, not a file")
+	tmpdir = flag.String("p", "", "make it in `dir`")
+	quiet  = flag.Bool("q", false, "do not print errors")
+	inTmp  = flag.Bool("t", false, "make it in dir, or $TMPDIR, or /tmp")
+	dryRun = flag.Bool("u", false, "do not make anything; just print a name")
+	suffix = flag.String("suffix", "", "end the name with `suffix`")
+)
+
+// A template is a name, with Xs at the end of prefix to be replaced.
+type template struct {
+	prefix string
+	xs     int
+	suffix string
+}
+
+// parseTemplate parses the template s, which ends with suffix after the
+// Xs.
+func parseTemplate(s, suffix string) (template, error) {
+	if strings.ContainsRune(suffix, '/') {
+		return template{}, fmt.Errorf("invalid suffix %q, contains directory separator", suffix)
+	}
+	prefix := strings.TrimRight(s, "X")
+	t := template{prefix: prefix, xs: len(s) - len(prefix), suffix: suffix}
+	if t.xs < 3 {
+		return template{}, fmt.Errorf("too few X's in template %q", s+suffix)
+	}
+	return t, nil
+}
+
+// name returns a name made from t, with random letters and digits in
+// place of the Xs.
+func (t template) name() (string, error) {
+	b := make([]byte, t.xs)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i, c := range b {
+		b[i] = letters[int(c)%len(letters)]
+	}
+	return t.prefix + string(b) + t.suffix, nil
+}
+
+// makeTemp makes a file, or with isDir, a directory, named from t, and
+// returns its name. With dryRun, it only returns a name that is not
+// there.
+func makeTemp(t template, isDir, dryRun bool) (string, error) {
+	for i := 0; i < attempts; i++ {
+		name, err := t.name()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case dryRun:
+			_, err = os.Lstat(name)
+			if os.IsNotExist(err) {
+				return name, nil
+			}
+			if err == nil {
+				err = os.ErrExist
+			}
+		case isDir:
+			err = os.Mkdir(name, 0700)
+		default:
+			var f *os.File
+			if f, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600); err == nil {
+				err = f.Close()
+			}
+		}
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("cannot make a temporary name from %q", t.prefix+strings.Repeat("X", t.xs)+t.suffix)
+}
+
+// args splits combined flags, such as -dp/run, into flags as flag takes
+// them, -d -p /run.
+func args(a []string) []string {
+	out := []string{a[0]}
+	for i := 1; i < len(a); i++ {
+		s := a[i]
+		switch {
+		case s == "--" || len(s) < 2 || s[0] != '-':
+			return append(out, a[i:]...)
+		case s == "--suffix" || s == "-suffix":
+			out = append(out, s)
+			if i+1 < len(a) {
+				i++
+				out = append(out, a[i])
+			}
+			continue
+		case strings.HasPrefix(s, "--") || strings.HasPrefix(s, "-suffix="):
+			out = append(out, s)
+			continue
+		}
+		for j := 1; j < len(s); j++ {
+			out = append(out, "-"+s[j:j+1])
+			if s[j] != 'p' {
+				continue
+			}
+			if j+1 < len(s) {
+				out = append(out, s[j+1:])
+			} else if i+1 < len(a) {
+				i++
+				out = append(out, a[i])
+			}
+			break
+		}
+	}
+	return out
+}
+
+func main() {
+	os.Args = args(os.Args)
+	flag.Parse()
+	if *quiet {
+		log.SetOutput(ioutil.Discard)
+	}
+	if flag.NArg() > 1 {
+		log.Fatalf("mktemp: too many templates")
+	}
+
+	s := flag.Arg(0)
+	d := *tmpdir
+	if s == "" {
+		s, *inTmp = defaultTemplate, true
+	}
+	if *inTmp || d != "" {
+		if d == "" {
+			d = os.TempDir()
+		}
+		if filepath.IsAbs(s) || *inTmp && strings.ContainsRune(s, '/') {
+			log.Fatalf("mktemp: invalid template %q, contains directory separator", s)
+		}
+		s = filepath.Join(d, s)
+	}
+
+	t, err := parseTemplate(s, *suffix)
+	if err != nil {
+		log.Fatalf("mktemp: %v", err)
+	}
+	name, err := makeTemp(t, *dir, *dryRun)
+	if err != nil {
+		log.Fatalf("mktemp: %v", err)
+	}
+	fmt.Println(name)
+}
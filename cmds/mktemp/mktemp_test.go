@@ -0,0 +1,100 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		s, suffix string
+		want      template
+		ok        bool
+	}{
+		{"tmp.XXXXXXXXXX", "", template{"tmp.", 10, ""}, true},
+		{"/run/lock.XXX", ".pid", template{"/run/lock.", 3, ".pid"}, true},
+		{"XXXX", "", template{"", 4, ""}, true},
+		{"aXXbXX", "", template{}, false},
+		{"tmp.XXXXXX", "/x", template{}, false},
+		{"tmp", "", template{}, false},
+	} {
+		got, err := parseTemplate(tt.s, tt.suffix)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseTemplate(%q, %q): got %+v, %v, want %+v, ok %v", tt.s, tt.suffix, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMakeTemp(t *testing.T) {
+	d, err := ioutil.TempDir("", "mktemp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	tmpl := template{prefix: filepath.Join(d, "x."), xs: 6, suffix: ".txt"}
+	for _, tt := range []struct {
+		isDir, dryRun bool
+		mode          os.FileMode
+	}{
+		{false, false, 0600},
+		{true, false, os.ModeDir | 0700},
+		{false, true, 0},
+	} {
+		name, err := makeTemp(tmpl, tt.isDir, tt.dryRun)
+		if err != nil {
+			t.Errorf("makeTemp(%+v, %v, %v): %v", tmpl, tt.isDir, tt.dryRun, err)
+			continue
+		}
+		x := strings.TrimSuffix(strings.TrimPrefix(name, tmpl.prefix), ".txt")
+		if len(x) != 6 || strings.Trim(x, letters) != "" {
+			t.Errorf("makeTemp(%+v, %v, %v): got %q, which does not match the template", tmpl, tt.isDir, tt.dryRun, name)
+		}
+		fi, err := os.Lstat(name)
+		if tt.dryRun {
+			if !os.IsNotExist(err) {
+				t.Errorf("makeTemp with dryRun made %q", name)
+			}
+			continue
+		}
+		if err != nil || fi.Mode() != tt.mode {
+			t.Errorf("mode of %q: got %v, %v, want %v", name, fi.Mode(), err, tt.mode)
+		}
+	}
+
+	// With every name taken, makeTemp gives up.
+	tmpl = template{prefix: filepath.Join(d, "y"), xs: 1}
+	for _, c := range letters {
+		if err := ioutil.WriteFile(tmpl.prefix+string(c), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if name, err := makeTemp(tmpl, false, false); err == nil {
+		t.Errorf("makeTemp with every name taken: got %q, nil, want an error", name)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"mktemp", "-dt", "build.XXX"}, []string{"mktemp", "-d", "-t", "build.XXX"}},
+		{[]string{"mktemp", "-dp/run", "x.XXX"}, []string{"mktemp", "-d", "-p", "/run", "x.XXX"}},
+		{[]string{"mktemp", "-qp", "/run"}, []string{"mktemp", "-q", "-p", "/run"}},
+		{[]string{"mktemp", "--suffix", ".json", "-du"}, []string{"mktemp", "--suffix", ".json", "-d", "-u"}},
+		{[]string{"mktemp", "--suffix=.json", "-u"}, []string{"mktemp", "--suffix=.json", "-u"}},
+		{[]string{"mktemp", "XXX", "-d"}, []string{"mktemp", "XXX", "-d"}},
+	} {
+		if got := args(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("args(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Load kernel modules and the modules they depend on.
+//
+// Synopsis:
+//     modprobe [-nvq] [-d DIR] MODULE [OPTIONS...]
+//     modprobe -a [-nvq] [-d DIR] MODULE...
+//     modprobe -r [-v] [-d DIR] MODULE...
+//
+// Description:
+//     modprobe finds MODULE, by its name or an alias such as a PCI ID, in
+//     the modules.dep and modules.alias files of /lib/modules/RELEASE,
+//     which depmod writes, then loads the modules it depends on and
+//     itself. Modules which are loaded or built in are skipped. Modules
+//     may be compressed with gzip, xz or zstd.
+//
+// Options:
+//     -a: load all MODULEs, rather than passing OPTIONS to one
+//     -r: remove the MODULEs, and the modules they depend on if unused
+//     -n: print what would be loaded, but do not load it
+//     -v: print what is loaded
+//     -q: do not complain about modules which are not found
+//     -d: the module directory, rather than /lib/modules/RELEASE
+//
+// Example:
+//     modprobe e1000e
+//     modprobe pci:v00008086d000010D3sv*sd*bc*sc*i*
+//     modprobe loop max_loop=16
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+)
+
+var (
+	all     = flag.Bool("a", false, "load all the modules named")
+	remove  = flag.Bool("r", false, "remove the modules")
+	dryRun  = flag.Bool("n", false, "print what would be loaded")
+	verbose = flag.Bool("v", false, "print what is loaded")
+	quiet   = flag.Bool("q", false, "do not complain about modules which are not found")
+	dir     = flag.String("d", "", "module `directory`")
+)
+
+// probe loads name with opts, or just prints what it would load.
+func probe(m *kmodule.Modules, name, opts string) error {
+	if !*dryRun && !*verbose {
+		return m.Probe(name, opts)
+	}
+	names, err := m.Lookup(name)
+	if err != nil {
+		return err
+	}
+	loaded, err := kmodule.Loaded()
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		files, err := m.Deps(n, loaded)
+		if err != nil {
+			return err
+		}
+		for i, f := range files {
+			o := ""
+			if i == len(files)-1 {
+				o = opts
+			}
+			fmt.Println(strings.TrimSpace("insmod " + f + " " + o))
+			if *dryRun {
+				continue
+			}
+			if err := kmodule.Load(f, o, 0); err != nil {
+				return err
+			}
+			loaded[kmodule.Name(f)] = true
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("Usage: modprobe [-anrvq] [-d DIR] MODULE [OPTIONS...]")
+	}
+	d := *dir
+	if d == "" {
+		var err error
+		if d, err = kmodule.ModuleDir(); err != nil {
+			log.Fatalf("modprobe: %v", err)
+		}
+	}
+	m, err := kmodule.Open(d)
+	if err != nil {
+		log.Fatalf("modprobe: %v", err)
+	}
+
+	names, opts := flag.Args(), ""
+	if !*all && !*remove {
+		names, opts = flag.Args()[:1], strings.Join(flag.Args()[1:], " ")
+	}
+	status := 0
+	for _, n := range names {
+		if *remove {
+			if *verbose {
+				fmt.Printf("rmmod %v\n", n)
+			}
+			err = m.Remove(n)
+		} else {
+			err = probe(m, n, opts)
+		}
+		if err != nil && !(*quiet && kmodule.IsNotFound(err)) {
+			log.Printf("modprobe: %v", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
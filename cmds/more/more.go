@@ -0,0 +1,311 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Page through text.
+//
+// Synopsis:
+//     more [-i] [FILE...]
+//
+// Description:
+//     more shows the FILEs, or stdin, a screen at a time, and says how far
+//     through them it is. Text which fits on one screen is just printed,
+//     as it is if stdout is not a terminal. Keys are read from the
+//     terminal even if the text comes from stdin, e.g. dmesg | more.
+//
+// Options:
+//     -i: ignore case in searches
+//
+// Keys:
+//     space, f, ^F, PgDn: next screen
+//     b, ^B, PgUp: last screen
+//     enter, j, down: next line
+//     k, up: last line
+//     d, u: down or up half a screen
+//     g, <, Home: start
+//     G, >, End: end
+//     /RE: search forward for the regular expression RE
+//     ?RE: search backward
+//     n, N: search forward or backward again
+//     q: quit
+//
+// Example:
+//     dmesg | more
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+var ignoreCase = flag.Bool("i", false, "ignore case in searches")
+
+// readLines reads the lines of the files, or stdin if there are none. The
+// files are headed by their names if there are more than one.
+func readLines(files []string) ([]string, error) {
+	if len(files) == 0 {
+		return read(os.Stdin, nil)
+	}
+	var lines []string
+	for _, n := range files {
+		f, err := os.Open(n)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 1 {
+			lines = append(lines, "::::::::::::::", n, "::::::::::::::")
+		}
+		lines, err = read(f, lines)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+func read(r io.Reader, lines []string) ([]string, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return lines, s.Err()
+}
+
+// cat copies the files, or stdin, to stdout.
+func cat(files []string) error {
+	if len(files) == 0 {
+		_, err := io.Copy(os.Stdout, os.Stdin)
+		return err
+	}
+	for _, n := range files {
+		f, err := os.Open(n)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(os.Stdout, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode reads a key from r, with the escape sequences of the arrows and
+// such turned into their names.
+func decode(r io.ByteReader) (string, error) {
+	k, err := r.ReadByte()
+	if err != nil || k != '\033' {
+		return string(k), err
+	}
+	if k, err = r.ReadByte(); err != nil || k != '[' {
+		return "\033", err
+	}
+	seq := ""
+	for {
+		if k, err = r.ReadByte(); err != nil {
+			return "", err
+		}
+		seq += string(k)
+		if k >= 'A' && k <= 'Z' || k == '~' {
+			break
+		}
+	}
+	switch seq {
+	case "A":
+		return "up", nil
+	case "B":
+		return "down", nil
+	case "5~":
+		return "pgup", nil
+	case "6~":
+		return "pgdn", nil
+	case "H", "1~":
+		return "home", nil
+	case "F", "4~":
+		return "end", nil
+	}
+	return "", nil
+}
+
+// keys sends the keys typed on f to c, and closes it when there are no
+// more.
+func keys(f *os.File, c chan<- string) {
+	r := bufio.NewReader(f)
+	for {
+		k, err := decode(r)
+		if err != nil {
+			close(c)
+			return
+		}
+		c <- k
+	}
+}
+
+// A terminal is where the keys come from and the screen goes.
+type terminal struct {
+	keys  chan string
+	winch chan os.Signal
+	out   io.Writer
+}
+
+// readLine reads a line after prompt on the last line of the screen, with
+// backspace, and returns it. It returns false if it was given up with ^C
+// or escape.
+func (t *terminal) readLine(prompt string) (string, bool) {
+	var l []string
+	fmt.Fprintf(t.out, "\r\033[K%s", prompt)
+	for k := range t.keys {
+		switch k {
+		case "\r", "\n":
+			return strings.Join(l, ""), true
+		case "\x03", "\033":
+			return "", false
+		case "\x7f", "\b":
+			if len(l) > 0 {
+				l = l[:len(l)-1]
+				fmt.Fprint(t.out, "\b \b")
+			}
+		default:
+			if len(k) == 1 {
+				l = append(l, k)
+				fmt.Fprint(t.out, k)
+			}
+		}
+	}
+	return "", false
+}
+
+// size returns the size of the screen.
+func size() (int, int) {
+	if ws, err := termios.GetWinSize(os.Stdout.Fd()); err == nil && ws.Row > 0 && ws.Col > 0 {
+		return int(ws.Row), int(ws.Col)
+	}
+	return 24, 80
+}
+
+// run pages through the text until q is typed.
+func run(p *pager, t *terminal) {
+	for {
+		p.draw(t.out)
+		var k string
+		var ok bool
+		select {
+		case <-t.winch:
+			p.resize(size())
+			continue
+		case k, ok = <-t.keys:
+			if !ok {
+				return
+			}
+		}
+		switch k {
+		case "q", "Q":
+			return
+		case " ", "f", "\x06", "pgdn":
+			p.scroll(p.page())
+		case "b", "\x02", "pgup":
+			p.scroll(-p.page())
+		case "\r", "\n", "j", "down":
+			p.scroll(1)
+		case "k", "up":
+			p.scroll(-1)
+		case "d", "\x04":
+			p.scroll(p.page() / 2)
+		case "u", "\x15":
+			p.scroll(-p.page() / 2)
+		case "g", "<", "home":
+			p.top = 0
+		case "G", ">", "end":
+			p.scroll(len(p.rows))
+		case "/", "?":
+			s, ok := t.readLine(k)
+			if !ok {
+				break
+			}
+			// An empty search is the last one again.
+			re := p.re
+			if s != "" || re == nil {
+				if *ignoreCase {
+					s = "(?i)" + s
+				}
+				var err error
+				if re, err = regexp.Compile(s); err != nil {
+					p.msg = err.Error()
+					break
+				}
+			}
+			if !p.search(re, k == "?") {
+				p.msg = "Pattern not found"
+			}
+		case "n", "N":
+			if p.re == nil {
+				p.msg = "No previous search"
+				break
+			}
+			if !p.search(p.re, k == "N") {
+				p.msg = "Pattern not found"
+			}
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	files := flag.Args()
+	if _, err := termios.GetTermios(os.Stdout.Fd()); err != nil {
+		if err := cat(files); err != nil {
+			log.Fatalf("more: %v", err)
+		}
+		return
+	}
+
+	lines, err := readLines(files)
+	if err != nil {
+		log.Fatalf("more: %v", err)
+	}
+	height, width := size()
+	p := newPager(lines, height, width)
+	if p.atEnd() {
+		fmt.Println(strings.Join(lines, "\n"))
+		return
+	}
+
+	// The text may be on stdin, so keys come from the terminal.
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		log.Fatalf("more: %v", err)
+	}
+	t, err := termios.GetTermios(tty.Fd())
+	if err != nil {
+		log.Fatalf("more: %v", err)
+	}
+	raw := *t
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Oflag &^= unix.OPOST
+	raw.Cc[unix.VMIN], raw.Cc[unix.VTIME] = 1, 0
+	if err := termios.SetTermios(tty.Fd(), &raw); err != nil {
+		log.Fatalf("more: %v", err)
+	}
+
+	term := &terminal{keys: make(chan string), winch: make(chan os.Signal, 1), out: os.Stdout}
+	signal.Notify(term.winch, syscall.SIGWINCH)
+	go keys(tty, term.keys)
+	run(p, term)
+	// Clear the prompt.
+	fmt.Print("\r\033[K")
+	termios.SetTermios(tty.Fd(), t)
+}
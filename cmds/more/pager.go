@@ -0,0 +1,189 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// A pager shows text a screen at a time.
+type pager struct {
+	// text is the lines of the text; rows are those lines cut to the
+	// width of the screen, and line is the line of text of each row.
+	text []string
+	rows []string
+	line []int
+	// top is the first row on the screen.
+	top int
+	// height and width are the size of the screen; the last line of it
+	// is the prompt.
+	height, width int
+	// re is the last search, and match the row it last matched.
+	re    *regexp.Regexp
+	match int
+	// msg is shown in the prompt once, instead of where we are.
+	msg string
+}
+
+func newPager(text []string, height, width int) *pager {
+	p := &pager{text: text}
+	p.resize(height, width)
+	return p
+}
+
+// expand expands the tabs of s, and drops carriage returns.
+func expand(s string) string {
+	if !strings.ContainsAny(s, "\t\r") {
+		return s
+	}
+	var b []rune
+	for _, r := range s {
+		switch r {
+		case '\t':
+			b = append(b, ' ')
+			for len(b)%8 != 0 {
+				b = append(b, ' ')
+			}
+		case '\r':
+		default:
+			b = append(b, r)
+		}
+	}
+	return string(b)
+}
+
+// resize cuts the text to the width of the screen again, keeping the
+// line at the top.
+func (p *pager) resize(height, width int) {
+	if height < 2 {
+		height = 2
+	}
+	if width < 1 {
+		width = 1
+	}
+	top := 0
+	if p.top < len(p.line) {
+		top = p.line[p.top]
+	}
+	p.height, p.width = height, width
+	p.rows, p.line = p.rows[:0], p.line[:0]
+	p.top = 0
+	for i, l := range p.text {
+		if i == top {
+			p.top = len(p.rows)
+		}
+		l = expand(l)
+		for {
+			p.rows = append(p.rows, l)
+			p.line = append(p.line, i)
+			if utf8.RuneCountInString(l) <= width {
+				break
+			}
+			// Cut after width runes.
+			n := 0
+			for j := range l {
+				if n == width {
+					p.rows[len(p.rows)-1] = l[:j]
+					l = l[j:]
+					break
+				}
+				n++
+			}
+		}
+	}
+	p.scroll(0)
+}
+
+// page is how many rows there are on the screen.
+func (p *pager) page() int {
+	return p.height - 1
+}
+
+// scroll moves down n rows, or up if n is negative, without going past
+// the end.
+func (p *pager) scroll(n int) {
+	p.top += n
+	if max := len(p.rows) - p.page(); p.top > max {
+		p.top = max
+	}
+	if p.top < 0 {
+		p.top = 0
+	}
+}
+
+// atEnd reports whether the last row is on the screen.
+func (p *pager) atEnd() bool {
+	return p.top+p.page() >= len(p.rows)
+}
+
+// percent is how much of the text has been shown.
+func (p *pager) percent() int {
+	if len(p.rows) == 0 || p.atEnd() {
+		return 100
+	}
+	return 100 * (p.top + p.page()) / len(p.rows)
+}
+
+// search moves to the next row, or with backward the last one, which re
+// matches. The search starts from the last match if it is on the screen,
+// or else the top. It returns false if there is none.
+func (p *pager) search(re *regexp.Regexp, backward bool) bool {
+	p.re = re
+	from, step := p.top, 1
+	if p.match >= p.top && p.match < p.top+p.page() {
+		from = p.match
+	}
+	if backward {
+		step = -1
+	}
+	for i := from + step; i >= 0 && i < len(p.rows); i += step {
+		if re.MatchString(p.rows[i]) {
+			p.match, p.top = i, i
+			// The end of the text stays at the bottom.
+			p.scroll(0)
+			return true
+		}
+	}
+	return false
+}
+
+// prompt is the last line of the screen.
+func (p *pager) prompt() string {
+	if p.msg != "" {
+		return p.msg
+	}
+	if p.atEnd() {
+		return "(END)"
+	}
+	return fmt.Sprintf("--More--(%d%%)", p.percent())
+}
+
+// draw draws the screen. Matches of the last search are in reverse video.
+func (p *pager) draw(w io.Writer) {
+	b := bufio.NewWriter(w)
+	// Home, then clear to the end of the screen.
+	b.WriteString("\033[H\033[J")
+	for i := p.top; i < p.top+p.page(); i++ {
+		if i < len(p.rows) {
+			l := p.rows[i]
+			if p.re != nil && p.re.String() != "" {
+				l = p.re.ReplaceAllStringFunc(l, func(m string) string {
+					return "\033[7m" + m + "\033[m"
+				})
+			}
+			b.WriteString(l)
+		}
+		// The terminal does not turn \n into \r\n in raw mode.
+		b.WriteString("\r\n")
+	}
+	b.WriteString("\033[7m" + p.prompt() + "\033[m")
+	p.msg = ""
+	b.Flush()
+}
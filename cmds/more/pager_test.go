@@ -0,0 +1,121 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"abc", "abc"},
+		{"\tx", "        x"},
+		{"ab\tx", "ab      x"},
+		{"line\r", "line"},
+	} {
+		if got := expand(tt.in); got != tt.want {
+			t.Errorf("expand(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	p := newPager([]string{"abcdefgh", "", "ab", "αβγδε"}, 10, 3)
+	want := []string{"abc", "def", "gh", "", "ab", "αβγ", "δε"}
+	if !reflect.DeepEqual(p.rows, want) {
+		t.Errorf("rows: got %q, want %q", p.rows, want)
+	}
+	wantLine := []int{0, 0, 0, 1, 2, 3, 3}
+	if !reflect.DeepEqual(p.line, wantLine) {
+		t.Errorf("line: got %v, want %v", p.line, wantLine)
+	}
+
+	// The line at the top stays there.
+	p = newPager([]string{"abcdefgh", "", "ab", "αβγδε", "", "", "", ""}, 3, 3)
+	p.scroll(4)
+	p.resize(3, 80)
+	if p.top != 2 {
+		t.Errorf("top after resize: got %d, want 2", p.top)
+	}
+}
+
+func lines(n int) []string {
+	var l []string
+	for i := 0; i < n; i++ {
+		l = append(l, strings.Repeat("x", i))
+	}
+	return l
+}
+
+func TestScroll(t *testing.T) {
+	// 9 rows fit on a screen of 10.
+	p := newPager(lines(20), 10, 80)
+	for _, tt := range []struct {
+		n, top, percent int
+		end             bool
+	}{
+		{0, 0, 45, false},
+		{-1, 0, 45, false},
+		{9, 9, 90, false},
+		{9, 11, 100, true},
+		{-5, 6, 75, false},
+	} {
+		p.scroll(tt.n)
+		if p.top != tt.top || p.percent() != tt.percent || p.atEnd() != tt.end {
+			t.Errorf("scroll(%d): got top %d, %d%%, end %v, want %d, %d%%, %v",
+				tt.n, p.top, p.percent(), p.atEnd(), tt.top, tt.percent, tt.end)
+		}
+	}
+	if p := newPager(nil, 10, 80); p.percent() != 100 || !p.atEnd() {
+		t.Errorf("empty text: got %d%%, end %v, want 100%%, true", p.percent(), p.atEnd())
+	}
+}
+
+func TestSearch(t *testing.T) {
+	text := []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+	p := newPager(text, 4, 80)
+	for _, tt := range []struct {
+		re       string
+		backward bool
+		found    bool
+		top      int
+	}{
+		{"^t", false, true, 1},
+		{"^t", false, true, 2},
+		// ten is on the last screen, which starts at eight.
+		{"^t", false, true, 7},
+		{"^t", false, false, 7},
+		{"^t", true, true, 2},
+		{"^f", true, false, 2},
+		{"one", true, true, 0},
+	} {
+		re := regexp.MustCompile(tt.re)
+		if found := p.search(re, tt.backward); found != tt.found || p.top != tt.top {
+			t.Errorf("search(%q, %v): got %v at %d, want %v at %d", tt.re, tt.backward, found, p.top, tt.found, tt.top)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("q\033[A\033[6~\033[H/\r"))
+	var got []string
+	for {
+		k, err := decode(r)
+		if err != nil {
+			break
+		}
+		got = append(got, k)
+	}
+	want := []string{"q", "up", "pgdn", "home", "/", "\r"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decode: got %q, want %q", got, want)
+	}
+}
@@ -6,39 +6,112 @@
 //
 // Synopsis:
 //     mount [-r] [-o options] [-t FSTYPE] DEV PATH
+//     mount -bind DIR PATH
+//
+// Description:
+//     DEV may be UUID=UUID or LABEL=LABEL, as in /etc/fstab, for the block
+//     device with the file system with that UUID or label.
+//
+//     Without -t, or with -t auto, the type of the file system on DEV is
+//     found from its superblock; ext2, ext3, ext4, vfat, squashfs, iso9660,
+//     xfs and btrfs are known. If that fails, each type in
+//     /proc/filesystems is tried.
+//
+//     If DEV is a regular file, or the loop option is given, DEV is
+//     attached to a free loop device, which is detached again when the
+//     file system is unmounted.
 //
 // Options:
-//     -r: read only
+//     -r:    read only
+//     -o:    comma separated options, e.g. ro,noexec,mode=0755
+//     -t:    file system type
+//     -bind: bind mount DIR at PATH
+//
+// Example:
+//     mount /dev/sda1 /mnt
+//     mount LABEL=root /mnt
+//     mount -o ro,loop rootfs.squashfs /mnt
+//     mount -t tmpfs -o size=64m,mode=1777 tmpfs /tmp
 package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
+	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/mount/loop"
 	"golang.org/x/sys/unix"
 )
 
 var (
-	ro     = flag.Bool("r", false, "Read only mount")
-	fsType = flag.String("t", "", "File system type")
-	data   = flag.String("o", "", "Specify mount options")
+	ro      = flag.Bool("r", false, "Read only mount")
+	bind    = flag.Bool("bind", false, "Bind mount")
+	fsType  = flag.String("t", "", "File system type")
+	options = flag.String("o", "", "Specify mount options")
 )
 
+// hasOption says if the comma separated options o have opt.
+func hasOption(o, opt string) bool {
+	for _, s := range strings.Split(o, ",") {
+		if s == opt {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
-	// The need for this conversion is not clear to me, but we get an overflow error
-	// on ARM without it.
-	flags := uintptr(unix.MS_MGC_VAL)
 	flag.Parse()
 	a := flag.Args()
 	if len(a) < 2 {
-		log.Fatalf("Usage: mount [-r] [-t fstype] dev path")
+		log.Fatalf("Usage: mount [-r] [-bind] [-o options] [-t fstype] dev path")
+	}
+	dev, err := mount.FindDevice(a[0])
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
-	dev := a[0]
 	path := a[1]
+	flags, data := mount.ParseOptions(*options, 0)
 	if *ro {
 		flags |= unix.MS_RDONLY
 	}
-	if err := unix.Mount(a[0], a[1], *fsType, flags, *data); err != nil {
-		log.Fatalf("Mount :%s: on :%s: type :%s: flags %x: %v\n", dev, path, *fsType, flags, err)
+	if *bind {
+		flags |= unix.MS_BIND
+	}
+
+	types := []string{*fsType}
+	// Bind mounts, remounts and changes of propagation have no device.
+	if flags&(unix.MS_BIND|unix.MS_REMOUNT|unix.MS_SHARED|unix.MS_PRIVATE|unix.MS_SLAVE) == 0 {
+		if fi, err := os.Stat(dev); err == nil && fi.Mode().IsRegular() || hasOption(*options, "loop") {
+			l, err := loop.New(dev, flags&unix.MS_RDONLY != 0)
+			if err != nil {
+				log.Fatalf("Setting up a loop device for %s: %v", dev, err)
+			}
+			// The loop device stays once the file system is mounted.
+			defer l.Close()
+			dev = l.Name()
+		}
+		if fi, err := os.Stat(dev); err == nil && fi.Mode()&os.ModeDevice != 0 {
+			var err error
+			if types, err = mount.FSTypes(dev, *fsType); err != nil {
+				log.Fatalf("Finding the file system type of %s: %v", dev, err)
+			}
+		}
+	}
+
+	// The need for this conversion is not clear to me, but we get an overflow error
+	// on ARM without it.
+	flags |= uintptr(unix.MS_MGC_VAL)
+	var errs []string
+	for _, t := range types {
+		if err := unix.Mount(dev, path, t, flags, data); err != nil {
+			errs = append(errs, fmt.Sprintf("type %s: %v", t, err))
+			continue
+		}
+		return
 	}
+	log.Fatalf("Mount :%s: on :%s: flags %x: %v\n", dev, path, flags, strings.Join(errs, "; "))
 }
@@ -0,0 +1,69 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Mountall mounts the file systems in /etc/fstab.
+//
+// Synopsis:
+//     mountall [-n] [-f FSTAB]
+//
+// Description:
+//     mountall mounts the file systems in FSTAB, parents before the mount
+//     points in them, making the mount points if need be. Devices may be
+//     UUID=UUID or LABEL=LABEL. Before a device with a non-zero pass number
+//     is mounted, it is checked with fsck.TYPE -a, such as fsck.ext or
+//     fsck.vfat, or else fsck -a, if there is one. Entries which are
+//     noauto, swap, or already mounted are skipped; one which fails to
+//     mount is an error unless it is nofail.
+//
+// Options:
+//     -n: do not check file systems
+//     -f: the fstab file
+//
+// Example:
+//     $ cat /etc/fstab
+//     LABEL=data /data ext4 defaults 0 2
+//     tmpfs /run tmpfs mode=0755 0 0
+//     $ mountall
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+var (
+	noFsck = flag.Bool("n", false, "do not check file systems")
+	fstab  = flag.String("f", "/etc/fstab", "the fstab `file`")
+)
+
+func mountAll(fstab string, fsck mount.Fsck) error {
+	f, err := os.Open(fstab)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := mount.ParseFstab(f)
+	if err != nil {
+		return err
+	}
+	return mount.MountAll(entries, fsck)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	fsck := mount.ExecFsck
+	if *noFsck {
+		fsck = nil
+	}
+	if err := mountAll(*fstab, fsck); err != nil {
+		log.Fatalf("mountall: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestMountAll(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "mountall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := mountAll(filepath.Join(tmp, "missing"), nil); !os.IsNotExist(err) {
+		t.Errorf("mountall -f missing: got %v, want it not to exist", err)
+	}
+	bad := filepath.Join(tmp, "bad")
+	if err := ioutil.WriteFile(bad, []byte("tmpfs /x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mountAll(bad, nil); err == nil {
+		t.Errorf("mountall -f %v: got nil, want an error", bad)
+	}
+
+	if os.Getuid() != 0 {
+		t.Skip("mount needs root")
+	}
+	mnt := filepath.Join(tmp, "mnt")
+	fstab := filepath.Join(tmp, "fstab")
+	if err := ioutil.WriteFile(fstab, []byte("tmpfs "+mnt+" tmpfs size=1m 0 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mountAll(fstab, nil); err != nil {
+		t.Fatalf("mountall -f %v: %v", fstab, err)
+	}
+	defer unix.Unmount(mnt, unix.MNT_DETACH)
+	var fs unix.Statfs_t
+	if err := unix.Statfs(mnt, &fs); err != nil || uint32(fs.Type) != 0x01021994 {
+		t.Errorf("%v: got file system type %#x, %v, want tmpfs", mnt, fs.Type, err)
+	}
+}
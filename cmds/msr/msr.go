@@ -2,87 +2,194 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// msr lets you read and write an msr for one or more cores.
-// The cores are specified via a filepath.Glob string.
-// The string should be for core number only, with no
-// surrounding paths, e.g. you use 0 for core 0, not
-// /dev/cpu/0/msr.
-// To specify all cores, use '*'
-// To specify all cores with two digits, use '??'
-// To specify all odd cores, use '*[13579]'
-// To specify, e.g., all the even cores, use '*[02468]'.
-// Usage:
-// msr r glob 32-bit-msr-number
-// msr w glob 32-bit-msr-number 64-bit-value
-// For each MSR operation msr will print an error if any.
-// If your kernel does not have MSRs for any reason,
-// this will fail due to file access. But it's quite possible
-// that non-x86 architectures might someday implement MSRs,
-// which on (e.g.) PPC might have a slightly different name
-// (DICR) but would implement the same kinds of functions.
+// msr reads and writes model-specific registers, MSRs, of CPUs.
+//
+// Synopsis:
+//     msr r GLOB REGISTER[:HI[:LO]]
+//     msr w GLOB REGISTER[:HI[:LO]] VALUE
+//     msr l
+//
+// Description:
+//     msr reads or writes an MSR of the CPUs GLOB matches, through
+//     /dev/cpu/N/msr, and prints an error for each CPU that fails.
+//     GLOB is a filepath.Glob of CPU numbers: 0 for CPU 0, * for all of
+//     them, ?? for those with two digits, *[13579] for the odd ones.
+//
+//     REGISTER is a number, or the name of a common MSR, such as
+//     IA32_PERF_STATUS, in any case; msr l lists them. With :HI:LO, only
+//     bits HI to LO of it are read, or written, the other bits being
+//     kept; with :HI, only bit HI.
+//
+//     The write is not checked by reading the MSR back, as many MSRs are
+//     write-only, or have bits which do not read back as written.
+//
+//     If there is no /dev/cpu/N/msr, the kernel needs the msr module.
+//
+// Example:
+//     $ msr r 0 IA32_PERF_STATUS:15:8
+//     /dev/cpu/0/msr: 0x1c
+//     $ msr w '*' IA32_ENERGY_PERF_BIAS:3:0 6
 package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 )
 
-const usage = `msr r glob register
-msr w glob register value`
+const usage = `msr r glob register[:hi[:lo]]
+msr w glob register[:hi[:lo]] value
+msr l`
 
-func main() {
-	a := os.Args[1:]
-	if len(a) < 3 {
-		log.Fatal(usage)
+// A field is bits hi to lo of a register.
+type field struct {
+	hi, lo uint
+}
+
+// all is all the bits of a register.
+var all = field{hi: 63, lo: 0}
+
+// mask returns the mask of the bits of f, shifted down to bit 0.
+func (f field) mask() uint64 {
+	return ^uint64(0) >> (63 - f.hi + f.lo)
+}
+
+// get returns f of v.
+func (f field) get(v uint64) uint64 {
+	return v >> f.lo & f.mask()
+}
+
+// set returns old with f set to v.
+func (f field) set(old, v uint64) uint64 {
+	return old&^(f.mask()<<f.lo) | (v&f.mask())<<f.lo
+}
+
+// parseRegister parses REGISTER[:HI[:LO]].
+func parseRegister(s string) (uint32, field, error) {
+	f := all
+	p := strings.Split(s, ":")
+	if len(p) > 3 {
+		return 0, f, fmt.Errorf("%q is not REGISTER[:HI[:LO]]", s)
 	}
+	reg, ok := registers[strings.ToUpper(p[0])]
+	if !ok {
+		r, err := strconv.ParseUint(p[0], 0, 32)
+		if err != nil {
+			return 0, f, fmt.Errorf("%q is not a number, nor an MSR msr knows", p[0])
+		}
+		reg = uint32(r)
+	}
+	if len(p) == 1 {
+		return reg, f, nil
+	}
+	hi, err := strconv.ParseUint(p[1], 0, 8)
+	if err != nil || hi > 63 {
+		return 0, f, fmt.Errorf("bit %q is not 0 to 63", p[1])
+	}
+	lo := hi
+	if len(p) == 3 {
+		if lo, err = strconv.ParseUint(p[2], 0, 8); err != nil || lo > hi {
+			return 0, f, fmt.Errorf("bit %q is not 0 to %d", p[2], hi)
+		}
+	}
+	return reg, field{hi: uint(hi), lo: uint(lo)}, nil
+}
 
-	m := msrList(a[1])
+// list lists the MSRs msr knows by name.
+func list(w io.Writer) {
+	var names []string
+	for n := range registers {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := registers[names[i]], registers[names[j]]
+		return ri < rj || ri == rj && names[i] < names[j]
+	})
+	for _, n := range names {
+		fmt.Fprintf(w, "0x%08x %s\n", registers[n], n)
+	}
+}
 
-	reg, err := strconv.ParseUint(a[2], 0, 32)
+// run does what args say, printing to w. The errors of each CPU are
+// printed, and make it return an error at the end.
+func run(w io.Writer, args []string) error {
+	if len(args) == 1 && args[0] == "l" {
+		list(w)
+		return nil
+	}
+	if len(args) < 3 || args[0] != "r" && args[0] != "w" || args[0] == "w" && len(args) != 4 {
+		return fmt.Errorf("usage:\n%s", usage)
+	}
+	m, err := msrList(args[1])
+	if err != nil {
+		return err
+	}
+	reg, f, err := parseRegister(args[2])
 	if err != nil {
-		log.Fatalf("%v: %v", a[2], err)
+		return err
 	}
-	switch a[0] {
-	case "r":
-		data, errs := rdmsr(m, uint32(reg))
+	failed := false
+	report := func(cpus []string, errs []error) {
+		for i, e := range errs {
+			if e != nil {
+				fmt.Fprintf(w, "%v: %v\n", cpus[i], e)
+				failed = true
+			}
+		}
+	}
+
+	if args[0] == "r" {
+		data, errs := rdmsr(m, reg)
 		for i, v := range m {
 			if errs[i] != nil {
-				fmt.Printf("%v: %v\n", v, errs[i])
+				continue
+			}
+			if f == all {
+				fmt.Fprintf(w, "%v: %#016x\n", v, data[i])
 			} else {
-				fmt.Printf("%v: %#016x\n", v, data[i])
+				fmt.Fprintf(w, "%v: %#x\n", v, f.get(data[i]))
 			}
 		}
-
-	case "w":
-		// Sadly, we don't get an error on write if the values
-		// don't match.  Reading it back to check it is
-		// not always going to work. There are many write-only
-		// MSRs. If there are no errors there is still no
-		// guarantee that it worked, or if we read it we would
-		// see what we wrote, or that vmware did not do
-		// something stupid, or the x86 did not do something really
-		// stupid, or the particular implementation of the x86
-		// that we are on did not do something really stupid.
-		// Why is it this way? Because vendors hide proprietary
-		// information in hidden MSRs, or in hidden fields in MSRs.
-		// Checking is just not an option. There, feel better now?
-		if len(a) < 4 {
-			log.Fatal(usage)
-		}
-		v, err := strconv.ParseUint(a[3], 0, 64)
+		report(m, errs)
+	} else {
+		v, err := strconv.ParseUint(args[3], 0, 64)
 		if err != nil {
-			log.Fatalf("%v: %v", a, err)
+			return fmt.Errorf("%v: %v", args[3], err)
 		}
-		errs := wrmsr(m, uint32(reg), v)
-		for i, e := range errs {
-			if e != nil {
-				fmt.Printf("%v: %v\n", m[i], e)
+		if v&^f.mask() != 0 {
+			return fmt.Errorf("%#x does not fit in bits %d to %d", v, f.hi, f.lo)
+		}
+		cpus, data := m, make([]uint64, len(m))
+		if f == all {
+			for i := range data {
+				data[i] = v
+			}
+		} else {
+			// The other bits are kept, so the MSR is read first,
+			// and only written on the CPUs it could be read on.
+			old, errs := rdmsr(m, reg)
+			report(m, errs)
+			cpus, data = nil, nil
+			for i := range m {
+				if errs[i] == nil {
+					cpus, data = append(cpus, m[i]), append(data, f.set(old[i], v))
+				}
 			}
 		}
+		report(cpus, wrmsr(cpus, reg, data))
+	}
+	if failed {
+		return fmt.Errorf("not all MSRs could be accessed")
+	}
+	return nil
+}
 
-	default:
-		log.Fatalf(usage)
+func main() {
+	if err := run(os.Stdout, os.Args[1:]); err != nil {
+		log.Fatal(err)
 	}
 }
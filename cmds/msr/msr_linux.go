@@ -8,66 +8,65 @@ package main
 import (
 	"encoding/binary"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 )
 
-func msrList(n string) []string {
-	m, err := filepath.Glob(filepath.Join("/dev/cpu", n, "msr"))
+// cpuDir is where the MSR devices of the CPUs are. Tests change it.
+var cpuDir = "/dev/cpu"
+
+func msrList(n string) ([]string, error) {
+	m, err := filepath.Glob(filepath.Join(cpuDir, n, "msr"))
 	// This err will be if the glob was bad.
 	if err != nil {
-		log.Fatalf("No MSRs matched %v: %v", n, err)
+		return nil, fmt.Errorf("No MSRs matched %v: %v", n, err)
 	}
 	// len will be zero for any of a number of reasons.
 	if len(m) == 0 {
-		log.Fatalf("No msrs found. Make sure your kernel is compiled with msrs, and you may need to 'sudo modprobe msr'. To see available msrs, ls /dev/cpu.")
-	}
-	return m
-}
-
-func openAll(m []string, o int) ([]*os.File, []error) {
-	var (
-		f    = make([]*os.File, len(m))
-		errs = make([]error, len(m))
-	)
-	for i := range m {
-		f[i], errs[i] = os.OpenFile(m[i], o, 0)
+		return nil, fmt.Errorf("No msrs found. Make sure your kernel is compiled with msrs, and you may need to 'sudo modprobe msr'. To see available msrs, ls /dev/cpu.")
 	}
-	return f, errs
+	return m, nil
 }
 
-func doio(msr *os.File, addr uint32, f func(*os.File) error) error {
-	if _, err := msr.Seek(int64(addr), 0); err != nil {
-		return fmt.Errorf("Bad address %v: %v", addr, err)
+// doio opens the MSR device of a CPU, and calls f with it.
+func doio(msr string, o int, f func(*os.File) error) error {
+	d, err := os.OpenFile(msr, o, 0)
+	if err != nil {
+		return err
 	}
-	return f(msr)
+	defer d.Close()
+	return f(d)
 }
 
 func rdmsr(m []string, addr uint32) ([]uint64, []error) {
-	var regs = make([]uint64, len(m))
-
-	f, errs := openAll(m, os.O_RDONLY)
+	var (
+		regs = make([]uint64, len(m))
+		errs = make([]error, len(m))
+	)
 	for i := range m {
-		if errs[i] != nil {
-			continue
-		}
-		errs[i] = doio(f[i], addr, func(port *os.File) error {
-			return binary.Read(port, binary.LittleEndian, &regs[i])
+		errs[i] = doio(m[i], os.O_RDONLY, func(msr *os.File) error {
+			var b [8]byte
+			if _, err := msr.ReadAt(b[:], int64(addr)); err != nil {
+				return fmt.Errorf("reading MSR %#x: %v", addr, err)
+			}
+			regs[i] = binary.LittleEndian.Uint64(b[:])
+			return nil
 		})
 	}
 	return regs, errs
 }
 
-func wrmsr(m []string, addr uint32, data uint64) []error {
-	f, errs := openAll(m, os.O_RDWR)
-
+// wrmsr writes data[i] to the MSR of m[i].
+func wrmsr(m []string, addr uint32, data []uint64) []error {
+	errs := make([]error, len(m))
 	for i := range m {
-		if errs[i] != nil {
-			continue
-		}
-		errs[i] = doio(f[i], addr, func(port *os.File) error {
-			return binary.Write(port, binary.LittleEndian, data)
+		errs[i] = doio(m[i], os.O_WRONLY, func(msr *os.File) error {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], data[i])
+			if _, err := msr.WriteAt(b[:], int64(addr)); err != nil {
+				return fmt.Errorf("writing MSR %#x: %v", addr, err)
+			}
+			return nil
 		})
 	}
 	return errs
@@ -0,0 +1,103 @@
+// Copyright 2012-2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRegister(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		reg uint32
+		f   field
+	}{
+		{"0x198", 0x198, all},
+		{"ia32_perf_status", 0x198, all},
+		{"IA32_PERF_STATUS:15:8", 0x198, field{15, 8}},
+		{"IA32_EFER:11", 0xc0000080, field{11, 11}},
+		{"16:63:0", 16, all},
+	} {
+		reg, f, err := parseRegister(tt.s)
+		if err != nil || reg != tt.reg || f != tt.f {
+			t.Errorf("parseRegister(%q): got %#x, %v, %v, want %#x, %v, nil", tt.s, reg, f, err, tt.reg, tt.f)
+		}
+	}
+	for _, s := range []string{"NO_SUCH_MSR", "0x100000000", "16:64", "16:3:4", "16:1:0:0"} {
+		if _, _, err := parseRegister(s); err == nil {
+			t.Errorf("parseRegister(%q): got nil, want error", s)
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	f := field{15, 8}
+	if got := f.get(0x1234abcd); got != 0xab {
+		t.Errorf("get: got %#x, want 0xab", got)
+	}
+	if got := f.set(0x1234abcd, 0x56); got != 0x123456cd {
+		t.Errorf("set: got %#x, want 0x123456cd", got)
+	}
+	if got := all.set(1, 2); got != 2 {
+		t.Errorf("set of all: got %#x, want 2", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "msr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	cpuDir = tmp
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], 0x1234abcd)
+	for _, c := range []string{"0", "1"} {
+		if err := os.MkdirAll(filepath.Join(tmp, c), 0755); err != nil {
+			t.Fatal(err)
+		}
+		// The MSR files are sparse, with 0x198 set.
+		msr := make([]byte, 0x200)
+		copy(msr[0x198:], b[:])
+		if err := ioutil.WriteFile(filepath.Join(tmp, c, "msr"), msr, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cpu0, cpu1 := filepath.Join(tmp, "0", "msr"), filepath.Join(tmp, "1", "msr")
+
+	for _, tt := range []struct {
+		args, want string
+	}{
+		{"r * IA32_PERF_STATUS", cpu0 + ": 0x000000001234abcd\n" + cpu1 + ": 0x000000001234abcd\n"},
+		{"r 1 IA32_PERF_STATUS:15:8", cpu1 + ": 0xab\n"},
+		{"w 0 0x198:15:8 0x56", ""},
+		{"r * 0x198", cpu0 + ": 0x00000000123456cd\n" + cpu1 + ": 0x000000001234abcd\n"},
+		{"w 1 0x198 7", ""},
+		{"r 1 0x198", cpu1 + ": 0x0000000000000007\n"},
+	} {
+		var out bytes.Buffer
+		if err := run(&out, strings.Fields(tt.args)); err != nil || out.String() != tt.want {
+			t.Errorf("msr %v: got %q, %v, want %q, nil", tt.args, out.String(), err, tt.want)
+		}
+	}
+
+	for _, args := range []string{"", "r", "r 0", "x 0 0x198", "w 0 0x198", "r 2 0x198", "w 0 0x198:3:0 16", "r 0 0x1000"} {
+		if err := run(ioutil.Discard, strings.Fields(args)); err == nil {
+			t.Errorf("msr %v: got nil, want error", args)
+		}
+	}
+
+	var out bytes.Buffer
+	list(&out)
+	if l := strings.Split(out.String(), "\n"); l[0] != "0x00000010 IA32_TSC" || len(l) != len(registers)+1 {
+		t.Errorf("list: got %q", out.String())
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2012-2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// registers are the MSRs msr knows by name. The IA32_ ones are
+// architectural, and so on all Intel CPUs which have them, and many AMD
+// ones; the MSR_ ones are on most Intel CPUs since Nehalem, but not all.
+var registers = map[string]uint32{
+	"IA32_TSC":                  0x10,
+	"IA32_PLATFORM_ID":          0x17,
+	"IA32_APIC_BASE":            0x1b,
+	"IA32_FEATURE_CONTROL":      0x3a,
+	"IA32_TSC_ADJUST":           0x3b,
+	"IA32_SPEC_CTRL":            0x48,
+	"IA32_BIOS_SIGN_ID":         0x8b,
+	"MSR_SMI_COUNT":             0x34,
+	"MSR_PLATFORM_INFO":         0xce,
+	"IA32_MPERF":                0xe7,
+	"IA32_APERF":                0xe8,
+	"IA32_MTRRCAP":              0xfe,
+	"IA32_ARCH_CAPABILITIES":    0x10a,
+	"IA32_PERF_STATUS":          0x198,
+	"IA32_PERF_CTL":             0x199,
+	"IA32_CLOCK_MODULATION":     0x19a,
+	"IA32_THERM_INTERRUPT":      0x19b,
+	"IA32_THERM_STATUS":         0x19c,
+	"IA32_MISC_ENABLE":          0x1a0,
+	"MSR_TEMPERATURE_TARGET":    0x1a2,
+	"MSR_TURBO_RATIO_LIMIT":     0x1ad,
+	"IA32_ENERGY_PERF_BIAS":     0x1b0,
+	"IA32_PACKAGE_THERM_STATUS": 0x1b1,
+	"IA32_PAT":                  0x277,
+	"IA32_MTRR_DEF_TYPE":        0x2ff,
+	"IA32_FIXED_CTR0":           0x309,
+	"IA32_FIXED_CTR1":           0x30a,
+	"IA32_FIXED_CTR2":           0x30b,
+	"IA32_FIXED_CTR_CTRL":       0x38d,
+	"IA32_PERF_GLOBAL_STATUS":   0x38e,
+	"IA32_PERF_GLOBAL_CTRL":     0x38f,
+	"MSR_RAPL_POWER_UNIT":       0x606,
+	"MSR_PKG_POWER_LIMIT":       0x610,
+	"MSR_PKG_ENERGY_STATUS":     0x611,
+	"MSR_DRAM_ENERGY_STATUS":    0x619,
+	"MSR_PP0_ENERGY_STATUS":     0x639,
+	"IA32_TSC_DEADLINE":         0x6e0,
+	"IA32_PM_ENABLE":            0x770,
+	"IA32_HWP_CAPABILITIES":     0x771,
+	"IA32_HWP_REQUEST":          0x774,
+	"IA32_HWP_STATUS":           0x777,
+	"IA32_EFER":                 0xc0000080,
+	"IA32_STAR":                 0xc0000081,
+	"IA32_LSTAR":                0xc0000082,
+	"IA32_FS_BASE":              0xc0000100,
+	"IA32_GS_BASE":              0xc0000101,
+	"IA32_KERNEL_GS_BASE":       0xc0000102,
+	"IA32_TSC_AUX":              0xc0000103,
+}
@@ -3,6 +3,33 @@
 // license that can be found in the LICENSE file.
 
 // Netcat pipes over the network.
+//
+// Synopsis:
+//     netcat [-u] [-w TIMEOUT] HOST PORT
+//     netcat -l [-u] [HOST] PORT
+//     netcat -z [-u] [-v] [-w TIMEOUT] HOST PORT[-PORT]
+//     netcat [-net NET] ADDRESS
+//
+// Description:
+//     netcat connects to PORT of HOST and copies stdin to the connection,
+//     and what comes back to stdout. With -l it waits for a connection
+//     instead. With -z it only says which of the ports are open.
+//
+//     A single ADDRESS is in the form of Go, e.g. host:port, or a path
+//     for -net unix.
+//
+// Options:
+//     -l: listen for a connection
+//     -u: use UDP rather than TCP
+//     -z: scan the ports, sending no data
+//     -v: with -z, also print closed ports
+//     -w: give up connecting after TIMEOUT
+//     -net: network, e.g. tcp, udp, unix
+//
+// Example:
+//     netcat -l 1234 > file      # on one machine
+//     netcat 10.0.2.2 1234 < file  # on the other
+//     netcat -z -w 1s 10.0.2.2 20-25
 package main
 
 import (
@@ -12,36 +39,203 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/u-root/u-root/pkg/uroot/util"
 )
 
-const usage = "netcat [go-style network address]"
+const usage = "netcat [-l] [-u] [-z] [-v] [-w timeout] [-net net] host port | address"
 
-var netType = flag.String("net", "tcp", "What net type to use, e.g. tcp, unix, etc.")
+var (
+	netType = flag.String("net", "tcp", "What net type to use, e.g. tcp, unix, etc.")
+	listen  = flag.Bool("l", false, "Listen for a connection")
+	udp     = flag.Bool("u", false, "Use UDP")
+	scan    = flag.Bool("z", false, "Scan ports without sending data")
+	verbose = flag.Bool("v", false, "Print closed ports too when scanning")
+	timeout = flag.Duration("w", 0, "Connect timeout, 0 for none")
+)
 
 func init() {
 	util.Usage(usage)
 }
 
+// parsePorts parses a port, or a range of them such as 20-25.
+func parsePorts(s string) (int, int, error) {
+	lo, hi := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		lo, hi = s[:i], s[i+1:]
+	}
+	l, err := strconv.Atoi(lo)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad port %q", s)
+	}
+	h, err := strconv.Atoi(hi)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad port %q", s)
+	}
+	if l < 0 || h > 65535 || l > h {
+		return 0, 0, fmt.Errorf("bad port range %q", s)
+	}
+	return l, h, nil
+}
+
+// address returns the host and ports of the arguments: HOST PORT, PORT, or
+// an address of net.Dial. ports is empty for an address.
+func address(args []string) (string, string, error) {
+	switch len(args) {
+	case 1:
+		if _, err := strconv.Atoi(args[0]); err == nil {
+			return "", args[0], nil
+		}
+		return args[0], "", nil
+	case 2:
+		return args[0], args[1], nil
+	}
+	return "", "", fmt.Errorf("want HOST PORT or ADDRESS, not %q", args)
+}
+
+// pipe copies in to c, and c to out, until c has nothing more. Once in
+// is all sent, c is closed for writing, if it can be, so that the other
+// end knows.
+func pipe(c net.Conn, in io.Reader, out io.Writer) error {
+	go func() {
+		if _, err := io.Copy(c, in); err != nil {
+			log.Printf("netcat: %v", err)
+		}
+		if cw, ok := c.(interface {
+			CloseWrite() error
+		}); ok {
+			cw.CloseWrite()
+		}
+	}()
+	_, err := io.Copy(out, c)
+	return err
+}
+
+// packetConn is a net.Conn for the first peer to send to a listening UDP
+// socket.
+type packetConn struct {
+	net.PacketConn
+	peer  net.Addr
+	first []byte
+}
+
+func (c *packetConn) Read(b []byte) (int, error) {
+	if c.first != nil {
+		n := copy(b, c.first)
+		c.first = nil
+		return n, nil
+	}
+	for {
+		n, addr, err := c.ReadFrom(b)
+		if err != nil || addr.String() == c.peer.String() {
+			return n, err
+		}
+	}
+}
+
+func (c *packetConn) Write(b []byte) (int, error) {
+	return c.WriteTo(b, c.peer)
+}
+
+func (c *packetConn) RemoteAddr() net.Addr {
+	return c.peer
+}
+
+// accept waits for a connection, or for UDP the first datagram, to addr.
+func accept(network, addr string) (net.Conn, error) {
+	if strings.HasPrefix(network, "udp") || network == "unixgram" {
+		pc, err := net.ListenPacket(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 65536)
+		n, peer, err := pc.ReadFrom(b)
+		if err != nil {
+			pc.Close()
+			return nil, err
+		}
+		return &packetConn{PacketConn: pc, peer: peer, first: b[:n]}, nil
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	return l.Accept()
+}
+
+// scanPorts prints the ports from lo to hi of host which are open. A UDP
+// port is open unless something says it is not.
+func scanPorts(w io.Writer, network, host string, lo, hi int, d time.Duration, all bool) {
+	if d == 0 {
+		d = time.Second
+	}
+	for port := lo; port <= hi; port++ {
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		c, err := net.DialTimeout(network, addr, d)
+		if err == nil && strings.HasPrefix(network, "udp") {
+			// An ICMP port unreachable makes the read fail.
+			c.Write(nil)
+			c.SetReadDeadline(time.Now().Add(d))
+			_, err = c.Read(make([]byte, 1))
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				err = nil
+			}
+		}
+		if c != nil {
+			c.Close()
+		}
+		switch {
+		case err == nil:
+			fmt.Fprintf(w, "%v %d open\n", host, port)
+		case all:
+			fmt.Fprintf(w, "%v %d closed\n", host, port)
+		}
+	}
+}
+
 func main() {
 	flag.Parse()
-	var c net.Conn
-	var err error
-	if len(flag.Args()) < 1 {
+	if flag.NArg() < 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
-
-	if c, err = net.Dial(*netType, flag.Args()[0]); err != nil {
-		log.Fatalf("%v\n", err)
+	network := *netType
+	if *udp {
+		network = "udp"
 	}
-	go func() {
-		if _, err := io.Copy(c, os.Stdin); err != nil {
-			fmt.Printf("%v", err)
+	host, ports, err := address(flag.Args())
+	if err != nil {
+		log.Fatalf("netcat: %v", err)
+	}
+
+	if *scan {
+		lo, hi, err := parsePorts(ports)
+		if err != nil {
+			log.Fatalf("netcat: %v", err)
 		}
-	}()
-	if _, err = io.Copy(os.Stdout, c); err != nil {
-		fmt.Printf("%v", err)
+		scanPorts(os.Stdout, network, host, lo, hi, *timeout, *verbose)
+		return
+	}
+
+	addr := host
+	if ports != "" {
+		addr = net.JoinHostPort(host, ports)
+	}
+	var c net.Conn
+	if *listen {
+		c, err = accept(network, addr)
+	} else {
+		c, err = net.DialTimeout(network, addr, *timeout)
+	}
+	if err != nil {
+		log.Fatalf("netcat: %v", err)
+	}
+	defer c.Close()
+	if err := pipe(c, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("netcat: %v", err)
 	}
 }
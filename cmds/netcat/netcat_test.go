@@ -0,0 +1,108 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	for _, tt := range []struct {
+		s      string
+		lo, hi int
+		err    bool
+	}{
+		{s: "80", lo: 80, hi: 80},
+		{s: "20-25", lo: 20, hi: 25},
+		{s: "25-20", err: true},
+		{s: "http", err: true},
+		{s: "1-65536", err: true},
+	} {
+		lo, hi, err := parsePorts(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("parsePorts(%q): got %v, want error %v", tt.s, err, tt.err)
+			continue
+		}
+		if lo != tt.lo || hi != tt.hi {
+			t.Errorf("parsePorts(%q): got %d-%d, want %d-%d", tt.s, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestAddress(t *testing.T) {
+	for _, tt := range []struct {
+		args        []string
+		host, ports string
+	}{
+		{[]string{"localhost", "80"}, "localhost", "80"},
+		{[]string{"1234"}, "", "1234"},
+		{[]string{"localhost:80"}, "localhost:80", ""},
+		{[]string{"/tmp/sock"}, "/tmp/sock", ""},
+	} {
+		host, ports, err := address(tt.args)
+		if err != nil || host != tt.host || ports != tt.ports {
+			t.Errorf("address(%q): got %q, %q, %v, want %q, %q, nil", tt.args, host, ports, err, tt.host, tt.ports)
+		}
+	}
+	if _, _, err := address([]string{"a", "b", "c"}); err == nil {
+		t.Errorf("address(a b c): got nil, want error")
+	}
+}
+
+func TestPipe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got := make(chan string, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		defer c.Close()
+		// This only ends if the client closes its end for writing.
+		b, _ := ioutil.ReadAll(c)
+		got <- string(b)
+		c.Write([]byte("pong"))
+	}()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var out bytes.Buffer
+	if err := pipe(c, strings.NewReader("ping"), &out); err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	if s := <-got; s != "ping" {
+		t.Errorf("pipe: server got %q, want %q", s, "ping")
+	}
+	if out.String() != "pong" {
+		t.Errorf("pipe: got %q, want %q", out.String(), "pong")
+	}
+}
+
+func TestScanPorts(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	var out bytes.Buffer
+	scanPorts(&out, "tcp", "127.0.0.1", port, port, 0, false)
+	if want := "127.0.0.1 " + strconv.Itoa(port) + " open\n"; out.String() != want {
+		t.Errorf("scanPorts: got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Set the clock from an NTP server.
+//
+// Synopsis:
+//     ntpdate [-q] [-t TIMEOUT] [SERVER...]
+//
+// Description:
+//     ntpdate asks the SERVERs, in order, for the time, and sets the clock
+//     from the first which answers. The default SERVER is pool.ntp.org.
+//
+//     Init does the same at boot if uroot.ntp=SERVER[,SERVER...] is on the
+//     kernel command line and the network is up, so that certificates can
+//     be checked by their dates.
+//
+// Options:
+//     -q: only print the offset of the clock; do not set it
+//     -t: how long to wait for each server
+//
+// Example:
+//     ntpdate -q time.google.com
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/u-root/u-root/pkg/ntp"
+)
+
+const defaultServer = "pool.ntp.org"
+
+var (
+	query   = flag.Bool("q", false, "only print the offset, do not set the clock")
+	timeout = flag.Duration("t", 5*time.Second, "how long to wait for each server")
+)
+
+func main() {
+	flag.Parse()
+	servers := flag.Args()
+	if len(servers) == 0 {
+		servers = []string{defaultServer}
+	}
+
+	for _, s := range servers {
+		r, err := ntp.Query(s, *timeout)
+		if err != nil {
+			log.Printf("ntpdate: %v", err)
+			continue
+		}
+		fmt.Printf("server %v, stratum %d, offset %+.6f, delay %.5f\n", r.Server, r.Stratum, r.Offset.Seconds(), r.Delay.Seconds())
+		if *query {
+			return
+		}
+		if err := ntp.Adjust(r.Offset); err != nil {
+			log.Fatalf("ntpdate: can not set the clock: %v", err)
+		}
+		fmt.Printf("step time server %v offset %+.6f sec\n", r.Server, r.Offset.Seconds())
+		return
+	}
+	log.Printf("ntpdate: no server suitable for synchronization found")
+	os.Exit(1)
+}
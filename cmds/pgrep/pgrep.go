@@ -0,0 +1,83 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Print the PIDs of processes matching a pattern.
+//
+// Synopsis:
+//     pgrep [-flaxic] [-d DELIM] PATTERN
+//
+// Description:
+//     PATTERN is a regular expression, matched against the name of each
+//     process, as in /proc/PID/comm, or its full command line with -f.
+//     pgrep never matches itself. The exit status is 0 if a process
+//     matched, 1 if none did and 2 on error.
+//
+// Options:
+//     -f: match the full command line
+//     -x: match all of the name or command line
+//     -i: ignore case
+//     -l: print the name as well as the PID
+//     -a: print the command line as well as the PID
+//     -c: print only the number of processes which matched
+//     -d: print DELIM between PIDs, rather than a newline
+//
+// Example:
+//     pgrep -f 'dhclient.*eth0'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/pgrep"
+)
+
+var (
+	opts      pgrep.Options
+	listName  = flag.Bool("l", false, "print the name as well as the PID")
+	listFull  = flag.Bool("a", false, "print the command line as well as the PID")
+	count     = flag.Bool("c", false, "print the number of matching processes")
+	delimiter = flag.String("d", "\n", "`delimiter` between PIDs")
+)
+
+func init() {
+	opts.RegisterFlags(flag.CommandLine)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Printf("Usage: pgrep [-flaxic] [-d DELIM] PATTERN")
+		os.Exit(2)
+	}
+	procs, err := opts.Find(flag.Arg(0))
+	if err != nil {
+		log.Printf("pgrep: %v", err)
+		os.Exit(2)
+	}
+	if *count {
+		fmt.Println(len(procs))
+	} else {
+		var l []string
+		for _, p := range procs {
+			s := fmt.Sprint(p.PID)
+			switch {
+			case *listFull && p.Cmdline != "":
+				s += " " + p.Cmdline
+			case *listFull, *listName:
+				s += " " + p.Name
+			}
+			l = append(l, s)
+		}
+		if len(l) > 0 {
+			fmt.Println(strings.Join(l, *delimiter))
+		}
+	}
+	if len(procs) == 0 {
+		os.Exit(1)
+	}
+}
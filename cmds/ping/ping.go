@@ -5,39 +5,69 @@
 // Send icmp packets to a server to test network connectivity.
 //
 // Synopsis:
-//     ping [-hV] [-c COUNT] [-i INTERVAL] [-s PACKETSIZE] [-w DEADLINE] DESTINATION
+//     ping [-hV6] [-c COUNT] [-i INTERVAL] [-s PACKETSIZE] [-w DEADLINE] [-W TIMEOUT] DESTINATION
+//
+// Description:
+//     ping sends ICMP echo requests to DESTINATION and prints the replies
+//     and how long they took. When it stops, after COUNT requests, at the
+//     DEADLINE or on ^C, it prints how many were lost and the round trip
+//     times. It exits 1 if no reply came back.
+//
+//     ping uses raw sockets if it can, and otherwise the datagram ICMP
+//     sockets which Linux lets the groups in net.ipv4.ping_group_range use.
 //
 // Options:
-//     -6: use ipv6 (ip6:ipv6-icmp)
-//     -s: data size (default: 64)
+//     -6: use ipv6 (ip6:ipv6-icmp); the default if DESTINATION only has an
+//         ipv6 address
+//     -s: data size (default: 56)
 //     -c: # iterations, 0 to run forever (default)
 //     -i: interval in milliseconds (default: 1000)
+//     -w: stop after DEADLINE seconds, 0 for none (default)
+//     -W: wait time for the last reply in milliseconds (default: 1000)
 //     -V: version
-//     -w: wait time in milliseconds (default: 100)
 //     -h: help
+//
+// Example:
+//     ping -c 3 10.0.2.2
 package main
 
 import (
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"net"
 	"os"
+	"os/signal"
 	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 var (
 	net6       = flag.Bool("6", false, "use ipv4 (means ip4:icmp) or 6 (ip6:ipv6-icmp)")
-	packetSize = flag.Int("s", 64, "Data size")
+	packetSize = flag.Int("s", 56, "Data size")
 	iter       = flag.Int64("c", 0, "# iterations")
 	intv       = flag.Int("i", 1000, "interval in milliseconds")
+	deadline   = flag.Int("w", 0, "deadline in seconds")
 	version    = flag.Bool("V", false, "version")
-	wtf        = flag.Int("w", 100, "wait time in milliseconds")
+	wtf        = flag.Int("W", 1000, "wait time for the last reply in milliseconds")
 	help       = flag.Bool("h", false, "help")
 )
 
+const (
+	protoICMP   = 1
+	protoICMPv6 = 58
+	// timeSize is the size of the time of sending, at the start of the
+	// data.
+	timeSize = 8
+)
+
 func usage() {
-	fmt.Fprintf(os.Stdout, "ping [-hV] [-c count] [-i interval] [-s packetsize] [-w deadline] destination\n")
+	fmt.Fprintf(os.Stdout, "ping [-hV6] [-c count] [-i interval] [-s packetsize] [-w deadline] [-W timeout] destination\n")
 	os.Exit(0)
 }
 
@@ -54,62 +84,232 @@ func optwithoutparam() {
 	usage()
 }
 
-func beatifulLatency(before time.Time) (latency string) {
-	now := float64(time.Since(before).Nanoseconds())
+// ms returns d in milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// stats are the statistics of the replies.
+type stats struct {
+	sent, received int
+	// timed is how many replies had the time they were sent in them.
+	timed         int
+	min, max, sum time.Duration
+	// sum2 is the sum of the squares of the round trip times, in ms, for
+	// their mean deviation.
+	sum2 float64
+}
+
+func (s *stats) add(r reply) {
+	s.received++
+	if !r.timed {
+		return
+	}
+	rtt := r.rtt
+	if s.timed == 0 || rtt < s.min {
+		s.min = rtt
+	}
+	if rtt > s.max {
+		s.max = rtt
+	}
+	s.timed++
+	s.sum += rtt
+	s.sum2 += ms(rtt) * ms(rtt)
+}
+
+// String formats s as ping does at the end, for the destination host and
+// the time it took.
+func (s *stats) String(host string, took time.Duration) string {
+	loss := 0
+	if s.sent > 0 {
+		loss = 100 * (s.sent - s.received) / s.sent
+	}
+	r := fmt.Sprintf("--- %v ping statistics ---\n%d packets transmitted, %d received, %d%% packet loss, time %.0fms\n",
+		host, s.sent, s.received, loss, ms(took))
+	if s.timed > 0 {
+		avg := ms(s.sum) / float64(s.timed)
+		mdev := math.Sqrt(math.Max(0, s.sum2/float64(s.timed)-avg*avg))
+		r += fmt.Sprintf("rtt min/avg/max/mdev = %.3f/%.3f/%.3f/%.3f ms\n", ms(s.min), avg, ms(s.max), mdev)
+	}
+	return r
+}
+
+// A pinger sends echo requests and reads the replies.
+type pinger struct {
+	c     *icmp.PacketConn
+	dst   net.Addr
+	proto int
+	id    int
+	// raw is set for raw sockets. Datagram sockets only get replies to
+	// their own requests, with an ID chosen by the kernel.
+	raw bool
+}
+
+// listen opens a raw socket for ip, or if that is not allowed, a datagram
+// one.
+func listen(ip net.IP) (*pinger, error) {
+	p := &pinger{proto: protoICMP, id: os.Getpid() & 0xffff}
+	raw, dgram, any := "ip4:icmp", "udp4", "0.0.0.0"
+	if ip.To4() == nil {
+		p.proto = protoICMPv6
+		raw, dgram, any = "ip6:ipv6-icmp", "udp6", "::"
+	}
+	c, err := icmp.ListenPacket(raw, any)
+	if err == nil {
+		p.c, p.raw, p.dst = c, true, &net.IPAddr{IP: ip}
+		return p, nil
+	}
+	c, derr := icmp.ListenPacket(dgram, any)
+	if derr != nil {
+		return nil, fmt.Errorf("%v, and %v", err, derr)
+	}
+	p.c, p.dst = c, &net.UDPAddr{IP: ip}
+	return p, nil
+}
 
-	switch {
-	case now > 1e6:
-		latency = fmt.Sprintf("%.2f ms", now/1e6)
-	case now > 1e3:
-		latency = fmt.Sprintf("%.2f µ", now/1e3)
-	default:
-		latency = fmt.Sprintf("%v ns", now)
+// send sends the echo request seq, with the time in its data.
+func (p *pinger) send(seq int, size int) error {
+	data := make([]byte, size)
+	if size >= timeSize {
+		binary.BigEndian.PutUint64(data, uint64(time.Now().UnixNano()))
 	}
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if p.proto == protoICMPv6 {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	m := icmp.Message{Type: typ, Body: &icmp.Echo{ID: p.id, Seq: seq, Data: data}}
+	b, err := m.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	_, err = p.c.WriteTo(b, p.dst)
+	return err
+}
 
-	return latency
+// A reply is an echo reply.
+type reply struct {
+	size int
+	from net.Addr
+	seq  int
+	// timed is set if the request had room for the time it was sent,
+	// so that the round trip time, rtt, is known.
+	timed bool
+	rtt   time.Duration
+}
+
+// receive sends the replies to our requests to replies, until it can not
+// read any more.
+func (p *pinger) receive(replies chan<- reply) {
+	b := make([]byte, 65536)
+	for {
+		n, from, err := p.c.ReadFrom(b)
+		if err != nil {
+			close(replies)
+			return
+		}
+		now := time.Now()
+		m, err := icmp.ParseMessage(p.proto, b[:n])
+		if err != nil {
+			continue
+		}
+		if m.Type != ipv4.ICMPTypeEchoReply && m.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		e, ok := m.Body.(*icmp.Echo)
+		if !ok || (p.raw && e.ID != p.id) {
+			continue
+		}
+		r := reply{size: n, from: from, seq: e.Seq}
+		if len(e.Data) >= timeSize {
+			sent := time.Unix(0, int64(binary.BigEndian.Uint64(e.Data)))
+			r.timed, r.rtt = true, now.Sub(sent)
+		}
+		replies <- r
+	}
 }
 
 func main() {
 	flag.Parse()
 
 	// options without parameters (right now just: -hV)
-	if flag.NArg() < 1 {
+	if flag.NArg() < 1 || *help {
 		optwithoutparam()
 	}
 
-	netname := "ip4:icmp"
-	interval := time.Duration(*intv)
-	waitFor := time.Duration(*wtf) * time.Millisecond
 	host := flag.Args()[0]
-	// todo: just figure out if it's an ip6 address and go from there.
+	netname := "ip4"
 	if *net6 {
-		netname = "ip6:ipv6-icmp"
+		netname = "ip6"
 	}
-	msg := make([]byte, *packetSize)
+	dst, err := net.ResolveIPAddr(netname, host)
+	if err != nil && !*net6 {
+		// Try ipv6 if the host has no ipv4 address.
+		dst, err = net.ResolveIPAddr("ip6", host)
+	}
+	if err != nil {
+		log.Fatalf("ping: %v: %v", host, err)
+	}
+	p, err := listen(dst.IP)
+	if err != nil {
+		log.Fatalf("ping: %v", err)
+	}
+	defer p.c.Close()
 
-	// ping needs to run forever, except if '*iter' is not zero
-	var i int64
-	for i = 1; *iter == 0 || i < *iter; i++ {
-		c, err := net.Dial(netname, host)
-		if err != nil {
-			log.Fatalf("net.Dial(%v %v) failed: %v", netname, host, err)
-		}
+	replies := make(chan reply)
+	go p.receive(replies)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	var end <-chan time.Time
+	if *deadline > 0 {
+		end = time.After(time.Duration(*deadline) * time.Second)
+	}
+	ticker := time.NewTicker(time.Duration(*intv) * time.Millisecond)
+	defer ticker.Stop()
 
-		c.SetDeadline(time.Now().Add(waitFor))
-		defer c.Close()
-		msg[0] = byte(i)
-		if _, err := c.Write(msg[:]); err != nil {
-			log.Printf("Write failed: %v", err)
-		} else {
-			before := time.Now()
-			c.SetDeadline(time.Now().Add(waitFor))
-			if amt, err := c.Read(msg[:]); err == nil {
-				latency := beatifulLatency(before)
-				log.Printf("%d bytes from %v: icmp_seq=%v, time=%v", amt, host, i, latency)
-			} else {
-				log.Printf("Read failed: %v", err)
+	fmt.Printf("PING %v (%v) %d data bytes\n", host, dst.IP, *packetSize)
+	var s stats
+	start := time.Now()
+	// last fires once the last request has had its time to reply.
+	var last <-chan time.Time
+	send := func() {
+		s.sent++
+		if err := p.send(s.sent, *packetSize); err != nil {
+			log.Printf("ping: %v", err)
+		}
+		if *iter != 0 && int64(s.sent) >= *iter {
+			ticker.Stop()
+			last = time.After(time.Duration(*wtf) * time.Millisecond)
+		}
+	}
+	send()
+loop:
+	for {
+		select {
+		case r, ok := <-replies:
+			if !ok {
+				break loop
 			}
+			s.add(r)
+			fmt.Printf("%d bytes from %v: icmp_seq=%d", r.size, r.from, r.seq)
+			if r.timed {
+				fmt.Printf(" time=%.3f ms", ms(r.rtt))
+			}
+			fmt.Println()
+			if last != nil && s.received >= s.sent {
+				break loop
+			}
+		case <-ticker.C:
+			send()
+		case <-last:
+			break loop
+		case <-end:
+			break loop
+		case <-interrupt:
+			break loop
 		}
-		time.Sleep(time.Millisecond * interval)
+	}
+	fmt.Print(s.String(host, time.Since(start)))
+	if s.received == 0 {
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,46 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	for _, tt := range []struct {
+		sent    int
+		replies []reply
+		want    string
+	}{
+		{
+			sent: 2,
+			want: "--- h ping statistics ---\n2 packets transmitted, 0 received, 100% packet loss, time 2000ms\n",
+		},
+		{
+			sent: 4,
+			replies: []reply{
+				{timed: true, rtt: time.Millisecond},
+				{timed: true, rtt: 3 * time.Millisecond},
+				{timed: true, rtt: 2 * time.Millisecond},
+			},
+			want: "--- h ping statistics ---\n4 packets transmitted, 3 received, 25% packet loss, time 2000ms\n" +
+				"rtt min/avg/max/mdev = 1.000/2.000/3.000/0.816 ms\n",
+		},
+		{
+			sent:    1,
+			replies: []reply{{}},
+			want:    "--- h ping statistics ---\n1 packets transmitted, 1 received, 0% packet loss, time 2000ms\n",
+		},
+	} {
+		s := stats{sent: tt.sent}
+		for _, r := range tt.replies {
+			s.add(r)
+		}
+		if got := s.String("h", 2*time.Second); got != tt.want {
+			t.Errorf("stats of %v: got %q, want %q", tt.replies, got, tt.want)
+		}
+	}
+}
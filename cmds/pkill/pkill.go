@@ -0,0 +1,94 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Signal processes matching a pattern.
+//
+// Synopsis:
+//     pkill [-SIGNAL] [-fxie] [-signal SIGNAL] PATTERN
+//
+// Description:
+//     PATTERN is a regular expression, matched against the name of each
+//     process, as in /proc/PID/comm, or its full command line with -f.
+//     Matching processes are sent SIGNAL, by default TERM. SIGNAL is a
+//     name, with or without SIG, or a number. pkill never signals itself.
+//     The exit status is 0 if a process matched, 1 if none did and 2 on
+//     error.
+//
+// Options:
+//     -f: match the full command line
+//     -x: match all of the name or command line
+//     -i: ignore case
+//     -e: print what was signalled
+//     -signal: the signal to send
+//
+// Example:
+//     pkill -HUP -x dhclient
+//     pkill -9 -f 'sleep 100'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/pgrep"
+)
+
+var (
+	opts   pgrep.Options
+	echo   = flag.Bool("e", false, "print what was signalled")
+	signal = flag.String("signal", "TERM", "the `signal` to send")
+)
+
+func init() {
+	opts.RegisterFlags(flag.CommandLine)
+}
+
+func usage() {
+	log.Printf("Usage: pkill [-SIGNAL] [-fxie] [-signal SIGNAL] PATTERN")
+	os.Exit(2)
+}
+
+func main() {
+	// -SIGNAL is not a flag, so it is taken off before the flags are
+	// parsed.
+	for i, a := range os.Args[1:] {
+		if len(a) < 2 || a[0] != '-' {
+			break
+		}
+		if _, err := pgrep.Signal(a[1:]); err == nil {
+			*signal = a[1:]
+			os.Args = append(os.Args[:i+1], os.Args[i+2:]...)
+			break
+		}
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+	}
+	sig, err := pgrep.Signal(*signal)
+	if err != nil {
+		log.Printf("pkill: %v", err)
+		usage()
+	}
+	procs, err := opts.Find(flag.Arg(0))
+	if err != nil {
+		log.Printf("pkill: %v", err)
+		os.Exit(2)
+	}
+	if len(procs) == 0 {
+		os.Exit(1)
+	}
+	for _, p := range procs {
+		if err := syscall.Kill(p.PID, sig); err != nil {
+			log.Printf("pkill: %d: %v", p.PID, err)
+			continue
+		}
+		if *echo {
+			fmt.Printf("%s killed (pid %d)\n", p.Name, p.PID)
+		}
+	}
+}
@@ -5,7 +5,7 @@
 // Print process information.
 //
 // Synopsis:
-//     ps [-Aaex] [aux]
+//     ps [-Aaex] [-o FORMAT] [-sort KEYS] [aux]
 //
 // Description:
 //     ps reads the /proc filesystem and prints nice things about what it
@@ -17,7 +17,18 @@
 //     -e: select all processes. Identical to -A.
 //     -x: BSD-Like style, with STAT Column and long CommandLine
 //     -a: print all process except whose are session leaders or unlinked with terminal
+//     -o: print the columns in the comma-separated FORMAT
+//     -sort: sort by the comma-separated columns in KEYS; a - before a
+//         column sorts it in descending order
 //    aux: see every process on the system using BSD syntax
+//
+// Columns:
+//     pid, ppid, pgid, sid, tty, stat, time, comm, args, user, uid, rss,
+//     vsz, pcpu, pmem, start, nice, pri, nlwp and psr. rss and vsz are in
+//     KiB.
+//
+// Example:
+//     ps -e -o pid,comm,rss,stat -sort -rss
 package main
 
 import (
@@ -36,8 +47,10 @@ var (
 		nSidTty bool
 		x       bool
 		aux     bool
+		format  string
+		sort    string
 	}
-	cmd     = "ps [-Aaex] [aux]"
+	cmd     = "ps [-Aaex] [-o FORMAT] [-sort KEYS] [aux]"
 	eUID    = os.Geteuid()
 	mainPID = os.Getpid()
 )
@@ -52,6 +65,8 @@ func init() {
 	flag.BoolVar(&flags.all, "e", false, "Select all processes.  Identical to -A.")
 	flag.BoolVar(&flags.x, "x", false, "BSD-Like style, with STAT Column and long CommandLine")
 	flag.BoolVar(&flags.nSidTty, "a", false, "Print all process except whose are session leaders or unlinked with terminal")
+	flag.StringVar(&flags.format, "o", "", "Comma-separated columns to print, e.g. pid,comm,rss,stat")
+	flag.StringVar(&flags.sort, "sort", "pid", "Comma-separated columns to sort by, with - for descending order")
 
 	if len(os.Args) > 1 {
 		if isPermutation(os.Args[1], "aux") {
@@ -68,7 +83,101 @@ type ProcessTable struct {
 	headers  []string // each column to print
 	fields   []string // which fields of process to print, on order
 	fstring  []string // formated strings
-	maxwidth int      // DEPRECATED: reason -> remove terminal stuff
+	right    []bool   // which columns are right aligned
+	keys     []sortKey
+	maxwidth int // DEPRECATED: reason -> remove terminal stuff
+}
+
+// A column is what ps can print, named by a keyword of -o.
+type column struct {
+	header string
+	field  string // of process
+	right  bool   // numbers are right aligned
+}
+
+var columns = map[string]column{
+	"pid":     {"PID", "Pid", true},
+	"ppid":    {"PPID", "Ppid", true},
+	"pgid":    {"PGID", "Pgrp", true},
+	"pgrp":    {"PGRP", "Pgrp", true},
+	"sid":     {"SID", "Sid", true},
+	"tty":     {"TTY", "Ctty", false},
+	"stat":    {"STAT", "State", false},
+	"time":    {"TIME", "Time", true},
+	"comm":    {"COMMAND", "Cmd", false},
+	"args":    {"COMMAND", "Args", false},
+	"cmd":     {"CMD", "Args", false},
+	"ucmd":    {"CMD", "Cmd", false},
+	"command": {"COMMAND", "Args", false},
+	"user":    {"USER", "User", false},
+	"uid":     {"UID", "Uid", true},
+	"rss":     {"RSS", "RssKB", true},
+	"vsz":     {"VSZ", "VszKB", true},
+	"pcpu":    {"%CPU", "PCPU", true},
+	"%cpu":    {"%CPU", "PCPU", true},
+	"pmem":    {"%MEM", "PMEM", true},
+	"%mem":    {"%MEM", "PMEM", true},
+	"start":   {"START", "Start", false},
+	"nice":    {"NI", "Nice", true},
+	"ni":      {"NI", "Nice", true},
+	"pri":     {"PRI", "Priority", true},
+	"nlwp":    {"NLWP", "NumThreads", true},
+	"psr":     {"PSR", "TaskCpu", true},
+}
+
+// parseColumns turns a comma-separated list of keywords into columns.
+func parseColumns(format string) ([]column, error) {
+	var cols []column
+	for _, k := range strings.Split(format, ",") {
+		c, ok := columns[strings.ToLower(strings.TrimSpace(k))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", k)
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+// A sortKey is a field to sort processes by.
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// parseSort turns a comma-separated list of keywords, each optionally
+// preceded by + or -, into sort keys.
+func parseSort(keys string) ([]sortKey, error) {
+	var sk []sortKey
+	for _, k := range strings.Split(keys, ",") {
+		var desc bool
+		switch {
+		case strings.HasPrefix(k, "-"):
+			desc, k = true, k[1:]
+		case strings.HasPrefix(k, "+"):
+			k = k[1:]
+		}
+		c, ok := columns[strings.ToLower(k)]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort key %q", k)
+		}
+		sk = append(sk, sortKey{c.field, desc})
+	}
+	return sk, nil
+}
+
+// compareField compares two values of a field, as numbers if they are.
+func compareField(a, b string) int {
+	x, errx := strconv.ParseFloat(a, 64)
+	y, erry := strconv.ParseFloat(b, 64)
+	switch {
+	case errx != nil || erry != nil:
+		return strings.Compare(a, b)
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
 }
 
 // to use on sort.Sort
@@ -77,7 +186,14 @@ func (pT ProcessTable) Len() int {
 }
 
 // to use on sort.Sort
+// Processes are sorted by the keys, then by PID.
 func (pT ProcessTable) Less(i, j int) bool {
+	for _, k := range pT.keys {
+		c := compareField(pT.table[i].Search(k.field), pT.table[j].Search(k.field))
+		if c != 0 {
+			return (c < 0) != k.desc
+		}
+	}
 	a, _ := strconv.Atoi(pT.table[i].Pid)
 	b, _ := strconv.Atoi(pT.table[j].Pid)
 	return a < b
@@ -102,6 +218,9 @@ func (pT ProcessTable) GetProcess(pid int) (found *Process) {
 
 // Return the biggest value in a slice of ints.
 func max(slice []int) int {
+	if len(slice) == 0 {
+		return 0
+	}
 	max := slice[0]
 	for _, value := range slice {
 		if value > max {
@@ -149,30 +268,21 @@ func (pT ProcessTable) PrintProcess(index int) {
 	fmt.Printf("%v\n", row)
 }
 
+// PrepareString makes the format of each column as wide as its widest
+// value. The last column is not padded.
 func (pT *ProcessTable) PrepareString() {
-	var (
-		fstring  []string
-		formated string
-		PID      = pT.MaxLenght("Pid")
-		TTY      = pT.MaxLenght("Ctty")
-		STAT     = 4 | pT.MaxLenght("State") // min : 4
-		TIME     = pT.MaxLenght("Time")
-		CMD      = pT.MaxLenght("Cmd")
-	)
-	for _, f := range pT.headers {
-		switch f {
-		case "PID":
-			formated = fmt.Sprintf("%%%dv ", PID)
-		case "TTY":
-			formated = fmt.Sprintf("%%-%dv    ", TTY)
-		case "STAT":
-			formated = fmt.Sprintf("%%-%dv    ", STAT)
-		case "TIME":
-			formated = fmt.Sprintf("%%%dv ", TIME)
-		case "CMD":
-			formated = fmt.Sprintf("%%-%dv ", CMD)
+	var fstring []string
+	for i, f := range pT.fields {
+		if i == len(pT.fields)-1 {
+			fstring = append(fstring, "%v")
+			break
+		}
+		w := max([]int{len(pT.headers[i]), pT.MaxLenght(f)})
+		if pT.right[i] {
+			fstring = append(fstring, fmt.Sprintf("%%%dv ", w))
+		} else {
+			fstring = append(fstring, fmt.Sprintf("%%-%dv ", w))
 		}
-		fstring = append(fstring, formated)
 	}
 
 	pT.fstring = fstring
@@ -196,39 +306,38 @@ func isPermutation(check string, ref string) bool {
 	return true
 }
 
-// For now, just read /proc/pid/stat and dump its brains.
-// TODO: a nice clean way to turn /proc/pid/stat into a struct. (trying now)
-// There has to be a way.
+// ps prints the processes of pT which were asked for.
 func ps(pT ProcessTable) error {
-	// sorting ProcessTable by PID
-	sort.Sort(pT)
-
+	var format string
 	switch {
+	case flags.format != "":
+		format = flags.format
 	case flags.aux:
-		pT.headers = []string{"PID", "PGRP", "SID", "TTY", "STAT", "TIME", "COMMAND"}
-		pT.fields = []string{"Pid", "Pgrp", "Sid", "Ctty", "State", "Time", "Cmd"}
+		format = "user,pid,%cpu,%mem,vsz,rss,tty,stat,start,time,command"
 	case flags.x:
-		pT.headers = []string{"PID", "TTY", "STAT", "TIME", "COMMAND"}
-		pT.fields = []string{"Pid", "Ctty", "State", "Time", "Cmd"}
+		format = "pid,tty,stat,time,command"
 	default:
-		pT.headers = []string{"PID", "TTY", "TIME", "CMD"}
-		pT.fields = []string{"Pid", "Ctty", "Time", "Cmd"}
+		format = "pid,tty,time,ucmd"
+	}
+	cols, err := parseColumns(format)
+	if err != nil {
+		return err
+	}
+	pT.headers, pT.fields, pT.right = nil, nil, nil
+	for _, c := range cols {
+		pT.headers = append(pT.headers, c.header)
+		pT.fields = append(pT.fields, c.field)
+		pT.right = append(pT.right, c.right)
+	}
+	if pT.keys, err = parseSort(flags.sort); err != nil {
+		return err
 	}
 
 	mProc := pT.GetProcess(mainPID)
 
-	pT.PrepareString()
-	pT.PrintHeader()
-	for index, p := range pT.table {
-		uid, err := p.GetUid()
-		if err != nil {
-			// It is extremely common for a directory to disappear from
-			// /proc when a process terminates, so ignore those errors.
-			if os.IsNotExist(err) {
-				continue
-			}
-			return err
-		}
+	var table []*Process
+	for _, p := range pT.table {
+		uid, _ := strconv.Atoi(p.Uid)
 
 		switch {
 		case flags.nSidTty:
@@ -249,16 +358,23 @@ func ps(pT ProcessTable) error {
 		default:
 			// default for no flags only same session
 			// and same uid process
-			if p.Sid != mProc.Sid || eUID != uid {
+			if mProc == nil || p.Sid != mProc.Sid || eUID != uid {
 				continue
 			}
 		}
 
+		table = append(table, p)
+	}
+	pT.table = table
+	sort.Sort(pT)
+
+	pT.PrepareString()
+	pT.PrintHeader()
+	for index := range pT.table {
 		pT.PrintProcess(index)
 	}
 
 	return nil
-
 }
 
 func main() {
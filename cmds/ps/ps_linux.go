@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/user"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -83,8 +85,31 @@ type process struct {
 	ExitCode    string // the thread's exit_code in the form reported by the waitpid system call (end of stat)
 	Ctty        string // extra member (don't parsed from stat)
 	Time        string // extra member (don't parsed from stat)
+	Uid         string // extra member, from status
+	User        string // extra member, name of Uid
+	Args        string // extra member, from cmdline
+	VszKB       string // extra member, Vsize in KiB
+	RssKB       string // extra member, Rss in KiB
+	PCPU        string // extra member, percentage of CPU time used since start
+	PMEM        string // extra member, percentage of memory resident
+	Start       string // extra member, StartTime as a time of day or date
 }
 
+// statFields is the number of fields of process read from stat. The extra
+// members come after them.
+var statFields = func() int {
+	f, _ := reflect.TypeOf(process{}).FieldByName("Ctty")
+	return f.Index[0]
+}()
+
+// System-wide values which some of the extra members depend on. They are
+// read by LoadTable.
+var (
+	uptime   float64 // seconds since boot
+	memTotal int64   // KiB
+	users    = map[string]string{}
+)
+
 // Parse all content of stat to a Process Struct
 // by gived the pid (linux)
 func (p *process) readStat(pid int) error {
@@ -93,32 +118,108 @@ func (p *process) readStat(pid int) error {
 		return err
 	}
 
-	fields := strings.Split(string(b), " ")
+	// The command name may have spaces and parentheses in it, so it
+	// is whatever is between the first ( and the last ).
+	s := string(b)
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return fmt.Errorf("%v: bad stat %q", pid, s)
+	}
+	fields := append([]string{strings.TrimSpace(s[:open]), s[open+1 : end]}, strings.Fields(s[end+1:])...)
 
 	// set struct fields from stat file data
 	v := reflect.ValueOf(p).Elem()
-	for i := 0; i < len(fields); i++ {
+	for i := 0; i < len(fields) && i < statFields; i++ {
 		fieldVal := v.Field(i)
 		fieldVal.Set(reflect.ValueOf(fields[i]))
 	}
 
 	p.Time = p.getTime()
 	p.Ctty = p.getCtty()
-	cmd := p.Cmd
-	p.Cmd = cmd[1 : len(cmd)-1]
-	if flags.x && false {
-		// disable that, because after removed the max width limit
-		// we had some incredible long cmd lines whose breaks the
-		// visual table of process at running ps
-		cmdline, err := p.longCmdLine()
-		if err != nil {
-			return err
-		}
-		if cmdline != "" {
-			p.Cmd = cmdline
-		}
+	uid, err := p.getUid()
+	if err != nil {
+		return err
 	}
+	p.Uid = strconv.Itoa(uid)
+	p.User = userName(p.Uid)
+	// The command line is the last column, so it is not a problem
+	// if it is long. Kernel threads have none.
+	if cmdline, err := p.longCmdLine(); err == nil && cmdline != "" {
+		p.Args = cmdline
+	} else {
+		p.Args = "[" + p.Cmd + "]"
+	}
+	p.memory()
+	p.cpu()
+	return nil
+}
 
+// userName returns the name of the user with uid, or uid if it has none.
+func userName(uid string) string {
+	if n, ok := users[uid]; ok {
+		return n
+	}
+	n := uid
+	if u, err := user.LookupId(uid); err == nil {
+		n = u.Username
+	}
+	users[uid] = n
+	return n
+}
+
+// memory sets the memory sizes of p in KiB.
+func (p *process) memory() {
+	vsize, _ := strconv.ParseInt(p.Vsize, 10, 64)
+	rss, _ := strconv.ParseInt(p.Rss, 10, 64)
+	rss *= int64(os.Getpagesize()) / 1024
+	p.VszKB = strconv.FormatInt(vsize/1024, 10)
+	p.RssKB = strconv.FormatInt(rss, 10)
+	pmem := 0.0
+	if memTotal > 0 {
+		pmem = 100 * float64(rss) / float64(memTotal)
+	}
+	p.PMEM = fmt.Sprintf("%.1f", pmem)
+}
+
+// cpu sets the CPU use and start time of p.
+func (p *process) cpu() {
+	utime, _ := strconv.ParseFloat(p.Utime, 64)
+	stime, _ := strconv.ParseFloat(p.Stime, 64)
+	start, _ := strconv.ParseFloat(p.StartTime, 64)
+	start /= USER_HZ
+	pcpu := 0.0
+	if uptime > start {
+		pcpu = 100 * (utime + stime) / USER_HZ / (uptime - start)
+	}
+	p.PCPU = fmt.Sprintf("%.1f", pcpu)
+
+	now := time.Now()
+	t := now.Add(-time.Duration((uptime - start) * float64(time.Second)))
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		p.Start = t.Format("15:04")
+	} else {
+		p.Start = t.Format("Jan02")
+	}
+}
+
+// readSystem reads the uptime and the size of memory.
+func readSystem() error {
+	b, err := ioutil.ReadFile(filepath.Join(proc, "uptime"))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Sscan(string(b), &uptime); err != nil {
+		return fmt.Errorf("uptime: %v", err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(proc, "meminfo"))
+	if err != nil {
+		return err
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(l); len(f) > 1 && f[0] == "MemTotal:" {
+			memTotal, _ = strconv.ParseInt(f[1], 10, 64)
+		}
+	}
 	return nil
 }
 
@@ -176,7 +277,7 @@ func (p Process) GetUid() (int, error) {
 	return p.process.getUid()
 }
 
-// change p.Cmd to long command line with args
+// longCmdLine returns the command line, with spaces between the args.
 func (p process) longCmdLine() (string, error) {
 	b, err := ioutil.ReadFile(filepath.Join(proc, p.Pid, "cmdline"))
 
@@ -184,7 +285,7 @@ func (p process) longCmdLine() (string, error) {
 		return "", err
 	}
 
-	return string(b), nil
+	return strings.TrimSpace(strings.Replace(string(b), "\x00", " ", -1)), nil
 }
 
 // Get total time stat formated hh:mm:ss
@@ -203,6 +304,10 @@ func (p process) getTime() string {
 
 // Create a ProcessTable containing stats on all processes.
 func (pT *ProcessTable) LoadTable() error {
+	if err := readSystem(); err != nil {
+		return err
+	}
+
 	// Match all files and directories directly inside of /proc.
 	matches, err := filepath.Glob(filepath.Join(proc, "*"))
 	if err != nil {
@@ -7,6 +7,10 @@
 package main
 
 import (
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
 	"testing"
 )
 
@@ -22,3 +26,71 @@ func TestPsExecution(t *testing.T) {
 		t.Fatalf("Calling ps fails; %v", err)
 	}
 }
+
+func TestColumns(t *testing.T) {
+	if _, err := parseColumns("pid,comm,RSS, stat"); err != nil {
+		t.Errorf("parseColumns: %v", err)
+	}
+	for _, f := range []string{"pid,", "nope"} {
+		if _, err := parseColumns(f); err == nil {
+			t.Errorf("parseColumns(%q): got nil, want error", f)
+		}
+		if _, err := parseSort(f); err == nil {
+			t.Errorf("parseSort(%q): got nil, want error", f)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	newProcess := func(pid, rss, comm string) *Process {
+		return &Process{process{Pid: pid, RssKB: rss, Cmd: comm}}
+	}
+	for _, tt := range []struct {
+		keys string
+		want []string
+	}{
+		{"pid", []string{"2", "10", "30"}},
+		{"-pid", []string{"30", "10", "2"}},
+		{"rss", []string{"10", "2", "30"}},
+		{"-rss", []string{"30", "2", "10"}},
+		{"comm,-pid", []string{"30", "10", "2"}},
+	} {
+		keys, err := parseSort(tt.keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pT := ProcessTable{
+			table: []*Process{
+				newProcess("10", "8", "sh"),
+				newProcess("2", "100", "sh"),
+				newProcess("30", "2000", "init"),
+			},
+			keys: keys,
+		}
+		sort.Sort(pT)
+		var got []string
+		for _, p := range pT.table {
+			got = append(got, p.Pid)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort by %v: got %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestReadStat(t *testing.T) {
+	pT := ProcessTable{}
+	if err := pT.LoadTable(); err != nil {
+		t.Fatal(err)
+	}
+	p := pT.GetProcess(os.Getpid())
+	if p == nil {
+		t.Fatalf("process %d not found", os.Getpid())
+	}
+	if p.Cmd != "ps.test" || p.Ppid != strconv.Itoa(os.Getppid()) {
+		t.Errorf("got command %q, parent %v, want %q, %v", p.Cmd, p.Ppid, "ps.test", os.Getppid())
+	}
+	if p.Uid != strconv.Itoa(os.Getuid()) || p.RssKB == "0" {
+		t.Errorf("got uid %v, rss %v KiB, want uid %v and some rss", p.Uid, p.RssKB, os.Getuid())
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// pxeboot boots a kernel from the network.
+//
+// Synopsis:
+//     pxeboot [OPTIONS...]
+//
+// Description:
+//     pxeboot gets an address with DHCP, then fetches the boot file the
+//     DHCP server names, by TFTP, or by HTTP if it is an http:// or
+//     https:// URL. If the boot file is an iPXE script, its kernel, initrd
+//     and arguments are booted. Otherwise, the pxelinux configuration is
+//     looked for next to the boot file, in pxelinux.cfg, and its default
+//     label is booted. The kernel and initrd are fetched like the boot
+//     file, and kexec'd.
+//
+// Options:
+//     -i:       interface to use
+//     -timeout: DHCP timeout in seconds
+//     -dry-run: fetch the kernel and initrd, but do not boot them
+//     -v:       verbose output
+//
+// Example:
+//     pxeboot -i eth1
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/d2g/dhcp4"
+	"github.com/d2g/dhcp4client"
+	"github.com/u-root/u-root/pkg/kexec"
+	"github.com/u-root/u-root/pkg/pxe"
+	"github.com/u-root/u-root/pkg/tftp"
+	"github.com/vishvananda/netlink"
+)
+
+const linkUpAttempt = 30 * time.Second
+
+var (
+	ifName  = flag.String("i", "eth0", "Interface to boot from")
+	timeout = flag.Int("timeout", 15, "DHCP timeout in seconds")
+	retry   = flag.Int("retry", 3, "Number of DHCP requests to send before giving up")
+	dryRun  = flag.Bool("dry-run", false, "Fetch the kernel and initrd, but do not boot them")
+	verbose = flag.Bool("v", false, "Verbose output")
+	debug   = func(string, ...interface{}) {}
+)
+
+func ifup(ifname string) (netlink.Link, error) {
+	start := time.Now()
+	for time.Since(start) < linkUpAttempt {
+		iface, err := netlink.LinkByName(ifname)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get interface by name %v: %v", ifname, err)
+		}
+		if iface.Attrs().OperState == netlink.OperUp {
+			return iface, nil
+		}
+		if err := netlink.LinkSetUp(iface); err != nil {
+			return nil, fmt.Errorf("%v: can't make it up: %v", ifname, err)
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return nil, fmt.Errorf("link %v still down after %v", ifname, linkUpAttempt)
+}
+
+// dhcp gets a lease on iface and configures its address and default route.
+func dhcp(iface netlink.Link) (dhcp4.Packet, error) {
+	conn, err := dhcp4client.NewPacketSock(iface.Attrs().Index)
+	if err != nil {
+		return nil, fmt.Errorf("client connection generation: %v", err)
+	}
+	client, err := dhcp4client.New(dhcp4client.HardwareAddr(iface.Attrs().HardwareAddr), dhcp4client.Connection(conn), dhcp4client.Timeout(time.Duration(*timeout)*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var packet dhcp4.Packet
+	var ok bool
+	for i := 0; i < *retry && !ok; i++ {
+		if ok, packet, err = client.Request(); err != nil {
+			log.Printf("DHCP request: %v", err)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: no DHCP lease", iface.Attrs().Name)
+	}
+	debug("Got %v", packet.YIAddr())
+
+	o := packet.ParseOptions()
+	mask, ok := o[dhcp4.OptionSubnetMask]
+	if !ok {
+		mask = net.IPv4Mask(255, 255, 255, 255)
+	}
+	addr := &netlink.Addr{IPNet: &net.IPNet{IP: packet.YIAddr(), Mask: mask}}
+	if err := netlink.AddrReplace(iface, addr); err != nil {
+		return nil, fmt.Errorf("add/replace %v to %v: %v", addr, iface.Attrs().Name, err)
+	}
+	if gw, ok := o[dhcp4.OptionRouter]; ok && len(gw) >= 4 {
+		r := &netlink.Route{LinkIndex: iface.Attrs().Index, Gw: net.IP(gw[:4])}
+		if err := netlink.RouteReplace(r); err != nil {
+			return nil, fmt.Errorf("%s: add %s: %v", iface.Attrs().Name, r, err)
+		}
+	}
+	return packet, nil
+}
+
+// cstring returns b up to its first NUL.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// bootFile returns the URL of the boot file the DHCP server gave us.
+func bootFile(p dhcp4.Packet) (*url.URL, error) {
+	o := p.ParseOptions()
+	file := cstring(o[dhcp4.OptionBootFileName])
+	if file == "" && len(p) >= 236 {
+		file = cstring(p[108:236])
+	}
+	if file == "" {
+		return nil, fmt.Errorf("the DHCP server gave no boot file")
+	}
+	if u, err := url.Parse(file); err == nil && u.Scheme != "" {
+		return u, nil
+	}
+
+	server := cstring(o[dhcp4.OptionTFTPServerName])
+	if server == "" && !p.SIAddr().IsUnspecified() {
+		server = p.SIAddr().String()
+	}
+	if id, ok := o[dhcp4.OptionServerIdentifier]; server == "" && ok && len(id) == 4 {
+		server = net.IP(id).String()
+	}
+	if server == "" {
+		return nil, fmt.Errorf("the DHCP server gave no TFTP server")
+	}
+	return &url.URL{Scheme: "tftp", Host: server, Path: "/" + strings.TrimPrefix(file, "/")}, nil
+}
+
+// fetch gets the file u names, by TFTP or HTTP.
+func fetch(u *url.URL) ([]byte, error) {
+	debug("Fetching %v", u)
+	switch u.Scheme {
+	case "tftp":
+		var b bytes.Buffer
+		if err := tftp.Get(u.Host, strings.TrimPrefix(u.Path, "/"), &b); err != nil {
+			return nil, fmt.Errorf("%v: %v", u, err)
+		}
+		return b.Bytes(), nil
+	case "http", "https":
+		r, err := http.Get(u.String())
+		if err != nil {
+			return nil, err
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%v: %v", u, r.Status)
+		}
+		return ioutil.ReadAll(r.Body)
+	}
+	return nil, fmt.Errorf("%v: scheme %q is not supported", u, u.Scheme)
+}
+
+// label finds what to boot from the boot file at u, and returns it with
+// the URL the names in it are relative to.
+func label(u *url.URL, mac net.HardwareAddr, ip net.IP) (*pxe.Label, error) {
+	b, err := fetch(u)
+	if err != nil {
+		return nil, err
+	}
+	if pxe.IsIPXE(b) {
+		return pxe.ParseIPXE(bytes.NewReader(b))
+	}
+	for _, name := range pxe.ConfigFiles(mac, ip) {
+		cu := u.ResolveReference(&url.URL{Path: name})
+		b, err := fetch(cu)
+		if err != nil {
+			debug("%v", err)
+			continue
+		}
+		log.Printf("Using %v", cu)
+		c, err := pxe.ParseConfig(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", cu, err)
+		}
+		return c.Boot()
+	}
+	return nil, fmt.Errorf("%v is not an iPXE script, and no pxelinux configuration was found", u)
+}
+
+// download fetches name, relative to base, into a temporary file.
+func download(base *url.URL, name string) (*os.File, error) {
+	ref, err := url.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := fetch(base.ResolveReference(ref))
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile("", "pxeboot")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func pxeboot() error {
+	iface, err := ifup(*ifName)
+	if err != nil {
+		return err
+	}
+	p, err := dhcp(iface)
+	if err != nil {
+		return err
+	}
+	u, err := bootFile(p)
+	if err != nil {
+		return err
+	}
+	log.Printf("Boot file is %v", u)
+
+	l, err := label(u, iface.Attrs().HardwareAddr, p.YIAddr())
+	if err != nil {
+		return err
+	}
+	log.Printf("Booting %s with initrd %q and command line %q", l.Kernel, l.Initrd, l.Cmdline)
+
+	kernel, err := download(u, l.Kernel)
+	if err != nil {
+		return err
+	}
+	defer kernel.Close()
+	var initrd *os.File
+	if l.Initrd != "" {
+		if initrd, err = download(u, l.Initrd); err != nil {
+			return err
+		}
+		defer initrd.Close()
+	}
+
+	if *dryRun {
+		return nil
+	}
+	if err := kexec.FileLoad(kernel, initrd, l.Cmdline); err != nil {
+		return err
+	}
+	return kexec.Reboot()
+}
+
+func main() {
+	flag.Parse()
+	if *verbose {
+		debug = log.Printf
+	}
+	// The DHCP package panics if there is no randomness yet; see
+	// dhclient.
+	if n, err := rand.Read([]byte{0}); err != nil || n != 1 {
+		log.Fatalf("The random number generator is not up")
+	}
+	if err := pxeboot(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/d2g/dhcp4"
+)
+
+func TestBootFile(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		siaddr  net.IP
+		file    string
+		options map[dhcp4.OptionCode]string
+		want    string
+	}{
+		{
+			name:   "next server and file field",
+			siaddr: net.IPv4(192, 0, 2, 1),
+			file:   "pxelinux.0",
+			want:   "tftp://192.0.2.1/pxelinux.0",
+		},
+		{
+			name:   "options",
+			siaddr: net.IPv4(192, 0, 2, 1),
+			options: map[dhcp4.OptionCode]string{
+				dhcp4.OptionTFTPServerName: "boot.example.com",
+				dhcp4.OptionBootFileName:   "/bios/pxelinux.0",
+			},
+			want: "tftp://boot.example.com/bios/pxelinux.0",
+		},
+		{
+			name:    "URL",
+			options: map[dhcp4.OptionCode]string{dhcp4.OptionBootFileName: "http://192.0.2.1/boot.ipxe"},
+			want:    "http://192.0.2.1/boot.ipxe",
+		},
+		{
+			name:    "server identifier",
+			options: map[dhcp4.OptionCode]string{dhcp4.OptionBootFileName: "lpxelinux.0", dhcp4.OptionServerIdentifier: "\xc0\x00\x02\x02"},
+			want:    "tftp://192.0.2.2/lpxelinux.0",
+		},
+	} {
+		p := dhcp4.NewPacket(dhcp4.BootReply)
+		if tt.siaddr != nil {
+			p.SetSIAddr(tt.siaddr)
+		}
+		copy(p[108:236], tt.file)
+		for c, v := range tt.options {
+			p.AddOption(c, []byte(v))
+		}
+		u, err := bootFile(p)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, u, tt.want)
+		}
+	}
+
+	if _, err := bootFile(dhcp4.NewPacket(dhcp4.BootReply)); err == nil {
+		t.Errorf("no boot file: got nil, want error")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	files := map[string]string{
+		"/boot.ipxe":                 "#!ipxe\nkernel vmlinuz quiet\ninitrd /images/initrd\nboot\n",
+		"/pxe/pxelinux.0":            "\x7fELF",
+		"/pxe/pxelinux.cfg/C0000202": "DEFAULT u-root\nLABEL u-root\nKERNEL bzImage\nAPPEND console=ttyS0\n",
+		"/pxe/pxelinux.cfg/default":  "DEFAULT wrong\n",
+		"/vmlinuz":                   "kernel",
+		"/pxe/bzImage":               "other kernel",
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(f))
+	}))
+	defer s.Close()
+
+	mac, _ := net.ParseMAC("52:54:00:12:34:56")
+	ip := net.IPv4(192, 0, 2, 2)
+	for _, tt := range []struct {
+		file   string
+		kernel string
+		want   string
+	}{
+		{"/boot.ipxe", "kernel", "quiet"},
+		{"/pxe/pxelinux.0", "other kernel", "console=ttyS0"},
+	} {
+		u, _ := url.Parse(s.URL + tt.file)
+		l, err := label(u, mac, ip)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if l.Cmdline != tt.want {
+			t.Errorf("%s: command line is %q, want %q", tt.file, l.Cmdline, tt.want)
+		}
+		f, err := download(u, l.Kernel)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil || string(b) != tt.kernel {
+			t.Errorf("%s: kernel is %q, %v, want %q", tt.file, b, err, tt.kernel)
+		}
+	}
+}
@@ -5,26 +5,48 @@
 // readlink display value of symbolic link file.
 //
 // Synopsis:
-//     readlink [OPTIONS] FILE
+//     readlink [OPTIONS] FILE...
+//
+// Description:
+//     Without -f, -e or -m, readlink prints what each FILE, a symbolic
+//     link, points to. With them, it prints the canonical name of FILE:
+//     its absolute path, with no ., .. or symbolic links in it.
 //
 // Options:
-//     -f: follow
-//     -v: verbose
+//     -f: canonicalize; all but the last part of the name must exist
+//     -e: canonicalize; all of the name must exist
+//     -m: canonicalize; none of the name need exist
+//     -n: do not print a newline after the name
+//     -z: print a NUL after each name, rather than a newline
+//     -q, -s: do not report errors, which is the default
+//     -v: report errors
 //
+// Example:
+//     $ readlink /proc/self/exe
+//     /bbin/bb
+//     $ readlink -f /bin/../lib/./modules
+//     /lib/modules
 package main
 
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 )
 
-const cmd = "readlink [-fv] FILE"
+const cmd = "readlink [-efmnqsvz] FILE..."
 
 var (
-	follow  = flag.Bool("f", false, "follow recursively")
-	verbose = flag.Bool("v", false, "report error messages")
+	follow   = flag.Bool("f", false, "canonicalize; all but the last part of the name must exist")
+	existing = flag.Bool("e", false, "canonicalize; all of the name must exist")
+	missing  = flag.Bool("m", false, "canonicalize; none of the name need exist")
+	noLine   = flag.Bool("n", false, "do not print a newline after the name")
+	zero     = flag.Bool("z", false, "print a NUL after each name")
+	verbose  = flag.Bool("v", false, "report error messages")
 )
 
 func init() {
@@ -33,34 +55,129 @@ func init() {
 		os.Args[0] = cmd
 		defUsage()
 	}
-	flag.Parse()
+	for _, n := range []string{"q", "s"} {
+		flag.Bool(n, false, "do not report errors")
+	}
 }
 
-func readLink(file string) error {
-	path, err := os.Readlink(file)
-	if err != nil {
-		return err
-	}
+// How much of a name must exist to canonicalize it.
+type mode int
+
+const (
+	allButLast mode = iota
+	all
+	none
+)
+
+// maxLinks is how many symbolic links canonicalize follows before it
+// gives up, as the kernel does.
+const maxLinks = 40
 
-	if *follow {
-		path, err = filepath.EvalSymlinks(file)
+// canonicalize returns the absolute name of name, with no ., .., or
+// symbolic links in it. m says how much of it must exist.
+func canonicalize(name string, m mode) (string, error) {
+	pathErr := func(err error) error {
+		return &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	rest := name
+	if !filepath.IsAbs(rest) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		rest = wd + "/" + rest
+	}
+	resolved := "/"
+	links := 0
+	for rest != "" {
+		var part string
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			part, rest = rest[:i], rest[i+1:]
+		} else {
+			part, rest = rest, ""
+		}
+		last := strings.Trim(rest, "/") == ""
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		fi, err := os.Lstat(next)
+		switch {
+		case err != nil && m == none:
+			// Under a name that does not exist, or is not a
+			// directory, the rest of the name does not exist.
+		case err != nil && os.IsNotExist(err):
+			if m == all || !last {
+				return "", pathErr(syscall.ENOENT)
+			}
+		case err != nil:
+			return "", err
+		case fi.Mode()&os.ModeSymlink != 0:
+			if links++; links > maxLinks {
+				return "", pathErr(syscall.ELOOP)
+			}
+			target, err := os.Readlink(next)
+			if err != nil {
+				return "", err
+			}
+			if filepath.IsAbs(target) {
+				resolved = "/"
+			}
+			rest = target + "/" + rest
+			continue
+		case !fi.IsDir() && !last && m != none:
+			return "", pathErr(syscall.ENOTDIR)
+		}
+		resolved = next
 	}
+	return resolved, nil
+}
 
-	fmt.Printf("%s\n", path)
-	return err
+// readLink returns what file points to, or with -e, -f or -m, its
+// canonical name.
+func readLink(file string) (string, error) {
+	switch {
+	case *existing:
+		return canonicalize(file, all)
+	case *missing:
+		return canonicalize(file, none)
+	case *follow:
+		return canonicalize(file, allButLast)
+	}
+	return os.Readlink(file)
 }
 
-func main() {
+func run(w, errw io.Writer, files []string) int {
 	var exitStatus int
-
-	for _, file := range flag.Args() {
-		if err := readLink(file); err != nil {
+	end := "\n"
+	switch {
+	case *zero:
+		end = "\x00"
+	case *noLine:
+		end = ""
+	}
+	for _, file := range files {
+		path, err := readLink(file)
+		if err != nil {
 			if *verbose {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
+				fmt.Fprintf(errw, "%v\n", err)
 			}
 			exitStatus = 1
+			continue
 		}
+		fmt.Fprintf(w, "%s%s", path, end)
+	}
+	if len(files) == 0 {
+		exitStatus = 1
 	}
+	return exitStatus
+}
 
-	os.Exit(exitStatus)
+func main() {
+	flag.Parse()
+	os.Exit(run(os.Stdout, os.Stderr, flag.Args()))
 }
@@ -40,6 +40,13 @@ func TestReadlink(t *testing.T) {
 		t.Error(err)
 	}
 
+	// The canonical name of the directory, in case the temporary
+	// directory is behind a symlink.
+	realDir, err := filepath.EvalSymlinks(testDir)
+	if err != nil {
+		t.Error(err)
+	}
+
 	var tests = []test{
 		{
 			flags:      []string{},
@@ -53,10 +60,52 @@ func TestReadlink(t *testing.T) {
 			exitStatus: 1,
 		}, {
 			flags:      []string{"-f", "f2"},
-			out:        "",
+			out:        realDir + "/f2\n",
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-f", "multilinks", "./../readLinkDir/f1symlink"},
+			out:        realDir + "/f1\n" + realDir + "/f1\n",
 			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-f", "-n", "nothere"},
+			out:        realDir + "/nothere",
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-v", "-f", "nothere/f1"},
+			out:        "",
+			stdErr:     "readlink nothere/f1: no such file or directory\n",
 			exitStatus: 1,
 		},
+		{
+			flags:      []string{"-v", "-e", "nothere"},
+			out:        "",
+			stdErr:     "readlink nothere: no such file or directory\n",
+			exitStatus: 1,
+		},
+		{
+			flags:      []string{"-v", "-e", "f1/f2"},
+			out:        "",
+			stdErr:     "readlink f1/f2: not a directory\n",
+			exitStatus: 1,
+		},
+		{
+			flags:      []string{"-m", "nothere/../f1symlink/x"},
+			out:        realDir + "/f1/x\n",
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-z", "-e", "f1", "f2"},
+			out:        realDir + "/f1\x00" + realDir + "/f2\x00",
+			stdErr:     "",
+			exitStatus: 0,
+		},
 		{
 			flags:      []string{"f1symlink"},
 			out:        "f1\n",
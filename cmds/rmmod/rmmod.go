@@ -8,7 +8,8 @@
 //	rmmod name
 //
 // Description:
-//	rmmod is a clone of rmmod(8)
+//	rmmod is a clone of rmmod(8). name may also be the file of the
+//	module, e.g. e1000e.ko.
 //
 // Author:
 //     Roland Kammerer <dev.rck@gmail.com>
@@ -28,7 +29,7 @@ func main() {
 	}
 
 	for _, modname := range os.Args[1:] {
-		if err := kmodule.Delete(modname, syscall.O_NONBLOCK); err != nil {
+		if err := kmodule.Delete(kmodule.Name(modname), syscall.O_NONBLOCK); err != nil {
 			log.Fatalf("rmmod: %v", err)
 		}
 	}
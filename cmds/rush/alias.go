@@ -0,0 +1,148 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Aliases.
+//
+// Synopsis:
+//     alias [NAME[=VALUE]]...
+//     unalias [-a] [NAME]...
+//
+// Description:
+//     If the first word of a command is the NAME of an alias, it is replaced
+//     by the commands in VALUE, and the rest of the command's arguments are
+//     added to the last of them. VALUE may hold pipes, && and ||, but not
+//     ';' or newlines. Aliases in VALUE are expanded too, except for the
+//     alias being expanded.
+//
+//     alias defines NAME as VALUE, or prints the alias NAME. Without
+//     arguments, it prints all aliases. unalias removes aliases; with -a,
+//     it removes all of them.
+//
+// Example:
+//     alias 'll=ls -l'
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var aliases = map[string]string{}
+
+func init() {
+	addBuiltIn("alias", "define or print aliases", alias)
+	addBuiltIn("unalias", "remove aliases", unalias)
+}
+
+// parseAlias parses the value of an alias.
+func parseAlias(v string) ([]*Command, error) {
+	l, t, err := getCommand(bufio.NewReader(strings.NewReader(v)))
+	if err != nil {
+		return nil, err
+	}
+	if t != "EOF" && t != "EOL" {
+		return nil, errors.New("only one line of commands is allowed")
+	}
+	if len(l) == 0 {
+		return nil, errors.New("no command")
+	}
+	return l, nil
+}
+
+// expandAliases replaces the aliases which start commands in l.
+// Aliases in seen are not expanded again.
+func expandAliases(l []*Command, seen map[string]bool) ([]*Command, error) {
+	var out []*Command
+	for _, c := range l {
+		if c.group != nil {
+			out = append(out, c)
+			continue
+		}
+		a := c.args[0]
+		v, ok := aliases[a.val]
+		if a.mod != "ARG" || !ok || seen[a.val] {
+			out = append(out, c)
+			continue
+		}
+		e, err := parseAlias(v)
+		if err != nil {
+			return nil, fmt.Errorf("alias %v: %v", a.val, err)
+		}
+		seen[a.val] = true
+		e, err = expandAliases(e, seen)
+		delete(seen, a.val)
+		if err != nil {
+			return nil, err
+		}
+		// The last command gets the arguments, redirects and link
+		// of the command we replace.
+		last := e[len(e)-1]
+		last.args = append(last.args, c.args[1:]...)
+		for fd, r := range c.fdmap {
+			last.fdmap[fd] = r
+		}
+		if c.doc != nil {
+			last.doc = c.doc
+		}
+		last.link = c.link
+		last.bg = last.bg || c.bg
+		e[0].timed = e[0].timed || c.timed
+		out = append(out, e...)
+	}
+	return out, nil
+}
+
+func alias(c *Command) error {
+	if len(c.argv) == 0 {
+		var names []string
+		for n := range aliases {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(c.Stdout, "alias %v='%v'\n", n, aliases[n])
+		}
+		return nil
+	}
+	for _, a := range c.argv {
+		i := strings.Index(a, "=")
+		if i < 0 {
+			v, ok := aliases[a]
+			if !ok {
+				return fmt.Errorf("alias: %v: not found", a)
+			}
+			fmt.Fprintf(c.Stdout, "alias %v='%v'\n", a, v)
+			continue
+		}
+		n, v := a[:i], a[i+1:]
+		if n == "" || strings.ContainsAny(n, punct+"/=") {
+			return fmt.Errorf("alias: %v: invalid name", n)
+		}
+		if _, err := parseAlias(v); err != nil {
+			return fmt.Errorf("alias %v: %v", n, err)
+		}
+		aliases[n] = v
+	}
+	return nil
+}
+
+func unalias(c *Command) error {
+	if len(c.argv) == 1 && c.argv[0] == "-a" {
+		aliases = map[string]string{}
+		return nil
+	}
+	if len(c.argv) == 0 {
+		return errors.New("usage: unalias [-a] [NAME]...")
+	}
+	for _, n := range c.argv {
+		if _, ok := aliases[n]; !ok {
+			return fmt.Errorf("unalias: %v: not found", n)
+		}
+		delete(aliases, n)
+	}
+	return nil
+}
@@ -7,18 +7,50 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
 )
 
 func init() {
-	addBuiltIn("cd", cd)
+	addBuiltIn("cd", "change the working directory", cd)
+	addBuiltIn("pwd", "print the working directory", pwd)
 }
 
 func cd(c *Command) error {
-	if len(c.argv) != 1 {
-		return errors.New("usage: cd one-path")
+	var dir string
+	switch len(c.argv) {
+	case 0:
+		if dir = os.Getenv("HOME"); dir == "" {
+			return errors.New("cd: HOME not set")
+		}
+	case 1:
+		dir = c.argv[0]
+	default:
+		return errors.New("usage: cd [one-path]")
 	}
 
-	err := os.Chdir(c.argv[0])
+	old, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	// Keep PWD and OLDPWD up to date for the benefit of our children.
+	if wd, err := os.Getwd(); err == nil {
+		os.Setenv("PWD", wd)
+	}
+	if old != "" {
+		os.Setenv("OLDPWD", old)
+	}
+	return nil
+}
+
+func pwd(c *Command) error {
+	if len(c.argv) != 0 {
+		return errors.New("usage: pwd")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.Stdout, wd)
 	return err
 }
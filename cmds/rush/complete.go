@@ -0,0 +1,162 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tab completion.
+//
+// Description:
+//     Typing tab in the line editor completes the word before the cursor.
+//     The first word of a command is completed from the builtins and the
+//     executables in $PATH, anything else from file names. If the word can
+//     not be completed any further, a second tab lists the candidates.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A pathDir caches the executables in a $PATH directory. The directory
+// is only read again if it changes.
+type pathDir struct {
+	mtime int64
+	names []string
+}
+
+var pathCache = map[string]*pathDir{}
+
+// executables returns the names of executables in dir.
+func executables(dir string) []string {
+	fi, err := os.Stat(dir)
+	if err != nil || !fi.IsDir() {
+		return nil
+	}
+	mtime := fi.ModTime().UnixNano()
+	if d, ok := pathCache[dir]; ok && d.mtime == mtime {
+		return d.names
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	fis, err := f.Readdir(-1)
+	if err != nil {
+		return nil
+	}
+	d := &pathDir{mtime: mtime}
+	for _, fi := range fis {
+		// Busybox commands are symlinks, so look at what they point to.
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if fi, err = os.Stat(filepath.Join(dir, fi.Name())); err != nil {
+				continue
+			}
+		}
+		if fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+			d.names = append(d.names, fi.Name())
+		}
+	}
+	pathCache[dir] = d
+	return d.names
+}
+
+// completeCommand returns builtins and executables in $PATH starting with prefix.
+func completeCommand(prefix string) []string {
+	var m []string
+	for n, b := range builtins {
+		if !b.fork && strings.HasPrefix(n, prefix) {
+			m = append(m, n)
+		}
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		for _, n := range executables(dir) {
+			if strings.HasPrefix(n, prefix) {
+				m = append(m, n)
+			}
+		}
+	}
+	return m
+}
+
+// completeFile returns file names starting with prefix. Directories
+// have a / appended.
+func completeFile(prefix string) []string {
+	dir, base := filepath.Split(prefix)
+	d := dir
+	if d == "" {
+		d = "."
+	}
+	f, err := os.Open(d)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil
+	}
+	var m []string
+	for _, n := range names {
+		if !strings.HasPrefix(n, base) {
+			continue
+		}
+		// Dot files only show up if asked for.
+		if strings.HasPrefix(n, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(d, n)); err == nil && fi.IsDir() {
+			n += "/"
+		}
+		m = append(m, dir+n)
+	}
+	return m
+}
+
+// complete returns the candidates for completing the word which ends the
+// line. It also returns where that word starts.
+func complete(line string) (int, []string) {
+	start := strings.LastIndexAny(line, punct) + 1
+	word := line[start:]
+	// The word is a command name if only separators come before it.
+	cmd := strings.TrimRight(line[:start], " \t")
+	isCommand := cmd == "" || strings.LastIndexAny(cmd, "|&;") == len(cmd)-1
+	var m []string
+	if isCommand && !strings.Contains(word, "/") {
+		m = completeCommand(word)
+	} else {
+		m = completeFile(word)
+	}
+	sort.Strings(m)
+	// Builtins may also be in $PATH.
+	u := m[:0]
+	for i, s := range m {
+		if i == 0 || s != m[i-1] {
+			u = append(u, s)
+		}
+	}
+	return start, u
+}
+
+// commonPrefix returns the longest common prefix of the strings in m.
+func commonPrefix(m []string) string {
+	if len(m) == 0 {
+		return ""
+	}
+	p := m[0]
+	for _, s := range m[1:] {
+		for !strings.HasPrefix(s, p) {
+			p = p[:len(p)-1]
+		}
+	}
+	// Don't split a character in half.
+	for !utf8.ValidString(p) {
+		p = p[:len(p)-1]
+	}
+	return p
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// completeDir makes a directory to complete in, with executables in bin,
+// and changes to it. It returns a function to undo that.
+func completeDir(t *testing.T) func() {
+	d, err := ioutil.TempDir("", "TestComplete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	undo := func() {
+		os.Chdir(wd)
+		os.Setenv("PATH", path)
+		os.RemoveAll(d)
+	}
+	for _, f := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"bin/zzfoo", 0755},
+		{"bin/zzfob", 0755},
+		{"bin/zzbar", 0700},
+		{"bin/zznoexec", 0644},
+		{"bin/history", 0755},
+		{"alpha", 0644},
+		{"beta", 0644},
+		{".hidden", 0644},
+		{"alps/one", 0644},
+		{"alps/two", 0644},
+	} {
+		name := filepath.Join(d, f.name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			undo()
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, nil, f.mode); err != nil {
+			undo()
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("zzfoo", filepath.Join(d, "bin", "zzlink")); err != nil {
+		undo()
+		t.Fatal(err)
+	}
+	if err := os.Chdir(d); err != nil {
+		undo()
+		t.Fatal(err)
+	}
+	os.Setenv("PATH", filepath.Join(d, "bin"))
+	return undo
+}
+
+func TestComplete(t *testing.T) {
+	defer completeDir(t)()
+	for _, tt := range []struct {
+		line  string
+		start int
+		want  []string
+	}{
+		// Commands.
+		{"zzf", 0, []string{"zzfob", "zzfoo"}},
+		{"zz", 0, []string{"zzbar", "zzfob", "zzfoo", "zzlink"}},
+		{"zzb", 0, []string{"zzbar"}},
+		{"zzno", 0, nil},
+		{"ls | zzb", 5, []string{"zzbar"}},
+		{"true && zzl", 8, []string{"zzlink"}},
+		{"  zzfoo", 2, []string{"zzfoo"}},
+		// history is a builtin and in $PATH, but listed once.
+		{"histor", 0, []string{"history"}},
+		// Paths.
+		{"cat al", 4, []string{"alpha", "alps/"}},
+		{"cat be", 4, []string{"beta"}},
+		{"cat zz", 4, nil},
+		{"cat .h", 4, []string{".hidden"}},
+		{"cat ", 4, []string{"alpha", "alps/", "beta", "bin/"}},
+		{"cat <al", 5, []string{"alpha", "alps/"}},
+		{"./bin/zzf", 0, []string{"./bin/zzfob", "./bin/zzfoo"}},
+		// In a directory.
+		{"cat alps/", 4, []string{"alps/one", "alps/two"}},
+		{"cat alps/o", 4, []string{"alps/one"}},
+		{"cat alps/x", 4, nil},
+		{"cat nothere/", 4, nil},
+	} {
+		start, got := complete(tt.line)
+		if len(got) == 0 {
+			got = nil
+		}
+		if start != tt.start || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q): got %d, %q, want %d, %q", tt.line, start, got, tt.start, tt.want)
+		}
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		in   []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"alpha"}, "alpha"},
+		{[]string{"alpha", "alps/"}, "alp"},
+		{[]string{"alpha", "beta"}, ""},
+		{[]string{"abc", "ab", "abd"}, "ab"},
+		{[]string{"héllo", "hèllo"}, "h"},
+	} {
+		if got := commonPrefix(tt.in); got != tt.want {
+			t.Errorf("commonPrefix(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditComplete(t *testing.T) {
+	defer completeDir(t)()
+	defer setHistory()()
+	for _, tt := range []struct {
+		keys string
+		want string
+	}{
+		// As far as the candidates agree.
+		{"cat al\t\r", "cat alp"},
+		// One candidate is finished with a space.
+		{"cat be\t\r", "cat beta "},
+		{"zzb\t-x\r", "zzbar -x"},
+		// But not a directory.
+		{"cat alps\tone\r", "cat alps/one"},
+		// Nothing to complete to.
+		{"cat zz\t\t\r", "cat zz"},
+		// A second tab lists the candidates, leaving the line as it is.
+		{"zzf\t\t\r", "zzfo"},
+		// In the middle of the line.
+		{"cat be x\x02\x02\t\r", "cat beta  x"},
+	} {
+		got, err := testEditor(t, tt.keys).edit()
+		if err != nil || got != tt.want {
+			t.Errorf("edit(%q): got %q, %v, want %q, nil", tt.keys, got, err, tt.want)
+		}
+	}
+}
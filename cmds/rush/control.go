@@ -0,0 +1,301 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Control structures.
+//
+// Synopsis:
+//     if LIST; then LIST; [elif LIST; then LIST;]... [else LIST;] fi
+//     while LIST; do LIST; done
+//     until LIST; do LIST; done
+//
+// Description:
+//     A LIST is one or more commands, separated by newlines or ';'. A LIST
+//     succeeds if its last command does. if runs the first branch whose
+//     condition succeeds; while runs its body as long as its condition
+//     succeeds and until as long as it fails. Commands failing in a
+//     condition are not reported.
+//
+// Example:
+//     until mount /dev/sda1 /mnt; do
+//         sleep 1
+//     done
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A statement is a list of pipelines joined by && and ||, or a
+// control structure.
+type statement interface{}
+
+type list []*Command
+
+type ifStmt struct {
+	cond, then, els []statement
+}
+
+type whileStmt struct {
+	cond, body []statement
+	// until loops while cond fails.
+	until bool
+}
+
+var keywords = map[string]bool{
+	"if":    true,
+	"then":  true,
+	"elif":  true,
+	"else":  true,
+	"fi":    true,
+	"while": true,
+	"until": true,
+	"do":    true,
+	"done":  true,
+}
+
+// A parser turns the commands read by getCommand into statements.
+type parser struct {
+	b *bufio.Reader
+	// pending is what is left of a list after we took a keyword off it.
+	pending []*Command
+	eof     bool
+	// semi is set if the last list read ended in a ';'.
+	semi bool
+}
+
+func newParser(b *bufio.Reader) *parser {
+	return &parser{b: b}
+}
+
+// keyword returns the keyword l starts with, or "".
+func keyword(l []*Command) string {
+	if len(l) == 0 || len(l[0].args) == 0 {
+		return ""
+	}
+	a := l[0].args[0]
+	if a.mod != "ARG" || !keywords[a.val] {
+		return ""
+	}
+	return a.val
+}
+
+// next returns the next list of commands, which may be empty.
+// At the end of the input, it returns io.EOF.
+func (p *parser) next() ([]*Command, error) {
+	if p.pending != nil {
+		l := p.pending
+		p.pending = nil
+		return l, nil
+	}
+	if p.eof {
+		return nil, io.EOF
+	}
+	l, t, err := getCommand(p.b)
+	p.eof, p.semi = t == "EOF", t == "SEMI"
+	if err != nil {
+		return nil, err
+	}
+	timePipelines(l)
+	return expandAliases(l, map[string]bool{})
+}
+
+// skip drops the keyword l starts with. Whatever follows it is returned
+// by the next call to next.
+func (p *parser) skip(l []*Command) error {
+	kw := l[0].args[0].val
+	l[0].args = l[0].args[1:]
+	if len(l[0].args) > 0 {
+		p.pending = l
+		return nil
+	}
+	if len(l) > 1 || l[0].link != "" || l[0].bg {
+		return fmt.Errorf("syntax error after %v", kw)
+	}
+	return nil
+}
+
+// more is called before reading a list which continues a statement.
+// If that needs a new line, we prompt for it.
+func (p *parser) more() {
+	if p.pending == nil && !p.semi && interactive {
+		showPrompt("> ")
+	}
+}
+
+// statement parses one statement. An empty line is a nil statement.
+func (p *parser) statement() (statement, error) {
+	l, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if len(l) == 0 {
+		if p.eof {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	switch kw := keyword(l); kw {
+	case "":
+		return list(l), nil
+	case "if":
+		if err := p.skip(l); err != nil {
+			return nil, err
+		}
+		return p.ifStmt()
+	case "while", "until":
+		if err := p.skip(l); err != nil {
+			return nil, err
+		}
+		return p.whileStmt(kw == "until")
+	}
+	return nil, fmt.Errorf("syntax error: unexpected %v", l[0].args[0].val)
+}
+
+// block parses statements up to one of the keywords in end, which it returns.
+func (p *parser) block(end ...string) ([]statement, string, error) {
+	var b []statement
+	for {
+		p.more()
+		l, err := p.next()
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("syntax error: unexpected end of file, expecting %v", end[0])
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if len(l) == 0 {
+			continue
+		}
+		kw := keyword(l)
+		for _, e := range end {
+			if kw == e {
+				return b, kw, p.skip(l)
+			}
+		}
+		p.pending = l
+		s, err := p.statement()
+		if err != nil {
+			return nil, "", err
+		}
+		b = append(b, s)
+	}
+}
+
+// ifStmt parses what follows an if or elif, up to and including the fi.
+func (p *parser) ifStmt() (statement, error) {
+	var s ifStmt
+	var err error
+	if s.cond, _, err = p.block("then"); err != nil {
+		return nil, err
+	}
+	var kw string
+	if s.then, kw, err = p.block("fi", "elif", "else"); err != nil {
+		return nil, err
+	}
+	switch kw {
+	case "elif":
+		elif, err := p.ifStmt()
+		if err != nil {
+			return nil, err
+		}
+		s.els = []statement{elif}
+	case "else":
+		if s.els, _, err = p.block("fi"); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// whileStmt parses what follows a while or until, up to and including the done.
+func (p *parser) whileStmt(until bool) (statement, error) {
+	s := whileStmt{until: until}
+	var err error
+	if s.cond, _, err = p.block("do"); err != nil {
+		return nil, err
+	}
+	if s.body, _, err = p.block("done"); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// run runs a statement and returns the error of the last command it ran.
+// If quiet is set, commands which run but fail are not reported.
+func run(s statement, quiet bool) error {
+	switch s := s.(type) {
+	case list:
+		return runList(s, quiet)
+	case *ifStmt:
+		if runBlock(s.cond, true) == nil {
+			return runBlock(s.then, quiet)
+		}
+		if len(s.els) > 0 {
+			return runBlock(s.els, quiet)
+		}
+		// With no branch taken, $? is 0, not that of the condition.
+		status = 0
+		return nil
+	case *whileStmt:
+		var err error
+		// $? is that of the last command of the body, not of the
+		// condition which ended the loop, or 0 if the body never ran.
+		last := 0
+		for (runBlock(s.cond, true) == nil) != s.until {
+			err = runBlock(s.body, quiet)
+			last = status
+			// ^C ends the loop as well as the command.
+			if interrupted(err) {
+				break
+			}
+		}
+		status = last
+		return err
+	}
+	return fmt.Errorf("unknown statement %T", s)
+}
+
+func runBlock(b []statement, quiet bool) error {
+	var err error
+	for _, s := range b {
+		err = run(s, quiet)
+	}
+	return err
+}
+
+// runList runs pipelines joined by && and ||. A pipeline after && runs
+// only if $? is 0, and one after || only if it is not; one which does not
+// run leaves $? as it was for the next.
+func runList(cmds []*Command, quiet bool) error {
+	var err error
+	ps := pipelines(cmds)
+	for i, p := range ps {
+		if i > 0 {
+			switch ps[i-1][len(ps[i-1])-1].link {
+			case "&&":
+				if status != 0 {
+					continue
+				}
+			case "||":
+				if status == 0 {
+					continue
+				}
+			}
+		}
+		if err = command(p); err != nil {
+			if _, ok := err.(*exitError); !ok || !quiet {
+				fmt.Fprintf(stderr, "%v\n", err)
+			}
+		}
+		// With set -e, failures only count if nothing else
+		// depends on them.
+		if status != 0 && errexit && !quiet && i == len(ps)-1 {
+			os.Exit(status)
+		}
+	}
+	return err
+}
@@ -0,0 +1,412 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Line editing.
+//
+// Description:
+//     When rush reads from a terminal, lines can be edited before they are
+//     run:
+//         Left, Right, ^B, ^F   move the cursor
+//         Home, End, ^A, ^E     move to the start or end of the line
+//         Backspace, Delete     delete a character
+//         ^U, ^K                delete to the start or end of the line
+//         Up, Down, ^P, ^N      step through the history
+//         ^R                    search the history backwards
+//         Tab                   complete a command or file name
+//         ^C                    abandon the line
+//         ^D                    end of file on an empty line
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ctrlA     = 'A' - '@'
+	ctrlB     = 'B' - '@'
+	ctrlC     = 'C' - '@'
+	ctrlD     = 'D' - '@'
+	ctrlE     = 'E' - '@'
+	ctrlF     = 'F' - '@'
+	ctrlG     = 'G' - '@'
+	ctrlH     = 'H' - '@'
+	tab       = 'I' - '@'
+	ctrlK     = 'K' - '@'
+	ctrlN     = 'N' - '@'
+	ctrlP     = 'P' - '@'
+	ctrlR     = 'R' - '@'
+	ctrlU     = 'U' - '@'
+	esc       = 0x1b
+	backspace = 0x7f
+
+	// Keys which arrive as escape sequences are mapped
+	// to runes no terminal sends.
+	keyUp rune = utf8.MaxRune + 1 + iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// An editor is an io.Reader returning lines read from a terminal,
+// after the user is done editing them.
+type editor struct {
+	in  *os.File
+	out io.Writer
+	// prompt is printed before each line.
+	prompt string
+	// buf holds what is left of the last line.
+	buf []byte
+	// line is the line being edited and pos is the cursor position in it.
+	line []rune
+	pos  int
+}
+
+// ed is the line editor, if rush is reading from a terminal.
+var ed *editor
+
+// newEditor returns an editor for in, or an error if in is not a terminal.
+func newEditor(in *os.File, out io.Writer) (*editor, error) {
+	if !isTerminal(in) {
+		return nil, fmt.Errorf("%v is not a terminal", in.Name())
+	}
+	return &editor{in: in, out: out}, nil
+}
+
+func (e *editor) Read(p []byte) (int, error) {
+	if len(e.buf) == 0 {
+		l, err := e.readLine()
+		if err != nil {
+			return 0, err
+		}
+		e.buf = []byte(l + "\n")
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+// readRune reads one, possibly multibyte, character.
+func (e *editor) readRune() (rune, error) {
+	var b [utf8.UTFMax]byte
+	if _, err := e.in.Read(b[:1]); err != nil {
+		return 0, err
+	}
+	n := 1
+	for ; n < len(b) && !utf8.FullRune(b[:n]); n++ {
+		if _, err := e.in.Read(b[n : n+1]); err != nil {
+			return 0, err
+		}
+	}
+	r, _ := utf8.DecodeRune(b[:n])
+	return r, nil
+}
+
+// readKey reads a key, turning the escape sequences
+// sent by cursor keys into single runes.
+func (e *editor) readKey() (rune, error) {
+	r, err := e.readRune()
+	if err != nil || r != esc {
+		return r, err
+	}
+	if r, err = e.readRune(); err != nil {
+		return 0, err
+	}
+	if r != '[' && r != 'O' {
+		return r, nil
+	}
+	// Collect parameters up to the final byte of the sequence.
+	var param string
+	for {
+		if r, err = e.readRune(); err != nil {
+			return 0, err
+		}
+		if r < '0' || r > '?' {
+			break
+		}
+		param += string(r)
+	}
+	switch r {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch param {
+		case "1", "7":
+			return keyHome, nil
+		case "4", "8":
+			return keyEnd, nil
+		case "3":
+			return keyDelete, nil
+		}
+	}
+	// Anything else is ignored.
+	return 0, nil
+}
+
+// refresh redraws the line and puts the cursor where it belongs.
+func (e *editor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.line))
+	if n := len(e.line) - e.pos; n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n)
+	}
+}
+
+// setLine replaces the line being edited and moves the cursor to its end.
+func (e *editor) setLine(s string) {
+	e.line = []rune(s)
+	e.pos = len(e.line)
+	e.refresh()
+}
+
+// readLine reads and edits a line in raw mode.
+func (e *editor) readLine() (string, error) {
+	restore, err := rawMode(e.in)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	return e.edit()
+}
+
+// edit reads keys and edits a line until it is done.
+func (e *editor) edit() (string, error) {
+	fmt.Fprint(e.out, e.prompt)
+	e.line, e.pos = nil, 0
+	// hist is where we are in the history; saved is the line we
+	// were editing before we started moving through it.
+	hist, saved := len(history), ""
+	var tabbed bool
+	for {
+		r, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+	again:
+		// A second tab in a row lists what we could complete to.
+		if r == tab {
+			e.complete(tabbed)
+			tabbed = true
+			continue
+		}
+		tabbed = false
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			l := string(e.line)
+			addHistory(strings.TrimSpace(l))
+			return l, nil
+		case ctrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", nil
+		case ctrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			fallthrough
+		case keyDelete:
+			if e.pos < len(e.line) {
+				e.line = append(e.line[:e.pos], e.line[e.pos+1:]...)
+				e.refresh()
+			}
+		case backspace, ctrlH:
+			if e.pos > 0 {
+				e.line = append(e.line[:e.pos-1], e.line[e.pos:]...)
+				e.pos--
+				e.refresh()
+			}
+		case keyLeft, ctrlB:
+			if e.pos > 0 {
+				e.pos--
+				e.refresh()
+			}
+		case keyRight, ctrlF:
+			if e.pos < len(e.line) {
+				e.pos++
+				e.refresh()
+			}
+		case keyHome, ctrlA:
+			e.pos = 0
+			e.refresh()
+		case keyEnd, ctrlE:
+			e.pos = len(e.line)
+			e.refresh()
+		case ctrlU:
+			e.line = e.line[e.pos:]
+			e.pos = 0
+			e.refresh()
+		case ctrlK:
+			e.line = e.line[:e.pos]
+			e.refresh()
+		case keyUp, ctrlP:
+			if hist == 0 {
+				break
+			}
+			if hist == len(history) {
+				saved = string(e.line)
+			}
+			hist--
+			e.setLine(history[hist])
+		case keyDown, ctrlN:
+			if hist >= len(history) {
+				break
+			}
+			hist++
+			if hist == len(history) {
+				e.setLine(saved)
+			} else {
+				e.setLine(history[hist])
+			}
+		case ctrlR:
+			var accept bool
+			if r, accept, err = e.search(); err != nil {
+				return "", err
+			}
+			hist = len(history)
+			if accept {
+				r = '\r'
+			}
+			if r != 0 {
+				goto again
+			}
+		default:
+			if !unicode.IsPrint(r) {
+				break
+			}
+			e.line = append(e.line, 0)
+			copy(e.line[e.pos+1:], e.line[e.pos:])
+			e.line[e.pos] = r
+			e.pos++
+			if e.pos == len(e.line) {
+				fmt.Fprint(e.out, string(r))
+				break
+			}
+			e.refresh()
+		}
+	}
+}
+
+// complete completes the word before the cursor as far as possible.
+// If there is nothing to add and list is set, the candidates are printed.
+func (e *editor) complete(list bool) {
+	before := string(e.line[:e.pos])
+	start, m := complete(before)
+	if len(m) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+	word := before[start:]
+	add := commonPrefix(m)[len(word):]
+	if len(m) == 1 && !strings.HasSuffix(m[0], "/") {
+		add += " "
+	}
+	if add != "" {
+		a := []rune(add)
+		e.line = append(e.line[:e.pos], append(a, e.line[e.pos:]...)...)
+		e.pos += len(a)
+		e.refresh()
+		return
+	}
+	if !list {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+	// Only show what follows the directory of the word.
+	dir := word[:strings.LastIndex(word, "/")+1]
+	width := 0
+	for _, s := range m {
+		if n := utf8.RuneCountInString(s) - len(dir) + 2; n > width {
+			width = n
+		}
+	}
+	perLine := 80 / width
+	if perLine == 0 {
+		perLine = 1
+	}
+	fmt.Fprint(e.out, "\r\n")
+	for i, s := range m {
+		fmt.Fprintf(e.out, "%-*s", width, strings.TrimPrefix(s, dir))
+		if (i+1)%perLine == 0 || i == len(m)-1 {
+			fmt.Fprint(e.out, "\r\n")
+		}
+	}
+	e.refresh()
+}
+
+// search does an incremental search backwards through the history.
+// The line found replaces the line being edited. search returns the key
+// which ended the search, to be handled as usual, or, if the line was
+// accepted with return, accept is set.
+func (e *editor) search() (r rune, accept bool, err error) {
+	orig, origPos := e.line, e.pos
+	query, found := "", len(history)
+	show := func() {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", query, string(e.line))
+	}
+	// find looks for the query starting at history entry i, going backwards.
+	find := func(i int) {
+		for ; i >= 0; i-- {
+			if strings.Contains(history[i], query) {
+				found = i
+				e.line = []rune(history[i])
+				e.pos = len(e.line)
+				return
+			}
+		}
+	}
+	show()
+	for {
+		if r, err = e.readKey(); err != nil {
+			return 0, false, err
+		}
+		switch r {
+		case ctrlR:
+			find(found - 1)
+		case backspace, ctrlH:
+			if len(query) > 0 {
+				_, n := utf8.DecodeLastRuneInString(query)
+				query = query[:len(query)-n]
+				find(len(history) - 1)
+			}
+		case ctrlC, ctrlG:
+			e.line, e.pos = orig, origPos
+			e.refresh()
+			return 0, false, nil
+		case '\r', '\n':
+			e.refresh()
+			return 0, true, nil
+		default:
+			if !unicode.IsPrint(r) {
+				e.refresh()
+				if r == esc {
+					r = 0
+				}
+				return r, false, nil
+			}
+			query += string(r)
+			if found == len(history) {
+				found--
+			}
+			find(found)
+		}
+		show()
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// testEditor returns an editor which reads keys.
+func testEditor(t *testing.T, keys string) *editor {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		io.WriteString(w, keys)
+		w.Close()
+	}()
+	return &editor{in: r, out: ioutil.Discard}
+}
+
+// setHistory replaces the history, which is not saved to a file, and
+// returns a function to put it back.
+func setHistory(h ...string) func() {
+	saved, savedFile := history, historyFile
+	history, historyFile = h, ""
+	return func() {
+		history, historyFile = saved, savedFile
+	}
+}
+
+func TestEdit(t *testing.T) {
+	for _, tt := range []struct {
+		keys string
+		want string
+		err  error
+	}{
+		{"abc\r", "abc", nil},
+		{"abc\x02\x02X\r", "aXbc", nil},
+		{"abc\x01X\x05Y\r", "XabcY", nil},
+		{"abc\x1b[D\x1b[D\x1b[3~\r", "ac", nil},
+		{"abc\x1b[H\x1b[C\x06\x1b[FZ\n", "abcZ", nil},
+		{"abcd\x02\x02\x0b\r", "ab", nil},
+		{"abcd\x02\x02\x15\r", "cd", nil},
+		{"ab\x7f\r", "a", nil},
+		{"ab\x01\x04\r", "b", nil},
+		{"\x1b[A\r", "make test", nil},
+		{"\x1b[A\x1b[A\r", "ls -l", nil},
+		{"\x10\x10\x10\x10\r", "make", nil},
+		{"x\x1b[A\x1b[B\r", "x", nil},
+		{"\x10\x10\x0e\r", "make test", nil},
+		{"\x12ls\r", "ls -l", nil},
+		{"\x12ma\x12\r", "make", nil},
+		{"\x12ls\x05 /\r", "ls -l /", nil},
+		{"ab\x12zz\x07c\r", "abc", nil},
+		{"abc\x03", "", nil},
+		{"\x04", "", io.EOF},
+		{"ab", "", io.EOF},
+	} {
+		restore := setHistory("make", "ls -l", "make test")
+		got, err := testEditor(t, tt.keys).edit()
+		if got != tt.want || err != tt.err {
+			t.Errorf("edit(%q): got %q, %v, want %q, %v", tt.keys, got, err, tt.want, tt.err)
+		}
+		restore()
+	}
+}
+
+func TestEditAddsHistory(t *testing.T) {
+	defer setHistory("ls")()
+	for _, keys := range []string{"  ls  \r", "\r", "pwd\r"} {
+		if _, err := testEditor(t, keys).edit(); err != nil {
+			t.Fatalf("edit(%q): %v", keys, err)
+		}
+	}
+	if len(history) != 2 || history[1] != "pwd" {
+		t.Errorf("history: got %q, want [\"ls\" \"pwd\"]", history)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	for _, tt := range []struct {
+		keys   string
+		line   string
+		r      rune
+		accept bool
+	}{
+		{"ma\r", "make test", 0, true},
+		{"ma\x12\r", "make", 0, true},
+		{"ma\x12\x12\x12\r", "make", 0, true},
+		{"ls\x1b[C", "ls -l", keyRight, false},
+		{"ls\x01", "ls -l", ctrlA, false},
+		{"zz\x07", "orig", 0, false},
+		{"ma\x03", "orig", 0, false},
+		{"zz\r", "orig", 0, true},
+		{"x\x7fls\r", "ls -l", 0, true},
+		{"test\x7f\x7f\x7f\x7fls\r", "ls -l", 0, true},
+		{"ls\x1b\x1b", "ls -l", 0, false},
+	} {
+		restore := setHistory("make", "ls -l", "make test")
+		e := testEditor(t, tt.keys)
+		e.line, e.pos = []rune("orig"), 4
+		r, accept, err := e.search()
+		if err != nil {
+			t.Errorf("search(%q): %v", tt.keys, err)
+		}
+		if got := string(e.line); got != tt.line || r != tt.r || accept != tt.accept {
+			t.Errorf("search(%q): got %q, %q, %v, want %q, %q, %v", tt.keys, got, r, accept, tt.line, tt.r, tt.accept)
+		}
+		restore()
+	}
+}
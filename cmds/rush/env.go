@@ -0,0 +1,150 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Environment variables.
+//
+// Synopsis:
+//     export [NAME=VALUE|NAME]...
+//
+// Description:
+//     $NAME and ${NAME} in arguments are replaced by the value of the
+//     environment variable NAME. If NAME is not set in the environment, the
+//     contents of the file NAME in the environment directory (/env) are used
+//     instead. Unset variables expand to the empty string.
+//
+//     $? is the exit status of the last pipeline and $PIPESTATUS holds the
+//     exit status of each of its commands, separated by spaces. $0 to $9,
+//     $# and $@, or $*, are the arguments of a script.
+//
+//     export sets NAME to VALUE in the environment of rush and of all
+//     commands it starts. With no arguments, export prints the environment.
+//     A line of only NAME=VALUE words does the same. NAME=VALUE words before
+//     a command set NAME for that command only.
+//
+// Example:
+//     LANG=C sort /etc/passwd
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	addBuiltIn("export", "set environment variables", export)
+}
+
+// lookupVar returns the value of the variable name, for use with os.Expand.
+func lookupVar(name string) string {
+	switch name {
+	case "?":
+		return strconv.Itoa(status)
+	case "!":
+		if lastBg == 0 {
+			return ""
+		}
+		return strconv.Itoa(lastBg)
+	case "#":
+		return strconv.Itoa(len(positional) - 1)
+	case "@", "*":
+		return strings.Join(positional[1:], " ")
+	case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		if i := int(name[0] - '0'); i < len(positional) {
+			return positional[i]
+		}
+		return ""
+	case "PIPESTATUS":
+		var s []string
+		for _, st := range pipeStatus {
+			s = append(s, strconv.Itoa(st))
+		}
+		return strings.Join(s, " ")
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	// Names with a / in them can not be valid variables, and we do not
+	// want them to escape the environment directory.
+	if strings.Contains(name, "/") {
+		return ""
+	}
+	b, err := ioutil.ReadFile(filepath.Join(envDir, name))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// isAssignment returns whether the word s, as the parser read it,
+// is NAME=VALUE.
+func isAssignment(s string) bool {
+	i := strings.Index(s, "=")
+	if i <= 0 || '0' <= s[0] && s[0] <= '9' {
+		return false
+	}
+	for j := 0; j < i; j++ {
+		if !isNameChar(s[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeEnv returns env with the NAME=VALUE pairs in vars added,
+// replacing any NAME in env.
+func mergeEnv(env, vars []string) []string {
+	var out []string
+	set := map[string]bool{}
+	for _, v := range vars {
+		set[v[:strings.Index(v, "=")]] = true
+	}
+	for _, e := range env {
+		if i := strings.Index(e, "="); i < 0 || !set[e[:i]] {
+			out = append(out, e)
+		}
+	}
+	return append(out, vars...)
+}
+
+// runBuiltin runs a builtin with the variables assigned for it
+// in the environment.
+func runBuiltin(b builtin, c *Command) error {
+	if len(c.env) > 0 {
+		defer restoreEnv(os.Environ())
+		for _, v := range c.env {
+			i := strings.Index(v, "=")
+			os.Setenv(v[:i], v[i+1:])
+		}
+	}
+	return b(c)
+}
+
+func export(c *Command) error {
+	if len(c.argv) == 0 {
+		for _, e := range os.Environ() {
+			fmt.Fprintf(c.Stdout, "export %s\n", e)
+		}
+		return nil
+	}
+	for _, a := range c.argv {
+		// Everything is already exported, so export NAME
+		// has nothing to do.
+		i := strings.Index(a, "=")
+		if i == -1 {
+			continue
+		}
+		if i == 0 {
+			return errors.New("usage: export [NAME=VALUE|NAME]...")
+		}
+		if err := os.Setenv(a[:i], a[i+1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
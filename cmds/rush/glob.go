@@ -0,0 +1,208 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Brace expansion and globbing.
+//
+// Description:
+//     An argument with {A,B,...} in it becomes one argument for each of A,
+//     B, ..., in that order; braces may be nested. Then, arguments with *,
+//     ? or [...] in them are replaced by the names of the files they
+//     match, if there are any. ** matches any number of directories, down
+//     to a depth of 16, without following symbolic links. Quoted braces and
+//     glob characters are taken literally.
+//
+// Example:
+//     cp /lib/modules/**/*.ko /target
+//     mkdir -p /tmp/{etc,dev,proc/{1,2}}
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxGlobDepth is how many directories deep ** looks.
+const maxGlobDepth = 16
+
+// braceExpand expands the braces in the word s, as the parser read it.
+func braceExpand(s string) []string {
+	open, comma, end := findBraces(s)
+	if open < 0 {
+		return []string{s}
+	}
+	pre, post := s[:open], s[end+1:]
+	var words []string
+	start := open + 1
+	for _, c := range append(comma, end) {
+		words = append(words, braceExpand(pre+s[start:c]+post)...)
+		start = c + 1
+	}
+	return words
+}
+
+// findBraces finds the first {A,B,...} in s which is not quoted. It returns
+// the index of the {, those of the commas in it and that of the }, or -1
+// if there is none.
+func findBraces(s string) (int, []int, int) {
+	for i := 0; i < len(s); i++ {
+		if i = skipQuoted(s, i); i >= len(s) || s[i] != '{' {
+			continue
+		}
+		// ${NAME} is a variable.
+		if i > 0 && s[i-1] == '$' {
+			continue
+		}
+		depth := 0
+		var comma []int
+		for j := i + 1; j < len(s); j++ {
+			if j = skipQuoted(s, j); j >= len(s) {
+				break
+			}
+			switch s[j] {
+			case '{':
+				depth++
+			case ',':
+				if depth == 0 {
+					comma = append(comma, j)
+				}
+			case '}':
+				if depth > 0 {
+					depth--
+					continue
+				}
+				if len(comma) > 0 {
+					return i, comma, j
+				}
+				// {A} is not expanded, but may hold braces
+				// which are.
+				j = len(s)
+			}
+		}
+	}
+	return -1, nil, -1
+}
+
+// skipQuoted returns the index of the first character in s, starting at
+// i, which is not quoted or part of a command substitution.
+func skipQuoted(s string, i int) int {
+	for i < len(s) {
+		switch {
+		case s[i] == '\\':
+			i += 2
+		case s[i] == '\'':
+			if j := strings.IndexByte(s[i+1:], '\''); j >= 0 {
+				i += j + 2
+			} else {
+				i = len(s)
+			}
+		case s[i] == '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case s[i] == '`':
+			i = matchBackquote(s, i+1) + 1
+		case strings.HasPrefix(s[i:], "$("):
+			i = matchParen(s, i+2) + 1
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// glob is filepath.Glob, with ** matching any number of directories.
+func glob(pat string) ([]string, error) {
+	before, after, ok := splitDoubleStar(pat)
+	if !ok {
+		return filepath.Glob(pat)
+	}
+	bases := []string{"."}
+	if before != "" {
+		var err error
+		if bases, err = filepath.Glob(before); err != nil {
+			return nil, err
+		}
+	}
+	found := map[string]bool{}
+	for _, b := range bases {
+		// ** at the end matches everything below b.
+		if after == "" {
+			for _, f := range allFiles(b, maxGlobDepth) {
+				found[f] = true
+			}
+			continue
+		}
+		for _, d := range subdirs(b, maxGlobDepth) {
+			m, err := glob(filepath.Join(d, after))
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range m {
+				found[f] = true
+			}
+		}
+	}
+	var m []string
+	for f := range found {
+		m = append(m, f)
+	}
+	sort.Strings(m)
+	return m, nil
+}
+
+// splitDoubleStar splits pat around its first ** directory.
+func splitDoubleStar(pat string) (string, string, bool) {
+	p := strings.Split(pat, "/")
+	for i, e := range p {
+		if e == "**" {
+			return strings.Join(p[:i], "/"), strings.Join(p[i+1:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// subdirs returns dir and the directories below it, down to depth.
+func subdirs(dir string, depth int) []string {
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil
+	}
+	d := []string{dir}
+	if depth == 0 {
+		return d
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return d
+	}
+	for _, fi := range fis {
+		// ReadDir does not follow symbolic links, so links to
+		// directories are not directories here.
+		if fi.IsDir() {
+			d = append(d, subdirs(filepath.Join(dir, fi.Name()), depth-1)...)
+		}
+	}
+	return d
+}
+
+// allFiles returns the files and directories below dir, down to depth.
+// Directories at depth are not looked into.
+func allFiles(dir string, depth int) []string {
+	var f []string
+	for _, d := range subdirs(dir, depth) {
+		fis, err := ioutil.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, fi := range fis {
+			f = append(f, filepath.Join(d, fi.Name()))
+		}
+	}
+	return f
+}
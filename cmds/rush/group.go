@@ -0,0 +1,108 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Grouping.
+//
+// Synopsis:
+//     ( LIST )
+//     { LIST; }
+//
+// Description:
+//     A group runs a LIST of commands as if it were one command, so they
+//     can share redirects or a pipe. ( LIST ) runs in a new rush, so that
+//     changes to the working directory or variables do not last; aliases
+//     and options are not passed on to it either. { LIST; } runs in rush
+//     itself, unless it is part of a pipeline or in the background.
+//
+// Example:
+//     ( dmesg; cat /proc/meminfo ) > /tmp/report
+//     { echo -n "uptime: "; cat /proc/uptime; } | tee -a /tmp/log
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A group is a list of commands in ( ) or { }.
+type group struct {
+	// text is the list as typed, which a subshell parses again.
+	text     string
+	body     []statement
+	subshell bool
+}
+
+func init() {
+	addBuiltIn("{", "run commands as a group", braceGroup)
+	// The parent passes the text of the group as the only argument.
+	addForkBuiltIn("(", "", subshell)
+}
+
+// newGroup parses the text of a group. It panics if it can not, like
+// the rest of the parser.
+func newGroup(text string, subshell bool) *group {
+	g := &group{text: text, subshell: subshell}
+	// The here-documents of the line the group is on
+	// are not in the group.
+	defer func(d []*heredoc, i bool) {
+		pendingDocs, interactive = d, i
+	}(pendingDocs, interactive)
+	pendingDocs, interactive = nil, false
+	p := newParser(bufio.NewReader(strings.NewReader(text)))
+	for {
+		s, err := p.statement()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			panic(err)
+		}
+		if s != nil {
+			g.body = append(g.body, s)
+		}
+	}
+	if len(g.body) == 0 {
+		panic(errors.New("syntax error: empty group"))
+	}
+	return g
+}
+
+func (g *group) String() string {
+	if g.subshell {
+		return "(" + g.text + ")"
+	}
+	return "{" + g.text + "}"
+}
+
+// braceGroup runs a { } group in the shell, with the stdin, stdout and
+// stderr of the group.
+func braceGroup(c *Command) error {
+	if c.group == nil {
+		return errors.New("{: not in a group")
+	}
+	defer func(i io.Reader, o, e io.Writer) {
+		stdin, stdout, stderr = i, o, e
+	}(stdin, stdout, stderr)
+	stdin, stdout, stderr = c.Stdin, c.Stdout, c.Stderr
+	return runBlock(c.group.body, false)
+}
+
+// subshell is run by a new rush to run a ( ) group, or a { } group
+// which can not run in its parent. It exits with the status of the
+// last command of the group.
+func subshell(c *Command) error {
+	if len(c.argv) != 1 {
+		return errors.New("usage: ( LIST )")
+	}
+	if err := runCommands(strings.NewReader(c.argv[0])); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	os.Exit(status)
+	return nil
+}
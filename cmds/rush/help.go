@@ -0,0 +1,34 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+func init() {
+	addBuiltIn("help", "describe builtins", help)
+}
+
+func help(c *Command) error {
+	names := c.argv
+	if len(names) == 0 {
+		for n, b := range builtins {
+			if !b.fork {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+	}
+	for _, n := range names {
+		b, ok := builtins[n]
+		if !ok || b.fork {
+			return fmt.Errorf("help: no builtin %v", n)
+		}
+		fmt.Fprintf(c.Stdout, "%-10s %s\n", n, b.help)
+	}
+	return nil
+}
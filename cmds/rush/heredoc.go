@@ -0,0 +1,89 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Here-documents.
+//
+// Synopsis:
+//     CMD <<[-]WORD
+//     TEXT
+//     WORD
+//
+//     CMD <<<WORD
+//
+// Description:
+//     The lines following the command, up to a line containing only WORD,
+//     are its stdin. With <<-, leading tabs are removed from them. Variables
+//     in the text are expanded, unless WORD is quoted. A here-document
+//     must be the last command on its line.
+//
+//     With <<<, stdin is WORD, expanded, followed by a newline.
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// A heredoc is the text of a here-document or here-string.
+type heredoc struct {
+	delim     string
+	text      string
+	expand    bool
+	stripTabs bool
+	// word is set for a here-string, whose text is an argument
+	// as the parser read it.
+	word bool
+}
+
+// pendingDocs are the here-documents on the line being parsed.
+// Their text follows the line.
+var pendingDocs []*heredoc
+
+// readDocs reads the text of the pending here-documents.
+func readDocs(b *bufio.Reader) {
+	for _, d := range pendingDocs {
+		var text []string
+		for {
+			continueLine(b)
+			l, err := b.ReadString('\n')
+			if err != nil && l == "" {
+				break
+			}
+			l = strings.TrimSuffix(l, "\n")
+			if d.stripTabs {
+				l = strings.TrimLeft(l, "\t")
+			}
+			if l == d.delim {
+				break
+			}
+			text = append(text, l+"\n")
+		}
+		d.text = strings.Join(text, "")
+	}
+	pendingDocs = nil
+}
+
+// open returns a pipe from which the text of the document can be read.
+func (d *heredoc) open() (*os.File, error) {
+	text := d.text
+	if d.word {
+		text, _, _ = expandWord(text)
+		text += "\n"
+	} else if d.expand {
+		text = expand(text)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	// The pipe may not hold all the text, so write from
+	// a goroutine. It ends once the reader closes the pipe.
+	go func() {
+		io.WriteString(w, text)
+		w.Close()
+	}()
+	return r, nil
+}
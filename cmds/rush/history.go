@@ -0,0 +1,123 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command history.
+//
+// Synopsis:
+//     history [-c]
+//
+// Description:
+//     Lines typed at the prompt are remembered in the history, which is
+//     kept in ~/.rush_history across sessions; only the last 1000 lines
+//     are kept. history prints it; with -c, it is cleared instead.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is the number of lines kept in the history.
+const maxHistory = 1000
+
+var (
+	history     []string
+	historyFile string
+)
+
+func init() {
+	addBuiltIn("history", "print the command history", historyBuiltin)
+}
+
+// loadHistory reads the history file from the user's home directory.
+func loadHistory() {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return
+	}
+	historyFile = filepath.Join(home, ".rush_history")
+	f, err := os.Open(historyFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		history = append(history, s.Text())
+	}
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+}
+
+// addHistory adds a line to the history, in memory and in the history file.
+// Empty lines and repeats of the last line are not recorded.
+func addHistory(line string) {
+	if line == "" || (len(history) > 0 && history[len(history)-1] == line) {
+		return
+	}
+	history = append(history, line)
+	if len(history) > maxHistory {
+		history = history[1:]
+	}
+	if historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, line)
+	f.Close()
+	trimHistory(historyFile, maxHistory)
+}
+
+// trimHistory cuts the history file name down to its last max lines, so
+// that it does not grow without bound. Other sessions append to it too, so
+// it is read again rather than written from the history in memory.
+func trimHistory(name string, max int) error {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= max {
+		return nil
+	}
+	// The new file is renamed over the old one, so that a session
+	// reading it never sees half of it.
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strings.Join(lines[len(lines)-max:], "")), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func historyBuiltin(c *Command) error {
+	switch {
+	case len(c.argv) == 0:
+		for i, l := range history {
+			fmt.Fprintf(c.Stdout, "%5d  %s\n", i+1, l)
+		}
+		return nil
+	case len(c.argv) == 1 && c.argv[0] == "-c":
+		history = nil
+		if historyFile == "" {
+			return nil
+		}
+		if err := os.Truncate(historyFile, 0); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return errors.New("usage: history [-c]")
+}
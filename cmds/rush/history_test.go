@@ -0,0 +1,79 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrimHistory(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestTrimHistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	name := filepath.Join(d, "history")
+	for _, tt := range []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"a\nb\nc\nd\ne\n", 3, "c\nd\ne\n"},
+		{"a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"a\nb\n", 3, "a\nb\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"", 3, ""},
+	} {
+		if err := ioutil.WriteFile(name, []byte(tt.in), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := trimHistory(name, tt.max); err != nil {
+			t.Errorf("trimHistory(%q, %d): %v", tt.in, tt.max, err)
+			continue
+		}
+		if b, err := ioutil.ReadFile(name); err != nil || string(b) != tt.want {
+			t.Errorf("trimHistory(%q, %d): got %q, %v, want %q", tt.in, tt.max, b, err, tt.want)
+		}
+	}
+	if err := trimHistory(filepath.Join(d, "nothere"), 3); !os.IsNotExist(err) {
+		t.Errorf("trimHistory(nothere): got %v, want a does not exist error", err)
+	}
+}
+
+func TestAddHistory(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestAddHistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	defer setHistory()()
+	historyFile = filepath.Join(d, "history")
+
+	addHistory("")
+	addHistory("a")
+	addHistory("a")
+	if len(history) != 1 {
+		t.Errorf("history: got %q, want [\"a\"]", history)
+	}
+	for i := 0; i < maxHistory+10; i++ {
+		addHistory(fmt.Sprint(i))
+	}
+	if len(history) != maxHistory || history[0] != "10" {
+		t.Errorf("history: got %d lines from %q, want %d from \"10\"", len(history), history[0], maxHistory)
+	}
+	b, err := ioutil.ReadFile(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != maxHistory || lines[0] != "10" || lines[len(lines)-1] != fmt.Sprint(maxHistory+9) {
+		t.Errorf("%s: got %d lines from %q to %q, want %d from \"10\"", historyFile, len(lines), lines[0], lines[len(lines)-1], maxHistory)
+	}
+}
@@ -0,0 +1,466 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Job control.
+//
+// Synopsis:
+//     jobs
+//     fg [[%]JOB]
+//     bg [[%]JOB]
+//     wait [%JOB|PID]...
+//
+// Description:
+//     Every pipeline rush starts is a job with its own process group. A
+//     foreground job can be stopped with ^Z. jobs lists the jobs, fg resumes
+//     a job in the foreground and bg resumes it in the background. If no JOB
+//     is given, the most recent job is used.
+//
+//     wait waits for the given jobs, or the jobs with the given processes,
+//     to finish, and fails like the last of them. Without arguments, it
+//     waits for all running jobs. ^C stops waiting. $! is the process id of
+//     the last command of the last job started in the background.
+//
+//     If rush is init, it also waits for the processes it inherits from
+//     processes which died, so they don't linger as zombies.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	running = "Running"
+	stopped = "Stopped"
+	done    = "Done"
+)
+
+// A job is a pipeline, i.e. one or more commands joined by pipes.
+type job struct {
+	id   int
+	pgid int
+	cmds []*Command
+	// errs holds the result of each command in cmds.
+	errs []error
+	// bg is set if nobody is waiting for the job to finish.
+	bg bool
+	// state is one of running, stopped or done.
+	state string
+	// builtins counts builtins running as part of the job.
+	builtins sync.WaitGroup
+	// user and sys are the CPU times used by the job's processes.
+	user, sys time.Duration
+}
+
+var (
+	// jobsMu protects jobTable and the state of each job.
+	jobsMu sync.Mutex
+	// jobsCond is signalled when the state of any job changes.
+	jobsCond = sync.NewCond(&jobsMu)
+	jobTable []*job
+	// fgJob is the job the shell is waiting for, if any.
+	fgJob *job
+
+	// status is the exit status of the last pipeline, and pipeStatus
+	// that of each of its commands.
+	status     int
+	pipeStatus []int
+	// lastBg is the process id for $!.
+	lastBg int
+	// sigints counts the SIGINTs which came while no job was in the
+	// foreground, so that the wait builtin can be interrupted.
+	sigints int
+)
+
+func init() {
+	addBuiltIn("jobs", "list jobs", jobsBuiltin)
+	addBuiltIn("fg", "resume a job in the foreground", fg)
+	addBuiltIn("bg", "resume a job in the background", bg)
+	addBuiltIn("wait", "wait for background jobs", waitBuiltin)
+}
+
+func (j *job) String() string {
+	var s []string
+	for _, c := range j.cmds {
+		if c.group != nil {
+			s = append(s, c.group.String())
+			continue
+		}
+		s = append(s, strings.Join(append([]string{c.cmd}, c.argv...), " "))
+	}
+	return strings.Join(s, " | ")
+}
+
+func (j *job) setState(state string) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	j.state = state
+	jobsCond.Broadcast()
+}
+
+// err returns the result of the job, which is that of its last command.
+func (j *job) err() error {
+	return j.errs[len(j.errs)-1]
+}
+
+// pipelines splits cmds into runs of commands joined by pipes.
+func pipelines(cmds []*Command) [][]*Command {
+	var p [][]*Command
+	start := 0
+	for i, c := range cmds {
+		if c.link != "|" {
+			p = append(p, cmds[start:i+1])
+			start = i + 1
+		}
+	}
+	return p
+}
+
+// startJob starts all commands of the pipeline p and adds it to the job table.
+// It does not wait for them.
+func startJob(p []*Command) (*job, error) {
+	j := &job{cmds: p, errs: make([]error, len(p)), bg: p[len(p)-1].bg, state: running}
+	for i, c := range p {
+		if b, ok := getBuiltin(c.cmd); ok {
+			j.builtins.Add(1)
+			go func(i int, c *Command, b builtin) {
+				defer j.builtins.Done()
+				j.errs[i] = runBuiltin(b, c)
+				closeFiles(c)
+			}(i, c, b)
+			continue
+		}
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		// Without a tty there is no job control, but background
+		// jobs still get their own process group so they do not
+		// see signals meant for the foreground.
+		if ttyf != nil || j.bg {
+			c.SysProcAttr.Setpgid = true
+			c.SysProcAttr.Pgid = j.pgid
+			if ttyf != nil && !j.bg && j.pgid == 0 {
+				c.SysProcAttr.Foreground = true
+				c.SysProcAttr.Ctty = int(ttyf.Fd())
+			}
+		}
+		err := start(c.Cmd)
+		closeFiles(c)
+		if err != nil {
+			// Anything we already started is on its own now.
+			for _, c := range p[i+1:] {
+				closeFiles(c)
+			}
+			j.cmds = p[:i]
+			go j.monitor()
+			return nil, fmt.Errorf("%v: Path %v", err, os.Getenv("PATH"))
+		}
+		if j.pgid == 0 {
+			j.pgid = c.Process.Pid
+		}
+	}
+
+	jobsMu.Lock()
+	for _, o := range jobTable {
+		if o.id > j.id {
+			j.id = o.id
+		}
+	}
+	j.id++
+	jobTable = append(jobTable, j)
+	jobsMu.Unlock()
+
+	go j.monitor()
+	return j, nil
+}
+
+// monitor waits for the processes of a job and keeps its state up to date.
+func (j *job) monitor() {
+	for i, c := range j.cmds {
+		if c.Process == nil {
+			continue
+		}
+		for {
+			var ws syscall.WaitStatus
+			var ru syscall.Rusage
+			_, err := syscall.Wait4(c.Process.Pid, &ws, syscall.WUNTRACED, &ru)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				j.errs[i] = err
+				break
+			}
+			if ws.Stopped() {
+				j.setState(stopped)
+				continue
+			}
+			j.errs[i] = waitError(ws)
+			j.user += time.Duration(ru.Utime.Nano())
+			j.sys += time.Duration(ru.Stime.Nano())
+			break
+		}
+		forget(c.Process.Pid)
+		c.Process.Release()
+	}
+	j.builtins.Wait()
+	j.setState(done)
+}
+
+// An exitError is returned for a command which ran, but failed.
+type exitError struct {
+	syscall.WaitStatus
+}
+
+func (e *exitError) Error() string {
+	if e.Signaled() {
+		return fmt.Sprintf("wait: signal: %v", e.Signal())
+	}
+	return fmt.Sprintf("wait: exit status %d", e.ExitStatus())
+}
+
+// exitStatus returns the exit status for the result of a command.
+func exitStatus(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case *exitError:
+		if e.Signaled() {
+			return 128 + int(e.Signal())
+		}
+		return e.ExitStatus()
+	}
+	return 1
+}
+
+// setStatus records the results of the commands of a pipeline.
+func setStatus(errs ...error) {
+	pipeStatus = make([]int, len(errs))
+	for i, err := range errs {
+		pipeStatus[i] = exitStatus(err)
+	}
+	status = pipeStatus[len(errs)-1]
+}
+
+// waitError converts a wait status into an error like the ones os/exec returns.
+func waitError(ws syscall.WaitStatus) error {
+	if ws.Signaled() || ws.ExitStatus() != 0 {
+		return &exitError{ws}
+	}
+	return nil
+}
+
+// wait waits for a foreground job to either stop or finish and gives
+// the tty back to the shell.
+func (j *job) wait() error {
+	jobsMu.Lock()
+	fgJob = j
+	for j.state == running {
+		jobsCond.Wait()
+	}
+	fgJob = nil
+	state := j.state
+	if state == stopped {
+		j.bg = true
+	}
+	jobsMu.Unlock()
+	foreground()
+
+	if state == stopped {
+		fmt.Fprintf(os.Stderr, "\n[%d]+ %s\t%s\n", j.id, state, j)
+		return nil
+	}
+	removeJob(j)
+	return j.err()
+}
+
+func removeJob(j *job) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for i, o := range jobTable {
+		if o == j {
+			jobTable = append(jobTable[:i], jobTable[i+1:]...)
+			return
+		}
+	}
+}
+
+// reportJobs prints, and forgets, background jobs that have finished.
+func reportJobs() {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	var t []*job
+	for _, j := range jobTable {
+		if j.state == done && j.bg {
+			fmt.Fprintf(os.Stderr, "[%d]+ %s\t%s\n", j.id, j.state, j)
+			continue
+		}
+		t = append(t, j)
+	}
+	jobTable = t
+}
+
+// findJob returns the job named by the arguments of a job control builtin.
+func findJob(c *Command) (*job, error) {
+	if len(c.argv) > 1 {
+		return nil, fmt.Errorf("usage: %v [[%%]JOB]", c.cmd)
+	}
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if len(c.argv) == 0 {
+		if len(jobTable) == 0 {
+			return nil, fmt.Errorf("%v: no current job", c.cmd)
+		}
+		return jobTable[len(jobTable)-1], nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(c.argv[0], "%"))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v: bad job id", c.cmd, c.argv[0])
+	}
+	for _, j := range jobTable {
+		if j.id == id {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %v: no such job", c.cmd, c.argv[0])
+}
+
+// resume sends SIGCONT to a job, which must have a process group.
+func (j *job) resume(bg bool) error {
+	jobsMu.Lock()
+	if j.state == done {
+		jobsMu.Unlock()
+		return errors.New("job has terminated")
+	}
+	j.state = running
+	j.bg = bg
+	jobsMu.Unlock()
+	if j.pgid == 0 {
+		return nil
+	}
+	return syscall.Kill(-j.pgid, syscall.SIGCONT)
+}
+
+func jobsBuiltin(c *Command) error {
+	if len(c.argv) != 0 {
+		return errors.New("usage: jobs")
+	}
+	jobsMu.Lock()
+	for _, j := range jobTable {
+		fmt.Fprintf(c.Stdout, "[%d] %s\t%s\n", j.id, j.state, j)
+	}
+	jobsMu.Unlock()
+	reportJobs()
+	return nil
+}
+
+func fg(c *Command) error {
+	j, err := findJob(c)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Stdout, "%s\n", j)
+	if ttyf != nil && j.pgid != 0 {
+		setForeground(j.pgid)
+	}
+	if err := j.resume(false); err != nil {
+		foreground()
+		return err
+	}
+	return j.wait()
+}
+
+func bg(c *Command) error {
+	j, err := findJob(c)
+	if err != nil {
+		return err
+	}
+	if err := j.resume(true); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Stdout, "[%d]+ %s &\n", j.id, j)
+	return nil
+}
+
+// interrupt wakes up the wait builtin, if it is waiting.
+func interrupt() {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	sigints++
+	jobsCond.Broadcast()
+}
+
+// waitJobs returns the jobs named by the arguments of wait.
+func waitJobs(c *Command) ([]*job, error) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	var js []*job
+	if len(c.argv) == 0 {
+		for _, j := range jobTable {
+			if j.state == running {
+				js = append(js, j)
+			}
+		}
+		return js, nil
+	}
+next:
+	for _, a := range c.argv {
+		id, err := strconv.Atoi(strings.TrimPrefix(a, "%"))
+		if err != nil {
+			return nil, fmt.Errorf("wait: %v: bad job or process id", a)
+		}
+		for _, j := range jobTable {
+			if strings.HasPrefix(a, "%") && j.id == id {
+				js = append(js, j)
+				continue next
+			}
+			for _, jc := range j.cmds {
+				if !strings.HasPrefix(a, "%") && jc.Process != nil && jc.Process.Pid == id {
+					js = append(js, j)
+					continue next
+				}
+			}
+		}
+		return nil, fmt.Errorf("wait: %v: no such job", a)
+	}
+	return js, nil
+}
+
+func waitBuiltin(c *Command) error {
+	js, err := waitJobs(c)
+	if err != nil {
+		return err
+	}
+	jobsMu.Lock()
+	n := sigints
+	for _, j := range js {
+		for j.state == running {
+			if sigints != n {
+				jobsMu.Unlock()
+				return &exitError{syscall.WaitStatus(syscall.SIGINT)}
+			}
+			jobsCond.Wait()
+		}
+	}
+	jobsMu.Unlock()
+
+	err = nil
+	for _, j := range js {
+		if j.state != done {
+			continue
+		}
+		removeJob(j)
+		err = j.err()
+	}
+	if len(c.argv) == 0 {
+		return nil
+	}
+	return err
+}
@@ -9,14 +9,11 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"os"
 	"os/exec"
-	"path"
-	"path/filepath"
 	"strings"
 )
 
@@ -25,16 +22,40 @@ type arg struct {
 	mod string
 }
 
+// A redirect says where a file descriptor of a command goes.
+type redirect struct {
+	// file is the file to open or, if dup is set, the number of
+	// the file descriptor to copy.
+	file   string
+	dup    bool
+	append bool
+	// clobber is set for >|, which overwrites files even with set -C.
+	clobber bool
+	// procSub is set if file is the LIST of a process substitution.
+	procSub bool
+}
+
 // The Command struct is initially filled in by the parser. The shell itself
 // adds to it as processing continues, and then uses it to creates os.Commands
 type Command struct {
 	*exec.Cmd
 	// These are filled in by the parser.
 	args  []arg
-	fdmap map[int]string
+	fdmap map[int]*redirect
 	files map[int]io.Closer
 	link  string
 	bg    bool
+	// doc, if set, is the text of a here-document for stdin.
+	doc *heredoc
+	// group is set if the command is a ( ) or { } group, in which
+	// case there are no args.
+	group *group
+	// extra are files passed to the command, from fd 3 on.
+	extra []*os.File
+	// env holds the NAME=VALUE assignments for the command only.
+	env []string
+	// timed is set on the first command of a pipeline run by time.
+	timed bool
 
 	// These are set up by the shell as it evaluates the Commands
 	// provided by the parser.
@@ -46,7 +67,7 @@ type Command struct {
 
 var (
 	cmds  []Command
-	punct = "<>|&$ \t\n"
+	punct = "<>|&;() \t\n"
 )
 
 func pushback(b *bufio.Reader) {
@@ -68,65 +89,86 @@ func one(b *bufio.Reader) byte {
 	return c
 }
 
-func next(b *bufio.Reader) byte {
-	c := one(b)
-	if c == '\\' {
-		return one(b)
-	}
-	return byte(c)
-}
-
 // Tokenize stuff coming in from the stream. For everything but an arg, the
 // type is just the thing itself, since we can switch on strings.
+// Args are returned as they were typed, quotes and all; they are ARG, or
+// QUOTE if any part of them is quoted.
 func tok(b *bufio.Reader) (string, string) {
 	tokType, arg := "white", ""
-	c := next(b)
+	c := one(b)
 
 	//fmt.Printf("TOK %v", c)
 	switch c {
 	case 0:
 		return "EOF", ""
 	case '>':
-		return "FD", "1"
+		if nc := one(b); nc == '(' {
+			return "PROCSUB", ">" + readSubst(b, ')')
+		} else if nc != 0 {
+			pushback(b)
+		}
+		return redirOut(b, "1")
 	case '<':
-		return "FD", "0"
-	// yes, I realize $ handling is still pretty hokey.
-	case '$':
-		arg = ""
-		c = next(b)
-		for {
-			if c == 0 {
-				break
-			}
-			if strings.Index(punct, string(c)) > -1 {
+		// peek ahead for <(, << and <<<.
+		nc := one(b)
+		if nc == '(' {
+			return "PROCSUB", "<" + readSubst(b, ')')
+		}
+		if nc != '<' {
+			if nc != 0 {
 				pushback(b)
-				break
 			}
-			arg = arg + string(c)
-			c = next(b)
+			return "FD", "0"
 		}
-		return "ENV", arg
-	case '\'':
-		for {
-			nc := next(b)
-			if nc == '\'' {
-				return "ARG", arg
-			}
-			arg = arg + string(nc)
+		switch nc := one(b); nc {
+		case '<':
+			return "HERESTRING", ""
+		case '-':
+			return "HEREDOC", "-"
+		case 0:
+		default:
+			pushback(b)
 		}
+		return "HEREDOC", ""
 	case ' ', '\t':
 		return "white", string(c)
+	case '(', ')':
+		return string(c), string(c)
+	// Comments run to the end of the line. This also takes care of
+	// the #! line at the top of scripts.
+	case '#':
+		for {
+			nc := one(b)
+			if nc == 0 {
+				return "EOF", ""
+			}
+			if nc == '\n' {
+				return "EOL", ""
+			}
+		}
 	case '\n':
 		//fmt.Printf("NEWLINE\n")
 		return "EOL", ""
+	// ; ends a command just like a newline, but we need to know that
+	// more commands follow on the same line.
+	case ';':
+		return "SEMI", ""
 	case '|', '&':
 		//fmt.Printf("LINK %v\n", c)
-		// peek ahead. We need the literal, so don't use next()
+		// peek ahead. We need the literal.
 		nc := one(b)
 		if nc == c {
 			//fmt.Printf("LINK %v\n", string(c)+string(c))
 			return "LINK", string(c) + string(c)
 		}
+		// &> sends both stdout and stderr to a file.
+		if c == '&' && nc == '>' {
+			t, fd := redirOut(b, "&")
+			if t == "DUP" {
+				panic(errors.New("&>& is not a redirect"))
+			}
+			return t, fd
+		}
 		pushback(b)
 		if c == '&' {
 			//fmt.Printf("BG\n")
@@ -138,68 +180,267 @@ func tok(b *bufio.Reader) (string, string) {
 		}
 		//fmt.Printf("LINK %v\n", string(c))
 		return "LINK", string(c)
+	case '\\':
+		// A \ at the end of a line joins it with the next.
+		if nc := one(b); nc == '\n' {
+			continueLine(b)
+			return "white", " "
+		} else if nc != 0 {
+			pushback(b)
+		}
+		fallthrough
 	default:
+		tokType = "ARG"
 		for {
-			if c == 0 {
-				return "ARG", arg
+			switch {
+			case c == 0:
+				return tokType, arg
+			// A number right before a redirect is the file
+			// descriptor to redirect.
+			case c == '>' && tokType == "ARG" && isNumber(arg):
+				return redirOut(b, arg)
+			case c == '<' && tokType == "ARG" && isNumber(arg):
+				return "FD", arg
+			case strings.IndexByte(punct, c) > -1:
+				pushback(b)
+				return tokType, arg
+			case c == '\\':
+				switch nc := one(b); nc {
+				case 0:
+					arg += "\\"
+				case '\n':
+					continueLine(b)
+				default:
+					arg += "\\" + string(nc)
+				}
+				tokType = "QUOTE"
+			case c == '\'', c == '"':
+				arg += string(c) + readQuote(b, c)
+				tokType = "QUOTE"
+			// Command substitutions are part of the word, whatever
+			// is in them. doArgs runs them.
+			case c == '`':
+				arg += "`" + readSubst(b, '`')
+			case c == '$':
+				arg += "$"
+				if nc := one(b); nc == '(' {
+					arg += "(" + readSubst(b, ')')
+				} else if nc != 0 {
+					pushback(b)
+				}
+			default:
+				arg += string(c)
+			}
+			c = one(b)
+		}
+	}
+}
+
+// continueLine prompts for the next line, if a word or line we are
+// reading goes on there.
+func continueLine(b *bufio.Reader) {
+	if interactive && b.Buffered() == 0 {
+		showPrompt("> ")
+	}
+}
+
+// readQuote reads the rest of a quoted string, up to and including the
+// closing quote q. Double quotes may hold backslash escapes and command
+// substitutions.
+func readQuote(b *bufio.Reader, q byte) string {
+	var s []byte
+	for {
+		c := one(b)
+		switch {
+		case c == 0:
+			panic(fmt.Errorf("syntax error: unterminated %c", q))
+		case c == '\n':
+			continueLine(b)
+		case q == '\'':
+		case c == '\\':
+			if c = one(b); c == 0 {
+				panic(fmt.Errorf("syntax error: unterminated %c", q))
 			}
-			if strings.Index(punct, string(c)) > -1 {
+			s = append(s, '\\', c)
+			continue
+		case c == '`':
+			s = append(s, c)
+			s = append(s, readSubst(b, '`')...)
+			continue
+		case c == '$':
+			if nc := one(b); nc == '(' {
+				s = append(s, "$("...)
+				s = append(s, readSubst(b, ')')...)
+				continue
+			} else if nc != 0 {
 				pushback(b)
-				return "ARG", arg
 			}
-			arg = arg + string(c)
-			c = next(b)
 		}
+		s = append(s, c)
+		if c == q {
+			return string(s)
+		}
+	}
+}
 
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// redirOut returns the token for an output redirect of fd, whose > we
+// just read. It is FD for >, APPEND for >>, CLOBBER for >| or DUP for >&.
+func redirOut(b *bufio.Reader, fd string) (string, string) {
+	switch nc := one(b); nc {
+	case '>':
+		return "APPEND", fd
+	case '|':
+		return "CLOBBER", fd
+	case '&':
+		return "DUP", fd
+	case 0:
+	default:
+		pushback(b)
 	}
+	return "FD", fd
+}
 
+// readSubst reads the text of a command substitution or group up to and
+// including end, which is `, ) or }. Nested $( ), ( ) and { } are included.
+func readSubst(b *bufio.Reader, end byte) string {
+	var s []byte
+	open := map[byte]byte{')': '(', '}': '{'}[end]
+	depth := 1
+	for {
+		c := one(b)
+		switch {
+		case c == 0:
+			panic(fmt.Errorf("missing %c", end))
+		case c == '\n':
+			continueLine(b)
+		case c == '\\':
+			s = append(s, c)
+			c = one(b)
+		case c == '"' && end != '`':
+			s = append(s, c)
+			s = append(s, readQuote(b, '"')...)
+			continue
+		case c == '\'' && end != '`':
+			// Parentheses in quotes don't count.
+			for s = append(s, c); c != 0; {
+				if c = one(b); c == 0 || c == '\'' {
+					break
+				}
+				s = append(s, c)
+			}
+		case c == open && end != '`':
+			depth++
+		case c == end:
+			depth--
+		}
+		s = append(s, c)
+		if c == end && depth == 0 {
+			return string(s)
+		}
+	}
 }
 
-// get an ARG. It has to work.
-func getArg(b *bufio.Reader, what string) string {
+// get an ARG. It has to work. We return its type, ARG or QUOTE, as well.
+func getArg(b *bufio.Reader, what string) (string, string) {
 	for {
 		nt, s := tok(b)
-		if nt == "EOF" || nt == "EOL" {
+		if nt == "EOF" || nt == "EOL" || nt == "SEMI" {
 			panic(fmt.Errorf("%v requires an argument", what))
 		}
 		if nt == "white" {
 			continue
 		}
-		if nt != "ARG" {
+		// Only files can be process substitutions.
+		if nt == "PROCSUB" && (what == "FD" || what == "APPEND" || what == "CLOBBER") {
+			return nt, s
+		}
+		if nt != "ARG" && nt != "QUOTE" {
 			panic(fmt.Errorf("%v requires an argument, not %v", what, nt))
 		}
-		return s
+		return nt, s
 	}
 }
 func parsestring(b *bufio.Reader, c *Command) (*Command, string) {
 	t, s := tok(b)
-	if s == "\n" || t == "EOF" || t == "EOL" {
+	if s == "\n" || t == "EOF" || t == "EOL" || t == "SEMI" {
 		return nil, t
 	}
 	for {
 		switch t {
-		case "ENV":
-			if !path.IsAbs(s) {
-				s = filepath.Join(envDir, s)
+		case "ARG", "QUOTE":
+			if c.group != nil {
+				panic(fmt.Errorf("syntax error: unexpected %v after %v", s, c.group))
 			}
-			b, err := ioutil.ReadFile(s)
-			if err != nil {
-				panic(fmt.Errorf("%s: %v", s, err))
+			// { starts a group only where a command starts.
+			if t == "ARG" && s == "{" && len(c.args) == 0 {
+				g := readSubst(b, '}')
+				c.group = newGroup(g[:len(g)-1], false)
+				break
 			}
-			f := bufio.NewReader(bytes.NewReader(b))
-			// the whole string is consumed.
-			parsestring(f, c)
-		case "ARG":
 			c.args = append(c.args, arg{s, t})
+		case "PROCSUB":
+			if c.group != nil {
+				panic(fmt.Errorf("syntax error: unexpected %v after %v", s, c.group))
+			}
+			c.args = append(c.args, arg{s[:len(s)-1], t})
+		case "(":
+			if c.group != nil || len(c.args) > 0 {
+				panic(errors.New("syntax error: unexpected ("))
+			}
+			g := readSubst(b, ')')
+			c.group = newGroup(g[:len(g)-1], true)
+		case ")":
+			panic(errors.New("syntax error: unexpected )"))
 		case "white":
-		case "FD":
+		case "FD", "APPEND", "CLOBBER", "DUP":
+			// whitespace is allowed
+			at, f := getArg(b, t)
+			ps := at == "PROCSUB"
+			if ps {
+				f = f[:len(f)-1]
+			}
+			// &> is >FILE 2>&1.
+			if s == "&" {
+				c.fdmap[1] = &redirect{file: f, append: t == "APPEND", clobber: t == "CLOBBER", procSub: ps}
+				c.fdmap[2] = &redirect{file: "1", dup: true}
+				break
+			}
 			x := 0
 			_, err := fmt.Sscanf(s, "%v", &x)
 			if err != nil {
 				panic(fmt.Errorf("bad FD on redirect: %v, %v", s, err))
 			}
-			// whitespace is allowed
-			c.fdmap[x] = getArg(b, t)
+			if x > 2 {
+				panic(fmt.Errorf("can only redirect fds 0, 1 and 2, not %v", x))
+			}
+			if t == "DUP" && (x == 0 || f != "1" && f != "2") {
+				panic(fmt.Errorf("bad FD on redirect: %v>&%v", s, f))
+			}
+			c.fdmap[x] = &redirect{file: f, dup: t == "DUP", append: t == "APPEND", clobber: t == "CLOBBER", procSub: ps}
+			if x == 0 {
+				c.doc = nil
+			}
+		case "HEREDOC":
+			at, delim := getArg(b, t)
+			c.doc = &heredoc{delim: unquote(delim), expand: at == "ARG", stripTabs: s == "-"}
+			delete(c.fdmap, 0)
+			pendingDocs = append(pendingDocs, c.doc)
+		case "HERESTRING":
+			_, word := getArg(b, t)
+			c.doc = &heredoc{text: word, word: true}
+			delete(c.fdmap, 0)
 		// LINK and BG are similar save that LINK requires another command. If we don't get one, well.
 		case "LINK":
 			c.link = s
@@ -208,9 +449,11 @@ func parsestring(b *bufio.Reader, c *Command) (*Command, string) {
 		case "BG":
 			c.bg = true
 			return c, t
-		case "EOF":
-			return c, t
-		case "EOL":
+		case "EOF", "EOL", "SEMI":
+			// Only white space is no command at all.
+			if len(c.args) == 0 && c.group == nil && len(c.fdmap) == 0 && c.doc == nil {
+				return nil, t
+			}
 			return c, t
 		default:
 			panic(fmt.Errorf("unknown token type %v", t))
@@ -225,20 +468,24 @@ func parse(b *bufio.Reader) (*Command, string) {
 }
 
 func newCommand() *Command {
-	return &Command{fdmap: make(map[int]string), files: make(map[int]io.Closer)}
+	return &Command{fdmap: make(map[int]*redirect), files: make(map[int]io.Closer)}
 }
 
 // Just eat it up until you have all the commands you need.
+// Here-documents follow the line, so we read them last.
 func parsecommands(b *bufio.Reader) ([]*Command, string) {
 	cmds := make([]*Command, 0)
 	for {
 		c, t := parse(b)
-		if c == nil {
-			return cmds, t
+		if c != nil {
+			//fmt.Printf("cmd  %v\n", *c)
+			cmds = append(cmds, c)
 		}
-		//fmt.Printf("cmd  %v\n", *c)
-		cmds = append(cmds, c)
-		if t == "EOF" || t == "EOL" {
+		if c == nil || t == "EOF" || t == "EOL" || t == "SEMI" {
+			if t == "SEMI" && len(pendingDocs) > 0 {
+				panic(errors.New("a here-document must be the last command on its line"))
+			}
+			readDocs(b)
 			return cmds, t
 		}
 	}
@@ -248,6 +495,7 @@ func getCommand(b *bufio.Reader) (c []*Command, t string, err error) {
 	defer func() {
 		if e := recover(); e != nil {
 			err = e.(error)
+			pendingDocs = nil
 		}
 	}()
 
@@ -257,16 +505,16 @@ func getCommand(b *bufio.Reader) (c []*Command, t string, err error) {
 	// For now, no empty commands.
 	// Can't have a redir and a redirect for fd1.
 	for i, v := range c {
-		if len(v.args) == 0 {
+		if len(v.args) == 0 && v.group == nil {
 			return nil, "", errors.New("empty commands not allowed (yet)")
 		}
-		if v.link == "|" && v.fdmap[1] != "" {
+		if v.link == "|" && v.fdmap[1] != nil {
 			return nil, "", errors.New("Can't have a pipe and > on one command")
 		}
 		if v.link == "|" && i == len(c)-1 {
 			return nil, "", errors.New("Can't have a pipe to nowhere")
 		}
-		if i < len(c)-1 && v.link == "|" && c[i+1].fdmap[0] != "" {
+		if i < len(c)-1 && v.link == "|" && (c[i+1].fdmap[0] != nil || c[i+1].doc != nil) {
 			return nil, "", errors.New("Can't have a pipe to command with redirect on stdin")
 		}
 	}
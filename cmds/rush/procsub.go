@@ -0,0 +1,69 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Process substitution.
+//
+// Synopsis:
+//     <(LIST)
+//     >(LIST)
+//
+// Description:
+//     <(LIST) is replaced by the name of a file from which the output of
+//     LIST can be read, and >(LIST) by the name of a file whose contents
+//     are the input of LIST. LIST runs in a new rush, like a subshell, at
+//     the same time as the command. The files are pipes, named by their
+//     /proc/self/fd path.
+//
+// Example:
+//     diff <(ls /a) <(ls /b)
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// procSub starts the LIST of a process substitution, whose text is s
+// without the ), and returns the name of the pipe it reads or writes.
+// If c is a builtin, the name is for rush; otherwise it is for the
+// process c starts, which gets the pipe as an extra file.
+func procSub(c *Command, s string, builtin bool) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	l := &exec.Cmd{Path: self, Args: []string{"(", s[1:]}, Stdin: stdin, Stdout: stdout, Stderr: stderr}
+	// ours is the end of the pipe the command uses.
+	ours, theirs := r, w
+	if s[0] == '>' {
+		ours, theirs = w, r
+		l.Stdin = r
+	} else {
+		l.Stdout = w
+	}
+	err = start(l)
+	theirs.Close()
+	if err != nil {
+		ours.Close()
+		return "", err
+	}
+	// Nobody waits for the list but us.
+	go func() {
+		l.Wait()
+		forget(l.Process.Pid)
+	}()
+
+	fd := int(ours.Fd())
+	if !builtin {
+		fd = 3 + len(c.extra)
+		c.extra = append(c.extra, ours)
+	}
+	c.files[fd] = ours
+	return fmt.Sprintf("/proc/self/fd/%d", fd), nil
+}
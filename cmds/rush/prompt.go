@@ -0,0 +1,96 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Prompt.
+//
+// Description:
+//     The prompt is $PS1, or "% " if PS1 is not set. Like any variable, PS1
+//     may come from the environment directory. These escapes in it are
+//     replaced:
+//         \u      the user name
+//         \h      the host name up to the first '.'
+//         \H      the host name
+//         \w      the working directory, with $HOME shown as ~
+//         \W      the last element of the working directory
+//         \$      # for root, $ for everyone else
+//         \e      an escape character, e.g. \e[31m turns the prompt red
+//         \n      a newline
+//         \\      a backslash
+//         \[, \]  nothing; bash uses them to mark colors
+//
+// Example:
+//     export 'PS1=\e[32m\u@\h\e[0m:\w\$ '
+package main
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultPrompt = "% "
+
+// prompt returns the expanded $PS1.
+func prompt() string {
+	ps1 := lookupVar("PS1")
+	if ps1 == "" {
+		return defaultPrompt
+	}
+	var p []string
+	for i := 0; i < len(ps1); i++ {
+		if ps1[i] != '\\' || i == len(ps1)-1 {
+			p = append(p, ps1[i:i+1])
+			continue
+		}
+		i++
+		switch ps1[i] {
+		case 'u':
+			p = append(p, userName())
+		case 'h', 'H':
+			h, _ := os.Hostname()
+			if ps1[i] == 'h' {
+				h = strings.SplitN(h, ".", 2)[0]
+			}
+			p = append(p, h)
+		case 'w', 'W':
+			wd, _ := os.Getwd()
+			if ps1[i] == 'W' {
+				wd = filepath.Base(wd)
+			} else if home := os.Getenv("HOME"); home != "" && home != "/" && (wd == home || strings.HasPrefix(wd, home+"/")) {
+				wd = "~" + wd[len(home):]
+			}
+			p = append(p, wd)
+		case '$':
+			if os.Geteuid() == 0 {
+				p = append(p, "#")
+			} else {
+				p = append(p, "$")
+			}
+		case 'e':
+			p = append(p, "\x1b")
+		case 'n':
+			p = append(p, "\n")
+		case '\\':
+			p = append(p, "\\")
+		case '[', ']':
+		default:
+			p = append(p, ps1[i-1:i+1])
+		}
+	}
+	return strings.Join(p, "")
+}
+
+// userName returns the name of the user, or the user id if there is
+// none, which is not unusual in an initramfs.
+func userName() string {
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Geteuid())
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Quoting.
+//
+// Synopsis:
+//     'TEXT'
+//     "TEXT"
+//     \C
+//
+// Description:
+//     Quotes make spaces and other special characters part of an argument.
+//     Nothing is special in single quotes. In double quotes, variables and
+//     command substitutions are expanded, and \ escapes only $, `, " and \.
+//     Outside quotes, \ takes the next character literally, and a \ at the
+//     end of a line joins it with the next one. Quoted text is never used
+//     as a glob pattern, and a quoted word is never a keyword or an alias.
+//
+// Example:
+//     echo "$HOME is home" 'and $HOME is not' \$HOME\ neither
+package main
+
+import (
+	"strings"
+)
+
+// A word collects an argument as it is expanded. lit is the argument and
+// pat the same argument as a pattern for filepath.Glob, with the quoted
+// parts escaped.
+type word struct {
+	lit, pat []byte
+	// glob is set if the unquoted parts have glob characters.
+	glob bool
+}
+
+func (w *word) add(s string, quoted bool) {
+	w.lit = append(w.lit, s...)
+	if !quoted {
+		w.pat = append(w.pat, s...)
+		w.glob = w.glob || strings.ContainsAny(s, "*?[")
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(`*?[\`, s[i]) >= 0 {
+			w.pat = append(w.pat, '\\')
+		}
+		w.pat = append(w.pat, s[i])
+	}
+}
+
+// expandWord removes the quotes from an argument as the parser read it,
+// and expands variables and command substitutions outside single quotes.
+// It returns the argument and, if glob is set, a pattern to match files
+// against instead.
+func expandWord(s string) (lit, pat string, glob bool) {
+	w := scan(s, false)
+	return string(w.lit), string(w.pat), w.glob
+}
+
+// unquote removes the quotes from s without expanding anything.
+func unquote(s string) string {
+	var w word
+	var double bool
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' && !double:
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				j = len(s) - i - 1
+			}
+			w.add(s[i+1:i+1+j], true)
+			i += j + 1
+		case c == '"':
+			double = !double
+		case c == '\\' && i+1 < len(s) && (!double || strings.IndexByte("$`\"\\", s[i+1]) >= 0):
+			i++
+			w.add(s[i:i+1], true)
+		default:
+			w.add(s[i:i+1], true)
+		}
+	}
+	return string(w.lit)
+}
+
+// scan expands s. The text of a here-document is scanned as if it were
+// in double quotes, except that " is not special.
+func scan(s string, doc bool) *word {
+	var w word
+	double := doc
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' && !double:
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				j = len(s) - i - 1
+			}
+			w.add(s[i+1:i+1+j], true)
+			i += j + 1
+		case c == '"' && !doc:
+			double = !double
+		case c == '\\' && i+1 < len(s):
+			// In double quotes, \ only escapes what is special there.
+			e := "$`\"\\\n"
+			if doc {
+				e = "$`\\\n"
+			}
+			if double && strings.IndexByte(e, s[i+1]) < 0 {
+				w.add("\\", true)
+				break
+			}
+			i++
+			if s[i] != '\n' {
+				w.add(s[i:i+1], true)
+			}
+		case c == '$' || c == '`':
+			v, n := dollar(s[i:])
+			w.add(v, double)
+			i += n - 1
+		default:
+			w.add(s[i:i+1], double)
+		}
+	}
+	return &w
+}
+
+// dollar expands the variable or command substitution s starts with. It
+// returns its value and the length of its text in s.
+func dollar(s string) (string, int) {
+	switch {
+	case s[0] == '`':
+		end := matchBackquote(s, 1)
+		return substitute(strings.NewReplacer("\\`", "`", "\\\\", "\\").Replace(s[1:end])), end + 1
+	case len(s) < 2:
+	case s[1] == '(':
+		end := matchParen(s, 2)
+		return substitute(s[2:end]), end + 1
+	case s[1] == '{':
+		if end := strings.IndexByte(s, '}'); end > 2 {
+			return lookupVar(s[2:end]), end + 1
+		}
+	case strings.IndexByte("*#$@!?-0123456789", s[1]) >= 0:
+		return lookupVar(s[1:2]), 2
+	default:
+		n := 1
+		for n < len(s) && isNameChar(s[n]) {
+			n++
+		}
+		if n > 1 {
+			return lookupVar(s[1:n]), n
+		}
+	}
+	return "$", 1
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
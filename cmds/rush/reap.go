@@ -0,0 +1,92 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	// childMu protects children. It is held while a child is started,
+	// so that the reaper never sees a child before it is in children.
+	childMu sync.Mutex
+	// children are the processes started by rush which it has not
+	// waited for yet.
+	children = map[int]bool{}
+)
+
+// start starts c and remembers it is ours.
+func start(c *exec.Cmd) error {
+	childMu.Lock()
+	defer childMu.Unlock()
+	if err := c.Start(); err != nil {
+		return err
+	}
+	children[c.Process.Pid] = true
+	return nil
+}
+
+// forget is called once a child has been waited for.
+func forget(pid int) {
+	childMu.Lock()
+	defer childMu.Unlock()
+	delete(children, pid)
+}
+
+// reapOrphans waits for processes which are not ours, but became our
+// children when their parent died. Only init gets those, so it is
+// started if rush is init.
+func reapOrphans() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGCHLD)
+	go func() {
+		for range sigs {
+			childMu.Lock()
+			for _, pid := range zombies() {
+				if !children[pid] {
+					var ws syscall.WaitStatus
+					syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+				}
+			}
+			childMu.Unlock()
+		}
+	}()
+}
+
+// zombies returns the children of rush which have exited, but have not
+// been waited for.
+func zombies() []int {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil
+	}
+	me := os.Getpid()
+	var z []int
+	for _, d := range dirs {
+		b, err := ioutil.ReadFile(filepath.Join(d, "stat"))
+		if err != nil {
+			continue
+		}
+		// The command name may contain anything, even spaces and
+		// parentheses, so look at what follows the last ).
+		s := string(b)
+		f := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+		if len(f) < 2 || f[0] != "Z" || f[1] != strconv.Itoa(me) {
+			continue
+		}
+		if pid, err := strconv.Atoi(filepath.Base(d)); err == nil {
+			z = append(z, pid)
+		}
+	}
+	return z
+}
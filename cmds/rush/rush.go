@@ -4,125 +4,218 @@
 
 // Rush is an interactive shell similar to sh.
 //
+// Synopsis:
+//     rush [SCRIPT [ARG...]]
+//     rush -c COMMANDS [NAME [ARG...]]
+//
 // Description:
-//     Prompt is '% '.
+//     The prompt is '% ', unless $PS1 says otherwise.
+//
+//     If SCRIPT is given, commands are read from it instead of stdin and no
+//     prompt is printed. A leading '#!' line, like any other '#' comment,
+//     is ignored, so scripts may start with '#!/bin/rush'. With -c, the
+//     commands are COMMANDS instead, e.g. for other programs to run a
+//     one-liner. Either way rush exits with the status of the last
+//     command, and $0 is SCRIPT, or NAME, and $1 to $9 the ARGs; $# is
+//     how many there are and $@ all of them.
+//
+//     An interactive rush first runs the commands in /etc/rushrc and then
+//     those in ~/.rushrc, if they exist.
+//
+//     Redirections:
+//         < FILE, 0< FILE    read stdin from FILE
+//         > FILE, 1> FILE    write stdout to FILE
+//         >> FILE            append stdout to FILE
+//         >| FILE            write stdout to FILE, even with set -C
+//         2> FILE, 2>> FILE  write or append stderr to FILE
+//         2>&1, 1>&2         send stderr to stdout, or stdout to stderr
+//         &> FILE, &>> FILE  write or append both stdout and stderr to FILE
+//     A >& copies wherever the other output goes once all other
+//     redirections and pipes are set up, so "cmd 2>&1 | less" and
+//     "cmd >FILE 2>&1" both capture stderr.
 package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 )
 
 type builtin func(c *Command) error
 
-// TODO: probably have one builtin map and use it for both types?
+// A builtinCmd is a command implemented by rush itself.
+type builtinCmd struct {
+	name string
+	// help is a one line description, shown by the help builtin.
+	help string
+	run  builtin
+	// Some builtins really want to be forked off, esp. in the busybox
+	// case. These are not run by the shell; rush runs them instead of
+	// itself when it is invoked by their name.
+	fork bool
+}
+
 var (
 	urpath   = "/go/bin:/ubin:/buildbin:/bbin:/bin:/usr/local/bin:"
-	builtins = make(map[string]builtin)
-	// Some builtins really want to be forked off, esp. in the busybox case.
-	forkBuiltins = make(map[string]builtin)
+	builtins = make(map[string]*builtinCmd)
 	// the environment dir is INTENDED to be per-user and bound in
 	// a private name space at /env.
 	envDir = "/env"
+	// interactive is set if rush is reading commands from stdin
+	// rather than a script.
+	interactive bool
+	// commandString is set by -c.
+	commandString = flag.Bool("c", false, "run the commands in the first argument")
+	// positional are the arguments of the script, $0 to $9.
+	positional = []string{"rush"}
+	// stdin, stdout and stderr are the files of commands which are
+	// not redirected. Command substitutions and groups change them.
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
 )
 
-func addBuiltIn(name string, f builtin) error {
-	if _, ok := builtins[name]; ok {
-		return fmt.Errorf("%v already a builtin", name)
+func register(b *builtinCmd) error {
+	if _, ok := builtins[b.name]; ok {
+		return fmt.Errorf("%v already a builtin", b.name)
 	}
-	builtins[name] = f
+	builtins[b.name] = b
 	return nil
 }
 
-func addForkBuiltIn(name string, f builtin) error {
-	if _, ok := builtins[name]; ok {
-		return fmt.Errorf("%v already a forkBuiltin", name)
+func addBuiltIn(name, help string, f builtin) error {
+	return register(&builtinCmd{name: name, help: help, run: f})
+}
+
+func addForkBuiltIn(name, help string, f builtin) error {
+	return register(&builtinCmd{name: name, help: help, run: f, fork: true})
+}
+
+// getBuiltin returns the builtin the shell runs for name, if there is one.
+func getBuiltin(name string) (builtin, bool) {
+	b, ok := builtins[name]
+	if !ok || b.fork {
+		return nil, false
 	}
-	forkBuiltins[name] = f
-	return nil
+	return b.run, true
 }
 
 func wire(cmds []*Command) error {
+	// Pipes go first, so that redirects like 2>&1 can copy them.
+	// The validation is such that "|" is not set on the last one.
+	// Also, there won't be redirects and "|" inappropriately.
 	for i, c := range cmds {
+		if c.link != "|" {
+			continue
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		c.Stdout, c.files[1] = w, w
+		cmds[i+1].Stdin, cmds[i+1].files[0] = r, r
+	}
+	for _, c := range cmds {
 		// IO defaults.
 		var err error
 		if c.Stdin == nil {
-			if c.Stdin, err = openRead(c, os.Stdin, 0); err != nil {
+			if c.Stdin, err = openRead(c, stdin, 0); err != nil {
 				return err
 			}
 		}
-		if c.link != "|" {
-			if c.Stdout, err = openWrite(c, os.Stdout, 1); err != nil {
+		if c.Stdout == nil {
+			if c.Stdout, err = openWrite(c, stdout, 1); err != nil {
 				return err
 			}
 		}
-		if c.Stderr, err = openWrite(c, os.Stderr, 2); err != nil {
+		if c.Stderr, err = openWrite(c, stderr, 2); err != nil {
 			return err
 		}
-		// The validation is such that "|" is not set on the last one.
-		// Also, there won't be redirects and "|" inappropriately.
-		if c.link != "|" {
-			continue
-		}
-		w, err := cmds[i+1].StdinPipe()
-		if err != nil {
-			return err
-		}
-		r, err := cmds[i].StdoutPipe()
-		if err != nil {
-			return err
-		}
-		// Oh, yuck.
-		// There seems to be no way to do the classic
-		// inherited pipes thing in Go. Hard to believe.
-		go func() {
-			io.Copy(w, r)
-			w.Close()
-		}()
+		dup(c)
 	}
 	return nil
 }
 
+// dup handles N>&M redirects. M is where its output ends up after all other
+// redirects, so 2>&1 sends stderr wherever stdout goes.
+func dup(c *Command) {
+	out := map[string]io.Writer{"1": c.Stdout, "2": c.Stderr}
+	if r := c.fdmap[1]; r != nil && r.dup && out[r.file] != nil {
+		c.Stdout = out[r.file]
+	}
+	if r := c.fdmap[2]; r != nil && r.dup && out[r.file] != nil {
+		c.Stderr = out[r.file]
+	}
+}
+
+// closeFiles closes the files the shell opened for a command. Once the
+// command is started, the shell has no more use for them.
+func closeFiles(c *Command) {
+	for fd, f := range c.files {
+		f.Close()
+		delete(c.files, fd)
+	}
+}
+
 func runit(c *Command) error {
-	defer func() {
-		for fd, f := range c.files {
-			f.Close()
-			delete(c.files, fd)
-		}
-	}()
-	if b, ok := builtins[c.cmd]; ok {
-		if err := b(c); err != nil {
+	defer closeFiles(c)
+	if b, ok := getBuiltin(c.cmd); ok {
+		if err := runBuiltin(b, c); err != nil {
 			return err
 		}
 	} else {
 		c.Cmd.SysProcAttr = &syscall.SysProcAttr{}
 		if c.bg {
 			c.Cmd.SysProcAttr.Setpgid = true
-		} else {
+		} else if ttyf != nil {
 			c.Cmd.SysProcAttr.Foreground = true
 			c.Cmd.SysProcAttr.Ctty = int(ttyf.Fd())
 		}
-		if err := c.Start(); err != nil {
+		if err := start(c.Cmd); err != nil {
 			return fmt.Errorf("%v: Path %v", err, os.Getenv("PATH"))
 		}
-		if err := c.Wait(); err != nil {
+		err := c.Wait()
+		forget(c.Process.Pid)
+		if err != nil {
 			return fmt.Errorf("wait: %v", err)
 		}
 	}
 	return nil
 }
 
+// redirectName returns the name of the file of a redirect.
+func redirectName(c *Command, r *redirect) (string, error) {
+	if r.procSub {
+		// rush opens the file, not the command.
+		return procSub(c, r.file, true)
+	}
+	name, _, _ := expandWord(r.file)
+	return name, nil
+}
+
 func openRead(c *Command, r io.Reader, fd int) (io.Reader, error) {
-	if c.fdmap[fd] != "" {
-		f, err := os.Open(c.fdmap[fd])
+	if fd == 0 && c.doc != nil {
+		f, err := c.doc.open()
+		if err == nil {
+			c.files[fd] = f
+		}
+		return f, err
+	}
+	if r := c.fdmap[fd]; r != nil && !r.dup {
+		name, err := redirectName(c, r)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(name)
 		c.files[fd] = f
 		return f, err
 	}
@@ -130,8 +223,21 @@ func openRead(c *Command, r io.Reader, fd int) (io.Reader, error) {
 }
 
 func openWrite(c *Command, w io.Writer, fd int) (io.Writer, error) {
-	if c.fdmap[fd] != "" {
-		f, err := os.Create(c.fdmap[fd])
+	if r := c.fdmap[fd]; r != nil && !r.dup {
+		name, err := redirectName(c, r)
+		if err != nil {
+			return nil, err
+		}
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if r.append {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		} else if noclobber && !r.clobber {
+			// Devices like /dev/null are still fine.
+			if fi, err := os.Stat(name); err == nil && fi.Mode().IsRegular() {
+				return nil, fmt.Errorf("%v: cannot overwrite existing file", name)
+			}
+		}
+		f, err := os.OpenFile(name, flags, 0666)
 		c.files[fd] = f
 		return f, err
 	}
@@ -140,24 +246,46 @@ func openWrite(c *Command, w io.Writer, fd int) (io.Writer, error) {
 
 func doArgs(cmds []*Command) error {
 	for _, c := range cmds {
+		if c.group != nil {
+			continue
+		}
+		// Assignments before the command are for its environment.
+		args := c.args
+		c.env = nil
+		for len(args) > 0 && args[0].mod != "PROCSUB" && isAssignment(args[0].val) {
+			i := strings.Index(args[0].val, "=")
+			v, _, _ := expandWord(args[0].val[i+1:])
+			c.env = append(c.env, args[0].val[:i+1]+v)
+			args = args[1:]
+		}
+		// Without a command, they are for the shell.
+		if len(args) == 0 {
+			c.cmd, c.argv, c.env = "export", c.env, nil
+			continue
+		}
 		globargv := []string{}
-		for _, v := range c.args {
-			if v.mod == "ENV" {
-				e := v.val
-				if !path.IsAbs(v.val) {
-					e = filepath.Join(envDir, e)
+		for _, v := range args {
+			if v.mod == "PROCSUB" {
+				builtin := false
+				if len(globargv) > 0 {
+					_, builtin = getBuiltin(globargv[0])
 				}
-				b, err := ioutil.ReadFile(e)
+				f, err := procSub(c, v.val, builtin)
 				if err != nil {
 					return err
 				}
-				// It goes in as one argument. Not sure if this is what we want
-				// but it gets very weird to start splitting it on spaces. Or maybe not?
-				globargv = append(globargv, string(b))
-			} else if globs, err := filepath.Glob(v.val); err == nil && len(globs) > 0 {
-				globargv = append(globargv, globs...)
-			} else {
-				globargv = append(globargv, v.val)
+				globargv = append(globargv, f)
+				continue
+			}
+			for _, w := range braceExpand(v.val) {
+				e, pat, isGlob := expandWord(w)
+				if !isGlob {
+					globargv = append(globargv, e)
+				} else if globs, err := glob(pat); err == nil && len(globs) > 0 {
+					globargv = append(globargv, globs...)
+				} else {
+					globargv = append(globargv, e)
+				}
 			}
 		}
 
@@ -167,41 +295,171 @@ func doArgs(cmds []*Command) error {
 	return nil
 }
 
+// installable returns the command to run for name and its args. In
+// source-based images, commands which are not in the PATH are built from
+// their source by installcommand, into /ubin, the first time they are run;
+// after that they are in the PATH.
+func installable(name string, args []string) (string, []string) {
+	if _, ok := builtins[name]; ok || strings.Contains(name, "/") {
+		return name, args
+	}
+	if _, err := exec.LookPath(name); err == nil {
+		return name, args
+	}
+	ic, err := exec.LookPath("installcommand")
+	if err != nil {
+		return name, args
+	}
+	return ic, append([]string{name}, args...)
+}
+
 // There seems to be no harm in creating a Cmd struct
 // even for builtins, so for now, we do.
 // It will, however, do a path lookup, which we really don't need,
 // and we may change it later.
 func commands(cmds []*Command) error {
 	for _, c := range cmds {
-		c.Cmd = exec.Command(c.cmd, c.argv[:]...)
+		if c.group != nil {
+			// Subshells, and { } groups which can not run
+			// in the shell, are run by a new rush.
+			c.cmd, c.argv = "(", []string{c.group.text}
+			if !c.group.subshell && len(cmds) == 1 && !c.bg {
+				c.cmd = "{"
+			}
+			self, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			c.Cmd = &exec.Cmd{Path: self, Args: []string{"(", c.group.text}}
+			continue
+		}
+		name, argv := installable(c.cmd, c.argv)
+		c.Cmd = exec.Command(name, argv...)
+		c.Cmd.ExtraFiles = c.extra
+		if len(c.env) > 0 {
+			c.Cmd.Env = mergeEnv(os.Environ(), c.env)
+		}
 		// this is a Very Special Case related to a Go issue.
 		// we're not able to unshare correctly in builtin.
 		// Not sure of the issue but this hack will have to do until
 		// we understand it. Barf.
 		if c.cmd == "builtin" {
+			c.Cmd.SysProcAttr = &syscall.SysProcAttr{}
 			c.Cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS
 		}
 	}
 	return nil
 }
-func command(c *Command) error {
-	// for now, bg will just happen in background.
-	if c.bg {
-		go func() {
-			if err := runit(c); err != nil {
-				fmt.Fprintf(os.Stderr, "%v", err)
+
+// command runs the pipeline p and sets $? and $PIPESTATUS. A lone builtin
+// is run by the shell itself; anything else is started as a job, which is
+// waited for unless it was put in the background with &.
+func command(p []*Command) (err error) {
+	var j *job
+	defer func() {
+		if j != nil && !j.bg {
+			setStatus(j.errs...)
+		} else {
+			setStatus(err)
+		}
+	}()
+	if p[0].timed {
+		start := time.Now()
+		defer func() {
+			if j == nil || !j.bg {
+				printTimes(time.Since(start), j)
 			}
 		}()
-	} else {
-		err := runit(c)
+	}
+	if err := doArgs(p); err != nil {
+		for _, c := range p {
+			closeFiles(c)
+		}
+		return fmt.Errorf("args problem: %v", err)
+	}
+	if err := commands(p); err != nil {
+		return err
+	}
+	if err := wire(p); err != nil {
+		for _, c := range p {
+			closeFiles(c)
+		}
+		return err
+	}
+	last := p[len(p)-1]
+	if _, ok := getBuiltin(last.cmd); ok && len(p) == 1 && !last.bg {
+		return runit(last)
+	}
+	if j, err = startJob(p); err != nil {
 		return err
 	}
+	if j.bg {
+		if c := p[len(p)-1]; c.Process != nil {
+			lastBg = c.Process.Pid
+		}
+		fmt.Fprintf(os.Stderr, "[%d] %d\n", j.id, j.pgid)
+		return nil
+	}
+	return j.wait()
+}
+
+// showPrompt prints the prompt, or, if there is a line editor,
+// has it print the prompt when it starts reading.
+func showPrompt(p string) {
+	if ed != nil {
+		ed.prompt = p
+		return
+	}
+	fmt.Print(p)
+}
+
+// runCommands runs the commands read from r, without prompting.
+// It stops at the first line it can not parse.
+func runCommands(r io.Reader) error {
+	defer func(i bool) { interactive = i }(interactive)
+	interactive = false
+	p := newParser(bufio.NewReader(r))
+	for {
+		s, err := p.statement()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			run(s, false)
+		}
+	}
+}
+
+// runFile runs the commands in the file name.
+func runFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := runCommands(f); err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
 	return nil
 }
 
-func main() {
-	b := bufio.NewReader(os.Stdin)
+// runRC runs the startup files.
+func runRC() {
+	rc := []string{"/etc/rushrc"}
+	if home := os.Getenv("HOME"); home != "" {
+		rc = append(rc, filepath.Join(home, ".rushrc"))
+	}
+	for _, n := range rc {
+		if err := runFile(n); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+}
 
+func main() {
 	defer func() {
 		switch err := recover().(type) {
 		case nil:
@@ -213,59 +471,77 @@ func main() {
 	}()
 
 	// we use path.Base in case they type something like ./cmd
-	if f, ok := forkBuiltins[path.Base(os.Args[0])]; ok {
-		if err := f(&Command{cmd: os.Args[0], Cmd: &exec.Cmd{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}, argv: os.Args[1:]}); err != nil {
+	if b, ok := builtins[path.Base(os.Args[0])]; ok && b.fork {
+		if err := b.run(&Command{cmd: os.Args[0], Cmd: &exec.Cmd{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}, argv: os.Args[1:]}); err != nil {
 			log.Fatalf("%v", err)
 		}
 		os.Exit(0)
 	}
 
-	if len(os.Args) != 1 {
-		fmt.Println("no scripts/args yet")
-		os.Exit(1)
+	flag.Parse()
+	b := bufio.NewReader(os.Stdin)
+	positional = []string{os.Args[0]}
+	interactive = !*commandString && flag.NArg() == 0
+	switch {
+	case *commandString:
+		if flag.NArg() == 0 {
+			log.Fatalf("rush: -c needs commands to run")
+		}
+		b = bufio.NewReader(strings.NewReader(flag.Arg(0)))
+		if flag.NArg() > 1 {
+			positional = flag.Args()[1:]
+		}
+	case !interactive:
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("rush: %v", err)
+		}
+		defer f.Close()
+		b = bufio.NewReader(f)
+		positional = flag.Args()
 	}
 
-	tty()
-	fmt.Printf("%% ")
+	if os.Getpid() == 1 {
+		reapOrphans()
+	}
+	if interactive {
+		tty()
+		handleSignals()
+		if e, err := newEditor(os.Stdin, os.Stdout); err == nil {
+			ed = e
+			b = bufio.NewReader(ed)
+			loadHistory()
+		}
+		runRC()
+		showPrompt(prompt())
+	}
+	p := newParser(b)
 	for {
 		foreground()
-		cmds, status, err := getCommand(b)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-		}
-		if err := doArgs(cmds); err != nil {
-			fmt.Fprintf(os.Stderr, "args problem: %v\n", err)
-			continue
-		}
-		if err := commands(cmds); err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			continue
+		s, err := p.statement()
+		if err == io.EOF {
+			break
 		}
-		if err := wire(cmds); err != nil {
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "%v\n", err)
-			continue
-		}
-		for _, c := range cmds {
-			if err := command(c); err != nil {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
-				if c.link == "||" {
-					continue
-				}
-				// yes, not needed, but useful so you know
-				// what goes on here.
-				if c.link == "&&" {
-					break
-				}
+			// As in sh, a syntax error is status 2, and ends a
+			// script or -c.
+			status = 2
+			if !interactive {
 				break
-			} else {
-				if c.link == "||" {
-					break
-				}
 			}
+			// Forget the rest of a statement we could not parse.
+			p.pending = nil
+		} else if s != nil {
+			run(s, false)
 		}
-		if status == "EOF" {
-			break
+		// More commands may follow on the same line.
+		if interactive && !p.semi {
+			reportJobs()
+			showPrompt(prompt())
 		}
-		fmt.Printf("%% ")
+	}
+	if !interactive {
+		os.Exit(status)
 	}
 }
@@ -22,14 +22,53 @@ var tests = []struct {
 	stderr string // output (regular expression)
 	ret    int    // output
 }{
-	// TODO: Create a `-c` flag for rush so stdout does not contain
-	// prompts, or have the prompt be derived from $PS1.
+	// TODO: Do not print prompts when stdin is not a terminal.
 	{"exit\n", "% ", "", 0},
 	{"exit 77\n", "% ", "", 77},
 	{"exit 1 2 3\n", "% % ", "Too many arguments\n", 0},
 	{"exit abcd\n", "% % ", "Non numeric argument\n", 0},
 	{"time cd .\n", "% % ", `real 0.0\d\d\n`, 0},
 	{"time sleep 0.25\n", "% % ", `real 0.2\d\d\nuser 0.00\d\nsys 0.00\d\n`, 0},
+	{"time sleep 0.25 | cat\n", "% % ", `real 0.2\d\d\nuser 0.00\d\nsys 0.00\d\n`, 0},
+	{"echo hello | tr a-z A-Z\n", "% HELLO\n% ", "", 0},
+	{"fg\n", "% % ", "fg: no current job\n", 0},
+	{"if false; then echo no; elif true; then echo yes; else echo no; fi\n", "% yes\n% ", "", 0},
+	{"while false; do echo no; done; until true; do echo no; done\n", "% % ", "", 0},
+	{"while false; do :; done\necho $?\n", "% % 0\n% ", "", 0},
+	{"X=\nwhile [ -z $X ]; do X=1; true; done\necho $?\nuntil [ -n $X ]; do true; done; echo $?\n", "% % % 0\n% 0\n% ", "", 0},
+	{"X=\nwhile [ -z $X ]; do X=1; sh -c 'exit 4'; done\necho $?\n", "% % % 4\n% ", "wait: exit status 4\n", 0},
+	{"if false; then :; fi\necho $?\nif true; then sh -c 'exit 2'; fi\necho $?\n", "% % 0\n% % 2\n% ", "wait: exit status 2\n", 0},
+	{"cat <<EOF\nhello\nEOF\ncat <<< there\n", "% hello\n% there\n% ", "", 0},
+	{"echo $(echo a $(echo b))c `echo d`\n", "% a bc d\n% ", "", 0},
+	{"true && false || echo yes\n", "% yes\n% ", "wait: exit status 1\n", 0},
+	{"false && echo no || echo yes\ntrue || echo no && echo yes\n", "% yes\n% yes\n% ", "wait: exit status 1\n", 0},
+	{"false || false && echo no; echo $?\ntrue && false || false && echo no || echo yes\n", "% 1\n% yes\n% ", "(wait: exit status 1\n){4}", 0},
+	{"set -e\nfalse\necho no\n", "% % ", "wait: exit status 1\n", 1},
+	{")\necho $?\n", "% % % 2\n% ", "syntax error: unexpected \\)\n", 0},
+	{"echo 'a\n", "% > % ", "syntax error: unterminated '\n", 0},
+	{"sh -c 'echo err >&2' 2>&1 | tr a-z A-Z\n", "% ERR\n% ", "", 0},
+	{"alias 'hi=echo hi' 'up=tr a-z A-Z'\nhi there | up\nunalias hi\nalias\n", "% % HI THERE\n% % alias up='tr a-z A-Z'\n% ", "", 0},
+	{"false | true\necho $? $PIPESTATUS\ntrue | sh -c 'exit 3'\necho $? $PIPESTATUS\n", "% % 0 1 0\n% % 3 0 3\n% ", "wait: exit status 3\n", 0},
+	{`echo "a  b" 'c $HOME' \$x a\ b "$(echo ")")" it\'s` + "\n", `% a  b c \$HOME \$x a b \) it's\n% `, "", 0},
+	{"( cd /; echo a ) | tr a-z A-Z; { cd /; echo $PWD; } | cat; { cd /; }; pwd\n", "% A\n/\n/\n% ", "", 0},
+	{"export 'PS1=\\W\\$ '\ncd /\n", `% rush[$#] /[$#] `, "", 0},
+	{"cat <(echo a) - < <(echo b)\n", "% a\nb\n% ", "", 0},
+	{"X='a b' Y=1\nY=2 sh -c 'echo $X $Y'; echo $Y\n", "% % a b 2\n1\n% ", "", 0},
+	{"sh -c 'exit 3' &\nwait $!\necho $?\n", "% % % 3\n% ", `\[1\] \d+\nwait: exit status 3\n`, 0},
+	{"echo {a,b}{1,2} '{c,d}' ${X}{e}\n", "% a1 a2 b1 b2 {c,d} {e}\n% ", "", 0},
+	{"cd $(mktemp -d)\nset -C\necho a > f\necho b > f\necho c >| f\necho d >> f\ncat f\nrm -r $PWD\n", "% % % % % % % c\nd\n% % ", "f: cannot overwrite existing file\n", 0},
+	{"cd $(mktemp -d)\necho a > f\necho b > f\nset -C\necho c >> f\necho d >| f\necho e > /dev/null\ncat f\nrm -r $PWD\n", "% % % % % % % % d\n% % ", "", 0},
+	{"export FOO=bar\necho $FOO ${FOO}x '$FOO' $UNSET.\n", `% % bar barx \$FOO \.\n% `, "", 0},
+}
+
+// compile builds rush into dir and returns the path to the binary.
+func compile(t *testing.T, dir string) string {
+	rushPath := filepath.Join(dir, "rush")
+	out, err := exec.Command("go", "build", "-o", rushPath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go build -o %v cmds/rush: %v\n%s", rushPath, err, string(out))
+	}
+	return rushPath
 }
 
 func TestRush(t *testing.T) {
@@ -41,11 +80,7 @@ func TestRush(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// Compile rush
-	rushPath := filepath.Join(tmpDir, "rush")
-	out, err := exec.Command("go", "build", "-o", rushPath).CombinedOutput()
-	if err != nil {
-		t.Fatalf("go build -o %v cmds/rush: %v\n%s", rushPath, err, string(out))
-	}
+	rushPath := compile(t, tmpDir)
 
 	// Table-driven testing
 	for _, tt := range tests {
@@ -85,3 +120,99 @@ func TestRush(t *testing.T) {
 		}
 	}
 }
+
+func TestRushScript(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestRushScript")
+	if err != nil {
+		t.Fatal("TempDir failed: ", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rushPath := compile(t, tmpDir)
+	script := filepath.Join(tmpDir, "script")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/rush\n# a comment\n\nexit 3 # done\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(rushPath, script).CombinedOutput()
+	if len(out) != 0 {
+		t.Errorf("Want no output; Got: %q", out)
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("Want exit status 3; Got: %v", err)
+	}
+	if ret := exitErr.Sys().(syscall.WaitStatus).ExitStatus(); ret != 3 {
+		t.Errorf("Want: 3; Got: %d", ret)
+	}
+}
+
+func TestRushCommand(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestRushCommand")
+	if err != nil {
+		t.Fatal("TempDir failed: ", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rushPath := compile(t, tmpDir)
+	for _, tt := range []struct {
+		args []string
+		out  string
+		ret  int
+	}{
+		{[]string{"-c", "echo a; echo b"}, "a\nb\n", 0},
+		{[]string{"-c", "echo $0 $# $1 $2 $3. $@", "name", "x", "y"}, "name 2 x y . x y\n", 0},
+		{[]string{"-c", "echo $0", "-"}, "-\n", 0},
+		{[]string{"-c", "sh -c 'exit 3'"}, "", 3},
+		{[]string{"-c", "false\ntrue"}, "", 0},
+		{[]string{"-c", "true; false"}, "", 1},
+		{[]string{"-c", "exit 5; echo no"}, "", 5},
+		{[]string{"-c", ""}, "", 0},
+		{[]string{"-c", "if true; then echo a"}, "", 2},
+		{[]string{"-c", ")"}, "", 2},
+		{[]string{"-c", "echo $(echo"}, "", 2},
+		{[]string{"-c", "echo a\n)\necho b"}, "a\n", 2},
+		{[]string{"-c", `echo "unterminated`}, "", 2},
+		{[]string{"-c", "echo 'a\nb"}, "", 2},
+		{[]string{"-c", `echo "a\`}, "", 2},
+	} {
+		out, err := exec.Command(rushPath, tt.args...).Output()
+		if string(out) != tt.out {
+			t.Errorf("rush %q: Want: %q; Got: %q", tt.args, tt.out, out)
+		}
+		ret := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			ret = exitErr.Sys().(syscall.WaitStatus).ExitStatus()
+		} else if err != nil {
+			t.Errorf("rush %q: %v", tt.args, err)
+		}
+		if ret != tt.ret {
+			t.Errorf("rush %q: Want exit status %d; Got: %d", tt.args, tt.ret, ret)
+		}
+	}
+}
+
+func TestInstallable(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestInstallable")
+	if err != nil {
+		t.Fatal("TempDir failed: ", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rushPath := compile(t, tmpDir)
+	// A command which is not in the PATH is given to installcommand.
+	ic := filepath.Join(tmpDir, "installcommand")
+	if err := ioutil.WriteFile(ic, []byte("#!/bin/sh\necho built \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(rushPath)
+	cmd.Env = append(os.Environ(), "PATH="+tmpDir+":"+os.Getenv("PATH"))
+	cmd.Stdin = strings.NewReader("nosuchcommand a b\necho c\n")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("rush: %v", err)
+	}
+	if want := "% built nosuchcommand a b\n% c\n% "; string(out) != want {
+		t.Errorf("Want: %q; Got: %q", want, out)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Shell options.
+//
+// Synopsis:
+//     set [-+OPTIONS]...
+//
+// Description:
+//     -OPTIONS turns options on and +OPTIONS turns them off. Without
+//     arguments, set prints the options. The options are:
+//         C    do not let > overwrite existing files; >| still does
+//         e    exit as soon as a command fails, unless it is a condition or
+//              followed by && or ||
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Options.
+var (
+	errexit   bool
+	noclobber bool
+)
+
+// options maps option letters to the variable they set.
+var options = map[byte]*bool{
+	'C': &noclobber,
+	'e': &errexit,
+}
+
+func init() {
+	addBuiltIn("set", "set shell options", set)
+}
+
+func set(c *Command) error {
+	if len(c.argv) == 0 {
+		var letters []string
+		for l := range options {
+			letters = append(letters, string(l))
+		}
+		sort.Strings(letters)
+		for _, l := range letters {
+			on := "+"
+			if *options[l[0]] {
+				on = "-"
+			}
+			fmt.Fprintf(c.Stdout, "set %s%s\n", on, l)
+		}
+		return nil
+	}
+	for _, a := range c.argv {
+		if len(a) < 2 || (a[0] != '-' && a[0] != '+') {
+			return fmt.Errorf("usage: set [-+OPTIONS]...")
+		}
+		for i := 1; i < len(a); i++ {
+			o, ok := options[a[i]]
+			if !ok {
+				return fmt.Errorf("set: unknown option %c", a[i])
+			}
+			*o = a[0] == '-'
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Signals.
+//
+// Description:
+//     An interactive rush is not killed by SIGINT or SIGQUIT. If it gets
+//     one while waiting for a job in its own process group, it passes the
+//     signal on to the job. If no job is running, SIGINT just gets a new
+//     prompt.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleSignals keeps SIGINT and SIGQUIT from killing the shell.
+func handleSignals() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGQUIT)
+	go func() {
+		for s := range sigs {
+			jobsMu.Lock()
+			j := fgJob
+			jobsMu.Unlock()
+			if j == nil {
+				if s != syscall.SIGINT {
+					continue
+				}
+				interrupt()
+				if ed == nil {
+					fmt.Println()
+					showPrompt(prompt())
+				}
+				continue
+			}
+			// Without job control, the job shares our process group,
+			// so anything from the terminal has already reached it.
+			if ttyf != nil && j.pgid != 0 {
+				syscall.Kill(-j.pgid, s.(syscall.Signal))
+			}
+		}
+	}()
+}
+
+// interrupted returns true if err is from a command killed by SIGINT.
+func interrupted(err error) bool {
+	e, ok := err.(*exitError)
+	return ok && e.Signaled() && e.Signal() == syscall.SIGINT
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command substitution.
+//
+// Synopsis:
+//     $(COMMANDS)
+//     `COMMANDS`
+//
+// Description:
+//     The output of COMMANDS, with trailing newlines removed, replaces the
+//     substitution. Like a variable, it becomes part of a single argument.
+//     Substitutions may be nested; in backquotes, the inner backquotes must
+//     be escaped as \`. Changes to the working directory or environment made
+//     by COMMANDS do not last.
+//
+// Example:
+//     kexec -l $(ls /boot/vmlinuz-*)
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// expand replaces variables and command substitutions in the text
+// of a here-document.
+func expand(s string) string {
+	return string(scan(s, true).lit)
+}
+
+// matchParen returns the index of the ) which closes
+// the parenthesis before i, or len(s).
+func matchParen(s string, i int) int {
+	depth := 1
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			if j := strings.IndexByte(s[i+1:], '\''); j >= 0 {
+				i += j + 1
+			}
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// matchBackquote returns the index of the first unescaped ` in s,
+// starting at i, or len(s).
+func matchBackquote(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// substitute runs cmds and returns what they write to stdout.
+func substitute(cmds string) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ""
+	}
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&out, r)
+		r.Close()
+		close(done)
+	}()
+
+	defer func(o io.Writer) { stdout = o }(stdout)
+	stdout = w
+	defer restoreEnv(os.Environ())
+	if wd, err := os.Getwd(); err == nil {
+		defer os.Chdir(wd)
+	}
+
+	if err := runCommands(strings.NewReader(cmds)); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+	w.Close()
+	<-done
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// restoreEnv makes env the environment again.
+func restoreEnv(env []string) {
+	os.Clearenv()
+	for _, e := range env {
+		if i := strings.Index(e, "="); i > 0 {
+			os.Setenv(e[:i], e[i+1:])
+		}
+	}
+}
@@ -5,13 +5,16 @@
 // Time process execution.
 //
 // Synopsis:
-//     time CMD [ARG]...
+//     time PIPELINE
 //
 // Description:
-//     After executing CMD, its real, user and system times are printed to
-//     stderr in the POSIX format.
+//     After executing PIPELINE, its real time and the user and system
+//     times of all of its processes are printed to stderr in the POSIX
+//     format. If PIPELINE only runs builtins, just the real time is
+//     printed. Pipelines run in the background are not timed.
 //
-//     CMD can be a builtin, e.g. time cd
+//     time is only special at the start of a pipeline. Elsewhere, it is
+//     a builtin which times a single command.
 //
 // Example:
 //     $ time sleep 1.23s
@@ -19,9 +22,7 @@
 //     user 0.001
 //     sys 0.000
 //
-// Note:
-//     This is different from bash's time command which is built into the shell
-//     and can time the entire pipeline.
+//     $ time gzip -dc initramfs.cpio.gz | cpio -t > /dev/null
 //
 // Bugs:
 //     Time is not reported when exiting due to a signal.
@@ -29,17 +30,40 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"time"
 )
 
 func init() {
-	addBuiltIn("time", runtime)
+	addBuiltIn("time", "time a command", runtime)
+}
+
+// timePipelines takes the time keyword off the pipelines in l which
+// start with it, and marks them to be timed.
+func timePipelines(l []*Command) {
+	for i, c := range l {
+		if i > 0 && l[i-1].link == "|" {
+			continue
+		}
+		if len(c.args) > 1 && c.args[0].mod == "ARG" && c.args[0].val == "time" {
+			c.args = c.args[1:]
+			c.timed = true
+		}
+	}
 }
 
 func printTime(label string, t time.Duration) {
-	fmt.Fprintf(os.Stderr, "%s %.03f\n", label, t.Seconds())
+	fmt.Fprintf(stderr, "%s %.03f\n", label, t.Seconds())
+}
+
+// printTimes prints the times of a pipeline. j is nil if it did not
+// start any processes.
+func printTimes(elapsed time.Duration, j *job) {
+	printTime("real", elapsed)
+	if j != nil {
+		printTime("user", j.user)
+		printTime("sys", j.sys)
+	}
 }
 
 func runtime(c *Command) error {
@@ -48,7 +72,6 @@ func runtime(c *Command) error {
 	if len(c.argv) > 0 {
 		c.cmd = c.argv[0]
 		c.argv = c.argv[1:]
-		c.args = c.args[1:]
 		// If we are in a builtin, then the lookup failed.
 		// The result of the failed lookup remains in
 		// c.Cmd and will make start fail. We have to make
@@ -0,0 +1,24 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"syscall"
+)
+
+func init() {
+	addBuiltIn("true", "do nothing, successfully", trueBuiltin)
+	addBuiltIn("false", "do nothing, unsuccessfully", falseBuiltin)
+}
+
+func trueBuiltin(c *Command) error {
+	return nil
+}
+
+// falseBuiltin fails the way an external false would, so that it is
+// not reported in conditions.
+func falseBuiltin(c *Command) error {
+	return &exitError{syscall.WaitStatus(1 << 8)}
+}
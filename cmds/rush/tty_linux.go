@@ -5,12 +5,12 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"unsafe"
 
+	"github.com/u-root/u-root/pkg/termios"
 	"golang.org/x/sys/unix"
 )
 
@@ -23,12 +23,13 @@ var (
 func tty() {
 	var err error
 
-	sigs := make(chan os.Signal, 512)
-	signal.Notify(sigs, os.Interrupt)
 	signal.Ignore(unix.SIGTTOU)
+	// ^Z is for jobs, not the shell. We can't ignore SIGTSTP, since
+	// children would inherit that, so catch it and drop it on the floor.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, unix.SIGTSTP)
 	go func() {
-		for i := range sigs {
-			fmt.Println(i)
+		for range stop {
 		}
 	}()
 
@@ -50,13 +51,37 @@ func tty() {
 	}
 }
 
+// foreground puts the shell back in the foreground.
 func foreground() {
-	// Place process group in foreground.
 	if ttypgrp != 0 {
-		_, _, errno := unix.RawSyscall(unix.SYS_IOCTL, ttyf.Fd(), uintptr(unix.TIOCSPGRP), uintptr(unsafe.Pointer(&ttypgrp)))
-		if errno != 0 {
-			log.Printf("rush pid %v: Can't set foreground to %v: %v", os.Getpid(), ttypgrp, errno)
-		}
+		setForeground(ttypgrp)
 	}
+}
+
+// setForeground places the process group pgrp in the foreground.
+func setForeground(pgrp int) {
+	_, _, errno := unix.RawSyscall(unix.SYS_IOCTL, ttyf.Fd(), uintptr(unix.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	if errno != 0 {
+		log.Printf("rush pid %v: Can't set foreground to %v: %v", os.Getpid(), pgrp, errno)
+	}
+}
 
+// isTerminal returns true if f is a terminal.
+func isTerminal(f *os.File) bool {
+	_, err := termios.GetTermios(f.Fd())
+	return err == nil
+}
+
+// rawMode puts the terminal f in raw mode. It returns a function to undo that.
+func rawMode(f *os.File) (func(), error) {
+	t, err := termios.GetTermios(f.Fd())
+	if err != nil {
+		return nil, err
+	}
+	if err := termios.SetTermios(f.Fd(), termios.MakeRaw(t)); err != nil {
+		return nil, err
+	}
+	return func() {
+		termios.SetTermios(f.Fd(), t)
+	}, nil
 }
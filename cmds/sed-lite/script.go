@@ -0,0 +1,373 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// An address picks lines: line number line, the last line, or those re
+// matches.
+type address struct {
+	line int
+	last bool
+	re   *regexp.Regexp
+}
+
+// match returns whether a picks line n, which is l, and is the last line
+// if last.
+func (a *address) match(n int, l string, last bool) bool {
+	switch {
+	case a.re != nil:
+		return a.re.MatchString(l)
+	case a.last:
+		return last
+	}
+	return n == a.line
+}
+
+// A command is a command of a script, and the addresses of the lines it
+// runs on.
+type command struct {
+	a1, a2 *address
+	// not runs the command on the lines the addresses do not pick.
+	not bool
+	// active is whether the lines are between a1 and a2.
+	active bool
+
+	name byte
+	// text is the text of a, i and c.
+	text string
+
+	// re, repl, global, nth and print are the parts of s.
+	re     *regexp.Regexp
+	repl   []byte
+	global bool
+	nth    int
+	print  bool
+}
+
+// selects returns whether c runs on line n, which is l, and is the last
+// line if last.
+func (c *command) selects(n int, l string, last bool) bool {
+	return c.picks(n, l, last) != c.not
+}
+
+func (c *command) picks(n int, l string, last bool) bool {
+	switch {
+	case c.a1 == nil:
+		return true
+	case c.a2 == nil:
+		return c.a1.match(n, l, last)
+	case c.active:
+		c.active = !c.ends(n, l, last)
+		return true
+	case c.a1.match(n, l, last):
+		// /RE/ is only tried on the lines after the first, but a line
+		// number can end the range at once.
+		c.active = c.a2.re != nil || !c.ends(n, l, last)
+		return true
+	}
+	return false
+}
+
+// ends returns whether line n ends the range of c. A line number ends it
+// at the first line not before it.
+func (c *command) ends(n int, l string, last bool) bool {
+	if a := c.a2; a.re == nil && !a.last {
+		return n >= a.line
+	}
+	return c.a2.match(n, l, last)
+}
+
+// delimited returns s up to the first delim which is not escaped, and
+// what follows it. ok is whether there is a delim.
+func delimited(s string, delim byte) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// unescape turns \delim in an RE, which is delim in sed, into what it is
+// in Go.
+func unescape(re string, delim byte) string {
+	// Go escapes punctuation as sed does, so \delim is already delim.
+	if strings.IndexByte(`\.+*?()|[]{}^$`, delim) >= 0 {
+		return re
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(re); i++ {
+		if re[i] == '\\' && i+1 < len(re) {
+			i++
+			if re[i] != delim {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(re[i])
+	}
+	return b.String()
+}
+
+// template turns a replacement of s, where & is the match and \1 to \9
+// are its groups, into a template for Regexp.Expand.
+func template(s string) []byte {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			b.WriteString("$$")
+		case c == '&':
+			b.WriteString("${0}")
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch c := s[i]; {
+			case c >= '0' && c <= '9':
+				fmt.Fprintf(&b, "${%c}", c)
+			case c == 'n':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.Bytes()
+}
+
+// checkRefs checks that the groups which the replacement s refers to, as
+// \1 to \9, are among the n groups of its RE.
+func checkRefs(s string, n int) error {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '\\' {
+			continue
+		}
+		i++
+		if c := s[i]; c >= '1' && c <= '9' && int(c-'0') > n {
+			return fmt.Errorf("invalid reference \\%c on `s' command's RHS", c)
+		}
+	}
+	return nil
+}
+
+// A parser parses scripts.
+type parser struct {
+	s string
+	// last is the last RE, which an empty one is.
+	last *regexp.Regexp
+}
+
+// compile compiles re, which was between delims; empty, it is the last
+// RE.
+func (p *parser) compile(re string, delim byte, icase bool) (*regexp.Regexp, error) {
+	if re == "" {
+		if p.last == nil {
+			return nil, fmt.Errorf("no previous regular expression")
+		}
+		return p.last, nil
+	}
+	re = unescape(re, delim)
+	if icase {
+		re = "(?i)" + re
+	}
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return nil, err
+	}
+	p.last = r
+	return r, nil
+}
+
+func (p *parser) skipSpace() {
+	p.s = strings.TrimLeft(p.s, " \t")
+}
+
+// address parses an address, if there is one.
+func (p *parser) address() (*address, error) {
+	switch {
+	case p.s == "":
+		return nil, nil
+	case p.s[0] == '$':
+		p.s = p.s[1:]
+		return &address{last: true}, nil
+	case p.s[0] >= '0' && p.s[0] <= '9':
+		i := strings.IndexFunc(p.s, func(r rune) bool { return r < '0' || r > '9' })
+		if i < 0 {
+			i = len(p.s)
+		}
+		n, err := strconv.Atoi(p.s[:i])
+		if err != nil {
+			return nil, err
+		}
+		p.s = p.s[i:]
+		return &address{line: n}, nil
+	case p.s[0] == '/', p.s[0] == '\\' && len(p.s) > 1:
+		delim := p.s[0]
+		if delim == '\\' {
+			delim, p.s = p.s[1], p.s[1:]
+		}
+		re, rest, ok := delimited(p.s[1:], delim)
+		if !ok {
+			return nil, fmt.Errorf("unterminated address regex")
+		}
+		p.s = rest
+		icase := strings.HasPrefix(p.s, "I")
+		if icase {
+			p.s = p.s[1:]
+		}
+		r, err := p.compile(re, delim, icase)
+		if err != nil {
+			return nil, err
+		}
+		return &address{re: r}, nil
+	}
+	return nil, nil
+}
+
+// text parses the text of a, i and c, which is either the rest of the
+// line, or, after a \, the lines which follow, up to one which does not
+// end in \.
+func (p *parser) text() string {
+	p.skipSpace()
+	if strings.HasPrefix(p.s, "\\\n") {
+		p.s = p.s[2:]
+	} else if strings.HasPrefix(p.s, "\\") {
+		p.s = p.s[1:]
+	}
+	var b bytes.Buffer
+	for {
+		l := p.s
+		i := strings.IndexByte(l, '\n')
+		if i >= 0 {
+			l, p.s = l[:i], l[i+1:]
+		} else {
+			p.s = ""
+		}
+		if !strings.HasSuffix(l, "\\") || p.s == "" {
+			b.WriteString(strings.Replace(l, "\\", "", -1))
+			return b.String()
+		}
+		b.WriteString(strings.Replace(l[:len(l)-1], "\\", "", -1))
+		b.WriteByte('\n')
+	}
+}
+
+// subst parses the rest of an s command.
+func (p *parser) subst(c *command) error {
+	if p.s == "" || p.s[0] == '\n' || p.s[0] == '\\' {
+		return fmt.Errorf("s: missing delimiter")
+	}
+	delim := p.s[0]
+	re, rest, ok := delimited(p.s[1:], delim)
+	if !ok {
+		return fmt.Errorf("s: unterminated regex")
+	}
+	repl, rest, ok := delimited(rest, delim)
+	if !ok {
+		return fmt.Errorf("s: unterminated replacement")
+	}
+	p.s = rest
+	c.repl = template(repl)
+	c.nth = 1
+	var icase bool
+flags:
+	for len(p.s) > 0 {
+		switch f := p.s[0]; {
+		case f == 'g':
+			c.global = true
+		case f == 'p':
+			c.print = true
+		case f == 'I' || f == 'i':
+			icase = true
+		case f >= '1' && f <= '9':
+			i := 1
+			for i < len(p.s) && p.s[i] >= '0' && p.s[i] <= '9' {
+				i++
+			}
+			c.nth, _ = strconv.Atoi(p.s[:i])
+			p.s = p.s[i-1:]
+		default:
+			break flags
+		}
+		p.s = p.s[1:]
+	}
+	var err error
+	if c.re, err = p.compile(re, delim, icase); err != nil {
+		return err
+	}
+	return checkRefs(repl, c.re.NumSubexp())
+}
+
+// parse parses script, which is commands separated by newlines or ;.
+func parse(script string) ([]*command, error) {
+	var cmds []*command
+	p := &parser{s: script}
+	for {
+		p.s = strings.TrimLeft(p.s, " \t\n;")
+		if p.s == "" {
+			return cmds, nil
+		}
+		if p.s[0] == '#' {
+			i := strings.IndexByte(p.s, '\n')
+			if i < 0 {
+				return cmds, nil
+			}
+			p.s = p.s[i:]
+			continue
+		}
+
+		c := &command{}
+		var err error
+		if c.a1, err = p.address(); err != nil {
+			return nil, err
+		}
+		if c.a1 != nil && strings.HasPrefix(p.s, ",") {
+			p.s = p.s[1:]
+			if c.a2, err = p.address(); err != nil {
+				return nil, err
+			}
+			if c.a2 == nil {
+				return nil, fmt.Errorf("missing address after ,")
+			}
+		}
+		p.skipSpace()
+		if strings.HasPrefix(p.s, "!") {
+			c.not = true
+			p.s = p.s[1:]
+			p.skipSpace()
+		}
+		if p.s == "" {
+			return nil, fmt.Errorf("missing command")
+		}
+		c.name, p.s = p.s[0], p.s[1:]
+		switch c.name {
+		case 's':
+			err = p.subst(c)
+		case 'a', 'i', 'c':
+			c.text = p.text()
+		case 'd', 'p', 'q', '=':
+		default:
+			return nil, fmt.Errorf("%c: unknown command", c.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.s != "" && p.s[0] != ';' && p.s[0] != '\n' && p.s[0] != '#' {
+			return nil, fmt.Errorf("%c: extra characters after command: %q", c.name, p.s)
+		}
+		cmds = append(cmds, c)
+	}
+}
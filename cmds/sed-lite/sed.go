@@ -0,0 +1,321 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Sed-lite is a stream editor, with the commands of sed most used in
+// scripts.
+//
+// Synopsis:
+//     sed-lite [-n] [-i] SCRIPT [FILE]...
+//     sed-lite [-n] [-i] -e SCRIPT... [-f SCRIPTFILE]... [FILE]...
+//
+// Description:
+//     sed-lite reads the FILEs, or stdin, a line at a time, runs the
+//     script on each, and prints what is left of the line. The script is
+//     SCRIPT, or all the -e SCRIPTs and -f SCRIPTFILEs, in order, joined
+//     by newlines.
+//
+//     A script is commands, one per line or separated by ;, in the form
+//     [ADDRESS[,ADDRESS]][!]COMMAND. An ADDRESS is a line number; $, the
+//     last line; or /RE/ or \%RE%, the lines RE matches, with I after it
+//     to ignore case. A command with no address runs on every line, with
+//     one on the lines it picks, and with two on the lines from one the
+//     first picks to the next the second does. ! runs it on the other
+//     lines.
+//
+//     The commands are:
+//         s/RE/REPLACEMENT/[FLAGS]: replace the first match of RE in the
+//               line; & in REPLACEMENT is the match, \1 to \9 its groups
+//               and \n a newline. The FLAGS are g, to replace every
+//               match; N, to replace the Nth; p, to print the line if
+//               one was replaced; and I, to ignore case.
+//         d: delete the line, and start the next one
+//         p: print the line
+//         a TEXT, i TEXT, c TEXT: append TEXT after the line, insert it
+//               before, or change the line, or the lines of a range, to
+//               it. After a\, i\ or c\, TEXT is on the lines which
+//               follow, up to one which does not end in \.
+//         =: print the line number
+//         q: print the line and quit
+//
+//     An empty RE is the last one before it. REs are in the syntax of Go,
+//     e.g. s/(a+)b/\1/ rather than s/\(a\+\)b/\1/, and so -E and -r,
+//     for the extended syntax, do nothing.
+//
+//     With -i, the FILEs are edited in place, each on its own, so $ is
+//     the last line of each.
+//
+// Options:
+//     -e:     add SCRIPT to the script
+//     -f:     add the script in SCRIPTFILE to the script
+//     -i:     edit the FILEs in place
+//     -n:     only print lines p, s///p and = print
+//     -E, -r: REs are extended, which they are anyway
+//
+// Example:
+//     $ sed-lite -i 's/^#\s*(PermitRootLogin).*/\1 no/' /etc/ssh/sshd_config
+//     $ sed-lite -n '/^\[net\]/,/^\[/p' config.ini
+//     $ sed-lite -e '1d' -e '$a # end' list
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type scripts []string
+
+func (s *scripts) String() string {
+	return strings.Join(*s, "\n")
+}
+
+func (s *scripts) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// scriptFiles are -f files, which are read into the script.
+type scriptFiles struct {
+	s *scripts
+}
+
+func (f scriptFiles) String() string {
+	return ""
+}
+
+func (f scriptFiles) Set(n string) error {
+	b, err := ioutil.ReadFile(n)
+	if err != nil {
+		return err
+	}
+	*f.s = append(*f.s, strings.TrimSuffix(string(b), "\n"))
+	return nil
+}
+
+var (
+	quiet   = flag.Bool("n", false, "only print what the script prints")
+	inPlace = flag.Bool("i", false, "edit the files in place")
+	script  scripts
+)
+
+func init() {
+	flag.Var(&script, "e", "add `script` to the script")
+	flag.Var(scriptFiles{&script}, "f", "add the script in `file` to the script")
+	for _, n := range []string{"E", "r"} {
+		flag.Bool(n, false, "use extended REs, which they are anyway")
+	}
+}
+
+// A sed runs a script.
+type sed struct {
+	cmds  []*command
+	quiet bool
+}
+
+// line is a line, and whether it ended in a newline; the last line of a
+// file may not.
+type line struct {
+	s  string
+	nl bool
+}
+
+func readLine(r *bufio.Reader) (line, error) {
+	s, err := r.ReadString('\n')
+	if err == io.EOF && s != "" {
+		return line{s, false}, nil
+	}
+	if err != nil {
+		return line{}, err
+	}
+	return line{s[:len(s)-1], true}, nil
+}
+
+// run runs the script on the lines of r, and writes them to w.
+func (s *sed) run(w io.Writer, r io.Reader) error {
+	for _, c := range s.cmds {
+		c.active = false
+	}
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	next, err := readLine(br)
+	for n := 1; err == nil; n++ {
+		l := next
+		next, err = readLine(br)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if !s.line(bw, n, l, err == io.EOF) {
+			break
+		}
+	}
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return bw.Flush()
+}
+
+// line runs the script on l, line n, and returns whether to go on.
+func (s *sed) line(w *bufio.Writer, n int, l line, last bool) bool {
+	put := func(t string, nl bool) {
+		w.WriteString(t)
+		if nl {
+			w.WriteByte('\n')
+		}
+	}
+	var (
+		appended []string
+		deleted  bool
+		quit     bool
+	)
+	for _, c := range s.cmds {
+		if !c.selects(n, l.s, last) {
+			continue
+		}
+		switch c.name {
+		case 's':
+			var replaced bool
+			if l.s, replaced = c.subst(l.s); replaced && c.print {
+				put(l.s, true)
+			}
+		case 'd':
+			deleted = true
+		case 'p':
+			put(l.s, true)
+		case 'a':
+			appended = append(appended, c.text)
+		case 'i':
+			put(c.text, true)
+		case 'c':
+			deleted = true
+			// A range is changed once, at its end.
+			if !c.active || c.not {
+				put(c.text, true)
+			}
+		case '=':
+			fmt.Fprintln(w, n)
+		case 'q':
+			quit = true
+		}
+		if deleted || quit {
+			break
+		}
+	}
+	if !deleted && !s.quiet {
+		put(l.s, l.nl || len(appended) > 0)
+	}
+	for _, t := range appended {
+		put(t, true)
+	}
+	return !quit
+}
+
+// subst runs s on l, and returns it and whether anything was replaced.
+func (c *command) subst(l string) (string, bool) {
+	var (
+		out      []byte
+		prev     int
+		replaced bool
+	)
+	for i, m := range c.re.FindAllStringSubmatchIndex(l, -1) {
+		if c.global && i+1 < c.nth || !c.global && i+1 != c.nth {
+			continue
+		}
+		out = append(out, l[prev:m[0]]...)
+		out = c.re.ExpandString(out, string(c.repl), l, m)
+		prev = m[1]
+		replaced = true
+	}
+	if !replaced {
+		return l, false
+	}
+	return string(append(out, l[prev:]...)), true
+}
+
+// edit edits the file n in place: the script is run on it into a
+// temporary file, which is renamed to it.
+func (s *sed) edit(n string) error {
+	f, err := os.Open(n)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	t, err := ioutil.TempFile(filepath.Dir(n), ".sed")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(t.Name())
+	if err := s.run(t, f); err != nil {
+		t.Close()
+		return err
+	}
+	if err := t.Chmod(fi.Mode().Perm()); err != nil {
+		t.Close()
+		return err
+	}
+	if err := t.Close(); err != nil {
+		return err
+	}
+	return os.Rename(t.Name(), n)
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	if len(script) == 0 {
+		if len(args) == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		script, args = scripts{args[0]}, args[1:]
+	}
+	cmds, err := parse(script.String())
+	if err != nil {
+		log.Fatalf("sed-lite: %v", err)
+	}
+	s := &sed{cmds: cmds, quiet: *quiet}
+
+	if *inPlace {
+		if len(args) == 0 {
+			log.Fatal("sed-lite: -i needs files to edit")
+		}
+		var failed bool
+		for _, n := range args {
+			if err := s.edit(n); err != nil {
+				log.Printf("sed-lite: %v", err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The files are one stream, so $ is the last line of the last one.
+	var rs []io.Reader
+	for _, n := range args {
+		f, err := os.Open(n)
+		if err != nil {
+			log.Fatalf("sed-lite: %v", err)
+		}
+		defer f.Close()
+		rs = append(rs, f)
+	}
+	if len(args) == 0 {
+		rs = append(rs, os.Stdin)
+	}
+	if err := s.run(os.Stdout, io.MultiReader(rs...)); err != nil {
+		log.Fatalf("sed-lite: %v", err)
+	}
+}
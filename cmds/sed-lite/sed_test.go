@@ -0,0 +1,157 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lines = "one\ntwo\nthree\nfour\nfive\n"
+
+func TestSed(t *testing.T) {
+	for _, tt := range []struct {
+		script string
+		quiet  bool
+		in     string
+		want   string
+	}{
+		{"s/o/0/", false, lines, "0ne\ntw0\nthree\nf0ur\nfive\n"},
+		{"s/e/E/g", false, "eee\n", "EEE\n"},
+		{"s/e/E/2", false, "eee\n", "eEe\n"},
+		{"s/e/E/2g", false, "eee\n", "eEE\n"},
+		{"s/(t)(w|h)/\\2\\1/", false, "two three\n", "wto three\n"},
+		{`/(w)/s//\1\1/`, false, "two\n", "twwo\n"},
+		{`s/a/\\1/`, false, "a\n", "\\1\n"},
+		{"s/[aeiou]/<&>/g", false, "bead\n", "b<e><a>d\n"},
+		{"s/ONE/1/I", false, lines, "1\ntwo\nthree\nfour\nfive\n"},
+		{"s|/usr|/opt|", false, "/usr/bin\n", "/opt/bin\n"},
+		{`s/\//:/g`, false, "a/b/c\n", "a:b:c\n"},
+		{`s/x/a\nb/`, false, "x\n", "a\nb\n"},
+		{"s/$/ $HOME/", false, "a\n", "a $HOME\n"},
+		{"s/o/0/p", true, lines, "0ne\ntw0\nf0ur\n"},
+		{"2d", false, lines, "one\nthree\nfour\nfive\n"},
+		{"$d", false, lines, "one\ntwo\nthree\nfour\n"},
+		{"2,4d", false, lines, "one\nfive\n"},
+		{"2,4!d", false, lines, "two\nthree\nfour\n"},
+		{"4,2d", false, lines, "one\ntwo\nthree\nfive\n"},
+		{"/two/,/four/d", false, lines, "one\nfive\n"},
+		{"/t/,/t/d", false, lines, "one\nfour\nfive\n"},
+		{"/three/,$d", false, lines, "one\ntwo\n"},
+		{"/^f/d", false, lines, "one\ntwo\nthree\n"},
+		{"/TWO/Id", false, lines, "one\nthree\nfour\nfive\n"},
+		{`\%o%d`, false, lines, "three\nfive\n"},
+		{"/o/s//0/g", false, "foo\nbar\n", "f00\nbar\n"},
+		{"/x/,/y/p", true, "a\nx\nb\ny\nc\nx\nd\n", "x\nb\ny\nx\nd\n"},
+		{"$p", true, lines, "five\n"},
+		{"2p", false, "a\nb\n", "a\nb\nb\n"},
+		{"2q", false, lines, "one\ntwo\n"},
+		{"=", true, "a\nb\n", "1\n2\n"},
+		{"1i# head", false, "a\n", "# head\na\n"},
+		{"$a end", false, "a\nb\n", "a\nb\nend\n"},
+		{"$a\\\nfirst\\\nsecond", false, "a\n", "a\nfirst\nsecond\n"},
+		{"2c two", false, "a\nb\nc\n", "a\ntwo\nc\n"},
+		{"2,3c gone", false, "a\nb\nc\nd\n", "a\ngone\nd\n"},
+		{"s/a/b/;s/b/c/", false, "a\n", "c\n"},
+		{"s/a/b/\n# comment\n2d", false, "a\na\n", "b\n"},
+		{"1d;p", true, "a\nb\n", "b\n"},
+		{"s/a/b/", false, "a\na", "b\nb"},
+		{"$a end", false, "a", "a\nend\n"},
+	} {
+		cmds, err := parse(tt.script)
+		if err != nil {
+			t.Errorf("%q: %v", tt.script, err)
+			continue
+		}
+		var b bytes.Buffer
+		s := &sed{cmds: cmds, quiet: tt.quiet}
+		if err := s.run(&b, strings.NewReader(tt.in)); err != nil {
+			t.Errorf("%q: %v", tt.script, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("%q -n=%v of %q: got %q, want %q", tt.script, tt.quiet, tt.in, b.String(), tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, script := range []string{
+		"x",
+		"1,",
+		"/a",
+		"s/a/b",
+		"s/a",
+		"s/(/x/",
+		"s//x/",
+		"s/a/b/q",
+		`s/(a)/\9/`,
+		`s/a/\1/`,
+		`/a/s//\1/`,
+		"dp",
+		"2",
+	} {
+		if _, err := parse(script); err == nil {
+			t.Errorf("%q: got nil, want an error", script)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	d, err := ioutil.TempDir("", "sed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	// Each file is edited on its own, so $ is the last line of each.
+	var names []string
+	for _, n := range []string{"a", "b"} {
+		n = filepath.Join(d, n)
+		if err := ioutil.WriteFile(n, []byte("#PermitRootLogin yes\nPort 22\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, n)
+	}
+	cmds, err := parse("s/^#(PermitRootLogin).*/\\1 no/\n$a UseDNS no")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &sed{cmds: cmds}
+	for _, n := range names {
+		if err := s.edit(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "PermitRootLogin no\nPort 22\nUseDNS no\n"
+	for _, n := range names {
+		b, err := ioutil.ReadFile(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", n, b, want)
+		}
+		fi, err := os.Stat(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("%s: got mode %v, want %v", n, fi.Mode().Perm(), os.FileMode(0600))
+		}
+	}
+	// Only the files are left.
+	fis, err := ioutil.ReadDir(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != len(names) {
+		t.Errorf("got %d files in %s, want %d", len(fis), d, len(names))
+	}
+}
@@ -14,6 +14,8 @@
 //    2 3 4
 //    % seq -s=' ' 3 2 7
 //    3 5 7
+//    % seq -s=' ' 3 -1 1
+//    3 2 1
 //
 // Options:
 //     -f: use printf style floating-point FORMAT (default: %v)
@@ -27,6 +29,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"strings"
 )
@@ -68,8 +71,8 @@ func seq(w io.Writer, args []string) error {
 	// loading step value if args is <start> <step> <end>
 	if argc == 3 {
 		_, err := fmt.Sscanf(argv[1], "%v", &stp)
-		if stp-float64(int(stp)) > 0 && format == "%v" {
-			d := len(fmt.Sprintf("%v", stp-float64(int(stp)))) - 2 // get the nums of y.xx decimal part
+		if frac := math.Abs(stp - math.Trunc(stp)); frac > 0 && format == "%v" {
+			d := len(fmt.Sprintf("%v", frac)) - 2 // get the nums of y.xx decimal part
 			format = fmt.Sprintf("%%.%df", d)
 		}
 		if stp == 0.0 {
@@ -94,15 +97,26 @@ func seq(w io.Writer, args []string) error {
 	format = strings.Replace(format, "%", "%0*", 1) // support widthEqual
 	if flags.widthEqual {
 		width = len(fmt.Sprintf(format, 0, end))
+		if w := len(fmt.Sprintf(format, 0, stt)); w > width {
+			width = w
+		}
 	}
 
-	defer fmt.Fprint(w, "\n") // last char is always '\n'
-	for stt <= end {
-		fmt.Fprintf(w, format, width, stt)
-		stt += stp
-		if stt <= end { // print only between the values
+	// Each number is START + i*STEP, not the sum of the steps, so that
+	// rounding does not add up; and END is reached if it is within a
+	// rounding error.
+	past := func(v float64) bool {
+		return (v-end)/stp > 1e-9
+	}
+	i := 0
+	for ; !past(stt + float64(i)*stp); i++ {
+		if i > 0 { // print only between the values
 			fmt.Fprint(w, flags.separator)
 		}
+		fmt.Fprintf(w, format, width, stt+float64(i)*stp)
+	}
+	if i > 0 { // an empty range prints nothing, not even '\n'
+		fmt.Fprint(w, "\n")
 	}
 
 	return nil
@@ -54,6 +54,18 @@ func TestSeqDefault(t *testing.T) {
 			[]string{"1", "0.5", "3"},
 			"1.0\n1.5\n2.0\n2.5\n3.0\n",
 		},
+		{
+			[]string{"3", "-1", "1"},
+			"3\n2\n1\n",
+		},
+		{
+			[]string{"0", "0.1", "0.3"},
+			"0.0\n0.1\n0.2\n0.3\n",
+		},
+		{
+			[]string{"1", "-0.5", "0"},
+			"1.0\n0.5\n0.0\n",
+		},
 	}
 
 	testseq(tests, t)
@@ -112,3 +124,16 @@ func TestSeqSeparator(t *testing.T) {
 	testseq(tests, t)
 
 }
+
+// test that an empty range prints nothing, not even a newline
+func TestSeqEmptyRange(t *testing.T) {
+	for _, args := range [][]string{{"5", "1"}, {"1", "-1", "3"}} {
+		var b bytes.Buffer
+		if err := seq(&b, args); err != nil {
+			t.Error(err)
+		}
+		if b.Len() != 0 {
+			t.Errorf("seq %v: got %q, want nothing", args, b.String())
+		}
+	}
+}
@@ -5,15 +5,20 @@
 // Delay for the specified amount of time.
 //
 // Synopsis:
-//     sleep DURATION
+//     sleep DURATION...
 //
 // Description:
-//     If no units are given, the duration is assumed to be measured in
-//     seconds, otherwise any format parsed by Go's `time.ParseDuration` is
-//     accepted.
+//     sleep waits for the sum of the DURATIONs. A DURATION is a number,
+//     which may have a fraction or an exponent, with a suffix of s for
+//     seconds, the default, m for minutes, h for hours or d for days, as
+//     for coreutils; or any format parsed by Go's `time.ParseDuration`.
+//     inf, or infinity, is forever.
 //
 // Examples:
 //     sleep 2.5
+//     sleep 0.1
+//     sleep 1m 30s
+//     sleep 1.5d
 //     sleep 300ms
 //     sleep 2h45m
 //
@@ -26,30 +31,78 @@ import (
 	"errors"
 	"flag"
 	"log"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var errDuration = errors.New("invalid duration")
 
+// forever is the longest duration there is, which inf is.
+const forever = time.Duration(math.MaxInt64)
+
+// units are the suffixes of coreutils' sleep.
+var units = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+}
+
 func parseDuration(s string) (time.Duration, error) {
-	d, err := time.ParseDuration(s)
-	if err != nil {
-		d, err = time.ParseDuration(s + "s")
+	if s == "inf" || s == "infinity" {
+		return forever, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return time.Duration(0), errDuration
+		}
+		return d, nil
+	}
+	unit := time.Second
+	if len(s) > 0 {
+		if u, ok := units[s[len(s)-1]]; ok {
+			s, unit = s[:len(s)-1], u
+		}
 	}
-	if err != nil || d < 0 {
+	// ParseFloat takes Inf and NaN, which only inf, above, may be.
+	if strings.ContainsAny(s, "iInN") {
 		return time.Duration(0), errDuration
 	}
-	return d, nil
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return time.Duration(0), errDuration
+	}
+	if f*float64(unit) >= float64(forever) {
+		return forever, nil
+	}
+	return time.Duration(f * float64(unit)), nil
+}
+
+// sum returns the sum of the durations args, which is at most forever.
+func sum(args []string) (time.Duration, error) {
+	var total time.Duration
+	for _, a := range args {
+		d, err := parseDuration(a)
+		if err != nil {
+			return 0, err
+		}
+		if total += d; total < d {
+			total = forever
+		}
+	}
+	return total, nil
 }
 
 func main() {
 	flag.Parse()
 
-	if flag.NArg() != 1 {
+	if flag.NArg() == 0 {
 		log.Fatal("Incorrect number of arguments")
 	}
 
-	d, err := parseDuration(flag.Arg(0))
+	d, err := sum(flag.Args())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -23,6 +23,19 @@ func TestParseDuration(t *testing.T) {
 		{"2.5s", time.Duration(2500 * time.Millisecond), nil},
 		{"300m", time.Duration(300 * time.Minute), nil},
 		{"2h45m", time.Duration(2*time.Hour + 45*time.Minute), nil},
+		{"0.1", time.Duration(100 * time.Millisecond), nil},
+		{".5", time.Duration(500 * time.Millisecond), nil},
+		{"1e-3", time.Duration(time.Millisecond), nil},
+		{"1.5m", time.Duration(90 * time.Second), nil},
+		{"0.5h", time.Duration(30 * time.Minute), nil},
+		{"1.5d", time.Duration(36 * time.Hour), nil},
+		{"inf", forever, nil},
+		{"infinity", forever, nil},
+		{"1e300d", forever, nil},
+		{"NaN", time.Duration(0), errDuration},
+		{"-inf", time.Duration(0), errDuration},
+		{"d", time.Duration(0), errDuration},
+		{"2x", time.Duration(0), errDuration},
 	}
 
 	// Table-driven testing
@@ -34,3 +47,25 @@ func TestParseDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestSum(t *testing.T) {
+	var tests = []struct {
+		in  []string
+		out time.Duration
+		err error
+	}{
+		{[]string{"1"}, time.Duration(time.Second), nil},
+		{[]string{"1m", "30s"}, time.Duration(90 * time.Second), nil},
+		{[]string{"0.25", "0.25", "500ms"}, time.Duration(time.Second), nil},
+		{[]string{"inf", "1"}, forever, nil},
+		{[]string{"1", "x"}, time.Duration(0), errDuration},
+	}
+
+	for _, tt := range tests {
+		out, err := sum(tt.in)
+		if out != tt.out || err != tt.err {
+			t.Errorf("sum(%#v) = %v, %v; want %v, %v",
+				tt.in, out, err, tt.out, tt.err)
+		}
+	}
+}
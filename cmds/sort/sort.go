@@ -5,99 +5,547 @@
 // Sort lines.
 //
 // Synopsis:
-//     sort [OPTIONS]... [INPUT]...
+//     sort [-bfnru] [-t SEP] [-k POS1[,POS2]]... [-S SIZE] [-o FILE] [INPUT]...
 //
 // Description:
-//     Sort copies lines from the input to the output, sorting them in the
-//     process. This does nothing fancy (no multi-threading, compression,
-//     optiminzations, ...); it simply uses Go's sort.Sort function.
+//     Sort copies lines from the inputs, or stdin, to the output, sorting
+//     them in the process. Lines which are equal by the keys are sorted by
+//     all of their bytes, unless -u prints only the first of them.
+//
+//     Input which does not fit in the buffer, of -S SIZE, is sorted a
+//     buffer at a time into files in $TMPDIR, which are then merged.
+//
+//     A key is from field F, character C, to field F2, character C2,
+//     counting from 1, given as -k F[.C][OPTS][,F2[.C2][OPTS]]; without F2,
+//     it is to the end of the line, and without C2, to the end of F2. OPTS
+//     are b, f, n and r, which are as the options, for that key. Fields
+//     are split by SEP, or else each is the blanks before it and the
+//     non-blanks after them.
 //
 // Options:
+//     -b:      ignore the blanks at the start of keys
+//     -f:      fold lower case to upper case
+//     -n:      compare numbers: an optional -, digits, and a fraction
 //     -r:      reverse
-//     -o FILE: output file
+//     -u:      print only the first of lines which are equal
+//     -t SEP:  the field separator
+//     -k POS:  sort by a key; more than one may be given
+//     -S SIZE: the size of the buffer; may end in K, M or G
+//     -o FILE: output file, which may be an input
+//
+// Example:
+//     $ du -s * | sort -r -n | head
+//     $ sort -t : -k 3,3n /etc/passwd
 package main
 
 import (
+	"bufio"
+	"container/heap"
 	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+// An options is the ways lines, or keys of them, are compared.
+type options struct {
+	blanks, fold, numeric, reverse bool
+}
+
 var (
-	reverse    = flag.Bool("r", false, "Reverse")
+	global     options
+	unique     = flag.Bool("u", false, "print only the first of equal lines")
+	separator  = flag.String("t", "", "field `separator`")
 	outputFile = flag.String("o", "", "Output file")
+	bufSize    = sizeFlag(64 << 20)
+	keys       keyList
 )
 
-func readInput() string {
-	// Input files
-	from := []*os.File{}
-	if flag.NArg() > 0 {
-		for _, v := range flag.Args() {
-			if f, err := os.Open(v); err == nil {
-				from = append(from, f)
-				defer f.Close()
-			} else {
-				log.Fatal(err)
+func init() {
+	flag.BoolVar(&global.blanks, "b", false, "ignore leading blanks")
+	flag.BoolVar(&global.fold, "f", false, "fold lower case to upper case")
+	flag.BoolVar(&global.numeric, "n", false, "compare numbers")
+	flag.BoolVar(&global.reverse, "r", false, "Reverse")
+	flag.Var(&keys, "k", "sort by the key `F[.C][OPTS][,F2[.C2][OPTS]]`")
+	flag.Var(&bufSize, "S", "buffer `size`; may end in K, M or G")
+}
+
+// sizeFlag is a size in bytes, which may end in K, M or G.
+type sizeFlag int64
+
+func (s *sizeFlag) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *sizeFlag) Set(v string) error {
+	shift := uint(0)
+	if i := strings.IndexAny(v, "KMGkmg"); i >= 0 && i == len(v)-1 {
+		shift = map[byte]uint{'k': 10, 'm': 20, 'g': 30}[v[i]|0x20]
+		v = v[:i]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid size %q", v)
+	}
+	*s = sizeFlag(n << shift)
+	return nil
+}
+
+// A key is the part of a line a -k says to compare.
+type key struct {
+	// The first and last field and character, from 1; a last field of 0
+	// is the end of the line, and a last character of 0, the end of the
+	// field.
+	sf, sc, ef, ec int
+	opts           options
+	// hasOpts is set if the key has options of its own, which it then
+	// does not take from the global ones.
+	hasOpts bool
+}
+
+// keyList is the -k flags.
+type keyList []key
+
+func (k *keyList) String() string {
+	return fmt.Sprint(*k)
+}
+
+// parsePos parses F[.C][OPTS] into k's options, and returns F and C.
+func parsePos(s string, k *key) (int, int, error) {
+	opts := strings.TrimLeft(s, "0123456789.")
+	pos := s[:len(s)-len(opts)]
+	for _, o := range opts {
+		switch o {
+		case 'b':
+			k.opts.blanks = true
+		case 'f':
+			k.opts.fold = true
+		case 'n':
+			k.opts.numeric = true
+		case 'r':
+			k.opts.reverse = true
+		default:
+			return 0, 0, fmt.Errorf("invalid key option %q", o)
+		}
+		k.hasOpts = true
+	}
+	f, c := pos, ""
+	if i := strings.IndexByte(pos, '.'); i >= 0 {
+		f, c = pos[:i], pos[i+1:]
+	}
+	fn, err := strconv.Atoi(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid key %q", s)
+	}
+	cn := 0
+	if c != "" {
+		if cn, err = strconv.Atoi(c); err != nil {
+			return 0, 0, fmt.Errorf("invalid key %q", s)
+		}
+	}
+	return fn, cn, nil
+}
+
+func (k *keyList) Set(s string) error {
+	var n key
+	start, end := s, ""
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		start, end = s[:i], s[i+1:]
+	}
+	var err error
+	if n.sf, n.sc, err = parsePos(start, &n); err != nil {
+		return err
+	}
+	if n.sf < 1 || n.sc < 0 {
+		return fmt.Errorf("invalid key %q: fields and characters are from 1", s)
+	}
+	if n.sc == 0 {
+		n.sc = 1
+	}
+	if end != "" {
+		if n.ef, n.ec, err = parsePos(end, &n); err != nil {
+			return err
+		}
+		if n.ef < 1 {
+			return fmt.Errorf("invalid key %q: fields are from 1", s)
+		}
+	}
+	*k = append(*k, n)
+	return nil
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// fields returns where the fields of l start, and the end of l.
+func fields(l string, sep string) []int {
+	starts := []int{0}
+	if sep != "" {
+		for i := 0; ; {
+			j := strings.Index(l[i:], sep)
+			if j < 0 {
+				break
 			}
+			i += j + len(sep)
+			starts = append(starts, i)
+		}
+		return append(starts, len(l)+len(sep))
+	}
+	for i := 0; i < len(l); {
+		for i < len(l) && isBlank(l[i]) {
+			i++
+		}
+		for i < len(l) && !isBlank(l[i]) {
+			i++
+		}
+		if i < len(l) {
+			starts = append(starts, i)
 		}
-	} else {
-		from = []*os.File{os.Stdin}
 	}
+	// The end is as if there were a separator after the last field.
+	return append(starts, len(l))
+}
 
-	// Read unicode string from input
-	fileContents := []string{}
-	for _, f := range from {
-		bytes, err := ioutil.ReadAll(f)
-		if err != nil {
-			log.Fatal(err)
+// extract returns the part of l that k is.
+func (k key) extract(l string, sep string) string {
+	f := fields(l, sep)
+	n := len(f) - 1
+	// fieldEnd is the end of field i, from 0, without its separator.
+	fieldEnd := func(i int) int {
+		if sep != "" {
+			return f[i+1] - len(sep)
+		}
+		return f[i+1]
+	}
+	if k.sf > n {
+		return ""
+	}
+	start := f[k.sf-1]
+	if k.opts.blanks {
+		for start < len(l) && isBlank(l[start]) {
+			start++
+		}
+	}
+	start += k.sc - 1
+	end := len(l)
+	if k.ef > 0 && k.ef <= n {
+		end = fieldEnd(k.ef - 1)
+		if k.ec > 0 {
+			end = f[k.ef-1]
+			if k.opts.blanks {
+				for end < len(l) && isBlank(l[end]) {
+					end++
+				}
+			}
+			end += k.ec
+			if e := fieldEnd(k.ef - 1); end > e {
+				end = e
+			}
 		}
-		s := string(bytes)
-		fileContents = append(fileContents, s)
-		// Add a separator between files if the file is not newline
-		// terminated. Prevents concatenating lines between files.
-		if len(s) > 0 && s[len(s)-1] != '\n' {
-			fileContents = append(fileContents, "\n")
+	}
+	if start > end || start > len(l) {
+		return ""
+	}
+	return l[start:end]
+}
+
+// number returns the number at the start of s, after any blanks: an
+// optional -, digits, and a fraction. If there is none, it is 0.
+func number(s string) float64 {
+	s = strings.TrimLeft(s, " \t")
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
 		}
 	}
-	return strings.Join(fileContents, "")
+	n, _ := strconv.ParseFloat(s[:i], 64)
+	return n
 }
 
-func sortAlgorithm(s string) string {
-	if len(s) == 0 {
-		return "" // edge case mimics coreutils
+// compareBy compares a and b by o, not reversed.
+func compareBy(a, b string, o options) int {
+	if o.numeric {
+		x, y := number(a), number(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
 	}
-	if s[len(s)-1] == '\n' {
-		s = s[:len(s)-1] // remove newline terminator
+	if o.blanks {
+		a, b = strings.TrimLeft(a, " \t"), strings.TrimLeft(b, " \t")
 	}
-	lines := strings.Split(string(s), "\n")
-	if *reverse {
-		sort.Sort(sort.Reverse(sort.StringSlice(lines)))
-	} else {
-		sort.Strings(lines)
+	if o.fold {
+		a, b = strings.ToUpper(a), strings.ToUpper(b)
 	}
-	return strings.Join(lines, "\n") + "\n" // append newline terminator
+	return strings.Compare(a, b)
+}
+
+// A sorter compares lines.
+type sorter struct {
+	keys   []key
+	sep    string
+	global options
+	unique bool
 }
 
-func writeOutput(s string) {
-	to := os.Stdout
-	if *outputFile != "" {
-		if f, err := os.Create(*outputFile); err == nil {
-			to = f
-			defer f.Close()
+// compare compares the lines a and b by the keys, or if there are none,
+// the whole lines; if they are equal, and not unique, it compares all of
+// their bytes.
+func (s *sorter) compare(a, b string) int {
+	if len(s.keys) == 0 {
+		c := compareBy(a, b, s.global)
+		if c == 0 && !s.unique {
+			c = strings.Compare(a, b)
+		}
+		if s.global.reverse {
+			c = -c
+		}
+		return c
+	}
+	for _, k := range s.keys {
+		if !k.hasOpts {
+			k.opts = s.global
+		}
+		c := compareBy(k.extract(a, s.sep), k.extract(b, s.sep), k.opts)
+		if k.opts.reverse {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	if s.unique {
+		return 0
+	}
+	c := strings.Compare(a, b)
+	if s.global.reverse {
+		c = -c
+	}
+	return c
+}
+
+// sortLines sorts lines, dropping those equal to the one before if unique.
+func (s *sorter) sortLines(lines []string) []string {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return s.compare(lines[i], lines[j]) < 0
+	})
+	if !s.unique || len(lines) == 0 {
+		return lines
+	}
+	u := lines[:1]
+	for _, l := range lines[1:] {
+		if s.compare(u[len(u)-1], l) != 0 {
+			u = append(u, l)
+		}
+	}
+	return u
+}
+
+// writeLines writes lines to w, each with a newline.
+func writeLines(w io.Writer, lines []string) error {
+	b := bufio.NewWriter(w)
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return b.Flush()
+}
+
+// A run is a sorted file being merged.
+type run struct {
+	r    *bufio.Reader
+	line string
+}
+
+// next reads the next line of r, and returns false at its end.
+func (r *run) next() (bool, error) {
+	l, err := r.r.ReadString('\n')
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.line = l[:len(l)-1]
+	return true, nil
+}
+
+// runs is a heap of runs, the least line first; of equal lines, that of
+// the earlier run, so that the merge is stable.
+type runs struct {
+	s *sorter
+	r []*run
+	n []int
+}
+
+func (h *runs) Len() int { return len(h.r) }
+func (h *runs) Less(i, j int) bool {
+	if c := h.s.compare(h.r[i].line, h.r[j].line); c != 0 {
+		return c < 0
+	}
+	return h.n[i] < h.n[j]
+}
+func (h *runs) Swap(i, j int) {
+	h.r[i], h.r[j] = h.r[j], h.r[i]
+	h.n[i], h.n[j] = h.n[j], h.n[i]
+}
+func (h *runs) Push(x interface{}) {}
+func (h *runs) Pop() interface{} {
+	h.r, h.n = h.r[:len(h.r)-1], h.n[:len(h.n)-1]
+	return nil
+}
+
+// merge merges the sorted files to w.
+func (s *sorter) merge(w io.Writer, files []*os.File) error {
+	h := &runs{s: s}
+	for i, f := range files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r := &run{r: bufio.NewReader(f)}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.r, h.n = append(h.r, r), append(h.n, i)
+		}
+	}
+	heap.Init(h)
+	b := bufio.NewWriter(w)
+	var last string
+	printed := false
+	for h.Len() > 0 {
+		r := h.r[0]
+		if !s.unique || !printed || s.compare(last, r.line) != 0 {
+			b.WriteString(r.line)
+			b.WriteByte('\n')
+			last, printed = r.line, true
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
 		} else {
-			log.Fatal(err)
+			heap.Pop(h)
+		}
+	}
+	return b.Flush()
+}
+
+// sortFiles sorts the lines of the inputs to the file output, or stdout if
+// it is "". The inputs are all read before output is created, so it may be
+// one of them.
+func (s *sorter) sortFiles(inputs []io.Reader, output string, size int64) error {
+	var (
+		lines []string
+		n     int64
+		temps []*os.File
+	)
+	defer func() {
+		for _, f := range temps {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	// spill sorts the lines read so far into a temporary file.
+	spill := func() error {
+		f, err := ioutil.TempFile("", "sort")
+		if err != nil {
+			return err
+		}
+		temps = append(temps, f)
+		if err := writeLines(f, s.sortLines(lines)); err != nil {
+			return err
+		}
+		lines, n = nil, 0
+		return nil
+	}
+	for _, in := range inputs {
+		r := bufio.NewReader(in)
+		for {
+			l, err := r.ReadString('\n')
+			if len(l) > 0 {
+				// A line at the end of an input need not have a
+				// newline.
+				lines = append(lines, strings.TrimSuffix(l, "\n"))
+				// The size of a string header, as well as the line.
+				n += int64(len(l)) + 16
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if n >= size {
+				if err := spill(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(temps) > 0 && len(lines) > 0 {
+		if err := spill(); err != nil {
+			return err
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
 		}
+		defer f.Close()
+		w = f
 	}
-	to.Write([]byte(s))
+	if len(temps) == 0 {
+		return writeLines(w, s.sortLines(lines))
+	}
+	return s.merge(w, temps)
 }
 
 func main() {
 	flag.Parse()
 
-	// Input files must be closed before writing to output files to solve
+	// Input files must be read before writing to output files to solve
 	// the situtation in which the output file is the same as an input.
-	writeOutput(sortAlgorithm(readInput()))
+	var inputs []io.Reader
+	for _, v := range flag.Args() {
+		if v == "-" {
+			inputs = append(inputs, os.Stdin)
+			continue
+		}
+		f, err := os.Open(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = []io.Reader{os.Stdin}
+	}
+	s := &sorter{keys: keys, sep: *separator, global: global, unique: *unique}
+	if err := s.sortFiles(inputs, *outputFile, int64(bufSize)); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -39,6 +40,22 @@ var sortTests = []test{
 	{[]string{"-r"}, "c\na\nb\n", "c\nb\na\n"},
 	// reverse sort without terminating newline
 	{[]string{"-r"}, "a\nb\nc", "c\nb\na\n"},
+	// numeric sort
+	{[]string{"-n"}, "10\n9\n-1\nx\n1.5\n", "-1\nx\n1.5\n9\n10\n"},
+	// reverse numeric sort
+	{[]string{"-r", "-n"}, "10\n9\n100\n", "100\n10\n9\n"},
+	// unique
+	{[]string{"-u"}, "b\na\nb\na\n", "a\nb\n"},
+	// fold case, unique by the folded lines
+	{[]string{"-f", "-u"}, "b\nA\na\nB\n", "A\nb\n"},
+	// key with a separator
+	{[]string{"-t", ":", "-k", "3,3n"}, "a:x:10\nb:x:9\nc:x:100\n", "b:x:9\na:x:10\nc:x:100\n"},
+	// second key, after the first, reversed
+	{[]string{"-k", "1,1", "-k", "2nr"}, "b 1\na 2\na 10\n", "a 10\na 2\nb 1\n"},
+	// key of characters, ignoring blanks: b and a
+	{[]string{"-k", "2.2b,2.2"}, "x   ab\ny ba\n", "y ba\nx   ab\n"},
+	// unique by key
+	{[]string{"-u", "-k", "2"}, "a 1\nb 1\nc 0\n", "c 0\na 1\n"},
 }
 
 // sort < in > out
@@ -134,3 +151,75 @@ func TestMultipleFileInputs(t *testing.T) {
 	sortWithFiles(t, tt, tmpDir, sortPath,
 		[]string{"in1", "in2", "in3", "in4"}, "out")
 }
+
+// sort -S with a small buffer, so the input is sorted into files, then
+// merged.
+func TestExternalMerge(t *testing.T) {
+	tmpDir, sortPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	var in, want []string
+	for i := 0; i < 5000; i++ {
+		in = append(in, fmt.Sprintf("%d", (i*7919)%5000))
+		want = append(want, fmt.Sprintf("%d", i))
+	}
+	for _, args := range [][]string{{"-n", "-S", "1K"}, {"-n", "-S", "1K", "-u"}} {
+		// Every line twice, which -u drops.
+		cmd := exec.Command(sortPath, append(args, "-", "-")...)
+		cmd.Stdin = strings.NewReader(strings.Join(append(in, in...), "\n") + "\n")
+		cmd.Env = append(os.Environ(), "TMPDIR="+tmpDir)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("sort %v: %v", args, err)
+		}
+		w := want
+		if len(args) == 3 {
+			w = nil
+			for _, l := range want {
+				w = append(w, l, l)
+			}
+		}
+		if got := string(out); got != strings.Join(w, "\n")+"\n" {
+			t.Errorf("sort %v: got %.40q..., want %.40q...", args, got, strings.Join(w, "\n"))
+		}
+	}
+	// The temporary files are removed.
+	if files, _ := filepath.Glob(filepath.Join(tmpDir, "sort*")); len(files) != 0 {
+		t.Errorf("temporary files left: %v", files)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for _, tt := range []struct {
+		key  string
+		sep  string
+		line string
+		want string
+	}{
+		{"2", "", "a b c", " b c"},
+		{"2,2", "", "a b c", " b"},
+		{"2b,2", "", "a   b c", "b"},
+		{"2.2,2.3", "", "a xyz", "xy"},
+		{"2.2b,2.3b", "", "a   xyz", "yz"},
+		{"4", "", "a b c", ""},
+		{"2,2", ":", "a::c", ""},
+		{"3,3", ":", "a::c", "c"},
+		{"1.2,1.3", ":", "abcd:e", "bc"},
+		{"2,3", "::", "a::b::c::d", "b::c"},
+	} {
+		var k keyList
+		if err := k.Set(tt.key); err != nil {
+			t.Errorf("-k %s: %v", tt.key, err)
+			continue
+		}
+		if got := k[0].extract(tt.line, tt.sep); got != tt.want {
+			t.Errorf("-k %s -t %q of %q: got %q, want %q", tt.key, tt.sep, tt.line, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"0", "x", "1.x", "1,0", "1q"} {
+		var k keyList
+		if err := k.Set(bad); err == nil {
+			t.Errorf("-k %s: got nil, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A forward forwards connections to a local address to a remote one, from
+// the other end of an SSH connection.
+type forward struct {
+	local, remote string
+}
+
+// parseForward parses the argument of -L, [BIND:]PORT:HOST:HOSTPORT. IPv6
+// addresses are in brackets.
+func parseForward(arg string) (*forward, error) {
+	var f []string
+	s := arg
+	for s != "" {
+		var w string
+		if s[0] == '[' {
+			i := strings.IndexByte(s, ']')
+			if i < 0 {
+				return nil, fmt.Errorf("bad forward %q", arg)
+			}
+			w, s = s[1:i], s[i+1:]
+		} else if i := strings.IndexByte(s, ':'); i >= 0 {
+			w, s = s[:i], s[i:]
+		} else {
+			w, s = s, ""
+		}
+		f = append(f, w)
+		s = strings.TrimPrefix(s, ":")
+	}
+	switch len(f) {
+	case 3:
+		return &forward{net.JoinHostPort("localhost", f[0]), net.JoinHostPort(f[1], f[2])}, nil
+	case 4:
+		return &forward{net.JoinHostPort(f[0], f[1]), net.JoinHostPort(f[2], f[3])}, nil
+	}
+	return nil, fmt.Errorf("bad forward %q: want [bind:]port:host:hostport", arg)
+}
+
+// listen listens on the local address, and forwards what connects over c.
+func (f *forward) listen(c *ssh.Client) error {
+	l, err := net.Listen("tcp", f.local)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer l.Close()
+		for {
+			lc, err := l.Accept()
+			if err != nil {
+				log.Print(err)
+				return
+			}
+			go f.copy(c, lc)
+		}
+	}()
+	return nil
+}
+
+// copy connects lc to the remote address.
+func (f *forward) copy(c *ssh.Client, lc net.Conn) {
+	defer lc.Close()
+	rc, err := c.Dial("tcp", f.remote)
+	if err != nil {
+		log.Printf("forward to %v: %v", f.remote, err)
+		return
+	}
+	defer rc.Close()
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(rc, lc)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(lc, rc)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// hostKeyCallback checks host keys against the known hosts file. Unknown
+// hosts are added to it if accept is set, or if the answer to ask is yes.
+func hostKeyCallback(file string, accept bool, ask func(string) (string, error)) (ssh.HostKeyCallback, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+			return nil, err
+		}
+	}
+	known, err := knownhosts.New(file)
+	if err != nil {
+		return nil, err
+	}
+	return func(host string, remote net.Addr, key ssh.PublicKey) error {
+		err := known(host, remote, key)
+		ke, ok := err.(*knownhosts.KeyError)
+		if !ok || len(ke.Want) > 0 {
+			// It is known, or it has another key, which may
+			// be an attack.
+			if ok {
+				return fmt.Errorf("the %v key of %v has changed; if that is right, remove %v from %v", key.Type(), host, ke.Want[0].String(), file)
+			}
+			return err
+		}
+		if !accept {
+			a, err := ask(fmt.Sprintf("The %v key of %v is %v.\nAre you sure you want to go on (yes/no)? ", key.Type(), host, ssh.FingerprintSHA256(key)))
+			if err != nil {
+				return err
+			}
+			if strings.ToLower(a) != "yes" {
+				return fmt.Errorf("the key of %v was not accepted", host)
+			}
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(host)}, key))
+		return err
+	}, nil
+}
@@ -0,0 +1,353 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Log in to, or run a command on, another machine.
+//
+// Synopsis:
+//     ssh [OPTIONS] [USER@]HOST [COMMAND...]
+//
+// Description:
+//     ssh connects to HOST and runs COMMAND there, or a shell if there is
+//     none. The exit status is that of COMMAND, or 255 if ssh fails.
+//
+//     It logs in with the keys in the -i files, which can be in PEM or
+//     OpenSSH format, and if none of them are let in, with a password
+//     read from the terminal.
+//
+//     The keys of hosts are checked against the known hosts file. If a
+//     host is not in it, ssh asks whether to go on and then adds it; with
+//     -accept it does not ask. If the key of a host has changed, ssh
+//     refuses to go on.
+//
+// Options:
+//     -i: private key files, separated by commas
+//     -l: user to log in as, if USER@ is not given
+//     -p: port
+//     -t: ask for a pty even if there is a COMMAND
+//     -T: do not ask for a pty
+//     -N: run nothing; only forward ports
+//     -L: forward connections to [BIND:]PORT here to HOST:HOSTPORT from
+//         the other machine; may be given more than once
+//     -known-hosts: known hosts file
+//     -accept: add unknown hosts to the known hosts without asking
+//
+// Example:
+//     ssh -i ~/.ssh/id_ecdsa root@10.0.2.15
+//     ssh -N -L 8080:localhost:80 10.0.2.15
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/crypto/ssh"
+)
+
+// forwards are the arguments of -L.
+type forwards []string
+
+func (f *forwards) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *forwards) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+var (
+	identities = flag.String("i", "", "private key `files`, separated by commas")
+	login      = flag.String("l", "", "`user` to log in as")
+	port       = flag.String("p", "22", "port")
+	forcePty   = flag.Bool("t", false, "ask for a pty even with a command")
+	noPty      = flag.Bool("T", false, "do not ask for a pty")
+	noCommand  = flag.Bool("N", false, "run nothing, only forward ports")
+	knownHosts = flag.String("known-hosts", "", "known hosts `file` (default $HOME/.ssh/known_hosts)")
+	accept     = flag.Bool("accept", false, "add unknown hosts without asking")
+	locals     forwards
+)
+
+func init() {
+	flag.Var(&locals, "L", "forward `[bind:]port:host:hostport`")
+}
+
+// home returns the home directory of the user.
+func home() string {
+	if h := os.Getenv("HOME"); h != "" {
+		return h
+	}
+	return "/"
+}
+
+// userHost splits [USER@]HOST.
+func userHost(s, defaultUser string) (string, string) {
+	if i := strings.LastIndexByte(s, '@'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return defaultUser, s
+}
+
+// signers reads the private keys in files. If files is empty, the usual
+// keys in ~/.ssh which exist are read.
+func signers(files string) ([]ssh.Signer, error) {
+	var names []string
+	mustExist := files != ""
+	if mustExist {
+		names = strings.Split(files, ",")
+	} else {
+		for _, n := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			names = append(names, filepath.Join(home(), ".ssh", n))
+		}
+	}
+	var s []ssh.Signer
+	for _, n := range names {
+		b, err := ioutil.ReadFile(n)
+		if os.IsNotExist(err) && !mustExist {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		k, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", n, err)
+		}
+		s = append(s, k)
+	}
+	return s, nil
+}
+
+// readPassword prints prompt and reads a line from the terminal, without
+// echoing it.
+func readPassword(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	old, err := termios.GetTermios(tty.Fd())
+	if err != nil {
+		return "", err
+	}
+	noEcho := *old
+	noEcho.Lflag &^= syscall.ECHO
+	noEcho.Lflag |= syscall.ICANON | syscall.ISIG
+	if err := termios.SetTermios(tty.Fd(), &noEcho); err != nil {
+		return "", err
+	}
+	defer termios.SetTermios(tty.Fd(), old)
+
+	fmt.Fprint(tty, prompt)
+	defer fmt.Fprintln(tty)
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := tty.Read(b)
+		if err != nil {
+			return "", err
+		}
+		if n == 0 || b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+}
+
+// ask asks a question on the terminal and returns the answer.
+func ask(question string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, question)
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := tty.Read(b)
+		if err != nil || n == 0 || b[0] == '\n' {
+			return strings.TrimSpace(string(line)), err
+		}
+		line = append(line, b[0])
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatalf("Usage: ssh [OPTIONS] [USER@]HOST [COMMAND...]")
+	}
+	os.Exit(run())
+}
+
+// run runs ssh and returns its exit status.
+func run() int {
+	log.SetPrefix("ssh: ")
+	log.SetFlags(0)
+	defaultUser := *login
+	if defaultUser == "" {
+		defaultUser = os.Getenv("USER")
+	}
+	if defaultUser == "" {
+		defaultUser = "root"
+	}
+	user, host := userHost(flag.Arg(0), defaultUser)
+	addr := net.JoinHostPort(host, *port)
+	command := strings.Join(flag.Args()[1:], " ")
+
+	keys, err := signers(*identities)
+	if err != nil {
+		log.Print(err)
+		return 255
+	}
+	kh := *knownHosts
+	if kh == "" {
+		kh = filepath.Join(home(), ".ssh", "known_hosts")
+	}
+	hostKeys, err := hostKeyCallback(kh, *accept, ask)
+	if err != nil {
+		log.Print(err)
+		return 255
+	}
+	conf := &ssh.ClientConfig{
+		User:            user,
+		HostKeyCallback: hostKeys,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(keys...),
+			ssh.PasswordCallback(func() (string, error) {
+				return readPassword(fmt.Sprintf("%v@%v's password: ", user, host))
+			}),
+			ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i, q := range questions {
+					read := readPassword
+					if echos[i] {
+						read = ask
+					}
+					if answers[i], err = read(q); err != nil {
+						return nil, err
+					}
+				}
+				return answers, nil
+			}),
+		},
+	}
+	c, err := ssh.Dial("tcp", addr, conf)
+	if err != nil {
+		log.Print(err)
+		return 255
+	}
+	defer c.Close()
+
+	for _, l := range locals {
+		f, err := parseForward(l)
+		if err != nil {
+			log.Print(err)
+			return 255
+		}
+		if err := f.listen(c); err != nil {
+			log.Print(err)
+			return 255
+		}
+	}
+	if *noCommand {
+		// Forward until killed, or until the server goes away.
+		c.Wait()
+		return 0
+	}
+	return session(c, command)
+}
+
+// session runs command, or a shell, on c, and returns its exit status.
+func session(c *ssh.Client, command string) int {
+	s, err := c.NewSession()
+	if err != nil {
+		log.Print(err)
+		return 255
+	}
+	defer s.Close()
+	s.Stdin, s.Stdout, s.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	_, err = termios.GetTermios(os.Stdin.Fd())
+	isTerminal := err == nil
+	if !*noPty && (*forcePty || (command == "" && isTerminal)) {
+		restore, err := startPty(s, isTerminal)
+		if err != nil {
+			log.Print(err)
+			return 255
+		}
+		defer restore()
+	}
+
+	if command == "" {
+		err = s.Shell()
+	} else {
+		err = s.Start(command)
+	}
+	if err != nil {
+		log.Print(err)
+		return 255
+	}
+	err = s.Wait()
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case *ssh.ExitError:
+		return e.ExitStatus()
+	case *ssh.ExitMissingError:
+		return 255
+	}
+	log.Print(err)
+	return 255
+}
+
+// startPty asks for a pty of the size of the terminal, and puts the
+// terminal in raw mode so that the keys go to the pty. The function it
+// returns undoes that.
+func startPty(s *ssh.Session, isTerminal bool) (func(), error) {
+	rows, cols := 24, 80
+	if ws, err := termios.GetWinSize(os.Stdin.Fd()); err == nil && ws.Row > 0 {
+		rows, cols = int(ws.Row), int(ws.Col)
+	}
+	term := os.Getenv("TERM")
+	if term == "" {
+		term = "vt100"
+	}
+	if err := s.RequestPty(term, rows, cols, ssh.TerminalModes{}); err != nil {
+		return nil, err
+	}
+	if !isTerminal {
+		return func() {}, nil
+	}
+	old, err := termios.GetTermios(os.Stdin.Fd())
+	if err != nil {
+		return nil, err
+	}
+	if err := termios.SetTermios(os.Stdin.Fd(), termios.MakeRaw(old)); err != nil {
+		return nil, err
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for range winch {
+			if ws, err := termios.GetWinSize(os.Stdin.Fd()); err == nil {
+				s.WindowChange(int(ws.Row), int(ws.Col))
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(winch)
+		termios.SetTermios(os.Stdin.Fd(), old)
+	}, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestUserHost(t *testing.T) {
+	for _, tt := range []struct {
+		s, user, host string
+	}{
+		{"host", "def", "host"},
+		{"root@host", "root", "host"},
+		{"a@b@host", "a@b", "host"},
+	} {
+		if u, h := userHost(tt.s, "def"); u != tt.user || h != tt.host {
+			t.Errorf("userHost(%q): got %q, %q, want %q, %q", tt.s, u, h, tt.user, tt.host)
+		}
+	}
+}
+
+func TestParseForward(t *testing.T) {
+	for _, tt := range []struct {
+		s             string
+		local, remote string
+	}{
+		{"8080:localhost:80", "localhost:8080", "localhost:80"},
+		{"0.0.0.0:8080:10.0.0.1:80", "0.0.0.0:8080", "10.0.0.1:80"},
+		{"[::1]:8080:[fe80::1]:80", "[::1]:8080", "[fe80::1]:80"},
+	} {
+		f, err := parseForward(tt.s)
+		if err != nil {
+			t.Errorf("parseForward(%q): %v", tt.s, err)
+			continue
+		}
+		if f.local != tt.local || f.remote != tt.remote {
+			t.Errorf("parseForward(%q): got %q, %q, want %q, %q", tt.s, f.local, f.remote, tt.local, tt.remote)
+		}
+	}
+	for _, s := range []string{"8080", "8080:80", "[::1:80:a:b"} {
+		if _, err := parseForward(s); err == nil {
+			t.Errorf("parseForward(%q): got nil, want error", s)
+		}
+	}
+}
+
+func newKey(t *testing.T) ssh.PublicKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ssh.NewPublicKey(&k.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	d, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, ".ssh/known_hosts")
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+	key, other := newKey(t), newKey(t)
+
+	var asked int
+	answer := "no"
+	ask := func(string) (string, error) {
+		asked++
+		return answer, nil
+	}
+	check := func(accept bool, k ssh.PublicKey) error {
+		cb, err := hostKeyCallback(file, accept, ask)
+		if err != nil {
+			t.Fatalf("hostKeyCallback: %v", err)
+		}
+		return cb("host:22", addr, k)
+	}
+
+	if err := check(false, key); err == nil || asked != 1 {
+		t.Errorf("unknown host, answer no: got %v after %d questions, want error after 1", err, asked)
+	}
+	answer = "yes"
+	if err := check(false, key); err != nil || asked != 2 {
+		t.Errorf("unknown host, answer yes: got %v after %d questions, want nil after 2", err, asked)
+	}
+	if err := check(false, key); err != nil || asked != 2 {
+		t.Errorf("known host: got %v after %d questions, want nil after 2", err, asked)
+	}
+	if err := check(true, other); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("changed key: got %v, want it to say it changed", err)
+	}
+	if err := check(true, key); err != nil {
+		t.Errorf("known host again: got %v, want nil", err)
+	}
+	cb, _ := hostKeyCallback(file, true, ask)
+	if err := cb("other:22", addr, other); err != nil || asked != 2 {
+		t.Errorf("-accept: got %v after %d questions, want nil after 2", err, asked)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/pty"
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/sys/unix"
+)
+
+// server says what sessions run.
+type server struct {
+	shell string
+	// sftp is the program for the sftp subsystem, if any.
+	sftp string
+}
+
+// The payloads of the requests of RFC 4254.
+type (
+	ptyRequest struct {
+		Term          string
+		Columns, Rows uint32
+		Width, Height uint32
+		Modes         string
+	}
+	windowChange struct {
+		Columns, Rows uint32
+		Width, Height uint32
+	}
+	envRequest struct {
+		Name, Value string
+	}
+	execRequest struct {
+		Command string
+	}
+	subsystemRequest struct {
+		Name string
+	}
+	exitStatus struct {
+		Status uint32
+	}
+)
+
+// A session is a session channel.
+type session struct {
+	ch  ssh.Channel
+	env []string
+	// ptm and pts are the pty, if the client asked for one.
+	ptm, pts *os.File
+	cmd      *exec.Cmd
+	// waiting is set once wait has been started for cmd; done is
+	// closed when cmd has exited.
+	waiting bool
+	done    chan struct{}
+}
+
+func (s *session) setWinSize(cols, rows uint32) {
+	if s.ptm == nil {
+		return
+	}
+	termios.SetWinSize(s.ptm.Fd(), &unix.Winsize{Col: uint16(cols), Row: uint16(rows)})
+}
+
+// command returns the command which a shell, exec or subsystem request
+// with payload p asks for, or nil if there is none.
+func (srv *server) command(typ string, p []byte) *exec.Cmd {
+	switch typ {
+	case "shell":
+		return exec.Command(srv.shell)
+	case "exec":
+		var r execRequest
+		if ssh.Unmarshal(p, &r) != nil {
+			return nil
+		}
+		return exec.Command(srv.shell, "-c", r.Command)
+	case "subsystem":
+		var r subsystemRequest
+		if ssh.Unmarshal(p, &r) != nil || r.Name != "sftp" || srv.sftp == "" {
+			return nil
+		}
+		return exec.Command(srv.sftp)
+	}
+	return nil
+}
+
+// start starts c in the session.
+func (s *session) start(c *exec.Cmd) error {
+	c.Env = append(os.Environ(), s.env...)
+	if s.pts != nil {
+		c.Stdin, c.Stdout, c.Stderr = s.pts, s.pts, s.pts
+		c.SysProcAttr = &syscall.SysProcAttr{Setctty: true, Setsid: true}
+		if err := c.Start(); err != nil {
+			return err
+		}
+		// The pts is the child's now; once it has exited, reading the
+		// ptm fails.
+		s.pts.Close()
+		go io.Copy(s.ptm, s.ch)
+		s.cmd = c
+		return nil
+	}
+	// Wait would wait for the client to stop sending if the channel
+	// were Stdin, so a pipe is used.
+	in, err := c.StdinPipe()
+	if err != nil {
+		return err
+	}
+	c.Stdout, c.Stderr = s.ch, s.ch.Stderr()
+	if err := c.Start(); err != nil {
+		return err
+	}
+	go func() {
+		io.Copy(in, s.ch)
+		in.Close()
+	}()
+	s.cmd = c
+	return nil
+}
+
+// wait waits for the command of the session, sends its exit status and
+// closes the channel.
+func (s *session) wait() {
+	if s.ptm != nil {
+		io.Copy(s.ch, s.ptm)
+	}
+	err := s.cmd.Wait()
+	status := 0
+	if ee, ok := err.(*exec.ExitError); ok {
+		ws := ee.Sys().(syscall.WaitStatus)
+		status = ws.ExitStatus()
+		if ws.Signaled() {
+			status = 128 + int(ws.Signal())
+		}
+	} else if err != nil {
+		status = 255
+	}
+	close(s.done)
+	s.ch.SendRequest("exit-status", false, ssh.Marshal(&exitStatus{uint32(status)}))
+	s.ch.Close()
+	if s.ptm != nil {
+		s.ptm.Close()
+	}
+}
+
+// session serves a session channel.
+func (srv *server) session(nc ssh.NewChannel) {
+	ch, reqs, err := nc.Accept()
+	if err != nil {
+		log.Printf("sshd: %v", err)
+		return
+	}
+	s := &session{ch: ch, done: make(chan struct{})}
+	for req := range reqs {
+		ok := false
+		switch req.Type {
+		case "pty-req":
+			var r ptyRequest
+			if s.ptm != nil || ssh.Unmarshal(req.Payload, &r) != nil {
+				break
+			}
+			if s.ptm, s.pts, err = pty.Open(); err != nil {
+				log.Printf("sshd: %v", err)
+				break
+			}
+			s.env = append(s.env, "TERM="+r.Term)
+			s.setWinSize(r.Columns, r.Rows)
+			ok = true
+		case "window-change":
+			var r windowChange
+			if ssh.Unmarshal(req.Payload, &r) == nil {
+				s.setWinSize(r.Columns, r.Rows)
+				ok = true
+			}
+		case "env":
+			var r envRequest
+			if ssh.Unmarshal(req.Payload, &r) == nil {
+				s.env = append(s.env, r.Name+"="+r.Value)
+				ok = true
+			}
+		case "shell", "exec", "subsystem":
+			if s.cmd != nil {
+				break
+			}
+			c := srv.command(req.Type, req.Payload)
+			if c == nil {
+				break
+			}
+			if err := s.start(c); err != nil {
+				log.Printf("sshd: %v", err)
+				break
+			}
+			ok = true
+		}
+		if req.WantReply {
+			req.Reply(ok, nil)
+		}
+		// The exit status has to come after the reply.
+		if ok && s.cmd != nil && !s.waiting {
+			s.waiting = true
+			go s.wait()
+		}
+	}
+	if s.cmd != nil {
+		select {
+		case <-s.done:
+		default:
+			// The client has gone.
+			s.cmd.Process.Kill()
+		}
+		return
+	}
+	ch.Close()
+	if s.ptm != nil {
+		s.ptm.Close()
+		s.pts.Close()
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Serve shells over SSH.
+//
+// Synopsis:
+//     sshd [-addr ADDRESS] [-keys FILE] [-hostkey FILE] [-shell SHELL] [-sftp SERVER]
+//
+// Description:
+//     sshd lets the users whose public keys are in the authorized keys
+//     file log in and run SHELL, with a pty if they ask for one, or run
+//     commands with SHELL -c. There are no passwords. The keys are read
+//     again at each login, so they can be changed without a restart.
+//     Key options, such as command= or from=, are not supported; a key
+//     with options is not let in.
+//
+//     If there is no host key, an ECDSA key is made and saved, so that
+//     the first boot of a device gives it one.
+//
+// Options:
+//     -addr: address to listen on
+//     -keys: authorized keys file, as of OpenSSH
+//     -hostkey: host key file, made if it does not exist
+//     -shell: shell to run
+//     -sftp: SFTP server program, such as OpenSSH's sftp-server, to run
+//            for the sftp subsystem; by default there is none
+//
+// Example:
+//     sshd -addr :2222 -keys /etc/ssh/authorized_keys
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	addr    = flag.String("addr", ":22", "address to listen on")
+	keys    = flag.String("keys", "/etc/ssh/authorized_keys", "authorized keys `file`")
+	hostKey = flag.String("hostkey", "/etc/ssh/ssh_host_ecdsa_key", "host key `file`, made if it does not exist")
+	shell   = flag.String("shell", "/buildbin/rush", "shell to run")
+	sftp    = flag.String("sftp", "", "SFTP server `program` for the sftp subsystem")
+)
+
+// loadHostKey reads the host key in file. If there is none, it makes one
+// and writes it there.
+func loadHostKey(file string) (ssh.Signer, error) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		b = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(file, b, 0600); err != nil {
+			return nil, err
+		}
+		log.Printf("sshd: made host key %v", file)
+	} else if err != nil {
+		return nil, err
+	}
+	s, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+	return s, nil
+}
+
+// authorized reports whether key is one of those in the authorized keys
+// file. Options, such as command= or from=, are not supported, so a key
+// with options is not let in, rather than let in without them.
+func authorized(file string, key ssh.PublicKey) (bool, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	want := key.Marshal()
+	for len(b) > 0 {
+		k, _, opts, rest, err := ssh.ParseAuthorizedKey(b)
+		if err != nil {
+			// There are no more keys.
+			return false, nil
+		}
+		if bytes.Equal(k.Marshal(), want) {
+			if len(opts) > 0 {
+				return false, fmt.Errorf("%v: %v key has options %q, which are not supported", file, k.Type(), strings.Join(opts, ","))
+			}
+			return true, nil
+		}
+		b = rest
+	}
+	return false, nil
+}
+
+// config returns the server configuration, with the host key signer,
+// for the keys in the authorized keys file.
+func config(signer ssh.Signer, keys string) *ssh.ServerConfig {
+	c := &ssh.ServerConfig{
+		PublicKeyCallback: func(m ssh.ConnMetadata, k ssh.PublicKey) (*ssh.Permissions, error) {
+			ok, err := authorized(keys, k)
+			if err != nil {
+				log.Printf("sshd: %v", err)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%v key of %v@%v is not authorized", k.Type(), m.User(), m.RemoteAddr())
+			}
+			return &ssh.Permissions{}, nil
+		},
+	}
+	c.AddHostKey(signer)
+	return c
+}
+
+// serveConn runs the SSH protocol on c.
+func serveConn(c net.Conn, config *ssh.ServerConfig, s *server) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, config)
+	if err != nil {
+		log.Printf("sshd: %v: %v", c.RemoteAddr(), err)
+		c.Close()
+		return
+	}
+	defer conn.Close()
+	log.Printf("sshd: %v logged in as %v", conn.RemoteAddr(), conn.User())
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		go s.session(nc)
+	}
+}
+
+func main() {
+	flag.Parse()
+	signer, err := loadHostKey(*hostKey)
+	if err != nil {
+		log.Fatalf("sshd: %v", err)
+	}
+	if _, err := os.Stat(*keys); err != nil {
+		log.Printf("sshd: no one can log in: %v", err)
+	}
+	conf := config(signer, *keys)
+	s := &server{shell: *shell, sftp: *sftp}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("sshd: %v", err)
+	}
+	log.Printf("sshd: listening on %v", l.Addr())
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			log.Fatalf("sshd: %v", err)
+		}
+		go serveConn(c, conf, s)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLoadHostKey(t *testing.T) {
+	d, err := ioutil.TempDir("", "sshd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	f := filepath.Join(d, "etc/ssh/key")
+	s1, err := loadHostKey(f)
+	if err != nil {
+		t.Fatalf("loadHostKey(%v): %v", f, err)
+	}
+	fi, err := os.Stat(f)
+	if err != nil {
+		t.Fatalf("loadHostKey(%v) did not save the key: %v", f, err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("loadHostKey(%v): got mode %v, want 0600", f, fi.Mode().Perm())
+	}
+	s2, err := loadHostKey(f)
+	if err != nil {
+		t.Fatalf("loadHostKey(%v) again: %v", f, err)
+	}
+	if !bytes.Equal(s1.PublicKey().Marshal(), s2.PublicKey().Marshal()) {
+		t.Errorf("loadHostKey(%v) made a new key the second time", f)
+	}
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ssh.NewSignerFromKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAuthorized(t *testing.T) {
+	d, err := ioutil.TempDir("", "sshd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	key, other := newSigner(t).PublicKey(), newSigner(t).PublicKey()
+	line := string(ssh.MarshalAuthorizedKey(key))
+	keys := filepath.Join(d, "authorized_keys")
+	for _, tt := range []struct {
+		file string
+		want bool
+		ok   bool
+	}{
+		{"# comment\n" + line, true, true},
+		{string(ssh.MarshalAuthorizedKey(other)), false, true},
+		{`command="/bin/date" ` + line, false, false},
+		{"no-pty,restrict " + line, false, false},
+		{`from="10.0.0.1" ` + string(ssh.MarshalAuthorizedKey(other)) + line, true, true},
+	} {
+		if err := ioutil.WriteFile(keys, []byte(tt.file), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := authorized(keys, key)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("authorized with %q: got %v, %v, want %v, ok %v", tt.file, got, err, tt.want, tt.ok)
+		}
+	}
+	if _, err := authorized(filepath.Join(d, "nothere"), key); !os.IsNotExist(err) {
+		t.Errorf("authorized with no file: got %v, want a does not exist error", err)
+	}
+}
+
+// serve starts a server with /bin/sh as the shell, which lets in the
+// client key. It returns its address.
+func serve(t *testing.T, d string, client ssh.Signer) string {
+	keys := filepath.Join(d, "authorized_keys")
+	if err := ioutil.WriteFile(keys, ssh.MarshalAuthorizedKey(client.PublicKey()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf := config(newSigner(t), keys)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(c, conf, &server{shell: "/bin/sh"})
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestSession(t *testing.T) {
+	d, err := ioutil.TempDir("", "sshd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	key := newSigner(t)
+	addr := serve(t, d, key)
+
+	// Other keys are not let in.
+	_, err = ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "root",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(newSigner(t))},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err == nil {
+		t.Errorf("Dial with an unauthorized key: got nil, want error")
+	}
+
+	c, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "root",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(key)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+
+	s, err := c.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out, stderr bytes.Buffer
+	s.Stdin, s.Stdout, s.Stderr = strings.NewReader("in"), &out, &stderr
+	err = s.Run("cat; echo out; echo err >&2; exit 3")
+	if ee, ok := err.(*ssh.ExitError); !ok || ee.ExitStatus() != 3 {
+		t.Errorf("Run: got %v, want exit status 3", err)
+	}
+	if out.String() != "inout\n" || stderr.String() != "err\n" {
+		t.Errorf("Run: got %q, %q, want %q, %q", out.String(), stderr.String(), "inout\n", "err\n")
+	}
+
+	s, err = c.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RequestPty("xterm", 24, 80, ssh.TerminalModes{}); err != nil {
+		t.Fatalf("RequestPty: %v", err)
+	}
+	b, err := s.Output("tty; echo $TERM; stty size")
+	if err != nil {
+		t.Fatalf("Output with a pty: %v", err)
+	}
+	if f := strings.Fields(string(b)); len(f) != 4 || !strings.HasPrefix(f[0], "/dev/pts/") || f[1] != "xterm" || f[2] != "24" || f[3] != "80" {
+		t.Errorf("Output with a pty: got %q, want /dev/pts/N xterm 24 80", b)
+	}
+}
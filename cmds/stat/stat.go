@@ -0,0 +1,403 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Stat prints the status of files.
+//
+// Synopsis:
+//     stat [-L] [-t] [-c FORMAT | --printf FORMAT] FILE...
+//
+// Description:
+//     stat prints the type, size, mode, owner, times and more of each
+//     FILE; or with -t, all of them on one line, for scripts; or with -c,
+//     those FORMAT asks for.
+//
+//     FORMAT is text with directives, which may have the flags, width and
+//     precision of printf, e.g. %-10s:
+//         %a  permissions in octal       %A  permissions, as ls shows them
+//         %b  blocks allocated           %B  the size of a block, 512
+//         %d  device, in decimal         %D  device, in hex
+//         %f  raw mode, in hex           %F  type of file
+//         %g  group ID                   %G  group name
+//         %h  number of hard links       %i  inode number
+//         %n  file name                  %N  quoted file name, and target
+//         %o  optimal I/O size           %s  size in bytes
+//         %t  device major, in hex       %T  device minor, in hex
+//         %u  user ID                    %U  user name
+//         %w  birth time, or -           %W  birth time, seconds, or 0
+//         %x  access time                %X  access time, seconds
+//         %y  modify time                %Y  modify time, seconds
+//         %z  change time                %Z  change time, seconds
+//         %%  a %
+//     An unknown directive is printed as it is.
+//
+// Options:
+//     -c, --format: print FORMAT, and a newline, for each FILE
+//     --printf: print FORMAT, with \ escapes, and no newline, for each FILE
+//     -t, --terse: print the status on one line
+//     -L, --dereference: follow symbolic links
+//
+// Example:
+//     $ stat -c '%U:%G %a' /etc/passwd
+//     root:root 644
+//     $ [ $(stat -c %s core) -gt 0 ] && echo dumped
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	format      string
+	printf      = flag.String("printf", "", "print `format`, with \\ escapes, and no newline")
+	terse       bool
+	dereference bool
+)
+
+func init() {
+	for _, n := range []string{"c", "format"} {
+		flag.StringVar(&format, n, "", "print `format`, and a newline")
+	}
+	for _, n := range []string{"t", "terse"} {
+		flag.BoolVar(&terse, n, false, "print the status on one line")
+	}
+	for _, n := range []string{"L", "dereference"} {
+		flag.BoolVar(&dereference, n, false, "follow symbolic links")
+	}
+}
+
+const (
+	terseFormat = "%n %s %b %f %u %g %D %i %h %t %T %X %Y %Z %W %o\n"
+	timeFormat  = "2006-01-02 15:04:05.000000000 -0700"
+)
+
+// A file is a file, and its status.
+type file struct {
+	name   string
+	target string
+	fi     os.FileInfo
+	st     *syscall.Stat_t
+}
+
+// statFile returns the status of name, or with follow, of what it links
+// to.
+func statFile(name string, follow bool) (*file, error) {
+	stat := os.Lstat
+	if follow {
+		stat = os.Stat
+	}
+	fi, err := stat(name)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("%s: no status", name)
+	}
+	f := &file{name: name, fi: fi, st: st}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if f.target, err = os.Readlink(name); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// fileType returns the type of the file with the raw mode m, as the
+// directive %F prints it.
+func fileType(m uint32, size int64) string {
+	switch m & syscall.S_IFMT {
+	case syscall.S_IFREG:
+		if size == 0 {
+			return "regular empty file"
+		}
+		return "regular file"
+	case syscall.S_IFDIR:
+		return "directory"
+	case syscall.S_IFLNK:
+		return "symbolic link"
+	case syscall.S_IFIFO:
+		return "fifo"
+	case syscall.S_IFSOCK:
+		return "socket"
+	case syscall.S_IFCHR:
+		return "character special file"
+	case syscall.S_IFBLK:
+		return "block special file"
+	}
+	return "weird file"
+}
+
+// modeString returns the raw mode m as ls shows it, e.g. -rwsr-xr-x.
+func modeString(m uint32) string {
+	b := []byte("?---------")
+	switch m & syscall.S_IFMT {
+	case syscall.S_IFREG:
+		b[0] = '-'
+	case syscall.S_IFDIR:
+		b[0] = 'd'
+	case syscall.S_IFLNK:
+		b[0] = 'l'
+	case syscall.S_IFIFO:
+		b[0] = 'p'
+	case syscall.S_IFSOCK:
+		b[0] = 's'
+	case syscall.S_IFCHR:
+		b[0] = 'c'
+	case syscall.S_IFBLK:
+		b[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if m&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	// The set-ID and sticky bits replace the x, and are capitals
+	// without it.
+	for _, s := range []struct {
+		bit uint32
+		i   int
+		c   byte
+	}{
+		{syscall.S_ISUID, 3, 's'},
+		{syscall.S_ISGID, 6, 's'},
+		{syscall.S_ISVTX, 9, 't'},
+	} {
+		if m&s.bit == 0 {
+			continue
+		}
+		if b[s.i] == 'x' {
+			b[s.i] = s.c
+		} else {
+			b[s.i] = s.c - 'a' + 'A'
+		}
+	}
+	return string(b)
+}
+
+// quote quotes s with single quotes, as the directive %N prints names.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func userName(id uint32) string {
+	if u, err := user.LookupId(fmt.Sprint(id)); err == nil {
+		return u.Username
+	}
+	return "UNKNOWN"
+}
+
+func groupName(id uint32) string {
+	if g, err := user.LookupGroupId(fmt.Sprint(id)); err == nil {
+		return g.Name
+	}
+	return "UNKNOWN"
+}
+
+func timespec(ts syscall.Timespec) time.Time {
+	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
+}
+
+// directive returns the value of the directive c for f, and the verb to
+// print it with, or false if there is no such directive.
+func (f *file) directive(c byte) (interface{}, byte, bool) {
+	st := f.st
+	switch c {
+	case 'a':
+		return uint64(st.Mode & 07777), 'o', true
+	case 'A':
+		return modeString(st.Mode), 's', true
+	case 'b':
+		return int64(st.Blocks), 'd', true
+	case 'B':
+		return 512, 'd', true
+	case 'd':
+		return uint64(st.Dev), 'd', true
+	case 'D':
+		return uint64(st.Dev), 'x', true
+	case 'f':
+		return uint64(st.Mode), 'x', true
+	case 'F':
+		return fileType(st.Mode, int64(st.Size)), 's', true
+	case 'g':
+		return uint64(st.Gid), 'd', true
+	case 'G':
+		return groupName(st.Gid), 's', true
+	case 'h':
+		return uint64(st.Nlink), 'd', true
+	case 'i':
+		return uint64(st.Ino), 'd', true
+	case 'n':
+		return f.name, 's', true
+	case 'N':
+		if f.target != "" {
+			return quote(f.name) + " -> " + quote(f.target), 's', true
+		}
+		return quote(f.name), 's', true
+	case 'o':
+		return int64(st.Blksize), 'd', true
+	case 's':
+		return int64(st.Size), 'd', true
+	case 't':
+		return uint64(unix.Major(uint64(st.Rdev))), 'x', true
+	case 'T':
+		return uint64(unix.Minor(uint64(st.Rdev))), 'x', true
+	case 'u':
+		return uint64(st.Uid), 'd', true
+	case 'U':
+		return userName(st.Uid), 's', true
+	case 'w':
+		// The birth time is not in a stat(2).
+		return "-", 's', true
+	case 'W':
+		return 0, 'd', true
+	case 'x':
+		return timespec(st.Atim).Format(timeFormat), 's', true
+	case 'X':
+		return int64(st.Atim.Sec), 'd', true
+	case 'y':
+		return timespec(st.Mtim).Format(timeFormat), 's', true
+	case 'Y':
+		return int64(st.Mtim.Sec), 'd', true
+	case 'z':
+		return timespec(st.Ctim).Format(timeFormat), 's', true
+	case 'Z':
+		return int64(st.Ctim.Sec), 'd', true
+	}
+	return nil, 0, false
+}
+
+// format returns format with the directives in it replaced by the status
+// of f.
+func (f *file) format(format string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ 0#'", format[j]) >= 0 {
+			j++
+		}
+		for j < len(format) && (format[j] >= '0' && format[j] <= '9' || format[j] == '.') {
+			j++
+		}
+		if j >= len(format) {
+			b.WriteString(format[i:])
+			break
+		}
+		spec := strings.Replace(format[i:j], "'", "", -1)
+		if format[j] == '%' {
+			b.WriteByte('%')
+		} else if v, verb, ok := f.directive(format[j]); ok {
+			fmt.Fprintf(&b, spec+string(verb), v)
+		} else {
+			b.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// defaultFormat returns the format of the status of f without -c or -t.
+func (f *file) defaultFormat() string {
+	name := strings.Replace(f.name, "%", "%%", -1)
+	if f.target != "" {
+		name += " -> " + strings.Replace(f.target, "%", "%%", -1)
+	}
+	links := "Links: %h\n"
+	if m := f.st.Mode & syscall.S_IFMT; m == syscall.S_IFCHR || m == syscall.S_IFBLK {
+		links = "Links: %-5h Device type: %t,%T\n"
+	}
+	return "  File: " + name + "\n" +
+		"  Size: %-10s\tBlocks: %-10b IO Block: %-6o %F\n" +
+		"Device: %Dh/%dd\tInode: %-11i " + links +
+		"Access: (%04a/%10.10A)  Uid: (%5u/%8U)   Gid: (%5g/%8G)\n" +
+		"Access: %x\n" +
+		"Modify: %y\n" +
+		"Change: %z\n" +
+		" Birth: %w\n"
+}
+
+// unescape replaces the escapes \a, \b, \f, \n, \r, \t, \v, \", \\, \NNN
+// in octal, and \xHH in hex, in s.
+func unescape(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if c, ok := map[byte]byte{'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v', '"': '"', '\\': '\\'}[s[i]]; ok {
+			b.WriteByte(c)
+			continue
+		}
+		base, digits, j := 8, "01234567", i
+		if s[i] == 'x' {
+			base, digits, j = 16, "0123456789abcdefABCDEF", i+1
+		}
+		k := j
+		for k < len(s) && k-j < 3 && strings.IndexByte(digits, s[k]) >= 0 {
+			k++
+		}
+		if base == 16 && k-j > 2 {
+			k = j + 2
+		}
+		if k == j {
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+			continue
+		}
+		n, _ := strconv.ParseUint(s[j:k], base, 16)
+		b.WriteByte(byte(n))
+		i = k - 1
+	}
+	return b.String()
+}
+
+func run(w io.Writer, files []string) int {
+	status := 0
+	for _, name := range files {
+		f, err := statFile(name, dereference)
+		if err != nil {
+			log.Printf("stat: %v", err)
+			status = 1
+			continue
+		}
+		switch {
+		case *printf != "":
+			io.WriteString(w, f.format(unescape(*printf)))
+		case format != "":
+			io.WriteString(w, f.format(format)+"\n")
+		case terse:
+			io.WriteString(w, f.format(terseFormat))
+		default:
+			io.WriteString(w, f.format(f.defaultFormat()))
+		}
+	}
+	return status
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("stat: missing file")
+	}
+	os.Exit(run(os.Stdout, flag.Args()))
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestModeString(t *testing.T) {
+	for _, tt := range []struct {
+		mode uint32
+		want string
+	}{
+		{syscall.S_IFREG | 0644, "-rw-r--r--"},
+		{syscall.S_IFDIR | 0755, "drwxr-xr-x"},
+		{syscall.S_IFDIR | 01777, "drwxrwxrwt"},
+		{syscall.S_IFDIR | 01770, "drwxrwx--T"},
+		{syscall.S_IFREG | 04755, "-rwsr-xr-x"},
+		{syscall.S_IFREG | 02644, "-rw-r-Sr--"},
+		{syscall.S_IFLNK | 0777, "lrwxrwxrwx"},
+		{syscall.S_IFCHR | 0666, "crw-rw-rw-"},
+		{syscall.S_IFBLK | 0660, "brw-rw----"},
+		{syscall.S_IFIFO | 0600, "prw-------"},
+		{syscall.S_IFSOCK | 0755, "srwxr-xr-x"},
+	} {
+		if got := modeString(tt.mode); got != tt.want {
+			t.Errorf("modeString(%o): got %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{`a\tb\n`, "a\tb\n"},
+		{`\101\x42\\`, "AB\\"},
+		{`\q`, `\q`},
+		{`end\`, `end\`},
+	} {
+		if got := unescape(tt.in); got != tt.want {
+			t.Errorf("unescape(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "f")
+	if err := ioutil.WriteFile(name, []byte("hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Unix(1500000000, 0)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "l")
+	if err := os.Symlink("f", link); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "e")
+	if err := ioutil.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := fmt.Sprint(os.Getuid())
+	for _, tt := range []struct {
+		name   string
+		follow bool
+		format string
+		want   string
+	}{
+		{name, false, "%s %a %A %F", "6 640 -rw-r----- regular file"},
+		{name, false, "[%-4s][%4s][%04a]", "[6   ][   6][0640]"},
+		{name, false, "%u %h %Y %X", uid + " 1 1500000000 1500000000"},
+		{name, false, "%f %B %W %w", "81a0 512 0 -"},
+		{name, false, "100%% %q %", "100% %q %"},
+		{link, false, "%F %a %N", "symbolic link 777 '" + link + "' -> 'f'"},
+		{link, true, "%F %s", "regular file 6"},
+		{empty, false, "%F", "regular empty file"},
+		{dir, false, "%F %A", "directory drwx------"},
+	} {
+		f, err := statFile(tt.name, tt.follow)
+		if err != nil {
+			t.Errorf("statFile(%q): %v", tt.name, err)
+			continue
+		}
+		if got := f.format(tt.format); got != tt.want {
+			t.Errorf("%q on %q: got %q, want %q", tt.format, tt.name, got, tt.want)
+		}
+	}
+
+	f, err := statFile(name, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(f.format(terseFormat))
+	if len(fields) != 16 || fields[0] != name || fields[1] != "6" || fields[3] != "81a0" {
+		t.Errorf("terse: got %q", fields)
+	}
+	def := f.format(f.defaultFormat())
+	for _, want := range []string{
+		"  File: " + name + "\n",
+		"  Size: 6         \tBlocks: ",
+		"Access: (0640/-rw-r-----)  Uid: (",
+		"Modify: " + mtime.Format(timeFormat) + "\n",
+	} {
+		if !strings.Contains(def, want) {
+			t.Errorf("default format: got %q, which does not contain %q", def, want)
+		}
+	}
+
+	if _, err := statFile(filepath.Join(dir, "nothere"), false); !os.IsNotExist(err) {
+		t.Errorf("statFile(nothere): got %v, want a does not exist error", err)
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Trace the system calls of a command.
+//
+// Synopsis:
+//     strace [-f] [-o FILE] COMMAND [ARGS...]
+//
+// Description:
+//     strace runs COMMAND and prints each system call it makes, with its
+//     arguments and what it returned, and the signals it gets. Paths and
+//     the data written are printed as strings, open flags by name and
+//     errors by their message. The exit status is that of COMMAND.
+//
+//     Only amd64 is supported.
+//
+// Options:
+//     -f: trace the children of COMMAND too; lines start with their PID
+//     -o: write the trace to FILE rather than stderr
+//
+// Example:
+//     strace -f rush -c 'cat /etc/hostname'
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var (
+	follow = flag.Bool("f", false, "trace children too")
+	output = flag.String("o", "", "write the trace to `file`")
+)
+
+const (
+	// maxString is how much of a string or buffer is printed.
+	maxString = 32
+	// errnoMax is the largest errno; returns from -errnoMax to -1 are
+	// errors.
+	errnoMax = 4095
+)
+
+// A sysinfo says how to print a system call. Each letter of args is
+// how to print an argument:
+//     d: decimal
+//     x: hexadecimal
+//     o: octal, for modes
+//     p: pointer
+//     s: string at the pointer
+//     b: buffer at the pointer, whose size is the next argument
+//     a: directory file descriptor of the *at calls
+//     f: open flags
+type sysinfo struct {
+	name string
+	args string
+}
+
+// A tracee is a process being traced.
+type tracee struct {
+	pid int
+	// inSyscall is set between the entry and exit of a system call.
+	inSyscall bool
+	// call is the name and arguments of the system call it is in.
+	call string
+	// name is the name of that system call.
+	name string
+}
+
+// openFlags are the names of the flags of open, but for the access mode.
+var openFlags = []struct {
+	flag int
+	name string
+}{
+	{syscall.O_CREAT, "O_CREAT"},
+	{syscall.O_EXCL, "O_EXCL"},
+	{syscall.O_NOCTTY, "O_NOCTTY"},
+	{syscall.O_TRUNC, "O_TRUNC"},
+	{syscall.O_APPEND, "O_APPEND"},
+	{syscall.O_NONBLOCK, "O_NONBLOCK"},
+	{syscall.O_DIRECTORY, "O_DIRECTORY"},
+	{syscall.O_NOFOLLOW, "O_NOFOLLOW"},
+	{syscall.O_CLOEXEC, "O_CLOEXEC"},
+}
+
+func flagString(f uint64) string {
+	names := []string{[]string{"O_RDONLY", "O_WRONLY", "O_RDWR", "O_ACCMODE"}[f&syscall.O_ACCMODE]}
+	f &^= syscall.O_ACCMODE
+	for _, o := range openFlags {
+		if f&uint64(o.flag) != 0 {
+			names = append(names, o.name)
+			f &^= uint64(o.flag)
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("%#x", f))
+	}
+	return strings.Join(names, "|")
+}
+
+// readString reads a NUL-terminated string of at most max bytes at addr
+// in the memory of pid.
+func readString(pid int, addr uint64, max int) (string, bool) {
+	var b []byte
+	buf := make([]byte, 64)
+	for len(b) <= max {
+		n, err := syscall.PtracePeekData(pid, uintptr(addr)+uintptr(len(b)), buf)
+		if err != nil || n == 0 {
+			break
+		}
+		if i := bytes.IndexByte(buf[:n], 0); i >= 0 {
+			return string(append(b, buf[:i]...)), true
+		}
+		b = append(b, buf[:n]...)
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b), false
+}
+
+// quote quotes s, adding ... if it is not all there is.
+func quote(s string, complete bool) string {
+	q := strconv.Quote(s)
+	if !complete {
+		q += "..."
+	}
+	return q
+}
+
+// formatArgs formats the arguments a of a system call as kinds says.
+func formatArgs(pid int, kinds string, a [6]uint64) string {
+	var s []string
+	for i, k := range kinds {
+		v := a[i]
+		switch k {
+		case 'd':
+			// ints are 32 bits, with the upper bits left as they were.
+			if v>>32 == 0 || v>>31 == 1<<33-1 {
+				v = uint64(int32(v))
+			}
+			s = append(s, strconv.FormatInt(int64(v), 10))
+		case 'o':
+			s = append(s, fmt.Sprintf("%#o", v))
+		case 'p':
+			if v == 0 {
+				s = append(s, "NULL")
+			} else {
+				s = append(s, fmt.Sprintf("%#x", v))
+			}
+		case 's':
+			if v == 0 {
+				s = append(s, "NULL")
+				break
+			}
+			s = append(s, quote(readString(pid, v, 4096)))
+		case 'b':
+			n := int(a[i+1])
+			if n > maxString {
+				n = maxString
+			}
+			b := make([]byte, n)
+			if _, err := syscall.PtracePeekData(pid, uintptr(v), b); err != nil {
+				s = append(s, fmt.Sprintf("%#x", v))
+				break
+			}
+			s = append(s, quote(string(b), uint64(n) == a[i+1]))
+		case 'a':
+			if int32(v) == -100 {
+				s = append(s, "AT_FDCWD")
+			} else {
+				s = append(s, strconv.Itoa(int(int32(v))))
+			}
+		case 'f':
+			s = append(s, flagString(v))
+		default:
+			s = append(s, fmt.Sprintf("%#x", v))
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+// formatReturn formats what the system call name returned.
+func formatReturn(name string, r uint64) string {
+	if n := int64(r); n < 0 && n >= -errnoMax {
+		return fmt.Sprintf("-1 (%v)", syscall.Errno(-n))
+	}
+	switch name {
+	case "mmap", "mremap", "brk":
+		return fmt.Sprintf("%#x", r)
+	}
+	return strconv.FormatInt(int64(r), 10)
+}
+
+// call returns the name and arguments of the system call pid is entering.
+func call(pid int, r *syscall.PtraceRegs) (string, string) {
+	nr := sysno(r)
+	info, ok := syscalls[nr]
+	if !ok {
+		info = sysinfo{fmt.Sprintf("syscall_%d", nr), "xxxxxx"}
+	}
+	return info.name, info.name + "(" + formatArgs(pid, info.args, args(r)) + ")"
+}
+
+// tracer traces a process and, with -f, its children.
+type tracer struct {
+	w       io.Writer
+	tracees map[int]*tracee
+	// prefix is set to print PIDs.
+	prefix bool
+}
+
+func (t *tracer) printf(pid int, format string, a ...interface{}) {
+	if t.prefix {
+		fmt.Fprintf(t.w, "[pid %5d] ", pid)
+	}
+	fmt.Fprintf(t.w, format, a...)
+}
+
+// stop handles a stop of the tracee p. It returns the signal to pass on
+// to p.
+func (t *tracer) stop(p *tracee, ws syscall.WaitStatus) syscall.Signal {
+	sig := ws.StopSignal()
+	switch {
+	case sig == syscall.SIGTRAP|0x80:
+		var r syscall.PtraceRegs
+		if err := syscall.PtraceGetRegs(p.pid, &r); err != nil {
+			return 0
+		}
+		if !p.inSyscall {
+			p.name, p.call = call(p.pid, &r)
+			p.inSyscall = true
+			// These do not return.
+			if p.name == "exit" || p.name == "exit_group" {
+				t.printf(p.pid, "%s = ?\n", p.call)
+			}
+			return 0
+		}
+		p.inSyscall = false
+		t.printf(p.pid, "%s = %s\n", p.call, formatReturn(p.name, retval(&r)))
+		return 0
+	case sig == syscall.SIGTRAP && ws.TrapCause() > 0:
+		// A fork, clone or exec event; the new process is
+		// reported by its own stop.
+		return 0
+	}
+	t.printf(p.pid, "--- signal %d (%v) ---\n", sig, sig)
+	return sig
+}
+
+// run traces the processes until they have all exited, and returns the
+// exit status of the first.
+func (t *tracer) run(first int) int {
+	status := 0
+	for len(t.tracees) > 0 {
+		var ws syscall.WaitStatus
+		// __WALL waits for clones too.
+		pid, err := syscall.Wait4(-1, &ws, 0x40000000, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			log.Printf("strace: wait: %v", err)
+			break
+		}
+		p, ok := t.tracees[pid]
+		if !ok {
+			// A new child. It starts stopped.
+			p = &tracee{pid: pid}
+			t.tracees[pid] = p
+			if ws.Stopped() && ws.StopSignal() == syscall.SIGSTOP {
+				syscall.PtraceSyscall(pid, 0)
+				continue
+			}
+		}
+		switch {
+		case ws.Exited():
+			t.printf(pid, "+++ exited with %d +++\n", ws.ExitStatus())
+			delete(t.tracees, pid)
+			if pid == first {
+				status = ws.ExitStatus()
+			}
+			continue
+		case ws.Signaled():
+			t.printf(pid, "+++ killed by %v +++\n", ws.Signal())
+			delete(t.tracees, pid)
+			if pid == first {
+				status = 128 + int(ws.Signal())
+			}
+			continue
+		}
+		sig := t.stop(p, ws)
+		syscall.PtraceSyscall(pid, int(sig))
+	}
+	return status
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("Usage: strace [-f] [-o FILE] COMMAND [ARGS...]")
+	}
+	if !supported {
+		log.Fatalf("strace: %v is not supported", runtime.GOARCH)
+	}
+	w := os.Stderr
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("strace: %v", err)
+		}
+		w = f
+	}
+
+	// All ptrace requests have to come from the thread which traces.
+	runtime.LockOSThread()
+	c := exec.Command(flag.Arg(0), flag.Args()[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	if err := c.Start(); err != nil {
+		log.Fatalf("strace: %v", err)
+	}
+	pid := c.Process.Pid
+	// The child stops at its exec.
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, 0, nil); err != nil {
+		log.Fatalf("strace: %v", err)
+	}
+	opts := syscall.PTRACE_O_TRACESYSGOOD | syscall.PTRACE_O_TRACEEXEC
+	if *follow {
+		opts |= syscall.PTRACE_O_TRACEFORK | syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACECLONE
+	}
+	if err := syscall.PtraceSetOptions(pid, opts); err != nil {
+		log.Fatalf("strace: %v", err)
+	}
+	t := &tracer{w: w, tracees: map[int]*tracee{pid: {pid: pid}}, prefix: *follow}
+	if err := syscall.PtraceSyscall(pid, 0); err != nil {
+		log.Fatalf("strace: %v", err)
+	}
+	status := t.run(pid)
+	w.Close()
+	os.Exit(status)
+}
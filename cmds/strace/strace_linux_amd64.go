@@ -0,0 +1,156 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "syscall"
+
+const supported = true
+
+// sysno returns the number of the system call being made.
+func sysno(r *syscall.PtraceRegs) uint64 {
+	return r.Orig_rax
+}
+
+// args returns the arguments of the system call being made.
+func args(r *syscall.PtraceRegs) [6]uint64 {
+	return [6]uint64{r.Rdi, r.Rsi, r.Rdx, r.R10, r.R8, r.R9}
+}
+
+// retval returns what the system call returned.
+func retval(r *syscall.PtraceRegs) uint64 {
+	return r.Rax
+}
+
+var syscalls = map[uint64]sysinfo{
+	0:   {"read", "dpd"},
+	1:   {"write", "dbd"},
+	2:   {"open", "sfo"},
+	3:   {"close", "d"},
+	4:   {"stat", "sp"},
+	5:   {"fstat", "dp"},
+	6:   {"lstat", "sp"},
+	7:   {"poll", "pdd"},
+	8:   {"lseek", "ddd"},
+	9:   {"mmap", "pdxxdx"},
+	10:  {"mprotect", "pdx"},
+	11:  {"munmap", "pd"},
+	12:  {"brk", "p"},
+	13:  {"rt_sigaction", "dppd"},
+	14:  {"rt_sigprocmask", "dppd"},
+	15:  {"rt_sigreturn", ""},
+	16:  {"ioctl", "dxp"},
+	17:  {"pread64", "dpdd"},
+	18:  {"pwrite64", "dbdd"},
+	19:  {"readv", "dpd"},
+	20:  {"writev", "dpd"},
+	21:  {"access", "so"},
+	22:  {"pipe", "p"},
+	23:  {"select", "dpppp"},
+	24:  {"sched_yield", ""},
+	32:  {"dup", "d"},
+	33:  {"dup2", "dd"},
+	34:  {"pause", ""},
+	35:  {"nanosleep", "pp"},
+	39:  {"getpid", ""},
+	40:  {"sendfile", "ddpd"},
+	41:  {"socket", "ddd"},
+	42:  {"connect", "dpd"},
+	43:  {"accept", "dpp"},
+	44:  {"sendto", "dbdxpd"},
+	45:  {"recvfrom", "dpdxpp"},
+	49:  {"bind", "dpd"},
+	50:  {"listen", "dd"},
+	54:  {"setsockopt", "dddpd"},
+	55:  {"getsockopt", "dddpp"},
+	56:  {"clone", "xppp"},
+	57:  {"fork", ""},
+	58:  {"vfork", ""},
+	59:  {"execve", "spp"},
+	60:  {"exit", "d"},
+	61:  {"wait4", "dpxp"},
+	62:  {"kill", "dd"},
+	63:  {"uname", "p"},
+	72:  {"fcntl", "ddx"},
+	74:  {"fsync", "d"},
+	76:  {"truncate", "sd"},
+	77:  {"ftruncate", "dd"},
+	78:  {"getdents", "dpd"},
+	79:  {"getcwd", "pd"},
+	80:  {"chdir", "s"},
+	81:  {"fchdir", "d"},
+	82:  {"rename", "ss"},
+	83:  {"mkdir", "so"},
+	84:  {"rmdir", "s"},
+	86:  {"link", "ss"},
+	87:  {"unlink", "s"},
+	88:  {"symlink", "ss"},
+	89:  {"readlink", "spd"},
+	90:  {"chmod", "so"},
+	92:  {"chown", "sdd"},
+	95:  {"umask", "o"},
+	96:  {"gettimeofday", "pp"},
+	97:  {"getrlimit", "dp"},
+	99:  {"sysinfo", "p"},
+	101: {"ptrace", "ddpp"},
+	102: {"getuid", ""},
+	103: {"syslog", "dpd"},
+	104: {"getgid", ""},
+	107: {"geteuid", ""},
+	108: {"getegid", ""},
+	110: {"getppid", ""},
+	131: {"sigaltstack", "pp"},
+	137: {"statfs", "sp"},
+	138: {"fstatfs", "dp"},
+	155: {"pivot_root", "ss"},
+	157: {"prctl", "dxxxx"},
+	158: {"arch_prctl", "xp"},
+	161: {"chroot", "s"},
+	165: {"mount", "sssxp"},
+	166: {"umount2", "sx"},
+	169: {"reboot", "xxxp"},
+	170: {"sethostname", "bd"},
+	175: {"init_module", "pds"},
+	176: {"delete_module", "sx"},
+	186: {"gettid", ""},
+	202: {"futex", "pddppd"},
+	204: {"sched_getaffinity", "ddp"},
+	217: {"getdents64", "dpd"},
+	218: {"set_tid_address", "p"},
+	228: {"clock_gettime", "dp"},
+	230: {"clock_nanosleep", "dxpp"},
+	231: {"exit_group", "d"},
+	232: {"epoll_wait", "dpdd"},
+	233: {"epoll_ctl", "dddp"},
+	234: {"tgkill", "ddd"},
+	257: {"openat", "asfo"},
+	258: {"mkdirat", "aso"},
+	262: {"newfstatat", "aspx"},
+	263: {"unlinkat", "asx"},
+	264: {"renameat", "asas"},
+	265: {"linkat", "asasx"},
+	266: {"symlinkat", "sas"},
+	267: {"readlinkat", "aspd"},
+	268: {"fchmodat", "aso"},
+	269: {"faccessat", "aso"},
+	272: {"unshare", "x"},
+	273: {"set_robust_list", "pd"},
+	280: {"utimensat", "aspx"},
+	281: {"epoll_pwait", "dpddpd"},
+	290: {"eventfd2", "dx"},
+	291: {"epoll_create1", "x"},
+	292: {"dup3", "ddx"},
+	293: {"pipe2", "px"},
+	302: {"prlimit64", "ddpp"},
+	308: {"setns", "dx"},
+	313: {"finit_module", "dsx"},
+	316: {"renameat2", "asasx"},
+	318: {"getrandom", "pdx"},
+	320: {"kexec_file_load", "dddsx"},
+	322: {"execveat", "asppx"},
+	332: {"statx", "asxxp"},
+	334: {"rseq", "pdxx"},
+	435: {"clone3", "pd"},
+	439: {"faccessat2", "asox"},
+}
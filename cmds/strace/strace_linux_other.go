@@ -0,0 +1,26 @@
+// +build linux,!amd64
+
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "syscall"
+
+// The registers of system calls are only known for amd64.
+const supported = false
+
+func sysno(r *syscall.PtraceRegs) uint64 {
+	return 0
+}
+
+func args(r *syscall.PtraceRegs) [6]uint64 {
+	return [6]uint64{}
+}
+
+func retval(r *syscall.PtraceRegs) uint64 {
+	return 0
+}
+
+var syscalls = map[uint64]sysinfo{}
@@ -0,0 +1,62 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestFlagString(t *testing.T) {
+	for _, tt := range []struct {
+		f    uint64
+		want string
+	}{
+		{syscall.O_RDONLY, "O_RDONLY"},
+		{syscall.O_WRONLY | syscall.O_CREAT | syscall.O_TRUNC, "O_WRONLY|O_CREAT|O_TRUNC"},
+		{syscall.O_RDWR | syscall.O_CLOEXEC, "O_RDWR|O_CLOEXEC"},
+		{syscall.O_RDONLY | 0x10000000, "O_RDONLY|0x10000000"},
+	} {
+		if got := flagString(tt.f); got != tt.want {
+			t.Errorf("flagString(%#x): got %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestFormatArgs(t *testing.T) {
+	for _, tt := range []struct {
+		kinds string
+		a     [6]uint64
+		want  string
+	}{
+		{"d", [6]uint64{3}, "3"},
+		{"dd", [6]uint64{1<<32 - 1, 1<<64 - 1}, "-1, -1"},
+		{"dxo", [6]uint64{1 << 40, 255, 0644}, "1099511627776, 0xff, 0644"},
+		{"pp", [6]uint64{0, 0x1000}, "NULL, 0x1000"},
+		{"a", [6]uint64{1<<32 - 100}, "AT_FDCWD"},
+		{"af", [6]uint64{3, syscall.O_RDONLY}, "3, O_RDONLY"},
+	} {
+		if got := formatArgs(0, tt.kinds, tt.a); got != tt.want {
+			t.Errorf("formatArgs(%q, %v): got %q, want %q", tt.kinds, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestFormatReturn(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		r    int64
+		want string
+	}{
+		{"read", 10, "10"},
+		{"openat", -int64(syscall.ENOENT), "-1 (no such file or directory)"},
+		{"mmap", 0x7f0000000000, "0x7f0000000000"},
+		{"lseek", 1 << 40, "1099511627776"},
+	} {
+		if got := formatReturn(tt.name, uint64(tt.r)); got != tt.want {
+			t.Errorf("formatReturn(%q, %#x): got %q, want %q", tt.name, tt.r, got, tt.want)
+		}
+	}
+}
@@ -24,14 +24,19 @@
 // dump -- dump the json of the struct to stdout
 // load -- read a json file from stdin and use it to set
 // raw -- convenience command to set raw
-// cooked -- convenience command to set cooked
+// cooked -- convenience command to set cooked, undoing raw
+// size -- print the rows and columns
+// speed -- print the speed, or with an arg, set it
 // In common stty usage, options may be specified without a verb.
 //
 // any other verb, with a ~ or without, is taken to mean standard stty args, e.g.
 // stty ~echo
-// turns off echo. Flags with arguments work too:
+// turns off echo. - works as well as ~ after --, e.g. stty -- -echo. Flags
+// with arguments work too:
 // stty intr 1
-// sets the interrupt character to ^A.
+// sets the interrupt character to ^A, as does stty intr ^A. A number on its
+// own sets the speed:
+// stty 115200
 //
 // The JSON encoding lets you do things like this:
 // stty dump | sed whatever > file
@@ -55,10 +60,12 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+
+	"github.com/u-root/u-root/pkg/termios"
 )
 
 func main() {
-	t, err := gtty(0)
+	t, err := termios.GTTY(0)
 
 	if err != nil {
 		log.Fatalf("gtty: %v", err)
@@ -67,10 +74,15 @@ func main() {
 	if len(os.Args) == 1 {
 		os.Args = append(os.Args, "pretty")
 	}
+	// -- lets options start with -, which Go programs take for flags.
+	if os.Args[1] == "--" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 
 	switch os.Args[1] {
 	case "pretty":
-		pretty(os.Stdout, t)
+		fmt.Print(t)
+		return
 	case "dump":
 		b, err := json.MarshalIndent(t, "", "\t")
 
@@ -78,6 +90,15 @@ func main() {
 			log.Fatalf("json marshal: %v", err)
 		}
 		fmt.Printf("%s\n", b)
+		return
+	case "size":
+		fmt.Printf("%d %d\n", t.Row, t.Col)
+		return
+	case "speed":
+		if len(os.Args) == 2 {
+			fmt.Println(t.Ospeed)
+			return
+		}
 	case "load":
 		if len(os.Args) != 3 {
 			log.Fatalf("arg count")
@@ -89,22 +110,17 @@ func main() {
 		if err := json.Unmarshal(b, t); err != nil {
 			log.Fatalf("stty load: %v", err)
 		}
-		if t, err = stty(0, t); err != nil {
+		if t, err = t.STTY(0); err != nil {
 			log.Fatalf("stty: %v", err)
 		}
-		pretty(os.Stdout, t)
-	case "raw":
-		if _, err := setRaw(0); err != nil {
-			log.Fatalf("raw: %v", err)
-		}
-	default:
-		if err := setOpts(t, os.Args[1:]); err != nil {
-			log.Fatalf("setting opts: %v", err)
-		}
-		t, err = stty(0, t)
-		if err != nil {
-			log.Fatalf("stty: %v", err)
-		}
-		pretty(os.Stdout, t)
+		fmt.Print(t)
+		return
+	}
+
+	if err := t.SetOpts(os.Args[1:]); err != nil {
+		log.Fatalf("setting opts: %v", err)
+	}
+	if _, err = t.STTY(0); err != nil {
+		log.Fatalf("stty: %v", err)
 	}
 }
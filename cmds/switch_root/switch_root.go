@@ -2,6 +2,29 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Switch_root moves from the initramfs to the real root file system.
+//
+// Synopsis:
+//     switch_root [-c CONSOLE] NEW_ROOT NEW_INIT [ARGS...]
+//
+// Description:
+//     switch_root is run by init, as PID 1, once the real root file
+//     system is mounted at NEW_ROOT. It moves the /dev, /proc, /sys and
+//     /run mounts to NEW_ROOT, deletes everything in the initramfs to free
+//     the memory it takes, makes NEW_ROOT the root, and execs NEW_INIT,
+//     a path in it, with ARGS, which then is PID 1.
+//
+//     To keep from deleting the wrong files, switch_root will only run as
+//     PID 1, when / is a ramfs or tmpfs, NEW_ROOT is a mount point, and
+//     NEW_INIT is a program in it.
+//
+// Options:
+//     -c: reopen stdin, stdout and stderr on CONSOLE, e.g. /dev/console,
+//         in the new root
+//
+// Example:
+//     mount /dev/sda1 /newroot
+//     exec switch_root /newroot /sbin/init
 package main
 
 import (
@@ -9,187 +32,182 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ramfsMagic = 0x858458f6
+	tmpfsMagic = 0x01021994
 )
 
 var (
-	help    = flag.Bool("h", false, "Help")
-	version = flag.Bool("V", false, "Version")
+	console = flag.String("c", "", "Reopen stdin, stdout and stderr on `console` in the new root")
+
+	// moved are the mounts which go to the new root.
+	moved = []string{"/dev", "/proc", "/sys", "/run"}
 )
 
-func usage() string {
-	return "switch_root [-h] [-V]\nswitch_root newroot init"
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: switch_root [-c CONSOLE] NEW_ROOT NEW_INIT [ARGS...]\n")
+	flag.PrintDefaults()
 }
 
-// littleDoctor recursively deletes everything at "path" that
-// is in the same file system as "fs".
-func littleDoctor(path string, fsType int64) error {
-	var pathFS syscall.Statfs_t
+// device returns the device of the file system name is on.
+func device(name string) (uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(name, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Dev), nil
+}
 
-	if err := syscall.Statfs(path, &pathFS); err != nil {
+// check returns why it is not safe to switch from / to newRoot, or nil if
+// it is.
+func check(newRoot, init string) error {
+	if os.Getpid() != 1 {
+		return fmt.Errorf("not PID 1")
+	}
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs("/", &fs); err != nil {
 		return err
 	}
-
-	if pathFS.Type != fsType {
-		return nil
+	// Type is an int32 on some architectures.
+	if t := uint32(fs.Type); t != ramfsMagic && t != tmpfsMagic {
+		return fmt.Errorf("/ is not a ramfs or tmpfs, but file system type %#x", t)
+	}
+	root, err := device("/")
+	if err != nil {
+		return err
 	}
+	nr, err := device(newRoot)
+	if err != nil {
+		return err
+	}
+	if root == nr {
+		return fmt.Errorf("%v is not a mount point", newRoot)
+	}
+	fi, err := os.Stat(filepath.Join(newRoot, init))
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() || fi.Mode()&0111 == 0 {
+		return fmt.Errorf("%v in %v is not a program", init, newRoot)
+	}
+	return nil
+}
 
-	file, err := os.Open(path)
+// moveMounts moves the mounts of moved to newRoot. Those with nowhere to go
+// in newRoot are unmounted.
+func moveMounts(newRoot string) {
+	root, err := device("/")
 	if err != nil {
-		return fmt.Errorf("Could not open %s: %v", path, err)
+		log.Printf("switch_root: %v", err)
+		return
+	}
+	for _, m := range moved {
+		if d, err := device(m); err != nil || d == root {
+			// Not mounted.
+			continue
+		}
+		to := filepath.Join(newRoot, m)
+		if err := syscall.Mount(m, to, "", syscall.MS_MOVE, ""); err != nil {
+			log.Printf("switch_root: moving %v to %v: %v; unmounting it", m, to, err)
+			syscall.Unmount(m, syscall.MNT_DETACH)
+		}
 	}
+}
 
-	if fileStat, err := file.Stat(); fileStat.IsDir() {
+// deleteContents deletes everything at path which is on the file system
+// dev, and leaves mount points, such as the new root, alone. Errors are
+// logged: there is no going back now.
+func deleteContents(path string, dev uint64) {
+	d, err := device(path)
+	if err != nil {
+		log.Printf("switch_root: %v", err)
+		return
+	}
+	if d != dev {
+		return
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		log.Printf("switch_root: %v", err)
+		return
+	}
+	if fi.IsDir() {
+		f, err := os.Open(path)
 		if err != nil {
-			return err
+			log.Printf("switch_root: %v", err)
+			return
 		}
-
-		names, err := file.Readdirnames(-1)
-		defer file.Close()
+		names, err := f.Readdirnames(-1)
+		f.Close()
 		if err != nil {
-			return err
+			log.Printf("switch_root: %v", err)
 		}
-
-		for _, fileName := range names {
-
-			if fileName == "." || fileName == ".." {
-				continue
-			}
-
-			if err := littleDoctor(filepath.Join(path, fileName), fsType); err != nil {
-				return err
-			}
-			if err := os.Remove(path); err != nil {
-				return err
-			}
+		for _, n := range names {
+			deleteContents(filepath.Join(path, n), dev)
 		}
-
-	} else {
-		if err := os.Remove(path); err != nil {
-			return err
+		if path == "/" {
+			return
 		}
 	}
-
-	return nil
-}
-
-// execCommand will run the executable at "path" with PID 1.
-// It returns an error if the command exits incorrectly.
-func execCommand(path string) error {
-	cmd := exec.Command(path)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setctty:    true,
-		Setsid:     true,
-		Cloneflags: syscall.CLONE_THREAD | syscall.CLONE_NEWPID,
+	if err := os.Remove(path); err != nil {
+		log.Printf("switch_root: %v", err)
 	}
-
-	return cmd.Run()
 }
 
-// specialFS creates and mounts proc, sys and dev at the root level.
-func specialFS() error {
-
-	if err := syscall.Mkdir("/proc", 0); err != nil {
-		return err
-	}
-
-	if err := syscall.Mkdir("/sys", 0); err != nil {
+// switchRoot moves the mounts to newRoot, deletes the initramfs, makes
+// newRoot the root and execs init in it. It only returns if it fails.
+func switchRoot(newRoot, init string, args []string) error {
+	if err := check(newRoot, init); err != nil {
 		return err
 	}
-
-	if err := syscall.Mkdir("/dev", 0); err != nil {
+	moveMounts(newRoot)
+	if err := os.Chdir(newRoot); err != nil {
 		return err
 	}
-
-	if err := syscall.Mount("", "/proc", "proc", 0, ""); err != nil {
+	root, err := device("/")
+	if err != nil {
 		return err
 	}
+	deleteContents("/", root)
 
-	if err := syscall.Mount("", "/sys", "sysfs", 0, ""); err != nil {
-		return err
+	if err := syscall.Mount(".", "/", "", syscall.MS_MOVE, ""); err != nil {
+		return fmt.Errorf("moving %v to /: %v", newRoot, err)
 	}
-
-	return syscall.Mount("", "/dev", "devtmpfs", 0, "")
-}
-
-// switchRoot will recursive deletes current root, switches the current root to
-// the "newRoot", creates special filesystems (proc, sys and dev) in the new root
-// and execs "init"
-func switchRoot(newRoot string, init string) error {
-	log.Printf("switch_root: Changing directory")
-	var rootFS syscall.Statfs_t
-
-	if err := syscall.Chdir(newRoot); err != nil {
-		return fmt.Errorf("switch_root: failed change directory to new_root %v", err)
-	}
-
-	if err := syscall.Statfs("/", &rootFS); err != nil {
-		return fmt.Errorf("switch_root: failed statfs %v", err)
-	}
-
-	if err := littleDoctor("/", rootFS.Type); err != nil {
-		return fmt.Errorf("switch_root: failed Deletion of rootfs %v", err)
-	}
-
-	log.Printf("switch_root: Overmounting on /")
-
-	if err := syscall.Mount(".", "/", "ext4", syscall.MS_MOVE, ""); err != nil {
-		return fmt.Errorf("switch_root: fatal mount error %v", err)
-	}
-
-	log.Printf("switch_root: Changing root!")
-
 	if err := syscall.Chroot("."); err != nil {
-		return fmt.Errorf("switch_root: fatal chroot error %v", err)
-	}
-
-	log.Printf("switch_root: returning to slash")
-	if err := syscall.Chdir("/"); err != nil {
-		return fmt.Errorf("switch_root: failed change directory to '/' %v", err)
+		return fmt.Errorf("chroot: %v", err)
 	}
-
-	log.Printf("switch_root: creating proc, dev and sys")
-
-	if err := specialFS(); err != nil {
-		return fmt.Errorf("switch_root: failed to create special files %v", err)
+	if err := os.Chdir("/"); err != nil {
+		return err
 	}
-
-	log.Printf("switch_root: executing init")
-	if err := execCommand(init); err != nil {
-		return fmt.Errorf("switch_root: exec failed %v", err)
+	if *console != "" {
+		c, err := os.OpenFile(*console, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		for fd := 0; fd < 3; fd++ {
+			if err := unix.Dup3(int(c.Fd()), fd, 0); err != nil {
+				return err
+			}
+		}
+		c.Close()
 	}
-
-	return nil
-
+	return syscall.Exec(init, append([]string{init}, args...), os.Environ())
 }
 
 func main() {
+	flag.Usage = usage
 	flag.Parse()
-
-	if len(flag.Args()) == 0 {
-		fmt.Println(usage())
-		os.Exit(0)
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
 	}
-
-	if *help {
-		fmt.Println(usage())
-		os.Exit(0)
-	}
-
-	if *version {
-		fmt.Println("Version XX")
-		os.Exit(0)
-	}
-
-	newRoot := flag.Args()[0]
-	init := flag.Args()[1]
-
-	if err := switchRoot(newRoot, init); err != nil {
-		log.Fatalf("switch_root failed %v\n", err)
+	if err := switchRoot(flag.Arg(0), flag.Arg(1), flag.Args()[2:]); err != nil {
+		log.Fatalf("switch_root: %v", err)
 	}
 }
@@ -0,0 +1,57 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteContents(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "switch_root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	root := filepath.Join(tmp, "root")
+	for _, d := range []string{"bin", "etc/ssl/certs", "empty"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"init", "bin/rush", "etc/ssl/certs/ca.pem"} {
+		if err := ioutil.WriteFile(filepath.Join(root, f), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/no/such/file", filepath.Join(root, "bin/sh")); err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := device(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A file on another device is left alone.
+	deleteContents(root, dev+1)
+	if _, err := os.Stat(filepath.Join(root, "init")); err != nil {
+		t.Errorf("deleteContents on another device: got %v, want init left", err)
+	}
+
+	deleteContents(root, dev)
+	if _, err := os.Lstat(root); !os.IsNotExist(err) {
+		t.Errorf("deleteContents(%q): got %v, want it gone", root, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// Tests are not PID 1.
+	if err := check("/", "/bin/sh"); err == nil {
+		t.Errorf("check as PID %d: got nil, want an error", os.Getpid())
+	}
+}
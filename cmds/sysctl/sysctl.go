@@ -0,0 +1,143 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Read and write kernel parameters.
+//
+// Synopsis:
+//     sysctl [-n] [-e] NAME...
+//     sysctl [-q] [-e] -w NAME=VALUE...
+//     sysctl -a [-n]
+//     sysctl [-q] -p [FILE]...
+//
+// Description:
+//     Kernel parameters are the files in /proc/sys, named with dots rather
+//     than slashes, e.g. kernel.hostname. An argument with = in it is
+//     written even without -w. FILEs of -p have a NAME = VALUE on each
+//     line; a - before NAME means errors setting it are ignored.
+//
+// Options:
+//     -a: print all parameters
+//     -w: write the parameters
+//     -p: load the parameters in FILEs, by default /etc/sysctl.conf
+//     -n: print only values
+//     -e: ignore unknown parameters
+//     -q: do not print the values written
+//
+// Example:
+//     sysctl -w net.ipv4.ip_forward=1
+//     sysctl -p /etc/sysctl.d/99-serial.conf
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/sysctl"
+)
+
+var (
+	all        = flag.Bool("a", false, "print all parameters")
+	write      = flag.Bool("w", false, "write the parameters")
+	load       = flag.Bool("p", false, "load the parameters in files")
+	valuesOnly = flag.Bool("n", false, "print only values")
+	ignore     = flag.Bool("e", false, "ignore unknown parameters")
+	quiet      = flag.Bool("q", false, "do not print the values written")
+)
+
+// show prints a parameter. Names are printed with dots, however they
+// were given.
+func show(name, value string) {
+	name = sysctl.Name(sysctl.Path(name))
+	if *valuesOnly {
+		fmt.Println(value)
+	} else {
+		fmt.Printf("%s = %s\n", name, value)
+	}
+}
+
+func get(name string) error {
+	v, err := sysctl.Get(name)
+	if os.IsNotExist(err) && *ignore {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	show(name, v)
+	return nil
+}
+
+func set(arg string) error {
+	i := strings.IndexByte(arg, '=')
+	if i < 0 {
+		return fmt.Errorf("%q must be NAME=VALUE", arg)
+	}
+	name, value := arg[:i], arg[i+1:]
+	err := sysctl.Set(name, value)
+	if os.IsNotExist(err) && *ignore {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !*quiet {
+		show(name, value)
+	}
+	return nil
+}
+
+func loadFile(file string) error {
+	settings, err := sysctl.Load(file)
+	if !*quiet {
+		for _, st := range settings {
+			show(st.Name, st.Value)
+		}
+	}
+	return err
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	status := 0
+	check := func(err error) {
+		if err != nil {
+			log.Printf("sysctl: %v", err)
+			status = 1
+		}
+	}
+
+	switch {
+	case *all:
+		names, err := sysctl.All()
+		check(err)
+		for _, n := range names {
+			// Some parameters can not be read, even by root.
+			if v, err := sysctl.Get(n); err == nil {
+				show(n, v)
+			}
+		}
+	case *load:
+		if len(args) == 0 {
+			args = []string{"/etc/sysctl.conf"}
+		}
+		for _, f := range args {
+			check(loadFile(f))
+		}
+	case len(args) == 0:
+		log.Fatalf("Usage: sysctl [-neq] [-w] NAME[=VALUE]... | -a | -p [FILE]...")
+	default:
+		for _, a := range args {
+			if *write || strings.Contains(a, "=") {
+				check(set(a))
+			} else {
+				check(get(a))
+			}
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// What inotify wakes tail for: a file being written or truncated, or
+// replaced, for -F, by one created or moved into its directory.
+const (
+	fileEvents = unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+	dirEvents  = unix.IN_CREATE | unix.IN_MOVED_TO
+)
+
+// followable drops the files which can not be followed: pipes and the
+// like, which were read to their end, and, without -F, those which could
+// not be opened. It returns whether any are left.
+func (t *tailer) followable() bool {
+	var l []*file
+	for _, f := range t.files {
+		if f.f == nil {
+			if t.byName && f.name != "-" {
+				l = append(l, f)
+			}
+			continue
+		}
+		if fi, err := f.f.Stat(); err == nil && fi.Mode().IsRegular() {
+			l = append(l, f)
+		}
+	}
+	t.files = l
+	return len(l) > 0
+}
+
+// copy prints what was added to f since it was last read.
+func (t *tailer) copy(f *file) {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, f.f); err != nil {
+		t.errorf("%v: %v", f, err)
+	}
+	t.write(f, b.Bytes())
+}
+
+// reopen checks, for -F, whether f is still the file of its name, and if
+// not, prints what is left of it, and opens the new one.
+func (t *tailer) reopen(f *file) {
+	fi, err := os.Stat(f.name)
+	switch {
+	case err != nil:
+		if f.f != nil {
+			t.errorf("%v has become inaccessible: %v", f, err)
+			t.copy(f)
+			f.f.Close()
+			f.f = nil
+		}
+		return
+	case f.f == nil:
+		t.errorf("%v has appeared;  following new file", f)
+	default:
+		if old, err := f.f.Stat(); err != nil || os.SameFile(fi, old) {
+			return
+		}
+		t.errorf("%v has been replaced;  following new file", f)
+		t.copy(f)
+		f.f.Close()
+		f.f = nil
+	}
+	if f.f, err = os.Open(f.name); err != nil {
+		t.errorf("%v", err)
+		f.f = nil
+	}
+}
+
+// check prints what was added to f since it was last looked at.
+func (t *tailer) check(f *file) {
+	if t.byName && f.name != "-" {
+		t.reopen(f)
+	}
+	if f.f == nil {
+		return
+	}
+	fi, err := f.f.Stat()
+	if err != nil {
+		return
+	}
+	if off, err := f.f.Seek(0, io.SeekCurrent); err == nil && fi.Size() < off {
+		t.errorf("%v: file truncated", f)
+		f.f.Seek(0, io.SeekStart)
+	}
+	t.copy(f)
+}
+
+// watch adds inotify watches of the files to fd, again each time, as with
+// -F the file of a name may be a new one.
+func (t *tailer) watch(fd int) {
+	for _, f := range t.files {
+		if f.name == "-" {
+			continue
+		}
+		unix.InotifyAddWatch(fd, f.name, fileEvents)
+		if t.byName {
+			unix.InotifyAddWatch(fd, filepath.Dir(f.name), dirEvents)
+		}
+	}
+}
+
+// inotify returns an inotify fd, and a channel which gets a value when it
+// says one of the files it watches may have changed; or -1 and nil if
+// there is no inotify.
+func inotify() (int, <-chan struct{}) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return -1, nil
+	}
+	c := make(chan struct{}, 1)
+	go func() {
+		b := make([]byte, 4096)
+		for {
+			if _, err := unix.Read(fd, b); err != nil && err != unix.EINTR {
+				close(c)
+				return
+			}
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return fd, c
+}
+
+// follow prints what is added to the files, forever.
+func (t *tailer) follow(interval time.Duration) {
+	fd, changed := inotify()
+	for {
+		if fd >= 0 {
+			t.watch(fd)
+		}
+		select {
+		case _, ok := <-changed:
+			if !ok {
+				changed = nil
+			}
+		case <-time.After(interval):
+		}
+		for _, f := range t.files {
+			t.check(f)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Print the last lines of files, and what is added to them.
+//
+// Synopsis:
+//     tail [-n [+]N | -c [+]N] [-f|-F] [-s SECONDS] [-q|-v] [FILE...]
+//
+// Description:
+//     tail prints the last 10 lines of each FILE, or of stdin if there is
+//     none or FILE is -. With more than one FILE, each is under a header of
+//     its name, ==> FILE <==. tail -N is tail -n N.
+//
+//     With -f, tail then prints what is added to the files, as it is,
+//     until it is killed. It is woken by inotify, and also looks every -s
+//     SECONDS, which is all it does if there is no inotify. A file which
+//     gets shorter was truncated, and is printed from its start.
+//
+//     -F follows the files by name, not by what was opened: when a log
+//     is rotated, the new file of the name is printed from its start, and
+//     a file which is not there yet is waited for.
+//
+// Options:
+//     -n: print the last N lines; with +N, from line N on
+//     -c: print the last N bytes; with +N, from byte N on
+//     -f: print what is added to the files
+//     -F: as -f, following the files by name
+//     -s: with -f or -F, seconds between looking at the files
+//     -q: never print headers
+//     -v: always print headers
+//
+// Example:
+//     $ tail -n 2 /proc/mounts
+//     $ tail -F /var/log/messages
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	lines    = flag.String("n", "10", "print the last `N` lines; with +N, from line N on")
+	bytesN   = flag.String("c", "", "print the last `N` bytes; with +N, from byte N on")
+	follow   = flag.Bool("f", false, "print what is added to the files")
+	followF  = flag.Bool("F", false, "print what is added to the files, following them by name")
+	interval = flag.Float64("s", 1, "with -f or -F, `seconds` between looking at the files")
+	quiet    = flag.Bool("q", false, "never print headers")
+	verbose  = flag.Bool("v", false, "always print headers")
+)
+
+// parseCount parses the N of -n and -c: the last N, or from N on, with +.
+func parseCount(s string) (n int64, fromStart bool, err error) {
+	if strings.HasPrefix(s, "+") {
+		s, fromStart = s[1:], true
+	} else {
+		s = strings.TrimPrefix(s, "-")
+	}
+	if n, err = strconv.ParseInt(s, 10, 64); err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid number: %q", s)
+	}
+	return n, fromStart, nil
+}
+
+// lastOffset returns the offset of the last n lines, or if inBytes is set,
+// bytes, in the size bytes of r. A newline at the end does not start a
+// line.
+func lastOffset(r io.ReaderAt, size, n int64, inBytes bool) (int64, error) {
+	if inBytes {
+		if n >= size {
+			return 0, nil
+		}
+		return size - n, nil
+	}
+	if n == 0 {
+		return size, nil
+	}
+	buf := make([]byte, 8192)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		b := buf[:end-start]
+		if _, err := r.ReadAt(b, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(b) - 1; i >= 0; i-- {
+			if b[i] == '\n' && start+int64(i) != size-1 {
+				if n--; n == 0 {
+					return start + int64(i) + 1, nil
+				}
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// skip reads past the first n-1 lines, or if inBytes is set, bytes, of r,
+// so that what is left starts at the nth, counting from 1.
+func skip(r io.Reader, n int64, inBytes bool) (io.Reader, error) {
+	if n <= 1 {
+		return r, nil
+	}
+	if inBytes {
+		_, err := io.CopyN(ioutil.Discard, r, n-1)
+		if err == io.EOF {
+			err = nil
+		}
+		return r, err
+	}
+	// One byte at a time, so as not to read past where the file is to be
+	// followed from.
+	b := make([]byte, 1)
+	for n > 1 {
+		if _, err := r.Read(b); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if b[0] == '\n' {
+			n--
+		}
+	}
+	return r, nil
+}
+
+// A file is a file tail prints.
+type file struct {
+	// name is as it was given: - for stdin.
+	name string
+	// f is nil if the file could not be opened, or with -F, is gone.
+	f *os.File
+}
+
+func (f *file) String() string {
+	if f.name == "-" {
+		return "standard input"
+	}
+	return f.name
+}
+
+// A tailer prints the files.
+type tailer struct {
+	w, errw io.Writer
+	files   []*file
+	// byName is set for -F.
+	byName  bool
+	headers bool
+	// last is the file printed last, which need not be printed under its
+	// header again.
+	last    *file
+	printed bool
+}
+
+func (t *tailer) errorf(f string, v ...interface{}) {
+	fmt.Fprintf(t.errw, "tail: "+f+"\n", v...)
+}
+
+// header prints the header of f, if there are headers.
+func (t *tailer) header(f *file) {
+	if t.headers {
+		if t.printed {
+			fmt.Fprintln(t.w)
+		}
+		fmt.Fprintf(t.w, "==> %v <==\n", f)
+	}
+	t.last, t.printed = f, true
+}
+
+// write prints b, of f, under the header of f if f was not the last file
+// printed.
+func (t *tailer) write(f *file, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if t.last != f {
+		t.header(f)
+	}
+	t.w.Write(b)
+}
+
+// start prints the last n lines, or bytes, of f, or from the nth on.
+func (t *tailer) start(f *file, n int64, fromStart, inBytes bool) error {
+	t.header(f)
+	if fromStart {
+		r, err := skip(f.f, n, inBytes)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(t.w, r)
+		return err
+	}
+	if fi, err := f.f.Stat(); err == nil && fi.Mode().IsRegular() {
+		off, err := lastOffset(f.f, fi.Size(), n, inBytes)
+		if err != nil {
+			return err
+		}
+		if _, err := f.f.Seek(off, io.SeekStart); err != nil {
+			return err
+		}
+		_, err = io.Copy(t.w, f.f)
+		return err
+	}
+	b, err := ioutil.ReadAll(f.f)
+	if err != nil {
+		return err
+	}
+	off, _ := lastOffset(bytes.NewReader(b), int64(len(b)), n, inBytes)
+	_, err = t.w.Write(b[off:])
+	return err
+}
+
+// open opens the files, and prints the start of each.
+func (t *tailer) open(names []string, n int64, fromStart, inBytes bool) error {
+	var failed error
+	for _, name := range names {
+		f := &file{name: name}
+		t.files = append(t.files, f)
+		if name == "-" {
+			f.f = os.Stdin
+		} else {
+			var err error
+			if f.f, err = os.Open(name); err != nil {
+				t.errorf("%v", err)
+				failed = err
+				continue
+			}
+		}
+		if err := t.start(f, n, fromStart, inBytes); err != nil {
+			t.errorf("%v: %v", f, err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+// args turns the old -N form into -n N.
+func args(a []string) []string {
+	if len(a) > 1 && len(a[1]) > 1 && a[1][0] == '-' && a[1][1] >= '0' && a[1][1] <= '9' {
+		return append([]string{a[0], "-n", a[1][1:]}, a[2:]...)
+	}
+	return a
+}
+
+func main() {
+	os.Args = args(os.Args)
+	flag.Parse()
+	count, inBytes := *lines, false
+	if *bytesN != "" {
+		count, inBytes = *bytesN, true
+	}
+	n, fromStart, err := parseCount(count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tail: %v\n", err)
+		os.Exit(1)
+	}
+	names := flag.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+
+	t := &tailer{
+		w:       os.Stdout,
+		errw:    os.Stderr,
+		byName:  *followF,
+		headers: !*quiet && (*verbose || len(names) > 1),
+	}
+	err = t.open(names, n, fromStart, inBytes)
+	if !*follow && !*followF {
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if !t.followable() {
+		os.Exit(1)
+	}
+	t.follow(time.Duration(*interval * float64(time.Second)))
+}
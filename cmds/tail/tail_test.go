@@ -0,0 +1,231 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCount(t *testing.T) {
+	for _, tt := range []struct {
+		s         string
+		n         int64
+		fromStart bool
+		err       bool
+	}{
+		{"10", 10, false, false},
+		{"-3", 3, false, false},
+		{"+3", 3, true, false},
+		{"0", 0, false, false},
+		{"x", 0, false, true},
+		{"--3", 0, false, true},
+	} {
+		n, fromStart, err := parseCount(tt.s)
+		if (err != nil) != tt.err || n != tt.n || fromStart != tt.fromStart {
+			t.Errorf("parseCount(%q): got %d, %v, %v, want %d, %v, error %v", tt.s, n, fromStart, err, tt.n, tt.fromStart, tt.err)
+		}
+	}
+}
+
+func TestLastOffset(t *testing.T) {
+	// Enough lines that they are read in more than one piece.
+	var long []string
+	for i := 0; i < 10000; i++ {
+		long = append(long, fmt.Sprint(i))
+	}
+	for _, tt := range []struct {
+		in      string
+		n       int64
+		inBytes bool
+		want    string
+	}{
+		{"1\n2\n3\n", 2, false, "2\n3\n"},
+		{"1\n2\n3", 2, false, "2\n3"},
+		{"1\n2\n3\n", 0, false, ""},
+		{"1\n2\n3\n", 5, false, "1\n2\n3\n"},
+		{"\n\n\n", 2, false, "\n\n"},
+		{"", 2, false, ""},
+		{"1\n2\n3\n", 3, true, "\n3\n"},
+		{"1\n2\n3\n", 30, true, "1\n2\n3\n"},
+		{strings.Join(long, "\n") + "\n", 3, false, "9997\n9998\n9999\n"},
+		{strings.Join(long, "\n") + "\n", 9999, false, strings.Join(long[1:], "\n") + "\n"},
+	} {
+		r := strings.NewReader(tt.in)
+		off, err := lastOffset(r, int64(len(tt.in)), tt.n, tt.inBytes)
+		if err != nil {
+			t.Errorf("lastOffset(%.20q, %d, %v): %v", tt.in, tt.n, tt.inBytes, err)
+			continue
+		}
+		if got := tt.in[off:]; got != tt.want {
+			t.Errorf("lastOffset(%.20q, %d, %v): got %.20q, want %.20q", tt.in, tt.n, tt.inBytes, got, tt.want)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		n       int64
+		inBytes bool
+		want    string
+	}{
+		{"1\n2\n3\n", 2, false, "2\n3\n"},
+		{"1\n2\n3\n", 1, false, "1\n2\n3\n"},
+		{"1\n2\n3\n", 0, false, "1\n2\n3\n"},
+		{"1\n2\n3\n", 9, false, ""},
+		{"1\n2\n3\n", 3, true, "2\n3\n"},
+		{"1\n2\n3\n", 9, true, ""},
+	} {
+		r, err := skip(strings.NewReader(tt.in), tt.n, tt.inBytes)
+		if err != nil {
+			t.Errorf("skip(%q, %d, %v): %v", tt.in, tt.n, tt.inBytes, err)
+			continue
+		}
+		if got, _ := ioutil.ReadAll(r); string(got) != tt.want {
+			t.Errorf("skip(%q, %d, %v): got %q, want %q", tt.in, tt.n, tt.inBytes, got, tt.want)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	if got := args([]string{"tail", "-5", "f"}); strings.Join(got, " ") != "tail -n 5 f" {
+		t.Errorf("args: got %q, want [tail -n 5 f]", got)
+	}
+}
+
+// tempDir makes a directory with the files, and returns it, and a function
+// to remove it.
+func tempDir(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, c := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, n), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func appendFile(t *testing.T, name, s string) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, rm := tempDir(t, map[string]string{"a": "a1\na2\na3\n", "b": "b1\nb2\n"})
+	defer rm()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+
+	var out, errs bytes.Buffer
+	tl := &tailer{w: &out, errw: &errs, headers: true}
+	if err := tl.open([]string{a, filepath.Join(dir, "c"), b}, 1, false, false); err == nil {
+		t.Errorf("open with a missing file: got nil, want an error")
+	}
+	want := fmt.Sprintf("==> %s <==\na3\n\n==> %s <==\nb2\n", a, b)
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	if !strings.Contains(errs.String(), "c: no such file") {
+		t.Errorf("got errors %q, want one for c", errs.String())
+	}
+}
+
+func TestFollow(t *testing.T) {
+	dir, rm := tempDir(t, map[string]string{"a": "a1\n", "b": "b1\n"})
+	defer rm()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+
+	var out, errs bytes.Buffer
+	tl := &tailer{w: &out, errw: &errs, headers: true}
+	if err := tl.open([]string{a, b}, 10, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if !tl.followable() {
+		t.Fatal("followable: got false, want true")
+	}
+	check := func(step, want string) {
+		out.Reset()
+		for _, f := range tl.files {
+			tl.check(f)
+		}
+		if out.String() != want {
+			t.Errorf("%s: got %q, want %q", step, out.String(), want)
+		}
+	}
+
+	check("nothing added", "")
+	appendFile(t, b, "b2\n")
+	check("added to b", "b2\n")
+	appendFile(t, a, "a2\n")
+	appendFile(t, b, "b3\n")
+	check("added to both", fmt.Sprintf("\n==> %s <==\na2\n\n==> %s <==\nb3\n", a, b))
+
+	if err := ioutil.WriteFile(b, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("b truncated", "new\n")
+	if !strings.Contains(errs.String(), "file truncated") {
+		t.Errorf("got errors %q, want b truncated", errs.String())
+	}
+}
+
+func TestFollowByName(t *testing.T) {
+	dir, rm := tempDir(t, map[string]string{"log": "1\n"})
+	defer rm()
+	log, missing := filepath.Join(dir, "log"), filepath.Join(dir, "missing")
+
+	var out, errs bytes.Buffer
+	tl := &tailer{w: &out, errw: &errs, byName: true}
+	tl.open([]string{log, missing}, 10, false, false)
+	if !tl.followable() || len(tl.files) != 2 {
+		t.Fatalf("followable: got %d files, want 2", len(tl.files))
+	}
+	check := func(step, want, msg string) {
+		out.Reset()
+		errs.Reset()
+		for _, f := range tl.files {
+			tl.check(f)
+		}
+		if out.String() != want {
+			t.Errorf("%s: got %q, want %q", step, out.String(), want)
+		}
+		if !strings.Contains(errs.String(), msg) {
+			t.Errorf("%s: got errors %q, want %q", step, errs.String(), msg)
+		}
+	}
+
+	// The log is rotated: what was added to the old one is printed, then
+	// the new one.
+	appendFile(t, log, "2\n")
+	if err := os.Rename(log, log+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(log, []byte("3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("rotated", "2\n3\n", "has been replaced")
+
+	if err := ioutil.WriteFile(missing, []byte("here\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("appeared", "here\n", "has appeared")
+
+	os.Remove(missing)
+	check("removed", "", "has become inaccessible")
+}
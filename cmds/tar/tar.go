@@ -0,0 +1,485 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Create, extract or list tar archives.
+//
+// Synopsis:
+//     tar -c [-v] [-z] [-C DIR] [-f ARCHIVE] FILES...
+//     tar -x [-v] [-C DIR] [-f ARCHIVE]
+//     tar -t [-v] [-f ARCHIVE]
+//     tar {c|x|t}[vzf] [ARCHIVE] ...
+//
+// Description:
+//     The archive is ARCHIVE or, if it is - or not given, stdin or stdout.
+//     Archives compressed with gzip, bzip2 or xz are decompressed when they
+//     are extracted or listed; xz needs an xz command in PATH. Directories
+//     are added with all they hold. Permissions, owners (when run as root),
+//     modification times, symbolic and hard links, and devices are kept.
+//     Files which would be extracted outside of DIR are refused.
+//
+//     Flags may be combined, with or without the -, as in tar -czf
+//     ARCHIVE FILES... or tar xvf ARCHIVE.
+//
+// Options:
+//     -c: create an archive of FILES
+//     -x: extract an archive
+//     -t: list the files in an archive
+//     -f: the archive
+//     -C: change to DIR first
+//     -z: compress the created archive with gzip
+//     -v: print the names of files as they are added or extracted, or a
+//         long listing with -t
+//
+// Example:
+//     tar czf /tmp/etc.tgz -C / etc
+//     tar -x -f /tmp/etc.tgz -C /mnt
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	create  = flag.Bool("c", false, "create an archive")
+	extract = flag.Bool("x", false, "extract an archive")
+	list    = flag.Bool("t", false, "list an archive")
+	file    = flag.String("f", "-", "the archive; - is stdin or stdout")
+	dir     = flag.String("C", "", "change to `directory` first")
+	gz      = flag.Bool("z", false, "compress with gzip")
+	verbose = flag.Bool("v", false, "verbose")
+)
+
+// Magic numbers of the compressed formats.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0}
+)
+
+func usage() {
+	log.Fatalf("Usage: tar -c|-x|-t [-vz] [-C dir] [-f archive] [files...]")
+}
+
+// oldStyle turns arguments such as "czf out.tgz" into "-c -z -f out.tgz".
+func oldStyle(args []string) []string {
+	if len(args) == 0 || args[0] == "" || strings.HasPrefix(args[0], "-") ||
+		strings.Trim(args[0], "cxtvzjJf") != "" {
+		return args
+	}
+	var n []string
+	rest := args[1:]
+	for _, c := range args[0] {
+		switch c {
+		// Compression is found out when reading.
+		case 'j', 'J':
+		case 'f':
+			n = append(n, "-f")
+			if len(rest) > 0 {
+				n, rest = append(n, rest[0]), rest[1:]
+			}
+		default:
+			n = append(n, "-"+string(c))
+		}
+	}
+	return append(n, rest...)
+}
+
+// args splits combined flags, such as -czf, into flags as flag takes
+// them, -c -z -f. -f and -C take the rest of the flag, or the next
+// argument.
+func args(a []string) []string {
+	out := []string{a[0]}
+	for i := 1; i < len(a); i++ {
+		s := a[i]
+		if s == "--" || len(s) < 2 || s[0] != '-' {
+			return append(out, a[i:]...)
+		}
+		for j := 1; j < len(s); j++ {
+			// Compression is found out when reading.
+			if s[j] == 'j' || s[j] == 'J' {
+				continue
+			}
+			out = append(out, "-"+s[j:j+1])
+			if s[j] != 'f' && s[j] != 'C' {
+				continue
+			}
+			if j+1 < len(s) {
+				out = append(out, s[j+1:])
+			} else if i+1 < len(a) {
+				i++
+				out = append(out, a[i])
+			}
+			break
+		}
+	}
+	return out
+}
+
+// decompress returns the contents of r, decompressed if they are.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(xzMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(br), nil
+	case bytes.HasPrefix(magic, xzMagic):
+		c := exec.Command("xz", "-dc")
+		c.Stdin, c.Stderr = br, os.Stderr
+		out, err := c.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Start(); err != nil {
+			return nil, fmt.Errorf("xz compressed archive: %v", err)
+		}
+		return out, nil
+	}
+	return br, nil
+}
+
+type devInode struct {
+	dev, ino uint64
+}
+
+// add adds the files below each of names to w.
+func add(w *tar.Writer, names []string) error {
+	links := map[devInode]string{}
+	for _, name := range names {
+		err := filepath.Walk(name, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			var link string
+			if fi.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			h, err := tar.FileInfoHeader(fi, link)
+			if err != nil {
+				return err
+			}
+			h.Name = filepath.ToSlash(path)
+			if fi.IsDir() {
+				h.Name += "/"
+			}
+			if st, ok := fi.Sys().(*syscall.Stat_t); ok && fi.Mode().IsRegular() && st.Nlink > 1 {
+				di := devInode{uint64(st.Dev), uint64(st.Ino)}
+				if l, ok := links[di]; ok {
+					h.Typeflag, h.Linkname, h.Size = tar.TypeLink, l, 0
+				} else {
+					links[di] = h.Name
+				}
+			}
+			if *verbose {
+				fmt.Fprintln(os.Stderr, h.Name)
+			}
+			if err := w.WriteHeader(h); err != nil {
+				return err
+			}
+			if h.Typeflag != tar.TypeReg {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// safePath returns the path name is extracted to, or an error if it
+// would be outside of the current directory.
+func safePath(name string) (string, error) {
+	p := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("%s: outside of the extraction directory", name)
+	}
+	return p, nil
+}
+
+// extractFile creates the file h describes, with the contents in r.
+func extractFile(h *tar.Header, r io.Reader) error {
+	p, err := safePath(h.Name)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(p); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	switch h.Typeflag {
+	case tar.TypeDir:
+		if err := os.Mkdir(p, 0700); err != nil && !os.IsExist(err) {
+			return err
+		}
+	case tar.TypeReg, tar.TypeRegA:
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, r)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		os.Remove(p)
+		if err := os.Symlink(h.Linkname, p); err != nil {
+			return err
+		}
+		if os.Geteuid() == 0 {
+			return os.Lchown(p, h.Uid, h.Gid)
+		}
+	case tar.TypeLink:
+		l, err := safePath(h.Linkname)
+		if err != nil {
+			return err
+		}
+		os.Remove(p)
+		return os.Link(l, p)
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		m := uint32(h.Mode & 0777)
+		switch h.Typeflag {
+		case tar.TypeChar:
+			m |= syscall.S_IFCHR
+		case tar.TypeBlock:
+			m |= syscall.S_IFBLK
+		default:
+			m |= syscall.S_IFIFO
+		}
+		os.Remove(p)
+		if err := unix.Mknod(p, m, int(unix.Mkdev(uint32(h.Devmajor), uint32(h.Devminor)))); err != nil {
+			return err
+		}
+	default:
+		log.Printf("%s: skipping file of type %q", h.Name, h.Typeflag)
+	}
+	return nil
+}
+
+// setAttrs gives the file extracted from h its owner, mode and time.
+func setAttrs(h *tar.Header) error {
+	p, err := safePath(h.Name)
+	if err != nil {
+		return err
+	}
+	switch h.Typeflag {
+	case tar.TypeDir, tar.TypeReg, tar.TypeRegA, tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+	default:
+		return nil
+	}
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(p, h.Uid, h.Gid); err != nil {
+			return err
+		}
+	}
+	// Chmod, since the umask applies when a file is created.
+	mode := h.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	if err := os.Chmod(p, mode); err != nil {
+		return err
+	}
+	return os.Chtimes(p, h.ModTime, h.ModTime)
+}
+
+// walk calls f for each file in the archive r.
+func walk(r io.Reader, f func(*tar.Header, io.Reader) error) error {
+	r, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := f(h, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractAll extracts the archive r. Directories get their attributes
+// last, so that what is extracted into them does not change their time
+// and read-only ones can be filled.
+func extractAll(r io.Reader) error {
+	var dirs []*tar.Header
+	err := walk(r, func(h *tar.Header, r io.Reader) error {
+		if *verbose {
+			fmt.Fprintln(os.Stderr, h.Name)
+		}
+		if err := extractFile(h, r); err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeDir {
+			dirs = append(dirs, h)
+			return nil
+		}
+		return setAttrs(h)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := setAttrs(dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modeString returns the mode of the file h describes as ls prints it,
+// such as lrwxrwxrwx, with h for a hard link.
+func modeString(h *tar.Header) string {
+	b := []byte("----------")
+	switch h.Typeflag {
+	case tar.TypeDir:
+		b[0] = 'd'
+	case tar.TypeSymlink:
+		b[0] = 'l'
+	case tar.TypeLink:
+		b[0] = 'h'
+	case tar.TypeChar:
+		b[0] = 'c'
+	case tar.TypeBlock:
+		b[0] = 'b'
+	case tar.TypeFifo:
+		b[0] = 'p'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if h.Mode&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	// The set-ID and sticky bits replace the x, and are capitals
+	// without it.
+	for _, s := range []struct {
+		bit int64
+		i   int
+		c   byte
+	}{
+		{04000, 3, 's'},
+		{02000, 6, 's'},
+		{01000, 9, 't'},
+	} {
+		if h.Mode&s.bit == 0 {
+			continue
+		}
+		if b[s.i] == 'x' {
+			b[s.i] = s.c
+		} else {
+			b[s.i] = s.c - 'a' + 'A'
+		}
+	}
+	return string(b)
+}
+
+func listFile(h *tar.Header, _ io.Reader) error {
+	if !*verbose {
+		fmt.Println(h.Name)
+		return nil
+	}
+	name := h.Name
+	switch h.Typeflag {
+	case tar.TypeSymlink:
+		name += " -> " + h.Linkname
+	case tar.TypeLink:
+		name += " link to " + h.Linkname
+	}
+	fmt.Printf("%s %d/%d %8d %s %s\n", modeString(h), h.Uid, h.Gid, h.Size,
+		h.ModTime.Format(time.Stamp), name)
+	return nil
+}
+
+func main() {
+	os.Args = args(append([]string{os.Args[0]}, oldStyle(os.Args[1:])...))
+	flag.Parse()
+
+	n := 0
+	for _, b := range []bool{*create, *extract, *list} {
+		if b {
+			n++
+		}
+	}
+	if n != 1 || *create && flag.NArg() == 0 || !*create && flag.NArg() != 0 {
+		usage()
+	}
+
+	// The archive is named relative to where we start, not -C.
+	var err error
+	f := os.Stdin
+	switch {
+	case *file == "-" && *create:
+		f = os.Stdout
+	case *create:
+		f, err = os.Create(*file)
+	case *file != "-":
+		f, err = os.Open(*file)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *dir != "" {
+		if err := os.Chdir(*dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch {
+	case *create:
+		var w io.Writer = f
+		closers := []io.Closer{f}
+		if *gz {
+			z := gzip.NewWriter(f)
+			w, closers = z, append([]io.Closer{z}, closers...)
+		}
+		tw := tar.NewWriter(w)
+		err = add(tw, flag.Args())
+		for _, c := range append([]io.Closer{tw}, closers...) {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	case *extract:
+		err = extractAll(f)
+	case *list:
+		err = walk(f, listFile)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
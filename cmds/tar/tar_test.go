@@ -0,0 +1,181 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestOldStyle(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"czf", "a.tgz", "etc"}, []string{"-c", "-z", "-f", "a.tgz", "etc"}},
+		{[]string{"xjvf", "a.tbz"}, []string{"-x", "-v", "-f", "a.tbz"}},
+		{[]string{"-x", "-f", "a.tar"}, []string{"-x", "-f", "a.tar"}},
+		{[]string{"etc"}, []string{"etc"}},
+	} {
+		if got := oldStyle(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("oldStyle(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"tar", "-czf", "a.tgz", "etc"}, []string{"tar", "-c", "-z", "-f", "a.tgz", "etc"}},
+		{[]string{"tar", "-xjvf", "a.tbz", "-C", "/mnt"}, []string{"tar", "-x", "-v", "-f", "a.tbz", "-C", "/mnt"}},
+		{[]string{"tar", "-tvfa.tar"}, []string{"tar", "-t", "-v", "-f", "a.tar"}},
+		{[]string{"tar", "-xC/mnt", "-f", "-"}, []string{"tar", "-x", "-C", "/mnt", "-f", "-"}},
+		{[]string{"tar", "-c", "-f", "a.tar", "-v"}, []string{"tar", "-c", "-f", "a.tar", "-v"}},
+		{[]string{"tar", "-c", "etc", "-v"}, []string{"tar", "-c", "etc", "-v"}},
+	} {
+		if got := args(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("args(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestModeString(t *testing.T) {
+	for _, tt := range []struct {
+		h    tar.Header
+		want string
+	}{
+		{tar.Header{Typeflag: tar.TypeReg, Mode: 0644}, "-rw-r--r--"},
+		{tar.Header{Typeflag: tar.TypeDir, Mode: 0755}, "drwxr-xr-x"},
+		{tar.Header{Typeflag: tar.TypeSymlink, Mode: 0777}, "lrwxrwxrwx"},
+		{tar.Header{Typeflag: tar.TypeLink, Mode: 0600}, "hrw-------"},
+		{tar.Header{Typeflag: tar.TypeChar, Mode: 0666}, "crw-rw-rw-"},
+		{tar.Header{Typeflag: tar.TypeBlock, Mode: 0660}, "brw-rw----"},
+		{tar.Header{Typeflag: tar.TypeFifo, Mode: 0600}, "prw-------"},
+		{tar.Header{Typeflag: tar.TypeReg, Mode: 04755}, "-rwsr-xr-x"},
+		{tar.Header{Typeflag: tar.TypeDir, Mode: 01770}, "drwxrwx--T"},
+	} {
+		if got := modeString(&tt.h); got != tt.want {
+			t.Errorf("modeString(%c %o): got %q, want %q", tt.h.Typeflag, tt.h.Mode, got, tt.want)
+		}
+	}
+}
+
+func TestCreateExtract(t *testing.T) {
+	tmpDir, execPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	src := filepath.Join(tmpDir, "src")
+	for _, d := range []string{"src/a/b", "dst", "dstz", "dsty"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "a/b/f"), []byte("hello"), 0751); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(src, "a/b/f"), 0751); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("b/f", filepath.Join(src, "a/l")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(src, "a/b/f"), filepath.Join(src, "a/h")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		create  []string
+		archive string
+		dst     string
+	}{
+		{[]string{"-c", "-f", "../x.tar", "-C", src, "a"}, "x.tar", "dst"},
+		{[]string{"czf", "../x.tgz", "-C", src, "a"}, "x.tgz", "dstz"},
+		{[]string{"-czf", "../y.tgz", "-C", src, "a"}, "y.tgz", "dsty"},
+	} {
+		c := exec.Command(execPath, tt.create...)
+		c.Dir = filepath.Join(tmpDir, "dst")
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("tar %v: %v: %s", tt.create, err, out)
+		}
+		archive := filepath.Join(tmpDir, tt.archive)
+		dst := filepath.Join(tmpDir, tt.dst)
+		if out, err := exec.Command(execPath, "-xf", archive, "-C", dst).CombinedOutput(); err != nil {
+			t.Fatalf("tar -xf %v: %v: %s", archive, err, out)
+		}
+
+		fi, err := os.Stat(filepath.Join(dst, "a/b/f"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode() != 0751 {
+			t.Errorf("%s: a/b/f mode: got %v, want %v", archive, fi.Mode(), os.FileMode(0751))
+		}
+		if l, err := os.Readlink(filepath.Join(dst, "a/l")); err != nil || l != "b/f" {
+			t.Errorf("%s: a/l: got %q, %v, want %q", archive, l, err, "b/f")
+		}
+		hi, err := os.Stat(filepath.Join(dst, "a/h"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(fi, hi) {
+			t.Errorf("%s: a/h is not a hard link to a/b/f", archive)
+		}
+		if b, err := ioutil.ReadFile(filepath.Join(dst, "a/h")); err != nil || string(b) != "hello" {
+			t.Errorf("%s: a/h: got %q, %v, want %q", archive, b, err, "hello")
+		}
+
+		out, err := exec.Command(execPath, "-t", "-f", archive).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := strings.Fields(string(out)), []string{"a/", "a/b/", "a/b/f", "a/h", "a/l"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: tar -t: got %q, want %q", archive, got, want)
+		}
+
+		out, err = exec.Command(execPath, "-tvf", archive).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), "lrwxrwxrwx") || !strings.Contains(string(out), " a/l -> b/f\n") {
+			t.Errorf("%s: tar -tvf: got %q, want a/l listed as lrwxrwxrwx ... a/l -> b/f", archive, out)
+		}
+	}
+}
+
+func TestOutside(t *testing.T) {
+	tmpDir, execPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	archive := filepath.Join(tmpDir, "evil.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	if err := w.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	dst := filepath.Join(tmpDir, "dst")
+	if err := os.Mkdir(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(execPath, "-x", "-f", archive, "-C", dst).CombinedOutput(); err == nil {
+		t.Errorf("tar -x %s: got nil, want error; output %s", archive, out)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "evil")); err == nil {
+		t.Errorf("../evil was extracted")
+	}
+}
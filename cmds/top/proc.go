@@ -0,0 +1,205 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const proc = "/proc"
+
+// A process as read from /proc/PID/stat.
+type process struct {
+	pid   int
+	comm  string
+	state string
+	ticks uint64 // user and system time
+	rss   int64  // KiB
+	uid   uint32
+	cpu   float64 // percentage of one CPU since the last sample
+}
+
+// parseStat parses the contents of a /proc/PID/stat file.
+func parseStat(s string) (*process, error) {
+	// The command name may have spaces and parentheses in it, so it is
+	// whatever is between the first ( and the last ).
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("bad stat %q", s)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(s[:open]))
+	if err != nil {
+		return nil, fmt.Errorf("bad stat %q: %v", s, err)
+	}
+	// f[0] is the state, the third field of stat.
+	f := strings.Fields(s[end+1:])
+	if len(f) < 22 {
+		return nil, fmt.Errorf("bad stat %q: too few fields", s)
+	}
+	p := &process{pid: pid, comm: s[open+1 : end], state: f[0]}
+	utime, err := strconv.ParseUint(f[11], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	stime, err := strconv.ParseUint(f[12], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	p.ticks = utime + stime
+	rss, err := strconv.ParseInt(f[21], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	p.rss = rss * int64(os.Getpagesize()) / 1024
+	return p, nil
+}
+
+// readProcesses reads all processes in /proc.
+func readProcesses() (map[int]*process, error) {
+	dirs, err := filepath.Glob(filepath.Join(proc, "[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	procs := map[int]*process{}
+	for _, d := range dirs {
+		// Processes come and go all the time, so errors are ignored.
+		b, err := ioutil.ReadFile(filepath.Join(d, "stat"))
+		if err != nil {
+			continue
+		}
+		p, err := parseStat(string(b))
+		if err != nil {
+			continue
+		}
+		if fi, err := os.Stat(d); err == nil {
+			p.uid = fi.Sys().(*syscall.Stat_t).Uid
+		}
+		procs[p.pid] = p
+	}
+	return procs, nil
+}
+
+// cpuTimes are the times in the cpu line of /proc/stat: user, nice,
+// system, idle, iowait and so on.
+type cpuTimes struct {
+	t    []uint64
+	ncpu int
+}
+
+func (c cpuTimes) total() uint64 {
+	var n uint64
+	for _, t := range c.t {
+		n += t
+	}
+	return n
+}
+
+// parseCPU parses the contents of /proc/stat.
+func parseCPU(s string) (cpuTimes, error) {
+	var c cpuTimes
+	for _, l := range strings.Split(s, "\n") {
+		f := strings.Fields(l)
+		switch {
+		case len(f) == 0 || !strings.HasPrefix(f[0], "cpu"):
+		case f[0] == "cpu":
+			for _, v := range f[1:] {
+				n, err := strconv.ParseUint(v, 10, 64)
+				if err != nil {
+					return c, fmt.Errorf("bad cpu line %q: %v", l, err)
+				}
+				c.t = append(c.t, n)
+			}
+		default:
+			c.ncpu++
+		}
+	}
+	if len(c.t) < 4 {
+		return c, fmt.Errorf("no cpu line in %q", s)
+	}
+	if c.ncpu == 0 {
+		c.ncpu = 1
+	}
+	return c, nil
+}
+
+func readCPU() (cpuTimes, error) {
+	b, err := ioutil.ReadFile(filepath.Join(proc, "stat"))
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	return parseCPU(string(b))
+}
+
+// setCPU sets the CPU use of the processes in now, compared to before,
+// in which the total CPU time was dtotal less. 100% is all of one CPU.
+func setCPU(now, before map[int]*process, dtotal uint64, ncpu int) {
+	if dtotal == 0 {
+		return
+	}
+	for pid, p := range now {
+		var last uint64
+		if b, ok := before[pid]; ok && b.ticks <= p.ticks {
+			last = b.ticks
+		}
+		p.cpu = 100 * float64(p.ticks-last) * float64(ncpu) / float64(dtotal)
+	}
+}
+
+// meminfo returns the total and available memory, in KiB.
+func meminfo() (int64, int64) {
+	b, err := ioutil.ReadFile(filepath.Join(proc, "meminfo"))
+	if err != nil {
+		return 0, 0
+	}
+	var total, avail int64
+	for _, l := range strings.Split(string(b), "\n") {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "MemTotal:":
+			total, _ = strconv.ParseInt(f[1], 10, 64)
+		case "MemAvailable:":
+			avail, _ = strconv.ParseInt(f[1], 10, 64)
+		}
+	}
+	return total, avail
+}
+
+// readLoad returns the load averages.
+func readLoad() (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(proc, "loadavg"))
+	if err != nil {
+		return "?", err
+	}
+	f := strings.Fields(string(b))
+	if len(f) < 3 {
+		return "?", fmt.Errorf("bad loadavg %q", b)
+	}
+	return strings.Join(f[:3], ", "), nil
+}
+
+var users = map[uint32]string{}
+
+// userName returns the name of the user with uid, or uid if it has none.
+func userName(uid uint32) string {
+	if n, ok := users[uid]; ok {
+		return n
+	}
+	n := strconv.Itoa(int(uid))
+	if u, err := user.LookupId(n); err == nil {
+		n = u.Username
+	}
+	users[uid] = n
+	return n
+}
@@ -0,0 +1,261 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Display the busiest processes.
+//
+// Synopsis:
+//     top [-b] [-d SECONDS] [-n COUNT] [-o cpu|rss]
+//
+// Description:
+//     top prints the uptime, load, CPU and memory use of the system and the
+//     processes using the most CPU time or memory, every few seconds. The
+//     CPU use of a process is its share of the time since the last update,
+//     where 100% is all of one CPU. On a terminal, the screen is redrawn in
+//     place and as many processes as fit are shown; the size is read again
+//     when the terminal is resized.
+//
+// Options:
+//     -b: batch mode: print every update after the last, e.g. to a log
+//     -d: seconds between updates
+//     -n: stop after COUNT updates; 0 means never
+//     -o: sort by cpu or rss
+//
+// Keys:
+//     P: sort by CPU use
+//     M: sort by resident memory
+//     space: update now
+//     q: quit
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+const userHZ = 100
+
+var (
+	batch  = flag.Bool("b", false, "batch mode")
+	delay  = flag.Float64("d", 3, "seconds between updates")
+	count  = flag.Int("n", 0, "number of updates; 0 means no limit")
+	sortBy = flag.String("o", "cpu", "sort by cpu or rss")
+)
+
+// A screen is where top prints, and how big it is.
+type screen struct {
+	w          io.Writer
+	rows, cols int
+	// tty is set if the screen is redrawn in place.
+	tty bool
+}
+
+// size reads the size of the terminal.
+func (s *screen) size() {
+	if ws, err := termios.GetWinSize(os.Stdout.Fd()); err == nil && ws.Row > 0 && ws.Col > 0 {
+		s.rows, s.cols = int(ws.Row), int(ws.Col)
+	}
+}
+
+// sortProcesses sorts the processes by key, busiest first.
+func sortProcesses(procs map[int]*process, key string) []*process {
+	var l []*process
+	for _, p := range procs {
+		l = append(l, p)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		a, b := l[i], l[j]
+		switch {
+		case key == "rss" && a.rss != b.rss:
+			return a.rss > b.rss
+		case key != "rss" && a.cpu != b.cpu:
+			return a.cpu > b.cpu
+		}
+		return a.pid < b.pid
+	})
+	return l
+}
+
+// cpuTime formats ticks as minutes, seconds and hundredths.
+func cpuTime(ticks uint64) string {
+	cs := ticks * 100 / userHZ
+	return fmt.Sprintf("%d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// header returns the summary lines at the top of the screen.
+func header(procs map[int]*process, now, before cpuTimes) []string {
+	var h []string
+	up := "?"
+	var si unix.Sysinfo_t
+	if err := unix.Sysinfo(&si); err == nil {
+		d := time.Duration(si.Uptime) * time.Second
+		up = fmt.Sprintf("%d days, %d:%02d", d/(24*time.Hour), d/time.Hour%24, d/time.Minute%60)
+	}
+	load, _ := readLoad()
+	h = append(h, fmt.Sprintf("top - %s up %s, load average: %s", time.Now().Format("15:04:05"), up, load))
+
+	states := map[string]int{}
+	for _, p := range procs {
+		states[p.state]++
+	}
+	h = append(h, fmt.Sprintf("Tasks: %d total, %d running, %d sleeping, %d stopped, %d zombie",
+		len(procs), states["R"], states["S"]+states["D"]+states["I"], states["T"]+states["t"], states["Z"]))
+
+	// user, nice, system, idle, iowait
+	var pct [5]float64
+	if dtotal := float64(now.total() - before.total()); dtotal > 0 && len(before.t) == len(now.t) {
+		for i := range pct {
+			pct[i] = 100 * float64(now.t[i]-before.t[i]) / dtotal
+		}
+	}
+	h = append(h, fmt.Sprintf("%%Cpu(s): %5.1f us, %5.1f sy, %5.1f ni, %5.1f id, %5.1f wa", pct[0], pct[2], pct[1], pct[3], pct[4]))
+
+	total, avail := meminfo()
+	h = append(h, fmt.Sprintf("KiB Mem: %d total, %d used, %d avail", total, total-avail, avail))
+	return h
+}
+
+// draw prints one update.
+func draw(s *screen, procs map[int]*process, now, before cpuTimes, key string) {
+	total, _ := meminfo()
+	var lines []string
+	lines = append(lines, header(procs, now, before)...)
+	lines = append(lines, "", fmt.Sprintf("%7s %-8s %s %5s %5s %8s %9s %s", "PID", "USER", "S", "%CPU", "%MEM", "RSS", "TIME+", "COMMAND"))
+	for _, p := range sortProcesses(procs, key) {
+		if s.tty && len(lines) >= s.rows-1 {
+			break
+		}
+		mem := 0.0
+		if total > 0 {
+			mem = 100 * float64(p.rss) / float64(total)
+		}
+		lines = append(lines, fmt.Sprintf("%7d %-8.8s %s %5.1f %5.1f %8d %9s %s",
+			p.pid, userName(p.uid), p.state, p.cpu, mem, p.rss, cpuTime(p.ticks), p.comm))
+	}
+
+	b := bufio.NewWriter(s.w)
+	if s.tty {
+		// Home, then clear to the end of the screen.
+		b.WriteString("\033[H\033[J")
+	}
+	for _, l := range lines {
+		if s.tty && len(l) > s.cols {
+			l = l[:s.cols]
+		}
+		b.WriteString(l)
+		// The terminal does not turn \n into \r\n in raw mode.
+		if s.tty {
+			b.WriteString("\r")
+		}
+		b.WriteString("\n")
+	}
+	if !s.tty {
+		b.WriteString("\n")
+	}
+	b.Flush()
+}
+
+// keys sends the keys typed on stdin to c.
+func keys(c chan<- byte) {
+	b := make([]byte, 1)
+	for {
+		if _, err := os.Stdin.Read(b); err != nil {
+			close(c)
+			return
+		}
+		c <- b[0]
+	}
+}
+
+func top() error {
+	s := &screen{w: os.Stdout, rows: 24, cols: 80}
+	key := *sortBy
+	if key != "cpu" && key != "rss" {
+		return fmt.Errorf("can not sort by %q: use cpu or rss", key)
+	}
+
+	keyc := make(chan byte)
+	winch := make(chan os.Signal, 1)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	if !*batch {
+		if t, err := termios.GetTermios(os.Stdin.Fd()); err == nil {
+			raw := *t
+			raw.Lflag &^= unix.ICANON | unix.ECHO
+			raw.Oflag &^= unix.OPOST
+			raw.Cc[unix.VMIN], raw.Cc[unix.VTIME] = 1, 0
+			if err := termios.SetTermios(os.Stdin.Fd(), &raw); err != nil {
+				return err
+			}
+			defer termios.SetTermios(os.Stdin.Fd(), t)
+			s.tty = true
+			s.size()
+			signal.Notify(winch, syscall.SIGWINCH)
+			go keys(keyc)
+		}
+	}
+
+	before, err := readCPU()
+	if err != nil {
+		return err
+	}
+	last, err := readProcesses()
+	if err != nil {
+		return err
+	}
+	// The first update shows the use over a short time, rather than
+	// since boot.
+	wait := 500 * time.Millisecond
+	for n := 0; *count == 0 || n < *count; n++ {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-winch:
+			s.size()
+		case k, ok := <-keyc:
+			switch {
+			case !ok, k == 'q':
+				return nil
+			case k == 'P':
+				key = "cpu"
+			case k == 'M':
+				key = "rss"
+			}
+		case <-quit:
+			return nil
+		}
+		t.Stop()
+		wait = time.Duration(*delay * float64(time.Second))
+
+		now, err := readCPU()
+		if err != nil {
+			return err
+		}
+		procs, err := readProcesses()
+		if err != nil {
+			return err
+		}
+		setCPU(procs, last, now.total()-before.total(), now.ncpu)
+		draw(s, procs, now, before, key)
+		before, last = now, procs
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := top(); err != nil {
+		log.Fatalf("top: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseStat(t *testing.T) {
+	s := "42 (a (b) c) R 1 42 42 0 -1 4194304 82 0 0 0 150 50 0 0 20 0 1 0 354621 2703360 310 18446744073709551615 0\n"
+	p, err := parseStat(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &process{pid: 42, comm: "a (b) c", state: "R", ticks: 200, rss: 310 * int64(os.Getpagesize()) / 1024}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("parseStat: got %+v, want %+v", p, want)
+	}
+	for _, s := range []string{"", "42 (x) R 1 2", "x (x) R 1 42 42 0 -1 4194304 82 0 0 0 150 50 0 0 20 0 1 0 354621 2703360 310 0"} {
+		if _, err := parseStat(s); err == nil {
+			t.Errorf("parseStat(%q): got nil, want error", s)
+		}
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	s := "cpu  10 1 5 100 4 0 0 0 0 0\ncpu0 5 1 2 50 2 0 0 0 0 0\ncpu1 5 0 3 50 2 0 0 0 0 0\nintr 1234\n"
+	c, err := parseCPU(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ncpu != 2 || c.total() != 120 {
+		t.Errorf("parseCPU: got %d CPUs, total %d, want 2, 120", c.ncpu, c.total())
+	}
+	if _, err := parseCPU("intr 1234\n"); err == nil {
+		t.Errorf("parseCPU without a cpu line: got nil, want error")
+	}
+}
+
+func TestCPU(t *testing.T) {
+	before := map[int]*process{
+		1: {pid: 1, ticks: 100, rss: 10},
+		2: {pid: 2, ticks: 100, rss: 30},
+	}
+	now := map[int]*process{
+		1: {pid: 1, ticks: 150, rss: 10},
+		2: {pid: 2, ticks: 110, rss: 30},
+		3: {pid: 3, ticks: 100, rss: 20},
+	}
+	// 2 CPUs, 200 ticks in all, so 100 ticks is all of one CPU.
+	setCPU(now, before, 200, 2)
+	for pid, want := range map[int]float64{1: 50, 2: 10, 3: 100} {
+		if now[pid].cpu != want {
+			t.Errorf("CPU use of %d: got %v, want %v", pid, now[pid].cpu, want)
+		}
+	}
+	for key, want := range map[string][]int{"cpu": {3, 1, 2}, "rss": {2, 3, 1}} {
+		var got []int
+		for _, p := range sortProcesses(now, key) {
+			got = append(got, p.pid)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sorting by %v: got %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestCPUTime(t *testing.T) {
+	for ticks, want := range map[uint64]string{0: "0:00.00", 150: "0:01.50", 6123: "1:01.23", 600000: "100:00.00"} {
+		if got := cpuTime(ticks); got != want {
+			t.Errorf("cpuTime(%d): got %q, want %q", ticks, got, want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Translate or delete characters.
+//
+// Synopsis:
+//     tr [-c] SET1 SET2
+//     tr [-c] -d SET1
+//     tr [-c] -s SET1 [SET2]
+//     tr [-c] -d -s SET1 SET2
+//
+// Description:
+//     tr copies stdin to stdout, turning each byte of SET1 into the byte
+//     at the same place in SET2; if SET2 is shorter, its last byte is
+//     repeated. With -d, the bytes of SET1 are deleted instead.
+//
+//     With -s, a run of one byte of SET1, or with SET2, of SET2, after
+//     translating or deleting, is squeezed into one of it.
+//
+//     A SET is bytes, and: A-Z, the bytes from A to Z; escapes, \n, \t,
+//     \r, \a, \b, \f, \v, \\ and \NNN, in octal; and the classes
+//     [:alnum:], [:alpha:], [:blank:], [:cntrl:], [:digit:], [:graph:],
+//     [:lower:], [:print:], [:punct:], [:space:], [:upper:] and
+//     [:xdigit:], in the order of their bytes, so [:lower:] and [:upper:]
+//     translate to each other.
+//
+// Options:
+//     -c: use the bytes which are not in SET1, in order, as SET1
+//     -d: delete the bytes of SET1
+//     -s: squeeze runs of the bytes of the last SET into one
+//
+// Example:
+//     $ echo hello | tr a-z A-Z
+//     HELLO
+//     $ tr -d '\r' < dos.txt > unix.txt
+//     $ echo 'a  b   c' | tr -s ' '
+//     a b c
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	complement = flag.Bool("c", false, "use the bytes not in SET1")
+	del        = flag.Bool("d", false, "delete the bytes of SET1")
+	squeeze    = flag.Bool("s", false, "squeeze runs of the bytes of the last set")
+)
+
+// classes are the [:NAME:] classes of a set.
+var classes = map[string]func(r rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl":  unicode.IsControl,
+	"digit":  unicode.IsDigit,
+	"graph":  func(r rune) bool { return unicode.IsGraphic(r) && r != ' ' },
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"xdigit": func(r rune) bool { return strings.ContainsRune("0123456789ABCDEFabcdef", r) },
+}
+
+// escapes are the escapes of a set other than \NNN.
+var escapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v', '\\': '\\',
+}
+
+// unescape returns the byte at the start of s, which may be an escape,
+// and how long it is in s.
+func unescape(s string) (byte, int) {
+	if s[0] != '\\' || len(s) == 1 {
+		return s[0], 1
+	}
+	if c, ok := escapes[s[1]]; ok {
+		return c, 2
+	}
+	n := 1
+	for n < 4 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+		n++
+	}
+	if n == 1 {
+		// \ and any other byte is that byte.
+		return s[1], 2
+	}
+	v, _ := strconv.ParseUint(s[1:n], 8, 16)
+	if v > 0xff {
+		// The last digit is not part of it.
+		n--
+		v >>= 3
+	}
+	return byte(v), n
+}
+
+// parseSet returns the bytes of the set s, in order.
+func parseSet(s string) ([]byte, error) {
+	var b []byte
+	for len(s) > 0 {
+		if strings.HasPrefix(s, "[:") {
+			end := strings.Index(s, ":]")
+			if end < 0 {
+				return nil, fmt.Errorf("%q: unterminated class", s)
+			}
+			is, ok := classes[s[2:end]]
+			if !ok {
+				return nil, fmt.Errorf("%q: no such class", s[:end+2])
+			}
+			for c := 0; c < 256; c++ {
+				if c < 0x80 && is(rune(c)) {
+					b = append(b, byte(c))
+				}
+			}
+			s = s[end+2:]
+			continue
+		}
+		lo, n := unescape(s)
+		s = s[n:]
+		if len(s) > 1 && s[0] == '-' {
+			hi, n := unescape(s[1:])
+			if hi < lo {
+				return nil, fmt.Errorf("range %c-%c is backwards", lo, hi)
+			}
+			s = s[1+n:]
+			for c := int(lo); c <= int(hi); c++ {
+				b = append(b, byte(c))
+			}
+			continue
+		}
+		b = append(b, lo)
+	}
+	return b, nil
+}
+
+// A translator is what tr does to each byte.
+type translator struct {
+	// to is what each byte turns into.
+	to [256]byte
+	// deleted and squeezed are the bytes which are deleted, and those
+	// whose runs are squeezed.
+	deleted, squeezed [256]bool
+}
+
+// newTranslator makes a translator of the sets, as the flags say.
+func newTranslator(sets []string, complement, del, squeeze bool) (*translator, error) {
+	want := 2
+	if del != squeeze {
+		want = 1
+	}
+	if len(sets) < want || len(sets) > 2 || del && !squeeze && len(sets) != 1 {
+		return nil, fmt.Errorf("wrong number of sets")
+	}
+	t := &translator{}
+	for i := range t.to {
+		t.to[i] = byte(i)
+	}
+	set1, err := parseSet(sets[0])
+	if err != nil {
+		return nil, err
+	}
+	if complement {
+		var in [256]bool
+		for _, c := range set1 {
+			in[c] = true
+		}
+		set1 = nil
+		for c := 0; c < 256; c++ {
+			if !in[c] {
+				set1 = append(set1, byte(c))
+			}
+		}
+	}
+	var set2 []byte
+	if len(sets) == 2 {
+		if set2, err = parseSet(sets[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	last := set1
+	switch {
+	case del:
+		for _, c := range set1 {
+			t.deleted[c] = true
+		}
+		last = set2
+	case len(set2) > 0:
+		for i, c := range set1 {
+			if i < len(set2) {
+				t.to[c] = set2[i]
+			} else {
+				t.to[c] = set2[len(set2)-1]
+			}
+		}
+		last = set2
+	case len(sets) == 2:
+		return nil, fmt.Errorf("SET2 is empty")
+	}
+	if squeeze {
+		for _, c := range last {
+			t.squeezed[c] = true
+		}
+	}
+	return t, nil
+}
+
+// run translates r to w.
+func (t *translator) run(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	prev := -1
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if t.deleted[c] {
+			continue
+		}
+		c = t.to[c]
+		if t.squeezed[c] && int(c) == prev {
+			continue
+		}
+		bw.WriteByte(c)
+		prev = int(c)
+	}
+}
+
+func main() {
+	flag.Parse()
+	t, err := newTranslator(flag.Args(), *complement, *del, *squeeze)
+	if err != nil {
+		log.Fatalf("tr: %v", err)
+	}
+	if err := t.run(os.Stdout, os.Stdin); err != nil {
+		log.Fatalf("tr: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	for _, tt := range []struct {
+		set, want string
+	}{
+		{"abc", "abc"},
+		{"a-e", "abcde"},
+		{"a-c0-2", "abc012"},
+		{"-a", "-a"},
+		{"a-", "a-"},
+		{`\n\t\\`, "\n\t\\"},
+		{`\101\0`, "A\x00"},
+		{`\4000`, " 00"},
+		{`\-`, "-"},
+		{"[:digit:]", "0123456789"},
+		{"[:xdigit:]", "0123456789ABCDEFabcdef"},
+		{"x[:blank:]y", "x\t y"},
+	} {
+		got, err := parseSet(tt.set)
+		if err != nil {
+			t.Errorf("parseSet(%q): %v", tt.set, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("parseSet(%q): got %q, want %q", tt.set, got, tt.want)
+		}
+	}
+	for _, set := range []string{"z-a", "[:nope:]", "[:alpha"} {
+		if got, err := parseSet(set); err == nil {
+			t.Errorf("parseSet(%q): got %q, want an error", set, got)
+		}
+	}
+}
+
+func TestTr(t *testing.T) {
+	for _, tt := range []struct {
+		sets                []string
+		complement, del, sq bool
+		in, want            string
+	}{
+		{[]string{"a-z", "A-Z"}, false, false, false, "hello, world\n", "HELLO, WORLD\n"},
+		{[]string{"[:lower:]", "[:upper:]"}, false, false, false, "Go 1.9\n", "GO 1.9\n"},
+		{[]string{"abc", "x"}, false, false, false, "aabbcd", "xxxxxd"},
+		{[]string{"abc", "xyz"}, false, false, false, "cab", "zxy"},
+		{[]string{"\r"}, false, true, false, "a\r\nb\r\n", "a\nb\n"},
+		{[]string{"0-9"}, true, true, false, "a1b2\n", "12"},
+		{[]string{" "}, false, false, true, "a  b   c\n", "a b c\n"},
+		{[]string{"a-z", "\n"}, true, false, true, "one, two  three", "one\ntwo\nthree"},
+		{[]string{"a", "b"}, false, true, true, "aabbcc", "bcc"},
+		{[]string{"a-z", "A-Z"}, false, false, true, "aabbAc", "ABAC"},
+	} {
+		tr, err := newTranslator(tt.sets, tt.complement, tt.del, tt.sq)
+		if err != nil {
+			t.Errorf("%q -c=%v -d=%v -s=%v: %v", tt.sets, tt.complement, tt.del, tt.sq, err)
+			continue
+		}
+		var b bytes.Buffer
+		if err := tr.run(&b, strings.NewReader(tt.in)); err != nil {
+			t.Errorf("%q: %v", tt.sets, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("%q -c=%v -d=%v -s=%v of %q: got %q, want %q", tt.sets, tt.complement, tt.del, tt.sq, tt.in, b.String(), tt.want)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	for _, tt := range []struct {
+		sets    []string
+		del, sq bool
+	}{
+		{[]string{"a"}, false, false},
+		{[]string{"a", "b", "c"}, false, false},
+		{[]string{"a", "b"}, true, false},
+		{[]string{"a"}, true, true},
+		{[]string{"a", ""}, false, false},
+	} {
+		if _, err := newTranslator(tt.sets, false, tt.del, tt.sq); err == nil {
+			t.Errorf("%q -d=%v -s=%v: got nil, want an error", tt.sets, tt.del, tt.sq)
+		}
+	}
+}
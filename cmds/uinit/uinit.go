@@ -0,0 +1,238 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Uinit sets up the machine as the kernel command line says.
+//
+// Synopsis:
+//     uinit
+//
+// Description:
+//     init runs uinit, unless uroot.uinit= names another program, once it
+//     has set up the machine, and then rush. uinit reads these directives
+//     from the kernel command line, so that what an image does can be
+//     changed without building it again:
+//         uroot.modules=MODULE[,MODULE...]: load the kernel modules, by
+//             name from /lib/modules/RELEASE, or by path, with those they
+//             depend on
+//         ip=dhcp, ip=DEVICE:dhcp: ask for addresses with DHCP on DEVICE,
+//             or on each e* interface
+//         ip=CLIENT:SERVER:GATEWAY:NETMASK:HOSTNAME:DEVICE:AUTOCONF: as
+//             for a kernel with CONFIG_IP_PNP, give DEVICE the address
+//             CLIENT, with the default route through GATEWAY, and set the
+//             host name; AUTOCONF dhcp, on or any asks DHCP instead
+//         uroot.exec=COMMANDS: run COMMANDS, quoted if there are spaces in
+//             them, with rush -c
+//     The directives are done in that order. init itself knows
+//     uroot.uinit, uroot.initflags, uroot.nohwrng, uroot.nobgbuild,
+//     uroot.nortc, uroot.rtc, uroot.ntp and uroot.getty.
+//
+// Example:
+//     uroot.modules=e1000 ip=eth0:dhcp uroot.exec="wget http://10.0.2.2/boot.sh && rush boot.sh"
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+	"github.com/u-root/u-root/pkg/kmodule"
+	"github.com/vishvananda/netlink"
+)
+
+// dhcpWait is how long to wait for DHCP to give an address before going on.
+const dhcpWait = 30 * time.Second
+
+// loadModules loads the comma-separated modules.
+func loadModules(list string) {
+	// Only images with modules.dep can load modules by name.
+	var mods *kmodule.Modules
+	if d, err := kmodule.ModuleDir(); err == nil {
+		mods, _ = kmodule.Open(d)
+	}
+	for _, m := range strings.Split(list, ",") {
+		var err error
+		switch {
+		case m == "":
+			continue
+		case strings.ContainsRune(m, '/'):
+			err = kmodule.Load(m, "", 0)
+		case mods == nil:
+			err = fmt.Errorf("no modules.dep to find it in")
+		default:
+			err = mods.Probe(m, "")
+		}
+		// One module failing to load should not stop the others.
+		if err != nil {
+			log.Printf("uinit: module %v: %v", m, err)
+		}
+	}
+}
+
+// ipConfig is the network configuration of an ip= directive.
+type ipConfig struct {
+	client   net.IP
+	gateway  net.IP
+	mask     net.IPMask
+	hostname string
+	device   string
+	dhcp     bool
+}
+
+// parseIP parses the value of ip=, in the form of the kernel's, as
+// described in Documentation/filesystems/nfs/nfsroot.txt.
+func parseIP(v string) (*ipConfig, error) {
+	switch v {
+	case "dhcp", "on", "any", "both":
+		return &ipConfig{dhcp: true}, nil
+	case "off", "none":
+		return nil, nil
+	}
+	f := strings.Split(v, ":")
+	// ip=DEVICE:dhcp is short for ip=:::::DEVICE:dhcp.
+	if len(f) == 2 && (f[1] == "dhcp" || f[1] == "on") {
+		return &ipConfig{device: f[0], dhcp: true}, nil
+	}
+	for len(f) < 7 {
+		f = append(f, "")
+	}
+	c := &ipConfig{hostname: f[4], device: f[5]}
+	switch f[6] {
+	case "dhcp", "on", "any", "both":
+		c.dhcp = true
+		return c, nil
+	case "", "off", "none", "static":
+	default:
+		return nil, fmt.Errorf("ip=%v: can not do autoconf %q", v, f[6])
+	}
+	if c.client = net.ParseIP(f[0]).To4(); c.client == nil {
+		return nil, fmt.Errorf("ip=%v: bad address %q", v, f[0])
+	}
+	if f[2] != "" {
+		if c.gateway = net.ParseIP(f[2]).To4(); c.gateway == nil {
+			return nil, fmt.Errorf("ip=%v: bad gateway %q", v, f[2])
+		}
+	}
+	c.mask = c.client.DefaultMask()
+	if f[3] != "" {
+		m := net.ParseIP(f[3]).To4()
+		if m == nil {
+			return nil, fmt.Errorf("ip=%v: bad netmask %q", v, f[3])
+		}
+		c.mask = net.IPMask(m)
+	}
+	if c.device == "" {
+		return nil, fmt.Errorf("ip=%v: no device", v)
+	}
+	return c, nil
+}
+
+// configure sets up the network as c says.
+func (c *ipConfig) configure() error {
+	if c.dhcp {
+		return dhcp(c.device)
+	}
+	l, err := netlink.LinkByName(c.device)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(l); err != nil {
+		return err
+	}
+	addr := &netlink.Addr{IPNet: &net.IPNet{IP: c.client, Mask: c.mask}}
+	if err := netlink.AddrReplace(l, addr); err != nil {
+		return err
+	}
+	if c.gateway != nil {
+		r := &netlink.Route{LinkIndex: l.Attrs().Index, Gw: c.gateway}
+		if err := netlink.RouteReplace(r); err != nil {
+			return err
+		}
+	}
+	if c.hostname != "" {
+		return syscall.Sethostname([]byte(c.hostname))
+	}
+	return nil
+}
+
+// dhcp starts dhclient, which keeps renewing its leases, on device, or
+// the e* interfaces if it is "", and waits a while for an address.
+func dhcp(device string) error {
+	var args []string
+	if device != "" {
+		args = append(args, "^"+device+"$")
+	}
+	cmd := exec.Command("dhclient", args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	for start := time.Now(); time.Since(start) < dhcpWait; time.Sleep(time.Second) {
+		if hasAddr(device) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no address from DHCP after %v", dhcpWait)
+}
+
+// hasAddr returns whether device, or any interface if it is "", has a
+// global unicast address.
+func hasAddr(device string) bool {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return false
+	}
+	for _, l := range links {
+		if device != "" && l.Attrs().Name != device {
+			continue
+		}
+		addrs, err := netlink.AddrList(l, netlink.FAMILY_ALL)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if a.IP.IsGlobalUnicast() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// run runs commands with rush.
+func run(commands string) error {
+	cmd := exec.Command("rush", "-c", commands)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func main() {
+	args, err := cmdline.Read()
+	if err != nil {
+		log.Fatalf("uinit: %v", err)
+	}
+	if v, ok := args["uroot.modules"]; ok {
+		loadModules(v)
+	}
+	if v, ok := args["ip"]; ok {
+		c, err := parseIP(v)
+		if err != nil {
+			log.Printf("uinit: %v", err)
+		} else if c != nil {
+			if err := c.configure(); err != nil {
+				log.Printf("uinit: ip=%v: %v", v, err)
+			}
+		}
+	}
+	if v, ok := args["uroot.exec"]; ok {
+		if err := run(v); err != nil {
+			log.Printf("uinit: uroot.exec=%q: %v", v, err)
+		}
+	}
+}
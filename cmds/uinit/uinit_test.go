@@ -0,0 +1,49 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseIP(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want *ipConfig
+		err  bool
+	}{
+		{in: "dhcp", want: &ipConfig{dhcp: true}},
+		{in: "off"},
+		{in: "eth0:dhcp", want: &ipConfig{device: "eth0", dhcp: true}},
+		{in: ":::::eth1:any", want: &ipConfig{device: "eth1", dhcp: true}},
+		{in: "10.0.2.15::10.0.2.2:255.255.255.0:box:eth0:off", want: &ipConfig{
+			client:   net.IPv4(10, 0, 2, 15).To4(),
+			gateway:  net.IPv4(10, 0, 2, 2).To4(),
+			mask:     net.IPv4Mask(255, 255, 255, 0),
+			hostname: "box",
+			device:   "eth0",
+		}},
+		{in: "192.168.1.2:::::eth0", want: &ipConfig{
+			client: net.IPv4(192, 168, 1, 2).To4(),
+			mask:   net.IPv4Mask(255, 255, 255, 0),
+			device: "eth0",
+		}},
+		{in: "10.0.2.15", err: true},
+		{in: "nonsense:::::eth0", err: true},
+		{in: "10.0.2.15::x::::", err: true},
+		{in: "10.0.2.15:::::eth0:bootp", err: true},
+	} {
+		got, err := parseIP(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseIP(%q): got err %v, want err %v", tt.in, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIP(%q): got %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
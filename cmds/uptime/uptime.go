@@ -0,0 +1,138 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Uptime prints how long the system has been up, and its load.
+//
+// Synopsis:
+//     uptime [-p|-s]
+//
+// Description:
+//     uptime prints the time, how long the system has been up, and the
+//     load averages over the last 1, 5 and 15 minutes: the number of
+//     processes running or waiting to, from /proc/uptime and /proc/loadavg.
+//
+// Options:
+//     -p: only print how long it has been up, in words
+//     -s: only print when it came up, as YYYY-MM-DD HH:MM:SS
+//
+// Example:
+//     $ uptime
+//      10:04:05 up 3 days,  4:05,  load average: 0.23, 0.14, 0.24
+//     $ uptime -p
+//     up 3 days, 4 hours, 5 minutes
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	pretty = flag.Bool("p", false, "print how long it has been up in words")
+	since  = flag.Bool("s", false, "print when it came up")
+
+	procUptime  = "/proc/uptime"
+	procLoadavg = "/proc/loadavg"
+)
+
+// readUptime returns how long the system has been up.
+func readUptime() (time.Duration, error) {
+	b, err := ioutil.ReadFile(procUptime)
+	if err != nil {
+		return 0, err
+	}
+	f := strings.Fields(string(b))
+	if len(f) == 0 {
+		return 0, fmt.Errorf("%v is empty", procUptime)
+	}
+	s, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(s * float64(time.Second)), nil
+}
+
+// readLoad returns the load averages over 1, 5 and 15 minutes.
+func readLoad() (string, error) {
+	b, err := ioutil.ReadFile(procLoadavg)
+	if err != nil {
+		return "", err
+	}
+	f := strings.Fields(string(b))
+	if len(f) < 3 {
+		return "", fmt.Errorf("bad loadavg %q", b)
+	}
+	return strings.Join(f[:3], ", "), nil
+}
+
+// plural returns n and the word for the unit, in the plural if n is not 1.
+func plural(n time.Duration, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// short formats up as uptime does: days, then hours:minutes, or minutes.
+func short(up time.Duration) string {
+	days, hours, mins := up/(24*time.Hour), up/time.Hour%24, up/time.Minute%60
+	s := ""
+	if days > 0 {
+		s = plural(days, "day") + ", "
+	}
+	if hours > 0 {
+		return s + fmt.Sprintf("%2d:%02d", hours, mins)
+	}
+	return s + fmt.Sprintf("%d min", mins)
+}
+
+// long formats up in words, as uptime -p does.
+func long(up time.Duration) string {
+	var parts []string
+	for _, u := range []struct {
+		d    time.Duration
+		name string
+	}{{7 * 24 * time.Hour, "week"}, {24 * time.Hour, "day"}, {time.Hour, "hour"}, {time.Minute, "minute"}} {
+		if n := up / u.d; n > 0 {
+			parts = append(parts, plural(n, u.name))
+			up -= n * u.d
+		}
+	}
+	if parts == nil {
+		parts = []string{plural(0, "minute")}
+	}
+	return "up " + strings.Join(parts, ", ")
+}
+
+func uptime(now time.Time) (string, error) {
+	up, err := readUptime()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case *pretty:
+		return long(up), nil
+	case *since:
+		return now.Add(-up).Format("2006-01-02 15:04:05"), nil
+	}
+	load, err := readLoad()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(" %s up %s,  load average: %s", now.Format("15:04:05"), short(up), load), nil
+}
+
+func main() {
+	flag.Parse()
+	s, err := uptime(time.Now())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(s)
+}
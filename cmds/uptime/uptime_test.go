@@ -0,0 +1,64 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormats(t *testing.T) {
+	for _, tt := range []struct {
+		up          time.Duration
+		short, long string
+	}{
+		{30 * time.Second, "0 min", "up 0 minutes"},
+		{61 * time.Second, "1 min", "up 1 minute"},
+		{4*time.Hour + 5*time.Minute, " 4:05", "up 4 hours, 5 minutes"},
+		{24*time.Hour + 3*time.Minute, "1 day, 3 min", "up 1 day, 3 minutes"},
+		{10*24*time.Hour + 14*time.Hour, "10 days, 14:00", "up 1 week, 3 days, 14 hours"},
+	} {
+		if got := short(tt.up); got != tt.short {
+			t.Errorf("short(%v): got %q, want %q", tt.up, got, tt.short)
+		}
+		if got := long(tt.up); got != tt.long {
+			t.Errorf("long(%v): got %q, want %q", tt.up, got, tt.long)
+		}
+	}
+}
+
+func TestUptime(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "uptime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer func(u, l string) { procUptime, procLoadavg = u, l }(procUptime, procLoadavg)
+	procUptime, procLoadavg = filepath.Join(tmp, "uptime"), filepath.Join(tmp, "loadavg")
+	if err := ioutil.WriteFile(procUptime, []byte("273906.72 5513.90\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(procLoadavg, []byte("0.23 0.14 0.24 1/76 2909\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2017, 10, 15, 10, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		pretty, since bool
+		want          string
+	}{
+		{false, false, " 10:04:05 up 3 days,  4:05,  load average: 0.23, 0.14, 0.24"},
+		{true, false, "up 3 days, 4 hours, 5 minutes"},
+		{false, true, "2017-10-12 05:58:58"},
+	} {
+		*pretty, *since = tt.pretty, tt.since
+		if got, err := uptime(now); err != nil || got != tt.want {
+			t.Errorf("uptime -p=%v -s=%v: got %q, %v, want %q", tt.pretty, tt.since, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Vmstat prints statistics of processes, memory, swap, I/O and the CPUs.
+//
+// Synopsis:
+//     vmstat [-h] [DELAY [COUNT]]
+//
+// Description:
+//     vmstat prints a line of statistics from /proc/stat, /proc/vmstat and
+//     /proc/meminfo; then, with DELAY, a line every DELAY seconds, COUNT
+//     times or forever. The rates of the first line are since boot, those
+//     of the rest since the line before.
+//
+//     The columns are:
+//         r, b: processes which can run, and which are blocked on I/O
+//         swpd, free, buff, cache: swap used, and memory free, in buffers
+//             and in caches, in KiB
+//         si, so: KiB swapped in and out a second
+//         bi, bo: KiB read from and written to block devices a second
+//         in, cs: interrupts and context switches a second
+//         us, sy, id, wa, st: percent of CPU time in user code, the
+//             kernel, idle, waiting for I/O, and stolen by the hypervisor
+//
+// Options:
+//     -h: print memory in the largest unit it is at least one of, e.g. 1.5Gi
+//
+// Example:
+//     $ vmstat 1 3
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/meminfo"
+)
+
+var (
+	human = flag.Bool("h", false, "print memory in the largest unit it is at least one of")
+
+	procStat   = "/proc/stat"
+	procVmstat = "/proc/vmstat"
+	procUptime = "/proc/uptime"
+)
+
+// The CPU times in the cpu line of /proc/stat.
+const (
+	cpuUser = iota
+	cpuNice
+	cpuSystem
+	cpuIdle
+	cpuIOWait
+	cpuIRQ
+	cpuSoftIRQ
+	cpuSteal
+	cpuTimes
+)
+
+// A sample is the counters at one time.
+type sample struct {
+	running, blocked uint64
+	cpu              [cpuTimes]uint64
+	intr, ctxt       uint64
+	// pgpgin and pgpgout are in KiB, pswpin and pswpout in pages.
+	pgpgin, pgpgout, pswpin, pswpout uint64
+	mem                              meminfo.Meminfo
+}
+
+// readFields calls f with the fields of each line of name.
+func readFields(name string, f func([]string)) error {
+	r, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f(strings.Fields(s.Text()))
+	}
+	return s.Err()
+}
+
+func readSample() (*sample, error) {
+	s := &sample{}
+	err := readFields(procStat, func(f []string) {
+		if len(f) < 2 {
+			return
+		}
+		v, _ := strconv.ParseUint(f[1], 10, 64)
+		switch f[0] {
+		case "cpu":
+			for i := 0; i < cpuTimes && i+1 < len(f); i++ {
+				s.cpu[i], _ = strconv.ParseUint(f[i+1], 10, 64)
+			}
+		case "intr":
+			s.intr = v
+		case "ctxt":
+			s.ctxt = v
+		case "procs_running":
+			s.running = v
+		case "procs_blocked":
+			s.blocked = v
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	vm := map[string]*uint64{"pgpgin": &s.pgpgin, "pgpgout": &s.pgpgout, "pswpin": &s.pswpin, "pswpout": &s.pswpout}
+	err = readFields(procVmstat, func(f []string) {
+		if p, ok := vm[f[0]]; ok && len(f) > 1 {
+			*p, _ = strconv.ParseUint(f[1], 10, 64)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.mem, err = meminfo.Read()
+	return s, err
+}
+
+// uptime returns the seconds since boot.
+func uptime() (float64, error) {
+	var up float64
+	err := readFields(procUptime, func(f []string) {
+		if len(f) > 0 {
+			up, _ = strconv.ParseFloat(f[0], 64)
+		}
+	})
+	if up <= 0 {
+		up = 1
+	}
+	return up, err
+}
+
+func header(w io.Writer) {
+	fmt.Fprintln(w, "procs -----------memory---------- ---swap-- -----io---- -system-- ------cpu-----")
+	fmt.Fprintln(w, " r  b   swpd   free   buff  cache   si   so    bi    bo   in   cs us sy id wa st")
+}
+
+// line prints the statistics of now, with the rates since before, secs
+// seconds earlier.
+func line(w io.Writer, before, now *sample, secs float64, pageSize uint64) {
+	rate := func(b, n uint64) uint64 {
+		return uint64(float64(n-b)/secs + 0.5)
+	}
+	mem := func(b uint64) string {
+		if *human {
+			return meminfo.Human(b)
+		}
+		return strconv.FormatUint(b/1024, 10)
+	}
+	var d [cpuTimes]uint64
+	var total uint64
+	for i := range d {
+		d[i] = now.cpu[i] - before.cpu[i]
+		total += d[i]
+	}
+	if total == 0 {
+		total = 1
+	}
+	pct := func(t uint64) uint64 {
+		return (100*t + total/2) / total
+	}
+	m := now.mem
+	fmt.Fprintf(w, "%2d %2d %6s %6s %6s %6s %4d %4d %5d %5d %4d %4d %2d %2d %2d %2d %2d\n",
+		now.running, now.blocked,
+		mem(m["SwapTotal"]-m["SwapFree"]), mem(m["MemFree"]), mem(m["Buffers"]), mem(m["Cached"]+m["SReclaimable"]),
+		rate(before.pswpin, now.pswpin)*pageSize/1024, rate(before.pswpout, now.pswpout)*pageSize/1024,
+		rate(before.pgpgin, now.pgpgin), rate(before.pgpgout, now.pgpgout),
+		rate(before.intr, now.intr), rate(before.ctxt, now.ctxt),
+		pct(d[cpuUser]+d[cpuNice]), pct(d[cpuSystem]+d[cpuIRQ]+d[cpuSoftIRQ]), pct(d[cpuIdle]), pct(d[cpuIOWait]), pct(d[cpuSteal]))
+}
+
+func main() {
+	flag.Parse()
+	var (
+		delay time.Duration
+		count = 1
+		err   error
+	)
+	switch flag.NArg() {
+	case 2:
+		if count, err = strconv.Atoi(flag.Arg(1)); err != nil || count < 1 {
+			log.Fatalf("vmstat: bad count %q", flag.Arg(1))
+		}
+		fallthrough
+	case 1:
+		s, err := strconv.ParseFloat(flag.Arg(0), 64)
+		if err != nil || s <= 0 {
+			log.Fatalf("vmstat: bad delay %q", flag.Arg(0))
+		}
+		delay = time.Duration(s * float64(time.Second))
+		if flag.NArg() == 1 {
+			count = 0
+		}
+	case 0:
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	up, err := uptime()
+	if err != nil {
+		log.Fatal(err)
+	}
+	before := &sample{}
+	pageSize := uint64(os.Getpagesize())
+	header(os.Stdout)
+	for i := 1; ; i++ {
+		now, err := readSample()
+		if err != nil {
+			log.Fatal(err)
+		}
+		line(os.Stdout, before, now, up, pageSize)
+		if i == count {
+			return
+		}
+		before, up = now, delay.Seconds()
+		time.Sleep(delay)
+	}
+}
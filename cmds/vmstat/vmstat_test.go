@@ -0,0 +1,94 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/meminfo"
+)
+
+func TestLine(t *testing.T) {
+	before := &sample{
+		cpu:    [cpuTimes]uint64{100, 0, 50, 800, 50},
+		intr:   1000,
+		ctxt:   2000,
+		pgpgin: 100,
+		pswpin: 0,
+	}
+	now := &sample{
+		running: 2,
+		blocked: 1,
+		cpu:     [cpuTimes]uint64{200, 0, 100, 1600, 100},
+		intr:    3000,
+		ctxt:    6000,
+		pgpgin:  300,
+		pswpin:  4,
+		mem: meminfo.Meminfo{
+			"MemFree":   3 << 30,
+			"Buffers":   256 << 20,
+			"Cached":    768 << 20,
+			"SwapTotal": 1 << 30,
+			"SwapFree":  1<<30 - 512<<10,
+		},
+	}
+	for _, tt := range []struct {
+		human bool
+		want  string
+	}{
+		{false, " 2  1    512 3145728 262144 786432    8    0   100     0 1000 2000 10  5 80  5  0\n"},
+		{true, " 2  1  512Ki  3.0Gi  256Mi  768Mi    8    0   100     0 1000 2000 10  5 80  5  0\n"},
+	} {
+		*human = tt.human
+		var b bytes.Buffer
+		line(&b, before, now, 2, 4096)
+		if b.String() != tt.want {
+			t.Errorf("line -h=%v: got %q, want %q", tt.human, b.String(), tt.want)
+		}
+	}
+	*human = false
+}
+
+func TestReadSample(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "vmstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer func(s, v string) { procStat, procVmstat = s, v }(procStat, procVmstat)
+	procStat, procVmstat = filepath.Join(tmp, "stat"), filepath.Join(tmp, "vmstat")
+	for f, c := range map[string]string{
+		procStat:   "cpu  117590 0 17670 551390 4778 0 8 367 0 0\ncpu0 1 2 3 4 5 6 7 8 0 0\nintr 1702426 0 0\nctxt 4343756\nprocs_running 2\nprocs_blocked 1\n",
+		procVmstat: "nr_free_pages 1\npgpgin 2235076\npgpgout 2968142\npswpin 3\npswpout 4\n",
+	} {
+		if err := ioutil.WriteFile(f, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := readSample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mem = nil
+	want := &sample{
+		running: 2,
+		blocked: 1,
+		cpu:     [cpuTimes]uint64{117590, 0, 17670, 551390, 4778, 0, 8, 367},
+		intr:    1702426,
+		ctxt:    4343756,
+		pgpgin:  2235076,
+		pgpgout: 2968142,
+		pswpin:  3,
+		pswpout: 4,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("readSample: got %+v, want %+v", s, want)
+	}
+}
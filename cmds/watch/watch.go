@@ -0,0 +1,257 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Run a command again and again, showing what it prints.
+//
+// Synopsis:
+//     watch [-n SECONDS] [-d] [-g] [-t] [-x] COMMAND [ARG...]
+//
+// Description:
+//     watch runs COMMAND, with its ARGs, by rush -c, and shows what it
+//     prints, to stdout and stderr, on a cleared screen; then it waits
+//     and does it again, until it is interrupted. The lines are cut to the
+//     width of the terminal, and the screen shows as many as fit; both are
+//     read again when the terminal is resized.
+//
+//     If stdout is not a terminal, each time is printed after the last,
+//     with a blank line between.
+//
+// Options:
+//     -n: seconds to wait after the command is done; at least 0.1
+//     -d: highlight what changed since the last time
+//     -g: exit when what the command prints changes
+//     -t: do not print the header line
+//     -x: run COMMAND itself, not with rush -c
+//
+// Example:
+//     $ watch -n 1 -g 'ip link show eth0 | grep "state UP"'
+//     $ watch -d cat /proc/interrupts
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	interval = flag.Float64("n", 2, "seconds to wait between runs")
+	diff     = flag.Bool("d", false, "highlight what changed")
+	chgexit  = flag.Bool("g", false, "exit when the output changes")
+	noTitle  = flag.Bool("t", false, "do not print the header")
+	direct   = flag.Bool("x", false, "run the command itself, not with rush -c")
+)
+
+// The escapes to draw the screen with.
+const (
+	clear   = "\033[H\033[J"
+	reverse = "\033[7m"
+	normal  = "\033[0m"
+)
+
+// A screen is where watch prints, and how big it is.
+type screen struct {
+	w          io.Writer
+	rows, cols int
+	// tty is set if the screen is redrawn in place.
+	tty bool
+}
+
+// size reads the size of the terminal.
+func (s *screen) size() {
+	if ws, err := termios.GetWinSize(os.Stdout.Fd()); err == nil && ws.Row > 0 && ws.Col > 0 {
+		s.rows, s.cols = int(ws.Row), int(ws.Col)
+	}
+}
+
+// expand expands the tabs of l to spaces, to the next multiple of 8.
+func expand(l string) []rune {
+	var r []rune
+	for _, c := range l {
+		if c != '\t' {
+			r = append(r, c)
+			continue
+		}
+		for r = append(r, ' '); len(r)%8 != 0; {
+			r = append(r, ' ')
+		}
+	}
+	return r
+}
+
+// highlight returns l, in which the characters which are not those of
+// prev, the line before, are in reverse video.
+func highlight(l, prev []rune) string {
+	var b []rune
+	on := false
+	for i, c := range l {
+		changed := i >= len(prev) || prev[i] != c
+		if changed != on {
+			if changed {
+				b = append(b, []rune(reverse)...)
+			} else {
+				b = append(b, []rune(normal)...)
+			}
+			on = changed
+		}
+		b = append(b, c)
+	}
+	if on {
+		b = append(b, []rune(normal)...)
+	}
+	return string(b)
+}
+
+// header returns the header line: the interval and the command on the
+// left, and the host and time on the right, as far apart as cols allows.
+func header(interval float64, cmd, host string, now time.Time, cols int) string {
+	left := fmt.Sprintf("Every %.1fs: %s", interval, cmd)
+	right := fmt.Sprintf("%s: %s", host, now.Format(time.ANSIC))
+	if pad := cols - len(left) - len(right); pad > 0 {
+		return left + strings.Repeat(" ", pad) + right
+	}
+	return left + "  " + right
+}
+
+// render returns the lines to show of out, cut to s.cols and, with the
+// n lines of the header, s.rows. With diff, what changed from prev is
+// highlighted.
+func render(s *screen, out, prev string, n int, diff bool) []string {
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	before := strings.Split(strings.TrimSuffix(prev, "\n"), "\n")
+	var r []string
+	for i, l := range lines {
+		if s.tty && n+len(r) >= s.rows {
+			break
+		}
+		e := expand(l)
+		if s.tty && len(e) > s.cols {
+			e = e[:s.cols]
+		}
+		if !diff {
+			r = append(r, string(e))
+			continue
+		}
+		var p []rune
+		if i < len(before) {
+			p = expand(before[i])
+		}
+		r = append(r, highlight(e, p))
+	}
+	return r
+}
+
+// draw prints out, under head unless it is "".
+func draw(s *screen, head, out, prev string, diff bool) {
+	var lines []string
+	if head != "" {
+		if s.tty && len(head) > s.cols {
+			head = head[:s.cols]
+		}
+		lines = append(lines, head, "")
+	}
+	lines = append(lines, render(s, out, prev, len(lines), diff)...)
+
+	b := bufio.NewWriter(s.w)
+	if s.tty {
+		b.WriteString(clear)
+	}
+	for i, l := range lines {
+		b.WriteString(l)
+		// The last line has no newline, lest the screen scroll.
+		if !s.tty || i < len(lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	if !s.tty {
+		b.WriteString("\n")
+	}
+	b.Flush()
+}
+
+// command returns the command to run for args.
+func command(args []string) *exec.Cmd {
+	if *direct {
+		return exec.Command(args[0], args[1:]...)
+	}
+	return exec.Command("rush", "-c", strings.Join(args, " "))
+}
+
+func watch(args []string) error {
+	wait := *interval
+	if wait < 0.1 {
+		wait = 0.1
+	}
+	s := &screen{w: os.Stdout, rows: 24, cols: 80}
+	host, _ := os.Hostname()
+
+	winch := make(chan os.Signal, 1)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	if t, err := termios.GetTermios(os.Stdout.Fd()); err == nil {
+		// What is typed is not echoed, so as not to be on the screen.
+		noecho := *t
+		noecho.Lflag &^= unix.ECHO
+		if err := termios.SetTermios(os.Stdout.Fd(), &noecho); err != nil {
+			return err
+		}
+		defer termios.SetTermios(os.Stdout.Fd(), t)
+		// Leave the cursor below what was drawn.
+		defer fmt.Fprintln(os.Stdout)
+		s.tty = true
+		s.size()
+		signal.Notify(winch, syscall.SIGWINCH)
+	}
+
+	var prev string
+	for n := 0; ; n++ {
+		// What fails is shown, as is what it printed.
+		b, _ := command(args).CombinedOutput()
+		out := string(b)
+		head := ""
+		if !*noTitle {
+			head = header(wait, strings.Join(args, " "), host, time.Now(), s.cols)
+		}
+		if n == 0 {
+			prev = out
+		}
+		draw(s, head, out, prev, *diff)
+		if *chgexit && out != prev {
+			return nil
+		}
+		prev = out
+
+		t := time.NewTimer(time.Duration(wait * float64(time.Second)))
+		select {
+		case <-t.C:
+		case <-winch:
+			s.size()
+		case <-quit:
+			return nil
+		}
+		t.Stop()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := watch(flag.Args()); err != nil {
+		log.Fatalf("watch: %v", err)
+	}
+}
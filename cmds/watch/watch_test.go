@@ -0,0 +1,88 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"\tx", "        x"},
+		{"ab\tc\td", "ab      c       d"},
+		{"12345678\t9", "12345678        9"},
+	} {
+		if got := string(expand(tt.in)); got != tt.want {
+			t.Errorf("expand(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	for _, tt := range []struct {
+		l, prev, want string
+	}{
+		{"abc", "abc", "abc"},
+		{"abc", "axc", "a\033[7mb\033[0mc"},
+		{"abcd", "ab", "ab\033[7mcd\033[0m"},
+		{"ab", "abcd", "ab"},
+		{"xyz", "", "\033[7mxyz\033[0m"},
+	} {
+		if got := highlight([]rune(tt.l), []rune(tt.prev)); got != tt.want {
+			t.Errorf("highlight(%q, %q): got %q, want %q", tt.l, tt.prev, got, tt.want)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	now := time.Date(2017, time.October, 15, 12, 30, 5, 0, time.UTC)
+	want := "Every 2.0s: date" + "                       " + "box: Sun Oct 15 12:30:05 2017"
+	if got := header(2, "date", "box", now, 68); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want = "Every 0.5s: date  box: Sun Oct 15 12:30:05 2017"
+	if got := header(0.5, "date", "box", now, 20); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	s := &screen{rows: 4, cols: 5, tty: true}
+	got := render(s, "1234567\nab\nc\nd\ne\n", "1234567\naa\n", 1, true)
+	want := []string{"12345", "a\033[7mb\033[0m", "\033[7mc\033[0m"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("on a tty: got %q, want %q", got, want)
+	}
+
+	s.tty = false
+	got = render(s, "1234567\nab\nc\nd\ne\n", "", 1, false)
+	want = []string{"1234567", "ab", "c", "d", "e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not on a tty: got %q, want %q", got, want)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	var b bytes.Buffer
+	s := &screen{w: &b, rows: 24, cols: 80}
+	draw(s, "head", "a\nb\n", "", false)
+	if got, want := b.String(), "head\n\na\nb\n\n"; got != want {
+		t.Errorf("not on a tty: got %q, want %q", got, want)
+	}
+
+	b.Reset()
+	s.tty = true
+	draw(s, "", "a\nb\n", "", false)
+	if got, want := b.String(), "\033[H\033[Ja\nb"; got != want {
+		t.Errorf("on a tty: got %q, want %q", got, want)
+	}
+}
@@ -5,40 +5,156 @@
 // Wget reads one file from a url and writes to stdout.
 //
 // Synopsis:
-//     wget URL
+//     wget [OPTIONS] URL
 //
 // Description:
-//     Returns a non-zero code on failure.
+//     Returns a non-zero code on failure. Redirects are followed. A
+//     download which fails part way is retried from where it stopped, if
+//     there are tries left and the server supports ranges; otherwise what
+//     was already read is skipped.
+//
+//     HTTPS servers are checked against the system's CAs, which are read
+//     from /etc/ssl/certs; an image which fetches over HTTPS should include
+//     them, e.g. with u-root -files /etc/ssl/certs, or name a bundle with
+//     -ca-certificate.
+//
+// Options:
+//     -O FILE:  write to FILE rather than stdout
+//     -c:       continue the partial download in FILE
+//     -t N:     try N times; 4xx errors are not retried
+//     -q:       do not show progress when writing to FILE
+//     -ca-certificate FILE:  check servers against the CAs in FILE
+//     -no-check-certificate: do not check servers' certificates
 //
 // Notes:
 //     There are a few differences with GNU wget:
 //     - Upon error, the return value is always 1.
 //     - The protocol (http/https) is mandatory.
+//     - Without -O, the file goes to stdout.
 //
 // Example:
 //     wget http://google.com/ > e100.html
+//     wget -c -t 5 -O bzImage https://example.com/bzImage
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"time"
+)
+
+var (
+	outPath = flag.String("O", "-", "Write to `file`; - is stdout")
+	resume  = flag.Bool("c", false, "Continue the partial download in the output file")
+	tries   = flag.Int("t", 1, "Number of tries")
+	quiet   = flag.Bool("q", false, "Do not show progress")
+	caCert  = flag.String("ca-certificate", "", "Check servers against the CAs in PEM `file`")
+	noCheck = flag.Bool("no-check-certificate", false, "Do not check servers' certificates")
 )
 
-func wget(arg string, w io.Writer) error {
-	resp, err := http.Get(arg)
+// statusError is an HTTP status other than 200 or 206.
+type statusError int
+
+func (s statusError) Error() string {
+	return fmt.Sprintf("non-200 HTTP status: %d", int(s))
+}
+
+// progress counts the bytes written through it, and shows the count on
+// stderr at most once a second.
+type progress struct {
+	w     io.Writer
+	done  int64
+	total int64
+	last  time.Time
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if !*quiet && *outPath != "-" && time.Since(p.last) >= time.Second {
+		p.show()
+		p.last = time.Now()
+	}
+	return n, err
+}
+
+func (p *progress) show() {
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r%d/%d bytes (%d%%)", p.done, p.total, p.done*100/p.total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%d bytes", p.done)
+	}
+}
+
+// wget writes the contents of url to w, starting at offset. It returns the
+// number of bytes written.
+func wget(c *http.Client, url string, w io.Writer, offset int64) (int64, error) {
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("non-200 HTTP status: %d", resp.StatusCode)
+
+	p := &progress{w: w, done: offset, total: -1, last: time.Now()}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server does not do ranges, so skip what we have.
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+			return 0, err
+		}
+		p.total = resp.ContentLength
+	case http.StatusPartialContent:
+		if resp.ContentLength >= 0 {
+			p.total = offset + resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// We already have all of it.
+		if offset > 0 {
+			return 0, nil
+		}
+		fallthrough
+	default:
+		return 0, statusError(resp.StatusCode)
+	}
+	_, err = io.Copy(p, resp.Body)
+	if !*quiet && *outPath != "-" {
+		p.show()
+		fmt.Fprintln(os.Stderr)
+	}
+	return p.done - offset, err
+}
+
+func client() (*http.Client, error) {
+	t := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: *noCheck},
+	}
+	if *caCert != "" {
+		b, err := ioutil.ReadFile(*caCert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no certificates found", *caCert)
+		}
+		t.TLSClientConfig.RootCAs = pool
 	}
-	_, err = io.Copy(w, resp.Body)
-	return err
+	return &http.Client{Transport: t}, nil
 }
 
 func usage() {
@@ -53,8 +169,40 @@ func main() {
 		usage()
 	}
 
-	url := flag.Arg(0)
-	if err := wget(url, os.Stdout); err != nil {
+	c, err := client()
+	if err != nil {
 		log.Fatalf("%v\n", err)
 	}
+
+	var w io.Writer = os.Stdout
+	var offset int64
+	if *outPath != "-" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if *resume {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(*outPath, flags, 0666)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		defer f.Close()
+		if fi, err := f.Stat(); err == nil && *resume {
+			offset = fi.Size()
+		}
+		w = f
+	}
+
+	url := flag.Arg(0)
+	for i := 1; ; i++ {
+		n, err := wget(c, url, w, offset)
+		offset += n
+		if err == nil {
+			break
+		}
+		if s, ok := err.(statusError); i >= *tries || ok && s < 500 {
+			log.Fatalf("%v\n", err)
+		}
+		log.Printf("%v; retrying", err)
+		time.Sleep(time.Duration(i) * time.Second)
+	}
 }
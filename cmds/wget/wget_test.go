@@ -9,10 +9,12 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
@@ -32,6 +34,8 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(content))
 	case "/302":
 		http.Redirect(w, r, "/200", 302)
+	case "/file":
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
 	case "/500":
 		w.WriteHeader(500)
 		w.Write([]byte(content))
@@ -142,3 +146,42 @@ func TestWget(t *testing.T) {
 		}
 	}
 }
+
+func TestResume(t *testing.T) {
+	tmpDir, execPath := testutil.CompileInTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(l, handler{})
+
+	out := filepath.Join(tmpDir, "out")
+	for _, tt := range []struct {
+		name string
+		have string
+		url  string
+	}{
+		{"partial", content[:8], "/file"},
+		{"complete", content, "/file"},
+		{"no ranges", content[:8], "/200"},
+	} {
+		if err := ioutil.WriteFile(out, []byte(tt.have), 0644); err != nil {
+			t.Fatal(err)
+		}
+		url := fmt.Sprintf("http://%s%s", l.Addr(), tt.url)
+		if b, err := exec.Command(execPath, "-q", "-c", "-O", out, url).CombinedOutput(); err != nil {
+			t.Errorf("%s: wget -c: %v: %s", tt.name, err, b)
+			continue
+		}
+		b, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: got %q, want %q", tt.name, b, content)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Xargs runs a command with arguments read from stdin.
+//
+// Synopsis:
+//     xargs [-0] [-d DELIM] [-n MAX] [-s SIZE] [-I REPLACE] [-P PROCS] [-r] [-t] [COMMAND [ARG]...]
+//
+// Description:
+//     xargs reads arguments from stdin and runs COMMAND, echo by default,
+//     with ARGs and as many of them as fit, as many times as it takes to
+//     run them all.
+//
+//     The arguments are separated by blanks and newlines, which can be
+//     quoted with '...', "..." or \; with -0, by NULs, as find -print0
+//     prints them; or with -d, by DELIM.
+//
+//     With -I, each line of stdin, without its leading blanks, or with
+//     -0 or -d, each argument, is an argument, and COMMAND is run once for
+//     each, with REPLACE in the ARGs replaced by it.
+//
+//     COMMAND's stdin is /dev/null. xargs exits with 123 if COMMAND fails,
+//     124 if it exits with 255, 125 if it is killed, 126 if it cannot be
+//     run, and 127 if it is not found; in all but the first, xargs stops.
+//
+// Options:
+//     -0: arguments are separated by NULs
+//     -d: arguments are separated by DELIM, e.g. \n
+//     -n: run COMMAND with at most MAX arguments
+//     -s: run COMMAND with a command line of at most SIZE bytes
+//     -I: run COMMAND for each line, with REPLACE in the ARGs replaced
+//     -P: run up to PROCS COMMANDs at once; 0 is as many as there are
+//     -r: do not run COMMAND if there are no arguments
+//     -t: print each command line to stderr before it is run
+//
+// Example:
+//     $ find /lib/modules -name '*.ko' -print0 | xargs -0 -n 1 -P 4 xz -d
+//     $ ls *.conf | xargs -I {} cp {} {}.orig
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The exit statuses.
+const (
+	statusFailed   = 123
+	statusStopped  = 124
+	statusKilled   = 125
+	statusCannot   = 126
+	statusNotFound = 127
+)
+
+var (
+	null    = flag.Bool("0", false, "arguments are separated by NULs")
+	delim   = flag.String("d", "", "arguments are separated by `delim`")
+	maxArgs = flag.Int("n", 0, "run the command with at most `max` arguments")
+	maxSize = flag.Int("s", 128<<10, "run the command with a command line of at most `size` bytes")
+	replace = flag.String("I", "", "run the command for each line, with `replace` in the arguments replaced")
+	procs   = flag.Int("P", 1, "run up to `procs` commands at once")
+	noEmpty = flag.Bool("r", false, "do not run the command if there are no arguments")
+	trace   = flag.Bool("t", false, "print each command line before it is run")
+)
+
+// An argReader reads arguments.
+type argReader interface {
+	next() (string, error)
+}
+
+// delimReader reads arguments separated by a byte.
+type delimReader struct {
+	r     *bufio.Reader
+	delim byte
+	// trim trims the leading blanks of each argument.
+	trim bool
+}
+
+func (d *delimReader) next() (string, error) {
+	for {
+		s, err := d.r.ReadString(d.delim)
+		if err == io.EOF && s == "" {
+			return "", err
+		}
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		s = strings.TrimSuffix(s, string(d.delim))
+		if d.trim {
+			s = strings.TrimLeft(s, " \t")
+		}
+		// With -I, empty lines are skipped, but with -0 and -d, empty
+		// arguments are arguments.
+		if s != "" || !d.trim {
+			return s, nil
+		}
+	}
+}
+
+// wordReader reads arguments separated by blanks and newlines, which may
+// be quoted.
+type wordReader struct {
+	r *bufio.Reader
+}
+
+func (w *wordReader) next() (string, error) {
+	var (
+		b     []byte
+		quote byte
+		in    bool
+	)
+	for {
+		c, err := w.r.ReadByte()
+		if err == io.EOF {
+			if quote != 0 {
+				return "", fmt.Errorf("unmatched %c", quote)
+			}
+			if in {
+				return string(b), nil
+			}
+			return "", io.EOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0 && c == '\n':
+			return "", fmt.Errorf("unmatched %c", quote)
+		case quote != 0:
+			b = append(b, c)
+		case c == '\'' || c == '"':
+			quote, in = c, true
+		case c == '\\':
+			if c, err = w.r.ReadByte(); err != nil {
+				return "", fmt.Errorf("\\ at the end of the input")
+			}
+			b, in = append(b, c), true
+		case c == ' ' || c == '\t' || c == '\n':
+			if in {
+				return string(b), nil
+			}
+		default:
+			b, in = append(b, c), true
+		}
+	}
+}
+
+// parseDelim parses a -d DELIM: a character, or an escape, such as \n,
+// \t, \0 or \x0a.
+func parseDelim(s string) (byte, error) {
+	if len(s) == 1 {
+		return s[0], nil
+	}
+	if !strings.HasPrefix(s, "\\") || len(s) < 2 {
+		return 0, fmt.Errorf("%q: delimiter is not one character", s)
+	}
+	if c, ok := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '0': 0}[s[1]]; ok && len(s) == 2 {
+		return c, nil
+	}
+	if s[1] == 'x' {
+		if n, err := strconv.ParseUint(s[2:], 16, 8); err == nil {
+			return byte(n), nil
+		}
+	}
+	return 0, fmt.Errorf("%q: delimiter is not one character", s)
+}
+
+// checkLimits checks that max, if it was set, and size are at least 1.
+// max is 0, for no limit, when it was not.
+func checkLimits(max int, maxSet bool, size int) error {
+	if maxSet && max < 1 {
+		return fmt.Errorf("value %d for -n option should be >= 1", max)
+	}
+	if size < 1 {
+		return fmt.Errorf("value %d for -s option should be >= 1", size)
+	}
+	return nil
+}
+
+// An xargs runs a command with arguments.
+type xargs struct {
+	cmd     []string
+	max     int
+	size    int
+	replace string
+	procs   int
+	noEmpty bool
+	trace   io.Writer
+	// run runs a command line, and returns the status xargs exits
+	// with if it fails, or 0.
+	run func(argv []string) int
+}
+
+// runCommand runs argv. Its stdin is nil, which is /dev/null, as it is
+// not for the command to read what xargs is reading.
+func runCommand(argv []string) int {
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	err := c.Run()
+	if err == nil {
+		return 0
+	}
+	log.Printf("xargs: %v", err)
+	switch e := err.(type) {
+	case *exec.Error:
+		if e.Err == exec.ErrNotFound {
+			return statusNotFound
+		}
+		return statusCannot
+	case *exec.ExitError:
+		ws, ok := e.Sys().(syscall.WaitStatus)
+		switch {
+		case ok && ws.Signaled():
+			return statusKilled
+		case ok && ws.ExitStatus() == 255:
+			return statusStopped
+		}
+		return statusFailed
+	}
+	return statusCannot
+}
+
+// batches reads the arguments of r, and sends the command lines to run
+// them to c, until stop is closed.
+func (x *xargs) batches(r argReader, c chan<- []string, stop <-chan struct{}) error {
+	defer close(c)
+	send := func(argv []string) bool {
+		select {
+		case c <- argv:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	base := 0
+	for _, a := range x.cmd {
+		base += len(a) + 1
+	}
+	var (
+		argv = append([]string{}, x.cmd...)
+		size = base
+		sent bool
+	)
+	for {
+		a, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if x.replace != "" {
+			argv := make([]string, len(x.cmd))
+			for i, s := range x.cmd {
+				argv[i] = strings.Replace(s, x.replace, a, -1)
+			}
+			if !send(argv) {
+				return nil
+			}
+			sent = true
+			continue
+		}
+		if len(argv) > len(x.cmd) && (size+len(a)+1 > x.size || x.max > 0 && len(argv)-len(x.cmd) >= x.max) {
+			if !send(argv) {
+				return nil
+			}
+			sent = true
+			argv, size = append([]string{}, x.cmd...), base
+		}
+		if base+len(a)+1 > x.size {
+			return fmt.Errorf("argument of %d bytes is too long for a command line of %d", len(a), x.size)
+		}
+		argv = append(argv, a)
+		size += len(a) + 1
+	}
+	// The command is run once, even without arguments, unless -r or
+	// -I.
+	if len(argv) > len(x.cmd) || !sent && !x.noEmpty && x.replace == "" {
+		send(argv)
+	}
+	return nil
+}
+
+// xargs runs the command with the arguments of r, and returns the status
+// to exit with.
+func (x *xargs) xargs(r argReader) (int, error) {
+	var (
+		c      = make(chan []string)
+		stop   = make(chan struct{})
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		status int
+		once   sync.Once
+	)
+	procs := x.procs
+	if procs <= 0 {
+		procs = 1 << 16
+	}
+	sem := make(chan struct{}, procs)
+	errc := make(chan error, 1)
+	go func() { errc <- x.batches(r, c, stop) }()
+	for argv := range c {
+		sem <- struct{}{}
+		select {
+		case <-stop:
+			// A command exited with 255, was killed, or could not be
+			// run.
+			<-sem
+			continue
+		default:
+		}
+		if x.trace != nil {
+			fmt.Fprintln(x.trace, strings.Join(argv, " "))
+		}
+		wg.Add(1)
+		go func(argv []string) {
+			defer func() { <-sem; wg.Done() }()
+			s := x.run(argv)
+			mu.Lock()
+			defer mu.Unlock()
+			if s > status {
+				status = s
+			}
+			if s >= statusStopped {
+				once.Do(func() { close(stop) })
+			}
+		}(argv)
+	}
+	wg.Wait()
+	return status, <-errc
+}
+
+func main() {
+	flag.Parse()
+	var maxSet bool
+	flag.Visit(func(f *flag.Flag) { maxSet = maxSet || f.Name == "n" })
+	if err := checkLimits(*maxArgs, maxSet, *maxSize); err != nil {
+		log.Printf("xargs: %v", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	x := &xargs{
+		cmd:     flag.Args(),
+		max:     *maxArgs,
+		size:    *maxSize,
+		replace: *replace,
+		procs:   *procs,
+		noEmpty: *noEmpty,
+		run:     runCommand,
+	}
+	if len(x.cmd) == 0 {
+		x.cmd = []string{"echo"}
+	}
+	if *trace {
+		x.trace = os.Stderr
+	}
+
+	br := bufio.NewReader(os.Stdin)
+	var r argReader = &wordReader{br}
+	switch {
+	case *null:
+		r = &delimReader{r: br}
+	case *delim != "":
+		d, err := parseDelim(*delim)
+		if err != nil {
+			log.Fatalf("xargs: %v", err)
+		}
+		r = &delimReader{r: br, delim: d}
+	case x.replace != "":
+		r = &delimReader{r: br, delim: '\n', trim: true}
+	}
+
+	status, err := x.xargs(r)
+	if err != nil {
+		log.Printf("xargs: %v", err)
+		if status == 0 {
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,228 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readAll(r argReader) ([]string, error) {
+	var l []string
+	for {
+		a, err := r.next()
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return l, err
+		}
+		l = append(l, a)
+	}
+}
+
+func TestReaders(t *testing.T) {
+	br := func(s string) *bufio.Reader { return bufio.NewReader(strings.NewReader(s)) }
+	for _, tt := range []struct {
+		name string
+		r    argReader
+		want []string
+	}{
+		{"words", &wordReader{br("a b\t c\n\nd")}, []string{"a", "b", "c", "d"}},
+		{"quotes", &wordReader{br(`'a b' "c 'd'" e\ f g\"h ''`)}, []string{"a b", "c 'd'", "e f", `g"h`, ""}},
+		{"null", &delimReader{r: br("a b\x00c\nd\x00\x00e\x00")}, []string{"a b", "c\nd", "", "e"}},
+		{"delim", &delimReader{r: br("a,b c,"), delim: ','}, []string{"a", "b c"}},
+		{"lines", &delimReader{r: br("  a b\n\n\tc\n"), delim: '\n', trim: true}, []string{"a b", "c"}},
+	} {
+		got, err := readAll(tt.r)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	for _, s := range []string{`'a`, `"a` + "\n" + `"`, `a\`} {
+		if got, err := readAll(&wordReader{br(s)}); err == nil {
+			t.Errorf("%q: got %q, want an error", s, got)
+		}
+	}
+}
+
+func TestParseDelim(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want byte
+	}{
+		{",", ','},
+		{`\n`, '\n'},
+		{`\t`, '\t'},
+		{`\0`, 0},
+		{`\x1f`, 0x1f},
+	} {
+		got, err := parseDelim(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("parseDelim(%q): got %q, %v, want %q, nil", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"ab", `\q`, `\xzz`, ""} {
+		if _, err := parseDelim(s); err == nil {
+			t.Errorf("parseDelim(%q): got nil, want an error", s)
+		}
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	for _, tt := range []struct {
+		max    int
+		maxSet bool
+		size   int
+		ok     bool
+	}{
+		{0, false, 128 << 10, true},
+		{1, true, 1, true},
+		{0, true, 128 << 10, false},
+		{-1, true, 128 << 10, false},
+		{1, true, 0, false},
+		{0, false, -1, false},
+	} {
+		if err := checkLimits(tt.max, tt.maxSet, tt.size); (err == nil) != tt.ok {
+			t.Errorf("checkLimits(%d, %v, %d): got %v, want ok %v", tt.max, tt.maxSet, tt.size, err, tt.ok)
+		}
+	}
+}
+
+// recorder records the command lines it is given to run.
+type recorder struct {
+	mu    sync.Mutex
+	lines []string
+	// status is returned for the lines with fail in them.
+	fail   string
+	status int
+}
+
+func (r *recorder) run(argv []string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := strings.Join(argv, " ")
+	r.lines = append(r.lines, l)
+	if r.fail != "" && strings.Contains(l, r.fail) {
+		return r.status
+	}
+	return 0
+}
+
+func TestXargs(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		x      xargs
+		in     string
+		want   []string
+		status int
+	}{
+		{"all", xargs{cmd: []string{"echo"}}, "a b\nc", []string{"echo a b c"}, 0},
+		{"n", xargs{cmd: []string{"rm", "-f"}, max: 2}, "a b c d e", []string{"rm -f a b", "rm -f c d", "rm -f e"}, 0},
+		// Each line is echo and a space and an argument and a space.
+		{"size", xargs{cmd: []string{"echo"}, size: 12}, "aa bb cc dd", []string{"echo aa bb", "echo cc dd"}, 0},
+		{"replace", xargs{cmd: []string{"cp", "{}", "{}.orig"}, replace: "{}"}, "a\n  b c\n", []string{"cp a a.orig", "cp b c b c.orig"}, 0},
+		{"empty", xargs{cmd: []string{"echo"}}, "", []string{"echo"}, 0},
+		{"noEmpty", xargs{cmd: []string{"echo"}, noEmpty: true}, "", nil, 0},
+		{"emptyReplace", xargs{cmd: []string{"echo", "{}"}, replace: "{}"}, "", nil, 0},
+		{"failed", xargs{cmd: []string{"f"}, max: 1}, "a b c", []string{"f a", "f b", "f c"}, statusFailed},
+		// 255 stops xargs.
+		{"stopped", xargs{cmd: []string{"s"}, max: 1}, "a b c", []string{"s a", "s b"}, statusStopped},
+	} {
+		rec := &recorder{fail: "b", status: tt.status}
+		tt.x.run = rec.run
+		if tt.x.size == 0 {
+			tt.x.size = 128 << 10
+		}
+		tt.x.procs = 1
+		var r argReader = &wordReader{bufio.NewReader(strings.NewReader(tt.in))}
+		if tt.x.replace != "" {
+			r = &delimReader{r: bufio.NewReader(strings.NewReader(tt.in)), delim: '\n', trim: true}
+		}
+		status, err := tt.x.xargs(r)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if status != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, status, tt.status)
+		}
+		if !reflect.DeepEqual(rec.lines, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, rec.lines, tt.want)
+		}
+	}
+}
+
+func TestTooLong(t *testing.T) {
+	rec := &recorder{}
+	x := &xargs{cmd: []string{"echo"}, size: 8, procs: 1, run: rec.run}
+	if _, err := x.xargs(&wordReader{bufio.NewReader(strings.NewReader("a toolong"))}); err == nil {
+		t.Errorf("got nil, want an error")
+	}
+	if want := []string{"echo a"}; !reflect.DeepEqual(rec.lines, want) {
+		t.Errorf("got %q, want %q", rec.lines, want)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	// Each command waits for all of them to be running, so this only
+	// ends if they run at once.
+	const n = 4
+	var (
+		running sync.WaitGroup
+		rec     recorder
+	)
+	running.Add(n)
+	x := &xargs{cmd: []string{"p"}, max: 1, size: 1 << 10, procs: n, trace: &bytes.Buffer{}}
+	x.run = func(argv []string) int {
+		running.Done()
+		running.Wait()
+		return rec.run(argv)
+	}
+	status, err := x.xargs(&wordReader{bufio.NewReader(strings.NewReader("1 2 3 4"))})
+	if err != nil || status != 0 {
+		t.Fatalf("got %d, %v, want 0, nil", status, err)
+	}
+	sort.Strings(rec.lines)
+	if want := []string{"p 1", "p 2", "p 3", "p 4"}; !reflect.DeepEqual(rec.lines, want) {
+		t.Errorf("got %q, want %q", rec.lines, want)
+	}
+	if got, want := x.trace.(*bytes.Buffer).String(), "p 1\np 2\np 3\np 4\n"; got != want {
+		t.Errorf("trace: got %q, want %q", got, want)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	for _, tt := range []struct {
+		argv   []string
+		status int
+	}{
+		{[]string{"true"}, 0},
+		{[]string{"false"}, statusFailed},
+		{[]string{"sh", "-c", "exit 255"}, statusStopped},
+		{[]string{"sh", "-c", "kill $$"}, statusKilled},
+		{[]string{"/"}, statusCannot},
+		{[]string{"no-such-command-at-all"}, statusNotFound},
+	} {
+		if _, err := exec.LookPath(tt.argv[0]); err != nil && tt.status != statusNotFound && tt.status != statusCannot {
+			t.Logf("%s: %v", tt.argv[0], err)
+			continue
+		}
+		if got := runCommand(tt.argv); got != tt.status {
+			t.Errorf("%q: got %d, want %d", tt.argv, got, tt.status)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Decompress gzip files to stdout.
+//
+// Synopsis:
+//     zcat [FILES...]
+//
+// Description:
+//     zcat decompresses each FILE to stdout, or stdin if there are no FILES.
+//     Files of several gzip streams one after the other are decompressed
+//     as one.
+//
+// Example:
+//     zcat initramfs.cpio.gz | cpio -t
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/gzip"
+)
+
+var opts = gzip.Options{Decompress: true, Stdout: true}
+
+func init() {
+	opts.RegisterFlags(flag.CommandLine)
+}
+
+func main() {
+	flag.Parse()
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	status := 0
+	for _, f := range files {
+		if err := opts.File(f); err != nil {
+			log.Print(err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package acpi reads the ACPI tables firmware describes the machine with,
+// as Linux exports them in /sys/firmware/acpi/tables.
+package acpi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SysTables is where Linux exports the tables. The SSDTs it loads after
+// boot are in its dynamic directory.
+const SysTables = "/sys/firmware/acpi/tables"
+
+// HeaderLen is the length of the header all tables but the FACS start
+// with.
+const HeaderLen = 36
+
+// A Table is an ACPI table.
+type Table struct {
+	// Name is the name Linux gives it, which is its signature, and a
+	// number if there are more than one, as for SSDTs.
+	Name string
+
+	Signature       string
+	Length          uint32
+	Revision        uint8
+	Checksum        uint8
+	OEMID           string
+	OEMTableID      string
+	OEMRevision     uint32
+	CreatorID       string
+	CreatorRevision uint32
+
+	// Data is the whole table, header and all, so that offsets into it
+	// are those in the specification.
+	Data []byte
+}
+
+// Parse parses the table b, called name.
+func Parse(name string, b []byte) (*Table, error) {
+	if len(b) < HeaderLen {
+		return nil, fmt.Errorf("%v: %d bytes is too short for a table", name, len(b))
+	}
+	t := &Table{
+		Name:            name,
+		Signature:       string(b[0:4]),
+		Length:          binary.LittleEndian.Uint32(b[4:]),
+		Revision:        b[8],
+		Checksum:        b[9],
+		OEMID:           strings.TrimRight(string(b[10:16]), " \x00"),
+		OEMTableID:      strings.TrimRight(string(b[16:24]), " \x00"),
+		OEMRevision:     binary.LittleEndian.Uint32(b[24:]),
+		CreatorID:       strings.TrimRight(string(b[28:32]), " \x00"),
+		CreatorRevision: binary.LittleEndian.Uint32(b[32:]),
+		Data:            b,
+	}
+	if int(t.Length) != len(b) {
+		return nil, fmt.Errorf("%v: table is %d bytes, but says it is %d", name, len(b), t.Length)
+	}
+	return t, nil
+}
+
+// ChecksumOK returns whether the bytes of t add up to 0, as they should.
+func (t *Table) ChecksumOK() bool {
+	var sum uint8
+	for _, b := range t.Data {
+		sum += b
+	}
+	return sum == 0
+}
+
+// ReadTables reads the tables in dir, which is SysTables but in tests, and
+// those in its dynamic directory, which are named dynamic/NAME.
+func ReadTables(dir string) ([]*Table, error) {
+	var tables []*Table
+	for _, d := range []string{"", "dynamic"} {
+		fis, err := ioutil.ReadDir(filepath.Join(dir, d))
+		if os.IsNotExist(err) && d != "" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			if !fi.Mode().IsRegular() {
+				continue
+			}
+			name := filepath.Join(d, fi.Name())
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			t, err := Parse(name, b)
+			if err != nil {
+				return nil, err
+			}
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
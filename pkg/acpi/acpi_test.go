@@ -0,0 +1,82 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package acpi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// mcfg is the MCFG of a Firecracker VM.
+var mcfg = []byte("MCFG<\x00\x00\x00\x01\x7fFIRECKFCMVMCFG\x00\x00\x00\x00FCAT\x19\x01$ " +
+	"\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xc0\xee\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+
+func TestParse(t *testing.T) {
+	tab, err := Parse("MCFG", mcfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Table{
+		Name:            "MCFG",
+		Signature:       "MCFG",
+		Length:          60,
+		Revision:        1,
+		Checksum:        0x7f,
+		OEMID:           "FIRECK",
+		OEMTableID:      "FCMVMCFG",
+		CreatorID:       "FCAT",
+		CreatorRevision: 0x20240119,
+	}
+	tab.Data = nil
+	if !reflect.DeepEqual(*tab, want) {
+		t.Errorf("Parse: got %+v, want %+v", *tab, want)
+	}
+
+	for _, bad := range [][]byte{mcfg[:30], mcfg[:40]} {
+		if _, err := Parse("MCFG", bad); err == nil {
+			t.Errorf("Parse of %d bytes: got nil, want an error", len(bad))
+		}
+	}
+}
+
+func TestReadTables(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "acpi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	bad := append([]byte(nil), mcfg...)
+	bad[9]++
+	for n, b := range map[string][]byte{"MCFG": mcfg, "dynamic/SSDT1": bad} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmp, n)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmp, n), b, 0444); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(tmp, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := ReadTables(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("ReadTables: got %d tables, want 2", len(tables))
+	}
+	for i, want := range []struct {
+		name string
+		ok   bool
+	}{{"MCFG", true}, {"dynamic/SSDT1", false}} {
+		if tables[i].Name != want.name || tables[i].ChecksumOK() != want.ok {
+			t.Errorf("table %d: got %v, checksum ok %v, want %v, %v", i, tables[i].Name, tables[i].ChecksumOK(), want.name, want.ok)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package argon2 derives keys from passwords with Argon2, RFC 9106, which
+// is what LUKS2 does.
+package argon2
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// The types of Argon2.
+const (
+	argon2d = iota
+	argon2i
+	argon2id
+)
+
+const (
+	version     = 0x13
+	blockLength = 128 // in words
+	syncPoints  = 4
+)
+
+type block [blockLength]uint64
+
+// Key derives a key of keyLen bytes from password and salt with Argon2i,
+// time passes over memory KiB, in threads lanes.
+func Key(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	return deriveKey(argon2i, password, salt, nil, nil, time, memory, threads, keyLen)
+}
+
+// IDKey derives a key as Key does, but with Argon2id.
+func IDKey(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	return deriveKey(argon2id, password, salt, nil, nil, time, memory, threads, keyLen)
+}
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+// hashPrime is H', the hash of any length.
+func hashPrime(size uint32, in ...[]byte) []byte {
+	in = append([][]byte{le32(size)}, in...)
+	if size <= 64 {
+		return blake2b(int(size), in...)
+	}
+	v := blake2b(64, in...)
+	out := append(make([]byte, 0, size), v[:32]...)
+	for size-uint32(len(out)) > 64 {
+		v = blake2b(64, v)
+		out = append(out, v[:32]...)
+	}
+	return append(out, blake2b(int(size)-len(out), v)...)
+}
+
+func deriveKey(mode int, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if time < 1 {
+		panic("argon2: time must be at least 1")
+	}
+	if threads < 1 {
+		panic("argon2: threads must be at least 1")
+	}
+	p := uint32(threads)
+	h0 := blake2b(64, le32(p), le32(keyLen), le32(memory), le32(time), le32(version), le32(uint32(mode)),
+		le32(uint32(len(password))), password, le32(uint32(len(salt))), salt,
+		le32(uint32(len(secret))), secret, le32(uint32(len(data))), data)
+
+	memory = memory / (syncPoints * p) * (syncPoints * p)
+	if memory < 2*syncPoints*p {
+		memory = 2 * syncPoints * p
+	}
+	B := make([]block, memory)
+	lanes := memory / p
+	for lane := uint32(0); lane < p; lane++ {
+		for i := uint32(0); i < 2; i++ {
+			b := hashPrime(1024, h0, le32(i), le32(lane))
+			for j := range B[lane*lanes+i] {
+				B[lane*lanes+i][j] = binary.LittleEndian.Uint64(b[j*8:])
+			}
+		}
+	}
+	processBlocks(B, mode, time, memory, p)
+
+	var last block
+	for lane := uint32(0); lane < p; lane++ {
+		for i, v := range B[lane*lanes+lanes-1] {
+			last[i] ^= v
+		}
+	}
+	b := make([]byte, 1024)
+	for i, v := range last {
+		binary.LittleEndian.PutUint64(b[i*8:], v)
+	}
+	return hashPrime(keyLen, b)
+}
+
+func processBlocks(B []block, mode int, time, memory, threads uint32) {
+	lanes := memory / threads
+	segments := lanes / syncPoints
+
+	processSegment := func(n, slice, lane uint32, wg *sync.WaitGroup) {
+		defer wg.Done()
+		var addresses, in, zero block
+		independent := mode == argon2i || (mode == argon2id && n == 0 && slice < syncPoints/2)
+		if independent {
+			in[0], in[1], in[2] = uint64(n), uint64(lane), uint64(slice)
+			in[3], in[4], in[5] = uint64(memory), uint64(time), uint64(mode)
+		}
+		index := uint32(0)
+		if n == 0 && slice == 0 {
+			// The first two blocks are made from the hash.
+			index = 2
+			if independent {
+				in[6]++
+				processBlock(&addresses, &in, &zero, false)
+				processBlock(&addresses, &addresses, &zero, false)
+			}
+		}
+		offset := lane*lanes + slice*segments + index
+		for ; index < segments; index, offset = index+1, offset+1 {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += lanes
+			}
+			var random uint64
+			if independent {
+				if index%blockLength == 0 {
+					in[6]++
+					processBlock(&addresses, &in, &zero, false)
+					processBlock(&addresses, &addresses, &zero, false)
+				}
+				random = addresses[index%blockLength]
+			} else {
+				random = B[prev][0]
+			}
+			ref := indexAlpha(random, lanes, segments, threads, n, slice, lane, index)
+			processBlock(&B[offset], &B[prev], &B[ref], n > 0)
+		}
+	}
+
+	for n := uint32(0); n < time; n++ {
+		for slice := uint32(0); slice < syncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < threads; lane++ {
+				wg.Add(1)
+				go processSegment(n, slice, lane, &wg)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// indexAlpha returns the block to mix into block index of the segment.
+func indexAlpha(rand uint64, lanes, segments, threads, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(rand>>32) % threads
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+	m, s := 3*segments, ((slice+1)%syncPoints)*segments
+	if lane == refLane {
+		m += index
+	}
+	if n == 0 {
+		m, s = slice*segments, 0
+		if slice == 0 || lane == refLane {
+			m += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		m--
+	}
+	x := rand & 0xffffffff
+	x = x * x >> 32
+	x = x * uint64(m) >> 32
+	return refLane*lanes + uint32((uint64(s)+uint64(m)-(x+1))%uint64(lanes))
+}
+
+// processBlock is the compression function G: out is set to, or, if xor,
+// xored with, the mix of in1 and in2.
+func processBlock(out, in1, in2 *block, xor bool) {
+	var t block
+	for i := range t {
+		t[i] = in1[i] ^ in2[i]
+	}
+	for i := 0; i < blockLength; i += 16 {
+		blamka(&t[i], &t[i+1], &t[i+2], &t[i+3], &t[i+4], &t[i+5], &t[i+6], &t[i+7],
+			&t[i+8], &t[i+9], &t[i+10], &t[i+11], &t[i+12], &t[i+13], &t[i+14], &t[i+15])
+	}
+	for i := 0; i < blockLength/8; i += 2 {
+		blamka(&t[i], &t[i+1], &t[16+i], &t[16+i+1], &t[32+i], &t[32+i+1], &t[48+i], &t[48+i+1],
+			&t[64+i], &t[64+i+1], &t[80+i], &t[80+i+1], &t[96+i], &t[96+i+1], &t[112+i], &t[112+i+1])
+	}
+	for i := range t {
+		v := in1[i] ^ in2[i] ^ t[i]
+		if xor {
+			out[i] ^= v
+		} else {
+			out[i] = v
+		}
+	}
+}
+
+// gb is the G of BLAKE2b, with multiplications added, as BlaMka has it.
+func gb(a, b, c, d *uint64) {
+	mul := func(x, y uint64) uint64 { return 2 * uint64(uint32(x)) * uint64(uint32(y)) }
+	*a += *b + mul(*a, *b)
+	*d ^= *a
+	*d = *d>>32 | *d<<32
+	*c += *d + mul(*c, *d)
+	*b ^= *c
+	*b = *b>>24 | *b<<40
+	*a += *b + mul(*a, *b)
+	*d ^= *a
+	*d = *d>>16 | *d<<48
+	*c += *d + mul(*c, *d)
+	*b ^= *c
+	*b = *b>>63 | *b<<1
+}
+
+// blamka is the permutation P, of BLAKE2b's round, on 16 words.
+func blamka(v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 *uint64) {
+	gb(v0, v4, v8, v12)
+	gb(v1, v5, v9, v13)
+	gb(v2, v6, v10, v14)
+	gb(v3, v7, v11, v15)
+	gb(v0, v5, v10, v15)
+	gb(v1, v6, v11, v12)
+	gb(v2, v7, v8, v13)
+	gb(v3, v4, v9, v14)
+}
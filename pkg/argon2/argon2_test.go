@@ -0,0 +1,56 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBlake2b(t *testing.T) {
+	for _, tt := range []struct {
+		size int
+		in   string
+		want string
+	}{
+		{64, "", "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+		{64, "abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{32, "abc", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+	} {
+		if got := blake2b(tt.size, []byte(tt.in)); hex.EncodeToString(got) != tt.want {
+			t.Errorf("blake2b(%d, %q): got %x, want %v", tt.size, tt.in, got, tt.want)
+		}
+	}
+}
+
+// The test vectors of RFC 9106, section 5.
+func TestRFC9106(t *testing.T) {
+	password := bytes.Repeat([]byte{1}, 32)
+	salt := bytes.Repeat([]byte{2}, 16)
+	secret := bytes.Repeat([]byte{3}, 8)
+	data := bytes.Repeat([]byte{4}, 12)
+	for _, tt := range []struct {
+		mode int
+		want string
+	}{
+		{argon2d, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"},
+		{argon2i, "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8"},
+		{argon2id, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"},
+	} {
+		got := deriveKey(tt.mode, password, salt, secret, data, 3, 32, 4, 32)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("type %d: got %x, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestLongKey(t *testing.T) {
+	// Keys of more than 64 bytes are made by hashing 64 bytes at a time.
+	k := IDKey([]byte("password"), []byte("somesalt"), 1, 64, 1, 100)
+	if len(k) != 100 || !bytes.Equal(IDKey([]byte("password"), []byte("somesalt"), 1, 64, 1, 100), k) {
+		t.Errorf("IDKey of 100 bytes: got %x", k)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cmdline reads the kernel command line, e.g. for the uroot.
+// directives which change what init and uinit do.
+package cmdline
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// procCmdline is where the kernel command line is read from.
+var procCmdline = "/proc/cmdline"
+
+// Fields splits s into words at spaces, as the kernel does: spaces
+// between double quotes do not split, and the quotes are removed.
+func Fields(s string) []string {
+	var (
+		words  []string
+		w      []byte
+		inWord bool
+		quoted bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (c == ' ' || c == '\t' || c == '\n'):
+			if inWord {
+				words = append(words, string(w))
+			}
+			w, inWord = w[:0], false
+		default:
+			w = append(w, c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, string(w))
+	}
+	return words
+}
+
+// Parse returns the parameters of the command line s, by name. Those with
+// no value, e.g. quiet, are there with "". If a name is there more than
+// once, the last one counts.
+func Parse(s string) map[string]string {
+	m := make(map[string]string)
+	for _, w := range Fields(s) {
+		kv := strings.SplitN(w, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		m[kv[0]] = kv[1]
+	}
+	return m
+}
+
+// Read returns the parameters of the kernel command line, by name.
+func Read() (map[string]string, error) {
+	b, err := ioutil.ReadFile(procCmdline)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b)), nil
+}
+
+// Value returns the value of the parameter name on the kernel command line,
+// and whether it is there. If the command line can not be read, e.g. as
+// /proc is not mounted, no parameters are there.
+func Value(name string) (string, bool) {
+	m, err := Read()
+	if err != nil {
+		return "", false
+	}
+	v, ok := m[name]
+	return v, ok
+}
+
+// ContainsFlag returns whether the parameter name is on the kernel
+// command line.
+func ContainsFlag(name string) bool {
+	_, ok := Value(name)
+	return ok
+}
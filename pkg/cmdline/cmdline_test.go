@@ -0,0 +1,67 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  \n", nil},
+		{"quiet console=ttyS0,115200\n", []string{"quiet", "console=ttyS0,115200"}},
+		{`uroot.exec="echo  hi" x`, []string{"uroot.exec=echo  hi", "x"}},
+		{`a="" "b c"d`, []string{"a=", "b cd"}},
+		{`""`, []string{""}},
+	} {
+		if got := Fields(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Fields(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	f, err := ioutil.TempFile("", "cmdline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`BOOT_IMAGE=/vmlinuz ro uroot.initflags="-v -test" x=1 x=2 empty=` + "\n")
+	f.Close()
+	defer func(p string) { procCmdline = p }(procCmdline)
+	procCmdline = f.Name()
+
+	for _, tt := range []struct {
+		name string
+		v    string
+		ok   bool
+	}{
+		{"ro", "", true},
+		{"uroot.initflags", "-v -test", true},
+		{"x", "2", true},
+		{"empty", "", true},
+		{"BOOT_IMAGE", "/vmlinuz", true},
+		{"missing", "", false},
+	} {
+		if v, ok := Value(tt.name); v != tt.v || ok != tt.ok {
+			t.Errorf("Value(%q): got %q, %v, want %q, %v", tt.name, v, ok, tt.v, tt.ok)
+		}
+		if ok := ContainsFlag(tt.name); ok != tt.ok {
+			t.Errorf("ContainsFlag(%q): got %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+
+	procCmdline = "/no/such/file"
+	if v, ok := Value("ro"); ok {
+		t.Errorf("Value(%q) with no command line: got %q, true, want false", "ro", v)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// bin implements the old binary cpio file format. Archives are written
+// little-endian; either byte order is read.
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+const binMagic = 070707
+
+// The 32-bit MTime and FileSize are two 16-bit words, the most significant
+// first, whatever the byte order.
+type header struct {
+	Magic    uint16
+	Dev      uint16
+	Ino      uint16
+	Mode     uint16
+	UID      uint16
+	GID      uint16
+	NLink    uint16
+	Rdev     uint16
+	MTime    [2]uint16
+	NameSize uint16
+	FileSize [2]uint16
+}
+
+func split(n uint64) [2]uint16 {
+	return [2]uint16{uint16(n >> 16), uint16(n)}
+}
+
+func join(n [2]uint16) uint64 {
+	return uint64(n[0])<<16 | uint64(n[1])
+}
+
+func headerFromInfo(i cpio.Info) (header, error) {
+	h := header{
+		Magic:    binMagic,
+		Dev:      uint16(i.Major<<8 | i.Minor),
+		Ino:      uint16(i.Ino),
+		Mode:     uint16(i.Mode),
+		UID:      uint16(i.UID),
+		GID:      uint16(i.GID),
+		NLink:    uint16(i.NLink),
+		Rdev:     uint16(i.Rmajor<<8 | i.Rminor),
+		MTime:    split(i.MTime),
+		NameSize: uint16(len(i.Name) + 1),
+		FileSize: split(i.FileSize),
+	}
+	// Dev and Ino only tell hard links apart, so they may be truncated.
+	switch {
+	case i.UID > 0xffff, i.GID > 0xffff:
+		return h, fmt.Errorf("%q: uid %d or gid %d does not fit in a binary header", i.Name, i.UID, i.GID)
+	case i.NLink > 0xffff, i.Mode > 0xffff:
+		return h, fmt.Errorf("%q: nlink %d or mode %#o does not fit in a binary header", i.Name, i.NLink, i.Mode)
+	case i.Rmajor > 0xff, i.Rminor > 0xff:
+		return h, fmt.Errorf("%q: device %d,%d does not fit in a binary header", i.Name, i.Rmajor, i.Rminor)
+	case len(i.Name)+1 > 0xffff:
+		return h, fmt.Errorf("%q: name is too long for a binary header", i.Name)
+	case i.MTime > 0xffffffff, i.FileSize > 0xffffffff:
+		return h, fmt.Errorf("%q: mtime %d or size %d does not fit in a binary header", i.Name, i.MTime, i.FileSize)
+	}
+	return h, nil
+}
+
+func (h header) Info() cpio.Info {
+	return cpio.Info{
+		Major:    uint64(h.Dev >> 8),
+		Minor:    uint64(h.Dev & 0xff),
+		Ino:      uint64(h.Ino),
+		Mode:     uint64(h.Mode),
+		UID:      uint64(h.UID),
+		GID:      uint64(h.GID),
+		NLink:    uint64(h.NLink),
+		Rmajor:   uint64(h.Rdev >> 8),
+		Rminor:   uint64(h.Rdev & 0xff),
+		MTime:    join(h.MTime),
+		FileSize: join(h.FileSize),
+	}
+}
+
+type format struct{}
+
+// round2 returns the next multiple of 2 close to n.
+func round2(n int64) int64 {
+	return (n + 1) &^ 0x1
+}
+
+type writer struct {
+	w   io.Writer
+	pos int64
+}
+
+func (f format) Writer(w io.Writer) cpio.RecordWriter {
+	return &writer{w: w}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	if err != nil {
+		return 0, err
+	}
+	w.pos += int64(n)
+	return n, nil
+}
+
+func (w *writer) pad() error {
+	if w.pos != round2(w.pos) {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRecord writes a binary cpio record. The name and the data are
+// padded to 2 byte alignment.
+func (w *writer) WriteRecord(f cpio.Record) error {
+	if f.ReadCloser == nil {
+		f.FileSize = 0
+	}
+	hdr, err := headerFromInfo(f.Info)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, hdr); err != nil {
+		return err
+	}
+	if _, err := w.Write(append([]byte(f.Name), 0)); err != nil {
+		return err
+	}
+	if err := w.pad(); err != nil {
+		return err
+	}
+
+	// Some files do not have any content.
+	if f.ReadCloser == nil {
+		return nil
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return w.pad()
+}
+
+type reader struct {
+	r   io.ReaderAt
+	pos int64
+}
+
+func (f format) Reader(r io.ReaderAt) cpio.RecordReader {
+	return &reader{r: r}
+}
+
+func (r *reader) Read(p []byte) error {
+	n, err := r.r.ReadAt(p, r.pos)
+	if err == io.EOF && n == 0 {
+		return io.EOF
+	}
+	if n != len(p) {
+		return fmt.Errorf("ReadAt(pos = %d): got %d, want %d bytes; error %v", r.pos, n, len(p), err)
+	}
+	r.pos += int64(n)
+	return nil
+}
+
+func (r *reader) ReadAligned(p []byte) error {
+	err := r.Read(p)
+	r.pos = round2(r.pos)
+	return err
+}
+
+func (r *reader) ReadRecord() (cpio.Record, error) {
+	cpio.Debug("Next record: pos is %d\n", r.pos)
+
+	buf := make([]byte, binary.Size(header{}))
+	if err := r.Read(buf); err != nil {
+		return cpio.Record{}, err
+	}
+
+	// The magic tells us the byte order.
+	var order binary.ByteOrder = binary.LittleEndian
+	switch binary.LittleEndian.Uint16(buf) {
+	case binMagic:
+	case binMagic>>8 | binMagic&0xff<<8:
+		order = binary.BigEndian
+	default:
+		return cpio.Record{}, fmt.Errorf("reader: magic got %#o, want %#o", binary.LittleEndian.Uint16(buf), binMagic)
+	}
+	var hdr header
+	if err := binary.Read(bytes.NewReader(buf), order, &hdr); err != nil {
+		return cpio.Record{}, err
+	}
+	cpio.Debug("Decoded header is %v\n", hdr)
+
+	if hdr.NameSize == 0 {
+		return cpio.Record{}, fmt.Errorf("reader: name size is 0")
+	}
+	nameBuf := make([]byte, hdr.NameSize)
+	if err := r.ReadAligned(nameBuf); err != nil {
+		return cpio.Record{}, err
+	}
+
+	info := hdr.Info()
+	info.Name = string(nameBuf[:hdr.NameSize-1])
+
+	content := io.NewSectionReader(r.r, r.pos, int64(info.FileSize))
+	r.pos = round2(r.pos + int64(info.FileSize))
+	return cpio.Record{
+		Info:       info,
+		ReadCloser: cpio.NewReadCloser(content),
+	}, nil
+}
+
+func init() {
+	cpio.AddFormat("bin", format{})
+}
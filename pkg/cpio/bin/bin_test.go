@@ -0,0 +1,100 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"syscall"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+func TestWriteRead(t *testing.T) {
+	f, err := cpio.Format("bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []byte("LANAAAAAAAAAA")
+	rec := cpio.StaticRecord(contents, cpio.Info{
+		Ino:    1,
+		Mode:   syscall.S_IFREG | 2,
+		UID:    3,
+		GID:    4,
+		NLink:  5,
+		MTime:  1485152330,
+		Major:  8,
+		Minor:  9,
+		Rmajor: 10,
+		Rminor: 11,
+		Name:   "foobar",
+	})
+
+	buf := &bytes.Buffer{}
+	w := f.Writer(buf)
+	if err := w.WriteRecord(rec); err != nil {
+		t.Errorf("Could not write record %q: %v", rec.Name, err)
+	}
+	if err := w.WriteTrailer(); err != nil {
+		t.Errorf("Could not write trailer: %v", err)
+	}
+	if buf.Len()%2 != 0 {
+		t.Errorf("archive is %d bytes, want an even number", buf.Len())
+	}
+
+	r := f.Reader(bytes.NewReader(buf.Bytes()))
+	rec2, err := r.ReadRecord()
+	if err != nil {
+		t.Fatalf("Could not read record: %v", err)
+	}
+	if rec2.Info != rec.Info {
+		t.Errorf("Records not equal:\n%#v\n%#v", rec.Info, rec2.Info)
+	}
+	contents2, err := ioutil.ReadAll(rec2)
+	if err != nil {
+		t.Errorf("Could not read %q: %v", rec2.Name, err)
+	}
+	if !bytes.Equal(contents2, contents) {
+		t.Errorf("Read(%q) = %s, want %s", rec2.Name, contents2, contents)
+	}
+}
+
+// TestBigEndian reads a record written on a big-endian machine.
+func TestBigEndian(t *testing.T) {
+	f, err := cpio.Format("bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := []byte{
+		0x71, 0xc7, // magic
+		0x01, 0x02, // dev
+		0x00, 0x07, // ino
+		0x81, 0xa4, // mode
+		0x00, 0x01, // uid
+		0x00, 0x02, // gid
+		0x00, 0x01, // nlink
+		0x00, 0x00, // rdev
+		0x58, 0x85, 0xa0, 0x4a, // mtime
+		0x00, 0x02, // namesize
+		0x00, 0x00, 0x00, 0x03, // filesize
+		'x', 0,
+		'h', 'i', '\n', 0,
+	}
+	want := "x: Ino 7 Mode 0100644 UID 1 GID 2 NLink 1 MTime 2017-01-23 06:18:50 +0000 UTC FileSize 3 Major 1 Minor 2 Rmajor 0 Rminor 0"
+
+	rec, err := f.Reader(bytes.NewReader(b)).ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.String() != want {
+		t.Errorf("got \n%s, want \n%s", rec, want)
+	}
+	if c, err := ioutil.ReadAll(rec); err != nil || string(c) != "hi\n" {
+		t.Errorf("Read(%q) = %q, %v, want %q, nil", rec.Name, c, err, "hi\n")
+	}
+}
@@ -43,7 +43,13 @@ func (a Archiver) Reader(r io.ReaderAt) Reader {
 }
 
 func (a Archiver) Writer(w io.Writer) Writer {
-	return Writer{rw: a.RecordFormat.Writer(w), alreadyWritten: make(map[string]struct{})}
+	return NewWriter(a.RecordFormat.Writer(w))
+}
+
+// NewWriter returns a Writer which writes records to rw, which need not
+// write a cpio archive.
+func NewWriter(rw RecordWriter) Writer {
+	return Writer{rw: rw, alreadyWritten: make(map[string]struct{})}
 }
 
 type Reader struct {
@@ -101,15 +101,7 @@ func CreateFile(f Record) error {
 		return fmt.Errorf("%q: type %v: cannot create IPC endpoints", f.Name, m)
 
 	case os.FileMode(0):
-		nf, err := os.Create(f.Name)
-		if err != nil {
-			return err
-		}
-		defer nf.Close()
-		if _, err := io.Copy(nf, f); err != nil {
-			return err
-		}
-		return setModes(f)
+		return writeFile(f)
 
 	case os.ModeDir:
 		if err := os.MkdirAll(f.Name, os.FileMode(perm(f))); err != nil {
@@ -141,6 +133,61 @@ func CreateFile(f Record) error {
 	}
 }
 
+func writeFile(f Record) error {
+	nf, err := os.Create(f.Name)
+	if err != nil {
+		return err
+	}
+	defer nf.Close()
+	if _, err := io.Copy(nf, f); err != nil {
+		return err
+	}
+	return setModes(f)
+}
+
+// An Extractor creates files from records like CreateFile, but files which
+// share an inode in the archive are hard links to each other on disk.
+type Extractor struct {
+	inodes map[devInode]string
+}
+
+// NewExtractor returns a new Extractor.
+func NewExtractor() *Extractor {
+	return &Extractor{inodes: make(map[devInode]string)}
+}
+
+// CreateFile creates the file of f, or links it to one created before.
+func (e *Extractor) CreateFile(f Record) error {
+	m, err := linuxModeToMode(f.Mode)
+	if err != nil {
+		return err
+	}
+	if m != os.FileMode(0) || f.NLink < 2 {
+		return CreateFile(f)
+	}
+
+	d := devInode{dev: f.Major<<8 | f.Minor, ino: f.Ino}
+	old, ok := e.inodes[d]
+	if !ok {
+		e.inodes[d] = f.Name
+		return CreateFile(f)
+	}
+	if dir, _ := filepath.Split(f.Name); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.Link(old, f.Name); err != nil {
+		return err
+	}
+	// newc only stores the data with the last link; the others are
+	// empty.
+	if f.FileSize == 0 {
+		return nil
+	}
+	return writeFile(f)
+}
+
 // Inumber and devnumbers are unique to Unix-like
 // operating systems. You can not uniquely disambiguate a file in a
 // Unix system with just an inumber, you need a device number too.
@@ -0,0 +1,56 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestExtractorHardLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	info := Info{
+		Ino:   7,
+		Mode:  syscall.S_IFREG | 0644,
+		UID:   uint64(os.Getuid()),
+		GID:   uint64(os.Getgid()),
+		NLink: 2,
+	}
+	// As in newc, only the last link has the data.
+	a, b := info, info
+	a.Name = filepath.Join(dir, "a")
+	b.Name = filepath.Join(dir, "sub", "b")
+
+	e := NewExtractor()
+	if err := e.CreateFile(StaticRecord(nil, a)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CreateFile(StaticRecord([]byte("data"), b)); err != nil {
+		t.Fatal(err)
+	}
+
+	fa, err := os.Stat(a.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb, err := os.Stat(b.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(fa, fb) {
+		t.Errorf("%q and %q are not the same file", a.Name, b.Name)
+	}
+	if c, err := ioutil.ReadFile(a.Name); err != nil || string(c) != "data" {
+		t.Errorf("ReadFile(%q) = %q, %v, want %q, nil", a.Name, c, err, "data")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// odc implements the old portable ASCII cpio file format, as in POSIX.1.
+package odc
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+const (
+	odcMagic = "070707"
+	magicLen = 6
+)
+
+// Each header field is an octal number of the given width, in this order,
+// following the magic.
+var fields = []struct {
+	name  string
+	width int
+}{
+	{"dev", 6},
+	{"ino", 6},
+	{"mode", 6},
+	{"uid", 6},
+	{"gid", 6},
+	{"nlink", 6},
+	{"rdev", 6},
+	{"mtime", 11},
+	{"namesize", 6},
+	{"filesize", 11},
+}
+
+// headerLen is the length of a header, with the magic.
+const headerLen = magicLen + 6*8 + 11*2
+
+func headerFromInfo(i cpio.Info) []uint64 {
+	return []uint64{
+		i.Major<<8 | i.Minor,
+		i.Ino,
+		i.Mode,
+		i.UID,
+		i.GID,
+		i.NLink,
+		i.Rmajor<<8 | i.Rminor,
+		i.MTime,
+		uint64(len(i.Name)) + 1,
+		i.FileSize,
+	}
+}
+
+func infoFromHeader(h []uint64) cpio.Info {
+	return cpio.Info{
+		Major:    h[0] >> 8,
+		Minor:    h[0] & 0xff,
+		Ino:      h[1],
+		Mode:     h[2],
+		UID:      h[3],
+		GID:      h[4],
+		NLink:    h[5],
+		Rmajor:   h[6] >> 8,
+		Rminor:   h[6] & 0xff,
+		MTime:    h[7],
+		FileSize: h[9],
+	}
+}
+
+type format struct{}
+
+type writer struct {
+	w io.Writer
+}
+
+func (f format) Writer(w io.Writer) cpio.RecordWriter {
+	return &writer{w: w}
+}
+
+// WriteRecord writes an odc cpio record. There is no padding in this
+// format.
+func (w *writer) WriteRecord(f cpio.Record) error {
+	if f.ReadCloser == nil {
+		f.FileSize = 0
+	}
+	hdr := odcMagic
+	for i, v := range headerFromInfo(f.Info) {
+		s := strconv.FormatUint(v, 8)
+		if len(s) > fields[i].width {
+			return fmt.Errorf("%q: %s %d does not fit in an odc header", f.Name, fields[i].name, v)
+		}
+		hdr += fmt.Sprintf("%0*s", fields[i].width, s)
+	}
+	if _, err := io.WriteString(w.w, hdr+f.Name+"\x00"); err != nil {
+		return err
+	}
+
+	// Some files do not have any content.
+	if f.ReadCloser == nil {
+		return nil
+	}
+	if _, err := io.Copy(w.w, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+type reader struct {
+	r   io.ReaderAt
+	pos int64
+}
+
+func (f format) Reader(r io.ReaderAt) cpio.RecordReader {
+	return &reader{r: r}
+}
+
+func (r *reader) Read(p []byte) error {
+	n, err := r.r.ReadAt(p, r.pos)
+	if err == io.EOF && n == 0 {
+		return io.EOF
+	}
+	if n != len(p) {
+		return fmt.Errorf("ReadAt(pos = %d): got %d, want %d bytes; error %v", r.pos, n, len(p), err)
+	}
+	r.pos += int64(n)
+	return nil
+}
+
+func (r *reader) ReadRecord() (cpio.Record, error) {
+	cpio.Debug("Next record: pos is %d\n", r.pos)
+
+	buf := make([]byte, headerLen)
+	if err := r.Read(buf); err != nil {
+		return cpio.Record{}, err
+	}
+	if magic := string(buf[:magicLen]); magic != odcMagic {
+		return cpio.Record{}, fmt.Errorf("reader: magic got %q, want %q", magic, odcMagic)
+	}
+
+	var h []uint64
+	b := buf[magicLen:]
+	for _, f := range fields {
+		v, err := strconv.ParseUint(string(b[:f.width]), 8, 64)
+		if err != nil {
+			return cpio.Record{}, fmt.Errorf("reader: bad %s: %v", f.name, err)
+		}
+		h = append(h, v)
+		b = b[f.width:]
+	}
+	cpio.Debug("Decoded header is %v\n", h)
+
+	namesize := h[8]
+	if namesize == 0 {
+		return cpio.Record{}, fmt.Errorf("reader: name size is 0")
+	}
+	nameBuf := make([]byte, namesize)
+	if err := r.Read(nameBuf); err != nil {
+		return cpio.Record{}, err
+	}
+
+	info := infoFromHeader(h)
+	info.Name = string(nameBuf[:namesize-1])
+
+	content := io.NewSectionReader(r.r, r.pos, int64(info.FileSize))
+	r.pos += int64(info.FileSize)
+	return cpio.Record{
+		Info:       info,
+		ReadCloser: cpio.NewReadCloser(content),
+	}, nil
+}
+
+func init() {
+	cpio.AddFormat("odc", format{})
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odc
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"syscall"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+// testCPIO is an odc archive of a directory and a file in it.
+var testCPIO = []byte("070707000401000002040755000000000000000002000000" +
+	"1304132011200000200000000000" + "a\x00" +
+	"070707000401000003100644000001000001000001000000" +
+	"1304132011200000400000000003" + "a/b\x00" + "hi\n" +
+	"070707000000000000000000000000000000000001000000" +
+	"0000000000000001300000000000" + "TRAILER!!!\x00")
+
+var testResult = []string{
+	"a: Ino 2 Mode 040755 UID 0 GID 0 NLink 2 MTime 2017-01-23 06:18:50 +0000 UTC FileSize 0 Major 1 Minor 1 Rmajor 0 Rminor 0",
+	"a/b: Ino 3 Mode 0100644 UID 1 GID 1 NLink 1 MTime 2017-01-23 06:18:50 +0000 UTC FileSize 3 Major 1 Minor 1 Rmajor 0 Rminor 0",
+}
+
+func TestSimple(t *testing.T) {
+	f, err := cpio.Format("odc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := f.Reader(bytes.NewReader(testCPIO))
+	files, err := r.ReadRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(testResult) {
+		t.Fatalf("got %d records, want %d", len(files), len(testResult))
+	}
+	for i, f := range files {
+		if f.String() != testResult[i] {
+			t.Errorf("Value %d: got \n%s, want \n%s", i, f.String(), testResult[i])
+		}
+	}
+	if b, err := ioutil.ReadAll(files[1]); err != nil || string(b) != "hi\n" {
+		t.Errorf("Read(%q) = %q, %v, want %q, nil", files[1].Name, b, err, "hi\n")
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	f, err := cpio.Format("odc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []byte("LANAAAAAAAAAA")
+	rec := cpio.StaticRecord(contents, cpio.Info{
+		Ino:    1,
+		Mode:   syscall.S_IFREG | 2,
+		UID:    3,
+		GID:    4,
+		NLink:  5,
+		MTime:  6,
+		Major:  8,
+		Minor:  9,
+		Rmajor: 10,
+		Rminor: 11,
+		Name:   "foobar",
+	})
+
+	buf := &bytes.Buffer{}
+	w := f.Writer(buf)
+	if err := w.WriteRecord(rec); err != nil {
+		t.Errorf("Could not write record %q: %v", rec.Name, err)
+	}
+	if err := w.WriteTrailer(); err != nil {
+		t.Errorf("Could not write trailer: %v", err)
+	}
+
+	r := f.Reader(bytes.NewReader(buf.Bytes()))
+	rec2, err := r.ReadRecord()
+	if err != nil {
+		t.Fatalf("Could not read record: %v", err)
+	}
+	if rec2.Info != rec.Info {
+		t.Errorf("Records not equal:\n%#v\n%#v", rec.Info, rec2.Info)
+	}
+	contents2, err := ioutil.ReadAll(rec2)
+	if err != nil {
+		t.Errorf("Could not read %q: %v", rec2.Name, err)
+	}
+	if !bytes.Equal(contents2, contents) {
+		t.Errorf("Read(%q) = %s, want %s", rec2.Name, contents2, contents)
+	}
+	if _, err := r.ReadRecord(); err != io.EOF {
+		t.Errorf("ReadRecord after the trailer: got %v, want EOF", err)
+	}
+}
+
+func TestTooBig(t *testing.T) {
+	f, err := cpio.Format("odc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := cpio.StaticRecord(nil, cpio.Info{UID: 1 << 20, Name: "big"})
+	if err := f.Writer(&bytes.Buffer{}).WriteRecord(rec); err == nil {
+		t.Errorf("WriteRecord(%v): got nil, want error", rec.Info)
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cpuid runs the CPUID instruction of x86 CPUs, and decodes what it
+// says: the vendor, family, model and stepping of the CPU, its features,
+// and its caches.
+package cpuid
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// Regs are the registers CPUID returns.
+type Regs struct {
+	EAX, EBX, ECX, EDX uint32
+}
+
+// A Func runs CPUID with leaf in EAX and sub, the subleaf, in ECX. Native
+// is one; tests use others.
+type Func func(leaf, sub uint32) Regs
+
+// The leaves this package knows.
+const (
+	leafVendor     = 0
+	leafVersion    = 1
+	leafCache      = 4
+	leafExtended   = 7
+	leafTopology   = 0xb
+	leafTopologyV2 = 0x1f
+	leafExtMax     = 0x80000000
+	leafExtFeature = 0x80000001
+	leafBrand      = 0x80000002
+	leafAMDCache   = 0x8000001d
+)
+
+// A Cache is a cache of a CPU.
+type Cache struct {
+	Level int
+	// Type is "Data", "Instruction" or "Unified".
+	Type     string
+	Size     int
+	Ways     int
+	LineSize int
+	Sets     int
+	// SharedBy is the most threads which share it.
+	SharedBy int
+}
+
+// Name returns the name of c, such as L1d.
+func (c Cache) Name() string {
+	n := "L" + string('0'+byte(c.Level))
+	switch c.Type {
+	case "Data":
+		n += "d"
+	case "Instruction":
+		n += "i"
+	}
+	return n
+}
+
+// Info is what CPUID says of a CPU.
+type Info struct {
+	Vendor string
+	Brand  string
+	// MaxLeaf and MaxExtLeaf are the highest leaves there are.
+	MaxLeaf, MaxExtLeaf     uint32
+	Family, Model, Stepping uint32
+	Features                []string
+	Caches                  []Cache
+}
+
+// flags are the names of the feature bits of a register, as Linux names
+// them in /proc/cpuinfo.
+type flags map[uint]string
+
+var (
+	// Leaf 1, EDX and ECX.
+	features1EDX = flags{
+		0: "fpu", 1: "vme", 2: "de", 3: "pse", 4: "tsc", 5: "msr", 6: "pae", 7: "mce",
+		8: "cx8", 9: "apic", 11: "sep", 12: "mtrr", 13: "pge", 14: "mca", 15: "cmov",
+		16: "pat", 17: "pse36", 18: "pn", 19: "clflush", 21: "ds", 22: "acpi", 23: "mmx",
+		24: "fxsr", 25: "sse", 26: "sse2", 27: "ss", 28: "ht", 29: "tm", 31: "pbe",
+	}
+	features1ECX = flags{
+		0: "pni", 1: "pclmulqdq", 2: "dtes64", 3: "monitor", 4: "ds_cpl", 5: "vmx", 6: "smx",
+		7: "est", 8: "tm2", 9: "ssse3", 10: "cid", 11: "sdbg", 12: "fma", 13: "cx16",
+		14: "xtpr", 15: "pdcm", 17: "pcid", 18: "dca", 19: "sse4_1", 20: "sse4_2",
+		21: "x2apic", 22: "movbe", 23: "popcnt", 24: "tsc_deadline_timer", 25: "aes",
+		26: "xsave", 27: "osxsave", 28: "avx", 29: "f16c", 30: "rdrand", 31: "hypervisor",
+	}
+	// Leaf 7, subleaf 0, EBX, ECX and EDX.
+	features7EBX = flags{
+		0: "fsgsbase", 1: "tsc_adjust", 3: "bmi1", 4: "hle", 5: "avx2", 7: "smep",
+		8: "bmi2", 9: "erms", 10: "invpcid", 11: "rtm", 14: "mpx", 16: "avx512f",
+		17: "avx512dq", 18: "rdseed", 19: "adx", 20: "smap", 21: "avx512ifma",
+		23: "clflushopt", 24: "clwb", 25: "intel_pt", 26: "avx512pf", 27: "avx512er",
+		28: "avx512cd", 29: "sha_ni", 30: "avx512bw", 31: "avx512vl",
+	}
+	features7ECX = flags{
+		1: "avx512vbmi", 2: "umip", 3: "pku", 4: "ospke", 5: "waitpkg", 6: "avx512_vbmi2",
+		8: "gfni", 9: "vaes", 10: "vpclmulqdq", 11: "avx512_vnni", 12: "avx512_bitalg",
+		14: "avx512_vpopcntdq", 16: "la57", 22: "rdpid", 25: "cldemote", 27: "movdiri",
+		28: "movdir64b",
+	}
+	features7EDX = flags{
+		2: "avx512_4vnniw", 3: "avx512_4fmaps", 4: "fsrm", 8: "avx512_vp2intersect",
+		10: "md_clear", 14: "serialize", 16: "tsxldtrk", 18: "pconfig", 20: "ibt",
+		22: "amx_bf16", 24: "amx_tile", 25: "amx_int8", 26: "spec_ctrl", 27: "intel_stibp",
+		28: "flush_l1d", 29: "arch_capabilities", 31: "spec_ctrl_ssbd",
+	}
+	// Leaf 0x80000001, ECX and EDX.
+	featuresExtECX = flags{
+		0: "lahf_lm", 1: "cmp_legacy", 2: "svm", 3: "extapic", 4: "cr8_legacy", 5: "abm",
+		6: "sse4a", 7: "misalignsse", 8: "3dnowprefetch", 9: "osvw", 10: "ibs", 11: "xop",
+		12: "skinit", 13: "wdt", 15: "lwp", 16: "fma4", 17: "tce", 19: "nodeid_msr",
+		21: "tbm", 22: "topoext", 23: "perfctr_core", 24: "perfctr_nb", 26: "bpext",
+		27: "ptsc", 28: "perfctr_llc", 29: "mwaitx",
+	}
+	featuresExtEDX = flags{
+		11: "syscall", 19: "mp", 20: "nx", 22: "mmxext", 25: "fxsr_opt", 26: "pdpe1gb",
+		27: "rdtscp", 29: "lm", 30: "3dnowext", 31: "3dnow",
+	}
+)
+
+// names appends the names of the bits set in r to n, in the order of the
+// bits.
+func (f flags) names(n []string, r uint32) []string {
+	for b := uint(0); b < 32; b++ {
+		if name, ok := f[b]; ok && r&(1<<b) != 0 {
+			n = append(n, name)
+		}
+	}
+	return n
+}
+
+// str returns the bytes of the registers rs as a string.
+func str(rs ...uint32) string {
+	b := make([]byte, 4*len(rs))
+	for i, r := range rs {
+		binary.LittleEndian.PutUint32(b[4*i:], r)
+	}
+	return string(b)
+}
+
+// caches decodes the caches from leaf, which is 4 or 0x8000001d; they are
+// the same, with a subleaf for each cache.
+func caches(f Func, leaf uint32) []Cache {
+	var cs []Cache
+	// There are not more than a few; the limit is in case of junk.
+	for sub := uint32(0); sub < 16; sub++ {
+		r := f(leaf, sub)
+		t := r.EAX & 0x1f
+		if t == 0 {
+			break
+		}
+		c := Cache{
+			Level:    int(r.EAX >> 5 & 7),
+			Type:     [...]string{"", "Data", "Instruction", "Unified"}[t&3],
+			SharedBy: int(r.EAX>>14&0xfff) + 1,
+			Ways:     int(r.EBX>>22&0x3ff) + 1,
+			LineSize: int(r.EBX&0xfff) + 1,
+			Sets:     int(r.ECX) + 1,
+		}
+		partitions := int(r.EBX>>12&0x3ff) + 1
+		c.Size = c.Ways * partitions * c.LineSize * c.Sets
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// Decode decodes what f says of the CPU.
+func Decode(f Func) *Info {
+	r := f(leafVendor, 0)
+	i := &Info{
+		MaxLeaf: r.EAX,
+		Vendor:  str(r.EBX, r.EDX, r.ECX),
+	}
+	if r := f(leafExtMax, 0); r.EAX > leafExtMax && r.EAX < leafExtMax+0x10000 {
+		i.MaxExtLeaf = r.EAX
+	}
+
+	if i.MaxLeaf >= leafVersion {
+		r := f(leafVersion, 0)
+		i.Stepping = r.EAX & 0xf
+		i.Model = r.EAX >> 4 & 0xf
+		i.Family = r.EAX >> 8 & 0xf
+		if i.Family == 0xf {
+			i.Family += r.EAX >> 20 & 0xff
+		}
+		if i.Family == 6 || i.Family >= 0xf {
+			i.Model += (r.EAX >> 16 & 0xf) << 4
+		}
+		i.Features = features1EDX.names(i.Features, r.EDX)
+		i.Features = features1ECX.names(i.Features, r.ECX)
+	}
+	if i.MaxLeaf >= leafExtended {
+		r := f(leafExtended, 0)
+		i.Features = features7EBX.names(i.Features, r.EBX)
+		i.Features = features7ECX.names(i.Features, r.ECX)
+		i.Features = features7EDX.names(i.Features, r.EDX)
+	}
+	var ext Regs
+	if i.MaxExtLeaf >= leafExtFeature {
+		ext = f(leafExtFeature, 0)
+		i.Features = featuresExtEDX.names(i.Features, ext.EDX)
+		i.Features = featuresExtECX.names(i.Features, ext.ECX)
+	}
+	if i.MaxExtLeaf >= leafBrand+2 {
+		var rs []uint32
+		for l := uint32(leafBrand); l <= leafBrand+2; l++ {
+			r := f(l, 0)
+			rs = append(rs, r.EAX, r.EBX, r.ECX, r.EDX)
+		}
+		i.Brand = strings.TrimSpace(strings.TrimRight(str(rs...), "\x00"))
+	}
+
+	const topoext = 1 << 22
+	switch {
+	case i.Vendor == "GenuineIntel" && i.MaxLeaf >= leafCache:
+		i.Caches = caches(f, leafCache)
+	case ext.ECX&topoext != 0 && i.MaxExtLeaf >= leafAMDCache:
+		i.Caches = caches(f, leafAMDCache)
+	}
+	return i
+}
+
+// A Leaf is what CPUID returns for a leaf and subleaf.
+type Leaf struct {
+	Leaf, Sub uint32
+	Regs
+}
+
+// subleaves returns how many subleaves to dump of leaf, which is r for
+// subleaf 0. Those it does not know are dumped as one.
+func subleaves(f Func, leaf uint32, r Regs) uint32 {
+	const max = 64
+	n := uint32(1)
+	switch leaf {
+	case leafExtended:
+		n = r.EAX + 1
+	case leafCache, leafAMDCache:
+		for ; n < max && f(leaf, n).EAX&0x1f != 0; n++ {
+		}
+	case leafTopology, leafTopologyV2:
+		// The last level has a type, ECX bits 15 to 8, of 0.
+		for ; n < max && f(leaf, n).ECX>>8&0xff != 0; n++ {
+		}
+		n++
+	}
+	if n > max {
+		n = max
+	}
+	return n
+}
+
+// Dump runs all the leaves, and their subleaves, and returns what they
+// return.
+func Dump(f Func) []Leaf {
+	var l []Leaf
+	for _, base := range []uint32{0, leafExtMax} {
+		max := f(base, 0).EAX
+		if max < base || max > base+0xff {
+			continue
+		}
+		for leaf := base; leaf <= max; leaf++ {
+			r := f(leaf, 0)
+			l = append(l, Leaf{leaf, 0, r})
+			n := subleaves(f, leaf, r)
+			for sub := uint32(1); sub < n; sub++ {
+				l = append(l, Leaf{leaf, sub, f(leaf, sub)})
+			}
+		}
+	}
+	return l
+}
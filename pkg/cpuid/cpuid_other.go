@@ -0,0 +1,16 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !386 && !amd64
+// +build !386,!amd64
+
+package cpuid
+
+// Supported is whether the machine has the CPUID instruction.
+const Supported = false
+
+// Native returns zeroes: there is no CPUID instruction.
+func Native(leaf, sub uint32) Regs {
+	return Regs{}
+}
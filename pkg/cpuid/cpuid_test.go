@@ -0,0 +1,160 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpuid
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fake is a CPU; the leaves it does not have return zeros.
+type fake map[[2]uint32]Regs
+
+func (f fake) cpuid(leaf, sub uint32) Regs {
+	return f[[2]uint32{leaf, sub}]
+}
+
+// intel is a Sapphire Rapids Xeon, in a VM.
+var intel = fake{
+	{0x0, 0}:        {0x00000020, 0x756e6547, 0x6c65746e, 0x49656e69},
+	{0x1, 0}:        {0x000806f8, 0x00010800, 0xfffa3203, 0x0f8bfbff},
+	{0x4, 0}:        {0x00000121, 0x02c0003f, 0x0000003f, 0x00000000},
+	{0x4, 1}:        {0x00000122, 0x01c0003f, 0x0000003f, 0x00000000},
+	{0x4, 2}:        {0x00000143, 0x03c0003f, 0x000007ff, 0x00000000},
+	{0x4, 3}:        {0x00000163, 0x0380003f, 0x0001bfff, 0x00000004},
+	{0x7, 0}:        {0x00000002, 0xf1bf27eb, 0x1b415fde, 0xbfd14410},
+	{0x80000000, 0}: {0x80000008, 0x00000000, 0x00000000, 0x00000000},
+	{0x80000001, 0}: {0x00000000, 0x00000000, 0x00000121, 0x2c100800},
+	{0x80000002, 0}: {0x65746e49, 0x2952286c, 0x6f655820, 0x2952286e},
+	{0x80000003, 0}: {0x6f725020, 0x73736563, 0x0000726f, 0x00000000},
+}
+
+// amd is a Zen 3, with its caches in leaf 0x8000001d.
+var amd = fake{
+	{0x0, 0}:        {0x00000010, 0x68747541, 0x444d4163, 0x69746e65},
+	{0x1, 0}:        {0x00a20f10, 0x00000000, 0x00000000, 0x00000001},
+	{0x80000000, 0}: {0x8000001e, 0x00000000, 0x00000000, 0x00000000},
+	{0x80000001, 0}: {0x00000000, 0x00000000, 0x00400000, 0x20000000},
+	{0x8000001d, 0}: {0x00004121, 0x01c0003f, 0x0000003f, 0x00000000},
+	{0x8000001d, 1}: {0x0000c163, 0x03c0003f, 0x00007fff, 0x00000001},
+}
+
+func has(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDecode(t *testing.T) {
+	for _, tt := range []struct {
+		name                    string
+		f                       fake
+		vendor, brand           string
+		family, model, stepping uint32
+		with, without           []string
+		caches                  []Cache
+	}{
+		{
+			name:     "intel",
+			f:        intel,
+			vendor:   "GenuineIntel",
+			brand:    "Intel(R) Xeon(R) Processor",
+			family:   6,
+			model:    0x8f,
+			stepping: 8,
+			with:     []string{"fpu", "sse2", "hypervisor", "avx2", "avx512f", "amx_tile", "lm", "lahf_lm"},
+			without:  []string{"ht", "svm", "topoext"},
+			caches: []Cache{
+				{Level: 1, Type: "Data", Size: 48 << 10, Ways: 12, LineSize: 64, Sets: 64, SharedBy: 1},
+				{Level: 1, Type: "Instruction", Size: 32 << 10, Ways: 8, LineSize: 64, Sets: 64, SharedBy: 1},
+				{Level: 2, Type: "Unified", Size: 2 << 20, Ways: 16, LineSize: 64, Sets: 2048, SharedBy: 1},
+				{Level: 3, Type: "Unified", Size: 105 << 20, Ways: 15, LineSize: 64, Sets: 114688, SharedBy: 1},
+			},
+		},
+		{
+			// Family 0xf, so the extended family is added to it.
+			name:     "amd",
+			f:        amd,
+			vendor:   "AuthenticAMD",
+			family:   0x19,
+			model:    0x21,
+			stepping: 0,
+			with:     []string{"fpu", "lm", "topoext"},
+			without:  []string{"sse2", "avx2"},
+			caches: []Cache{
+				{Level: 1, Type: "Data", Size: 32 << 10, Ways: 8, LineSize: 64, Sets: 64, SharedBy: 2},
+				{Level: 3, Type: "Unified", Size: 32 << 20, Ways: 16, LineSize: 64, Sets: 32768, SharedBy: 4},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			i := Decode(tt.f.cpuid)
+			if i.Vendor != tt.vendor {
+				t.Errorf("Vendor: got %q, want %q", i.Vendor, tt.vendor)
+			}
+			if i.Brand != tt.brand {
+				t.Errorf("Brand: got %q, want %q", i.Brand, tt.brand)
+			}
+			if i.Family != tt.family || i.Model != tt.model || i.Stepping != tt.stepping {
+				t.Errorf("family, model, stepping: got %#x, %#x, %d, want %#x, %#x, %d", i.Family, i.Model, i.Stepping, tt.family, tt.model, tt.stepping)
+			}
+			for _, f := range tt.with {
+				if !has(i.Features, f) {
+					t.Errorf("Features: %q is missing from %v", f, i.Features)
+				}
+			}
+			for _, f := range tt.without {
+				if has(i.Features, f) {
+					t.Errorf("Features: got %q, want it not there", f)
+				}
+			}
+			if !reflect.DeepEqual(i.Caches, tt.caches) {
+				t.Errorf("Caches: got %+v, want %+v", i.Caches, tt.caches)
+			}
+		})
+	}
+}
+
+func TestCacheName(t *testing.T) {
+	for _, tt := range []struct {
+		c    Cache
+		want string
+	}{
+		{Cache{Level: 1, Type: "Data"}, "L1d"},
+		{Cache{Level: 1, Type: "Instruction"}, "L1i"},
+		{Cache{Level: 2, Type: "Unified"}, "L2"},
+	} {
+		if got := tt.c.Name(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	f := fake{
+		{0x0, 0}: {EAX: 0x7},
+		{0x4, 0}: {EAX: 0x121},
+		{0x4, 1}: {EAX: 0x143},
+		// Leaf 7 says how many subleaves it has.
+		{0x7, 0}: {EAX: 0x1, EBX: 0x20},
+		{0x7, 1}: {EAX: 0x10},
+		// No extended leaves.
+		{0x80000000, 0}: {EAX: 0x12345678},
+	}
+	var got [][2]uint32
+	for _, l := range Dump(f.cpuid) {
+		if l.Regs != f[[2]uint32{l.Leaf, l.Sub}] {
+			t.Errorf("leaf %#x %d: got %+v, want %+v", l.Leaf, l.Sub, l.Regs, f[[2]uint32{l.Leaf, l.Sub}])
+		}
+		got = append(got, [2]uint32{l.Leaf, l.Sub})
+	}
+	want := [][2]uint32{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {4, 1}, {5, 0}, {6, 0}, {7, 0}, {7, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got leaves %v, want %v", got, want)
+	}
+}
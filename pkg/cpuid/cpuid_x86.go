@@ -0,0 +1,20 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build 386 || amd64
+// +build 386 amd64
+
+package cpuid
+
+// Supported is whether the machine has the CPUID instruction.
+const Supported = true
+
+// cpuid is in cpuid_$GOARCH.s.
+func cpuid(leaf, sub uint32) (eax, ebx, ecx, edx uint32)
+
+// Native runs the CPUID instruction of the CPU it is on.
+func Native(leaf, sub uint32) Regs {
+	a, b, c, d := cpuid(leaf, sub)
+	return Regs{EAX: a, EBX: b, ECX: c, EDX: d}
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package devfs makes the device files which almost every program needs,
+// for when /dev can not be a devtmpfs, e.g. the kernel was built without
+// CONFIG_DEVTMPFS.
+package devfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Node is a device file.
+type Node struct {
+	Name  string
+	Mode  uint32
+	Major uint32
+	Minor uint32
+}
+
+// Nodes are the device files Populate makes.
+var Nodes = []Node{
+	{Name: "console", Mode: unix.S_IFCHR | 0600, Major: 5, Minor: 1},
+	{Name: "tty", Mode: unix.S_IFCHR | 0666, Major: 5, Minor: 0},
+	{Name: "null", Mode: unix.S_IFCHR | 0666, Major: 1, Minor: 3},
+	{Name: "zero", Mode: unix.S_IFCHR | 0666, Major: 1, Minor: 5},
+	{Name: "full", Mode: unix.S_IFCHR | 0666, Major: 1, Minor: 7},
+	{Name: "random", Mode: unix.S_IFCHR | 0666, Major: 1, Minor: 8},
+	{Name: "urandom", Mode: unix.S_IFCHR | 0666, Major: 1, Minor: 9},
+	{Name: "kmsg", Mode: unix.S_IFCHR | 0644, Major: 1, Minor: 11},
+	{Name: "port", Mode: unix.S_IFCHR | 0640, Major: 1, Minor: 4},
+}
+
+// Populate makes the Nodes in dir which are not there already, so it does
+// nothing to a devtmpfs. The umask is not applied.
+func Populate(dir string) error {
+	old := unix.Umask(0)
+	defer unix.Umask(old)
+	for _, n := range Nodes {
+		p := filepath.Join(dir, n.Name)
+		if _, err := os.Lstat(p); err == nil {
+			continue
+		}
+		if err := unix.Mknod(p, n.Mode, int(unix.Mkdev(n.Major, n.Minor))); err != nil {
+			return fmt.Errorf("mknod %v: %v", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package devfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestPopulate(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("mknod needs root")
+	}
+	dir, err := ioutil.TempDir("", "devfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// One which is there already is left alone.
+	if err := ioutil.WriteFile(filepath.Join(dir, "null"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Populate(dir); err != nil {
+		t.Fatalf("Populate(%q): %v", dir, err)
+	}
+	for _, n := range Nodes {
+		var st syscall.Stat_t
+		p := filepath.Join(dir, n.Name)
+		if err := syscall.Lstat(p, &st); err != nil {
+			t.Errorf("%v: %v", p, err)
+			continue
+		}
+		if n.Name == "null" {
+			if st.Mode&syscall.S_IFMT != syscall.S_IFREG {
+				t.Errorf("%v: got mode %#o, want the regular file left alone", p, st.Mode)
+			}
+			continue
+		}
+		if st.Mode != n.Mode {
+			t.Errorf("%v: got mode %#o, want %#o", p, st.Mode, n.Mode)
+		}
+		dev := uint64(st.Rdev)
+		if unix.Major(dev) != n.Major || unix.Minor(dev) != n.Minor {
+			t.Errorf("%v: got device %d:%d, want %d:%d", p, unix.Major(dev), unix.Minor(dev), n.Major, n.Minor)
+		}
+	}
+
+	// Doing it again is fine.
+	if err := Populate(dir); err != nil {
+		t.Errorf("Populate(%q) again: %v", dir, err)
+	}
+}
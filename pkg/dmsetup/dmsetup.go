@@ -0,0 +1,234 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dmsetup sets up device-mapper devices, such as dm-crypt ones,
+// through the ioctls of /dev/mapper/control.
+package dmsetup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Target maps Length sectors, from sector Start of a device, with a
+// target, such as linear or crypt, and its parameters.
+type Target struct {
+	Start  uint64
+	Length uint64
+	Type   string
+	Params string
+}
+
+// String returns t as a line of a table: START LENGTH TYPE PARAMS.
+func (t Target) String() string {
+	return fmt.Sprintf("%d %d %s %s", t.Start, t.Length, t.Type, t.Params)
+}
+
+// ParseTarget parses a line of a table.
+func ParseTarget(s string) (Target, error) {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return Target{}, fmt.Errorf("%q is not START LENGTH TYPE [PARAMS]", s)
+	}
+	start, err := strconv.ParseUint(f[0], 10, 64)
+	if err != nil {
+		return Target{}, err
+	}
+	length, err := strconv.ParseUint(f[1], 10, 64)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{Start: start, Length: length, Type: f[2], Params: strings.Join(f[3:], " ")}, nil
+}
+
+// The sizes of struct dm_ioctl and struct dm_target_spec, and the offsets
+// in dm_ioctl.
+const (
+	ioctlSize      = 312
+	targetSpecSize = 40
+
+	offDataSize    = 12
+	offDataStart   = 16
+	offTargetCount = 20
+	offOpenCount   = 24
+	offFlags       = 28
+	offEventNr     = 32
+	offDev         = 40
+	offName        = 48
+	offUUID        = 176
+
+	nameLen = 128
+	uuidLen = 129
+)
+
+// The flags of dm_ioctl.
+const (
+	flagReadOnly        = 1 << 0
+	flagSuspend         = 1 << 1
+	flagStatusTable     = 1 << 4
+	flagActivePresent   = 1 << 5
+	flagInactivePresent = 1 << 6
+	flagBufferFull      = 1 << 8
+	flagSecureData      = 1 << 15
+)
+
+// bufSize is the size of the buffers of ioctls, which the kernel writes
+// its answers to.
+const bufSize = 16 << 10
+
+// request makes a struct dm_ioctl for the device name, with data after it.
+func request(name, uuid string, flags uint32, data []byte) ([]byte, error) {
+	if len(name) >= nameLen {
+		return nil, fmt.Errorf("name %q is longer than %d bytes", name, nameLen-1)
+	}
+	if len(uuid) >= uuidLen {
+		return nil, fmt.Errorf("UUID %q is longer than %d bytes", uuid, uuidLen-1)
+	}
+	size := ioctlSize + len(data)
+	if size < bufSize {
+		size = bufSize
+	}
+	b := make([]byte, size)
+	// The version of the interface, 4.0.0, which all kernels have.
+	binary.LittleEndian.PutUint32(b, 4)
+	binary.LittleEndian.PutUint32(b[offDataSize:], uint32(size))
+	binary.LittleEndian.PutUint32(b[offDataStart:], ioctlSize)
+	binary.LittleEndian.PutUint32(b[offFlags:], flags)
+	copy(b[offName:], name)
+	copy(b[offUUID:], uuid)
+	copy(b[ioctlSize:], data)
+	return b, nil
+}
+
+// targetSpecs makes the struct dm_target_specs of targets, each with its
+// parameters after it, and returns them and how many there are.
+func targetSpecs(targets []Target) ([]byte, uint32) {
+	var b bytes.Buffer
+	for _, t := range targets {
+		// The parameters are NUL terminated, and the next spec is 8
+		// byte aligned.
+		n := (targetSpecSize + len(t.Params) + 1 + 7) &^ 7
+		s := make([]byte, n)
+		binary.LittleEndian.PutUint64(s, t.Start)
+		binary.LittleEndian.PutUint64(s[8:], t.Length)
+		binary.LittleEndian.PutUint32(s[20:], uint32(n))
+		copy(s[24:24+16], t.Type)
+		copy(s[targetSpecSize:], t.Params)
+		b.Write(s)
+	}
+	return b.Bytes(), uint32(len(targets))
+}
+
+// cstring returns the NUL terminated string at the start of b.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// Info is the state of a device.
+type Info struct {
+	Name string
+	UUID string
+	// Major and Minor are the device number.
+	Major, Minor uint32
+	// OpenCount is how many times it is open, and EventNr how many
+	// events, such as a resize, it has had.
+	OpenCount int32
+	EventNr   uint32
+	// TargetCount is the number of targets of the live table.
+	TargetCount uint32
+	Suspended   bool
+	ReadOnly    bool
+	// Live and Inactive are whether there is a live table, and one
+	// loaded which is not live yet.
+	Live, Inactive bool
+}
+
+// decodeDev decodes a device number as the kernel encodes it in dm_ioctl.
+func decodeDev(dev uint64) (major, minor uint32) {
+	return uint32(dev&0xfff00) >> 8, uint32(dev&0xff) | uint32(dev>>12)&0xfff00
+}
+
+// parseInfo parses the answer of an ioctl about a device.
+func parseInfo(b []byte) *Info {
+	le32 := binary.LittleEndian.Uint32
+	flags := le32(b[offFlags:])
+	i := &Info{
+		Name:        cstring(b[offName : offName+nameLen]),
+		UUID:        cstring(b[offUUID : offUUID+uuidLen]),
+		OpenCount:   int32(le32(b[offOpenCount:])),
+		EventNr:     le32(b[offEventNr:]),
+		TargetCount: le32(b[offTargetCount:]),
+		Suspended:   flags&flagSuspend != 0,
+		ReadOnly:    flags&flagReadOnly != 0,
+		Live:        flags&flagActivePresent != 0,
+		Inactive:    flags&flagInactivePresent != 0,
+	}
+	i.Major, i.Minor = decodeDev(binary.LittleEndian.Uint64(b[offDev:]))
+	return i
+}
+
+// data returns the data of the answer b.
+func data(b []byte) []byte {
+	start, size := binary.LittleEndian.Uint32(b[offDataStart:]), binary.LittleEndian.Uint32(b[offDataSize:])
+	if start > size || int(size) > len(b) {
+		return nil
+	}
+	return b[start:size]
+}
+
+// parseTargets parses the targets of a table status answer. Unlike those
+// of a table load, their next is from the start of the data.
+func parseTargets(b []byte) ([]Target, error) {
+	d := data(b)
+	var targets []Target
+	for i, off := uint32(0), uint32(0); i < binary.LittleEndian.Uint32(b[offTargetCount:]); i++ {
+		if int(off)+targetSpecSize > len(d) {
+			return nil, fmt.Errorf("target %d is past the end of the answer", i)
+		}
+		s := d[off:]
+		targets = append(targets, Target{
+			Start:  binary.LittleEndian.Uint64(s),
+			Length: binary.LittleEndian.Uint64(s[8:]),
+			Type:   cstring(s[24:targetSpecSize]),
+			Params: cstring(s[targetSpecSize:]),
+		})
+		off = binary.LittleEndian.Uint32(s[20:])
+	}
+	return targets, nil
+}
+
+// A Device is a device-mapper device.
+type Device struct {
+	Name         string
+	Major, Minor uint32
+}
+
+// parseDevices parses the answer of a list of the devices, struct
+// dm_name_lists, each with its next relative to it.
+func parseDevices(b []byte) []Device {
+	d := data(b)
+	var devs []Device
+	for len(d) >= 12 {
+		dev := binary.LittleEndian.Uint64(d)
+		if dev == 0 {
+			break
+		}
+		var n Device
+		n.Major, n.Minor = decodeDev(dev)
+		n.Name = cstring(d[12:])
+		devs = append(devs, n)
+		next := binary.LittleEndian.Uint32(d[8:])
+		if next == 0 || int(next) > len(d) {
+			break
+		}
+		d = d[next:]
+	}
+	return devs
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dmsetup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Dir is where the devices and the control device are.
+var Dir = "/dev/mapper"
+
+// The ioctls: _IOWR(0xfd, NR, struct dm_ioctl).
+const (
+	dmVersion     = 0xc138fd00
+	dmListDevices = 0xc138fd02
+	dmDevCreate   = 0xc138fd03
+	dmDevRemove   = 0xc138fd04
+	dmDevSuspend  = 0xc138fd06
+	dmDevStatus   = 0xc138fd07
+	dmTableLoad   = 0xc138fd09
+	dmTableClear  = 0xc138fd0a
+	dmTableStatus = 0xc138fd0c
+
+	// controlDev is the device number of the control device, a misc
+	// device, 10:236.
+	controlDev = 10<<8 | 236
+)
+
+// ioctl does the ioctl req with the struct dm_ioctl b, and returns the
+// answer in b.
+func ioctl(req uintptr, b []byte) error {
+	p := filepath.Join(Dir, "control")
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		// With no udev, it is only there if it is made.
+		if err := os.MkdirAll(Dir, 0755); err != nil {
+			return err
+		}
+		if err := syscall.Mknod(p, syscall.S_IFCHR|0600, controlDev); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(p, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(&b[0]))); errno != 0 {
+		return errno
+	}
+	if binary.LittleEndian.Uint32(b[offFlags:])&flagBufferFull != 0 {
+		return fmt.Errorf("the answer of device-mapper is more than %d bytes", len(b))
+	}
+	return nil
+}
+
+// do does the ioctl req for the device name.
+func do(req uintptr, name, uuid string, flags uint32, data []byte) ([]byte, error) {
+	b, err := request(name, uuid, flags, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioctl(req, b); err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	return b, nil
+}
+
+// Version returns the version of the kernel's device-mapper interface.
+func Version() (string, error) {
+	b, err := do(dmVersion, "", "", 0, nil)
+	if err != nil {
+		return "", err
+	}
+	v := func(i int) uint32 { return binary.LittleEndian.Uint32(b[i*4:]) }
+	return fmt.Sprintf("%d.%d.%d", v(0), v(1), v(2)), nil
+}
+
+// List returns the devices.
+func List() ([]Device, error) {
+	b, err := do(dmListDevices, "", "", 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseDevices(b), nil
+}
+
+// mknod makes the node of the device name, Dir/name: there is no udev to.
+func mknod(name string, b []byte) error {
+	p := filepath.Join(Dir, name)
+	os.Remove(p)
+	return syscall.Mknod(p, syscall.S_IFBLK|0600, int(binary.LittleEndian.Uint64(b[offDev:])))
+}
+
+// Create creates the device name, with uuid, which may be empty, and the
+// table targets, and makes its node, Dir/name.
+func Create(name, uuid string, readOnly bool, targets []Target) error {
+	if _, err := do(dmDevCreate, name, uuid, 0, nil); err != nil {
+		return err
+	}
+	if err := Load(name, readOnly, targets); err != nil {
+		Remove(name)
+		return err
+	}
+	if err := Resume(name); err != nil {
+		Remove(name)
+		return err
+	}
+	return nil
+}
+
+// Load loads the table targets of the device name, which is made live by
+// Resume. The table may have keys in it, so the kernel is asked to wipe
+// its copies of it.
+func Load(name string, readOnly bool, targets []Target) error {
+	var flags uint32 = flagSecureData
+	if readOnly {
+		flags |= flagReadOnly
+	}
+	specs, n := targetSpecs(targets)
+	b, err := request(name, "", flags, specs)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b[offTargetCount:], n)
+	if err := ioctl(dmTableLoad, b); err != nil {
+		return fmt.Errorf("%v: loading table: %v", name, err)
+	}
+	return nil
+}
+
+// Clear clears the loaded table of the device name which is not live.
+func Clear(name string) error {
+	_, err := do(dmTableClear, name, "", 0, nil)
+	return err
+}
+
+// Suspend suspends the device name: I/O to it waits until it is resumed.
+func Suspend(name string) error {
+	_, err := do(dmDevSuspend, name, "", flagSuspend, nil)
+	return err
+}
+
+// Resume resumes the device name, with its loaded table, if it has one,
+// made live, and makes its node.
+func Resume(name string) error {
+	b, err := do(dmDevSuspend, name, "", 0, nil)
+	if err != nil {
+		return err
+	}
+	return mknod(name, b)
+}
+
+// Remove removes the device name, and its node.
+func Remove(name string) error {
+	if _, err := do(dmDevRemove, name, "", 0, nil); err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(Dir, name))
+	return nil
+}
+
+// Status returns the state of the device name.
+func Status(name string) (*Info, error) {
+	b, err := do(dmDevStatus, name, "", 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseInfo(b), nil
+}
+
+// Table returns the live table of the device name.
+func Table(name string) ([]Target, error) {
+	b, err := do(dmTableStatus, name, "", flagStatusTable, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseTargets(b)
+}
+
+// TargetStatus returns the status of each target of the device name, as
+// its Params.
+func TargetStatus(name string) ([]Target, error) {
+	b, err := do(dmTableStatus, name, "", 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseTargets(b)
+}
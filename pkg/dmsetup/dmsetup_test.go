@@ -0,0 +1,128 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dmsetup
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	s := "0 2048 crypt aes-xts-plain64 0011 0 /dev/sda2 4096"
+	tg, err := ParseTarget(s)
+	want := Target{Start: 0, Length: 2048, Type: "crypt", Params: "aes-xts-plain64 0011 0 /dev/sda2 4096"}
+	if err != nil || tg != want {
+		t.Errorf("ParseTarget(%q): got %+v, %v, want %+v", s, tg, err, want)
+	}
+	if tg.String() != s {
+		t.Errorf("String: got %q, want %q", tg.String(), s)
+	}
+	for _, bad := range []string{"0 2048", "x 2048 linear /dev/sda 0", "0 -1 linear /dev/sda 0"} {
+		if _, err := ParseTarget(bad); err == nil {
+			t.Errorf("ParseTarget(%q): got nil, want error", bad)
+		}
+	}
+}
+
+func TestRequest(t *testing.T) {
+	specs, n := targetSpecs([]Target{
+		{Start: 0, Length: 100, Type: "linear", Params: "/dev/sda 0"},
+		{Start: 100, Length: 50, Type: "zero"},
+	})
+	if n != 2 || len(specs) != 56+48 {
+		t.Fatalf("targetSpecs: got %d specs in %d bytes, want 2 in 104", n, len(specs))
+	}
+	if next := binary.LittleEndian.Uint32(specs[20:]); next != 56 {
+		t.Errorf("next of the first spec: got %d, want 56", next)
+	}
+	if typ, p := cstring(specs[24:40]), cstring(specs[40:]); typ != "linear" || p != "/dev/sda 0" {
+		t.Errorf("first spec: got %q %q, want linear, /dev/sda 0", typ, p)
+	}
+	if start, typ := binary.LittleEndian.Uint64(specs[56:]), cstring(specs[56+24:56+40]); start != 100 || typ != "zero" {
+		t.Errorf("second spec: got %d %q, want 100, zero", start, typ)
+	}
+
+	b, err := request("root", "CRYPT-LUKS1-x", flagReadOnly, specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != bufSize || binary.LittleEndian.Uint32(b[offDataSize:]) != bufSize ||
+		binary.LittleEndian.Uint32(b[offDataStart:]) != ioctlSize || binary.LittleEndian.Uint32(b[offFlags:]) != flagReadOnly {
+		t.Errorf("request: header is wrong: % x", b[:ioctlSize])
+	}
+	if cstring(b[offName:]) != "root" || cstring(b[offUUID:]) != "CRYPT-LUKS1-x" {
+		t.Errorf("request: got name %q, UUID %q", cstring(b[offName:]), cstring(b[offUUID:]))
+	}
+	if _, err := request(string(make([]byte, 128)), "", 0, nil); err == nil {
+		t.Errorf("request with a name of 128 bytes: got nil, want error")
+	}
+}
+
+// answer makes an answer of the kernel, with data after the header.
+func answer(t *testing.T, name string, flags uint32, d []byte) []byte {
+	b, err := request(name, "", flags, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(b[offDataSize:], uint32(ioctlSize+len(d)))
+	return b
+}
+
+func TestParseInfo(t *testing.T) {
+	b := answer(t, "root", flagSuspend|flagActivePresent, nil)
+	copy(b[offUUID:], "CRYPT-LUKS2-x-root")
+	binary.LittleEndian.PutUint32(b[offOpenCount:], 1)
+	binary.LittleEndian.PutUint32(b[offTargetCount:], 2)
+	// 253:300, as the kernel encodes it.
+	binary.LittleEndian.PutUint64(b[offDev:], 300&0xff|253<<8|(300&^0xff)<<12)
+	want := Info{Name: "root", UUID: "CRYPT-LUKS2-x-root", Major: 253, Minor: 300, OpenCount: 1, TargetCount: 2,
+		Suspended: true, Live: true}
+	if got := parseInfo(b); *got != want {
+		t.Errorf("parseInfo: got %+v, want %+v", *got, want)
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	// A status answer: the next of each target is from the start of the
+	// data.
+	targets := []Target{
+		{Start: 0, Length: 100, Type: "linear", Params: "8:0 0"},
+		{Start: 100, Length: 50, Type: "crypt", Params: "aes-xts-plain64 0000 0 8:2 4096"},
+	}
+	d, n := targetSpecs(targets)
+	second := binary.LittleEndian.Uint32(d[20:])
+	binary.LittleEndian.PutUint32(d[second+20:], uint32(len(d)))
+	b := answer(t, "root", 0, d)
+	binary.LittleEndian.PutUint32(b[offTargetCount:], n)
+	got, err := parseTargets(b)
+	if err != nil || len(got) != 2 || got[0] != targets[0] || got[1] != targets[1] {
+		t.Errorf("parseTargets: got %v, %v, want %v", got, err, targets)
+	}
+
+	binary.LittleEndian.PutUint32(b[offTargetCount:], 3)
+	if _, err := parseTargets(b); err == nil {
+		t.Errorf("parseTargets of 3 targets of 2: got nil, want error")
+	}
+}
+
+func TestParseDevices(t *testing.T) {
+	var d []byte
+	for i, n := range []string{"root", "swap"} {
+		e := make([]byte, 24)
+		binary.LittleEndian.PutUint64(e, uint64(253<<8|i))
+		if i == 0 {
+			binary.LittleEndian.PutUint32(e[8:], 24)
+		}
+		copy(e[12:], n)
+		d = append(d, e...)
+	}
+	want := []Device{{"root", 253, 0}, {"swap", 253, 1}}
+	if got := parseDevices(answer(t, "", 0, d)); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("parseDevices: got %v, want %v", got, want)
+	}
+	if got := parseDevices(answer(t, "", 0, make([]byte, 8))); len(got) != 0 {
+		t.Errorf("parseDevices of none: got %v, want none", got)
+	}
+}
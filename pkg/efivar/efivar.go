@@ -0,0 +1,133 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package efivar reads and writes UEFI variables, through efivarfs, and
+// the boot entries which are kept in them.
+package efivar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// GlobalGUID is the vendor GUID of the variables UEFI defines, such as
+// BootOrder.
+const GlobalGUID = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// Attributes of variables.
+const (
+	NonVolatile                       = 0x1
+	BootserviceAccess                 = 0x2
+	RuntimeAccess                     = 0x4
+	HardwareErrorRecord               = 0x8
+	AuthenticatedWriteAccess          = 0x10
+	TimeBasedAuthenticatedWriteAccess = 0x20
+	AppendWrite                       = 0x40
+
+	// DefaultAttr are the attributes of boot variables.
+	DefaultAttr = NonVolatile | BootserviceAccess | RuntimeAccess
+)
+
+var attrNames = []string{
+	"Non-Volatile",
+	"Boot Service Access",
+	"Runtime Service Access",
+	"Hardware Error Record",
+	"Authenticated Write Access",
+	"Time-Based Authenticated Write Access",
+	"Append Write",
+}
+
+// AttrNames returns the names of the attributes set in attr.
+func AttrNames(attr uint32) []string {
+	var n []string
+	for i, name := range attrNames {
+		if attr&(1<<uint(i)) != 0 {
+			n = append(n, name)
+		}
+	}
+	return n
+}
+
+// A Var is a variable.
+type Var struct {
+	Name string
+	// GUID is the vendor GUID, which is part of the name of the variable.
+	GUID string
+	Attr uint32
+	Data []byte
+}
+
+// String returns the name of v as efivarfs has it: NAME-GUID.
+func (v *Var) String() string {
+	return v.Name + "-" + v.GUID
+}
+
+// ParseName splits the efivarfs name NAME-GUID of a variable.
+func ParseName(s string) (name, guid string, err error) {
+	// GUIDs are 36 characters, with dashes in them.
+	if len(s) < 38 || s[len(s)-37] != '-' {
+		return "", "", fmt.Errorf("%q is not NAME-GUID", s)
+	}
+	return s[:len(s)-37], strings.ToLower(s[len(s)-36:]), nil
+}
+
+// GUIDString formats the 16 bytes b of a GUID as it is stored, with its
+// first three fields little endian.
+func GUIDString(b []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x", binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint16(b[4:]),
+		binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:16])
+}
+
+// UCS2 encodes s as UEFI strings are, UTF-16 little endian, ending in NUL.
+func UCS2(s string) []byte {
+	u := utf16.Encode([]rune(s + "\x00"))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// ParseUCS2 returns the string which b starts with, and the length of it,
+// NUL and all, in bytes.
+func ParseUCS2(b []byte) (string, int) {
+	var u []uint16
+	n := 0
+	for ; n+1 < len(b); n += 2 {
+		c := binary.LittleEndian.Uint16(b[n:])
+		if c == 0 {
+			n += 2
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u)), n
+}
+
+// ParseBootOrder parses a BootOrder variable: a list of the numbers of Boot
+// variables.
+func ParseBootOrder(b []byte) []uint16 {
+	var order []uint16
+	for i := 0; i+1 < len(b); i += 2 {
+		order = append(order, binary.LittleEndian.Uint16(b[i:]))
+	}
+	return order
+}
+
+// BootOrder returns order as a BootOrder variable.
+func BootOrder(order []uint16) []byte {
+	b := make([]byte, 2*len(order))
+	for i, n := range order {
+		binary.LittleEndian.PutUint16(b[2*i:], n)
+	}
+	return b
+}
+
+// BootName returns the name of Boot variable n, such as Boot0001.
+func BootName(n uint16) string {
+	return fmt.Sprintf("Boot%04X", n)
+}
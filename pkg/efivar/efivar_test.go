@@ -0,0 +1,96 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivar
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	for _, tt := range []struct {
+		in, name, guid string
+	}{
+		{"BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8c", "BootOrder", GlobalGUID},
+		{"My-Var-8BE4DF61-93CA-11D2-AA0D-00E098032B8C", "My-Var", GlobalGUID},
+		{"BootOrder", "", ""},
+		{"-8be4df61-93ca-11d2-aa0d-00e098032b8c", "", ""},
+	} {
+		name, guid, err := ParseName(tt.in)
+		if name != tt.name || guid != tt.guid || (err == nil) != (tt.name != "") {
+			t.Errorf("ParseName(%q): got %q, %q, %v, want %q, %q", tt.in, name, guid, err, tt.name, tt.guid)
+		}
+	}
+}
+
+func TestUCS2(t *testing.T) {
+	b := UCS2("Linux é")
+	want := []byte{'L', 0, 'i', 0, 'n', 0, 'u', 0, 'x', 0, ' ', 0, 0xe9, 0, 0, 0}
+	if !bytes.Equal(b, want) {
+		t.Errorf("UCS2: got %v, want %v", b, want)
+	}
+	if s, n := ParseUCS2(append(b, 1, 2)); s != "Linux é" || n != len(want) {
+		t.Errorf("ParseUCS2: got %q, %d, want %q, %d", s, n, "Linux é", len(want))
+	}
+}
+
+func TestBootOrder(t *testing.T) {
+	order := []uint16{1, 0x10, 0xabcd}
+	b := BootOrder(order)
+	if want := []byte{1, 0, 0x10, 0, 0xcd, 0xab}; !bytes.Equal(b, want) {
+		t.Errorf("BootOrder(%v): got %v, want %v", order, b, want)
+	}
+	if got := ParseBootOrder(b); !reflect.DeepEqual(got, order) {
+		t.Errorf("ParseBootOrder(%v): got %v, want %v", b, got, order)
+	}
+}
+
+func TestLoadOption(t *testing.T) {
+	guid := [16]byte{0x28, 0x73, 0x2a, 0xc1, 0x1f, 0xf8, 0xd2, 0x11, 0xba, 0x4b, 0, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b}
+	path := append(append(HardDrivePath(1, 0x800, 0x100000, guid), FilePath("/EFI/BOOT/BOOTX64.EFI")...), EndPath...)
+	o := &LoadOption{
+		Attr:         LoadOptionActive,
+		Description:  "Linux",
+		FilePath:     path,
+		OptionalData: UCS2("console=ttyS0"),
+	}
+	got, err := ParseLoadOption(o.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, o) {
+		t.Errorf("ParseLoadOption(Bytes()): got %+v, want %+v", got, o)
+	}
+	want := `HD(1,GPT,c12a7328-f81f-11d2-ba4b-00a0c93ec93b,0x800,0x100000)/File(\EFI\BOOT\BOOTX64.EFI)`
+	if s := FormatDevicePath(path); s != want {
+		t.Errorf("FormatDevicePath: got %q, want %q", s, want)
+	}
+
+	if _, err := ParseLoadOption(o.Bytes()[:20]); err == nil {
+		t.Errorf("ParseLoadOption of a short option: got nil, want an error")
+	}
+}
+
+func TestFormatDevicePath(t *testing.T) {
+	for _, tt := range []struct {
+		path []byte
+		want string
+	}{
+		{
+			append(append(node(acpiPath, 1, []byte{0xd0, 0x41, 0x03, 0x0a, 0, 0, 0, 0}), node(hardwarePath, pciPath, []byte{0, 3})...), EndPath...),
+			"PciRoot(0x0)/Pci(0x3,0x0)",
+		},
+		{
+			append(node(9, 9, []byte{1, 2}), node(endPath, 1, nil)...),
+			"Path(9,9,0102),",
+		},
+		{[]byte{4, 4, 2, 0}, "<bad node length 2>"},
+	} {
+		if s := FormatDevicePath(tt.path); s != tt.want {
+			t.Errorf("FormatDevicePath(%v): got %q, want %q", tt.path, s, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+// Dir is where efivarfs is mounted.
+var Dir = "/sys/firmware/efi/efivars"
+
+// The ioctls for inode flags, such as immutable. They are _IOR('f', 1,
+// long) and _IOW('f', 2, long), though the flags are an int.
+const (
+	fsIocGetFlags = 0x80006601 | unsafe.Sizeof(uintptr(0))<<16
+	fsIocSetFlags = 0x40006602 | unsafe.Sizeof(uintptr(0))<<16
+
+	fsImmutableFl = 0x10
+
+	efivarfsMagic = 0xde5e81e4
+)
+
+// List returns the variables, without their data.
+func List() ([]*Var, error) {
+	fis, err := ioutil.ReadDir(Dir)
+	if err != nil {
+		return nil, err
+	}
+	var vars []*Var
+	for _, fi := range fis {
+		name, guid, err := ParseName(fi.Name())
+		if err != nil {
+			continue
+		}
+		vars = append(vars, &Var{Name: name, GUID: guid})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].String() < vars[j].String() })
+	return vars, nil
+}
+
+// Read reads the variable name with vendor GUID guid.
+func Read(name, guid string) (*Var, error) {
+	b, err := ioutil.ReadFile(filepath.Join(Dir, name+"-"+guid))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("%v-%v: %d bytes is too short for its attributes", name, guid, len(b))
+	}
+	return &Var{Name: name, GUID: guid, Attr: binary.LittleEndian.Uint32(b), Data: b[4:]}, nil
+}
+
+// mutable clears the immutable flag of the variable file p, if there is
+// one. efivarfs makes variables, but for some well known ones, immutable,
+// so that they are not removed or written by accident: some firmware does
+// not boot without them. Other file systems, as in tests, may not have the
+// flag.
+func mutable(p string) {
+	f, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var flags int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocGetFlags, uintptr(unsafe.Pointer(&flags))); errno != 0 || flags&fsImmutableFl == 0 {
+		return
+	}
+	flags &^= fsImmutableFl
+	syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocSetFlags, uintptr(unsafe.Pointer(&flags)))
+}
+
+// Write writes v, creating it if need be.
+func Write(v *Var) error {
+	p := filepath.Join(Dir, v.String())
+	mutable(p)
+	// efivarfs replaces the variable with each write, and can not
+	// truncate; other file systems, as in tests, need to.
+	flags := os.O_WRONLY | os.O_CREATE
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(Dir, &st); err == nil && uint32(st.Type) != efivarfsMagic {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(p, flags, 0644)
+	if err != nil {
+		return err
+	}
+	// efivarfs wants the attributes and data in a single write.
+	b := make([]byte, 4+len(v.Data))
+	binary.LittleEndian.PutUint32(b, v.Attr)
+	copy(b[4:], v.Data)
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("%v: %v", v, err)
+	}
+	return f.Close()
+}
+
+// Delete deletes the variable name with vendor GUID guid.
+func Delete(name, guid string) error {
+	p := filepath.Join(Dir, name+"-"+guid)
+	mutable(p)
+	return os.Remove(p)
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivar
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestVars(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "efivar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	Dir = tmp
+
+	for _, v := range []*Var{
+		{Name: "BootOrder", GUID: GlobalGUID, Attr: DefaultAttr, Data: []byte{1, 0, 2, 0}},
+		{Name: "Boot0001", GUID: GlobalGUID, Attr: DefaultAttr, Data: []byte("boot")},
+		// Writes replace what was there.
+		{Name: "BootOrder", GUID: GlobalGUID, Attr: DefaultAttr, Data: []byte{1, 0}},
+	} {
+		if err := Write(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vars, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Var{{Name: "Boot0001", GUID: GlobalGUID}, {Name: "BootOrder", GUID: GlobalGUID}}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("List: got %v, want %v", vars, want)
+	}
+	v, err := Read("BootOrder", GlobalGUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := (&Var{Name: "BootOrder", GUID: GlobalGUID, Attr: DefaultAttr, Data: []byte{1, 0}}); !reflect.DeepEqual(v, w) {
+		t.Errorf("Read: got %+v, want %+v", v, w)
+	}
+	if got := AttrNames(v.Attr); !reflect.DeepEqual(got, []string{"Non-Volatile", "Boot Service Access", "Runtime Service Access"}) {
+		t.Errorf("AttrNames(%#x): got %q", v.Attr, got)
+	}
+	if err := Delete("Boot0001", GlobalGUID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read("Boot0001", GlobalGUID); !os.IsNotExist(err) {
+		t.Errorf("Read of a deleted variable: got %v, want it not to exist", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// LoadOptionActive is the attribute of boot entries which are tried.
+const LoadOptionActive = 0x1
+
+// A LoadOption is a boot entry, the data of a Boot#### variable.
+type LoadOption struct {
+	Attr        uint32
+	Description string
+	// FilePath is the device path of what to boot.
+	FilePath []byte
+	// OptionalData is passed to what is booted; for Linux, it is the
+	// command line.
+	OptionalData []byte
+}
+
+// ParseLoadOption parses the load option b.
+func ParseLoadOption(b []byte) (*LoadOption, error) {
+	if len(b) < 6 {
+		return nil, fmt.Errorf("load option is %d bytes, want at least 6", len(b))
+	}
+	o := &LoadOption{Attr: binary.LittleEndian.Uint32(b)}
+	pathLen := int(binary.LittleEndian.Uint16(b[4:]))
+	var n int
+	o.Description, n = ParseUCS2(b[6:])
+	b = b[6+n:]
+	if pathLen > len(b) {
+		return nil, fmt.Errorf("load option %q: device path is %d bytes, but only %d are left", o.Description, pathLen, len(b))
+	}
+	o.FilePath, o.OptionalData = b[:pathLen], b[pathLen:]
+	return o, nil
+}
+
+// Bytes returns o as a Boot#### variable has it.
+func (o *LoadOption) Bytes() []byte {
+	b := make([]byte, 6)
+	binary.LittleEndian.PutUint32(b, o.Attr)
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(o.FilePath)))
+	b = append(b, UCS2(o.Description)...)
+	b = append(b, o.FilePath...)
+	return append(b, o.OptionalData...)
+}
+
+// Device path node types and subtypes.
+const (
+	hardwarePath  = 1
+	pciPath       = 1
+	acpiPath      = 2
+	messagingPath = 3
+	macPath       = 11
+	mediaPath     = 4
+	hardDrivePath = 1
+	filePathPath  = 4
+	fvFilePath    = 6
+	fvPath        = 7
+	endPath       = 0x7f
+	endEntire     = 0xff
+)
+
+// node returns a device path node.
+func node(typ, subtype uint8, data []byte) []byte {
+	b := []byte{typ, subtype, 0, 0}
+	binary.LittleEndian.PutUint16(b[2:], uint16(4+len(data)))
+	return append(b, data...)
+}
+
+// HardDrivePath returns the device path of GPT partition number part, from
+// block start, of size blocks, whose unique GUID, as it is stored, is guid.
+func HardDrivePath(part uint32, start, size uint64, guid [16]byte) []byte {
+	d := make([]byte, 20, 38)
+	binary.LittleEndian.PutUint32(d, part)
+	binary.LittleEndian.PutUint64(d[4:], start)
+	binary.LittleEndian.PutUint64(d[12:], size)
+	d = append(d, guid[:]...)
+	// The partition table is GPT, and the signature a GUID.
+	d = append(d, 2, 2)
+	return node(mediaPath, hardDrivePath, d)
+}
+
+// FilePath returns the device path of the file p, with / or \ between
+// directories.
+func FilePath(p string) []byte {
+	p = strings.Replace(p, "/", `\`, -1)
+	if !strings.HasPrefix(p, `\`) {
+		p = `\` + p
+	}
+	return node(mediaPath, filePathPath, UCS2(p))
+}
+
+// EndPath is the node device paths end with.
+var EndPath = node(endPath, endEntire, nil)
+
+// FormatDevicePath formats the device path b as the UEFI shell and
+// efibootmgr do, such as HD(1,GPT,GUID,0x800,0x100000)/File(\EFI\X.EFI).
+// Nodes it does not know are formatted as Path(TYPE,SUBTYPE,DATA).
+func FormatDevicePath(b []byte) string {
+	var s string
+	sep := ""
+	for len(b) >= 4 {
+		typ, sub := b[0], b[1]
+		l := int(binary.LittleEndian.Uint16(b[2:]))
+		if l < 4 || l > len(b) {
+			return s + sep + fmt.Sprintf("<bad node length %d>", l)
+		}
+		d := b[4:l]
+		b = b[l:]
+		var n string
+		switch {
+		case typ == endPath && sub == endEntire:
+			return s
+		case typ == endPath:
+			s, sep = s+",", ""
+			continue
+		case typ == hardwarePath && sub == pciPath && len(d) == 2:
+			n = fmt.Sprintf("Pci(%#x,%#x)", d[1], d[0])
+		case typ == acpiPath && sub == 1 && len(d) == 8:
+			hid, uid := binary.LittleEndian.Uint32(d), binary.LittleEndian.Uint32(d[4:])
+			switch hid {
+			case 0x0a0341d0, 0x0a0841d0:
+				n = fmt.Sprintf("PciRoot(%#x)", uid)
+			default:
+				n = fmt.Sprintf("Acpi(%#08x,%#x)", hid, uid)
+			}
+		case typ == messagingPath && sub == macPath && len(d) == 33:
+			n = fmt.Sprintf("MAC(%x,%#x)", d[:6], d[32])
+		case typ == mediaPath && sub == hardDrivePath && len(d) == 38:
+			part := binary.LittleEndian.Uint32(d)
+			start, size := binary.LittleEndian.Uint64(d[4:]), binary.LittleEndian.Uint64(d[12:])
+			switch d[36] {
+			case 2:
+				n = fmt.Sprintf("HD(%d,GPT,%s,%#x,%#x)", part, GUIDString(d[20:36]), start, size)
+			case 1:
+				n = fmt.Sprintf("HD(%d,MBR,%#x,%#x,%#x)", part, binary.LittleEndian.Uint32(d[20:]), start, size)
+			default:
+				n = fmt.Sprintf("HD(%d,%d,0,%#x,%#x)", part, d[36], start, size)
+			}
+		case typ == mediaPath && sub == filePathPath:
+			p, _ := ParseUCS2(d)
+			n = fmt.Sprintf("File(%s)", p)
+		case typ == mediaPath && (sub == fvFilePath || sub == fvPath) && len(d) == 16:
+			n = fmt.Sprintf("FvFile(%s)", GUIDString(d))
+			if sub == fvPath {
+				n = fmt.Sprintf("Fv(%s)", GUIDString(d))
+			}
+		default:
+			n = fmt.Sprintf("Path(%d,%d,%x)", typ, sub, d)
+		}
+		s += sep + n
+		sep = "/"
+	}
+	return s
+}
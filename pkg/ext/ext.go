@@ -0,0 +1,436 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ext checks ext2, ext3 and ext4 file systems.
+//
+// It only checks their metadata: the superblock, the group descriptors, the
+// bitmaps, and the list of orphan inodes. The tree of directories and the
+// blocks of files are not looked at.
+package ext
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+)
+
+// Where things are in the superblock, and what they are.
+const (
+	superblockOff  = 1024
+	superblockSize = 1024
+
+	offInodesCount     = 0x00
+	offBlocksCount     = 0x04
+	offFirstDataBlock  = 0x14
+	offLogBlockSize    = 0x18
+	offBlocksPerGroup  = 0x20
+	offInodesPerGroup  = 0x28
+	offMagic           = 0x38
+	offState           = 0x3a
+	offRevLevel        = 0x4c
+	offInodeSize       = 0x58
+	offFeatureCompat   = 0x5c
+	offFeatureIncompat = 0x60
+	offFeatureROCompat = 0x64
+	offUUID            = 0x68
+	offLastOrphan      = 0xe8
+	offDescSize        = 0xfe
+	offBlocksCountHi   = 0x150
+	offChecksumSeed    = 0x270
+	offChecksum        = 0x3fc
+
+	magic = 0xef53
+
+	stateValid  = 1
+	stateErrors = 2
+
+	compatHasJournal   = 0x4
+	incompatRecover    = 0x4
+	incompatMetaBG     = 0x10
+	incompat64Bit      = 0x80
+	incompatCsumSeed   = 0x2000
+	roCompatGDTCsum    = 0x10
+	roCompatBigalloc   = 0x200
+	roCompatMetaCsum   = 0x400
+	compatOrphanFile   = 0x1000
+	roCompatOrphanPres = 0x10000
+
+	// Where things are in group descriptors.
+	offBlockBitmap   = 0x00
+	offInodeBitmap   = 0x04
+	offInodeTable    = 0x08
+	offFreeBlocks    = 0x0c
+	offFreeInodes    = 0x0e
+	offFlags         = 0x12
+	offGDChecksum    = 0x1e
+	offBlockBitmapHi = 0x20
+	offInodeBitmapHi = 0x24
+	offInodeTableHi  = 0x28
+	offFreeBlocksHi  = 0x2c
+	offFreeInodesHi  = 0x2e
+
+	bgInodeUninit = 1
+	bgBlockUninit = 2
+
+	// Where things are in inodes.
+	offDtime      = 0x14
+	offLinksCount = 0x1a
+)
+
+// A Report is what Check found wrong with a file system.
+type Report struct {
+	// Errors are the problems which are left.
+	Errors []string
+	// Fixed are the problems which were fixed.
+	Fixed []string
+}
+
+func (r *Report) errorf(f string, v ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(f, v...))
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// crc32c is the CRC32C of p after crc, as the kernel has it, with neither
+// the crc nor the result inverted.
+func crc32c(crc uint32, p []byte) uint32 {
+	return ^crc32.Update(^crc, castagnoli, p)
+}
+
+// crc16 is the CRC16 ext4 checksums group descriptors with, if they are
+// not checksummed with CRC32C.
+func crc16(crc uint16, p []byte) uint16 {
+	for _, b := range p {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// fs is a file system being checked.
+type fs struct {
+	r  io.ReaderAt
+	sb []byte
+
+	blockSize      int64
+	blocks         uint64
+	firstDataBlock uint64
+	blocksPerGroup uint64
+	inodes         uint32
+	inodesPerGroup uint32
+	inodeSize      int64
+	descSize       int64
+	groups         uint32
+	incompat       uint32
+	roCompat       uint32
+	csumSeed       uint32
+
+	gdt []byte
+}
+
+func (f *fs) le16(o int) uint16 { return binary.LittleEndian.Uint16(f.sb[o:]) }
+func (f *fs) le32(o int) uint32 { return binary.LittleEndian.Uint32(f.sb[o:]) }
+
+// sbChecksum returns what the checksum of the superblock should be.
+func (f *fs) sbChecksum() uint32 {
+	return crc32c(^uint32(0), f.sb[:offChecksum])
+}
+
+// open reads the superblock and the group descriptors of the file system
+// of size bytes on r.
+func open(r io.ReaderAt, size int64) (*fs, error) {
+	f := &fs{r: r, sb: make([]byte, superblockSize)}
+	if _, err := r.ReadAt(f.sb, superblockOff); err != nil {
+		return nil, err
+	}
+	if f.le16(offMagic) != magic {
+		return nil, errors.New("not an ext2, ext3 or ext4 file system")
+	}
+	if l := f.le32(offLogBlockSize); l > 6 {
+		return nil, fmt.Errorf("the block size, 1024<<%d, is not valid", l)
+	}
+	f.blockSize = 1024 << f.le32(offLogBlockSize)
+	f.incompat = f.le32(offFeatureIncompat)
+	f.roCompat = f.le32(offFeatureROCompat)
+	f.blocks = uint64(f.le32(offBlocksCount))
+	f.descSize = 32
+	if f.incompat&incompat64Bit != 0 {
+		f.blocks |= uint64(f.le32(offBlocksCountHi)) << 32
+		f.descSize = int64(f.le16(offDescSize))
+		if f.descSize < 32 || f.descSize > 1024 || f.descSize&(f.descSize-1) != 0 {
+			return nil, fmt.Errorf("the group descriptor size, %d, is not valid", f.descSize)
+		}
+	}
+	f.firstDataBlock = uint64(f.le32(offFirstDataBlock))
+	f.blocksPerGroup = uint64(f.le32(offBlocksPerGroup))
+	f.inodes = f.le32(offInodesCount)
+	f.inodesPerGroup = f.le32(offInodesPerGroup)
+	f.inodeSize = 128
+	if f.le32(offRevLevel) > 0 {
+		f.inodeSize = int64(f.le16(offInodeSize))
+	}
+	switch {
+	case f.incompat&incompatMetaBG != 0:
+		return nil, errors.New("meta_bg file systems are not supported")
+	case f.roCompat&roCompatBigalloc != 0:
+		return nil, errors.New("bigalloc file systems are not supported")
+	case f.blocksPerGroup == 0 || f.blocksPerGroup > uint64(f.blockSize)*8:
+		return nil, fmt.Errorf("%d blocks per group is not valid", f.blocksPerGroup)
+	case f.inodesPerGroup == 0 || int64(f.inodesPerGroup) > f.blockSize*8:
+		return nil, fmt.Errorf("%d inodes per group is not valid", f.inodesPerGroup)
+	case f.inodeSize < 128 || f.inodeSize > f.blockSize || f.inodeSize&(f.inodeSize-1) != 0:
+		return nil, fmt.Errorf("the inode size, %d, is not valid", f.inodeSize)
+	case f.firstDataBlock >= f.blocks:
+		return nil, fmt.Errorf("the first data block, %d, is past the end, %d", f.firstDataBlock, f.blocks)
+	case int64(f.blocks) < 0 || int64(f.blocks) > size/f.blockSize:
+		return nil, fmt.Errorf("the file system is %d blocks of %d bytes, but the device only %d bytes", f.blocks, f.blockSize, size)
+	}
+	f.groups = uint32((f.blocks - f.firstDataBlock + f.blocksPerGroup - 1) / f.blocksPerGroup)
+	if uint64(f.groups)*uint64(f.inodesPerGroup) != uint64(f.inodes) {
+		return nil, fmt.Errorf("%d groups of %d inodes is not %d inodes", f.groups, f.inodesPerGroup, f.inodes)
+	}
+	f.csumSeed = crc32c(^uint32(0), f.sb[offUUID:offUUID+16])
+	if f.incompat&incompatCsumSeed != 0 {
+		f.csumSeed = f.le32(offChecksumSeed)
+	}
+	// The group descriptors are in the blocks after the superblock.
+	f.gdt = make([]byte, int64(f.groups)*f.descSize)
+	if _, err := r.ReadAt(f.gdt, (int64(f.firstDataBlock)+1)*f.blockSize); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// desc returns the descriptor of group g.
+func (f *fs) desc(g uint32) []byte {
+	return f.gdt[int64(g)*f.descSize : int64(g+1)*f.descSize]
+}
+
+// field returns a field of a group descriptor, with the high bits from hi
+// if descriptors are 64 bytes.
+func (f *fs) field(d []byte, lo, hi int, size int) uint64 {
+	var v uint64
+	if size == 2 {
+		v = uint64(binary.LittleEndian.Uint16(d[lo:]))
+	} else {
+		v = uint64(binary.LittleEndian.Uint32(d[lo:]))
+	}
+	if f.descSize >= 64 {
+		if size == 2 {
+			v |= uint64(binary.LittleEndian.Uint16(d[hi:])) << 16
+		} else {
+			v |= uint64(binary.LittleEndian.Uint32(d[hi:])) << 32
+		}
+	}
+	return v
+}
+
+// descChecksum returns what the checksum of the descriptor of group g
+// should be, and whether it has one.
+func (f *fs) descChecksum(g uint32) (uint16, bool) {
+	d := append([]byte(nil), f.desc(g)...)
+	d[offGDChecksum], d[offGDChecksum+1] = 0, 0
+	var le [4]byte
+	binary.LittleEndian.PutUint32(le[:], g)
+	switch {
+	case f.roCompat&roCompatMetaCsum != 0:
+		return uint16(crc32c(crc32c(f.csumSeed, le[:]), d)), true
+	case f.roCompat&roCompatGDTCsum != 0:
+		crc := crc16(0xffff, f.sb[offUUID:offUUID+16])
+		crc = crc16(crc, le[:])
+		crc = crc16(crc, d[:offGDChecksum])
+		if f.descSize > offGDChecksum+2 {
+			crc = crc16(crc, d[offGDChecksum+2:])
+		}
+		return crc, true
+	}
+	return 0, false
+}
+
+// groupBlocks returns how many blocks group g has; the last may have fewer
+// than the others.
+func (f *fs) groupBlocks(g uint32) uint64 {
+	start := f.firstDataBlock + uint64(g)*f.blocksPerGroup
+	if f.blocks-start < f.blocksPerGroup {
+		return f.blocks - start
+	}
+	return f.blocksPerGroup
+}
+
+// free counts the clear bits of the first n of the bitmap in block b.
+func (f *fs) free(b uint64, n uint64) (uint64, error) {
+	m := make([]byte, f.blockSize)
+	if _, err := f.r.ReadAt(m, int64(b)*f.blockSize); err != nil {
+		return 0, err
+	}
+	var used uint64
+	for i := uint64(0); i < n/8; i++ {
+		used += uint64(bits.OnesCount8(m[i]))
+	}
+	if n%8 != 0 {
+		used += uint64(bits.OnesCount8(m[n/8] & (1<<(n%8) - 1)))
+	}
+	return n - used, nil
+}
+
+// checkGroups checks the group descriptors, and the free counts in them
+// against the bitmaps.
+func (f *fs) checkGroups(r *Report) error {
+	for g := uint32(0); g < f.groups; g++ {
+		d := f.desc(g)
+		if want, ok := f.descChecksum(g); ok && binary.LittleEndian.Uint16(d[offGDChecksum:]) != want {
+			r.errorf("group %d: the checksum of the descriptor is wrong", g)
+			continue
+		}
+		bb := f.field(d, offBlockBitmap, offBlockBitmapHi, 4)
+		ib := f.field(d, offInodeBitmap, offInodeBitmapHi, 4)
+		it := f.field(d, offInodeTable, offInodeTableHi, 4)
+		itBlocks := (uint64(f.inodesPerGroup)*uint64(f.inodeSize) + uint64(f.blockSize) - 1) / uint64(f.blockSize)
+		if bb < f.firstDataBlock || bb >= f.blocks || ib < f.firstDataBlock || ib >= f.blocks ||
+			it < f.firstDataBlock || it+itBlocks > f.blocks {
+			r.errorf("group %d: the bitmaps or the inode table are outside the file system", g)
+			continue
+		}
+		flags := binary.LittleEndian.Uint16(d[offFlags:])
+		if f.roCompat&(roCompatGDTCsum|roCompatMetaCsum) == 0 {
+			flags = 0
+		}
+		if flags&bgBlockUninit == 0 {
+			n, err := f.free(bb, f.groupBlocks(g))
+			if err != nil {
+				return err
+			}
+			if got := f.field(d, offFreeBlocks, offFreeBlocksHi, 2); got != n {
+				r.errorf("group %d: %d blocks are free, but the descriptor says %d", g, n, got)
+			}
+		}
+		if flags&bgInodeUninit == 0 {
+			n, err := f.free(ib, uint64(f.inodesPerGroup))
+			if err != nil {
+				return err
+			}
+			if got := f.field(d, offFreeInodes, offFreeInodesHi, 2); got != n {
+				r.errorf("group %d: %d inodes are free, but the descriptor says %d", g, n, got)
+			}
+		}
+	}
+	return nil
+}
+
+// inode returns whether inode n is in use, and its links count and dtime.
+func (f *fs) inode(n uint32) (bool, uint16, uint32, error) {
+	g, i := (n-1)/f.inodesPerGroup, (n-1)%f.inodesPerGroup
+	d := f.desc(g)
+	if flags := binary.LittleEndian.Uint16(d[offFlags:]); f.roCompat&(roCompatGDTCsum|roCompatMetaCsum) != 0 && flags&bgInodeUninit != 0 {
+		return false, 0, 0, nil
+	}
+	var bit [1]byte
+	if _, err := f.r.ReadAt(bit[:], int64(f.field(d, offInodeBitmap, offInodeBitmapHi, 4))*f.blockSize+int64(i/8)); err != nil {
+		return false, 0, 0, err
+	}
+	b := make([]byte, 128)
+	if _, err := f.r.ReadAt(b, int64(f.field(d, offInodeTable, offInodeTableHi, 4))*f.blockSize+int64(i)*f.inodeSize); err != nil {
+		return false, 0, 0, err
+	}
+	return bit[0]&(1<<(i%8)) != 0, binary.LittleEndian.Uint16(b[offLinksCount:]), binary.LittleEndian.Uint32(b[offDtime:]), nil
+}
+
+// checkOrphans follows the list of orphan inodes, which are linked by
+// their dtimes, and returns an error for the first link which is wrong.
+func (f *fs) checkOrphans() (int, error) {
+	seen := map[uint32]bool{}
+	for n := f.le32(offLastOrphan); n != 0; {
+		if n > f.inodes {
+			return len(seen), fmt.Errorf("orphan inode %d is out of range", n)
+		}
+		if seen[n] {
+			return len(seen), fmt.Errorf("the list of orphan inodes loops at inode %d", n)
+		}
+		seen[n] = true
+		used, _, next, err := f.inode(n)
+		if err != nil {
+			return len(seen), err
+		}
+		if !used {
+			return len(seen), fmt.Errorf("orphan inode %d is not in use", n)
+		}
+		n = next
+	}
+	return len(seen), nil
+}
+
+// Check checks the ext2, ext3 or ext4 file system of size bytes on r: its
+// superblock, its group descriptors, that the free counts in them are
+// those of the bitmaps, and its list of orphan inodes, which the kernel
+// deletes or truncates when it mounts it.
+//
+// If w is not nil, and the list of orphan inodes is broken, it is cleared,
+// on w, so that the kernel does not trip over it; the inodes which were on
+// it are then left over until the file system is checked with e2fsck. If
+// the file system was not unmounted cleanly, and has no journal to replay
+// and nothing else wrong, it is marked clean. Nothing else is fixed.
+func Check(r io.ReaderAt, w io.WriterAt, size int64) (*Report, error) {
+	f, err := open(r, size)
+	if err != nil {
+		return nil, err
+	}
+	rep := &Report{}
+	if f.roCompat&roCompatMetaCsum != 0 && f.le32(offChecksum) != f.sbChecksum() {
+		rep.errorf("the checksum of the superblock is wrong")
+		// Nothing in it can be trusted.
+		return rep, nil
+	}
+	state := f.le16(offState)
+	if state&stateErrors != 0 {
+		rep.errorf("the kernel found errors in the file system")
+	}
+	if err := f.checkGroups(rep); err != nil {
+		return nil, err
+	}
+
+	var fixed []string
+	if f.le32(offFeatureCompat)&compatOrphanFile == 0 {
+		if n, err := f.checkOrphans(); err != nil {
+			msg := fmt.Sprintf("the list of orphan inodes is broken after %d inodes: %v", n, err)
+			if w == nil || len(rep.Errors) > 0 {
+				rep.errorf("%s", msg)
+			} else {
+				binary.LittleEndian.PutUint32(f.sb[offLastOrphan:], 0)
+				fixed = append(fixed, msg+"; it was cleared")
+			}
+		}
+	}
+
+	journal := f.le32(offFeatureCompat)&compatHasJournal != 0
+	if state&stateValid == 0 && !(journal && f.incompat&incompatRecover != 0) {
+		msg := "the file system was not unmounted cleanly"
+		if w == nil || len(rep.Errors) > 0 {
+			rep.errorf("%s", msg)
+		} else {
+			binary.LittleEndian.PutUint16(f.sb[offState:], state|stateValid)
+			fixed = append(fixed, msg+"; nothing else is wrong, so it is marked clean")
+		}
+	}
+
+	if len(fixed) == 0 {
+		return rep, nil
+	}
+	if f.roCompat&roCompatMetaCsum != 0 {
+		binary.LittleEndian.PutUint32(f.sb[offChecksum:], f.sbChecksum())
+	}
+	if _, err := w.WriteAt(f.sb, superblockOff); err != nil {
+		return nil, err
+	}
+	rep.Fixed = fixed
+	return rep, nil
+}
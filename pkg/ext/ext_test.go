@@ -0,0 +1,197 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// image is a file system in memory.
+type image []byte
+
+func (i image) ReadAt(b []byte, off int64) (int, error) {
+	return copy(b, i[off:]), nil
+}
+
+func (i image) WriteAt(b []byte, off int64) (int, error) {
+	return copy(i[off:], b), nil
+}
+
+// Where the parts of the file systems newImage makes are, in blocks of 1K.
+const (
+	gdtBlock         = 2
+	blockBitmapBlock = 3
+	inodeBitmapBlock = 4
+	inodeTableBlock  = 5
+)
+
+// newImage makes an ext2 file system of 64 blocks of 1K, in one group, with
+// 16 inodes, of which the first 11 are used.
+func newImage() image {
+	img := make(image, 64*1024)
+	le16 := func(o int, v uint16) { binary.LittleEndian.PutUint16(img[superblockOff+o:], v) }
+	le32 := func(o int, v uint32) { binary.LittleEndian.PutUint32(img[superblockOff+o:], v) }
+	le32(offInodesCount, 16)
+	le32(offBlocksCount, 64)
+	le32(offFirstDataBlock, 1)
+	le32(offBlocksPerGroup, 8192)
+	le32(offInodesPerGroup, 16)
+	le16(offMagic, magic)
+	le16(offState, stateValid)
+	le32(offRevLevel, 1)
+	le16(offInodeSize, 128)
+	copy(img[superblockOff+offUUID:], "0123456789abcdef")
+
+	d := img[gdtBlock*1024:]
+	binary.LittleEndian.PutUint32(d[offBlockBitmap:], blockBitmapBlock)
+	binary.LittleEndian.PutUint32(d[offInodeBitmap:], inodeBitmapBlock)
+	binary.LittleEndian.PutUint32(d[offInodeTable:], inodeTableBlock)
+	// Blocks 1 to 6 are used, of 63.
+	binary.LittleEndian.PutUint16(d[offFreeBlocks:], 57)
+	binary.LittleEndian.PutUint16(d[offFreeInodes:], 5)
+	img[blockBitmapBlock*1024] = 0x3f
+	img[inodeBitmapBlock*1024] = 0xff
+	img[inodeBitmapBlock*1024+1] = 0x07
+	return img
+}
+
+// setDtime sets the dtime of inode n.
+func setDtime(img image, n, dtime uint32) {
+	binary.LittleEndian.PutUint32(img[inodeTableBlock*1024+int(n-1)*128+offDtime:], dtime)
+}
+
+func TestCheck(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		change func(image)
+		errors []string
+		fixed  []string
+	}{
+		{"clean", func(image) {}, nil, nil},
+		{"free blocks", func(img image) {
+			img[blockBitmapBlock*1024+1] = 1
+		}, []string{"group 0: 56 blocks are free, but the descriptor says 57"}, nil},
+		{"free inodes", func(img image) {
+			img[inodeBitmapBlock*1024+1] = 0x0f
+		}, []string{"group 0: 4 inodes are free, but the descriptor says 5"}, nil},
+		{"kernel errors", func(img image) {
+			img[superblockOff+offState] = stateValid | stateErrors
+		}, []string{"the kernel found errors in the file system"}, nil},
+		{"not clean", func(img image) {
+			img[superblockOff+offState] = 0
+		}, nil, []string{"the file system was not unmounted cleanly; nothing else is wrong, so it is marked clean"}},
+		{"orphans", func(img image) {
+			binary.LittleEndian.PutUint32(img[superblockOff+offLastOrphan:], 11)
+			setDtime(img, 11, 10)
+		}, nil, nil},
+		{"orphans loop", func(img image) {
+			binary.LittleEndian.PutUint32(img[superblockOff+offLastOrphan:], 11)
+			setDtime(img, 11, 10)
+			setDtime(img, 10, 11)
+		}, nil, []string{"the list of orphan inodes is broken after 2 inodes: the list of orphan inodes loops at inode 11; it was cleared"}},
+		{"orphan out of range", func(img image) {
+			binary.LittleEndian.PutUint32(img[superblockOff+offLastOrphan:], 11)
+			setDtime(img, 11, 1000)
+		}, nil, []string{"the list of orphan inodes is broken after 1 inodes: orphan inode 1000 is out of range; it was cleared"}},
+		{"orphan not in use", func(img image) {
+			binary.LittleEndian.PutUint32(img[superblockOff+offLastOrphan:], 12)
+		}, nil, []string{"the list of orphan inodes is broken after 1 inodes: orphan inode 12 is not in use; it was cleared"}},
+	} {
+		img := newImage()
+		tt.change(img)
+		r, err := Check(img, img, int64(len(img)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(r.Errors, tt.errors) || !reflect.DeepEqual(r.Fixed, tt.fixed) {
+			t.Errorf("%s: got %q, fixed %q, want %q, fixed %q", tt.name, r.Errors, r.Fixed, tt.errors, tt.fixed)
+			continue
+		}
+		if tt.fixed == nil {
+			continue
+		}
+		if r, err = Check(img, nil, int64(len(img))); err != nil || r.Errors != nil {
+			t.Errorf("%s: after fixing, got %+v, %v, want no errors", tt.name, r, err)
+		}
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	img := newImage()
+	img[superblockOff+offState] = 0
+	orig := append(image(nil), img...)
+	r, err := Check(img, nil, int64(len(img)))
+	if want := []string{"the file system was not unmounted cleanly"}; err != nil || !reflect.DeepEqual(r.Errors, want) {
+		t.Errorf("Check: got %+v, %v, want %q", r, err, want)
+	}
+	if !reflect.DeepEqual(img, orig) {
+		t.Errorf("Check with no writer changed the file system")
+	}
+}
+
+func TestCheckChecksums(t *testing.T) {
+	img := newImage()
+	sb := img[superblockOff : superblockOff+superblockSize]
+	d := img[gdtBlock*1024 : gdtBlock*1024+32]
+	binary.LittleEndian.PutUint32(sb[offFeatureROCompat:], roCompatGDTCsum)
+	f, err := open(img, int64(len(img)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := f.descChecksum(0)
+	binary.LittleEndian.PutUint16(d[offGDChecksum:], c)
+	if r, err := Check(img, nil, int64(len(img))); err != nil || r.Errors != nil {
+		t.Errorf("gdt_csum: got %+v, %v, want no errors", r, err)
+	}
+	d[offGDChecksum]++
+	if r, err := Check(img, nil, int64(len(img))); err != nil || len(r.Errors) != 1 {
+		t.Errorf("gdt_csum, bad checksum: got %+v, %v, want an error", r, err)
+	}
+
+	binary.LittleEndian.PutUint32(sb[offFeatureROCompat:], roCompatMetaCsum)
+	if r, err := Check(img, nil, int64(len(img))); err != nil || !reflect.DeepEqual(r.Errors, []string{"the checksum of the superblock is wrong"}) {
+		t.Errorf("metadata_csum, bad superblock checksum: got %+v, %v", r, err)
+	}
+	binary.LittleEndian.PutUint32(sb[offChecksum:], crc32c(^uint32(0), sb[:offChecksum]))
+	if f, err = open(img, int64(len(img))); err != nil {
+		t.Fatal(err)
+	}
+	c, _ = f.descChecksum(0)
+	binary.LittleEndian.PutUint16(d[offGDChecksum:], c)
+	if r, err := Check(img, nil, int64(len(img))); err != nil || r.Errors != nil {
+		t.Errorf("metadata_csum: got %+v, %v, want no errors", r, err)
+	}
+}
+
+func TestCRC(t *testing.T) {
+	// The check values of CRC-16/MODBUS and CRC-32C.
+	if got := crc16(0xffff, []byte("123456789")); got != 0x4b37 {
+		t.Errorf("crc16: got %#x, want 0x4b37", got)
+	}
+	if got := ^crc32c(^uint32(0), []byte("123456789")); got != 0xe3069283 {
+		t.Errorf("crc32c: got %#x, want 0xe3069283", got)
+	}
+}
+
+func TestNotExt(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		change func(image)
+	}{
+		{"no magic", func(img image) { img[superblockOff+offMagic] = 0 }},
+		{"too big", func(img image) { img[superblockOff+offBlocksCount] = 65 }},
+		{"inodes", func(img image) { img[superblockOff+offInodesCount] = 17 }},
+		{"block size", func(img image) { img[superblockOff+offLogBlockSize] = 7 }},
+	} {
+		img := newImage()
+		tt.change(img)
+		if _, err := Check(img, nil, int64(len(img))); err == nil {
+			t.Errorf("%s: got nil, want error", tt.name)
+		}
+	}
+}
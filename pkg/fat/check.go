@@ -0,0 +1,348 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// A Report is what Check found wrong with a file system.
+type Report struct {
+	// Errors are the problems which are left.
+	Errors []string
+	// Fixed are the problems which were fixed.
+	Fixed []string
+}
+
+func (r *Report) errorf(f string, v ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(f, v...))
+}
+
+// Where the things Check looks at are, and what they are.
+const (
+	// The state byte, in which Linux sets bit 0 while the file system is
+	// mounted, is a reserved byte of the extended boot record.
+	fat16StateOff = 37
+	fat32StateOff = 65
+	stateDirty    = 0x01
+
+	// The second entry of the FAT has a bit which is set when the file
+	// system was unmounted cleanly, and one which is set when there were
+	// no I/O errors.
+	fat16Clean   = 0x8000
+	fat16NoError = 0x4000
+	fat32Clean   = 0x08000000
+	fat32NoError = 0x04000000
+
+	dirEntrySize = 32
+	attrVolumeID = 0x08
+	attrDir      = 0x10
+	attrLFN      = 0x0f
+)
+
+// fs is a FAT12, FAT16 or FAT32 file system being checked.
+type fs struct {
+	r    io.ReaderAt
+	bits int // 12, 16 or 32.
+
+	sectorSize, clusterSize int64
+	reserved                int64
+	numFATs                 int64
+	fatSectors              int64
+	rootEntries             int64
+	rootCluster             uint32
+	// dataStart is the byte cluster 2 starts at.
+	dataStart int64
+	clusters  uint32
+
+	fat  []byte
+	used []bool
+}
+
+// entry returns FAT entry n.
+func (f *fs) entry(n uint32) uint32 {
+	switch f.bits {
+	case 12:
+		v := uint32(binary.LittleEndian.Uint16(f.fat[n+n/2:]))
+		if n&1 != 0 {
+			return v >> 4
+		}
+		return v & 0xfff
+	case 16:
+		return uint32(binary.LittleEndian.Uint16(f.fat[2*n:]))
+	}
+	return binary.LittleEndian.Uint32(f.fat[4*n:]) & 0x0fffffff
+}
+
+// bad is the value of the entries of bad clusters; those above it are the
+// ends of chains.
+func (f *fs) bad() uint32 {
+	switch f.bits {
+	case 12:
+		return 0xff7
+	case 16:
+		return 0xfff7
+	}
+	return 0x0ffffff7
+}
+
+// open reads the boot sector and the first FAT of the file system of size
+// bytes on r.
+func open(r io.ReaderAt, size int64) (*fs, []byte, error) {
+	b := make([]byte, SectorSize)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return nil, nil, err
+	}
+	le16 := func(o int) int64 { return int64(binary.LittleEndian.Uint16(b[o:])) }
+	le32 := func(o int) int64 { return int64(binary.LittleEndian.Uint32(b[o:])) }
+	f := &fs{
+		r:           r,
+		sectorSize:  le16(11),
+		reserved:    le16(14),
+		numFATs:     int64(b[16]),
+		rootEntries: le16(17),
+		fatSectors:  le16(22),
+	}
+	spc := int64(b[13])
+	total := le16(19)
+	if total == 0 {
+		total = le32(32)
+	}
+	if f.fatSectors == 0 {
+		f.fatSectors = le32(36)
+		f.rootCluster = uint32(le32(44))
+	}
+	switch {
+	case f.sectorSize != 512 && f.sectorSize != 1024 && f.sectorSize != 2048 && f.sectorSize != 4096,
+		spc == 0 || spc&(spc-1) != 0,
+		f.reserved == 0, f.numFATs == 0, f.fatSectors == 0:
+		return nil, nil, fmt.Errorf("not a FAT file system: its boot sector is not valid")
+	}
+	f.clusterSize = spc * f.sectorSize
+	rootSectors := (f.rootEntries*dirEntrySize + f.sectorSize - 1) / f.sectorSize
+	f.dataStart = (f.reserved + f.numFATs*f.fatSectors + rootSectors) * f.sectorSize
+	if total*f.sectorSize > size {
+		return nil, nil, fmt.Errorf("the file system is %d bytes, but the device only %d", total*f.sectorSize, size)
+	}
+	if total*f.sectorSize <= f.dataStart {
+		return nil, nil, fmt.Errorf("the file system has no room for clusters")
+	}
+	f.clusters = uint32((total*f.sectorSize - f.dataStart) / f.clusterSize)
+	// The type is from the number of clusters, whatever else it says.
+	switch {
+	case f.clusters < 4085:
+		f.bits = 12
+	case f.clusters < 65525:
+		f.bits = 16
+	default:
+		f.bits = 32
+	}
+	if f.bits == 32 && f.rootCluster == 0 || f.bits != 32 && f.rootEntries == 0 {
+		return nil, nil, fmt.Errorf("FAT%d file system with the boot sector of another type", f.bits)
+	}
+	if need := (int64(f.clusters) + 2) * int64(f.bits) / 8; need > f.fatSectors*f.sectorSize {
+		return nil, nil, fmt.Errorf("the FAT is %d bytes, but it needs %d", f.fatSectors*f.sectorSize, need)
+	}
+	f.fat = make([]byte, f.fatSectors*f.sectorSize)
+	if _, err := r.ReadAt(f.fat, f.reserved*f.sectorSize); err != nil {
+		return nil, nil, err
+	}
+	f.used = make([]bool, f.clusters+2)
+	return f, b, nil
+}
+
+// chain follows the chain of clusters from first, marking them used, and
+// returns them. It stops where the chain is broken, and reports it.
+func (f *fs) chain(r *Report, name string, first uint32) []uint32 {
+	var c []uint32
+	for n := first; ; {
+		if n < 2 || n >= f.clusters+2 {
+			r.errorf("%s: cluster %d is out of range", name, n)
+			return c
+		}
+		if f.used[n] {
+			r.errorf("%s: cluster %d is cross-linked", name, n)
+			return c
+		}
+		f.used[n] = true
+		c = append(c, n)
+		next := f.entry(n)
+		switch {
+		case next == 0:
+			r.errorf("%s: cluster %d is in a chain, but free", name, n)
+			return c
+		case next == f.bad():
+			r.errorf("%s: cluster %d is in a chain, but bad", name, n)
+			return c
+		case next > f.bad():
+			return c
+		}
+		n = next
+	}
+}
+
+// read reads the clusters c.
+func (f *fs) read(c []uint32) ([]byte, error) {
+	b := make([]byte, int64(len(c))*f.clusterSize)
+	for i, n := range c {
+		off := f.dataStart + int64(n-2)*f.clusterSize
+		if _, err := f.r.ReadAt(b[int64(i)*f.clusterSize:int64(i+1)*f.clusterSize], off); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// dir checks the directory d, called name, and what is in it.
+func (f *fs) dir(r *Report, name string, d []byte) error {
+	for o := 0; o+dirEntrySize <= len(d); o += dirEntrySize {
+		e := d[o : o+dirEntrySize]
+		if e[0] == 0 {
+			break
+		}
+		attr := e[11]
+		if e[0] == 0xe5 || attr == attrLFN || attr&attrVolumeID != 0 {
+			continue
+		}
+		n := strings.TrimRight(string(e[:8]), " ")
+		if x := strings.TrimRight(string(e[8:11]), " "); x != "" {
+			n += "." + x
+		}
+		if n == "." || n == ".." {
+			continue
+		}
+		p := path.Join(name, n)
+		first := uint32(binary.LittleEndian.Uint16(e[26:]))
+		if f.bits == 32 {
+			first |= uint32(binary.LittleEndian.Uint16(e[20:])) << 16
+		}
+		size := int64(binary.LittleEndian.Uint32(e[28:]))
+		if first == 0 {
+			if attr&attrDir != 0 {
+				r.errorf("%s: directory has no clusters", p)
+			} else if size != 0 {
+				r.errorf("%s: file of %d bytes has no clusters", p, size)
+			}
+			continue
+		}
+		c := f.chain(r, p, first)
+		if attr&attrDir == 0 {
+			if want := (size + f.clusterSize - 1) / f.clusterSize; int64(len(c)) != want {
+				r.errorf("%s: file of %d bytes has %d clusters, not %d", p, size, len(c), want)
+			}
+			continue
+		}
+		b, err := f.read(c)
+		if err != nil {
+			return err
+		}
+		if err := f.dir(r, p, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check checks the FAT12, FAT16 or FAT32 file system of size bytes on r. It
+// checks the boot sector, that the FATs are the same, and that the chains
+// of clusters of the files and directories are whole, do not cross, and
+// are as long as the files; and it counts the clusters which are used but
+// in no chain. If w is not nil, the bits which say that the file system
+// was not unmounted cleanly are cleared, on w; nothing else is fixed.
+func Check(r io.ReaderAt, w io.WriterAt, size int64) (*Report, error) {
+	f, boot, err := open(r, size)
+	if err != nil {
+		return nil, err
+	}
+	rep := &Report{}
+	if boot[510] != 0x55 || boot[511] != 0xaa {
+		rep.errorf("the boot sector has no signature")
+	}
+
+	// The dirty bits.
+	stateOff, clean, noError := int64(fat16StateOff), uint32(fat16Clean), uint32(fat16NoError)
+	if f.bits == 32 {
+		stateOff, clean, noError = fat32StateOff, fat32Clean, fat32NoError
+	}
+	var dirty []string
+	if boot[stateOff]&stateDirty != 0 {
+		dirty = append(dirty, "the boot sector")
+	}
+	if f.bits != 12 && f.entry(1)&clean == 0 {
+		dirty = append(dirty, "the FAT")
+	}
+	if f.bits != 12 && f.entry(1)&noError == 0 {
+		rep.errorf("the FAT says there were I/O errors")
+	}
+
+	for i := int64(1); i < f.numFATs; i++ {
+		b := make([]byte, len(f.fat))
+		if _, err := r.ReadAt(b, (f.reserved+i*f.fatSectors)*f.sectorSize); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(b, f.fat) {
+			rep.errorf("FAT %d is not the same as FAT 1", i+1)
+		}
+	}
+
+	// The tree of directories, from the root.
+	var root []byte
+	if f.bits == 32 {
+		if root, err = f.read(f.chain(rep, "/", f.rootCluster)); err != nil {
+			return nil, err
+		}
+	} else {
+		root = make([]byte, f.rootEntries*dirEntrySize)
+		if _, err := r.ReadAt(root, (f.reserved+f.numFATs*f.fatSectors)*f.sectorSize); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.dir(rep, "/", root); err != nil {
+		return nil, err
+	}
+	lost := 0
+	for n := uint32(2); n < f.clusters+2; n++ {
+		if e := f.entry(n); e != 0 && e != f.bad() && !f.used[n] {
+			lost++
+		}
+	}
+	if lost > 0 {
+		rep.errorf("%d clusters are used, but in no file or directory", lost)
+	}
+
+	if len(dirty) == 0 {
+		return rep, nil
+	}
+	msg := fmt.Sprintf("%s says the file system was not unmounted cleanly", strings.Join(dirty, " and "))
+	if w == nil {
+		rep.errorf("%s", msg)
+		return rep, nil
+	}
+	if _, err := w.WriteAt([]byte{boot[stateOff] &^ stateDirty}, stateOff); err != nil {
+		return nil, err
+	}
+	if f.bits != 12 {
+		for i := int64(0); i < f.numFATs; i++ {
+			off := (f.reserved+i*f.fatSectors)*f.sectorSize + int64(f.bits/8)
+			b := make([]byte, f.bits/8)
+			if f.bits == 16 {
+				binary.LittleEndian.PutUint16(b, uint16(f.entry(1)|clean))
+			} else {
+				// The top 4 bits are reserved, and kept.
+				binary.LittleEndian.PutUint32(b, binary.LittleEndian.Uint32(f.fat[4:])|clean)
+			}
+			if _, err := w.WriteAt(b, off); err != nil {
+				return nil, err
+			}
+		}
+	}
+	rep.Fixed = append(rep.Fixed, msg)
+	return rep, nil
+}
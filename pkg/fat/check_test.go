@@ -0,0 +1,180 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// image is a file system in memory.
+type image []byte
+
+func (i image) ReadAt(b []byte, off int64) (int, error) {
+	return copy(b, i[off:]), nil
+}
+
+func (i image) WriteAt(b []byte, off int64) (int, error) {
+	return copy(i[off:], b), nil
+}
+
+// dirent makes a directory entry.
+func dirent(name string, attr byte, first, size uint32) []byte {
+	e := make([]byte, dirEntrySize)
+	copy(e, name)
+	e[11] = attr
+	binary.LittleEndian.PutUint16(e[20:], uint16(first>>16))
+	binary.LittleEndian.PutUint16(e[26:], uint16(first))
+	binary.LittleEndian.PutUint32(e[28:], size)
+	return e
+}
+
+// fat32 makes a FAT32 file system of 64MiB, with clusters of a sector, and
+// a directory SUB with a file FILE.TXT of 1000 bytes in it, in clusters
+// 3, then 4 and 5.
+func fat32(t *testing.T) (image, Layout) {
+	const size = 64 << 20
+	img := make(image, size)
+	if err := Format(img, size, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLayout(size, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, v := range map[uint32]uint32{3: 0x0fffffff, 4: 5, 5: 0x0fffffff} {
+		setFAT32(img, l, n, v)
+	}
+	copy(img[int64(l.DataStart())*SectorSize:], dirent("SUB        ", attrDir, 3, 0))
+	sub := int64(l.DataStart()+1) * SectorSize
+	copy(img[sub:], dirent(".          ", attrDir, 3, 0))
+	copy(img[sub+32:], dirent("..         ", attrDir, 0, 0))
+	copy(img[sub+64:], dirent("FILE    TXT", 0, 4, 1000))
+	return img, l
+}
+
+// setFAT32 sets entry n of both FATs to v.
+func setFAT32(img image, l Layout, n, v uint32) {
+	for i := uint32(0); i < numFATs; i++ {
+		binary.LittleEndian.PutUint32(img[int64(reservedSectors+i*l.FATSectors)*SectorSize+4*int64(n):], v)
+	}
+}
+
+func TestCheckFAT32(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		change func(image, Layout)
+		errors []string
+	}{
+		{"clean", func(image, Layout) {}, nil},
+		{"cross-linked", func(img image, l Layout) {
+			copy(img[int64(l.DataStart())*SectorSize+32:], dirent("OTHER      ", 0, 5, 512))
+		}, []string{"/OTHER: cluster 5 is cross-linked", "/OTHER: file of 512 bytes has 0 clusters, not 1"}},
+		{"free in chain", func(img image, l Layout) {
+			setFAT32(img, l, 4, 0)
+		}, []string{"/SUB/FILE.TXT: cluster 4 is in a chain, but free", "/SUB/FILE.TXT: file of 1000 bytes has 1 clusters, not 2", "1 clusters are used, but in no file or directory"}},
+		{"out of range", func(img image, l Layout) {
+			setFAT32(img, l, 4, 0x0ffffff0)
+		}, []string{"/SUB/FILE.TXT: cluster 268435440 is out of range", "/SUB/FILE.TXT: file of 1000 bytes has 1 clusters, not 2", "1 clusters are used, but in no file or directory"}},
+		{"lost", func(img image, l Layout) {
+			setFAT32(img, l, 100, 0x0fffffff)
+		}, []string{"1 clusters are used, but in no file or directory"}},
+		{"FATs differ", func(img image, l Layout) {
+			img[int64(reservedSectors+l.FATSectors)*SectorSize+400] = 1
+		}, []string{"FAT 2 is not the same as FAT 1"}},
+	} {
+		img, l := fat32(t)
+		tt.change(img, l)
+		r, err := Check(img, nil, int64(len(img)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(r.Errors, tt.errors) || r.Fixed != nil {
+			t.Errorf("%s: got %q, fixed %q, want %q", tt.name, r.Errors, r.Fixed, tt.errors)
+		}
+	}
+}
+
+func TestCheckDirty(t *testing.T) {
+	img, l := fat32(t)
+	img[fat32StateOff] |= stateDirty
+	setFAT32(img, l, 1, 0x07ffffff)
+	want := "the boot sector and the FAT says the file system was not unmounted cleanly"
+	r, err := Check(img, nil, int64(len(img)))
+	if err != nil || !reflect.DeepEqual(r.Errors, []string{want}) {
+		t.Fatalf("Check: got %+v, %v, want error %q", r, err, want)
+	}
+	r, err = Check(img, img, int64(len(img)))
+	if err != nil || r.Errors != nil || !reflect.DeepEqual(r.Fixed, []string{want}) {
+		t.Fatalf("Check fixing: got %+v, %v, want fixed %q", r, err, want)
+	}
+	r, err = Check(img, nil, int64(len(img)))
+	if err != nil || r.Errors != nil || r.Fixed != nil {
+		t.Errorf("Check after fixing: got %+v, %v, want nothing", r, err)
+	}
+}
+
+// fat16 makes a FAT16 file system of 5000 sectors, with clusters of a
+// sector, a root directory of 512 entries, and a file A of 600 bytes in
+// clusters 2 and 3.
+func fat16() image {
+	img := make(image, 5000*SectorSize)
+	b := img[:SectorSize]
+	le := binary.LittleEndian
+	le.PutUint16(b[11:], SectorSize)
+	b[13] = 1
+	le.PutUint16(b[14:], 1)
+	b[16] = 2
+	le.PutUint16(b[17:], 512)
+	le.PutUint16(b[19:], 5000)
+	le.PutUint16(b[22:], 20)
+	b[510], b[511] = 0x55, 0xaa
+	for i := 0; i < 2; i++ {
+		fat := img[(1+20*i)*SectorSize:]
+		for n, v := range []uint16{0xfff8, 0xffff, 3, 0xffff} {
+			le.PutUint16(fat[2*n:], v)
+		}
+	}
+	copy(img[41*SectorSize:], dirent("A          ", 0, 2, 600))
+	return img
+}
+
+func TestCheckFAT16(t *testing.T) {
+	img := fat16()
+	r, err := Check(img, nil, int64(len(img)))
+	if err != nil || r.Errors != nil {
+		t.Fatalf("Check: got %+v, %v, want no errors", r, err)
+	}
+
+	img[fat16StateOff] |= stateDirty
+	r, err = Check(img, img, int64(len(img)))
+	if err != nil || r.Errors != nil || len(r.Fixed) != 1 {
+		t.Fatalf("Check fixing: got %+v, %v, want one fixed", r, err)
+	}
+	if img[fat16StateOff] != 0 {
+		t.Errorf("state after fixing: got %#x, want 0", img[fat16StateOff])
+	}
+
+	img = fat16()
+	binary.LittleEndian.PutUint16(img[41*SectorSize+28:], 1200)
+	r, err = Check(img, nil, int64(len(img)))
+	if want := []string{"/A: file of 1200 bytes has 2 clusters, not 3"}; err != nil || !reflect.DeepEqual(r.Errors, want) {
+		t.Errorf("Check: got %+v, %v, want %q", r, err, want)
+	}
+}
+
+func TestCheckNotFAT(t *testing.T) {
+	img := make(image, 1<<20)
+	if _, err := Check(img, nil, int64(len(img))); err == nil {
+		t.Errorf("Check of zeroes: got nil, want error")
+	}
+	img = fat16()
+	if _, err := Check(bytes.NewReader(img[:1<<20]), nil, 1<<20); err == nil {
+		t.Errorf("Check of a truncated file system: got nil, want error")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fat makes FAT32 file systems, and checks FAT12, FAT16 and FAT32
+// ones.
+//
+// The layout of those it makes is that of Microsoft's FAT specification: a boot sector and an
+// FSInfo sector, with backups at sectors 6 and 7, 32 reserved sectors in
+// all, two FATs, and an empty root directory in cluster 2.
+package fat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// SectorSize is the size of the sectors of the file systems Format
+	// makes.
+	SectorSize = 512
+
+	reservedSectors = 32
+	numFATs         = 2
+	rootCluster     = 2
+	fsInfoSector    = 1
+	backupSector    = 6
+
+	// minClusters is the fewest clusters a FAT32 file system should have.
+	// Linux mounts ones with fewer; other systems may not.
+	minClusters = 65525
+	// maxClusters is the most a FAT32 file system can have.
+	maxClusters = 0x0ffffff5
+)
+
+// Options say how to make a file system.
+type Options struct {
+	// Label is the volume label, at most 11 characters. "" is NO NAME.
+	Label string
+	// ID is the volume serial number.
+	ID uint32
+	// SectorsPerCluster is the size of clusters, a power of 2 up to 128.
+	// 0 picks one from the size of the file system.
+	SectorsPerCluster int
+}
+
+// Layout is where the parts of a file system are, in sectors.
+type Layout struct {
+	Sectors           uint32
+	SectorsPerCluster uint32
+	FATSectors        uint32
+	Clusters          uint32
+}
+
+// DataStart is the sector cluster 2 starts at.
+func (l Layout) DataStart() uint32 {
+	return reservedSectors + numFATs*l.FATSectors
+}
+
+// sectorsPerCluster is the cluster size Microsoft recommends for a file
+// system of n sectors.
+func sectorsPerCluster(n int64) uint32 {
+	switch {
+	case n <= 532480: // 260 MB
+		return 1
+	case n <= 16777216: // 8 GB
+		return 8
+	case n <= 33554432: // 16 GB
+		return 16
+	case n <= 67108864: // 32 GB
+		return 32
+	}
+	return 64
+}
+
+// NewLayout returns the layout of a file system of size bytes.
+func NewLayout(size int64, o Options) (Layout, error) {
+	n := size / SectorSize
+	if n > 0xffffffff {
+		return Layout{}, fmt.Errorf("%d bytes is too big for FAT32", size)
+	}
+	l := Layout{Sectors: uint32(n), SectorsPerCluster: uint32(o.SectorsPerCluster)}
+	if l.SectorsPerCluster == 0 {
+		l.SectorsPerCluster = sectorsPerCluster(n)
+	}
+	if l.SectorsPerCluster > 128 || l.SectorsPerCluster&(l.SectorsPerCluster-1) != 0 {
+		return Layout{}, fmt.Errorf("%d sectors per cluster is not a power of 2 up to 128", l.SectorsPerCluster)
+	}
+	if n < reservedSectors+numFATs+int64(l.SectorsPerCluster) {
+		return Layout{}, fmt.Errorf("%d bytes is too small for FAT32", size)
+	}
+	// This is the specification's way of finding the size of the FATs,
+	// which is sometimes a little more than needed.
+	t1 := int64(l.Sectors) - reservedSectors
+	t2 := (256*int64(l.SectorsPerCluster) + numFATs) / 2
+	l.FATSectors = uint32((t1 + t2 - 1) / t2)
+	l.Clusters = (l.Sectors - l.DataStart()) / l.SectorsPerCluster
+	if l.Clusters > maxClusters {
+		return Layout{}, fmt.Errorf("%d clusters is too many for FAT32; use bigger clusters", l.Clusters)
+	}
+	return l, nil
+}
+
+// label returns s as a volume label: upper case and padded with spaces.
+func label(s string) ([11]byte, error) {
+	var b [11]byte
+	if s == "" {
+		s = "NO NAME"
+	}
+	if len(s) > len(b) {
+		return b, fmt.Errorf("label %q is longer than %d characters", s, len(b))
+	}
+	s = strings.ToUpper(s)
+	for i := range b {
+		b[i] = ' '
+		if i < len(s) {
+			b[i] = s[i]
+		}
+	}
+	return b, nil
+}
+
+// bootSector returns the boot sector of l.
+func (l Layout) bootSector(o Options, vl [11]byte) []byte {
+	b := make([]byte, SectorSize)
+	le := binary.LittleEndian
+	// Jump over the BPB to a loop: the file system is not bootable.
+	copy(b, []byte{0xeb, 0x58, 0x90})
+	copy(b[3:11], "u-root  ")
+	le.PutUint16(b[11:], SectorSize)
+	b[13] = byte(l.SectorsPerCluster)
+	le.PutUint16(b[14:], reservedSectors)
+	b[16] = numFATs
+	b[21] = 0xf8             // Fixed disk.
+	le.PutUint16(b[24:], 32) // Sectors per track.
+	le.PutUint16(b[26:], 64) // Heads.
+	le.PutUint32(b[32:], l.Sectors)
+	le.PutUint32(b[36:], l.FATSectors)
+	le.PutUint32(b[44:], rootCluster)
+	le.PutUint16(b[48:], fsInfoSector)
+	le.PutUint16(b[50:], backupSector)
+	b[64] = 0x80 // Drive number.
+	b[66] = 0x29 // The next three fields are there.
+	le.PutUint32(b[67:], o.ID)
+	copy(b[71:82], vl[:])
+	copy(b[82:90], "FAT32   ")
+	copy(b[90:], []byte{0xeb, 0xfe})
+	b[510], b[511] = 0x55, 0xaa
+	return b
+}
+
+// fsInfo returns the FSInfo sector of l.
+func (l Layout) fsInfo() []byte {
+	b := make([]byte, SectorSize)
+	le := binary.LittleEndian
+	le.PutUint32(b, 0x41615252)
+	le.PutUint32(b[484:], 0x61417272)
+	// All clusters but the root directory's are free.
+	le.PutUint32(b[488:], l.Clusters-1)
+	le.PutUint32(b[492:], rootCluster+1)
+	le.PutUint32(b[508:], 0xaa550000)
+	return b
+}
+
+// ErrFewClusters is returned by Format, along with a usable file system,
+// if it has fewer clusters than FAT32 should.
+var ErrFewClusters = errors.New("file system has fewer than 65525 clusters, which some systems do not like")
+
+// Format makes a FAT32 file system of size bytes on w. The whole of the
+// reserved sectors, the FATs and the root directory are written, so w
+// need not be zeroed first.
+func Format(w io.WriterAt, size int64, o Options) error {
+	l, err := NewLayout(size, o)
+	if err != nil {
+		return err
+	}
+	vl, err := label(o.Label)
+	if err != nil {
+		return err
+	}
+
+	// Everything up to the end of the root directory's cluster is written
+	// in chunks, zeroes where there is nothing else.
+	end := int64(l.DataStart()+l.SectorsPerCluster) * SectorSize
+	sectors := map[int64][]byte{
+		0:                           l.bootSector(o, vl),
+		fsInfoSector:                l.fsInfo(),
+		backupSector:                l.bootSector(o, vl),
+		backupSector + fsInfoSector: l.fsInfo(),
+	}
+	// The first entries of each FAT are the media type, an end of chain
+	// marker, and the end of the root directory's chain.
+	fat := make([]byte, 12)
+	binary.LittleEndian.PutUint32(fat, 0x0ffffff8)
+	binary.LittleEndian.PutUint32(fat[4:], 0x0fffffff)
+	binary.LittleEndian.PutUint32(fat[8:], 0x0fffffff)
+	for i := uint32(0); i < numFATs; i++ {
+		sectors[int64(reservedSectors+i*l.FATSectors)] = fat
+	}
+	if o.Label != "" {
+		// The label is also the first entry of the root directory.
+		d := make([]byte, 32)
+		copy(d, vl[:])
+		d[11] = 0x08 // Volume label.
+		sectors[int64(l.DataStart())] = d
+	}
+
+	const chunk = 64 * 1024
+	buf := make([]byte, chunk)
+	for off := int64(0); off < end; off += chunk {
+		for i := range buf {
+			buf[i] = 0
+		}
+		n := int64(chunk)
+		if end-off < n {
+			n = end - off
+		}
+		for s, b := range sectors {
+			if p := s * SectorSize; p >= off && p < off+n {
+				copy(buf[p-off:], b)
+			}
+		}
+		if _, err := w.WriteAt(buf[:n], off); err != nil {
+			return err
+		}
+	}
+	if l.Clusters < minClusters {
+		return ErrFewClusters
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNewLayout(t *testing.T) {
+	for _, tt := range []struct {
+		size int64
+		spc  uint32
+	}{
+		{64 << 20, 1},
+		{1 << 30, 8},
+		{12 << 30, 16},
+		{24 << 30, 32},
+		{1 << 40, 64},
+	} {
+		l, err := NewLayout(tt.size, Options{})
+		if err != nil {
+			t.Errorf("NewLayout(%d): %v", tt.size, err)
+			continue
+		}
+		if l.SectorsPerCluster != tt.spc {
+			t.Errorf("NewLayout(%d): got %d sectors per cluster, want %d", tt.size, l.SectorsPerCluster, tt.spc)
+		}
+		// The FATs must have an entry for each cluster, and the
+		// clusters must fit.
+		if n := l.FATSectors * SectorSize / 4; n < l.Clusters+2 {
+			t.Errorf("NewLayout(%d): FAT has %d entries for %d clusters", tt.size, n, l.Clusters)
+		}
+		if end := l.DataStart() + l.Clusters*l.SectorsPerCluster; end > l.Sectors {
+			t.Errorf("NewLayout(%d): clusters end at sector %d, past %d", tt.size, end, l.Sectors)
+		}
+		if l.Clusters < minClusters {
+			t.Errorf("NewLayout(%d): %d clusters, want at least %d", tt.size, l.Clusters, minClusters)
+		}
+	}
+
+	for _, tt := range []struct {
+		size int64
+		o    Options
+	}{
+		{10 * SectorSize, Options{}},
+		{64 << 20, Options{SectorsPerCluster: 3}},
+		{64 << 20, Options{SectorsPerCluster: 256}},
+	} {
+		if _, err := NewLayout(tt.size, tt.o); err == nil {
+			t.Errorf("NewLayout(%d, %+v): got nil, want error", tt.size, tt.o)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	f, err := ioutil.TempFile("", "fat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	const size = 64 << 20
+	// Garbage which Format should clear.
+	if _, err := f.WriteAt(bytes.Repeat([]byte{0xff}, 1<<20), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Format(f, size, Options{Label: "efi", ID: 0x1234}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLayout(size, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, SectorSize)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	le := binary.LittleEndian
+	for _, c := range []struct {
+		name      string
+		got, want uint32
+	}{
+		{"bytes per sector", uint32(le.Uint16(b[11:])), SectorSize},
+		{"sectors per cluster", uint32(b[13]), l.SectorsPerCluster},
+		{"reserved sectors", uint32(le.Uint16(b[14:])), reservedSectors},
+		{"FATs", uint32(b[16]), numFATs},
+		{"sectors", le.Uint32(b[32:]), size / SectorSize},
+		{"FAT sectors", le.Uint32(b[36:]), l.FATSectors},
+		{"root cluster", le.Uint32(b[44:]), rootCluster},
+		{"volume ID", le.Uint32(b[67:]), 0x1234},
+		{"signature", uint32(le.Uint16(b[510:])), 0xaa55},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %#x, want %#x", c.name, c.got, c.want)
+		}
+	}
+	if got, want := string(b[71:82]), "EFI        "; got != want {
+		t.Errorf("label: got %q, want %q", got, want)
+	}
+	if got, want := string(b[82:90]), "FAT32   "; got != want {
+		t.Errorf("file system type: got %q, want %q", got, want)
+	}
+	backup := make([]byte, SectorSize)
+	if _, err := f.ReadAt(backup, backupSector*SectorSize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, backup) {
+		t.Errorf("backup boot sector differs from the boot sector")
+	}
+
+	if _, err := f.ReadAt(b, fsInfoSector*SectorSize); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := le.Uint32(b[488:]), l.Clusters-1; got != want {
+		t.Errorf("free clusters: got %d, want %d", got, want)
+	}
+
+	for i := uint32(0); i < numFATs; i++ {
+		fat := make([]byte, 16)
+		if _, err := f.ReadAt(fat, int64(reservedSectors+i*l.FATSectors)*SectorSize); err != nil {
+			t.Fatal(err)
+		}
+		want := []uint32{0x0ffffff8, 0x0fffffff, 0x0fffffff, 0}
+		for j, w := range want {
+			if got := le.Uint32(fat[4*j:]); got != w {
+				t.Errorf("FAT %d entry %d: got %#x, want %#x", i, j, got, w)
+			}
+		}
+	}
+
+	root := make([]byte, 64)
+	if _, err := f.ReadAt(root, int64(l.DataStart())*SectorSize); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(root[:11]), "EFI        "; got != want || root[11] != 0x08 {
+		t.Errorf("root directory label: got %q, %#x, want %q, 0x08", got, root[11], want)
+	}
+	if !bytes.Equal(root[32:], make([]byte, 32)) {
+		t.Errorf("root directory has more than a label: %x", root[32:])
+	}
+}
+
+func TestFormatSmall(t *testing.T) {
+	f, err := ioutil.TempFile("", "fat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := Format(f, 16<<20, Options{}); err != ErrFewClusters {
+		t.Errorf("Format of 16MiB: got %v, want %v", err, ErrFewClusters)
+	}
+	if err := Format(f, 64<<20, Options{Label: "much too long"}); err == nil {
+		t.Errorf("Format with a long label: got nil, want error")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package flash reads and writes NOR flash, such as the SPI flash firmware
+// is in, through the kernel's MTD devices or spidev.
+package flash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// A Flash is a NOR flash chip. Erasing sets bits to 1, a block at a time,
+// and writing can only clear them.
+type Flash interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	// Size is the size of the chip in bytes.
+	Size() int64
+	// EraseSize is the size of the blocks it is erased in.
+	EraseSize() int64
+	// Erase erases the n bytes at off, which are whole blocks.
+	Erase(off, n int64) error
+}
+
+// checkRange returns an error if the n bytes at off are not all in f.
+func checkRange(f Flash, off, n int64) error {
+	if off < 0 || n < 0 || off+n > f.Size() {
+		return fmt.Errorf("bytes %#x to %#x are not in the %#x bytes of flash", off, off+n, f.Size())
+	}
+	return nil
+}
+
+// Read reads the n bytes at off.
+func Read(f Flash, off, n int64) ([]byte, error) {
+	if err := checkRange(f, off, n); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := f.ReadAt(b, off); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Verify returns an error if the flash at off is not b.
+func Verify(f Flash, off int64, b []byte) error {
+	got, err := Read(f, off, int64(len(b)))
+	if err != nil {
+		return err
+	}
+	for i := range b {
+		if got[i] != b[i] {
+			return fmt.Errorf("verifying: byte %#x is %#02x, want %#02x", off+int64(i), got[i], b[i])
+		}
+	}
+	return nil
+}
+
+// needsErase returns whether writing want over have would need a bit set.
+func needsErase(have, want []byte) bool {
+	for i := range want {
+		if have[i]&want[i] != want[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes b at off, a block at a time, then verifies it. Blocks which
+// are already b are left alone, and blocks are only erased if they need to
+// be, to save time and wear. Where b does not cover the whole of a block,
+// the rest of it is kept.
+func Write(f Flash, off int64, b []byte) error {
+	if err := checkRange(f, off, int64(len(b))); err != nil {
+		return err
+	}
+	bs := f.EraseSize()
+	for start := off / bs * bs; start < off+int64(len(b)); start += bs {
+		have, err := Read(f, start, bs)
+		if err != nil {
+			return err
+		}
+		want := append([]byte(nil), have...)
+		// The part of the block b covers.
+		from, to := start, start+bs
+		if from < off {
+			from = off
+		}
+		if end := off + int64(len(b)); to > end {
+			to = end
+		}
+		copy(want[from-start:], b[from-off:to-off])
+		if bytes.Equal(have, want) {
+			continue
+		}
+		if needsErase(have, want) {
+			if err := f.Erase(start, bs); err != nil {
+				return fmt.Errorf("erasing block at %#x: %v", start, err)
+			}
+			for i := range have {
+				have[i] = 0xff
+			}
+		}
+		// Only write the bytes which change; erased flash is 0xff.
+		for i := 0; i < len(want); {
+			if have[i] == want[i] {
+				i++
+				continue
+			}
+			j := i
+			for j < len(want) && have[j] != want[j] {
+				j++
+			}
+			if _, err := f.WriteAt(want[i:j], start+int64(i)); err != nil {
+				return fmt.Errorf("writing at %#x: %v", start+int64(i), err)
+			}
+			i = j
+		}
+	}
+	return Verify(f, off, b)
+}
+
+// Erase erases the n bytes at off, which must be whole blocks.
+func Erase(f Flash, off, n int64) error {
+	if err := checkRange(f, off, n); err != nil {
+		return err
+	}
+	if bs := f.EraseSize(); off%bs != 0 || n%bs != 0 {
+		return fmt.Errorf("bytes %#x to %#x are not whole blocks of %#x bytes", off, off+n, bs)
+	}
+	return f.Erase(off, n)
+}
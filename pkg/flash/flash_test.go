@@ -0,0 +1,135 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flash
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memFlash is a Flash in memory, which counts its erases and writes, and,
+// like flash, can only clear bits when it is written.
+type memFlash struct {
+	b       []byte
+	bs      int64
+	erases  int
+	written int
+}
+
+func newMemFlash(size, bs int64) *memFlash {
+	return &memFlash{b: bytes.Repeat([]byte{0xff}, int(size)), bs: bs}
+}
+
+func (m *memFlash) ReadAt(b []byte, off int64) (int, error) {
+	return copy(b, m.b[off:]), nil
+}
+
+func (m *memFlash) WriteAt(b []byte, off int64) (int, error) {
+	for i, c := range b {
+		m.b[off+int64(i)] &= c
+	}
+	m.written += len(b)
+	return len(b), nil
+}
+
+func (m *memFlash) Erase(off, n int64) error {
+	if off%m.bs != 0 || n%m.bs != 0 {
+		return fmt.Errorf("erase of %#x bytes at %#x is not whole blocks", n, off)
+	}
+	for i := off; i < off+n; i++ {
+		m.b[i] = 0xff
+	}
+	m.erases += int(n / m.bs)
+	return nil
+}
+
+func (m *memFlash) Size() int64      { return int64(len(m.b)) }
+func (m *memFlash) EraseSize() int64 { return m.bs }
+func (m *memFlash) Close() error     { return nil }
+
+func TestWrite(t *testing.T) {
+	m := newMemFlash(64, 16)
+	img := make([]byte, 64)
+	for i := range img {
+		img[i] = byte(i)
+	}
+	if err := Write(m, 0, img); err != nil {
+		t.Fatal(err)
+	}
+	// Erased flash needs no erasing, and 0xff need not be written.
+	if m.erases != 0 || m.written != 64 || !bytes.Equal(m.b, img) {
+		t.Errorf("write to erased flash: %d erases, %d bytes written, flash % x, want 0, 64, % x", m.erases, m.written, m.b, img)
+	}
+
+	// Writing it again does nothing.
+	m.written = 0
+	if err := Write(m, 0, img); err != nil {
+		t.Fatal(err)
+	}
+	if m.erases != 0 || m.written != 0 {
+		t.Errorf("write of the same image: %d erases, %d bytes written, want none", m.erases, m.written)
+	}
+
+	// Setting bits in the middle of one block erases and rewrites just
+	// that block, and keeps the rest of it.
+	want := append([]byte(nil), img...)
+	want[20], want[21] = 0xff, 0xfe
+	if err := Write(m, 20, want[20:22]); err != nil {
+		t.Fatal(err)
+	}
+	if m.erases != 1 || m.written != 15 || !bytes.Equal(m.b, want) {
+		t.Errorf("write of 2 bytes: %d erases, %d bytes written, flash % x, want 1, 15, % x", m.erases, m.written, m.b, want)
+	}
+
+	if err := Write(m, 60, make([]byte, 8)); err == nil {
+		t.Errorf("write past the end: got nil, want error")
+	}
+	if err := Erase(m, 8, 16); err == nil {
+		t.Errorf("erase of part of a block: got nil, want error")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m := newMemFlash(32, 16)
+	if err := Verify(m, 4, []byte{0xff, 0xff}); err != nil {
+		t.Errorf("Verify: got %v, want nil", err)
+	}
+	err := Verify(m, 4, []byte{0xff, 0})
+	if want := "byte 0x5 is 0xff, want 0x00"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Verify: got %v, want %q", err, want)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	l, err := ParseLayout(strings.NewReader(`# Intel
+00000000:00000fff fd
+00001000:001fffff me
+
+00200000:00ffffff bios
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Layout{{"fd", 0, 0xfff}, {"me", 0x1000, 0x1fffff}, {"bios", 0x200000, 0xffffff}}
+	if !reflect.DeepEqual(l, want) {
+		t.Errorf("ParseLayout: got %v, want %v", l, want)
+	}
+	r, err := l.Find("bios")
+	if err != nil || r.Len() != 0xe00000 {
+		t.Errorf("Find(bios): got %v, %v, want a region of 0xe00000 bytes", r, err)
+	}
+	if _, err := l.Find("gbe"); err == nil {
+		t.Errorf("Find(gbe): got nil, want error")
+	}
+
+	for _, bad := range []string{"0:fff", "0-fff fd", "x:fff fd", "1000:fff fd"} {
+		if _, err := ParseLayout(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseLayout(%q): got nil, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flash
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Region is a named part of the flash, such as the BIOS region.
+type Region struct {
+	Name string
+	// Start and End are the first and last bytes of it.
+	Start, End int64
+}
+
+// Len returns the length of r in bytes.
+func (r Region) Len() int64 {
+	return r.End - r.Start + 1
+}
+
+// A Layout is the regions of a flash.
+type Layout []Region
+
+// ParseLayout parses a layout in the format of flashrom's: a line for each
+// region, START:END NAME, with START and END in hex.
+func ParseLayout(r io.Reader) (Layout, error) {
+	var l Layout
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		se := strings.Split(f[0], ":")
+		if len(f) != 2 || len(se) != 2 {
+			return nil, fmt.Errorf("line %d: %q is not START:END NAME", n, line)
+		}
+		start, err := strconv.ParseInt(se[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		end, err := strconv.ParseInt(se[1], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if end < start {
+			return nil, fmt.Errorf("line %d: region %v ends before it starts", n, f[1])
+		}
+		l = append(l, Region{Name: f[1], Start: start, End: end})
+	}
+	return l, s.Err()
+}
+
+// Find returns the region called name.
+func (l Layout) Find(name string) (Region, error) {
+	for _, r := range l {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return Region{}, fmt.Errorf("no region %q in the layout", name)
+}
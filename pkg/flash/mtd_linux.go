@@ -0,0 +1,95 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flash
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The MTD ioctls: _IOR('M', 1, struct mtd_info_user), and _IOW('M', 2 or
+// 6, struct erase_info_user).
+const (
+	memGetInfo = 0x80204d01
+	memErase   = 0x40084d02
+	memUnlock  = 0x40084d06
+
+	mtdNORFlash = 3
+	mtdWritable = 0x400
+)
+
+// mtdInfo is struct mtd_info_user.
+type mtdInfo struct {
+	Type      uint8
+	_         [3]uint8
+	Flags     uint32
+	Size      uint32
+	EraseSize uint32
+	WriteSize uint32
+	OOBSize   uint32
+	_         uint64
+}
+
+// eraseInfo is struct erase_info_user.
+type eraseInfo struct {
+	Start  uint32
+	Length uint32
+}
+
+// MTD is a NOR flash MTD device, such as /dev/mtd0, as the kernel's SPI NOR
+// drivers make for the firmware flash.
+type MTD struct {
+	*os.File
+	info mtdInfo
+}
+
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// OpenMTD opens the MTD device dev. NAND is not supported: it has bad
+// blocks, and firmware is not on it.
+func OpenMTD(dev string) (*MTD, error) {
+	f, err := os.OpenFile(dev, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	m := &MTD{File: f}
+	if err := ioctl(f, memGetInfo, unsafe.Pointer(&m.info)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: MEMGETINFO: %v", dev, err)
+	}
+	if m.info.Type != mtdNORFlash {
+		f.Close()
+		return nil, fmt.Errorf("%v: type %d is not NOR flash", dev, m.info.Type)
+	}
+	return m, nil
+}
+
+// Size returns the size of the device.
+func (m *MTD) Size() int64 {
+	return int64(m.info.Size)
+}
+
+// EraseSize returns the size of its erase blocks.
+func (m *MTD) EraseSize() int64 {
+	return int64(m.info.EraseSize)
+}
+
+// Erase erases the n bytes at off, after unlocking them: some chips are
+// locked at power on. Not all drivers can lock, so unlocking may fail.
+func (m *MTD) Erase(off, n int64) error {
+	if m.info.Flags&mtdWritable == 0 {
+		return fmt.Errorf("%v is read only", m.Name())
+	}
+	e := eraseInfo{Start: uint32(off), Length: uint32(n)}
+	ioctl(m.File, memUnlock, unsafe.Pointer(&e))
+	return ioctl(m.File, memErase, unsafe.Pointer(&e))
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flash
+
+import (
+	"os"
+	"runtime"
+	"unsafe"
+)
+
+// The spidev ioctls: _IOW('k', 1, __u8), _IOW('k', 4, __u32) and
+// SPI_IOC_MESSAGE(2), _IOW('k', 0, struct spi_ioc_transfer[2]).
+const (
+	spiIocWrMode       = 0x40016b01
+	spiIocWrMaxSpeedHz = 0x40046b04
+	spiIocMessage2     = 0x40406b00
+)
+
+// spiTransfer is struct spi_ioc_transfer.
+type spiTransfer struct {
+	TxBuf       uint64
+	RxBuf       uint64
+	Len         uint32
+	SpeedHz     uint32
+	DelayUsecs  uint16
+	BitsPerWord uint8
+	CSChange    uint8
+	TxNbits     uint8
+	RxNbits     uint8
+	_           uint16
+}
+
+// spidev is a SPI NOR flash on a spidev device.
+type spidev struct {
+	*SPINOR
+	f *os.File
+}
+
+// OpenSPI opens the SPI NOR flash on the spidev device dev, such as
+// /dev/spidev0.0, at speed hz, or the default speed if hz is 0.
+func OpenSPI(dev string, hz uint32) (Flash, error) {
+	f, err := os.OpenFile(dev, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	var mode uint8
+	if err := ioctl(f, spiIocWrMode, unsafe.Pointer(&mode)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if hz != 0 {
+		if err := ioctl(f, spiIocWrMaxSpeedHz, unsafe.Pointer(&hz)); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	s, err := NewSPINOR(func(w []byte, n int) ([]byte, error) {
+		r := make([]byte, n)
+		t := [2]spiTransfer{
+			{TxBuf: uint64(uintptr(unsafe.Pointer(&w[0]))), Len: uint32(len(w))},
+			{Len: uint32(n)},
+		}
+		if n > 0 {
+			t[1].RxBuf = uint64(uintptr(unsafe.Pointer(&r[0])))
+		}
+		err := ioctl(f, spiIocMessage2, unsafe.Pointer(&t))
+		runtime.KeepAlive(w)
+		runtime.KeepAlive(r)
+		return r, err
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &spidev{SPINOR: s, f: f}, nil
+}
+
+// Close closes the spidev device.
+func (s *spidev) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flash
+
+import (
+	"fmt"
+	"time"
+)
+
+// The SPI NOR commands, which almost all chips share.
+const (
+	cmdRDID = 0x9f // Read JEDEC ID
+	cmdREAD = 0x03 // Read
+	cmdWREN = 0x06 // Write Enable
+	cmdRDSR = 0x05 // Read Status Register
+	cmdPP   = 0x02 // Page Program
+	cmdSE   = 0x20 // Sector Erase, 4K
+
+	statusWIP = 1 // Write In Progress
+
+	sectorSize = 4096
+	pageSize   = 256
+	// maxRead is how much is read in one command; spidev limits a
+	// transfer to a page of memory by default.
+	maxRead = 2048
+)
+
+// A Transfer sends w to a SPI chip, then reads n bytes back, with chip
+// select held through both.
+type Transfer func(w []byte, n int) ([]byte, error)
+
+// SPINOR is a SPI NOR flash, with 3 byte addresses, so of up to 16MiB.
+type SPINOR struct {
+	xfer Transfer
+	// ID is the JEDEC ID of the chip: its manufacturer, then the type
+	// and capacity of it.
+	ID   [3]byte
+	size int64
+	// Timeout is how long to wait for a write or erase.
+	Timeout time.Duration
+}
+
+// NewSPINOR identifies the SPI NOR flash that xfer talks to. Its size is
+// the capacity byte of its JEDEC ID, as a power of 2, which is true of
+// all the chips firmware is on.
+func NewSPINOR(xfer Transfer) (*SPINOR, error) {
+	id, err := xfer([]byte{cmdRDID}, 3)
+	if err != nil {
+		return nil, fmt.Errorf("reading JEDEC ID: %v", err)
+	}
+	s := &SPINOR{xfer: xfer, Timeout: 10 * time.Second}
+	copy(s.ID[:], id)
+	if s.ID[0] == 0 || s.ID[0] == 0xff {
+		return nil, fmt.Errorf("no SPI flash: JEDEC ID is % x", id)
+	}
+	if s.ID[2] < 0x10 || s.ID[2] > 0x18 {
+		return nil, fmt.Errorf("unsupported SPI flash: JEDEC ID % x: it is not 64KiB to 16MiB", id)
+	}
+	s.size = 1 << s.ID[2]
+	return s, nil
+}
+
+// Size returns the size of the chip.
+func (s *SPINOR) Size() int64 {
+	return s.size
+}
+
+// EraseSize returns the size of a sector.
+func (s *SPINOR) EraseSize() int64 {
+	return sectorSize
+}
+
+// cmd makes a command with address a.
+func cmd(c byte, a int64) []byte {
+	return []byte{c, byte(a >> 16), byte(a >> 8), byte(a)}
+}
+
+// ReadAt implements io.ReaderAt.
+func (s *SPINOR) ReadAt(b []byte, off int64) (int, error) {
+	if err := checkRange(s, off, int64(len(b))); err != nil {
+		return 0, err
+	}
+	for n := 0; n < len(b); {
+		l := len(b) - n
+		if l > maxRead {
+			l = maxRead
+		}
+		r, err := s.xfer(cmd(cmdREAD, off+int64(n)), l)
+		if err != nil {
+			return n, err
+		}
+		n += copy(b[n:], r)
+	}
+	return len(b), nil
+}
+
+// wait waits for the write or erase in progress to be done.
+func (s *SPINOR) wait() error {
+	for start := time.Now(); time.Since(start) < s.Timeout; {
+		st, err := s.xfer([]byte{cmdRDSR}, 1)
+		if err != nil {
+			return err
+		}
+		if st[0]&statusWIP == 0 {
+			return nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return fmt.Errorf("flash still busy after %v", s.Timeout)
+}
+
+// do write enables, runs w, and waits for it to be done.
+func (s *SPINOR) do(w []byte) error {
+	if _, err := s.xfer([]byte{cmdWREN}, 0); err != nil {
+		return err
+	}
+	if _, err := s.xfer(w, 0); err != nil {
+		return err
+	}
+	return s.wait()
+}
+
+// WriteAt implements io.WriterAt. A page program wraps around at the end
+// of the page, so b is written at most a page at a time.
+func (s *SPINOR) WriteAt(b []byte, off int64) (int, error) {
+	if err := checkRange(s, off, int64(len(b))); err != nil {
+		return 0, err
+	}
+	for n := 0; n < len(b); {
+		a := off + int64(n)
+		l := pageSize - int(a%pageSize)
+		if l > len(b)-n {
+			l = len(b) - n
+		}
+		if err := s.do(append(cmd(cmdPP, a), b[n:n+l]...)); err != nil {
+			return n, err
+		}
+		n += l
+	}
+	return len(b), nil
+}
+
+// Erase erases the sectors of the n bytes at off.
+func (s *SPINOR) Erase(off, n int64) error {
+	for a := off; a < off+n; a += sectorSize {
+		if err := s.do(cmd(cmdSE, a)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing; it is the Transfer's to close.
+func (s *SPINOR) Close() error {
+	return nil
+}
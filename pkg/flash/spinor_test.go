@@ -0,0 +1,119 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// chip simulates a SPI NOR flash chip, as a Transfer.
+type chip struct {
+	id      []byte
+	mem     []byte
+	wel     bool
+	busy    int
+	program int
+}
+
+func (c *chip) xfer(w []byte, n int) ([]byte, error) {
+	addr := func() int {
+		return int(w[1])<<16 | int(w[2])<<8 | int(w[3])
+	}
+	if c.busy > 0 && w[0] != cmdRDSR {
+		return nil, fmt.Errorf("command %#x while busy", w[0])
+	}
+	switch w[0] {
+	case cmdRDID:
+		return c.id[:n], nil
+	case cmdRDSR:
+		if c.busy > 0 {
+			c.busy--
+			return []byte{statusWIP}, nil
+		}
+		return []byte{0}, nil
+	case cmdWREN:
+		c.wel = true
+		return nil, nil
+	case cmdREAD:
+		a := addr()
+		return append([]byte(nil), c.mem[a:a+n]...), nil
+	case cmdPP, cmdSE:
+		if !c.wel {
+			return nil, fmt.Errorf("command %#x without write enable", w[0])
+		}
+		c.wel, c.busy = false, 2
+		a := addr()
+		if w[0] == cmdSE {
+			a &^= sectorSize - 1
+			copy(c.mem[a:a+sectorSize], bytes.Repeat([]byte{0xff}, sectorSize))
+			return nil, nil
+		}
+		if len(w)-4 > pageSize-a%pageSize {
+			return nil, fmt.Errorf("page program of %d bytes at %#x crosses a page", len(w)-4, a)
+		}
+		for i, b := range w[4:] {
+			c.mem[a+i] &= b
+		}
+		c.program++
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown command %#x", w[0])
+}
+
+func TestSPINOR(t *testing.T) {
+	// A W25X10: 128KiB.
+	c := &chip{id: []byte{0xef, 0x30, 0x11}, mem: bytes.Repeat([]byte{0xff}, 1<<17)}
+	s, err := NewSPINOR(c.xfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 1<<17 || s.EraseSize() != 4096 {
+		t.Errorf("size: got %#x, %#x, want 0x20000, 0x1000", s.Size(), s.EraseSize())
+	}
+
+	// No 0xff in it, which is not written to erased flash, so every page
+	// is programmed.
+	img := make([]byte, 3*4096)
+	for i := range img {
+		img[i] = byte(i % 255)
+	}
+	// Writing at 0x1f00 crosses pages and sectors.
+	if err := Write(s, 0x1f00, img); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.mem[0x1f00:0x1f00+len(img)], img) {
+		t.Errorf("flash is not the image after writing it")
+	}
+	if c.program != 48 {
+		t.Errorf("got %d page programs, want 48", c.program)
+	}
+	got, err := Read(s, 0x1f00, int64(len(img)))
+	if err != nil || !bytes.Equal(got, img) {
+		t.Errorf("Read: got %v, want the image", err)
+	}
+
+	// Writing over it needs erases, and keeps what is around it.
+	for i := range img {
+		img[i] = ^img[i]
+	}
+	if err := Write(s, 0x1f00, img); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.mem[0x1f00:0x1f00+len(img)], img) {
+		t.Errorf("flash is not the image after writing over it")
+	}
+	if c.mem[0x1eff] != 0xff || c.mem[0x1f00+len(img)] != 0xff {
+		t.Errorf("bytes around the image changed")
+	}
+
+	for _, id := range [][]byte{{0xff, 0xff, 0xff}, {0, 0, 0}, {0xef, 0x40, 0x19}} {
+		c := &chip{id: id}
+		if _, err := NewSPINOR(c.xfer); err == nil {
+			t.Errorf("NewSPINOR with JEDEC ID % x: got nil, want error", id)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package golang
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/build"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -77,6 +80,51 @@ func (c Environ) ListDeps(pkg string) (*ListPackage, error) {
 	return &p, nil
 }
 
+// listError is an error go list reports for a package.
+type listError struct {
+	Err string
+}
+
+// BuildErrors returns why each of pkgs which can not be built for the
+// environment's OS and architecture can not be: e.g. build constraints
+// exclude all of its files, or those of a package it imports. Packages
+// which can be built are not in the map.
+func (c Environ) BuildErrors(pkgs []string) (map[string]error, error) {
+	errs := make(map[string]error)
+	if len(pkgs) == 0 {
+		return errs, nil
+	}
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json"}, pkgs...)...)
+	cmd.Env = append(os.Environ(), c.Env()...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %v: %v, %s", pkgs, err, stderr.String())
+	}
+
+	d := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var p struct {
+			ImportPath string
+			Error      *listError
+			DepsErrors []*listError
+		}
+		if err := d.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.Error != nil:
+			errs[p.ImportPath] = errors.New(p.Error.Err)
+		case len(p.DepsErrors) > 0:
+			errs[p.ImportPath] = errors.New(p.DepsErrors[0].Err)
+		}
+	}
+	return errs, nil
+}
+
 func (c Environ) Env() []string {
 	var env []string
 	if c.GOARCH != "" {
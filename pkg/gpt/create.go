@@ -0,0 +1,209 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// PartSize is the size of the partition entries of new tables.
+	PartSize = 0x80
+	// partAlign is where new partitions start a multiple of, in blocks:
+	// 1MiB.
+	partAlign = 2048
+)
+
+// GUID returns the GUID s, written the usual way, as it is stored on disk:
+// the first three fields are little endian.
+func GUID(s string) (uuid.UUID, error) {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return u, err
+	}
+	u[0], u[1], u[2], u[3] = u[3], u[2], u[1], u[0]
+	u[4], u[5] = u[5], u[4]
+	u[6], u[7] = u[7], u[6]
+	return u, nil
+}
+
+// PartTypes are the type GUIDs of common partitions, as they are stored.
+var PartTypes = map[string]uuid.UUID{}
+
+func init() {
+	for n, s := range map[string]string{
+		"efi":   "c12a7328-f81f-11d2-ba4b-00a0c93ec93b",
+		"bios":  "21686148-6449-6e6f-744e-656564454649",
+		"linux": "0fc63daf-8483-4772-8e79-3d69d8477de4",
+		"swap":  "0657fd6d-a4ab-43c4-84e5-0933c84b4f4f",
+		"raid":  "a19d880f-05fc-4d3b-a006-743f0f84911e",
+		"lvm":   "e6d6d379-f507-44c2-a23c-238f2a3df928",
+	} {
+		PartTypes[n] = uuid.Must(GUID(s))
+	}
+}
+
+// NewPartName returns s as a partition name, which is UTF-16. It is
+// truncated if it is too long.
+func NewPartName(s string) PartName {
+	var n PartName
+	u := utf16.Encode([]rune(s))
+	for i := 0; i < len(u) && 2*i+1 < len(n); i++ {
+		binary.LittleEndian.PutUint16(n[2*i:], u[i])
+	}
+	return n
+}
+
+// String returns the name.
+func (n PartName) String() string {
+	var u []uint16
+	for i := 0; i+1 < len(n); i += 2 {
+		c := binary.LittleEndian.Uint16(n[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+// partBlocks is how many blocks the partition entries of h take.
+func partBlocks(h Header) uint64 {
+	return (uint64(h.NPart)*uint64(h.PartSize) + BlockSize - 1) / BlockSize
+}
+
+// Create returns a GPT with no partitions for a disk of blocks blocks.
+func Create(blocks uint64) (*GPT, error) {
+	g := &GPT{
+		Header: Header{
+			Signature:  Signature,
+			Revision:   Revision,
+			HeaderSize: HeaderSize,
+			DiskGUID:   uuid.New(),
+			NPart:      MaxNPart,
+			PartSize:   PartSize,
+		},
+		Parts: make([]Part, MaxNPart),
+	}
+	if err := g.Place(blocks); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Place makes g the primary GPT of a disk of blocks blocks, with its
+// backup at the end of the disk. It is how to fix a backup which is not
+// at the end, e.g. once an image has been written to a bigger disk.
+func (g *GPT) Place(blocks uint64) error {
+	pb := partBlocks(g.Header)
+	if blocks < 3+2*pb {
+		return fmt.Errorf("%d blocks is too small for a GPT", blocks)
+	}
+	g.CurrentLBA = 1
+	g.BackupLBA = blocks - 1
+	g.PartStart = 2
+	g.FirstLBA = 2 + pb
+	g.LastLBA = blocks - 2 - pb
+	for i, p := range g.Parts {
+		if p.PartGUID != (uuid.UUID{}) && (p.FirstLBA < g.FirstLBA || p.LastLBA > g.LastLBA) {
+			return fmt.Errorf("partition %d, blocks %d to %d, does not fit in blocks %d to %d", i+1, p.FirstLBA, p.LastLBA, g.FirstLBA, g.LastLBA)
+		}
+	}
+	return nil
+}
+
+// Backup returns the backup of the primary GPT g.
+func Backup(g *GPT) *GPT {
+	b := &GPT{Header: g.Header, Parts: append([]Part(nil), g.Parts...)}
+	b.CurrentLBA, b.BackupLBA = g.BackupLBA, g.CurrentLBA
+	b.PartStart = g.BackupLBA - partBlocks(g.Header)
+	return b
+}
+
+// Primary returns the primary of the backup GPT b.
+func Primary(b *GPT) *GPT {
+	g := &GPT{Header: b.Header, Parts: append([]Part(nil), b.Parts...)}
+	g.CurrentLBA, g.BackupLBA = b.BackupLBA, b.CurrentLBA
+	g.PartStart = 2
+	return g
+}
+
+// AddPart adds a partition of type t, from block first to block last, in
+// the first unused entry of g, and returns its number, counting from 1.
+// If first is 0, the partition starts at the first aligned free block
+// after the other partitions; if last is 0, it ends at the end of the
+// disk.
+func (g *GPT) AddPart(t uuid.UUID, first, last uint64, name string) (int, error) {
+	n := -1
+	next := g.FirstLBA
+	for i, p := range g.Parts {
+		if p.PartGUID == (uuid.UUID{}) {
+			if n < 0 {
+				n = i
+			}
+			continue
+		}
+		if p.LastLBA >= next {
+			next = p.LastLBA + 1
+		}
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("no free partition entries")
+	}
+	if first == 0 {
+		first = (next + partAlign - 1) / partAlign * partAlign
+	}
+	if last == 0 {
+		last = g.LastLBA
+	}
+	if first < g.FirstLBA || last > g.LastLBA || first > last {
+		return 0, fmt.Errorf("blocks %d to %d are not in blocks %d to %d", first, last, g.FirstLBA, g.LastLBA)
+	}
+	for i, p := range g.Parts {
+		if p.PartGUID != (uuid.UUID{}) && first <= p.LastLBA && last >= p.FirstLBA {
+			return 0, fmt.Errorf("blocks %d to %d overlap partition %d", first, last, i+1)
+		}
+	}
+	g.Parts[n] = Part{
+		PartGUID:   t,
+		UniqueGUID: uuid.New(),
+		FirstLBA:   first,
+		LastLBA:    last,
+		Name:       NewPartName(name),
+	}
+	return n + 1, nil
+}
+
+// ParsePartType returns the type GUID for s, which is a name from
+// PartTypes or a GUID.
+func ParsePartType(s string) (uuid.UUID, error) {
+	if u, ok := PartTypes[strings.ToLower(s)]; ok {
+		return u, nil
+	}
+	return GUID(s)
+}
+
+// WriteProtectiveMBR writes the MBR which marks a disk of blocks blocks as
+// GPT. The boot code in the MBR is left as it is.
+func WriteProtectiveMBR(w io.WriterAt, blocks uint64) error {
+	size := blocks - 1
+	if size > 0xffffffff {
+		size = 0xffffffff
+	}
+	b := make([]byte, 66)
+	// One partition, of type 0xee, from block 1 to the end of the disk.
+	copy(b, []byte{0x00, 0x00, 0x02, 0x00, 0xee, 0xff, 0xff, 0xff})
+	binary.LittleEndian.PutUint32(b[8:], 1)
+	binary.LittleEndian.PutUint32(b[12:], uint32(size))
+	b[64], b[65] = 0x55, 0xaa
+	_, err := w.WriteAt(b, 446)
+	return err
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGUID(t *testing.T) {
+	u, err := GUID("c12a7328-f81f-11d2-ba4b-00a0c93ec93b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x28, 0x73, 0x2a, 0xc1, 0x1f, 0xf8, 0xd2, 0x11, 0xba, 0x4b, 0x00, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b}
+	if !bytes.Equal(u[:], want) {
+		t.Errorf("GUID: got % x, want % x", u[:], want)
+	}
+	if u != PartTypes["efi"] {
+		t.Errorf("PartTypes[efi]: got %v, want %v", PartTypes["efi"], u)
+	}
+	if _, err := ParsePartType("nonsense"); err == nil {
+		t.Errorf("ParsePartType(nonsense): got nil, want error")
+	}
+}
+
+func TestPartName(t *testing.T) {
+	for _, s := range []string{"", "EFI", "root ü", "a name which is far too long to fit in a partition entry"} {
+		want := s
+		if len(want) > 36 {
+			want = want[:36]
+		}
+		if got := NewPartName(s).String(); got != want {
+			t.Errorf("NewPartName(%q).String(): got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	const blocks = 64 * 1024 * 1024 / BlockSize
+	d := make(iodisk, blocks*BlockSize)
+	g, err := Create(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddPart(PartTypes["efi"], 0, 20479, "EFI"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := g.AddPart(PartTypes["linux"], 0, 0, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Parts[n-1]; n != 2 || p.FirstLBA != 20480 || p.LastLBA != g.LastLBA {
+		t.Errorf("second partition: got %d, blocks %d to %d, want 2, blocks 20480 to %d", n, p.FirstLBA, p.LastLBA, g.LastLBA)
+	}
+	if _, err := g.AddPart(PartTypes["swap"], 4096, 8191, ""); err == nil {
+		t.Errorf("adding an overlapping partition: got nil, want error")
+	}
+	if err := WriteProtectiveMBR(&d, blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(&d, g); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(&d, Backup(g)); err != nil {
+		t.Fatal(err)
+	}
+	if d[450] != 0xee || d[510] != 0x55 || d[511] != 0xaa {
+		t.Errorf("protective MBR: got type %#x, signature % x", d[450], d[510:512])
+	}
+
+	p, b, err := New(bytes.NewReader(d))
+	if err != nil {
+		t.Fatalf("reading the new GPT: %v", err)
+	}
+	if b.CurrentLBA != blocks-1 || b.PartStart != blocks-33 {
+		t.Errorf("backup: got header at %d, partitions at %d, want %d, %d", b.CurrentLBA, b.PartStart, blocks-1, blocks-33)
+	}
+	if got := p.Parts[0].Name.String(); got != "EFI" {
+		t.Errorf("name of partition 1: got %q, want %q", got, "EFI")
+	}
+	if err := EqualHeader(Primary(b).Header, b.Header); err != nil {
+		t.Errorf("Primary(backup) does not match the backup: %v", err)
+	}
+}
+
+func TestPlace(t *testing.T) {
+	g, err := Create(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddPart(PartTypes["linux"], 100, 900, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Place(4000); err != nil {
+		t.Fatal(err)
+	}
+	if g.BackupLBA != 3999 || g.LastLBA != 3966 {
+		t.Errorf("Place(4000): got backup at %d, last block %d, want 3999, 3966", g.BackupLBA, g.LastLBA)
+	}
+	if err := g.Place(800); err == nil {
+		t.Errorf("Place(800) with a partition to 900: got nil, want error")
+	}
+	if _, err := Create(50); err == nil {
+		t.Errorf("Create(50): got nil, want error")
+	}
+}
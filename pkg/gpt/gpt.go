@@ -137,7 +137,7 @@ func EqualPart(p, b Part) (err error) {
 		err = errAppend(err, "p.Attribute(%#x) != b.Attribute(%#x)", p.Attribute, b.Attribute)
 	}
 	if p.Name != b.Name {
-		err = errAppend(err, "p.Name(%#x) != b.Name(%#x)", p.Name, b.Name)
+		err = errAppend(err, "p.Name(%#x) != b.Name(%#x)", p.Name[:], b.Name[:])
 	}
 	return err
 }
@@ -0,0 +1,182 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gzip compresses and decompresses files for the gzip, gunzip and
+// zcat commands.
+//
+// Files are streamed, so they need not fit in memory. Files made of several
+// gzip streams one after the other, e.g. by cat a.gz b.gz, are decompressed
+// as one.
+package gzip
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options says what the commands do to each file.
+type Options struct {
+	// Decompress decompresses rather than compresses.
+	Decompress bool
+	// Stdout writes to stdout, and keeps the file.
+	Stdout bool
+	// Keep keeps the file, rather than removing it once it has been
+	// written compressed or decompressed.
+	Keep bool
+	// Force overwrites the output file if it exists.
+	Force bool
+	// Level is the compression level, from gzip.BestSpeed to
+	// gzip.BestCompression. 0 is gzip.DefaultCompression.
+	Level int
+}
+
+// levelFlag is one of the -1 to -9 flags, which set the level.
+type levelFlag struct {
+	o     *Options
+	level int
+}
+
+func (l levelFlag) String() string {
+	return "false"
+}
+
+func (l levelFlag) Set(s string) error {
+	if b, err := strconv.ParseBool(s); err != nil || !b {
+		return fmt.Errorf("-%d takes no value", l.level)
+	}
+	l.o.Level = l.level
+	return nil
+}
+
+func (l levelFlag) IsBoolFlag() bool {
+	return true
+}
+
+// RegisterFlags adds the flags of the commands to f.
+func (o *Options) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&o.Decompress, "d", o.Decompress, "decompress")
+	f.BoolVar(&o.Stdout, "c", o.Stdout, "write to stdout and keep the files")
+	f.BoolVar(&o.Keep, "k", o.Keep, "keep the files")
+	f.BoolVar(&o.Force, "f", o.Force, "overwrite output files")
+	for l := gzip.BestSpeed; l <= gzip.BestCompression; l++ {
+		f.Var(levelFlag{o, l}, strconv.Itoa(l), fmt.Sprintf("compression level %d", l))
+	}
+}
+
+// Compress writes r to w, compressed at level. name and mtime go in the
+// header; either may be empty.
+func Compress(r io.Reader, w io.Writer, level int, name string, mtime time.Time) error {
+	z, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return err
+	}
+	z.Name, z.ModTime = name, mtime
+	if _, err := io.Copy(z, r); err != nil {
+		z.Close()
+		return err
+	}
+	return z.Close()
+}
+
+// Decompress writes r, decompressed, to w.
+func Decompress(r io.Reader, w io.Writer) error {
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+	_, err = io.Copy(w, z)
+	return err
+}
+
+// outPath returns the name of the file path is compressed or decompressed
+// to.
+func (o *Options) outPath(path string) (string, error) {
+	if !o.Decompress {
+		if strings.HasSuffix(path, ".gz") {
+			return "", fmt.Errorf("%s: already has .gz suffix", path)
+		}
+		return path + ".gz", nil
+	}
+	switch {
+	case strings.HasSuffix(path, ".tgz"):
+		return strings.TrimSuffix(path, ".tgz") + ".tar", nil
+	case strings.HasSuffix(path, ".gz") && len(path) > len(".gz"):
+		return strings.TrimSuffix(path, ".gz"), nil
+	}
+	return "", fmt.Errorf("%s: unknown suffix", path)
+}
+
+// do compresses or decompresses r to w.
+func (o *Options) do(r io.Reader, w io.Writer, name string, mtime time.Time) error {
+	if o.Decompress {
+		return Decompress(r, w)
+	}
+	level := o.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return Compress(r, w, level, name, mtime)
+}
+
+// File compresses or decompresses the file path. If path is -, stdin is
+// written to stdout.
+func (o *Options) File(path string) error {
+	if path == "-" {
+		return o.do(os.Stdin, os.Stdout, "", time.Time{})
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file", path)
+	}
+	if o.Stdout {
+		if err := o.do(in, os.Stdout, filepath.Base(path), fi.ModTime()); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		return nil
+	}
+
+	op, err := o.outPath(path)
+	if err != nil {
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if o.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	out, err := os.OpenFile(op, flags, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	err = o.do(in, out, filepath.Base(path), fi.ModTime())
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(op)
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err := os.Chtimes(op, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	if o.Keep {
+		return nil
+	}
+	return os.Remove(path)
+}
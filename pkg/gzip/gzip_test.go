@@ -0,0 +1,99 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var content = strings.Repeat("u-root is a universal root. ", 1000)
+
+func TestRoundTrip(t *testing.T) {
+	for _, level := range []int{gzip.DefaultCompression, gzip.BestSpeed, gzip.BestCompression} {
+		var z, out bytes.Buffer
+		if err := Compress(strings.NewReader(content), &z, level, "f", time.Time{}); err != nil {
+			t.Fatalf("level %d: Compress: %v", level, err)
+		}
+		if err := Decompress(&z, &out); err != nil {
+			t.Fatalf("level %d: Decompress: %v", level, err)
+		}
+		if out.String() != content {
+			t.Errorf("level %d: got %d bytes back, want %d", level, out.Len(), len(content))
+		}
+	}
+}
+
+func TestMultiStream(t *testing.T) {
+	var z, out bytes.Buffer
+	for _, s := range []string{"first ", "second"} {
+		if err := Compress(strings.NewReader(s), &z, gzip.DefaultCompression, "", time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Decompress(&z, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "first second"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	var o Options
+	f := flag.NewFlagSet("gzip", flag.ContinueOnError)
+	o.RegisterFlags(f)
+	if err := f.Parse([]string{"-k", "-9", "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Options{Keep: true, Level: gzip.BestCompression}); o != want {
+		t.Errorf("got %+v, want %+v", o, want)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "f")
+	if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Options{}).File(p); err != nil {
+		t.Fatalf("compressing: %v", err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed: %v", p, err)
+	}
+	if err := (&Options{}).File(p + ".gz"); err == nil {
+		t.Errorf("compressing %s.gz: got nil, want error", p)
+	}
+
+	if err := (&Options{Decompress: true, Keep: true}).File(p + ".gz"); err != nil {
+		t.Fatalf("decompressing: %v", err)
+	}
+	if b, err := ioutil.ReadFile(p); err != nil || string(b) != content {
+		t.Errorf("%s: got %d bytes, %v, want %d bytes", p, len(b), err, len(content))
+	}
+	if _, err := os.Stat(p + ".gz"); err != nil {
+		t.Errorf("%s.gz was not kept: %v", p, err)
+	}
+	if err := (&Options{Decompress: true}).File(p + ".gz"); err == nil {
+		t.Errorf("decompressing over %s: got nil, want error", p)
+	}
+	if err := (&Options{Decompress: true, Force: true}).File(p + ".gz"); err != nil {
+		t.Errorf("decompressing over %s with Force: %v", p, err)
+	}
+}
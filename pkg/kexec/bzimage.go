@@ -0,0 +1,127 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kexec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Offsets in the x86 boot sector and setup header, from the kernel's
+// Documentation/x86/boot.txt.
+const (
+	setupSectsOff    = 0x1f1
+	bootFlagOff      = 0x1fe
+	headerOff        = 0x202
+	versionOff       = 0x206
+	kernelVersionOff = 0x20e
+	loadFlagsOff     = 0x211
+	relocatableOff   = 0x234
+	xloadFlagsOff    = 0x236
+	cmdlineSizeOff   = 0x238
+
+	// setupHeaderEnd is past all the fields we read.
+	setupHeaderEnd = 0x250
+
+	bootFlag     = 0xaa55
+	headerMagic  = "HdrS"
+	xlfKernel64  = 1 << 0
+	loadedHigh   = 1 << 0
+	sectorSize   = 512
+	defaultSetup = 4
+)
+
+// BzImage is the setup header of an x86 bzImage kernel.
+type BzImage struct {
+	// SetupSects is the number of 512 byte sectors of real mode code
+	// after the boot sector.
+	SetupSects int
+	// Protocol is the boot protocol version, e.g. 0x20c for 2.12.
+	Protocol uint16
+	// LoadFlags and XLoadFlags are the flags of the same name in the
+	// header.
+	LoadFlags  uint8
+	XLoadFlags uint16
+	// Relocatable is whether the protected mode kernel may be loaded
+	// anywhere.
+	Relocatable bool
+	// CmdlineSize is the longest command line the kernel takes, without
+	// the trailing NUL.
+	CmdlineSize uint32
+	// KernelVersion is the version string of the kernel, if it has one.
+	KernelVersion string
+}
+
+// ParseBzImage reads the setup header of the bzImage in r.
+func ParseBzImage(r io.ReaderAt) (*BzImage, error) {
+	h := make([]byte, setupHeaderEnd)
+	if _, err := r.ReadAt(h, 0); err != nil {
+		return nil, fmt.Errorf("reading bzImage header: %v", err)
+	}
+	le := binary.LittleEndian
+	if le.Uint16(h[bootFlagOff:]) != bootFlag {
+		return nil, fmt.Errorf("not a bzImage: no boot flag")
+	}
+	if string(h[headerOff:headerOff+4]) != headerMagic {
+		return nil, fmt.Errorf("not a bzImage: no %q in the setup header", headerMagic)
+	}
+
+	b := &BzImage{
+		SetupSects: int(h[setupSectsOff]),
+		Protocol:   le.Uint16(h[versionOff:]),
+		LoadFlags:  h[loadFlagsOff],
+	}
+	if b.SetupSects == 0 {
+		b.SetupSects = defaultSetup
+	}
+	if b.Protocol < 0x200 || b.LoadFlags&loadedHigh == 0 {
+		return nil, fmt.Errorf("not a bzImage: boot protocol %#x, load flags %#x", b.Protocol, b.LoadFlags)
+	}
+	// Fields were added to the header in later protocol versions.
+	if b.Protocol >= 0x205 {
+		b.Relocatable = h[relocatableOff] != 0
+	}
+	if b.Protocol >= 0x206 {
+		b.CmdlineSize = le.Uint32(h[cmdlineSizeOff:])
+	} else {
+		b.CmdlineSize = 255
+	}
+	if b.Protocol >= 0x20c {
+		b.XLoadFlags = le.Uint16(h[xloadFlagsOff:])
+	}
+
+	// kernel_version points at a string, less 0x200.
+	if off := le.Uint16(h[kernelVersionOff:]); off != 0 {
+		v := make([]byte, 128)
+		n, err := r.ReadAt(v, int64(off)+0x200)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("reading kernel version: %v", err)
+		}
+		if i := bytes.IndexByte(v[:n], 0); i >= 0 {
+			b.KernelVersion = string(v[:i])
+		}
+	}
+	return b, nil
+}
+
+// Is64Bit returns whether the kernel has a 64-bit entry point.
+func (b *BzImage) Is64Bit() bool {
+	return b.XLoadFlags&xlfKernel64 != 0
+}
+
+// KernelOffset returns where the protected mode kernel starts in the file.
+func (b *BzImage) KernelOffset() int64 {
+	return int64(b.SetupSects+1) * sectorSize
+}
+
+// CheckCmdline returns an error if the kernel can not take cmdline.
+func (b *BzImage) CheckCmdline(cmdline string) error {
+	if uint32(len(cmdline)) > b.CmdlineSize {
+		return fmt.Errorf("command line is %d bytes, the kernel takes at most %d", len(cmdline), b.CmdlineSize)
+	}
+	return nil
+}
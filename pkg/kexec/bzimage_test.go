@@ -0,0 +1,79 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kexec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// fakeBzImage returns the start of a bzImage with protocol version p.
+func fakeBzImage(p uint16) []byte {
+	b := make([]byte, 0x1000)
+	le := binary.LittleEndian
+	b[setupSectsOff] = 0
+	le.PutUint16(b[bootFlagOff:], bootFlag)
+	copy(b[headerOff:], headerMagic)
+	le.PutUint16(b[versionOff:], p)
+	le.PutUint16(b[kernelVersionOff:], 0x300)
+	copy(b[0x500:], "4.14.0 (u-root@localhost) #1\x00")
+	b[loadFlagsOff] = loadedHigh
+	b[relocatableOff] = 1
+	le.PutUint16(b[xloadFlagsOff:], xlfKernel64)
+	le.PutUint32(b[cmdlineSizeOff:], 2047)
+	return b
+}
+
+func TestParseBzImage(t *testing.T) {
+	b, err := ParseBzImage(bytes.NewReader(fakeBzImage(0x20d)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BzImage{
+		SetupSects:    4,
+		Protocol:      0x20d,
+		LoadFlags:     loadedHigh,
+		XLoadFlags:    xlfKernel64,
+		Relocatable:   true,
+		CmdlineSize:   2047,
+		KernelVersion: "4.14.0 (u-root@localhost) #1",
+	}
+	if *b != want {
+		t.Errorf("ParseBzImage: got %+v, want %+v", *b, want)
+	}
+	if !b.Is64Bit() {
+		t.Errorf("Is64Bit: got false, want true")
+	}
+	if o := b.KernelOffset(); o != 5*512 {
+		t.Errorf("KernelOffset: got %d, want %d", o, 5*512)
+	}
+	if err := b.CheckCmdline(string(make([]byte, 2048))); err == nil {
+		t.Errorf("CheckCmdline of 2048 bytes: got nil, want error")
+	}
+}
+
+func TestParseOldBzImage(t *testing.T) {
+	// Protocol 2.04 has no cmdline_size, relocatable_kernel or
+	// xloadflags.
+	b, err := ParseBzImage(bytes.NewReader(fakeBzImage(0x204)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.CmdlineSize != 255 || b.Relocatable || b.Is64Bit() {
+		t.Errorf("ParseBzImage: got %+v, want cmdline size 255, not relocatable or 64-bit", *b)
+	}
+}
+
+func TestParseNotBzImage(t *testing.T) {
+	for _, b := range [][]byte{
+		make([]byte, 0x1000),
+		[]byte("\x7fELF"),
+	} {
+		if _, err := ParseBzImage(bytes.NewReader(b)); err == nil {
+			t.Errorf("ParseBzImage(%q...): got nil, want error", b[:4])
+		}
+	}
+}
@@ -7,8 +7,12 @@ package kexec
 import (
 	"fmt"
 	"io/ioutil"
+	"runtime"
 	"strings"
 	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
 )
 
 // Reboot executes a kernel previously loaded with FileInit.
@@ -26,3 +30,48 @@ func CurrentKernelCmdline() (string, error) {
 	}
 	return strings.TrimRight(string(procCmdline), "\n"), nil
 }
+
+// Segment is memory of the new kernel for Load. Buf is copied to the
+// physical address Phys, and the rest of the Size bytes there are zeroed.
+type Segment struct {
+	Buf  []byte
+	Phys uintptr
+	Size uintptr
+}
+
+// kexecSegment is struct kexec_segment.
+type kexecSegment struct {
+	buf   uintptr
+	bufsz uintptr
+	mem   uintptr
+	memsz uintptr
+}
+
+// Load loads segments as the new kernel with kexec_load(2). Reboot jumps
+// to the physical address entry, with nothing else set up: the segments
+// must include the code, e.g. a purgatory, which the kernel expects to
+// have run first.
+func Load(entry uintptr, segments []Segment, flags uintptr) error {
+	ks := make([]kexecSegment, len(segments))
+	for i, s := range segments {
+		if uintptr(len(s.Buf)) > s.Size {
+			return fmt.Errorf("segment %d at %#x is %d bytes, more than its size %d", i, s.Phys, len(s.Buf), s.Size)
+		}
+		ks[i] = kexecSegment{mem: s.Phys, bufsz: uintptr(len(s.Buf)), memsz: s.Size}
+		if len(s.Buf) > 0 {
+			ks[i].buf = uintptr(unsafe.Pointer(&s.Buf[0]))
+		}
+	}
+	var p uintptr
+	if len(ks) > 0 {
+		p = uintptr(unsafe.Pointer(&ks[0]))
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_KEXEC_LOAD, entry, uintptr(len(ks)), p, flags, 0, 0)
+	// The kernel reads the buffers, which the GC knows nothing of.
+	runtime.KeepAlive(segments)
+	runtime.KeepAlive(ks)
+	if errno != 0 {
+		return fmt.Errorf("sys_kexec_load(%#x, %d segments, %#x) = %v", entry, len(ks), flags, errno)
+	}
+	return nil
+}
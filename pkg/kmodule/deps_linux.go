@@ -0,0 +1,293 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmodule
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// MODULE_INIT_COMPRESSED_FILE asks the kernel to decompress the module,
+// which it can do since Linux 5.17 if built with CONFIG_MODULE_DECOMPRESS.
+const MODULE_INIT_COMPRESSED_FILE = 0x4
+
+// ModuleDir returns the directory of the modules of the running kernel,
+// /lib/modules/RELEASE.
+func ModuleDir() (string, error) {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return "", err
+	}
+	return filepath.Join("/lib/modules", string(u.Release[:bytes.IndexByte(u.Release[:], 0)])), nil
+}
+
+// Name returns the name of the module in the file path, e.g. e1000e for
+// kernel/drivers/net/e1000e.ko.xz. The kernel does not tell - and _
+// apart in module names, so it always uses _.
+func Name(file string) string {
+	n := filepath.Base(file)
+	if i := strings.Index(n, ".ko"); i >= 0 {
+		n = n[:i]
+	}
+	return strings.Replace(n, "-", "_", -1)
+}
+
+// notFoundError says a module is not in the module directory.
+type notFoundError struct {
+	name, dir string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("module %v not found in %v", e.name, e.dir)
+}
+
+// IsNotFound reports whether err says a module was not found.
+func IsNotFound(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+type alias struct {
+	pattern, name string
+}
+
+// Modules is what depmod found in a module directory.
+type Modules struct {
+	// Dir is the module directory, e.g. /lib/modules/4.14.0.
+	Dir string
+
+	paths   map[string]string   // of the .ko files, relative to Dir
+	deps    map[string][]string // names of the modules each depends on
+	aliases []alias
+	builtin map[string]bool
+}
+
+// Open reads modules.dep, modules.alias and modules.builtin in dir. Only
+// modules.dep is needed.
+func Open(dir string) (*Modules, error) {
+	m := &Modules{
+		Dir:     dir,
+		paths:   map[string]string{},
+		deps:    map[string][]string{},
+		builtin: map[string]bool{},
+	}
+	err := readLines(filepath.Join(dir, "modules.dep"), func(l string) error {
+		// kernel/a.ko: kernel/b.ko kernel/c.ko
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			return fmt.Errorf("bad line %q", l)
+		}
+		n := Name(l[:i])
+		m.paths[n] = l[:i]
+		for _, d := range strings.Fields(l[i+1:]) {
+			m.deps[n] = append(m.deps[n], Name(d))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = readLines(filepath.Join(dir, "modules.alias"), func(l string) error {
+		// alias pci:v00008086d000010D3sv*sd*bc*sc*i* e1000e
+		f := strings.Fields(l)
+		if len(f) == 3 && f[0] == "alias" {
+			m.aliases = append(m.aliases, alias{f[1], Name(f[2])})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = readLines(filepath.Join(dir, "modules.builtin"), func(l string) error {
+		m.builtin[Name(l)] = true
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readLines calls f for each line of file which is not empty or a comment.
+func readLines(file string, f func(string) error) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		if err := f(l); err != nil {
+			return fmt.Errorf("%v: %v", file, err)
+		}
+	}
+	return s.Err()
+}
+
+// Lookup returns the names of the modules called, or with an alias
+// matching, name. If there are none, it returns an error.
+func (m *Modules) Lookup(name string) ([]string, error) {
+	n := Name(name)
+	if _, ok := m.paths[n]; ok || m.builtin[n] {
+		return []string{n}, nil
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, a := range m.aliases {
+		if ok, _ := path.Match(a.pattern, name); ok && !seen[a.name] {
+			seen[a.name] = true
+			names = append(names, a.name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, &notFoundError{name, m.Dir}
+	}
+	return names, nil
+}
+
+// Deps returns the files of the modules which have to be loaded, in order,
+// to load the module called name: the modules it depends on, then itself.
+// Modules which are built in, or in loaded, are left out.
+func (m *Modules) Deps(name string, loaded map[string]bool) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	var visit func(n string) error
+	visit = func(n string) error {
+		if seen[n] || loaded[n] || m.builtin[n] {
+			return nil
+		}
+		seen[n] = true
+		p, ok := m.paths[n]
+		if !ok {
+			return &notFoundError{n, m.Dir}
+		}
+		for _, d := range m.deps[n] {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		files = append(files, filepath.Join(m.Dir, p))
+		return nil
+	}
+	if err := visit(Name(name)); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Probe loads the modules called, or with an alias matching, name, and the
+// modules they depend on. opts are the options of the named modules.
+func (m *Modules) Probe(name, opts string) error {
+	names, err := m.Lookup(name)
+	if err != nil {
+		return err
+	}
+	loaded, err := Loaded()
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		files, err := m.Deps(n, loaded)
+		if err != nil {
+			return err
+		}
+		for i, f := range files {
+			o := ""
+			if i == len(files)-1 {
+				o = opts
+			}
+			if err := Load(f, o, 0); err != nil {
+				return err
+			}
+			loaded[Name(f)] = true
+		}
+	}
+	return nil
+}
+
+// Remove removes the module called name, then the modules it depends on
+// which are no longer used. It is an error only if name can not be removed.
+func (m *Modules) Remove(name string) error {
+	n := Name(name)
+	if m.builtin[n] {
+		return fmt.Errorf("module %v is built in", n)
+	}
+	if err := Delete(n, unix.O_NONBLOCK); err != nil {
+		return err
+	}
+	for _, d := range m.deps[n] {
+		// Modules in use by others can not be removed.
+		Delete(d, unix.O_NONBLOCK)
+	}
+	return nil
+}
+
+// Loaded returns the names of the modules in /proc/modules.
+func Loaded() (map[string]bool, error) {
+	loaded := map[string]bool{}
+	err := readLines("/proc/modules", func(l string) error {
+		loaded[strings.Fields(l)[0]] = true
+		return nil
+	})
+	// A kernel without module support has no /proc/modules.
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return loaded, err
+}
+
+// Load loads the module in file with opts and flags. Modules compressed
+// with gzip are decompressed here. Those compressed with xz or zstd are
+// decompressed by the kernel if it can, or else by an xz command in PATH.
+func Load(file, opts string, flags uintptr) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch filepath.Ext(file) {
+	case ".gz":
+		z, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%v: %v", file, err)
+		}
+		img, err := ioutil.ReadAll(z)
+		if err != nil {
+			return fmt.Errorf("%v: %v", file, err)
+		}
+		return Init(img, opts)
+	case ".xz", ".zst":
+		if err := FileInit(f, opts, flags|MODULE_INIT_COMPRESSED_FILE); err == nil {
+			return nil
+		}
+		if filepath.Ext(file) == ".zst" {
+			return fmt.Errorf("%v: the kernel can not decompress zstd modules", file)
+		}
+		img, err := exec.Command("xz", "-dc", file).Output()
+		if err != nil {
+			return fmt.Errorf("%v: xz: %v", file, err)
+		}
+		return Init(img, opts)
+	}
+	if err := FileInit(f, opts, flags); err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+	return nil
+}
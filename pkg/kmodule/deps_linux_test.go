@@ -0,0 +1,110 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmodule
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	modulesDep = `kernel/drivers/net/e1000e.ko.xz: kernel/lib/ptp.ko.xz kernel/lib/pps_core.ko.xz
+kernel/lib/ptp.ko.xz: kernel/lib/pps_core.ko.xz
+kernel/lib/pps_core.ko.xz:
+kernel/fs/fat/vfat.ko.gz: kernel/fs/fat/fat.ko.gz kernel/fs/nls/nls-base.ko
+kernel/fs/fat/fat.ko.gz: kernel/fs/nls/nls-base.ko
+kernel/fs/nls/nls-base.ko:
+`
+	modulesAlias = `# Aliases extracted from modules themselves.
+alias pci:v00008086d000010D3sv*sd*bc*sc*i* e1000e
+alias fs-vfat vfat
+alias fs-msdos vfat
+`
+	modulesBuiltin = `kernel/drivers/block/loop.ko
+`
+)
+
+func TestModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestModules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for f, s := range map[string]string{"modules.dep": modulesDep, "modules.alias": modulesAlias, "modules.builtin": modulesBuiltin} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		names []string
+	}{
+		{"e1000e", []string{"e1000e"}},
+		{"nls-base", []string{"nls_base"}},
+		{"loop", []string{"loop"}},
+		{"pci:v00008086d000010D3sv00008086sd00000000bc02sc00i00", []string{"e1000e"}},
+		{"fs-vfat", []string{"vfat"}},
+		{"floppy", nil},
+	} {
+		names, err := m.Lookup(tt.name)
+		if tt.names == nil {
+			if !IsNotFound(err) {
+				t.Errorf("Lookup(%q): got %v, want not found", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("Lookup(%q): got %v, %v, want %v, nil", tt.name, names, err, tt.names)
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		loaded map[string]bool
+		files  []string
+	}{
+		{"e1000e", nil, []string{"kernel/lib/pps_core.ko.xz", "kernel/lib/ptp.ko.xz", "kernel/drivers/net/e1000e.ko.xz"}},
+		{"e1000e", map[string]bool{"pps_core": true}, []string{"kernel/lib/ptp.ko.xz", "kernel/drivers/net/e1000e.ko.xz"}},
+		{"e1000e", map[string]bool{"e1000e": true}, nil},
+		{"vfat", nil, []string{"kernel/fs/nls/nls-base.ko", "kernel/fs/fat/fat.ko.gz", "kernel/fs/fat/vfat.ko.gz"}},
+		{"loop", nil, nil},
+	} {
+		files, err := m.Deps(tt.name, tt.loaded)
+		if err != nil {
+			t.Errorf("Deps(%q, %v): %v", tt.name, tt.loaded, err)
+			continue
+		}
+		var want []string
+		for _, f := range tt.files {
+			want = append(want, filepath.Join(dir, f))
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("Deps(%q, %v): got %v, want %v", tt.name, tt.loaded, files, want)
+		}
+	}
+	if _, err := m.Deps("floppy", nil); !IsNotFound(err) {
+		t.Errorf("Deps(floppy): got %v, want not found", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	for f, want := range map[string]string{
+		"kernel/fs/nls/nls-base.ko": "nls_base",
+		"e1000e.ko.xz":              "e1000e",
+		"snd-hda-intel":             "snd_hda_intel",
+	} {
+		if got := Name(f); got != want {
+			t.Errorf("Name(%q): got %q, want %q", f, got, want)
+		}
+	}
+}
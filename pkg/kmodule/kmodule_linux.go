@@ -1,3 +1,9 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kmodule loads and removes Linux kernel modules, and finds the
+// modules a module depends on from the files depmod writes.
 package kmodule
 
 import (
@@ -26,7 +32,7 @@ func Init(image []byte, opts string) error {
 	}
 
 	if _, _, e := unix.Syscall(unix.SYS_INIT_MODULE, uintptr(unsafe.Pointer(&image[0])), uintptr(len(image)), uintptr(unsafe.Pointer(optsNull))); e != 0 {
-		return fmt.Errorf("init_module(%v, %q) failed with %v", image, opts, e)
+		return fmt.Errorf("init_module(%d bytes, %q) failed with %v", len(image), opts, e)
 	}
 
 	return nil
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -90,3 +92,61 @@ func testLddBadSo(t *testing.T) {
 	t.Logf("Err on bad dir is %v", err)
 
 }
+
+func TestParseLdSoConf(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "ldd")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	if err := os.Mkdir(filepath.Join(tempDir, "ld.so.conf.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for n, c := range map[string]string{
+		"ld.so.conf":             "# comment\ninclude ld.so.conf.d/*.conf\n/opt/lib # trailing\nhwcap 0 nosegneg\n",
+		"ld.so.conf.d/a.conf":    "/a/lib\n\ninclude " + filepath.Join(tempDir, "ld.so.conf") + "\n",
+		"ld.so.conf.d/b.conf":    "/b/lib /b/lib64\n",
+		"ld.so.conf.d/c.notconf": "/c/lib\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, n), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := parseLdSoConf(filepath.Join(tempDir, "ld.so.conf"), make(map[string]bool))
+	want := []string{"/a/lib", "/b/lib", "/b/lib64", "/opt/lib"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLdSoConf: got %q, want %q", got, want)
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	for _, tt := range []struct {
+		dyn  []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"/a:/b"}, []string{"/a", "/b"}},
+		{[]string{"$ORIGIN/../lib::${ORIGIN}"}, []string{"/opt/x/bin/../lib", "/opt/x/bin"}},
+	} {
+		if got := searchPath(tt.dyn, "/opt/x/bin/prog"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchPath(%q): got %q, want %q", tt.dyn, got, tt.want)
+		}
+	}
+}
+
+// TestDeps tests that Deps finds all of what /bin/date needs, as Ldd
+// does, with the interpreter first.
+func TestDeps(t *testing.T) {
+	deps, err := Deps("/bin/date")
+	if err != nil {
+		t.Fatalf("Deps on /bin/date: want nil, got %v", err)
+	}
+	if len(deps) == 0 || !filepath.IsAbs(deps[0].Name) {
+		t.Fatalf("Deps on /bin/date: got %v, want the interpreter first", deps)
+	}
+	for _, d := range deps {
+		if d.Path == "" {
+			t.Errorf("Deps on /bin/date: %v needed by %v not found", d.Name, d.By)
+		}
+	}
+}
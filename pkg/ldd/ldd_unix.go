@@ -4,42 +4,58 @@
 
 // ldd returns all the library dependencies
 // of a list of file names.
-// The way this is done on GNU-based systems
-// is interesting. For each ELF, one finds the
-// .interp section. If there is no interpreter
-// there's not much to do. If there is an interpreter,
-// we run it with the --list option and the file as an argument.
-// We need to parse the output.
-// For all lines with =>  as the 2nd field, we take the
-// 3rd field as a dependency. The field may be a symlink.
-// Rather than stat the link and do other such fooling around,
-// we can do a readlink on it; if it fails, we just need to add
-// that file name; if it succeeds, we need to add that file name
-// and repeat with the next link in the chain. We can let the
+// It does what ld.so would, without running it, so it works on
+// binaries for other architectures too. For each ELF, the libraries
+// it needs are in its DT_NEEDED entries. Each is looked for, as ld.so
+// does, in the DT_RPATH of the file and of the executable, then
+// $LD_LIBRARY_PATH, the DT_RUNPATH of the file, the directories of
+// /etc/ld.so.conf, and last /lib and /usr/lib; the first with the same
+// ELF class and machine is used. Then we repeat with the libraries.
+// The program interpreter, if there is one, is a dependency too.
+// A library may be a symlink. Rather than stat the link and do other
+// such fooling around, we can do a readlink on it; if it fails, we
+// just need to add that file name; if it succeeds, we need to add that
+// file name and repeat with the next link in the chain. We can let the
 // kernel do the work of figuring what to do if and when we hit EMLINK.
 package ldd
 
 import (
+	"bufio"
 	"debug/elf"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
+// ldSoConf is the ld.so configuration file.
+var ldSoConf = "/etc/ld.so.conf"
+
 type FileInfo struct {
 	FullName string
 	os.FileInfo
 }
 
+// Dep is a shared library, or the program interpreter, which an ELF file
+// needs.
+type Dep struct {
+	// Name is the name the library is needed by, e.g. libc.so.6.
+	Name string
+
+	// Path is where the library was found, or "" if it was not.
+	Path string
+
+	// By is the file which needs it.
+	By string
+}
+
 // Follow starts at a pathname and adds it
 // to a map if it is not there.
 // If the pathname is a symlink, indicated by the Readlink
 // succeeding, links repeats and continues
 // for as long as the name is not found in the map.
-func follow(l string, names map[string]*FileInfo) error {
+func follow(l string, names map[string]*FileInfo, order *[]string) error {
 	for {
 		if names[l] != nil {
 			return nil
@@ -50,6 +66,7 @@ func follow(l string, names map[string]*FileInfo) error {
 		}
 
 		names[l] = &FileInfo{FullName: l, FileInfo: i}
+		*order = append(*order, l)
 		if i.Mode().IsRegular() {
 			return nil
 		}
@@ -70,43 +87,244 @@ func follow(l string, names map[string]*FileInfo) error {
 	}
 }
 
-// runinterp runs the interpreter with the --list switch
-// and the file as an argument. For each returned line
-// it looks for => as the second field, indicating a
-// real .so (as opposed to the .vdso or a string like
-// 'not a dynamic executable'.
-func runinterp(interp, file string) ([]string, error) {
-	var names []string
-	o, err := exec.Command(interp, "--list", file).Output()
+// parseLdSoConf returns the directories in the ld.so configuration file
+// name, and in those it includes.
+func parseLdSoConf(name string, seen map[string]bool) []string {
+	if seen[name] {
+		return nil
+	}
+	seen[name] = true
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var dirs []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		l := s.Text()
+		if i := strings.Index(l, "#"); i >= 0 {
+			l = l[:i]
+		}
+		fields := strings.Fields(l)
+		switch {
+		case len(fields) == 0, fields[0] == "hwcap":
+		case fields[0] == "include":
+			for _, p := range fields[1:] {
+				if !filepath.IsAbs(p) {
+					p = filepath.Join(filepath.Dir(name), p)
+				}
+				m, _ := filepath.Glob(p)
+				for _, inc := range m {
+					dirs = append(dirs, parseLdSoConf(inc, seen)...)
+				}
+			}
+		default:
+			dirs = append(dirs, fields...)
+		}
+	}
+	return dirs
+}
+
+// elfFile is what we need to know of an ELF file to find its
+// dependencies.
+type elfFile struct {
+	name    string
+	class   elf.Class
+	machine elf.Machine
+	interp  string
+	needed  []string
+	rpath   []string
+	runpath []string
+}
+
+// searchPath splits a DT_RPATH or DT_RUNPATH, with $ORIGIN replaced by the
+// directory of the file.
+func searchPath(dyn []string, file string) []string {
+	var dirs []string
+	origin := filepath.Dir(file)
+	for _, d := range dyn {
+		for _, p := range strings.Split(d, ":") {
+			if p == "" {
+				continue
+			}
+			p = strings.Replace(p, "${ORIGIN}", origin, -1)
+			p = strings.Replace(p, "$ORIGIN", origin, -1)
+			dirs = append(dirs, p)
+		}
+	}
+	return dirs
+}
+
+// readELF reads what we need of the ELF file name.
+func readELF(name string) (*elfFile, error) {
+	f, err := elf.Open(name)
 	if err != nil {
 		return nil, err
 	}
-	for _, p := range strings.Split(string(o), "\n") {
-		f := strings.Split(p, " ")
-		if len(f) < 3 {
+	defer f.Close()
+
+	e := &elfFile{name: name, class: f.Class, machine: f.Machine}
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_INTERP {
+			continue
+		}
+		i, err := ioutil.ReadAll(p.Open())
+		if err != nil {
+			return nil, err
+		}
+		e.interp = strings.TrimRight(string(i), "\x00")
+	}
+	if e.needed, err = f.DynString(elf.DT_NEEDED); err != nil {
+		return nil, err
+	}
+	rpath, err := f.DynString(elf.DT_RPATH)
+	if err != nil {
+		return nil, err
+	}
+	runpath, err := f.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		return nil, err
+	}
+	e.rpath, e.runpath = searchPath(rpath, name), searchPath(runpath, name)
+	return e, nil
+}
+
+// resolver finds libraries for the files of one executable.
+type resolver struct {
+	exe     *elfFile
+	conf    []string
+	found   map[string]string
+	visited map[string]bool
+	deps    []Dep
+}
+
+// compatible returns whether name is an ELF library which e can use.
+func compatible(name string, e *elfFile) bool {
+	f, err := elf.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return f.Class == e.class && f.Machine == e.machine
+}
+
+// find returns the path of the library lib which e needs, or "".
+func (r *resolver) find(lib string, e *elfFile) string {
+	if strings.Contains(lib, "/") {
+		if compatible(lib, e) {
+			return lib
+		}
+		return ""
+	}
+	var dirs []string
+	// DT_RPATH is only used if there is no DT_RUNPATH.
+	if len(e.runpath) == 0 {
+		dirs = append(dirs, e.rpath...)
+		if e != r.exe && len(r.exe.runpath) == 0 {
+			dirs = append(dirs, r.exe.rpath...)
+		}
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("LD_LIBRARY_PATH"))...)
+	dirs = append(dirs, e.runpath...)
+	dirs = append(dirs, r.conf...)
+	if e.class == elf.ELFCLASS64 {
+		dirs = append(dirs, "/lib64", "/usr/lib64")
+	}
+	dirs = append(dirs, "/lib", "/usr/lib")
+	for _, d := range dirs {
+		if d == "" {
 			continue
 		}
-		if f[1] != "=>" || len(f[2]) == 0 {
+		if p := filepath.Join(d, lib); compatible(p, e) {
+			return p
+		}
+	}
+	return ""
+}
+
+// walk adds the dependencies of e, and theirs, to r.deps.
+func (r *resolver) walk(e *elfFile) error {
+	if r.visited[e.name] {
+		return nil
+	}
+	r.visited[e.name] = true
+	if e.interp != "" {
+		if _, ok := r.found[e.interp]; !ok {
+			p := ""
+			if _, err := os.Stat(e.interp); err == nil {
+				p = e.interp
+			}
+			r.found[e.interp] = p
+			r.deps = append(r.deps, Dep{Name: e.interp, Path: p, By: e.name})
+			// Libraries which need it by name, as libc does, get
+			// the one already loaded.
+			if p != "" {
+				r.found[filepath.Base(p)] = p
+			}
+		}
+	}
+	var next []string
+	for _, lib := range e.needed {
+		if _, ok := r.found[lib]; ok {
 			continue
 		}
-		names = append(names, f[2])
+		p := r.find(lib, e)
+		r.found[lib] = p
+		r.deps = append(r.deps, Dep{Name: lib, Path: p, By: e.name})
+		if p != "" {
+			next = append(next, p)
+		}
 	}
-	return names, nil
+	for _, p := range next {
+		l, err := readELF(p)
+		if err != nil {
+			return err
+		}
+		if err := r.walk(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deps returns the libraries which the ELF file name needs, directly or
+// not, and its program interpreter, in the order they are found. Those
+// which can not be found have no Path.
+func Deps(name string) ([]Dep, error) {
+	e, err := readELF(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &resolver{
+		exe:     e,
+		conf:    parseLdSoConf(ldSoConf, make(map[string]bool)),
+		found:   make(map[string]string),
+		visited: make(map[string]bool),
+	}
+	if err := r.walk(e); err != nil {
+		return nil, err
+	}
+	return r.deps, nil
 }
 
 // Ldd returns a list of all library dependencies for a
 // set of files, suitable for feeding into (e.g.) a cpio
 // program. If a file has no dependencies, that is not an
 // error. The only possible error is if a file does not
-// exist, or it says it has an interpreter but we can't read
-// it, or we are not able to run its interpreter.
+// exist, or a library it needs can not be read.
 // It's not an error for a file to not be an ELF, as
 // this function should be convenient and the list might
-// include non-ELF executables (a.out format, scripts)
+// include non-ELF executables (a.out format, scripts).
+// Nor is it one for a library to be missing: it is left out.
+// Interpreters come first, as people expect to see them first.
 func Ldd(names []string) ([]*FileInfo, error) {
 	var (
 		list    = make(map[string]*FileInfo)
 		interps = make(map[string]*FileInfo)
+		lorder  []string
+		iorder  []string
 		libs    []*FileInfo
 	)
 	for _, n := range names {
@@ -114,70 +332,37 @@ func Ldd(names []string) ([]*FileInfo, error) {
 		if err != nil {
 			return nil, err
 		}
-		defer r.Close()
-		f, err := elf.NewFile(r)
+		_, err = elf.NewFile(r)
+		r.Close()
 		if err != nil {
 			continue
 		}
-		s := f.Section(".interp")
-		var interp string
-		if s != nil {
-			// If there is an interpreter section, it should be
-			// an error if we can't read it.
-			i, err := s.Data()
-			if err != nil {
-				return nil, err
-			}
-			// Ignore #! interpreters
-			if len(i) > 1 && i[0] == '#' && i[1] == '!' {
-				continue
-			}
-			// annoyingly, s.Data() seems to return the null at the end and,
-			// weirdly, that seems to confuse the kernel. Truncate it.
-			interp = string(i[:len(i)-1])
+		deps, err := Deps(n)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", n, err)
 		}
-		if interp == "" {
-			if f.Type != elf.ET_DYN {
+		for _, d := range deps {
+			if d.Path == "" {
 				continue
 			}
-			// This is a shared library. Turns out you can run an interpreter with
-			// --list and this shared library as an argument. What interpreter
-			// do we use? Well, there's no way to know. You have to guess.
-			// I'm not sure why they could not just put an interp section in
-			// .so's but maybe that would cause trouble somewhere else.
-			interp, err = LdSo()
-			if err != nil {
-				return nil, err
+			m, order := list, &lorder
+			if filepath.IsAbs(d.Name) {
+				m, order = interps, &iorder
 			}
-		}
-		// We could just append the interp but people
-		// expect to see that first.
-		if interps[interp] == nil {
-			err := follow(interp, interps)
-			if err != nil {
+			if err := follow(d.Path, m, order); err != nil {
 				return nil, err
 			}
 		}
-		// oh boy. Now to run the interp and get more names.
-		n, err := runinterp(interp, n)
-		if err != nil {
-			return nil, err
-		}
-		for i := range n {
-			if err := follow(n[i], list); err != nil {
-				log.Fatalf("ldd: %v", err)
-			}
-		}
 	}
 
-	for i := range interps {
-		libs = append(libs, interps[i])
+	for _, n := range iorder {
+		libs = append(libs, interps[n])
 	}
-
-	for i := range list {
-		libs = append(libs, list[i])
+	for _, n := range lorder {
+		if interps[n] == nil {
+			libs = append(libs, list[n])
+		}
 	}
-
 	return libs, nil
 }
 
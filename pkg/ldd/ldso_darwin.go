@@ -0,0 +1,15 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ldd
+
+import (
+	"fmt"
+)
+
+// LdSo returns an error: macOS has no ld.so to run on an ELF shared library.
+// ELF executables name their interpreter, so they need no LdSo.
+func LdSo() (string, error) {
+	return "", fmt.Errorf("no ld.so for ELF shared libraries on darwin")
+}
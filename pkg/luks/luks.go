@@ -0,0 +1,327 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package luks reads LUKS1 and LUKS2 headers, and unlocks the volume key
+// of a LUKS volume with a passphrase, so that it can be mapped with
+// dm-crypt.
+package luks
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	// The hashes LUKS headers name.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/u-root/u-root/pkg/argon2"
+	"github.com/u-root/u-root/pkg/dmsetup"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/xts"
+)
+
+// Magic starts a LUKS header.
+const Magic = "LUKS\xba\xbe"
+
+// SectorSize is the size of the sectors of key slots, and of the volume,
+// unless a LUKS2 header says otherwise.
+const SectorSize = 512
+
+// A KDF derives a key from a passphrase.
+type KDF struct {
+	// Type is pbkdf2, argon2i or argon2id.
+	Type       string
+	Hash       string
+	Iterations int
+	Salt       []byte
+	// Time, Memory, in KiB, and CPUs are for argon2.
+	Time, Memory, CPUs uint32
+}
+
+// A Keyslot has the volume key encrypted with a key derived from a
+// passphrase.
+type Keyslot struct {
+	ID  int
+	KDF KDF
+	// Offset and Size are of the area of the encrypted key material.
+	Offset, Size int64
+	// Cipher is that of the key material, and KeySize the size of the
+	// key derived for it.
+	Cipher  string
+	KeySize int
+	// The volume key is split into Stripes stripes with AFHash.
+	Stripes int
+	AFHash  string
+	digest  *digest
+}
+
+// digest is a PBKDF2 of the volume key, which checks it.
+type digest struct {
+	hash       string
+	iterations int
+	salt, sum  []byte
+}
+
+// A Header is a LUKS header, of version 1 or 2.
+type Header struct {
+	Version int
+	UUID    string
+	// Cipher is that of the volume, as dm-crypt names it, such as
+	// aes-xts-plain64, and KeySize the size of the volume key.
+	Cipher  string
+	KeySize int
+	// Offset is where the encrypted data starts, and Size how much of it
+	// there is, or 0 for the rest of the device, in bytes.
+	Offset, Size int64
+	// IVTweak is the IV of the first sector.
+	IVTweak    uint64
+	SectorSize int
+	Keyslots   []*Keyslot
+}
+
+// ReadHeader reads the LUKS header at the start of r.
+func ReadHeader(r io.ReaderAt) (*Header, error) {
+	b := make([]byte, luks1HeaderSize)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return nil, err
+	}
+	if string(b[:6]) != Magic {
+		return nil, errors.New("not a LUKS device")
+	}
+	switch v := binary.BigEndian.Uint16(b[6:]); v {
+	case 1:
+		return parseLUKS1(b)
+	case 2:
+		return readLUKS2(r)
+	default:
+		return nil, fmt.Errorf("LUKS version %d is not supported", v)
+	}
+}
+
+// cstring returns the NUL terminated string at the start of b.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// hashes are the hashes of LUKS headers, by name.
+var hashes = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha512": crypto.SHA512,
+}
+
+func newHash(name string) (func() hash.Hash, error) {
+	h, ok := hashes[strings.ToLower(name)]
+	if !ok || !h.Available() {
+		return nil, fmt.Errorf("hash %q is not supported", name)
+	}
+	return h.New, nil
+}
+
+// derive derives a key of n bytes from pass with k.
+func (k *KDF) derive(pass []byte, n int) ([]byte, error) {
+	switch k.Type {
+	case "pbkdf2":
+		h, err := newHash(k.Hash)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(pass, k.Salt, k.Iterations, n, h), nil
+	case "argon2i", "argon2id":
+		if k.Time < 1 || k.CPUs < 1 || k.CPUs > 255 {
+			return nil, fmt.Errorf("%v: time %d, CPUs %d are bad", k.Type, k.Time, k.CPUs)
+		}
+		if k.Type == "argon2i" {
+			return argon2.Key(pass, k.Salt, k.Time, k.Memory, uint8(k.CPUs), uint32(n)), nil
+		}
+		return argon2.IDKey(pass, k.Salt, k.Time, k.Memory, uint8(k.CPUs), uint32(n)), nil
+	}
+	return nil, fmt.Errorf("KDF %q is not supported", k.Type)
+}
+
+// crypt decrypts, or encrypts, b, in sectors of sectorSize from sector 0,
+// with the dm-crypt cipher spec, such as aes-xts-plain64 or
+// aes-cbc-essiv:sha256. Only AES is supported.
+func crypt(spec string, key, b []byte, sectorSize int, decrypt bool) ([]byte, error) {
+	f := strings.SplitN(spec, "-", 3)
+	if len(f) != 3 || f[0] != "aes" {
+		return nil, fmt.Errorf("cipher %q is not supported", spec)
+	}
+	if len(b)%sectorSize != 0 {
+		return nil, fmt.Errorf("%d bytes is not whole sectors", len(b))
+	}
+	out := make([]byte, len(b))
+	switch f[1] {
+	case "xts":
+		if f[2] != "plain" && f[2] != "plain64" {
+			return nil, fmt.Errorf("IV %q of xts is not supported", f[2])
+		}
+		c, err := xts.NewCipher(aes.NewCipher, key)
+		if err != nil {
+			return nil, err
+		}
+		for s := 0; s < len(b); s += sectorSize {
+			if decrypt {
+				c.Decrypt(out[s:s+sectorSize], b[s:s+sectorSize], uint64(s/sectorSize))
+			} else {
+				c.Encrypt(out[s:s+sectorSize], b[s:s+sectorSize], uint64(s/sectorSize))
+			}
+		}
+	case "cbc":
+		c, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		var essiv cipher.Block
+		switch {
+		case f[2] == "plain" || f[2] == "plain64":
+		case strings.HasPrefix(f[2], "essiv:"):
+			h, err := newHash(strings.TrimPrefix(f[2], "essiv:"))
+			if err != nil {
+				return nil, err
+			}
+			s := h()
+			s.Write(key)
+			if essiv, err = aes.NewCipher(s.Sum(nil)); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("IV %q of cbc is not supported", f[2])
+		}
+		for s := 0; s < len(b); s += sectorSize {
+			iv := make([]byte, aes.BlockSize)
+			binary.LittleEndian.PutUint64(iv, uint64(s/sectorSize))
+			if essiv != nil {
+				essiv.Encrypt(iv, iv)
+			}
+			if decrypt {
+				cipher.NewCBCDecrypter(c, iv).CryptBlocks(out[s:s+sectorSize], b[s:s+sectorSize])
+			} else {
+				cipher.NewCBCEncrypter(c, iv).CryptBlocks(out[s:s+sectorSize], b[s:s+sectorSize])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cipher mode %q is not supported", f[1])
+	}
+	return out, nil
+}
+
+// diffuse is the diffusion of the anti-forensic splitter: each hash sized
+// part of b is hashed with its number.
+func diffuse(b []byte, h func() hash.Hash) []byte {
+	out := make([]byte, len(b))
+	size := h().Size()
+	for i := 0; i*size < len(b); i++ {
+		d := h()
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(i))
+		d.Write(n[:])
+		end := (i + 1) * size
+		if end > len(b) {
+			end = len(b)
+		}
+		d.Write(b[i*size : end])
+		copy(out[i*size:end], d.Sum(nil))
+	}
+	return out
+}
+
+// afMerge merges the stripes of the key material into the key.
+func afMerge(m []byte, keySize, stripes int, h func() hash.Hash) []byte {
+	d := make([]byte, keySize)
+	for i := 0; i < stripes; i++ {
+		for j := range d {
+			d[j] ^= m[i*keySize+j]
+		}
+		if i < stripes-1 {
+			d = diffuse(d, h)
+		}
+	}
+	return d
+}
+
+// check returns whether key is the volume key.
+func (d *digest) check(key []byte) (bool, error) {
+	h, err := newHash(d.hash)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(pbkdf2.Key(key, d.salt, d.iterations, len(d.sum), h), d.sum), nil
+}
+
+// unlock returns the volume key, of size keySize, if pass opens slot k.
+func (k *Keyslot) unlock(r io.ReaderAt, pass []byte, keySize int) ([]byte, bool, error) {
+	h, err := newHash(k.AFHash)
+	if err != nil {
+		return nil, false, err
+	}
+	dk, err := k.KDF.derive(pass, k.KeySize)
+	if err != nil {
+		return nil, false, err
+	}
+	n := (keySize*k.Stripes + SectorSize - 1) / SectorSize * SectorSize
+	if k.Size != 0 && int64(n) > k.Size {
+		return nil, false, fmt.Errorf("key material of %d bytes is more than the area of %d", n, k.Size)
+	}
+	m := make([]byte, n)
+	if _, err := r.ReadAt(m, k.Offset); err != nil {
+		return nil, false, err
+	}
+	if m, err = crypt(k.Cipher, dk, m, SectorSize, true); err != nil {
+		return nil, false, err
+	}
+	key := afMerge(m, keySize, k.Stripes, h)
+	ok, err := k.digest.check(key)
+	return key, ok, err
+}
+
+// Unlock returns the volume key, and the key slot it was unlocked from,
+// if pass opens any of the key slots.
+func (h *Header) Unlock(r io.ReaderAt, pass []byte) ([]byte, int, error) {
+	var errs []string
+	for _, k := range h.Keyslots {
+		key, ok, err := k.unlock(r, pass, h.KeySize)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("key slot %d: %v", k.ID, err))
+			continue
+		}
+		if ok {
+			return key, k.ID, nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, -1, fmt.Errorf("no key slot matches the passphrase (%s)", strings.Join(errs, "; "))
+	}
+	return nil, -1, errors.New("no key slot matches the passphrase")
+}
+
+// Target returns the dm-crypt target which maps the volume, on dev, of
+// devSize bytes, with key.
+func (h *Header) Target(dev string, devSize int64, key []byte) (dmsetup.Target, error) {
+	size := h.Size
+	if size == 0 {
+		size = devSize - h.Offset
+	}
+	if size <= 0 {
+		return dmsetup.Target{}, fmt.Errorf("%v is too small: %d bytes, with data from %d", dev, devSize, h.Offset)
+	}
+	p := fmt.Sprintf("%s %s %d %s %d", h.Cipher, hex.EncodeToString(key), h.IVTweak, dev, h.Offset/SectorSize)
+	if h.SectorSize != SectorSize {
+		p += fmt.Sprintf(" 1 sector_size:%d", h.SectorSize)
+	}
+	return dmsetup.Target{Start: 0, Length: uint64(size / SectorSize), Type: "crypt", Params: p}, nil
+}
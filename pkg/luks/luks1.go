@@ -0,0 +1,53 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package luks
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The LUKS1 header, and its key slots, in it.
+const (
+	luks1HeaderSize  = 592
+	luks1KeyslotSize = 48
+	luks1Keyslots    = 8
+	luks1SlotActive  = 0x00ac71f3
+)
+
+// parseLUKS1 parses the LUKS1 header b.
+func parseLUKS1(b []byte) (*Header, error) {
+	be32 := binary.BigEndian.Uint32
+	hashSpec := cstring(b[72:104])
+	h := &Header{
+		Version:    1,
+		Cipher:     cstring(b[8:40]) + "-" + cstring(b[40:72]),
+		Offset:     int64(be32(b[104:])) * SectorSize,
+		KeySize:    int(be32(b[108:])),
+		UUID:       cstring(b[168:208]),
+		SectorSize: SectorSize,
+	}
+	d := &digest{hash: hashSpec, sum: b[112:132], salt: b[132:164], iterations: int(be32(b[164:]))}
+	for i := 0; i < luks1Keyslots; i++ {
+		s := b[208+i*luks1KeyslotSize:]
+		if be32(s) != luks1SlotActive {
+			continue
+		}
+		h.Keyslots = append(h.Keyslots, &Keyslot{
+			ID:      i,
+			KDF:     KDF{Type: "pbkdf2", Hash: hashSpec, Iterations: int(be32(s[4:])), Salt: s[8:40]},
+			Offset:  int64(be32(s[40:])) * SectorSize,
+			Cipher:  h.Cipher,
+			KeySize: h.KeySize,
+			Stripes: int(be32(s[44:])),
+			AFHash:  hashSpec,
+			digest:  d,
+		})
+	}
+	if h.KeySize == 0 {
+		return nil, fmt.Errorf("LUKS1 header has a key size of 0")
+	}
+	return h, nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package luks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// The LUKS2 binary header is followed by its JSON, and has a copy, with
+// its own magic, right after it.
+const (
+	luks2BinarySize = 4096
+	luks2MagicCopy  = "SKUL\xba\xbe"
+	luks2CsumOff    = 448
+)
+
+// luks2JSON is the JSON metadata of a LUKS2 header. Offsets and sizes are
+// strings, as they may not fit in a JSON number.
+type luks2JSON struct {
+	Keyslots map[string]struct {
+		Type    string
+		KeySize int `json:"key_size"`
+		Area    struct {
+			Type       string
+			Offset     string
+			Size       string
+			Encryption string
+			KeySize    int `json:"key_size"`
+		}
+		KDF struct {
+			Type       string
+			Hash       string
+			Iterations int
+			Salt       []byte
+			Time       uint32
+			Memory     uint32
+			CPUs       uint32
+		}
+		AF struct {
+			Type    string
+			Stripes int
+			Hash    string
+		}
+	}
+	Segments map[string]struct {
+		Type       string
+		Offset     string
+		Size       string
+		IVTweak    string `json:"iv_tweak"`
+		Encryption string
+		SectorSize int `json:"sector_size"`
+	}
+	Digests map[string]struct {
+		Type       string
+		Keyslots   []string
+		Segments   []string
+		Hash       string
+		Iterations int
+		Salt       []byte
+		Digest     []byte
+	}
+}
+
+// readLUKS2At reads the LUKS2 header at off, and checks its checksum.
+func readLUKS2At(r io.ReaderAt, off int64, magic string) ([]byte, error) {
+	bin := make([]byte, luks2BinarySize)
+	if _, err := r.ReadAt(bin, off); err != nil {
+		return nil, err
+	}
+	if string(bin[:6]) != magic {
+		return nil, errors.New("no LUKS2 header")
+	}
+	size := binary.BigEndian.Uint64(bin[8:])
+	if size < luks2BinarySize || size > 4<<20 {
+		return nil, fmt.Errorf("LUKS2 header size %d is bad", size)
+	}
+	b := make([]byte, size)
+	if _, err := r.ReadAt(b, off); err != nil {
+		return nil, err
+	}
+	if alg := cstring(b[72:104]); alg != "sha256" {
+		return nil, fmt.Errorf("LUKS2 checksum %q is not supported", alg)
+	}
+	want := append([]byte(nil), b[luks2CsumOff:luks2CsumOff+sha256.Size]...)
+	for i := luks2CsumOff; i < luks2CsumOff+64; i++ {
+		b[i] = 0
+	}
+	if sum := sha256.Sum256(b); !bytes.Equal(sum[:], want) {
+		return nil, fmt.Errorf("LUKS2 header at %#x: bad checksum", off)
+	}
+	return b, nil
+}
+
+// readLUKS2 reads the LUKS2 header, or, if it is bad, its copy.
+func readLUKS2(r io.ReaderAt) (*Header, error) {
+	b, err := readLUKS2At(r, 0, Magic)
+	if err != nil {
+		// The copy is at one of these offsets, as the size of the
+		// header is.
+		for off := int64(0x4000); off <= 0x400000; off *= 2 {
+			if b, err = readLUKS2At(r, off, luks2MagicCopy); err == nil {
+				break
+			}
+		}
+		if b == nil {
+			return nil, err
+		}
+	}
+	var j luks2JSON
+	if err := json.Unmarshal(bytes.TrimRight(b[luks2BinarySize:], "\x00"), &j); err != nil {
+		return nil, fmt.Errorf("LUKS2 metadata: %v", err)
+	}
+	return parseLUKS2(cstring(b[168:208]), &j)
+}
+
+// ids returns the keys of m, which are numbers, in order.
+func ids(m map[string]bool) []int {
+	var n []int
+	for k := range m {
+		if i, err := strconv.Atoi(k); err == nil {
+			n = append(n, i)
+		}
+	}
+	sort.Ints(n)
+	return n
+}
+
+func parseLUKS2(uuid string, j *luks2JSON) (*Header, error) {
+	h := &Header{Version: 2, UUID: uuid}
+
+	// The first segment is the volume.
+	segs := map[string]bool{}
+	for k, s := range j.Segments {
+		if s.Type == "crypt" {
+			segs[k] = true
+		}
+	}
+	sids := ids(segs)
+	if len(sids) == 0 {
+		return nil, errors.New("LUKS2 header has no crypt segment")
+	}
+	seg := strconv.Itoa(sids[0])
+	s := j.Segments[seg]
+	var err error
+	if h.Offset, err = strconv.ParseInt(s.Offset, 10, 64); err != nil {
+		return nil, fmt.Errorf("segment %v offset: %v", seg, err)
+	}
+	if s.Size != "dynamic" {
+		if h.Size, err = strconv.ParseInt(s.Size, 10, 64); err != nil {
+			return nil, fmt.Errorf("segment %v size: %v", seg, err)
+		}
+	}
+	if h.IVTweak, err = strconv.ParseUint(s.IVTweak, 10, 64); err != nil {
+		return nil, fmt.Errorf("segment %v iv_tweak: %v", seg, err)
+	}
+	h.Cipher, h.SectorSize = s.Encryption, s.SectorSize
+	if h.SectorSize == 0 {
+		h.SectorSize = SectorSize
+	}
+
+	// The digests of the segment, by key slot.
+	digests := map[string]*digest{}
+	for _, d := range j.Digests {
+		if d.Type != "pbkdf2" {
+			continue
+		}
+		for _, sg := range d.Segments {
+			if sg != seg {
+				continue
+			}
+			for _, k := range d.Keyslots {
+				digests[k] = &digest{hash: d.Hash, iterations: d.Iterations, salt: d.Salt, sum: d.Digest}
+			}
+		}
+	}
+
+	slots := map[string]bool{}
+	for k := range j.Keyslots {
+		slots[k] = true
+	}
+	for _, id := range ids(slots) {
+		k := j.Keyslots[strconv.Itoa(id)]
+		d, ok := digests[strconv.Itoa(id)]
+		if k.Type != "luks2" || k.Area.Type != "raw" || k.AF.Type != "luks1" || !ok {
+			continue
+		}
+		if h.KeySize == 0 {
+			h.KeySize = k.KeySize
+		}
+		ks := &Keyslot{
+			ID: id,
+			KDF: KDF{Type: k.KDF.Type, Hash: k.KDF.Hash, Iterations: k.KDF.Iterations, Salt: k.KDF.Salt,
+				Time: k.KDF.Time, Memory: k.KDF.Memory, CPUs: k.KDF.CPUs},
+			Cipher:  k.Area.Encryption,
+			KeySize: k.Area.KeySize,
+			Stripes: k.AF.Stripes,
+			AFHash:  k.AF.Hash,
+			digest:  d,
+		}
+		if ks.Offset, err = strconv.ParseInt(k.Area.Offset, 10, 64); err != nil {
+			return nil, fmt.Errorf("key slot %d offset: %v", id, err)
+		}
+		if ks.Size, err = strconv.ParseInt(k.Area.Size, 10, 64); err != nil {
+			return nil, fmt.Errorf("key slot %d size: %v", id, err)
+		}
+		h.Keyslots = append(h.Keyslots, ks)
+	}
+	return h, nil
+}
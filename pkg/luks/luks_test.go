@@ -0,0 +1,199 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package luks
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// afSplit splits key into stripes, as afMerge merges them.
+func afSplit(t *testing.T, key []byte, stripes int, h func() hash.Hash) []byte {
+	m := make([]byte, len(key)*stripes)
+	if _, err := rand.Read(m[:len(key)*(stripes-1)]); err != nil {
+		t.Fatal(err)
+	}
+	d := make([]byte, len(key))
+	for i := 0; i < stripes-1; i++ {
+		for j := range d {
+			d[j] ^= m[i*len(key)+j]
+		}
+		d = diffuse(d, h)
+	}
+	for j := range d {
+		m[(stripes-1)*len(key)+j] = d[j] ^ key[j]
+	}
+	return m
+}
+
+// keyMaterial returns the key material of key, for pass, with kdf, in
+// sectors, encrypted with spec.
+func keyMaterial(t *testing.T, key, pass []byte, kdf *KDF, spec string, keySize, stripes int) []byte {
+	m := afSplit(t, key, stripes, sha256.New)
+	m = append(m, make([]byte, (SectorSize-len(m)%SectorSize)%SectorSize)...)
+	dk, err := kdf.derive(pass, keySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = crypt(spec, dk, m, SectorSize, false); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func random(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLUKS1(t *testing.T) {
+	key := random(t, 64)
+	salt, mkSalt := random(t, 32), random(t, 32)
+	disk := make([]byte, 4096*SectorSize)
+	h := disk[:luks1HeaderSize]
+	copy(h, Magic)
+	binary.BigEndian.PutUint16(h[6:], 1)
+	copy(h[8:], "aes")
+	copy(h[40:], "xts-plain64")
+	copy(h[72:], "sha256")
+	binary.BigEndian.PutUint32(h[104:], 2048)
+	binary.BigEndian.PutUint32(h[108:], 64)
+	copy(h[112:], pbkdf2.Key(key, mkSalt, 100, 20, sha256.New))
+	copy(h[132:], mkSalt)
+	binary.BigEndian.PutUint32(h[164:], 100)
+	copy(h[168:], "0b8f6f6a-8a7d-4bd9-b5c5-1e1c3b2e6f3d")
+	for i := 0; i < luks1Keyslots; i++ {
+		binary.BigEndian.PutUint32(h[208+i*luks1KeyslotSize:], 0xdead)
+	}
+	// Key slot 3 is the one in use.
+	s := h[208+3*luks1KeyslotSize:]
+	binary.BigEndian.PutUint32(s, luks1SlotActive)
+	binary.BigEndian.PutUint32(s[4:], 1000)
+	copy(s[8:], salt)
+	binary.BigEndian.PutUint32(s[40:], 8)
+	binary.BigEndian.PutUint32(s[44:], 4000)
+	kdf := &KDF{Type: "pbkdf2", Hash: "sha256", Iterations: 1000, Salt: salt}
+	copy(disk[8*SectorSize:], keyMaterial(t, key, []byte("secret"), kdf, "aes-xts-plain64", 64, 4000))
+
+	hdr, err := ReadHeader(bytes.NewReader(disk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Version != 1 || hdr.Cipher != "aes-xts-plain64" || hdr.KeySize != 64 || hdr.Offset != 2048*SectorSize ||
+		hdr.UUID != "0b8f6f6a-8a7d-4bd9-b5c5-1e1c3b2e6f3d" || len(hdr.Keyslots) != 1 || hdr.Keyslots[0].ID != 3 {
+		t.Errorf("ReadHeader: got %+v", hdr)
+	}
+	got, slot, err := hdr.Unlock(bytes.NewReader(disk), []byte("secret"))
+	if err != nil || slot != 3 || !bytes.Equal(got, key) {
+		t.Errorf("Unlock: got key %x, slot %d, %v, want %x, 3", got, slot, err, key)
+	}
+	if _, _, err := hdr.Unlock(bytes.NewReader(disk), []byte("guess")); err == nil {
+		t.Errorf("Unlock with the wrong passphrase: got nil, want error")
+	}
+
+	tg, err := hdr.Target("/dev/sda2", int64(len(disk)), got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tg.Length != 2048 || tg.Type != "crypt" || !strings.HasPrefix(tg.Params, "aes-xts-plain64 ") || !strings.HasSuffix(tg.Params, " 0 /dev/sda2 2048") {
+		t.Errorf("Target: got %v", tg)
+	}
+
+	if _, err := ReadHeader(bytes.NewReader(make([]byte, 4096))); err == nil {
+		t.Errorf("ReadHeader of zeros: got nil, want error")
+	}
+}
+
+// luks2Header makes a LUKS2 header, with magic, of the metadata j.
+func luks2Header(t *testing.T, magic string, j interface{}) []byte {
+	const size = 0x4000
+	b := make([]byte, size)
+	copy(b, magic)
+	binary.BigEndian.PutUint16(b[6:], 2)
+	binary.BigEndian.PutUint64(b[8:], size)
+	copy(b[72:], "sha256")
+	copy(b[168:], "6f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9")
+	js, err := json.Marshal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(b[luks2BinarySize:], js)
+	sum := sha256.Sum256(b)
+	copy(b[luks2CsumOff:], sum[:])
+	return b
+}
+
+func TestLUKS2(t *testing.T) {
+	key := random(t, 64)
+	dsalt := random(t, 32)
+	argon := &KDF{Type: "argon2id", Time: 1, Memory: 64, CPUs: 2, Salt: random(t, 32)}
+	pbk := &KDF{Type: "pbkdf2", Hash: "sha256", Iterations: 1000, Salt: random(t, 32)}
+	slot := func(kdf *KDF, off, keySize int, enc string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":     "luks2",
+			"key_size": 64,
+			"area":     map[string]interface{}{"type": "raw", "offset": strconv.Itoa(off), "size": "258048", "encryption": enc, "key_size": keySize},
+			"kdf": map[string]interface{}{"type": kdf.Type, "hash": kdf.Hash, "iterations": kdf.Iterations, "salt": kdf.Salt,
+				"time": kdf.Time, "memory": kdf.Memory, "cpus": kdf.CPUs},
+			"af": map[string]interface{}{"type": "luks1", "stripes": 4000, "hash": "sha256"},
+		}
+	}
+	j := map[string]interface{}{
+		"keyslots": map[string]interface{}{
+			"0": slot(argon, 0x8000, 64, "aes-xts-plain64"),
+			"2": slot(pbk, 0x48000, 32, "aes-cbc-essiv:sha256"),
+		},
+		"segments": map[string]interface{}{
+			"0": map[string]interface{}{"type": "crypt", "offset": "16777216", "size": "dynamic", "iv_tweak": "0",
+				"encryption": "aes-xts-plain64", "sector_size": 4096},
+		},
+		"digests": map[string]interface{}{
+			"0": map[string]interface{}{"type": "pbkdf2", "keyslots": []string{"0", "2"}, "segments": []string{"0"},
+				"hash": "sha256", "iterations": 1000, "salt": dsalt, "digest": pbkdf2.Key(key, dsalt, 1000, 32, sha256.New)},
+		},
+	}
+	disk := make([]byte, 20<<20)
+	copy(disk, luks2Header(t, Magic, j))
+	copy(disk[0x4000:], luks2Header(t, luks2MagicCopy, j))
+	copy(disk[0x8000:], keyMaterial(t, key, []byte("argon"), argon, "aes-xts-plain64", 64, 4000))
+	copy(disk[0x48000:], keyMaterial(t, key, []byte("pbkdf2"), pbk, "aes-cbc-essiv:sha256", 32, 4000))
+
+	hdr, err := ReadHeader(bytes.NewReader(disk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Version != 2 || hdr.Cipher != "aes-xts-plain64" || hdr.KeySize != 64 || hdr.Offset != 16<<20 ||
+		hdr.SectorSize != 4096 || len(hdr.Keyslots) != 2 || hdr.Keyslots[1].ID != 2 || hdr.Keyslots[0].KDF.Memory != 64 {
+		t.Errorf("ReadHeader: got %+v", hdr)
+	}
+	for pass, want := range map[string]int{"argon": 0, "pbkdf2": 2} {
+		got, slot, err := hdr.Unlock(bytes.NewReader(disk), []byte(pass))
+		if err != nil || slot != want || !bytes.Equal(got, key) {
+			t.Errorf("Unlock(%q): got key %x, slot %d, %v, want %x, %d", pass, got, slot, err, key, want)
+		}
+	}
+	tg, err := hdr.Target("/dev/sda2", int64(len(disk)), key)
+	if err != nil || tg.Length != 4<<20/SectorSize || !strings.HasSuffix(tg.Params, " 0 /dev/sda2 32768 1 sector_size:4096") {
+		t.Errorf("Target: got %v, %v", tg, err)
+	}
+
+	// With the header broken, its copy is used.
+	disk[luks2BinarySize] = '['
+	if hdr, err = ReadHeader(bytes.NewReader(disk)); err != nil || len(hdr.Keyslots) != 2 {
+		t.Errorf("ReadHeader of a broken header: got %+v, %v, want its copy", hdr, err)
+	}
+}
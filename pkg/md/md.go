@@ -0,0 +1,345 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package md reads the superblocks of Linux software RAID, md, devices and
+// assembles the arrays they are in.
+package md
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Magic is the magic number of both superblock formats.
+const Magic = 0xa92b4efc
+
+// The roles of a device which is not one of the disks of the array.
+const (
+	RoleSpare  = -1
+	RoleFaulty = -2
+)
+
+// The RAID levels which are not numbers.
+const (
+	LevelLinear    = -1
+	LevelMultipath = -4
+)
+
+var errNoSuperblock = errors.New("no md superblock")
+
+// A Superblock is what a device says of itself and of its array. Both the
+// 0.90 and the 1.x superblocks are read into one.
+type Superblock struct {
+	// Major and Minor are the version of the superblock: 0.90, or 1.0,
+	// 1.1 or 1.2, which is where on the device it is.
+	Major, Minor int
+	// UUID is the UUID of the array.
+	UUID [16]byte
+	// Name is the name of the array, HOST:NAME, which only 1.x has.
+	Name string
+	// PreferredMinor is which md device the array wants to be, which
+	// only 0.90 has; it is -1 in 1.x.
+	PreferredMinor int
+	Level          int
+	Layout         int
+	// ChunkSize is in bytes.
+	ChunkSize int
+	RaidDisks int
+	// Size is the size in bytes of each device the array uses.
+	Size int64
+	// Events is how many times the superblock was updated; the devices
+	// with fewer than the others are out of date.
+	Events uint64
+	// Role is which disk of the array the device is, from 0, or
+	// RoleSpare or RoleFaulty.
+	Role int
+}
+
+// Version returns the version of s, such as "1.2".
+func (s *Superblock) Version() string {
+	if s.Major == 0 {
+		return "0.90"
+	}
+	return fmt.Sprintf("%d.%d", s.Major, s.Minor)
+}
+
+// UUIDString returns the UUID as mdadm prints it, 4 groups of 8 hex digits,
+// with colons between.
+func (s *Superblock) UUIDString() string {
+	return fmt.Sprintf("%x:%x:%x:%x", s.UUID[0:4], s.UUID[4:8], s.UUID[8:12], s.UUID[12:16])
+}
+
+// LevelString returns the level as mdadm names it, such as "raid1".
+func (s *Superblock) LevelString() string {
+	switch s.Level {
+	case LevelLinear:
+		return "linear"
+	case LevelMultipath:
+		return "multipath"
+	}
+	return fmt.Sprintf("raid%d", s.Level)
+}
+
+// fold folds the 64 bit sum of a superblock's words into its checksum.
+func fold(sum uint64) uint32 {
+	return uint32(sum) + uint32(sum>>32)
+}
+
+// parse090 parses the 0.90 superblock b, which is 4096 bytes of 32 bit words
+// in the CPU's byte order; that is little endian on all we run on.
+func parse090(b []byte) (*Superblock, error) {
+	w := func(i int) uint32 { return binary.LittleEndian.Uint32(b[i*4:]) }
+	if w(0) != Magic {
+		return nil, errNoSuperblock
+	}
+	if w(1) != 0 || w(2) != 90 {
+		return nil, fmt.Errorf("md superblock version %d.%d is not supported", w(1), w(2))
+	}
+	var sum uint64
+	for i := 0; i < 1024; i++ {
+		// The checksum is word 38, and is not in the sum.
+		if i != 38 {
+			sum += uint64(w(i))
+		}
+	}
+	if fold(sum) != w(38) {
+		return nil, errors.New("the checksum of the md superblock is wrong")
+	}
+	s := &Superblock{
+		Major:          0,
+		Minor:          90,
+		PreferredMinor: int(w(11)),
+		Level:          int(int32(w(7))),
+		Layout:         int(w(64)),
+		ChunkSize:      int(w(65)),
+		RaidDisks:      int(w(10)),
+		Size:           int64(w(8)) * 1024,
+		Events:         uint64(w(40))<<32 | uint64(w(39)),
+	}
+	// The UUID is 4 words, the first of them apart from the others.
+	for i, n := range []int{5, 13, 14, 15} {
+		binary.BigEndian.PutUint32(s.UUID[i*4:], w(n))
+	}
+	// This device is at word 992: its number, major, minor, raid disk
+	// and state.
+	const faulty, active, sync = 1 << 0, 1 << 1, 1 << 2
+	switch state := w(992 + 4); {
+	case state&faulty != 0:
+		s.Role = RoleFaulty
+	case state&active != 0 && state&sync != 0:
+		s.Role = int(w(992 + 3))
+	default:
+		s.Role = RoleSpare
+	}
+	return s, nil
+}
+
+// parse1 parses the 1.x superblock b, which is little endian.
+func parse1(b []byte) (*Superblock, error) {
+	le32 := func(o int) uint32 { return binary.LittleEndian.Uint32(b[o:]) }
+	le64 := func(o int) uint64 { return binary.LittleEndian.Uint64(b[o:]) }
+	if le32(0) != Magic {
+		return nil, errNoSuperblock
+	}
+	if le32(4) != 1 {
+		return nil, fmt.Errorf("md superblock major version %d is not supported", le32(4))
+	}
+	maxDev := int(le32(220))
+	n := 256 + 2*maxDev
+	if maxDev > 1920 || n > len(b) {
+		return nil, fmt.Errorf("md superblock has %d devices, which is too many", maxDev)
+	}
+	var sum uint64
+	for o := 0; o+4 <= n; o += 4 {
+		// The checksum is at 216, and is not in the sum.
+		if o != 216 {
+			sum += uint64(le32(o))
+		}
+	}
+	if n%4 != 0 {
+		sum += uint64(binary.LittleEndian.Uint16(b[n-2:]))
+	}
+	if fold(sum) != le32(216) {
+		return nil, errors.New("the checksum of the md superblock is wrong")
+	}
+	s := &Superblock{
+		Major:          1,
+		PreferredMinor: -1,
+		Name:           strings.TrimRight(string(b[32:64]), "\x00"),
+		Level:          int(int32(le32(72))),
+		Layout:         int(le32(76)),
+		Size:           int64(le64(80)) * 512,
+		ChunkSize:      int(le32(88)) * 512,
+		RaidDisks:      int(le32(92)),
+		Events:         le64(200),
+		Role:           RoleSpare,
+	}
+	copy(s.UUID[:], b[16:32])
+	if d := int(le32(160)); d < maxDev {
+		switch r := binary.LittleEndian.Uint16(b[256+2*d:]); {
+		case r == 0xfffe:
+			s.Role = RoleFaulty
+		case r < 0xfffd:
+			s.Role = int(r)
+		}
+	}
+	return s, nil
+}
+
+// ReadSuperblock reads the md superblock of the device r, of size bytes.
+// The 1.1 superblock is at its start, the 1.2 4K after it, and the 0.90
+// and 1.0 ones near its end.
+func ReadSuperblock(r io.ReaderAt, size int64) (*Superblock, error) {
+	b := make([]byte, 4096)
+	read := func(off int64) bool {
+		if off < 0 || off+int64(len(b)) > size {
+			return false
+		}
+		_, err := r.ReadAt(b, off)
+		return err == nil
+	}
+	for _, v := range []struct {
+		minor int
+		off   int64
+	}{
+		{1, 0},
+		{2, 4096},
+		// 8K from the end, rounded down to 4K.
+		{0, (size - 8192) &^ 4095},
+	} {
+		if !read(v.off) {
+			continue
+		}
+		s, err := parse1(b)
+		if err == errNoSuperblock {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.Minor = v.minor
+		return s, nil
+	}
+	// 64K from the end, rounded down to 64K.
+	if read(size&^65535 - 65536) {
+		return parse090(b)
+	}
+	return nil, errNoSuperblock
+}
+
+// Examine reads the md superblock of the device, or file, name.
+func Examine(name string) (*Superblock, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ReadSuperblock(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	return s, nil
+}
+
+// A Member is a device of an array.
+type Member struct {
+	Device string
+	*Superblock
+}
+
+// An Array is the devices with the superblocks of one array.
+type Array struct {
+	// Super is the newest of the superblocks.
+	Super *Superblock
+	// Members are the devices which are up to date, in the order of
+	// their roles, then the spares.
+	Members []Member
+	// Stale are the devices which are out of date or faulty, which are
+	// not assembled.
+	Stale []Member
+}
+
+// Group groups the devices into the arrays they are in, by the UUID in
+// their superblocks.
+func Group(members []Member) []*Array {
+	var arrays []*Array
+	byUUID := map[[16]byte]*Array{}
+	for _, m := range members {
+		a, ok := byUUID[m.UUID]
+		if !ok {
+			a = &Array{Super: m.Superblock}
+			byUUID[m.UUID] = a
+			arrays = append(arrays, a)
+		}
+		if m.Events > a.Super.Events {
+			a.Super = m.Superblock
+		}
+		a.Members = append(a.Members, m)
+	}
+	for _, a := range arrays {
+		all := a.Members
+		a.Members = nil
+		roles := map[int]bool{}
+		for _, m := range all {
+			// Two devices with one role are a device which was
+			// replaced; the one with the newest superblock wins.
+			if m.Events < a.Super.Events || m.Role == RoleFaulty || m.Role >= 0 && roles[m.Role] {
+				a.Stale = append(a.Stale, m)
+				continue
+			}
+			if m.Role >= 0 {
+				roles[m.Role] = true
+			}
+			a.Members = append(a.Members, m)
+		}
+		sort.SliceStable(a.Members, func(i, j int) bool {
+			ri, rj := a.Members[i].Role, a.Members[j].Role
+			if ri < 0 || rj < 0 {
+				return rj < 0 && ri >= 0
+			}
+			return ri < rj
+		})
+	}
+	return arrays
+}
+
+// Active returns how many of the disks of a are there.
+func (a *Array) Active() int {
+	n := 0
+	for _, m := range a.Members {
+		if m.Role >= 0 && m.Role < a.Super.RaidDisks {
+			n++
+		}
+	}
+	return n
+}
+
+// Degraded returns whether disks of a are missing.
+func (a *Array) Degraded() bool {
+	return a.Active() < a.Super.RaidDisks
+}
+
+// Runnable returns whether a has enough of its disks to run. RAID 10 is
+// only known to be runnable with all its disks, or with one missing.
+func (a *Array) Runnable() bool {
+	missing := a.Super.RaidDisks - a.Active()
+	switch a.Super.Level {
+	case 1, LevelMultipath:
+		return a.Active() > 0
+	case 4, 5, 10:
+		return missing <= 1
+	case 6:
+		return missing <= 2
+	}
+	return missing == 0
+}
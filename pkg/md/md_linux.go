@@ -0,0 +1,151 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package md
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Where the md devices are, and what sysfs says of them. Tests change them.
+var (
+	Dev      = "/dev"
+	SysBlock = "/sys/block"
+)
+
+// The ioctls, of major 9.
+const (
+	// _IOW(9, 0x21, mdu_disk_info_t), which is 5 ints.
+	addNewDisk = 0x40140921
+	// _IOW(9, 0x23, mdu_array_info_t), which is 18 ints.
+	setArrayInfo = 0x40480923
+	// _IOW(9, 0x30, mdu_param_t).
+	runArray  = 0x400c0930
+	stopArray = 0x932
+
+	mdMajor = 9
+)
+
+// ioctl does the ioctl req on f, with the ints in arg, if there are any.
+func ioctl(f *os.File, req uintptr, arg ...uint32) error {
+	var p uintptr
+	if len(arg) > 0 {
+		b := make([]byte, 4*len(arg))
+		for i, a := range arg {
+			binary.LittleEndian.PutUint32(b[4*i:], a)
+		}
+		p = uintptr(unsafe.Pointer(&b[0]))
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, p); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// State returns the state of the array mdN, from sysfs, such as "clean" or
+// "active", or "clear" if there is no array.
+func State(minor int) string {
+	b, err := ioutil.ReadFile(filepath.Join(SysBlock, fmt.Sprintf("md%d", minor), "md", "array_state"))
+	if err != nil {
+		return "clear"
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// FreeMinor returns the highest minor, from 127 down, which has no array,
+// as mdadm picks for arrays with no preferred minor.
+func FreeMinor() (int, error) {
+	for m := 127; m >= 0; m-- {
+		if State(m) == "clear" {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("no md device is free")
+}
+
+// Minor returns N of the device /dev/mdN.
+func Minor(dev string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dev), "md"))
+	if err != nil || !strings.HasPrefix(filepath.Base(dev), "md") {
+		return 0, fmt.Errorf("%v is not an md device, mdN", dev)
+	}
+	return n, nil
+}
+
+// open opens the md device minor, making the device file if it is not
+// there. Opening it makes the array, empty, in the kernel.
+func open(minor int) (*os.File, error) {
+	p := filepath.Join(Dev, fmt.Sprintf("md%d", minor))
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		if err := syscall.Mknod(p, syscall.S_IFBLK|0600, int(unix.Mkdev(mdMajor, uint32(minor)))); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(p, os.O_RDWR, 0)
+}
+
+// Assemble assembles the array a as mdN, with its members, and runs it.
+// If it cannot be run, it is stopped again.
+func Assemble(minor int, a *Array) error {
+	if s := State(minor); s != "clear" {
+		return fmt.Errorf("md%d is already %v", minor, s)
+	}
+	f, err := open(minor)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// With only the version set, the kernel reads the rest from the
+	// superblocks of the disks.
+	if err := ioctl(f, setArrayInfo, uint32(a.Super.Major), uint32(a.Super.Minor), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("md%d: setting the version: %v", minor, err)
+	}
+	err = func() error {
+		for _, m := range a.Members {
+			fi, err := os.Stat(m.Device)
+			if err != nil {
+				return err
+			}
+			st, ok := fi.Sys().(*syscall.Stat_t)
+			if !ok || fi.Mode()&os.ModeDevice == 0 {
+				return fmt.Errorf("%v is not a block device", m.Device)
+			}
+			dev := uint64(st.Rdev)
+			if err := ioctl(f, addNewDisk, 0, unix.Major(dev), unix.Minor(dev), 0, 0); err != nil {
+				return fmt.Errorf("md%d: adding %v: %v", minor, m.Device, err)
+			}
+		}
+		if err := ioctl(f, runArray); err != nil {
+			return fmt.Errorf("md%d: running it: %v", minor, err)
+		}
+		return nil
+	}()
+	if err != nil {
+		ioctl(f, stopArray)
+	}
+	return err
+}
+
+// Stop stops the array mdN.
+func Stop(minor int) error {
+	f, err := open(minor)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := ioctl(f, stopArray); err != nil {
+		return fmt.Errorf("md%d: %v", minor, err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package md
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+var uuid = [16]byte{0xde, 0xad, 0xbe, 0xef, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+// super1 makes a 1.x superblock of a RAID 1 of 2 disks, of which the
+// device is role.
+func super1(role uint16, events uint64) []byte {
+	b := make([]byte, 4096)
+	le32 := binary.LittleEndian.PutUint32
+	le32(b, Magic)
+	le32(b[4:], 1)
+	copy(b[16:], uuid[:])
+	copy(b[32:], "host:root")
+	le32(b[72:], 1)
+	binary.LittleEndian.PutUint64(b[80:], 2048)
+	le32(b[92:], 2)
+	le32(b[160:], 1)
+	binary.LittleEndian.PutUint64(b[200:], events)
+	le32(b[220:], 3)
+	binary.LittleEndian.PutUint16(b[256:], 0xffff)
+	binary.LittleEndian.PutUint16(b[258:], role)
+	binary.LittleEndian.PutUint16(b[260:], 0xffff)
+	var sum uint64
+	for o := 0; o < 256; o += 4 {
+		sum += uint64(binary.LittleEndian.Uint32(b[o:]))
+	}
+	sum += uint64(binary.LittleEndian.Uint32(b[256:])) + uint64(binary.LittleEndian.Uint16(b[260:]))
+	le32(b[216:], fold(sum))
+	return b
+}
+
+// super090 makes a 0.90 superblock of a RAID 5 of 3 disks, of which the
+// device is disk 2.
+func super090() []byte {
+	b := make([]byte, 4096)
+	w := func(i int, v uint32) { binary.LittleEndian.PutUint32(b[4*i:], v) }
+	w(0, Magic)
+	w(2, 90)
+	w(5, 0xdeadbeef)
+	w(13, 0x01020304)
+	w(14, 0x05060708)
+	w(15, 0x090a0b0c)
+	w(7, 5)
+	w(8, 1024)
+	w(10, 3)
+	w(11, 3)
+	w(39, 7)
+	w(65, 65536)
+	w(992+3, 2)
+	w(992+4, 6)
+	var sum uint64
+	for i := 0; i < 1024; i++ {
+		sum += uint64(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	w(38, fold(sum))
+	return b
+}
+
+func TestReadSuperblock(t *testing.T) {
+	const size = 1 << 20
+	for _, tt := range []struct {
+		version string
+		off     int64
+		sb      []byte
+		want    Superblock
+	}{
+		{"1.1", 0, super1(1, 9), Superblock{Major: 1, Minor: 1, UUID: uuid, Name: "host:root", PreferredMinor: -1,
+			Level: 1, RaidDisks: 2, Size: 1 << 20, Events: 9, Role: 1}},
+		{"1.2", 4096, super1(0, 9), Superblock{Major: 1, Minor: 2, UUID: uuid, Name: "host:root", PreferredMinor: -1,
+			Level: 1, RaidDisks: 2, Size: 1 << 20, Events: 9, Role: 0}},
+		{"1.0", size - 8192, super1(0xfffe, 9), Superblock{Major: 1, Minor: 0, UUID: uuid, Name: "host:root", PreferredMinor: -1,
+			Level: 1, RaidDisks: 2, Size: 1 << 20, Events: 9, Role: RoleFaulty}},
+		{"0.90", size - 65536, super090(), Superblock{Major: 0, Minor: 90, UUID: uuid, PreferredMinor: 3,
+			Level: 5, ChunkSize: 65536, RaidDisks: 3, Size: 1 << 20, Events: 7, Role: 2}},
+	} {
+		dev := make([]byte, size)
+		copy(dev[tt.off:], tt.sb)
+		s, err := ReadSuperblock(bytes.NewReader(dev), size)
+		if err != nil {
+			t.Errorf("%v: %v", tt.version, err)
+			continue
+		}
+		if !reflect.DeepEqual(*s, tt.want) {
+			t.Errorf("%v: got %+v, want %+v", tt.version, *s, tt.want)
+		}
+		if s.Version() != tt.version {
+			t.Errorf("Version: got %v, want %v", s.Version(), tt.version)
+		}
+		if s.UUIDString() != "deadbeef:01020304:05060708:090a0b0c" {
+			t.Errorf("UUIDString: got %v", s.UUIDString())
+		}
+	}
+
+	dev := make([]byte, size)
+	if _, err := ReadSuperblock(bytes.NewReader(dev), size); err != errNoSuperblock {
+		t.Errorf("no superblock: got %v, want %v", err, errNoSuperblock)
+	}
+	copy(dev[4096:], super1(0, 9))
+	dev[4096+100]++
+	if _, err := ReadSuperblock(bytes.NewReader(dev), size); err == nil {
+		t.Errorf("bad checksum: got nil, want error")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	sb := func(uuid byte, role int, events uint64) *Superblock {
+		return &Superblock{UUID: [16]byte{uuid}, Level: 1, RaidDisks: 2, Role: role, Events: events}
+	}
+	arrays := Group([]Member{
+		{"/dev/sdc1", sb(1, RoleSpare, 5)},
+		{"/dev/sdb1", sb(1, 1, 5)},
+		{"/dev/sdd1", sb(2, 0, 3)},
+		{"/dev/sda1", sb(1, 0, 5)},
+		{"/dev/sde1", sb(2, 1, 2)},
+		{"/dev/sdf1", sb(1, 1, 4)},
+	})
+	if len(arrays) != 2 {
+		t.Fatalf("got %d arrays, want 2", len(arrays))
+	}
+	devs := func(ms []Member) []string {
+		var d []string
+		for _, m := range ms {
+			d = append(d, m.Device)
+		}
+		return d
+	}
+	for i, tt := range []struct {
+		members, stale     []string
+		degraded, runnable bool
+	}{
+		{[]string{"/dev/sda1", "/dev/sdb1", "/dev/sdc1"}, []string{"/dev/sdf1"}, false, true},
+		{[]string{"/dev/sdd1"}, []string{"/dev/sde1"}, true, true},
+	} {
+		a := arrays[i]
+		if got := devs(a.Members); !reflect.DeepEqual(got, tt.members) {
+			t.Errorf("array %d: got members %v, want %v", i, got, tt.members)
+		}
+		if got := devs(a.Stale); !reflect.DeepEqual(got, tt.stale) {
+			t.Errorf("array %d: got stale %v, want %v", i, got, tt.stale)
+		}
+		if a.Degraded() != tt.degraded || a.Runnable() != tt.runnable {
+			t.Errorf("array %d: got degraded %v, runnable %v, want %v, %v", i, a.Degraded(), a.Runnable(), tt.degraded, tt.runnable)
+		}
+	}
+}
+
+func TestRunnable(t *testing.T) {
+	for _, tt := range []struct {
+		level, disks, active int
+		want                 bool
+	}{
+		{0, 2, 2, true},
+		{0, 2, 1, false},
+		{LevelLinear, 3, 2, false},
+		{1, 3, 1, true},
+		{1, 2, 0, false},
+		{5, 4, 3, true},
+		{5, 4, 2, false},
+		{6, 5, 3, true},
+		{6, 5, 2, false},
+		{10, 4, 3, true},
+		{10, 4, 2, false},
+	} {
+		a := &Array{Super: &Superblock{Level: tt.level, RaidDisks: tt.disks}}
+		for r := 0; r < tt.active; r++ {
+			a.Members = append(a.Members, Member{"", &Superblock{Role: r}})
+		}
+		if got := a.Runnable(); got != tt.want {
+			t.Errorf("level %d, %d of %d disks: got %v, want %v", tt.level, tt.active, tt.disks, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package measurement measures what u-root runs and boots, before it does,
+// into the PCRs of the TPM, as GRUB does, so that a verifier can attest to
+// how the machine was booted.
+package measurement
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/u-root/u-root/pkg/tpm"
+)
+
+// The PCRs measurements go in, as GRUB's.
+const (
+	// ConfigPCR has command lines and configuration.
+	ConfigPCR = 8
+	// FilePCR has kernels, initramfs and programs.
+	FilePCR = 9
+)
+
+// Log is the event log: a line for each measurement, of the PCR, the
+// digest, and what was measured, which a verifier replays to check the
+// PCRs.
+var Log = "/tmp/measurements"
+
+// A Measurer measures into a TPM.
+type Measurer struct {
+	TPM *tpm.TPM
+}
+
+// Open opens the TPM. If there is none, the error is os.IsNotExist.
+func Open() (*Measurer, error) {
+	t, err := tpm.Open(tpm.Device)
+	if err != nil {
+		return nil, err
+	}
+	return &Measurer{TPM: t}, nil
+}
+
+// Close closes the TPM.
+func (m *Measurer) Close() error {
+	return m.TPM.Close()
+}
+
+// measure measures b into pcr, and logs it as what.
+func (m *Measurer) measure(pcr uint32, what string, b []byte) error {
+	d, err := m.TPM.Measure(pcr, b)
+	if err != nil {
+		return fmt.Errorf("measuring %v: %v", what, err)
+	}
+	f, err := os.OpenFile(Log, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "%d %x %s\n", pcr, d, what)
+	return f.Close()
+}
+
+// File measures the file name into FilePCR.
+func (m *Measurer) File(name string) error {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return m.measure(FilePCR, name, b)
+}
+
+// Data measures b, a command line or configuration which is described as
+// what, into ConfigPCR.
+func (m *Measurer) Data(what string, b []byte) error {
+	return m.measure(ConfigPCR, what, b)
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package measurement
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/tpm"
+)
+
+// tpm12 is a TPM 1.2 which only extends PCRs.
+type tpm12 struct {
+	extended []string
+	resp     []byte
+}
+
+func (t *tpm12) Write(b []byte) (int, error) {
+	rc := uint32(0x1e)
+	if binary.BigEndian.Uint16(b) == 0xc1 && binary.BigEndian.Uint32(b[6:]) == 0x14 {
+		t.extended = append(t.extended, fmt.Sprintf("%d %x", binary.BigEndian.Uint32(b[10:]), b[14:]))
+		rc = 0
+	}
+	t.resp = []byte{0, 0xc4, 0, 0, 0, 10, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(t.resp[6:], rc)
+	return len(b), nil
+}
+
+func (t *tpm12) Read(b []byte) (int, error) {
+	return copy(b, t.resp), nil
+}
+
+func TestMeasure(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "measurement")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	Log = filepath.Join(tmp, "log")
+	kernel := filepath.Join(tmp, "kernel")
+	if err := ioutil.WriteFile(kernel, []byte("bzImage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &tpm12{}
+	tp, err := tpm.New(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Measurer{TPM: tp}
+	if err := m.File(kernel); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Data("cmdline", []byte("console=ttyS0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.File(filepath.Join(tmp, "initramfs")); err == nil {
+		t.Errorf("File of a file which is not there: got nil, want error")
+	}
+
+	k, c := sha1.Sum([]byte("bzImage")), sha1.Sum([]byte("console=ttyS0"))
+	want := []string{fmt.Sprintf("9 %x", k), fmt.Sprintf("8 %x", c)}
+	if fmt.Sprint(fake.extended) != fmt.Sprint(want) {
+		t.Errorf("extended: got %v, want %v", fake.extended, want)
+	}
+	b, err := ioutil.ReadFile(Log)
+	if wantLog := fmt.Sprintf("%s %s\n%s cmdline\n", want[0], kernel, want[1]); err != nil || string(b) != wantLog {
+		t.Errorf("log: got %q, %v, want %q", b, err, wantLog)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package meminfo reads how memory is used from /proc/meminfo.
+package meminfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// procMeminfo is read by Read.
+var procMeminfo = "/proc/meminfo"
+
+// Meminfo is the lines of /proc/meminfo, e.g. MemTotal, in bytes. Those
+// which are not sizes, such as HugePages_Total, are as they are.
+type Meminfo map[string]uint64
+
+// Parse parses lines in the format of /proc/meminfo: NAME: VALUE [kB].
+func Parse(r io.Reader) (Meminfo, error) {
+	m := make(Meminfo)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) < 2 || !strings.HasSuffix(f[0], ":") {
+			continue
+		}
+		v, err := strconv.ParseUint(f[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", s.Text(), err)
+		}
+		if len(f) > 2 && f[2] == "kB" {
+			v *= 1024
+		}
+		m[strings.TrimSuffix(f[0], ":")] = v
+	}
+	return m, s.Err()
+}
+
+// Read reads /proc/meminfo.
+func Read() (Meminfo, error) {
+	f, err := os.Open(procMeminfo)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// BuffCache is the memory in buffers and caches, which the kernel can get
+// back when it needs it.
+func (m Meminfo) BuffCache() uint64 {
+	return m["Buffers"] + m["Cached"] + m["SReclaimable"]
+}
+
+// Used is the memory which is neither free nor in buffers and caches.
+func (m Meminfo) Used() uint64 {
+	u := int64(m["MemTotal"] - m["MemFree"] - m.BuffCache())
+	if u < 0 {
+		return m["MemTotal"] - m["MemFree"]
+	}
+	return uint64(u)
+}
+
+// Available is how much memory can be had without swapping. Kernels before
+// 3.14 do not say, and it is taken to be what is free or in caches.
+func (m Meminfo) Available() uint64 {
+	if a, ok := m["MemAvailable"]; ok {
+		return a
+	}
+	return m["MemFree"] + m.BuffCache()
+}
+
+// Human formats a size of b bytes as free -h does: with three significant
+// digits at most, in the largest binary unit it is at least one of, e.g.
+// 1.5Gi or 512Mi.
+func Human(b uint64) string {
+	const units = "KMGTPE"
+	if b < 1024 {
+		return fmt.Sprintf("%dB", b)
+	}
+	f, u := float64(b)/1024, 0
+	// What would round up to 1024 is 1.0 of the next unit.
+	for f >= 1023.5 && u < len(units)-1 {
+		f /= 1024
+		u++
+	}
+	if f < 10 {
+		return fmt.Sprintf("%.1f%ci", f, units[u])
+	}
+	return fmt.Sprintf("%.0f%ci", f, units[u])
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package meminfo
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMeminfo = `MemTotal:        6147400 kB
+MemFree:         3872212 kB
+MemAvailable:    5478200 kB
+Buffers:          279356 kB
+Cached:          1488960 kB
+SwapTotal:       1048572 kB
+SwapFree:        1048000 kB
+SReclaimable:     100000 kB
+HugePages_Total:       2
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(testMeminfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name      string
+		got, want uint64
+	}{
+		{"MemTotal", m["MemTotal"], 6147400 * 1024},
+		{"HugePages_Total", m["HugePages_Total"], 2},
+		{"BuffCache", m.BuffCache(), (279356 + 1488960 + 100000) * 1024},
+		{"Used", m.Used(), (6147400 - 3872212 - 279356 - 1488960 - 100000) * 1024},
+		{"Available", m.Available(), 5478200 * 1024},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+
+	delete(m, "MemAvailable")
+	if got, want := m.Available(), uint64(3872212+279356+1488960+100000)*1024; got != want {
+		t.Errorf("Available without MemAvailable: got %d, want %d", got, want)
+	}
+
+	if _, err := Parse(strings.NewReader("MemTotal: lots kB\n")); err == nil {
+		t.Errorf("Parse(MemTotal: lots kB): got nil, want an error")
+	}
+}
+
+func TestHuman(t *testing.T) {
+	for _, tt := range []struct {
+		b    uint64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1536, "1.5Ki"},
+		{512 << 20, "512Mi"},
+		{6147400 * 1024, "5.9Gi"},
+		{20 << 30, "20Gi"},
+		{1<<30 - 512<<10, "1.0Gi"},
+	} {
+		if got := Human(tt.b); got != tt.want {
+			t.Errorf("Human(%d): got %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Where block devices are found. Tests change them.
+var (
+	sysClassBlock = "/sys/class/block"
+	devDir        = "/dev"
+)
+
+// BlockDevices returns the device files of the block devices, disks and
+// their partitions, the kernel knows of.
+func BlockDevices() ([]string, error) {
+	fis, err := ioutil.ReadDir(sysClassBlock)
+	if err != nil {
+		return nil, err
+	}
+	var devs []string
+	for _, fi := range fis {
+		devs = append(devs, filepath.Join(devDir, fi.Name()))
+	}
+	return devs, nil
+}
+
+// ProbeFile returns what the superblock of the file system on the device,
+// or file, name says of it.
+func ProbeFile(name string) (*FSInfo, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Probe(f)
+}
+
+// FindDevice returns the device named by spec, which is UUID=UUID or
+// LABEL=LABEL, as in /etc/fstab, or a device file, which is returned as
+// it is. UUIDs are compared without regard to case.
+func FindDevice(spec string) (string, error) {
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) != 2 || (kv[0] != "UUID" && kv[0] != "LABEL") {
+		return spec, nil
+	}
+	v := strings.Trim(kv[1], `"`)
+	devs, err := BlockDevices()
+	if err != nil {
+		return "", err
+	}
+	for _, d := range devs {
+		fs, err := ProbeFile(d)
+		if err != nil {
+			continue
+		}
+		if kv[0] == "UUID" && strings.EqualFold(fs.UUID, v) || kv[0] == "LABEL" && fs.Label == v {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("no block device has %v", spec)
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDevice(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "mount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer func(s, d string) { sysClassBlock, devDir = s, d }(sysClassBlock, devDir)
+	sysClassBlock, devDir = filepath.Join(tmp, "sys"), filepath.Join(tmp, "dev")
+
+	// Files stand in for the devices.
+	devs := map[string]map[int][]byte{
+		"sda":  nil,
+		"sda1": with(ext(0, 0, 0), map[int][]byte{extUUIDOff: testUUID, extLabelOff: []byte("root")}),
+		"sda2": {39: {0xef, 0xbe, 0xad, 0xde}, 43: []byte("BOOT       "), 54: []byte("FAT16   "), 510: {0x55, 0xaa}},
+	}
+	for _, d := range []string{sysClassBlock, devDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for n, img := range devs {
+		if err := os.Mkdir(filepath.Join(sysClassBlock, n), 0755); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(image(img))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(devDir, n), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		spec string
+		want string
+	}{
+		{"UUID=" + testUUIDString, "sda1"},
+		{"UUID=12345678-9ABC-DEF0-0123-456789ABCDEF", "sda1"},
+		{`LABEL="root"`, "sda1"},
+		{"UUID=DEAD-BEEF", "sda2"},
+		{"LABEL=BOOT", "sda2"},
+		{"LABEL=boot", ""},
+		{"UUID=0000", ""},
+	} {
+		got, err := FindDevice(tt.spec)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("FindDevice(%q): got %q, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if want := filepath.Join(devDir, tt.want); err != nil || got != want {
+			t.Errorf("FindDevice(%q): got %q, %v, want %q", tt.spec, got, err, want)
+		}
+	}
+	if got, err := FindDevice("/dev/sdb1"); err != nil || got != "/dev/sdb1" {
+		t.Errorf("FindDevice(/dev/sdb1): got %q, %v, want it as it is", got, err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FstabEntry is a line of /etc/fstab.
+type FstabEntry struct {
+	// Spec is the device, UUID=UUID, LABEL=LABEL, or, for file systems
+	// without one, any name, e.g. proc.
+	Spec string
+	// File is where it is mounted.
+	File    string
+	Type    string
+	Options string
+	// Freq is for dump, which we do not have.
+	Freq int
+	// PassNo is the order in which fsck checks it; 0 is not at all.
+	PassNo int
+}
+
+// HasOption says if the options of e have opt.
+func (e FstabEntry) HasOption(opt string) bool {
+	for _, o := range strings.Split(e.Options, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// unescape replaces the octal escapes fstab has for spaces, tabs and the
+// like, e.g. \040, with what they stand for.
+func unescape(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b = append(b, byte(c))
+				i += 3
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// ParseFstab parses entries in the format of /etc/fstab: SPEC FILE TYPE
+// [OPTIONS [FREQ [PASSNO]]]. OPTIONS are defaults if left out. Blank lines
+// and those which start with # are ignored.
+func ParseFstab(r io.Reader) ([]FstabEntry, error) {
+	var entries []FstabEntry
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		if len(f) < 3 || len(f) > 6 {
+			return nil, fmt.Errorf("line %d: want SPEC FILE TYPE [OPTIONS [FREQ [PASSNO]]], got %q", n, s.Text())
+		}
+		e := FstabEntry{
+			Spec:    unescape(f[0]),
+			File:    unescape(f[1]),
+			Type:    f[2],
+			Options: "defaults",
+		}
+		if len(f) > 3 {
+			e.Options = unescape(f[3])
+		}
+		var err error
+		if len(f) > 4 {
+			if e.Freq, err = strconv.Atoi(f[4]); err != nil {
+				return nil, fmt.Errorf("line %d: freq: %v", n, err)
+			}
+		}
+		if len(f) > 5 {
+			if e.PassNo, err = strconv.Atoi(f[5]); err != nil {
+				return nil, fmt.Errorf("line %d: passno: %v", n, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// procMounts is where the mounts are listed.
+var procMounts = "/proc/self/mounts"
+
+// Fsck checks the file system of type fsType on dev before it is mounted.
+type Fsck func(dev, fsType string) error
+
+// fsckNames returns the names of the checkers of fsType, the most specific
+// first. The ext checker checks all of ext2, ext3 and ext4.
+func fsckNames(fsType string) []string {
+	var n []string
+	if fsType != "" && fsType != "auto" {
+		n = append(n, "fsck."+fsType)
+	}
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		n = append(n, "fsck.ext")
+	case "msdos", "fat":
+		n = append(n, "fsck.vfat")
+	}
+	return n
+}
+
+// ExecFsck is a Fsck which runs fsck.TYPE -a, or, if there is none in $PATH,
+// fsck -a, if there is one. If the type is auto, it is found from the
+// superblock. An exit status of 1, for errors which were corrected, is
+// not an error.
+func ExecFsck(dev, fsType string) error {
+	if fsType == "" || fsType == "auto" {
+		if fs, err := ProbeFile(dev); err == nil {
+			fsType = fs.Type
+		}
+	}
+	var c *exec.Cmd
+	for _, n := range fsckNames(fsType) {
+		if p, err := exec.LookPath(n); err == nil {
+			c = exec.Command(p, "-a", dev)
+			break
+		}
+	}
+	if c == nil {
+		p, err := exec.LookPath("fsck")
+		if err != nil {
+			return nil
+		}
+		args := []string{"-a"}
+		if fsType != "" && fsType != "auto" {
+			args = append(args, "-t", fsType)
+		}
+		c = exec.Command(p, append(args, dev)...)
+	}
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	err := c.Run()
+	if e, ok := err.(*exec.ExitError); ok && e.Sys().(syscall.WaitStatus).ExitStatus() == 1 {
+		return nil
+	}
+	return err
+}
+
+// Mounted returns the mount points in /proc/self/mounts.
+func Mounted() (map[string]bool, error) {
+	b, err := ioutil.ReadFile(procMounts)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]bool)
+	for _, l := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(l); len(f) > 1 {
+			m[unescape(f[1])] = true
+		}
+	}
+	return m, nil
+}
+
+// depth is how many directories deep the mount point p is.
+func depth(p string) int {
+	p = filepath.Clean(p)
+	if p == "/" {
+		return 0
+	}
+	return strings.Count(p, "/")
+}
+
+// MountEntry mounts e, creating its mount point if need be. If fsck is not
+// nil, and e has a PassNo, its file system is checked first.
+func MountEntry(e FstabEntry, fsck Fsck) error {
+	dev, err := FindDevice(e.Spec)
+	if err != nil {
+		return err
+	}
+	flags, data := ParseOptions(e.Options, 0)
+	types := []string{e.Type}
+	if fi, err := os.Stat(dev); err == nil && fi.Mode()&os.ModeDevice != 0 {
+		if fsck != nil && e.PassNo > 0 {
+			if err := fsck(dev, e.Type); err != nil {
+				return fmt.Errorf("checking %v: %v", dev, err)
+			}
+		}
+		if flags&unix.MS_BIND == 0 {
+			if types, err = FSTypes(dev, e.Type); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.MkdirAll(e.File, 0755); err != nil {
+		return err
+	}
+	var errs []string
+	for _, t := range types {
+		if err := unix.Mount(dev, e.File, t, flags, data); err != nil {
+			errs = append(errs, fmt.Sprintf("type %s: %v", t, err))
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("mounting %v on %v: %v", dev, e.File, strings.Join(errs, "; "))
+}
+
+// MountAll mounts the entries, parents before the mount points in them.
+// Those which are noauto, swap, or already mounted, such as /, are left
+// out. It goes on past those which fail, and returns an error for them,
+// unless they are nofail.
+func MountAll(entries []FstabEntry, fsck Fsck) error {
+	mounted, err := Mounted()
+	if err != nil {
+		return err
+	}
+	sorted := make([]FstabEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return depth(sorted[i].File) < depth(sorted[j].File)
+	})
+	var errs []string
+	for _, e := range sorted {
+		if e.HasOption("noauto") || e.Type == "swap" || !filepath.IsAbs(e.File) || mounted[filepath.Clean(e.File)] {
+			continue
+		}
+		if err := MountEntry(e, fsck); err != nil && !e.HasOption("nofail") {
+			errs = append(errs, fmt.Sprintf("%v: %v", e.File, err))
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", strings.Join(errs, "; "))
+	}
+	return nil
+}
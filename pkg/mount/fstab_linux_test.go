@@ -0,0 +1,87 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestMountAll(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("mount needs root")
+	}
+	tmp, err := ioutil.TempDir("", "mountall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	a, b := filepath.Join(tmp, "a"), filepath.Join(tmp, "a/b")
+	defer unix.Unmount(a, unix.MNT_DETACH)
+	defer unix.Unmount(b, unix.MNT_DETACH)
+
+	var fscked []string
+	fsck := func(dev, fsType string) error {
+		fscked = append(fscked, dev)
+		return nil
+	}
+	// a/b is in a, so a has to be mounted first.
+	entries := []FstabEntry{
+		{Spec: "tmpfs", File: b, Type: "tmpfs", Options: "mode=0700", PassNo: 2},
+		{Spec: "tmpfs", File: a, Type: "tmpfs", Options: "size=1m"},
+		{Spec: "tmpfs", File: filepath.Join(tmp, "noauto"), Type: "tmpfs", Options: "noauto"},
+		{Spec: "LABEL=nosuchlabel", File: filepath.Join(tmp, "nofail"), Type: "ext4", Options: "nofail"},
+		{Spec: "/dev/sda1", File: "/", Type: "ext4"},
+	}
+	if err := MountAll(entries, fsck); err != nil {
+		t.Fatalf("MountAll: %v", err)
+	}
+	m, err := Mounted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]bool{a: true, b: true, filepath.Join(tmp, "noauto"): false, filepath.Join(tmp, "nofail"): false} {
+		if m[p] != want {
+			t.Errorf("%v mounted: got %v, want %v", p, m[p], want)
+		}
+	}
+	if fi, err := os.Stat(b); err != nil || fi.Mode().Perm() != 0700 {
+		t.Errorf("%v: got %v, %v, want mode 0700", b, fi, err)
+	}
+	// tmpfs is not a device, so there is nothing to check.
+	if fscked != nil {
+		t.Errorf("fsck: got %v, want nothing checked", fscked)
+	}
+
+	// Mounting them again does nothing.
+	if err := MountAll(entries, fsck); err != nil {
+		t.Errorf("MountAll again: %v", err)
+	}
+	bad := []FstabEntry{{Spec: "LABEL=nosuchlabel", File: filepath.Join(tmp, "fail"), Type: "ext4"}}
+	if err := MountAll(bad, fsck); err == nil {
+		t.Errorf("MountAll(%v): got nil, want an error", bad)
+	}
+}
+
+func TestFsckNames(t *testing.T) {
+	for _, tt := range []struct {
+		fsType string
+		want   []string
+	}{
+		{"ext4", []string{"fsck.ext4", "fsck.ext"}},
+		{"vfat", []string{"fsck.vfat"}},
+		{"msdos", []string{"fsck.msdos", "fsck.vfat"}},
+		{"auto", nil},
+	} {
+		if got := fsckNames(tt.fsType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fsckNames(%q): got %v, want %v", tt.fsType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFstab(t *testing.T) {
+	got, err := ParseFstab(strings.NewReader(`# <file system> <mount point> <type> <options> <dump> <pass>
+UUID=0e2a0c1e-6e2f-4d2e-9f3a-6c1b1e0c2d4f / ext4 errors=remount-ro 0 1
+
+LABEL=My\040Data /mnt/my\040data vfat noauto,nofail 0 2
+proc /proc proc
+tmpfs	/tmp	tmpfs	size=64m,mode=1777	0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FstabEntry{
+		{Spec: "UUID=0e2a0c1e-6e2f-4d2e-9f3a-6c1b1e0c2d4f", File: "/", Type: "ext4", Options: "errors=remount-ro", PassNo: 1},
+		{Spec: "LABEL=My Data", File: "/mnt/my data", Type: "vfat", Options: "noauto,nofail", PassNo: 2},
+		{Spec: "proc", File: "/proc", Type: "proc", Options: "defaults"},
+		{Spec: "tmpfs", File: "/tmp", Type: "tmpfs", Options: "size=64m,mode=1777"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFstab: got %+v, want %+v", got, want)
+	}
+	if !want[1].HasOption("nofail") || want[1].HasOption("no") {
+		t.Errorf("HasOption(%q): got it wrong for nofail or no", want[1].Options)
+	}
+
+	for _, bad := range []string{
+		"/dev/sda1 /",
+		"/dev/sda1 / ext4 defaults 0 1 x",
+		"/dev/sda1 / ext4 defaults x",
+		"/dev/sda1 / ext4 defaults 0 x",
+	} {
+		if _, err := ParseFstab(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseFstab(%q): got nil, want an error", bad)
+		}
+	}
+}
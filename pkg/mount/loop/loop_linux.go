@@ -0,0 +1,184 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package loop sets up loop devices, which make files into block devices.
+package loop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// ioctls of loop devices and /dev/loop-control.
+const (
+	setFD       = 0x4c00
+	clrFD       = 0x4c01
+	setStatus64 = 0x4c04
+	getStatus64 = 0x4c05
+	ctlGetFree  = 0x4c82
+
+	// FlagsReadOnly is set for read only devices.
+	FlagsReadOnly = 1
+	// FlagsAutoclear makes the device detach itself from its file when it
+	// is last closed, e.g. when the file system on it is unmounted.
+	FlagsAutoclear = 4
+	// FlagsPartScan makes the kernel look for partitions on the device,
+	// which become devices such as /dev/loop0p1.
+	FlagsPartScan = 8
+)
+
+// Info is struct loop_info64, the status of a loop device.
+type Info struct {
+	Device         uint64
+	Inode          uint64
+	RDevice        uint64
+	Offset         uint64
+	SizeLimit      uint64
+	Number         uint32
+	EncryptType    uint32
+	EncryptKeySize uint32
+	Flags          uint32
+	FileName       [64]byte
+	CryptName      [64]byte
+	EncryptKey     [32]byte
+	Init           [2]uint64
+}
+
+func ioctl(fd uintptr, req uintptr, arg uintptr) (uintptr, error) {
+	r, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	if errno != 0 {
+		return 0, errno
+	}
+	return r, nil
+}
+
+// FindDevice returns the name of a free loop device.
+func FindDevice() (string, error) {
+	c, err := os.OpenFile("/dev/loop-control", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	n, err := ioctl(c.Fd(), ctlGetFree, 0)
+	if err != nil {
+		return "", fmt.Errorf("finding a free loop device: %v", err)
+	}
+	return fmt.Sprintf("/dev/loop%d", n), nil
+}
+
+// SetFile makes the loop device dev a block device of file.
+func SetFile(dev, file *os.File) error {
+	_, err := ioctl(dev.Fd(), setFD, file.Fd())
+	return err
+}
+
+// ClearFile detaches the loop device dev from its file.
+func ClearFile(dev *os.File) error {
+	_, err := ioctl(dev.Fd(), clrFD, 0)
+	return err
+}
+
+// GetInfo returns the status of the loop device dev.
+func GetInfo(dev *os.File) (*Info, error) {
+	var i Info
+	if _, err := ioctl(dev.Fd(), getStatus64, uintptr(unsafe.Pointer(&i))); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// SetInfo sets the status of the loop device dev.
+func SetInfo(dev *os.File, i *Info) error {
+	_, err := ioctl(dev.Fd(), setStatus64, uintptr(unsafe.Pointer(i)))
+	return err
+}
+
+// Attach attaches file to the loop device name, with flags such as
+// FlagsPartScan, and returns the open device. With FlagsAutoclear, the
+// device detaches itself when it is last closed, so close it once it is in
+// use, e.g. mounted.
+func Attach(name, file string, readOnly bool, flags uint32) (*os.File, error) {
+	mode := os.O_RDWR
+	if readOnly {
+		mode = os.O_RDONLY
+	}
+	f, err := os.OpenFile(file, mode, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dev, err := os.OpenFile(name, mode, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := SetFile(dev, f); err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("attaching %s to %s: %v", file, name, err)
+	}
+	i := &Info{Flags: flags}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	copy(i.FileName[:len(i.FileName)-1], file)
+	if err := SetInfo(dev, i); err != nil {
+		ClearFile(dev)
+		dev.Close()
+		return nil, fmt.Errorf("setting status of %s: %v", name, err)
+	}
+	return dev, nil
+}
+
+// New attaches file to a free loop device, as Attach does with
+// FlagsAutoclear.
+func New(file string, readOnly bool) (*os.File, error) {
+	name, err := FindDevice()
+	if err != nil {
+		return nil, err
+	}
+	return Attach(name, file, readOnly, FlagsAutoclear)
+}
+
+// Detach detaches the loop device name from its file.
+func Detach(name string) error {
+	dev, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer dev.Close()
+	if err := ClearFile(dev); err != nil {
+		return fmt.Errorf("detaching %s: %v", name, err)
+	}
+	return nil
+}
+
+// Device is a loop device which is attached to a file.
+type Device struct {
+	// Name is the device's name, e.g. /dev/loop0.
+	Name string
+	// File is the name of the file.
+	File string
+}
+
+// List returns the loop devices which are attached to files, from /sys.
+func List() ([]Device, error) {
+	files, err := filepath.Glob("/sys/block/loop*/loop/backing_file")
+	if err != nil {
+		return nil, err
+	}
+	var d []Device
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(filepath.Dir(filepath.Dir(f)))
+		d = append(d, Device{Name: "/dev/" + name, File: strings.TrimSpace(string(b))})
+	}
+	return d, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loop
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := os.Stat("/dev/loop-control"); os.Getuid() != 0 || err != nil {
+		t.Skip("needs root and /dev/loop-control")
+	}
+	f, err := ioutil.TempFile("", "loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	want := bytes.Repeat([]byte("u-root"), 1024)
+	if _, err := f.Write(want); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dev, err := New(f.Name(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	i, err := GetInfo(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Flags&FlagsAutoclear == 0 {
+		t.Errorf("flags: got %#x, want %#x set", i.Flags, FlagsAutoclear)
+	}
+	got := make([]byte, len(want))
+	if _, err := dev.ReadAt(got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not read back what is in %s", dev.Name(), f.Name())
+	}
+}
+
+func TestAttachDetach(t *testing.T) {
+	if _, err := os.Stat("/dev/loop-control"); os.Getuid() != 0 || err != nil {
+		t.Skip("needs root and /dev/loop-control")
+	}
+	f, err := ioutil.TempFile("", "loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	name, err := FindDevice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := Attach(name, f.Name(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev.Close()
+	devs, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasDevice(devs, Device{name, f.Name()}) {
+		t.Errorf("List(): got %v, want %s: %s in it", devs, name, f.Name())
+	}
+	if err := Detach(name); err != nil {
+		t.Fatal(err)
+	}
+	if devs, _ := List(); hasDevice(devs, Device{name, f.Name()}) {
+		t.Errorf("%s is still attached after Detach", name)
+	}
+}
+
+func hasDevice(devs []Device, d Device) bool {
+	for _, v := range devs {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
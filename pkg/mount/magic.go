@@ -0,0 +1,99 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mount finds out the types, UUIDs and labels of file systems,
+// finds block devices by them, and parses mount options.
+package mount
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrUnknownFS is returned by FSType for a device it does not recognize.
+var ErrUnknownFS = errors.New("unknown file system type")
+
+// magics are the file systems found by their magic number alone.
+var magics = []struct {
+	fsType string
+	off    int64
+	magic  []byte
+}{
+	{"squashfs", 0, []byte("hsqs")},
+	{"xfs", 0, []byte("XFSB")},
+	{"btrfs", 0x10040, []byte("_BHRfS_M")},
+	{"iso9660", 0x8001, []byte("CD001")},
+}
+
+// Offsets and flags in the ext2, ext3 and ext4 superblock.
+const (
+	extSuperblock = 1024
+	extMagicOff   = extSuperblock + 0x38
+	extCompatOff  = extSuperblock + 0x5c
+	extMagic      = 0xef53
+
+	extCompatHasJournal   = 0x4
+	extIncompatExtents    = 0x40
+	extIncompat64Bit      = 0x80
+	extIncompatFlexBG     = 0x200
+	extROCompatHugeFile   = 0x8
+	extROCompatGDTCsum    = 0x10
+	extROCompatDirNlink   = 0x20
+	extROCompatExtraIsize = 0x40
+)
+
+// FSType returns the type of the file system on r, as mount wants it, by
+// looking at its superblock.
+func FSType(r io.ReaderAt) (string, error) {
+	for _, m := range magics {
+		b := make([]byte, len(m.magic))
+		if _, err := r.ReadAt(b, m.off); err == nil && bytes.Equal(b, m.magic) {
+			return m.fsType, nil
+		}
+	}
+	if t, ok := extType(r); ok {
+		return t, nil
+	}
+	if isFAT(r) {
+		return "vfat", nil
+	}
+	return "", ErrUnknownFS
+}
+
+// extType tells ext4 from ext3 and ext2 by their features.
+func extType(r io.ReaderAt) (string, bool) {
+	var magic uint16
+	if err := binary.Read(io.NewSectionReader(r, extMagicOff, 2), binary.LittleEndian, &magic); err != nil || magic != extMagic {
+		return "", false
+	}
+	var features struct {
+		Compat, Incompat, ROCompat uint32
+	}
+	if err := binary.Read(io.NewSectionReader(r, extCompatOff, 12), binary.LittleEndian, &features); err != nil {
+		return "", false
+	}
+	switch {
+	case features.Incompat&(extIncompatExtents|extIncompat64Bit|extIncompatFlexBG) != 0,
+		features.ROCompat&(extROCompatHugeFile|extROCompatGDTCsum|extROCompatDirNlink|extROCompatExtraIsize) != 0:
+		return "ext4", true
+	case features.Compat&extCompatHasJournal != 0:
+		return "ext3", true
+	}
+	return "ext2", true
+}
+
+// isFAT looks for a boot sector which names a FAT file system.
+func isFAT(r io.ReaderAt) bool {
+	b := make([]byte, 512)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return false
+	}
+	if b[510] != 0x55 || b[511] != 0xaa {
+		return false
+	}
+	// FAT12 and FAT16 name themselves at 54, FAT32 at 82.
+	return bytes.HasPrefix(b[54:], []byte("FAT")) || bytes.HasPrefix(b[82:], []byte("FAT32"))
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// image returns a 128KiB image with each of b at its offset.
+func image(b map[int][]byte) *bytes.Reader {
+	img := make([]byte, 128*1024)
+	for off, v := range b {
+		copy(img[off:], v)
+	}
+	return bytes.NewReader(img)
+}
+
+// ext returns the start of an ext superblock with the given features.
+func ext(compat, incompat, roCompat uint32) map[int][]byte {
+	f := make([]byte, 12)
+	binary.LittleEndian.PutUint32(f, compat)
+	binary.LittleEndian.PutUint32(f[4:], incompat)
+	binary.LittleEndian.PutUint32(f[8:], roCompat)
+	return map[int][]byte{
+		extMagicOff:  {0x53, 0xef},
+		extCompatOff: f,
+	}
+}
+
+func TestFSType(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		img  map[int][]byte
+		want string
+	}{
+		{"squashfs", map[int][]byte{0: []byte("hsqs")}, "squashfs"},
+		{"xfs", map[int][]byte{0: []byte("XFSB")}, "xfs"},
+		{"btrfs", map[int][]byte{0x10040: []byte("_BHRfS_M")}, "btrfs"},
+		{"iso9660", map[int][]byte{0x8001: []byte("CD001")}, "iso9660"},
+		{"ext2", ext(0, 0, 0), "ext2"},
+		{"ext3", ext(extCompatHasJournal, 0, 0), "ext3"},
+		{"ext4", ext(extCompatHasJournal, extIncompatExtents|extIncompatFlexBG, 0), "ext4"},
+		{"fat16", map[int][]byte{54: []byte("FAT16   "), 510: {0x55, 0xaa}}, "vfat"},
+		{"fat32", map[int][]byte{82: []byte("FAT32   "), 510: {0x55, 0xaa}}, "vfat"},
+		{"mbr", map[int][]byte{510: {0x55, 0xaa}}, ""},
+		{"zeroes", nil, ""},
+	} {
+		got, err := FSType(image(tt.img))
+		if tt.want == "" {
+			if err != ErrUnknownFS {
+				t.Errorf("%s: got %q, %v, want %v", tt.name, got, err, ErrUnknownFS)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q, nil", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestFSTypeShort(t *testing.T) {
+	if got, err := FSType(bytes.NewReader([]byte("hs"))); err != ErrUnknownFS {
+		t.Errorf("got %q, %v, want %v", got, err, ErrUnknownFS)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// optionFlags are the mount options which are flags to mount(2), rather
+// than data for the file system.
+var optionFlags = map[string]struct {
+	set   bool
+	flags uintptr
+}{
+	"ro":          {true, unix.MS_RDONLY},
+	"rw":          {false, unix.MS_RDONLY},
+	"nosuid":      {true, unix.MS_NOSUID},
+	"suid":        {false, unix.MS_NOSUID},
+	"nodev":       {true, unix.MS_NODEV},
+	"dev":         {false, unix.MS_NODEV},
+	"noexec":      {true, unix.MS_NOEXEC},
+	"exec":        {false, unix.MS_NOEXEC},
+	"sync":        {true, unix.MS_SYNCHRONOUS},
+	"async":       {false, unix.MS_SYNCHRONOUS},
+	"dirsync":     {true, unix.MS_DIRSYNC},
+	"remount":     {true, unix.MS_REMOUNT},
+	"bind":        {true, unix.MS_BIND},
+	"rbind":       {true, unix.MS_BIND | unix.MS_REC},
+	"noatime":     {true, unix.MS_NOATIME},
+	"atime":       {false, unix.MS_NOATIME},
+	"nodiratime":  {true, unix.MS_NODIRATIME},
+	"diratime":    {false, unix.MS_NODIRATIME},
+	"relatime":    {true, unix.MS_RELATIME},
+	"norelatime":  {false, unix.MS_RELATIME},
+	"strictatime": {true, unix.MS_STRICTATIME},
+	"mand":        {true, unix.MS_MANDLOCK},
+	"nomand":      {false, unix.MS_MANDLOCK},
+	"silent":      {true, unix.MS_SILENT},
+	"loud":        {false, unix.MS_SILENT},
+	"private":     {true, unix.MS_PRIVATE},
+	"rprivate":    {true, unix.MS_PRIVATE | unix.MS_REC},
+	"shared":      {true, unix.MS_SHARED},
+	"rshared":     {true, unix.MS_SHARED | unix.MS_REC},
+	"slave":       {true, unix.MS_SLAVE},
+	"rslave":      {true, unix.MS_SLAVE | unix.MS_REC},
+	"defaults":    {false, 0},
+	"loop":        {false, 0},
+
+	// These are for mount and /etc/fstab, not the file system.
+	"auto":    {false, 0},
+	"noauto":  {false, 0},
+	"nofail":  {false, 0},
+	"user":    {false, 0},
+	"users":   {false, 0},
+	"nouser":  {false, 0},
+	"owner":   {false, 0},
+	"group":   {false, 0},
+	"_netdev": {false, 0},
+}
+
+// ParseOptions splits the comma separated mount options o into the flags
+// for mount(2), starting from flags, and the data for the file system.
+// Options which are not flags, e.g. mode=755, go in the data. loop is left
+// out; it is up to the caller to set up a loop device. So are the options
+// which are only for /etc/fstab, such as noauto, nofail and x-NAME.
+func ParseOptions(o string, flags uintptr) (uintptr, string) {
+	var data []string
+	for _, opt := range strings.Split(o, ",") {
+		if opt == "" {
+			continue
+		}
+		f, ok := optionFlags[opt]
+		switch {
+		case strings.HasPrefix(opt, "x-"):
+		case !ok:
+			data = append(data, opt)
+		case f.set:
+			flags |= f.flags
+		default:
+			flags &^= f.flags
+		}
+	}
+	return flags, strings.Join(data, ",")
+}
+
+// FSTypes returns the types to try mounting dev as: fsType, unless it is ""
+// or auto; or the type its superblock says; or, if it says none we know,
+// those in /proc/filesystems.
+func FSTypes(dev, fsType string) ([]string, error) {
+	if fsType != "" && fsType != "auto" {
+		return []string{fsType}, nil
+	}
+	if fs, err := ProbeFile(dev); err == nil {
+		return []string{fs.Type}, nil
+	} else if err != ErrUnknownFS {
+		return nil, err
+	}
+	return FileSystems()
+}
+
+// FileSystems returns the types of the file systems the kernel has which
+// need a device, from /proc/filesystems.
+func FileSystems() ([]string, error) {
+	b, err := ioutil.ReadFile("/proc/filesystems")
+	if err != nil {
+		return nil, err
+	}
+	var fs []string
+	for _, l := range strings.Split(string(b), "\n") {
+		f := strings.Fields(l)
+		if len(f) == 1 {
+			fs = append(fs, f[0])
+		}
+	}
+	return fs, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseOptions(t *testing.T) {
+	for _, tt := range []struct {
+		opts  string
+		flags uintptr
+		data  string
+	}{
+		{"", 0, ""},
+		{"defaults", 0, ""},
+		{"ro,noexec", unix.MS_RDONLY | unix.MS_NOEXEC, ""},
+		{"ro,rw", 0, ""},
+		{"loop,ro", unix.MS_RDONLY, ""},
+		{"rbind", unix.MS_BIND | unix.MS_REC, ""},
+		{"nosuid,size=64m,mode=1777", unix.MS_NOSUID, "size=64m,mode=1777"},
+		{"noauto,nofail,x-systemd.automount,errors=remount-ro", 0, "errors=remount-ro"},
+	} {
+		flags, data := ParseOptions(tt.opts, 0)
+		if flags != tt.flags || data != tt.data {
+			t.Errorf("ParseOptions(%q): got %#x, %q, want %#x, %q", tt.opts, flags, data, tt.flags, tt.data)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FSInfo is what the superblock of a file system says of it.
+type FSInfo struct {
+	// Type is the type, as mount wants it, or "swap".
+	Type string
+	// UUID is in the form blkid prints, which for most file systems is
+	// 8-4-4-4-12 hex digits; or "".
+	UUID string
+	// Label is the volume label, or "".
+	Label string
+}
+
+// Where the UUID and label are in superblocks.
+const (
+	extUUIDOff  = extSuperblock + 0x68
+	extLabelOff = extSuperblock + 0x78
+
+	xfsUUIDOff  = 32
+	xfsLabelOff = 108
+
+	btrfsUUIDOff  = 0x10020
+	btrfsLabelOff = 0x1012b
+
+	isoLabelOff   = 0x8028
+	isoCreatedOff = 0x832d
+
+	// Swap has its signature at the end of the first page, which is 4KiB
+	// on most, but not all, machines.
+	swapUUIDOff  = 1024 + 12
+	swapLabelOff = 1024 + 28
+)
+
+// swapPageSizes are the page sizes swap signatures are looked for at the
+// end of.
+var swapPageSizes = []int64{4096, 8192, 16384, 65536}
+
+// Probe returns what the superblock of the file system on r says of it.
+func Probe(r io.ReaderAt) (*FSInfo, error) {
+	if isSwap(r) {
+		return &FSInfo{
+			Type:  "swap",
+			UUID:  uuid(read(r, swapUUIDOff, 16)),
+			Label: cString(read(r, swapLabelOff, 16)),
+		}, nil
+	}
+	t, err := FSType(r)
+	if err != nil {
+		return nil, err
+	}
+	fs := &FSInfo{Type: t}
+	switch t {
+	case "ext2", "ext3", "ext4":
+		fs.UUID = uuid(read(r, extUUIDOff, 16))
+		fs.Label = cString(read(r, extLabelOff, 16))
+	case "xfs":
+		fs.UUID = uuid(read(r, xfsUUIDOff, 16))
+		fs.Label = cString(read(r, xfsLabelOff, 12))
+	case "btrfs":
+		fs.UUID = uuid(read(r, btrfsUUIDOff, 16))
+		fs.Label = cString(read(r, btrfsLabelOff, 256))
+	case "iso9660":
+		fs.Label = strings.TrimRight(string(read(r, isoLabelOff, 32)), " \x00")
+		fs.UUID = isoUUID(read(r, isoCreatedOff, 16))
+	case "vfat":
+		// The extended boot record of FAT32 is further on than that of
+		// FAT12 and FAT16.
+		off := int64(39)
+		if bytes.HasPrefix(read(r, 82, 5), []byte("FAT32")) {
+			off = 67
+		}
+		if b := read(r, off, 4); b != nil {
+			id := binary.LittleEndian.Uint32(b)
+			fs.UUID = fmt.Sprintf("%04X-%04X", id>>16, id&0xffff)
+		}
+		if l := strings.TrimRight(string(read(r, off+4, 11)), " "); l != "NO NAME" {
+			fs.Label = l
+		}
+	}
+	return fs, nil
+}
+
+// read returns the n bytes at off in r, or nil.
+func read(r io.ReaderAt, off int64, n int) []byte {
+	b := make([]byte, n)
+	if _, err := r.ReadAt(b, off); err != nil {
+		return nil
+	}
+	return b
+}
+
+// isSwap looks for a Linux swap signature.
+func isSwap(r io.ReaderAt) bool {
+	for _, p := range swapPageSizes {
+		if s := string(read(r, p-10, 10)); s == "SWAPSPACE2" || s == "SWAP-SPACE" {
+			return true
+		}
+	}
+	return false
+}
+
+// uuid formats the 16 bytes b as a UUID, or returns "" if they are all
+// zero.
+func uuid(b []byte) string {
+	if len(b) != 16 || bytes.Count(b, []byte{0}) == 16 {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// isoUUID makes the UUID of an ISO 9660 file system, its creation time,
+// from its digits, YYYYMMDDHHMMSSCC, as YYYY-MM-DD-HH-MM-SS-CC.
+func isoUUID(b []byte) string {
+	if len(b) != 16 || strings.Trim(string(b), "0") == "" {
+		return ""
+	}
+	s := string(b)
+	return strings.Join([]string{s[:4], s[4:6], s[6:8], s[8:10], s[10:12], s[12:14], s[14:]}, "-")
+}
+
+// cString returns b up to the first NUL.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
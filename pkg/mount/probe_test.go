@@ -0,0 +1,80 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testUUID = []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+const testUUIDString = "12345678-9abc-def0-0123-456789abcdef"
+
+// with returns a with the entries of b added.
+func with(a, b map[int][]byte) map[int][]byte {
+	m := make(map[int][]byte)
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+func TestProbe(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		img  map[int][]byte
+		want *FSInfo
+	}{
+		{
+			"ext4",
+			with(ext(extCompatHasJournal, extIncompatExtents, 0), map[int][]byte{extUUIDOff: testUUID, extLabelOff: []byte("root\x00")}),
+			&FSInfo{Type: "ext4", UUID: testUUIDString, Label: "root"},
+		},
+		{"ext2 without a UUID", ext(0, 0, 0), &FSInfo{Type: "ext2"}},
+		{
+			"xfs",
+			map[int][]byte{0: []byte("XFSB"), xfsUUIDOff: testUUID, xfsLabelOff: []byte("data")},
+			&FSInfo{Type: "xfs", UUID: testUUIDString, Label: "data"},
+		},
+		{
+			"btrfs",
+			map[int][]byte{0x10040: []byte("_BHRfS_M"), btrfsUUIDOff: testUUID, btrfsLabelOff: []byte("pool")},
+			&FSInfo{Type: "btrfs", UUID: testUUIDString, Label: "pool"},
+		},
+		{
+			"iso9660",
+			map[int][]byte{0x8001: []byte("CD001"), isoLabelOff: []byte("UBUNTU    "), isoCreatedOff: []byte("2017101512304500")},
+			&FSInfo{Type: "iso9660", UUID: "2017-10-15-12-30-45-00", Label: "UBUNTU"},
+		},
+		{
+			"fat16",
+			map[int][]byte{39: {0xef, 0xbe, 0xad, 0xde}, 43: []byte("BOOT       "), 54: []byte("FAT16   "), 510: {0x55, 0xaa}},
+			&FSInfo{Type: "vfat", UUID: "DEAD-BEEF", Label: "BOOT"},
+		},
+		{
+			"fat32",
+			map[int][]byte{67: {0x78, 0x56, 0x34, 0x12}, 71: []byte("NO NAME    "), 82: []byte("FAT32   "), 510: {0x55, 0xaa}},
+			&FSInfo{Type: "vfat", UUID: "1234-5678"},
+		},
+		{
+			"swap",
+			map[int][]byte{4096 - 10: []byte("SWAPSPACE2"), swapUUIDOff: testUUID, swapLabelOff: []byte("swap0")},
+			&FSInfo{Type: "swap", UUID: testUUIDString, Label: "swap0"},
+		},
+		{"squashfs", map[int][]byte{0: []byte("hsqs")}, &FSInfo{Type: "squashfs"}},
+	} {
+		got, err := Probe(image(tt.img))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, %v, want %+v, nil", tt.name, got, err, tt.want)
+		}
+	}
+	if got, err := Probe(image(nil)); err != ErrUnknownFS {
+		t.Errorf("zeroes: got %+v, %v, want %v", got, err, ErrUnknownFS)
+	}
+}
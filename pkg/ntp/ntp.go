@@ -0,0 +1,142 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ntp asks NTP servers for the time, as a simple client (SNTP,
+// RFC 4330), and sets the clock.
+package ntp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// Port is the port of NTP servers.
+	Port = "123"
+
+	packetSize = 48
+	version    = 4
+	modeClient = 3
+	modeServer = 4
+	// epoch is the start of NTP time, 1900, in Unix time.
+	epoch = -2208988800
+)
+
+// A Response is what a server said.
+type Response struct {
+	// Server is the address of the server.
+	Server string
+	// Stratum is how far the server is from a reference clock: 1 if
+	// it has one, 2 if it gets the time from one which has, and so on.
+	Stratum int
+	// Offset is how far behind the server the clock is. Adding it to
+	// the time of the clock gives that of the server.
+	Offset time.Duration
+	// Delay is how long the request and response took on the network.
+	Delay time.Duration
+}
+
+// toNTP returns the 64 bit NTP timestamp of t: seconds since 1900 in the
+// upper 32 bits, and the fraction of a second in the lower.
+func toNTP(t time.Time) uint64 {
+	s := uint64(t.Unix() - epoch)
+	f := uint64(t.Nanosecond()) << 32 / 1e9
+	return s<<32 | f
+}
+
+// fromNTP returns the time of the NTP timestamp n.
+func fromNTP(n uint64) time.Time {
+	s := int64(n>>32) + epoch
+	ns := int64((n & (1<<32 - 1)) * 1e9 >> 32)
+	return time.Unix(s, ns)
+}
+
+// request returns a request sent at t.
+func request(t time.Time) []byte {
+	b := make([]byte, packetSize)
+	b[0] = version<<3 | modeClient
+	binary.BigEndian.PutUint64(b[40:], toNTP(t))
+	return b
+}
+
+// parse parses the response b to a request sent at sent, which came back
+// at recv.
+func parse(b []byte, sent, recv time.Time) (*Response, error) {
+	if len(b) < packetSize {
+		return nil, fmt.Errorf("response is %d bytes, want %d", len(b), packetSize)
+	}
+	if mode := b[0] & 7; mode != modeServer {
+		return nil, fmt.Errorf("response has mode %d, want %d", mode, modeServer)
+	}
+	if b[0]>>6 == 3 {
+		return nil, fmt.Errorf("server clock is not synchronized")
+	}
+	r := &Response{Stratum: int(b[1])}
+	// A stratum of 0 is a "kiss of death", e.g. to go away.
+	if r.Stratum == 0 {
+		return nil, fmt.Errorf("server refused: %q", b[12:16])
+	}
+	if binary.BigEndian.Uint64(b[24:]) != toNTP(sent) {
+		return nil, fmt.Errorf("response is not to our request")
+	}
+	// t1 and t4 are when the request was sent and the response came
+	// back, by our clock; t2 and t3 when the server got the request and
+	// sent the response, by its clock.
+	t1, t4 := sent, recv
+	t2 := fromNTP(binary.BigEndian.Uint64(b[32:]))
+	t3 := fromNTP(binary.BigEndian.Uint64(b[40:]))
+	r.Offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	r.Delay = t4.Sub(t1) - t3.Sub(t2)
+	return r, nil
+}
+
+// Query asks server, a host with an optional :port, for the time. It gives
+// up after timeout.
+func Query(server string, timeout time.Duration) (*Response, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, Port)
+	}
+	c, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	sent := time.Now()
+	if _, err := c.Write(request(sent)); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 512)
+	n, err := c.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parse(b[:n], sent, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", server, err)
+	}
+	r.Server = c.RemoteAddr().String()
+	return r, nil
+}
+
+// SetTime sets the clock to t.
+func SetTime(t time.Time) error {
+	ts := syscall.NsecToTimespec(t.UnixNano())
+	if _, _, err := syscall.Syscall(syscall.SYS_CLOCK_SETTIME, 0 /* CLOCK_REALTIME */, uintptr(unsafe.Pointer(&ts)), 0); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Adjust steps the clock by offset, e.g. the Offset of a Response.
+func Adjust(offset time.Duration) error {
+	return SetTime(time.Now().Add(offset))
+}
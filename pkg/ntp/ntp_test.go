@@ -0,0 +1,121 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	for _, tt := range []time.Time{
+		time.Unix(0, 0),
+		time.Unix(1500000000, 0),
+		time.Unix(1500000000, 500000000),
+		time.Date(2030, 1, 2, 3, 4, 5, 6000, time.UTC),
+	} {
+		got := fromNTP(toNTP(tt))
+		// The fraction is in units of 2^-32 s, less than a ns.
+		if d := got.Sub(tt); d < -time.Nanosecond || d > time.Nanosecond {
+			t.Errorf("fromNTP(toNTP(%v)): got %v, want it", tt, got)
+		}
+	}
+	if got, want := toNTP(time.Unix(0, 0))>>32, uint64(2208988800); got != want {
+		t.Errorf("toNTP(1970): got %d seconds, want %d", got, want)
+	}
+}
+
+// response returns the response of a server whose clock is off by offset
+// from ours, to a request sent at sent, which takes delay each way.
+func response(sent time.Time, offset, delay time.Duration) ([]byte, time.Time) {
+	b := make([]byte, packetSize)
+	b[0] = version<<3 | modeServer
+	b[1] = 2
+	binary.BigEndian.PutUint64(b[24:], toNTP(sent))
+	binary.BigEndian.PutUint64(b[32:], toNTP(sent.Add(delay+offset)))
+	binary.BigEndian.PutUint64(b[40:], toNTP(sent.Add(delay+offset+time.Millisecond)))
+	return b, sent.Add(2*delay + time.Millisecond)
+}
+
+func TestParse(t *testing.T) {
+	sent := time.Unix(1500000000, 0)
+	for _, tt := range []struct {
+		offset, delay time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{time.Hour, 50 * time.Millisecond},
+		{-3 * time.Second, time.Millisecond},
+	} {
+		b, recv := response(sent, tt.offset, tt.delay)
+		r, err := parse(b, sent, recv)
+		if err != nil {
+			t.Errorf("parse(offset %v, delay %v): %v", tt.offset, tt.delay, err)
+			continue
+		}
+		if d := r.Offset - tt.offset; d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("parse(offset %v, delay %v): got offset %v, want %v", tt.offset, tt.delay, r.Offset, tt.offset)
+		}
+		if d := r.Delay - 2*tt.delay; d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("parse(offset %v, delay %v): got delay %v, want %v", tt.offset, tt.delay, r.Delay, 2*tt.delay)
+		}
+		if r.Stratum != 2 {
+			t.Errorf("parse(offset %v, delay %v): got stratum %d, want 2", tt.offset, tt.delay, r.Stratum)
+		}
+	}
+}
+
+func TestParseBad(t *testing.T) {
+	sent := time.Unix(1500000000, 0)
+	good, recv := response(sent, 0, 0)
+	for _, tt := range []struct {
+		name string
+		mod  func(b []byte) []byte
+	}{
+		{"short", func(b []byte) []byte { return b[:20] }},
+		{"client mode", func(b []byte) []byte { b[0] = version<<3 | modeClient; return b }},
+		{"unsynchronized", func(b []byte) []byte { b[0] |= 3 << 6; return b }},
+		{"kiss of death", func(b []byte) []byte { b[1] = 0; copy(b[12:], "DENY"); return b }},
+		{"wrong origin", func(b []byte) []byte { b[31]++; return b }},
+	} {
+		b := tt.mod(append([]byte(nil), good...))
+		if _, err := parse(b, sent, recv); err == nil {
+			t.Errorf("parse(%v): got nil, want error", tt.name)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	const offset = time.Minute
+	go func() {
+		b := make([]byte, packetSize)
+		n, addr, err := c.ReadFrom(b)
+		if err != nil || n != packetSize {
+			return
+		}
+		sent := binary.BigEndian.Uint64(b[40:])
+		r, _ := response(fromNTP(sent), offset, 0)
+		// The origin has to be exactly what was sent.
+		binary.BigEndian.PutUint64(r[24:], sent)
+		c.WriteTo(r, addr)
+	}()
+
+	r, err := Query(c.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if r.Offset < offset-time.Second || r.Offset > offset+time.Second {
+		t.Errorf("Query: got offset %v, want about %v", r.Offset, offset)
+	}
+	if r.Server != c.LocalAddr().String() {
+		t.Errorf("Query: got server %v, want %v", r.Server, c.LocalAddr())
+	}
+}
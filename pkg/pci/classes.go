@@ -0,0 +1,136 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pci
+
+// classNames are the names of the PCI classes and subclasses, by their hex
+// codes, from the C section of pci.ids.
+var classNames = map[string]string{
+	"00":   "Unclassified device",
+	"0000": "Non-VGA unclassified device",
+	"0001": "VGA compatible unclassified device",
+	"01":   "Mass storage controller",
+	"0100": "SCSI storage controller",
+	"0101": "IDE interface",
+	"0102": "Floppy disk controller",
+	"0103": "IPI bus controller",
+	"0104": "RAID bus controller",
+	"0105": "ATA controller",
+	"0106": "SATA controller",
+	"0107": "Serial Attached SCSI controller",
+	"0108": "Non-Volatile memory controller",
+	"02":   "Network controller",
+	"0200": "Ethernet controller",
+	"0201": "Token ring network controller",
+	"0202": "FDDI network controller",
+	"0203": "ATM network controller",
+	"0204": "ISDN controller",
+	"0207": "Infiniband controller",
+	"0208": "Fabric controller",
+	"03":   "Display controller",
+	"0300": "VGA compatible controller",
+	"0301": "XGA compatible controller",
+	"0302": "3D controller",
+	"04":   "Multimedia controller",
+	"0400": "Multimedia video controller",
+	"0401": "Multimedia audio controller",
+	"0402": "Computer telephony device",
+	"0403": "Audio device",
+	"05":   "Memory controller",
+	"0500": "RAM memory",
+	"0501": "FLASH memory",
+	"06":   "Bridge",
+	"0600": "Host bridge",
+	"0601": "ISA bridge",
+	"0602": "EISA bridge",
+	"0603": "MicroChannel bridge",
+	"0604": "PCI bridge",
+	"0605": "PCMCIA bridge",
+	"0606": "NuBus bridge",
+	"0607": "CardBus bridge",
+	"0608": "RACEway bridge",
+	"0609": "Semi-transparent PCI-to-PCI bridge",
+	"060a": "InfiniBand to PCI host bridge",
+	"07":   "Communication controller",
+	"0700": "Serial controller",
+	"0701": "Parallel controller",
+	"0702": "Multiport serial controller",
+	"0703": "Modem",
+	"0704": "GPIB controller",
+	"0705": "Smart Card controller",
+	"08":   "Generic system peripheral",
+	"0800": "PIC",
+	"0801": "DMA controller",
+	"0802": "Timer",
+	"0803": "RTC",
+	"0804": "PCI Hot-plug controller",
+	"0805": "SD Host controller",
+	"0806": "IOMMU",
+	"0880": "System peripheral",
+	"09":   "Input device controller",
+	"0900": "Keyboard controller",
+	"0901": "Digitizer Pen",
+	"0902": "Mouse controller",
+	"0903": "Scanner controller",
+	"0904": "Gameport controller",
+	"0a":   "Docking station",
+	"0a00": "Generic Docking Station",
+	"0b":   "Processor",
+	"0b00": "386",
+	"0b01": "486",
+	"0b02": "Pentium",
+	"0b10": "Alpha",
+	"0b20": "Power PC",
+	"0b30": "MIPS",
+	"0b40": "Co-processor",
+	"0c":   "Serial bus controller",
+	"0c00": "FireWire (IEEE 1394)",
+	"0c01": "ACCESS Bus",
+	"0c02": "SSA",
+	"0c03": "USB controller",
+	"0c04": "Fibre Channel",
+	"0c05": "SMBus",
+	"0c06": "InfiniBand",
+	"0c07": "IPMI Interface",
+	"0c08": "SERCOS interface",
+	"0c09": "CANBUS",
+	"0d":   "Wireless controller",
+	"0d00": "IRDA controller",
+	"0d01": "Consumer IR controller",
+	"0d10": "RF controller",
+	"0d11": "Bluetooth",
+	"0d12": "Broadband",
+	"0d20": "802.1a controller",
+	"0d21": "802.1b controller",
+	"0e":   "Intelligent controller",
+	"0e00": "I2O",
+	"0f":   "Satellite communications controller",
+	"10":   "Encryption controller",
+	"1000": "Network and computing encryption device",
+	"1010": "Entertainment encryption device",
+	"11":   "Signal processing controller",
+	"1100": "DPIO module",
+	"1101": "Performance counters",
+	"1110": "Communication synchronizer",
+	"1120": "Signal processing management",
+	"12":   "Processing accelerators",
+	"13":   "Non-Essential Instrumentation",
+	"40":   "Coprocessor",
+	"ff":   "Unassigned class",
+}
+
+// ClassName returns the name of the subclass, or else the class, of the
+// hex class code class, e.g. Ethernet controller for 020000.
+func ClassName(class string) string {
+	if len(class) < 4 {
+		return "Class " + class
+	}
+	if n, ok := classNames[class[:4]]; ok {
+		return n
+	}
+	if n, ok := classNames[class[:2]]; ok {
+		return n
+	}
+	return "Class " + class[:4]
+}
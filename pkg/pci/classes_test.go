@@ -0,0 +1,25 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pci
+
+import "testing"
+
+func TestClassName(t *testing.T) {
+	for _, tt := range []struct {
+		class, want string
+	}{
+		{"020000", "Ethernet controller"},
+		{"010802", "Non-Volatile memory controller"},
+		{"0c0330", "USB controller"},
+		{"018000", "Mass storage controller"},
+		{"ff0000", "Unassigned class"},
+		{"770000", "Class 7700"},
+		{"02", "Class 02"},
+	} {
+		if got := ClassName(tt.class); got != tt.want {
+			t.Errorf("ClassName(%q): got %q, want %q", tt.class, got, tt.want)
+		}
+	}
+}
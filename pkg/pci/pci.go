@@ -6,16 +6,24 @@ package pci
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 )
 
 // PCI is a PCI device. We will fill this in as we add options.
-// For now it just holds two uint16 per the PCI spec.
+// The IDs are in hex, as in sysfs, without the 0x.
 type PCI struct {
 	Addr       string
 	Vendor     string `pci:"vendor"`
 	Device     string `pci:"device"`
+	Class      string `pci:"class"`
+	Revision   string `pci:"revision"`
 	VendorName string
 	DeviceName string
+	// FullPath is its directory in sysfs.
+	FullPath string
+	// Driver is the name of the driver bound to it, or "".
+	Driver string
 }
 
 // ToString concatenates PCI address, Vendor, and Device to make a useful
@@ -33,3 +41,9 @@ func (p PCI) ToString(n bool, ids map[string]Vendor) string {
 func (p PCI) String() string {
 	return p.ToString(true, nil)
 }
+
+// ReadConfig returns the configuration space of p: the first 64 bytes,
+// or, for root, 256, or 4096 for PCI Express.
+func (p PCI) ReadConfig() ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(p.FullPath, "config"))
+}
@@ -8,6 +8,7 @@ package pci
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 )
@@ -45,6 +46,10 @@ func (bus *bus) Read() (Devices, error) {
 			return nil, err
 		}
 		p.Addr = filepath.Base(d)
+		p.FullPath = d
+		if l, err := os.Readlink(filepath.Join(d, "driver")); err == nil {
+			p.Driver = filepath.Base(l)
+		}
 		devices[i] = p
 	}
 	return devices, nil
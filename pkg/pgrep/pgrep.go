@@ -0,0 +1,144 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgrep finds processes by name, for the pgrep and pkill commands.
+//
+// The name of a process is what is in /proc/PID/comm, which the kernel
+// cuts to 15 bytes. Its full command line is /proc/PID/cmdline, with
+// spaces between the arguments.
+package pgrep
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const proc = "/proc"
+
+// Options says how processes are matched.
+type Options struct {
+	// Full matches the whole command line, rather than the name.
+	Full bool
+	// Exact matches only if all of the name or command line matches.
+	Exact bool
+	// IgnoreCase ignores the case of letters.
+	IgnoreCase bool
+}
+
+// RegisterFlags adds the flags of the commands to f.
+func (o *Options) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&o.Full, "f", o.Full, "match the full command line")
+	f.BoolVar(&o.Exact, "x", o.Exact, "match all of the name or command line")
+	f.BoolVar(&o.IgnoreCase, "i", o.IgnoreCase, "ignore case")
+}
+
+// A Process is one which matched.
+type Process struct {
+	PID     int
+	Name    string
+	Cmdline string
+}
+
+// Find returns the processes which match the regular expression pattern,
+// in order of their PIDs. The calling process is never one of them.
+func (o *Options) Find(pattern string) ([]Process, error) {
+	if o.Exact {
+		pattern = "^(?:" + pattern + ")$"
+	}
+	if o.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := filepath.Glob(filepath.Join(proc, "[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	var found []Process
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(d))
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		// Processes may exit while we look at them, so errors are
+		// ignored.
+		name, err := ioutil.ReadFile(filepath.Join(d, "comm"))
+		if err != nil {
+			continue
+		}
+		cmdline, err := ioutil.ReadFile(filepath.Join(d, "cmdline"))
+		if err != nil {
+			continue
+		}
+		p := Process{
+			PID:     pid,
+			Name:    strings.TrimSuffix(string(name), "\n"),
+			Cmdline: strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1)),
+		}
+		s := p.Name
+		// Kernel threads have no command line.
+		if o.Full && p.Cmdline != "" {
+			s = p.Cmdline
+		}
+		if re.MatchString(s) {
+			found = append(found, p)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].PID < found[j].PID })
+	return found, nil
+}
+
+var signals = map[string]syscall.Signal{
+	"HUP":    syscall.SIGHUP,
+	"INT":    syscall.SIGINT,
+	"QUIT":   syscall.SIGQUIT,
+	"ILL":    syscall.SIGILL,
+	"TRAP":   syscall.SIGTRAP,
+	"ABRT":   syscall.SIGABRT,
+	"BUS":    syscall.SIGBUS,
+	"FPE":    syscall.SIGFPE,
+	"KILL":   syscall.SIGKILL,
+	"USR1":   syscall.SIGUSR1,
+	"SEGV":   syscall.SIGSEGV,
+	"USR2":   syscall.SIGUSR2,
+	"PIPE":   syscall.SIGPIPE,
+	"ALRM":   syscall.SIGALRM,
+	"TERM":   syscall.SIGTERM,
+	"CHLD":   syscall.SIGCHLD,
+	"CONT":   syscall.SIGCONT,
+	"STOP":   syscall.SIGSTOP,
+	"TSTP":   syscall.SIGTSTP,
+	"TTIN":   syscall.SIGTTIN,
+	"TTOU":   syscall.SIGTTOU,
+	"URG":    syscall.SIGURG,
+	"XCPU":   syscall.SIGXCPU,
+	"XFSZ":   syscall.SIGXFSZ,
+	"VTALRM": syscall.SIGVTALRM,
+	"PROF":   syscall.SIGPROF,
+	"WINCH":  syscall.SIGWINCH,
+	"IO":     syscall.SIGIO,
+	"PWR":    syscall.SIGPWR,
+	"SYS":    syscall.SIGSYS,
+}
+
+// Signal returns the signal named s, e.g. TERM, SIGTERM, term or 15.
+func Signal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 64 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signals[strings.TrimPrefix(strings.ToUpper(s), "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%v is not a valid signal", s)
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgrep
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	c := exec.Command("sleep", "17.25")
+	if err := c.Start(); err != nil {
+		t.Skipf("can not start sleep: %v", err)
+	}
+	defer c.Wait()
+	defer c.Process.Kill()
+	// Wait for the exec, so /proc shows sleep rather than the test.
+	time.Sleep(100 * time.Millisecond)
+
+	for _, tt := range []struct {
+		o       Options
+		pattern string
+		found   bool
+	}{
+		{Options{}, "sle+p", true},
+		{Options{}, "17.25", false},
+		{Options{Full: true}, "sleep 17\\.25", true},
+		{Options{Exact: true}, "slee", false},
+		{Options{Exact: true}, "sleep", true},
+		{Options{Full: true, Exact: true}, "sleep 17.25", true},
+		{Options{}, "SLEEP", false},
+		{Options{IgnoreCase: true}, "SLEEP", true},
+	} {
+		procs, err := tt.o.Find(tt.pattern)
+		if err != nil {
+			t.Errorf("%+v.Find(%q): %v", tt.o, tt.pattern, err)
+			continue
+		}
+		found := false
+		for _, p := range procs {
+			if p.PID == c.Process.Pid {
+				found = true
+				if p.Name != "sleep" || p.Cmdline != "sleep 17.25" {
+					t.Errorf("got name %q, command line %q, want %q, %q", p.Name, p.Cmdline, "sleep", "sleep 17.25")
+				}
+			}
+		}
+		if found != tt.found {
+			t.Errorf("%+v.Find(%q): got found %v, want %v", tt.o, tt.pattern, found, tt.found)
+		}
+	}
+	if _, err := (&Options{}).Find("("); err == nil {
+		t.Errorf("Find(%q): got nil, want error", "(")
+	}
+}
+
+func TestSignal(t *testing.T) {
+	for s, want := range map[string]syscall.Signal{
+		"9":       syscall.SIGKILL,
+		"HUP":     syscall.SIGHUP,
+		"SIGTERM": syscall.SIGTERM,
+		"usr1":    syscall.SIGUSR1,
+		"sigCont": syscall.SIGCONT,
+	} {
+		if got, err := Signal(s); err != nil || got != want {
+			t.Errorf("Signal(%q): got %v, %v, want %v, nil", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "FOO", "-1", "65"} {
+		if _, err := Signal(s); err == nil {
+			t.Errorf("Signal(%q): got nil, want error", s)
+		}
+	}
+}
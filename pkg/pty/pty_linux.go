@@ -32,18 +32,33 @@ func New(cmd string, args ...string) (*Pty, error) {
 	if err != nil {
 		return nil, err
 	}
-	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	ptm, pts, err := Open()
 	if err != nil {
 		return nil, err
 	}
+	c := exec.Command(cmd, args...)
+	c.Stdin, c.Stdout, c.Stderr = pts, pts, pts
+	c.SysProcAttr = &syscall.SysProcAttr{Setctty: true, Setsid: true}
+	return &Pty{Ptm: ptm, Pts: pts, Sname: pts.Name(), Kid: -1, C: c, TTY: tty, Restorer: restorer}, nil
+}
+
+// Open opens a new pty, and returns its master and slave. Unlike New, it
+// does not need a terminal, so that servers can give their clients ptys.
+func Open() (*os.File, *os.File, error) {
+	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	if err := ptsunlock(ptm); err != nil {
-		return nil, err
+		ptm.Close()
+		return nil, nil, err
 	}
 
 	sname, err := ptsname(ptm)
 	if err != nil {
-		return nil, err
+		ptm.Close()
+		return nil, nil, err
 	}
 
 	// It can take a non-zero time for a pts to appear, it seems.
@@ -56,12 +71,10 @@ func New(cmd string, args ...string) (*Pty, error) {
 	}
 	pts, err := os.OpenFile(sname, os.O_RDWR|syscall.O_NOCTTY, 0)
 	if err != nil {
-		return nil, err
+		ptm.Close()
+		return nil, nil, err
 	}
-	c := exec.Command(cmd, args...)
-	c.Stdin, c.Stdout, c.Stderr = pts, pts, pts
-	c.SysProcAttr = &syscall.SysProcAttr{Setctty: true, Setsid: true}
-	return &Pty{Ptm: ptm, Pts: pts, Sname: sname, Kid: -1, C: c, TTY: tty, Restorer: restorer}, nil
+	return ptm, pts, nil
 }
 
 func ptsname(f *os.File) (string, error) {
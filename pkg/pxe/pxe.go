@@ -0,0 +1,198 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pxe parses pxelinux configuration files and iPXE scripts, to find
+// the kernel, initrd and command line to boot.
+package pxe
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// A Label is something to boot: a kernel, with an optional initrd and
+// command line. Kernel and Initrd are as written in the configuration,
+// so they may be relative to where it came from.
+type Label struct {
+	Name    string
+	Kernel  string
+	Initrd  string
+	Cmdline string
+}
+
+// Config is a pxelinux configuration.
+type Config struct {
+	// Default is the name of the label to boot.
+	Default string
+	// Labels are the labels, in the order they were defined.
+	Labels []*Label
+}
+
+// ParseConfig parses a pxelinux configuration. Only the keywords needed to
+// boot are understood: DEFAULT, LABEL, KERNEL, LINUX, INITRD, APPEND and
+// MENU DEFAULT. Others are ignored.
+func ParseConfig(r io.Reader) (*Config, error) {
+	c := &Config{}
+	var l *Label
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		arg := strings.Join(f[1:], " ")
+		switch strings.ToLower(f[0]) {
+		case "default":
+			c.Default = arg
+		case "label":
+			l = &Label{Name: arg}
+			c.Labels = append(c.Labels, l)
+		case "menu":
+			if l != nil && len(f) > 1 && strings.ToLower(f[1]) == "default" {
+				c.Default = l.Name
+			}
+		case "kernel", "linux", "initrd", "append":
+			if l == nil {
+				return nil, fmt.Errorf("%s outside of a LABEL", f[0])
+			}
+			switch strings.ToLower(f[0]) {
+			case "kernel", "linux":
+				l.Kernel = arg
+			case "initrd":
+				l.Initrd = arg
+			case "append":
+				l.Cmdline, l.Initrd = parseAppend(arg, l.Initrd)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseAppend takes initrd= out of the arguments of APPEND. "APPEND -"
+// means no arguments.
+func parseAppend(arg, initrd string) (string, string) {
+	if arg == "-" {
+		return "", initrd
+	}
+	var cmdline []string
+	for _, a := range strings.Fields(arg) {
+		if strings.HasPrefix(a, "initrd=") {
+			// pxelinux allows a list, but Linux takes one.
+			initrd = strings.SplitN(a[len("initrd="):], ",", 2)[0]
+			continue
+		}
+		cmdline = append(cmdline, a)
+	}
+	return strings.Join(cmdline, " "), initrd
+}
+
+// Boot returns the label to boot: the default one, or the first if there
+// is no default. A DEFAULT which names no label is a kernel and its
+// command line.
+func (c *Config) Boot() (*Label, error) {
+	if c.Default == "" {
+		if len(c.Labels) == 0 {
+			return nil, fmt.Errorf("no labels and no default")
+		}
+		return c.Labels[0], nil
+	}
+	for _, l := range c.Labels {
+		if l.Name == c.Default {
+			return l, nil
+		}
+	}
+	f := strings.Fields(c.Default)
+	l := &Label{Name: f[0], Kernel: f[0]}
+	l.Cmdline, l.Initrd = parseAppend(strings.Join(f[1:], " "), "")
+	return l, nil
+}
+
+// ConfigFiles returns the names of the pxelinux configuration files to try,
+// in order, for a client with the hardware address mac and the IPv4 address
+// ip: pxelinux.cfg/01-MAC, pxelinux.cfg/ followed by the address in hex,
+// then with one less hex digit at a time, and pxelinux.cfg/default.
+func ConfigFiles(mac net.HardwareAddr, ip net.IP) []string {
+	var f []string
+	if len(mac) > 0 {
+		f = append(f, "pxelinux.cfg/01-"+strings.Replace(mac.String(), ":", "-", -1))
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		h := fmt.Sprintf("%02X%02X%02X%02X", ip4[0], ip4[1], ip4[2], ip4[3])
+		for i := len(h); i > 0; i-- {
+			f = append(f, "pxelinux.cfg/"+h[:i])
+		}
+	}
+	return append(f, "pxelinux.cfg/default")
+}
+
+// ipxeValueOpts are the options of iPXE image commands which take a value
+// as the next argument.
+var ipxeValueOpts = map[string]bool{
+	"-n":        true,
+	"--name":    true,
+	"-t":        true,
+	"--timeout": true,
+}
+
+// IsIPXE returns whether b is an iPXE script.
+func IsIPXE(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("#!ipxe"))
+}
+
+// ParseIPXE parses the kernel, initrd and imgargs commands of an iPXE script,
+// up to the boot command. Other commands are ignored.
+func ParseIPXE(r io.Reader) (*Label, error) {
+	l := &Label{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		// Options, e.g. --name, come before the image.
+		args := f[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			if ipxeValueOpts[args[0]] && len(args) > 1 {
+				args = args[1:]
+			}
+			args = args[1:]
+		}
+		switch f[0] {
+		case "kernel", "chain":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s without an image", f[0])
+			}
+			l.Kernel = args[0]
+			l.Cmdline, l.Initrd = parseAppend(strings.Join(args[1:], " "), l.Initrd)
+		case "initrd":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("initrd without an image")
+			}
+			l.Initrd = args[0]
+		case "imgargs":
+			if len(args) > 0 {
+				l.Cmdline, l.Initrd = parseAppend(strings.Join(args[1:], " "), l.Initrd)
+			}
+		case "boot":
+			if l.Kernel == "" {
+				return nil, fmt.Errorf("boot without a kernel")
+			}
+			return l, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if l.Kernel == "" {
+		return nil, fmt.Errorf("no kernel in iPXE script")
+	}
+	return l, nil
+}
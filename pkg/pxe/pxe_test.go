@@ -0,0 +1,133 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pxe
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config string
+		want   Label
+	}{
+		{
+			name: "default label",
+			config: `# a comment
+DEFAULT linux
+PROMPT 0
+LABEL local
+	LOCALBOOT 0
+LABEL linux
+	KERNEL vmlinuz
+	APPEND console=ttyS0 initrd=initrd.img,extra.img quiet
+`,
+			want: Label{Name: "linux", Kernel: "vmlinuz", Initrd: "initrd.img", Cmdline: "console=ttyS0 quiet"},
+		},
+		{
+			name: "menu default",
+			config: `label one
+	linux bzImage-1
+label two
+	menu default
+	linux bzImage-2
+	initrd /boot/initramfs.cpio
+	append -
+`,
+			want: Label{Name: "two", Kernel: "bzImage-2", Initrd: "/boot/initramfs.cpio"},
+		},
+		{
+			name: "first label",
+			config: `LABEL a
+KERNEL a.img
+LABEL b
+KERNEL b.img
+`,
+			want: Label{Name: "a", Kernel: "a.img"},
+		},
+		{
+			name:   "default kernel",
+			config: "DEFAULT vmlinuz root=/dev/sda1 initrd=i.cpio\n",
+			want:   Label{Name: "vmlinuz", Kernel: "vmlinuz", Initrd: "i.cpio", Cmdline: "root=/dev/sda1"},
+		},
+	} {
+		c, err := ParseConfig(strings.NewReader(tt.config))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		l, err := c.Boot()
+		if err != nil {
+			t.Errorf("%s: Boot: %v", tt.name, err)
+			continue
+		}
+		if *l != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *l, tt.want)
+		}
+	}
+}
+
+func TestParseConfigBad(t *testing.T) {
+	if _, err := ParseConfig(strings.NewReader("KERNEL vmlinuz\n")); err == nil {
+		t.Errorf("KERNEL outside of a LABEL: got nil, want error")
+	}
+	c, err := ParseConfig(strings.NewReader("PROMPT 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Boot(); err == nil {
+		t.Errorf("Boot with no labels: got nil, want error")
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	mac, _ := net.ParseMAC("88:99:aa:bb:cc:dd")
+	got := ConfigFiles(mac, net.IPv4(192, 0, 2, 91))
+	want := []string{
+		"pxelinux.cfg/01-88-99-aa-bb-cc-dd",
+		"pxelinux.cfg/C000025B",
+		"pxelinux.cfg/C000025",
+		"pxelinux.cfg/C00002",
+		"pxelinux.cfg/C0000",
+		"pxelinux.cfg/C000",
+		"pxelinux.cfg/C00",
+		"pxelinux.cfg/C0",
+		"pxelinux.cfg/C",
+		"pxelinux.cfg/default",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConfigFiles: got %v, want %v", got, want)
+	}
+}
+
+func TestParseIPXE(t *testing.T) {
+	script := `#!ipxe
+dhcp
+kernel --name vmlinuz http://192.0.2.1/vmlinuz console=ttyS0
+initrd http://192.0.2.1/initrd.cpio
+imgargs vmlinuz console=tty0 quiet
+boot
+kernel http://192.0.2.1/other
+`
+	if !IsIPXE([]byte(script)) {
+		t.Errorf("IsIPXE: got false, want true")
+	}
+	l, err := ParseIPXE(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Label{Kernel: "http://192.0.2.1/vmlinuz", Initrd: "http://192.0.2.1/initrd.cpio", Cmdline: "console=tty0 quiet"}
+	if *l != want {
+		t.Errorf("ParseIPXE: got %+v, want %+v", *l, want)
+	}
+
+	if _, err := ParseIPXE(strings.NewReader("#!ipxe\nboot\n")); err == nil {
+		t.Errorf("ParseIPXE without a kernel: got nil, want error")
+	}
+}
@@ -38,15 +38,21 @@ var DevCPIO = []cpio.Record{
 	{Info: cpio.Info{Name: "usr/lib", Mode: d | 0755}},
 	{Info: cpio.Info{Name: "lib64", Mode: d | 0755}},
 	{Info: cpio.Info{Name: "bin", Mode: d | 0755}},
+	// The kernel opens /dev/console for init's stdin, stdout and stderr
+	// before init runs, so it has to be here. init makes the other device
+	// files, with a devtmpfs or pkg/devfs.
 	{Info: cpio.Info{Name: "dev/console", Mode: c | 0600, Rmajor: 5, Rminor: 1}},
-	{Info: cpio.Info{Name: "dev/tty", Mode: c | 0666, Rmajor: 5, Rminor: 0}},
-	{Info: cpio.Info{Name: "dev/null", Mode: c | 0666, Rmajor: 1, Rminor: 3}},
-	{Info: cpio.Info{Name: "dev/port", Mode: c | 0640, Rmajor: 1, Rminor: 4}},
-	{Info: cpio.Info{Name: "dev/urandom", Mode: c | 0666, Rmajor: 1, Rminor: 9}},
 	{Info: cpio.Info{Name: "etc/resolv.conf", Mode: f | 0644, FileSize: uint64(len(nameserver))}, ReadCloser: cpio.NewBytesReadCloser([]byte(nameserver))},
 	{Info: cpio.Info{Name: "etc/localtime", Mode: f | 0644, FileSize: uint64(len(gmt0))}, ReadCloser: cpio.NewBytesReadCloser([]byte(gmt0))},
 }
 
+// devContents are the contents of the files in DevCPIO. A record can only be
+// read once, so each Initramfs gets new readers of them.
+var devContents = map[string]string{
+	"etc/resolv.conf": nameserver,
+	"etc/localtime":   gmt0,
+}
+
 type Initramfs struct {
 	cpio.Writer
 	files map[string]struct{}
@@ -54,7 +60,13 @@ type Initramfs struct {
 
 func NewInitramfs(w cpio.Writer) (*Initramfs, error) {
 	// Write devtmpfs records.
-	dcpio := DevCPIO[:]
+	dcpio := make([]cpio.Record, len(DevCPIO))
+	copy(dcpio, DevCPIO)
+	for i, r := range dcpio {
+		if c, ok := devContents[r.Name]; ok {
+			dcpio[i].ReadCloser = cpio.NewBytesReadCloser([]byte(c))
+		}
+	}
 	cpio.MakeAllReproducible(dcpio)
 	if err := w.WriteRecords(dcpio); err != nil {
 		return nil, err
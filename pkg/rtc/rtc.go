@@ -0,0 +1,73 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rtc reads and sets the battery backed real time clock, the RTC,
+// of a machine.
+//
+// The RTC only has the date and time, to the second, with no time zone.
+// Linux, and most of the rest of the world, keeps it in UTC; Windows keeps
+// it in local time, so a machine which also boots Windows may have it so.
+// /etc/adjtime says which, as hwclock writes it.
+package rtc
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Adjtime is where Local looks for which time the RTC is in.
+var Adjtime = "/etc/adjtime"
+
+// rtcTime is struct rtc_time, which is struct tm: the month is from 0, and
+// the year from 1900.
+type rtcTime struct {
+	Sec, Min, Hour, Mday, Mon, Year, Wday, Yday, Isdst int32
+}
+
+// toTime returns the time of r, which is in loc.
+func toTime(r rtcTime, loc *time.Location) time.Time {
+	return time.Date(int(r.Year)+1900, time.Month(r.Mon+1), int(r.Mday), int(r.Hour), int(r.Min), int(r.Sec), 0, loc)
+}
+
+// fromTime returns t in loc as an rtcTime.
+func fromTime(t time.Time, loc *time.Location) rtcTime {
+	t = t.In(loc)
+	return rtcTime{
+		Sec:   int32(t.Second()),
+		Min:   int32(t.Minute()),
+		Hour:  int32(t.Hour()),
+		Mday:  int32(t.Day()),
+		Mon:   int32(t.Month() - 1),
+		Year:  int32(t.Year() - 1900),
+		Wday:  int32(t.Weekday()),
+		Yday:  int32(t.YearDay() - 1),
+		Isdst: -1,
+	}
+}
+
+// ParseAdjtime returns whether the adjtime file r says the RTC is in local
+// time: its third line is LOCAL, rather than UTC.
+func ParseAdjtime(r io.Reader) bool {
+	s := bufio.NewScanner(r)
+	for i := 0; s.Scan(); i++ {
+		if i == 2 {
+			return strings.TrimSpace(s.Text()) == "LOCAL"
+		}
+	}
+	return false
+}
+
+// Local returns whether Adjtime says the RTC is in local time. If there is
+// no Adjtime, it is in UTC.
+func Local() bool {
+	f, err := os.Open(Adjtime)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return ParseAdjtime(f)
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rtc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Devices are the RTC devices Open tries, in order; /dev/rtc is a link to
+// one of the others on most systems, but mdev does not make it.
+var Devices = []string{"/dev/rtc", "/dev/rtc0"}
+
+// ErrNoRTC is returned by Open when none of Devices is there.
+var ErrNoRTC = errors.New("no RTC device")
+
+// The ioctls, of 'p', with a struct rtc_time, which is 9 ints.
+const (
+	// _IOR('p', 0x09, struct rtc_time)
+	rdTime = 0x80247009
+	// _IOW('p', 0x0a, struct rtc_time)
+	setTime = 0x4024700a
+)
+
+// An RTC is an open RTC device.
+type RTC struct {
+	f *os.File
+}
+
+// Open opens the RTC device name, or if name is "", the first of Devices
+// there is.
+func Open(name string) (*RTC, error) {
+	if name != "" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		return &RTC{f}, nil
+	}
+	for _, d := range Devices {
+		f, err := os.Open(d)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &RTC{f}, nil
+	}
+	return nil, ErrNoRTC
+}
+
+func (r *RTC) ioctl(req uintptr, t *rtcTime) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, r.f.Fd(), req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return fmt.Errorf("%v: %v", r.f.Name(), errno)
+	}
+	return nil
+}
+
+// Read reads the time of the RTC, which is in loc: time.UTC, or
+// time.Local if Local says so.
+func (r *RTC) Read(loc *time.Location) (time.Time, error) {
+	var t rtcTime
+	if err := r.ioctl(rdTime, &t); err != nil {
+		return time.Time{}, err
+	}
+	return toTime(t, loc), nil
+}
+
+// Set sets the RTC to t, in loc. The RTC only has whole seconds, so the
+// fraction of t is dropped.
+func (r *RTC) Set(t time.Time, loc *time.Location) error {
+	rt := fromTime(t, loc)
+	return r.ioctl(setTime, &rt)
+}
+
+// Close closes the device.
+func (r *RTC) Close() error {
+	return r.f.Close()
+}
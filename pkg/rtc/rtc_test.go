@@ -0,0 +1,56 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rtc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+	for _, tt := range []struct {
+		t   time.Time
+		loc *time.Location
+		r   rtcTime
+	}{
+		{
+			t:   time.Date(2017, time.October, 15, 12, 30, 5, 0, time.UTC),
+			loc: time.UTC,
+			r:   rtcTime{Sec: 5, Min: 30, Hour: 12, Mday: 15, Mon: 9, Year: 117, Wday: 0, Yday: 287, Isdst: -1},
+		},
+		{
+			// In local time, it is the day before.
+			t:   time.Date(2018, time.January, 1, 2, 0, 0, 0, time.UTC),
+			loc: est,
+			r:   rtcTime{Sec: 0, Min: 0, Hour: 21, Mday: 31, Mon: 11, Year: 117, Wday: 0, Yday: 364, Isdst: -1},
+		},
+	} {
+		if got := fromTime(tt.t, tt.loc); got != tt.r {
+			t.Errorf("fromTime(%v, %v): got %+v, want %+v", tt.t, tt.loc, got, tt.r)
+		}
+		if got := toTime(tt.r, tt.loc); !got.Equal(tt.t) {
+			t.Errorf("toTime(%+v, %v): got %v, want %v", tt.r, tt.loc, got, tt.t)
+		}
+	}
+}
+
+func TestParseAdjtime(t *testing.T) {
+	for _, tt := range []struct {
+		adjtime string
+		local   bool
+	}{
+		{"0.0 0 0.0\n0\nUTC\n", false},
+		{"0.0 0 0.0\n0\nLOCAL\n", true},
+		{"0.000000 1508068805 0.000000\n1508068805\nLOCAL", true},
+		{"", false},
+		{"0.0 0 0.0\n", false},
+	} {
+		if got := ParseAdjtime(strings.NewReader(tt.adjtime)); got != tt.local {
+			t.Errorf("%q: got %v, want %v", tt.adjtime, got, tt.local)
+		}
+	}
+}
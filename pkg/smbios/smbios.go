@@ -0,0 +1,140 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package smbios parses the SMBIOS tables firmware describes the machine
+// with, as Linux exports them in /sys/firmware/dmi/tables.
+package smbios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// SysTables is where Linux exports the entry point and tables.
+const SysTables = "/sys/firmware/dmi/tables"
+
+// EndOfTable is the type of the last table.
+const EndOfTable = 127
+
+// A Table is an SMBIOS structure.
+type Table struct {
+	Type   uint8
+	Handle uint16
+	// Data is the formatted area, header and all, so that offsets into
+	// it are those in the specification.
+	Data []byte
+	// Strings are the strings after it, which String numbers from 1.
+	Strings []string
+}
+
+// Byte returns the byte at off, and whether Data is long enough to have it.
+// Tables grew in later versions of the specification, so older firmware
+// may not have all the fields.
+func (t *Table) Byte(off int) (uint8, bool) {
+	if off+1 > len(t.Data) {
+		return 0, false
+	}
+	return t.Data[off], true
+}
+
+// Word returns the little endian 16 bit word at off.
+func (t *Table) Word(off int) (uint16, bool) {
+	if off+2 > len(t.Data) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint16(t.Data[off:]), true
+}
+
+// DWord returns the little endian 32 bit word at off.
+func (t *Table) DWord(off int) (uint32, bool) {
+	if off+4 > len(t.Data) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(t.Data[off:]), true
+}
+
+// QWord returns the little endian 64 bit word at off.
+func (t *Table) QWord(off int) (uint64, bool) {
+	if off+8 > len(t.Data) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(t.Data[off:]), true
+}
+
+// String returns the string numbered by the byte at off, and whether there
+// is one. Number 0 is no string.
+func (t *Table) String(off int) (string, bool) {
+	n, ok := t.Byte(off)
+	if !ok || n == 0 || int(n) > len(t.Strings) {
+		return "", false
+	}
+	return t.Strings[n-1], true
+}
+
+// ParseEntryPoint returns the version of SMBIOS the entry point b, 32 or 64
+// bit, is for.
+func ParseEntryPoint(b []byte) (major, minor int, err error) {
+	switch {
+	case bytes.HasPrefix(b, []byte("_SM3_")) && len(b) >= 24:
+		return int(b[7]), int(b[8]), nil
+	case bytes.HasPrefix(b, []byte("_SM_")) && len(b) >= 31:
+		return int(b[6]), int(b[7]), nil
+	}
+	return 0, 0, fmt.Errorf("no SMBIOS entry point signature")
+}
+
+// ParseTables parses the tables in b, up to the end of table table, if
+// there is one.
+func ParseTables(b []byte) ([]*Table, error) {
+	var tables []*Table
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("table header is %d bytes, want 4", len(b))
+		}
+		t := &Table{Type: b[0], Handle: binary.LittleEndian.Uint16(b[2:])}
+		l := int(b[1])
+		if l < 4 || l > len(b) {
+			return nil, fmt.Errorf("table %#04x: length %d is out of range", t.Handle, l)
+		}
+		t.Data, b = b[:l], b[l:]
+		// The strings end with an empty one, which there is one of
+		// even if there are no strings.
+		end := bytes.Index(b, []byte{0, 0})
+		if end < 0 {
+			return nil, fmt.Errorf("table %#04x: strings are not terminated", t.Handle)
+		}
+		if end > 0 {
+			for _, s := range bytes.Split(b[:end], []byte{0}) {
+				t.Strings = append(t.Strings, string(s))
+			}
+		}
+		b = b[end+2:]
+		tables = append(tables, t)
+		if t.Type == EndOfTable {
+			break
+		}
+	}
+	return tables, nil
+}
+
+// Read reads the entry point and tables in dir, which is SysTables but in
+// tests.
+func Read(dir string) (major, minor int, tables []*Table, err error) {
+	ep, err := ioutil.ReadFile(filepath.Join(dir, "smbios_entry_point"))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if major, minor, err = ParseEntryPoint(ep); err != nil {
+		return 0, 0, nil, err
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "DMI"))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	tables, err = ParseTables(b)
+	return major, minor, tables, err
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smbios
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntryPoint(t *testing.T) {
+	ep2 := append([]byte("_SM_\x00\x1f\x02\x08"), make([]byte, 23)...)
+	ep3 := append([]byte("_SM3_\x00\x18\x03\x01"), make([]byte, 15)...)
+	for _, tt := range []struct {
+		b            []byte
+		major, minor int
+		ok           bool
+	}{
+		{ep2, 2, 8, true},
+		{ep3, 3, 1, true},
+		{ep2[:20], 0, 0, false},
+		{[]byte("_DMI_"), 0, 0, false},
+	} {
+		major, minor, err := ParseEntryPoint(tt.b)
+		if major != tt.major || minor != tt.minor || (err == nil) != tt.ok {
+			t.Errorf("ParseEntryPoint(%q): got %d.%d, %v, want %d.%d", tt.b, major, minor, err, tt.major, tt.minor)
+		}
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	b := []byte{
+		// BIOS, with two strings.
+		0, 6, 0, 0, 1, 2, 'S', 'e', 'a', 0, '1', '.', '0', 0, 0,
+		// OEM, with none.
+		0x80, 5, 1, 0, 0xaa, 0, 0,
+		// End of table, then what is left of the buffer.
+		127, 4, 2, 0, 0, 0,
+		1, 2, 3,
+	}
+	tables, err := ParseTables(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Table{
+		{Type: 0, Handle: 0, Data: b[:6], Strings: []string{"Sea", "1.0"}},
+		{Type: 0x80, Handle: 1, Data: b[15:20]},
+		{Type: 127, Handle: 2, Data: b[22:26]},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Fatalf("ParseTables: got %+v, want %+v", tables, want)
+	}
+
+	bios := tables[0]
+	if s, ok := bios.String(4); s != "Sea" || !ok {
+		t.Errorf("String(4): got %q, %v, want Sea, true", s, ok)
+	}
+	if s, ok := bios.String(5); s != "1.0" || !ok {
+		t.Errorf("String(5): got %q, %v, want 1.0, true", s, ok)
+	}
+	if _, ok := bios.String(6); ok {
+		t.Errorf("String(6): got ok past the end of the table")
+	}
+	if w, ok := bios.Word(4); w != 0x0201 || !ok {
+		t.Errorf("Word(4): got %#x, %v, want 0x201, true", w, ok)
+	}
+	if _, ok := bios.DWord(4); ok {
+		t.Errorf("DWord(4): got ok past the end of the table")
+	}
+
+	for _, bad := range [][]byte{
+		{0, 6, 0},
+		{0, 3, 0, 0, 0, 0},
+		{0, 8, 0, 0, 0, 0},
+		{0, 4, 0, 0, 'a', 0},
+	} {
+		if _, err := ParseTables(bad); err == nil {
+			t.Errorf("ParseTables(%v): got nil, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sysctl reads and writes kernel parameters in /proc/sys.
+//
+// Parameters are named with dots, e.g. net.ipv4.ip_forward for
+// /proc/sys/net/ipv4/ip_forward. Slashes may be used instead of dots.
+package sysctl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dir is where the parameters are.
+var Dir = "/proc/sys"
+
+// Path returns the file of the parameter name.
+func Path(name string) string {
+	if !strings.Contains(name, "/") {
+		name = strings.Replace(name, ".", "/", -1)
+	}
+	return filepath.Join(Dir, filepath.Clean("/"+name))
+}
+
+// Name returns the name of the parameter in the file path.
+func Name(path string) string {
+	rel, err := filepath.Rel(Dir, path)
+	if err != nil {
+		return path
+	}
+	return strings.Replace(rel, "/", ".", -1)
+}
+
+// Get returns the value of the parameter name, without the newline at its
+// end.
+func Get(name string) (string, error) {
+	b, err := ioutil.ReadFile(Path(name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// Set sets the parameter name to value.
+func Set(name, value string) error {
+	f, err := os.OpenFile(Path(name), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	// The kernel wants the value in one write.
+	if _, err := f.Write([]byte(value)); err != nil {
+		f.Close()
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	return f.Close()
+}
+
+// All returns the names of all readable parameters, sorted.
+func All() ([]string, error) {
+	var names []string
+	err := filepath.Walk(Dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Some directories are only for root.
+			return nil
+		}
+		if fi.Mode().IsRegular() && fi.Mode().Perm()&0444 != 0 {
+			names = append(names, Name(path))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// A Setting is a line of a sysctl.conf file.
+type Setting struct {
+	Name, Value string
+	// IgnoreError is set if errors setting it are to be ignored. It
+	// is written as a - in front of the name.
+	IgnoreError bool
+}
+
+// Parse parses a sysctl.conf file: lines of NAME = VALUE. Empty lines and
+// those starting with # or ; are ignored.
+func Parse(r io.Reader) ([]Setting, error) {
+	var settings []Setting
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' || l[0] == ';' {
+			continue
+		}
+		i := strings.IndexByte(l, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: %q has no =", n, l)
+		}
+		st := Setting{Name: strings.TrimSpace(l[:i]), Value: strings.TrimSpace(l[i+1:])}
+		if strings.HasPrefix(st.Name, "-") {
+			st.Name, st.IgnoreError = st.Name[1:], true
+		}
+		settings = append(settings, st)
+	}
+	return settings, s.Err()
+}
+
+// Load sets the parameters in the sysctl.conf file. It sets all it can,
+// and returns the first error.
+func Load(file string) ([]Setting, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	settings, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+	var first error
+	for _, st := range settings {
+		if err := Set(st.Name, st.Value); err != nil && !st.IgnoreError && first == nil {
+			first = fmt.Errorf("%v: %v", file, err)
+		}
+	}
+	return settings, first
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sysctl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	for name, want := range map[string]string{
+		"net.ipv4.ip_forward":  "/proc/sys/net/ipv4/ip_forward",
+		"kernel/pid_max":       "/proc/sys/kernel/pid_max",
+		"net/ipv4/conf/eth0.1": "/proc/sys/net/ipv4/conf/eth0.1",
+		"../../etc/passwd":     "/proc/sys/etc/passwd",
+	} {
+		if got := Path(name); got != want {
+			t.Errorf("Path(%q): got %q, want %q", name, got, want)
+		}
+	}
+	if got := Name("/proc/sys/vm/swappiness"); got != "vm.swappiness" {
+		t.Errorf("Name(/proc/sys/vm/swappiness): got %q, want %q", got, "vm.swappiness")
+	}
+}
+
+func TestParse(t *testing.T) {
+	conf := `# comment
+; comment
+kernel.printk = 4 4 1 7
+-net.ipv6.conf.all.disable_ipv6=1
+
+vm.swappiness=10
+`
+	got, err := Parse(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Setting{
+		{"kernel.printk", "4 4 1 7", false},
+		{"net.ipv6.conf.all.disable_ipv6", "1", true},
+		{"vm.swappiness", "10", false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse: got %v, want %v", got, want)
+	}
+	if _, err := Parse(strings.NewReader("vm.swappiness\n")); err == nil {
+		t.Errorf("Parse of a line without =: got nil, want error")
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestGetSet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { Dir = d }(Dir)
+	Dir = dir
+	if err := os.MkdirAll(filepath.Join(dir, "vm"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"vm/swappiness", "kernel"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("60\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if v, err := Get("vm.swappiness"); err != nil || v != "60" {
+		t.Errorf("Get(vm.swappiness): got %q, %v, want 60, nil", v, err)
+	}
+	conf := filepath.Join(dir, "sysctl.conf")
+	if err := ioutil.WriteFile(conf, []byte("vm.swappiness = 10\n-vm.nope = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(conf); err != nil {
+		t.Errorf("Load: %v", err)
+	}
+	if v, err := Get("vm/swappiness"); err != nil || v != "10" {
+		t.Errorf("Get(vm/swappiness) after Load: got %q, %v, want 10, nil", v, err)
+	}
+	if err := Set("vm.nope", "1"); err == nil {
+		t.Errorf("Set(vm.nope): got nil, want error")
+	}
+	names, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"kernel", "sysctl.conf", "vm.swappiness"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("All: got %v, want %v", names, want)
+	}
+}
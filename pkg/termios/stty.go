@@ -0,0 +1,190 @@
+// Copyright 2015-2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package termios
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Termios is an os-independent version of the combined info in termios and
+// window size structs: the speed, size, control characters and flags of a
+// terminal, by the names stty gives them. stty dump prints it as JSON.
+type Termios struct {
+	Ispeed int
+	Ospeed int
+	Row    int
+	Col    int
+
+	CC map[string]uint8
+
+	Opts map[string]bool
+}
+
+var (
+	// RawOpts are the options of stty raw: no processing of input or
+	// output, and reads return each byte as it comes.
+	RawOpts = []string{"~ignbrk", "~brkint", "~parmrk", "~istrip", "~inlcr", "~igncr", "~icrnl", "~ixon", "~opost", "~echo", "~echonl", "~icanon", "~isig", "~iexten", "~parenb" /*"cs8", */, "min", "1", "time", "0"}
+	// CookedOpts are the options of stty cooked, which undo those of
+	// raw: lines are edited by the kernel and echoed, and ^C is a
+	// signal.
+	CookedOpts = []string{"brkint", "icrnl", "ixon", "opost", "onlcr", "isig", "icanon", "iexten", "echo", "echoe", "echok", "echoctl", "echoke"}
+)
+
+// ccString formats the control character c as ^C, or <undef>.
+func ccString(c uint8) string {
+	switch {
+	case c == 0:
+		return "<undef>"
+	case c < 32:
+		return "^" + string(rune(c+'@'))
+	case c == 127:
+		return "^?"
+	}
+	return string(rune(c))
+}
+
+// parseCC parses a control character: a number, ^C, a single character,
+// or undef, <undef> or ^- for none.
+func parseCC(s string) (uint8, error) {
+	switch {
+	case s == "undef" || s == "<undef>" || s == "^-":
+		return 0, nil
+	case s == "^?":
+		return 127, nil
+	case len(s) == 2 && s[0] == '^':
+		return strings.ToUpper(s)[1] - '@', nil
+	}
+	if n, err := strconv.ParseUint(s, 0, 8); err == nil {
+		return uint8(n), nil
+	}
+	if len(s) == 1 {
+		return s[0], nil
+	}
+	return 0, fmt.Errorf("bad control character %q", s)
+}
+
+// String formats t in the manner of stty.
+func (t *Termios) String() string {
+	s := fmt.Sprintf("speed %d baud; rows %d; columns %d;\n", t.Ospeed, t.Row, t.Col)
+
+	var names []string
+	for n := range t.CC {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var cc []string
+	for _, n := range names {
+		v := ccString(t.CC[n])
+		// min and time are counts, not characters.
+		if n == "min" || n == "time" {
+			v = strconv.Itoa(int(t.CC[n]))
+		}
+		cc = append(cc, n+" = "+v+";")
+	}
+	s += strings.Join(cc, " ") + "\n"
+
+	names = names[:0]
+	for n := range t.Opts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var opts []string
+	for _, n := range names {
+		if !t.Opts[n] {
+			n = "~" + n
+		}
+		opts = append(opts, n)
+	}
+	return s + strings.Join(opts, " ") + "\n"
+}
+
+// intarg returns the number after the option opts[i].
+func intarg(opts []string, i int) (int, error) {
+	if i+1 >= len(opts) {
+		return 0, fmt.Errorf("%s requires an arg", opts[i])
+	}
+	n, err := strconv.Atoi(opts[i+1])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a number", opts[i], opts[i+1])
+	}
+	return n, nil
+}
+
+// SetOpts changes t as the stty arguments opts say. They are a variety of
+// key-value pairs and booleans. Booleans are cleared if the first char is
+// a ~ or -, set otherwise. raw and cooked stand for RawOpts and CookedOpts,
+// and a number on its own is the speed.
+func (t *Termios) SetOpts(opts []string) error {
+	for i := 0; i < len(opts); i++ {
+		o := opts[i]
+		switch o {
+		case "row", "rows", "col", "cols", "columns", "speed", "ispeed", "ospeed":
+			n, err := intarg(opts, i)
+			if err != nil {
+				return err
+			}
+			switch o {
+			case "row", "rows":
+				t.Row = n
+			case "col", "cols", "columns":
+				t.Col = n
+			case "ispeed":
+				t.Ispeed = n
+			case "ospeed":
+				t.Ospeed = n
+			default:
+				t.Ispeed, t.Ospeed = n, n
+			}
+			i++
+			continue
+		case "--":
+			continue
+		case "raw", "~cooked", "-cooked":
+			if err := t.SetOpts(RawOpts); err != nil {
+				return err
+			}
+			continue
+		case "cooked", "~raw", "-raw":
+			if err := t.SetOpts(CookedOpts); err != nil {
+				return err
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(o); err == nil {
+			t.Ispeed, t.Ospeed = n, n
+			continue
+		}
+
+		// see if it's one of the control char options.
+		if _, ok := t.CC[o]; ok {
+			if i+1 >= len(opts) {
+				return fmt.Errorf("%s requires an arg", o)
+			}
+			c, err := parseCC(opts[i+1])
+			if err != nil {
+				return fmt.Errorf("%s: %v", o, err)
+			}
+			t.CC[o] = c
+			i++
+			continue
+		}
+
+		// At this point, it has to be one of the boolean ones
+		// or we're done here.
+		set := true
+		if o[0] == '~' || o[0] == '-' {
+			set = false
+			o = o[1:]
+		}
+		if _, ok := t.Opts[o]; !ok {
+			return fmt.Errorf("%s: unknown option", o)
+		}
+		t.Opts[o] = set
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2015-2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package termios
+
+import (
+	"fmt"
+	"reflect"
+
+	"golang.org/x/sys/unix"
+)
+
+type bit struct {
+	word int
+	mask uint32
+}
+
+var (
+	boolFields = map[string]*bit{
+		// Input processing
+		"ignbrk":  {word: I, mask: unix.IGNBRK},
+		"brkint":  {word: I, mask: unix.BRKINT},
+		"ignpar":  {word: I, mask: unix.IGNPAR},
+		"parmrk":  {word: I, mask: unix.PARMRK},
+		"inpck":   {word: I, mask: unix.INPCK},
+		"istrip":  {word: I, mask: unix.ISTRIP},
+		"inlcr":   {word: I, mask: unix.INLCR},
+		"igncr":   {word: I, mask: unix.IGNCR},
+		"icrnl":   {word: I, mask: unix.ICRNL},
+		"iuclc":   {word: I, mask: unix.IUCLC},
+		"ixon":    {word: I, mask: unix.IXON},
+		"ixany":   {word: I, mask: unix.IXANY},
+		"ixoff":   {word: I, mask: unix.IXOFF},
+		"imaxbel": {word: I, mask: unix.IMAXBEL},
+		"iutf8":   {word: I, mask: unix.IUTF8},
+
+		//Outputprocessing
+		"opost":  {word: O, mask: unix.OPOST},
+		"olcuc":  {word: O, mask: unix.OLCUC},
+		"onlcr":  {word: O, mask: unix.ONLCR},
+		"ocrnl":  {word: O, mask: unix.OCRNL},
+		"onocr":  {word: O, mask: unix.ONOCR},
+		"onlret": {word: O, mask: unix.ONLRET},
+		"ofill":  {word: O, mask: unix.OFILL},
+		"ofdel":  {word: O, mask: unix.OFDEL},
+
+		//Localprocessing
+		"isig":    {word: L, mask: unix.ISIG},
+		"icanon":  {word: L, mask: unix.ICANON},
+		"xcase":   {word: L, mask: unix.XCASE},
+		"echo":    {word: L, mask: unix.ECHO},
+		"echoe":   {word: L, mask: unix.ECHOE},
+		"echok":   {word: L, mask: unix.ECHOK},
+		"echonl":  {word: L, mask: unix.ECHONL},
+		"noflsh":  {word: L, mask: unix.NOFLSH},
+		"tostop":  {word: L, mask: unix.TOSTOP},
+		"echoctl": {word: L, mask: unix.ECHOCTL},
+		"echoprt": {word: L, mask: unix.ECHOPRT},
+		"echoke":  {word: L, mask: unix.ECHOKE},
+		"flusho":  {word: L, mask: unix.FLUSHO},
+		"pendin":  {word: L, mask: unix.PENDIN},
+		"iexten":  {word: L, mask: unix.IEXTEN},
+
+		//Controlprocessing
+
+		"cstopb": {word: C, mask: unix.CSTOPB},
+		"cread":  {word: C, mask: unix.CREAD},
+		"parenb": {word: C, mask: unix.PARENB},
+		"parodd": {word: C, mask: unix.PARODD},
+		"hupcl":  {word: C, mask: unix.HUPCL},
+		"clocal": {word: C, mask: unix.CLOCAL},
+	}
+	cc = map[string]int{
+		"min":   unix.VMIN,
+		"time":  unix.VTIME,
+		"lnext": unix.VLNEXT,
+		//"flush": unix.VFLUSH,
+		"intr":  unix.VINTR,
+		"quit":  unix.VQUIT,
+		"erase": unix.VERASE,
+		"kill":  unix.VKILL,
+		"eof":   unix.VEOF,
+		"eol":   unix.VEOL,
+		"eol2":  unix.VEOL2,
+		//"swtch": unix.VSWTCH,
+		"start": unix.VSTART,
+		"stop":  unix.VSTOP,
+		"susp":  unix.VSUSP,
+		//"rprnt": unix.VRPRNT,
+		"werase": unix.VWERASE,
+	}
+	// speeds are the speeds in the CBAUD bits of the control flags.
+	speeds = map[int]uint32{
+		0:       unix.B0,
+		50:      unix.B50,
+		75:      unix.B75,
+		110:     unix.B110,
+		134:     unix.B134,
+		150:     unix.B150,
+		200:     unix.B200,
+		300:     unix.B300,
+		600:     unix.B600,
+		1200:    unix.B1200,
+		1800:    unix.B1800,
+		2400:    unix.B2400,
+		4800:    unix.B4800,
+		9600:    unix.B9600,
+		19200:   unix.B19200,
+		38400:   unix.B38400,
+		57600:   unix.B57600,
+		115200:  unix.B115200,
+		230400:  unix.B230400,
+		460800:  unix.B460800,
+		500000:  unix.B500000,
+		576000:  unix.B576000,
+		921600:  unix.B921600,
+		1000000: unix.B1000000,
+		1152000: unix.B1152000,
+		1500000: unix.B1500000,
+		2000000: unix.B2000000,
+		2500000: unix.B2500000,
+		3000000: unix.B3000000,
+		3500000: unix.B3500000,
+		4000000: unix.B4000000,
+	}
+)
+
+// These consts describe the offsets into the termios struct of various elements.
+const (
+	I = iota // Input control
+	O        // Output control
+	C        // Control
+	L        // Line control
+)
+
+// speed returns the speed in the control flags cflag, or -1 if it is not
+// one of speeds.
+func speed(cflag uint32) int {
+	for s, b := range speeds {
+		if cflag&unix.CBAUD == b {
+			return s
+		}
+	}
+	return -1
+}
+
+// GTTY returns the state of the terminal fd.
+func GTTY(fd int) (*Termios, error) {
+	term, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+	w, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return nil, err
+	}
+
+	var t = Termios{Opts: make(map[string]bool), CC: make(map[string]uint8)}
+	for n, b := range boolFields {
+		val := uint32(reflect.ValueOf(term).Elem().Field(b.word).Uint()) & b.mask
+		t.Opts[n] = val != 0
+	}
+
+	for n, c := range cc {
+		t.CC[n] = term.Cc[c]
+	}
+
+	// back in the day, you could have different i and o speeds.
+	// since about 1975, this has not been a thing. It's still in POSIX
+	// evidently. WTF? Linux keeps just one, in the control flags.
+	t.Ispeed = speed(term.Cflag)
+	t.Ospeed = t.Ispeed
+	t.Row = int(w.Row)
+	t.Col = int(w.Col)
+
+	return &t, nil
+}
+
+// STTY sets the state of the terminal fd to t, and returns the new state.
+func (t *Termios) STTY(fd int) (*Termios, error) {
+	// Get a unix.Termios which we can partially fill in.
+	term, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, b := range boolFields {
+		set := t.Opts[n]
+		i := reflect.ValueOf(term).Elem().Field(b.word).Uint()
+		if set {
+			i |= uint64(b.mask)
+		} else {
+			i &= ^uint64(b.mask)
+		}
+		reflect.ValueOf(term).Elem().Field(b.word).SetUint(i)
+	}
+
+	for n, c := range cc {
+		term.Cc[c] = t.CC[n]
+	}
+
+	if t.Ispeed != t.Ospeed {
+		return nil, fmt.Errorf("input speed %d and output speed %d differ", t.Ispeed, t.Ospeed)
+	}
+	// An unknown speed is left as it is.
+	if t.Ospeed != -1 {
+		b, ok := speeds[t.Ospeed]
+		if !ok {
+			return nil, fmt.Errorf("%d is not a speed", t.Ospeed)
+		}
+		term.Cflag = term.Cflag&^unix.CBAUD | b
+	}
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, term); err != nil {
+		return nil, err
+	}
+
+	w := &unix.Winsize{Row: uint16(t.Row), Col: uint16(t.Col)}
+	if err := unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, w); err != nil {
+		return nil, err
+	}
+
+	return GTTY(fd)
+}
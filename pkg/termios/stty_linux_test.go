@@ -0,0 +1,52 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package termios_test
+
+import (
+	"testing"
+
+	"github.com/u-root/u-root/pkg/pty"
+	"github.com/u-root/u-root/pkg/termios"
+)
+
+func TestSTTY(t *testing.T) {
+	ptm, pts, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	defer ptm.Close()
+	defer pts.Close()
+	fd := int(pts.Fd())
+
+	tt, err := termios.GTTY(fd)
+	if err != nil {
+		t.Fatalf("GTTY: %v", err)
+	}
+	if err := tt.SetOpts([]string{"raw", "115200", "rows", "24", "cols", "80", "intr", "^B"}); err != nil {
+		t.Fatalf("SetOpts: %v", err)
+	}
+	got, err := tt.STTY(fd)
+	if err != nil {
+		t.Fatalf("STTY: %v", err)
+	}
+	if got.Ospeed != 115200 || got.Row != 24 || got.Col != 80 || got.CC["intr"] != 2 || got.Opts["echo"] || got.Opts["icanon"] {
+		t.Errorf("STTY: got %v, want raw at 115200 baud, 24x80, intr ^B", got)
+	}
+
+	if err := got.SetOpts([]string{"cooked"}); err != nil {
+		t.Fatalf("SetOpts(cooked): %v", err)
+	}
+	if got, err = got.STTY(fd); err != nil {
+		t.Fatalf("STTY: %v", err)
+	}
+	if !got.Opts["echo"] || !got.Opts["icanon"] || !got.Opts["isig"] {
+		t.Errorf("STTY(cooked): got %v, want echo icanon isig", got)
+	}
+
+	got.Ospeed = 1234
+	if _, err := got.STTY(fd); err == nil {
+		t.Errorf("STTY(1234 baud): got nil, want error")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package termios
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTermios() *Termios {
+	return &Termios{
+		Ispeed: 38400,
+		Ospeed: 38400,
+		CC:     map[string]uint8{"intr": 3, "min": 1, "time": 0},
+		Opts:   map[string]bool{"echo": true, "icanon": true, "opost": false},
+	}
+}
+
+func TestSetOpts(t *testing.T) {
+	for _, tt := range []struct {
+		opts []string
+		mod  func(t *Termios)
+	}{
+		{[]string{"~echo"}, func(t *Termios) { t.Opts["echo"] = false }},
+		{[]string{"--", "-echo", "opost"}, func(t *Termios) { t.Opts["echo"], t.Opts["opost"] = false, true }},
+		{[]string{"rows", "24", "cols", "80"}, func(t *Termios) { t.Row, t.Col = 24, 80 }},
+		{[]string{"115200"}, func(t *Termios) { t.Ispeed, t.Ospeed = 115200, 115200 }},
+		{[]string{"speed", "9600"}, func(t *Termios) { t.Ispeed, t.Ospeed = 9600, 9600 }},
+		{[]string{"intr", "^B"}, func(t *Termios) { t.CC["intr"] = 2 }},
+		{[]string{"intr", "undef", "min", "5"}, func(t *Termios) { t.CC["intr"], t.CC["min"] = 0, 5 }},
+	} {
+		got, want := newTermios(), newTermios()
+		tt.mod(want)
+		if err := got.SetOpts(tt.opts); err != nil {
+			t.Errorf("SetOpts(%q): %v", tt.opts, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SetOpts(%q): got %+v, want %+v", tt.opts, got, want)
+		}
+	}
+	for _, opts := range [][]string{{"bogus"}, {"rows"}, {"rows", "x"}, {"intr"}, {"intr", "^BB"}} {
+		if err := newTermios().SetOpts(opts); err == nil {
+			t.Errorf("SetOpts(%q): got nil, want error", opts)
+		}
+	}
+}
+
+func TestCC(t *testing.T) {
+	for _, tt := range []struct {
+		c uint8
+		s string
+	}{
+		{0, "<undef>"},
+		{3, "^C"},
+		{28, "^\\"},
+		{127, "^?"},
+		{'a', "a"},
+	} {
+		if got := ccString(tt.c); got != tt.s {
+			t.Errorf("ccString(%d): got %q, want %q", tt.c, got, tt.s)
+		}
+		if got, err := parseCC(tt.s); err != nil || got != tt.c {
+			t.Errorf("parseCC(%q): got %d, %v, want %d, nil", tt.s, got, err, tt.c)
+		}
+	}
+	if got, err := parseCC("^c"); err != nil || got != 3 {
+		t.Errorf("parseCC(^c): got %d, %v, want 3, nil", got, err)
+	}
+	if got, err := parseCC("0x7f"); err != nil || got != 127 {
+		t.Errorf("parseCC(0x7f): got %d, %v, want 127, nil", got, err)
+	}
+}
+
+func TestString(t *testing.T) {
+	want := "speed 38400 baud; rows 0; columns 0;\nintr = ^C; min = 1; time = 0;\necho icanon ~opost\n"
+	if got := newTermios().String(); got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+}
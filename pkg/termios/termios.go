@@ -11,6 +11,9 @@
 // restorer, err := tty.Raw()
 // do things
 // tty.Set(restorer)
+// To get or set the state of a terminal by the names stty uses, call
+// GTTY(fd), change the Termios it returns, e.g. with SetOpts, and call its
+// STTY(fd) method.
 package termios
 
 import (
@@ -0,0 +1,200 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tftp implements a TFTP client which reads files, as in RFC 1350,
+// with the block size option of RFC 2348.
+package tftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Opcodes.
+const (
+	opRRQ   = 1
+	opDATA  = 3
+	opACK   = 4
+	opERROR = 5
+	opOACK  = 6
+)
+
+const (
+	// DefaultBlockSize is the block size without the block size
+	// option.
+	DefaultBlockSize = 512
+	// DefaultPort is the TFTP server port.
+	DefaultPort = "69"
+)
+
+// Client reads files from TFTP servers.
+type Client struct {
+	// Timeout is how long to wait for a packet before sending the last
+	// one again.
+	Timeout time.Duration
+	// Retries is how many times a packet is sent again before giving up.
+	Retries int
+	// BlockSize is the block size to ask the server for. The server may
+	// choose a smaller one, or ignore it and use DefaultBlockSize.
+	BlockSize int
+}
+
+// DefaultClient is the Client used by Get. The block size fits an
+// Ethernet frame.
+var DefaultClient = &Client{
+	Timeout:   2 * time.Second,
+	Retries:   5,
+	BlockSize: 1468,
+}
+
+// Get reads file from the server at addr with DefaultClient and writes it
+// to w.
+func Get(addr, file string, w io.Writer) error {
+	return DefaultClient.Get(addr, file, w)
+}
+
+// An Error is an error packet from the server.
+type Error struct {
+	Code    uint16
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("tftp error %d: %s", e.Code, e.Message)
+}
+
+// Get reads file from the server at addr, which is a host with an optional
+// port, and writes it to w.
+func (c *Client) Get(addr, file string, w io.Writer) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+	server, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rrq := request(file, c.BlockSize)
+	// The server answers from a new port, its transfer ID, which the
+	// rest of the packets go to.
+	last, to := rrq, server
+	var tid *net.UDPAddr
+	blockSize := DefaultBlockSize
+	var block uint16
+	buf := make([]byte, 65536)
+	for tries := 0; ; {
+		if _, err := conn.WriteToUDP(last, to); err != nil {
+			return err
+		}
+		p, from, err := read(conn, buf, tid, c.Timeout)
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			if tries++; tries > c.Retries {
+				return fmt.Errorf("%s: %s: timed out", addr, file)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		op, arg := binary.BigEndian.Uint16(p), binary.BigEndian.Uint16(p[2:])
+		switch {
+		case op == opERROR:
+			return &Error{Code: arg, Message: string(bytes.TrimRight(p[4:], "\x00"))}
+
+		case op == opOACK && tid == nil:
+			tid = from
+			if blockSize, err = parseOACK(p[2:]); err != nil {
+				return err
+			}
+			last, to, tries = ack(0), tid, 0
+
+		case op == opDATA && arg == block+1:
+			tid = from
+			data := p[4:]
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			block++
+			last, to, tries = ack(block), tid, 0
+			if len(data) < blockSize {
+				// Without the last ACK, the server would
+				// send the last block again.
+				_, err := conn.WriteToUDP(last, to)
+				return err
+			}
+
+		case op == opDATA && arg == block:
+			// Our ACK was lost, and is sent again.
+
+		default:
+			return fmt.Errorf("%s: unexpected packet, opcode %d", addr, op)
+		}
+	}
+}
+
+// read reads the next packet from tid, or from anyone if tid is nil.
+func read(conn *net.UDPConn, buf []byte, tid *net.UDPAddr, timeout time.Duration) ([]byte, *net.UDPAddr, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tid != nil && (!from.IP.Equal(tid.IP) || from.Port != tid.Port) {
+			continue
+		}
+		if n < 4 {
+			return nil, nil, fmt.Errorf("short packet of %d bytes from %v", n, from)
+		}
+		return buf[:n], from, nil
+	}
+}
+
+// request returns a read request for file in octet mode, asking for a
+// block size of blockSize unless it is the default.
+func request(file string, blockSize int) []byte {
+	p := []byte{0, opRRQ}
+	p = append(p, file...)
+	p = append(p, 0)
+	p = append(p, "octet"...)
+	p = append(p, 0)
+	if blockSize > 0 && blockSize != DefaultBlockSize {
+		p = append(p, "blksize\x00"...)
+		p = append(p, strconv.Itoa(blockSize)...)
+		p = append(p, 0)
+	}
+	return p
+}
+
+func ack(block uint16) []byte {
+	return []byte{0, opACK, byte(block >> 8), byte(block)}
+}
+
+// parseOACK returns the block size the server chose in the options of
+// an OACK packet.
+func parseOACK(opts []byte) (int, error) {
+	f := bytes.Split(bytes.TrimRight(opts, "\x00"), []byte{0})
+	blockSize := DefaultBlockSize
+	for i := 0; i+1 < len(f); i += 2 {
+		if string(bytes.ToLower(f[i])) != "blksize" {
+			continue
+		}
+		n, err := strconv.Atoi(string(f[i+1]))
+		if err != nil || n < 8 || n > 65464 {
+			return 0, fmt.Errorf("bad block size %q from server", f[i+1])
+		}
+		blockSize = n
+	}
+	return blockSize, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// serve answers one read request on l with files. If oack is set, it
+// takes the block size option.
+func serve(t *testing.T, l *net.UDPConn, files map[string][]byte, oack bool) {
+	buf := make([]byte, 65536)
+	n, client, err := l.ReadFromUDP(buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f := bytes.Split(buf[2:n], []byte{0})
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	data, ok := files[string(f[0])]
+	if !ok {
+		conn.WriteToUDP(append([]byte{0, opERROR, 0, 1}, "File not found\x00"...), client)
+		return
+	}
+	blockSize := DefaultBlockSize
+	var block uint16
+	if oack && len(f) > 3 && string(f[2]) == "blksize" {
+		blockSize, _ = strconv.Atoi(string(f[3]))
+		conn.WriteToUDP(append([]byte{0, opOACK}, "blksize\x00"+string(f[3])+"\x00"...), client)
+		if !waitACK(t, conn, buf, block) {
+			return
+		}
+	}
+	for {
+		b := data
+		if len(b) > blockSize {
+			b = b[:blockSize]
+		}
+		data = data[len(b):]
+		block++
+		p := []byte{0, opDATA, byte(block >> 8), byte(block)}
+		conn.WriteToUDP(append(p, b...), client)
+		if !waitACK(t, conn, buf, block) || len(b) < blockSize {
+			return
+		}
+	}
+}
+
+func waitACK(t *testing.T, conn *net.UDPConn, buf []byte, block uint16) bool {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Errorf("waiting for ACK %d: %v", block, err)
+		return false
+	}
+	if n != 4 || binary.BigEndian.Uint16(buf) != opACK || binary.BigEndian.Uint16(buf[2:]) != block {
+		t.Errorf("got %v, want ACK %d", buf[:n], block)
+		return false
+	}
+	return true
+}
+
+func TestGet(t *testing.T) {
+	files := map[string][]byte{
+		"empty":      {},
+		"small":      []byte("hello"),
+		"two blocks": bytes.Repeat([]byte("x"), 1000),
+		"exact":      bytes.Repeat([]byte("y"), 2*1468),
+	}
+	for _, oack := range []bool{false, true} {
+		for name, want := range files {
+			l, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			done := make(chan bool)
+			go func() {
+				serve(t, l, files, oack)
+				close(done)
+			}()
+			var b bytes.Buffer
+			if err := Get(l.LocalAddr().String(), name, &b); err != nil {
+				t.Errorf("Get(%q) with OACK %v: %v", name, oack, err)
+			} else if !bytes.Equal(b.Bytes(), want) {
+				t.Errorf("Get(%q) with OACK %v: got %d bytes, want %d", name, oack, b.Len(), len(want))
+			}
+			<-done
+			l.Close()
+		}
+	}
+}
+
+func TestGetError(t *testing.T) {
+	l, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serve(t, l, nil, false)
+
+	err = Get(l.LocalAddr().String(), "nosuchfile", &bytes.Buffer{})
+	if e, ok := err.(*Error); !ok || e.Code != 1 {
+		t.Errorf("Get(nosuchfile): got %v, want tftp error 1", err)
+	}
+}
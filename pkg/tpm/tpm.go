@@ -0,0 +1,218 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tpm extends and reads the PCRs of a TPM, 1.2 or 2.0, through the
+// kernel's TPM device.
+package tpm
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	// The hashes of the PCR banks.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+)
+
+// Device is the TPM device. The kernel only lets one process at a time
+// open it.
+const Device = "/dev/tpm0"
+
+// The TPM 1.2 tags and ordinals.
+const (
+	tagRQUCommand = 0x00c1
+	tagRSPCommand = 0x00c4
+	ordExtend     = 0x14
+	ordPCRRead    = 0x15
+)
+
+// The TPM 2.0 tags, command codes, handles and algorithms.
+const (
+	stNoSessions = 0x8001
+	stSessions   = 0x8002
+	ccPCRExtend  = 0x182
+	ccPCRRead    = 0x17e
+	rsPW         = 0x40000009
+	algSHA256    = 0x000b
+)
+
+// NumPCRs is the number of PCRs both versions have.
+const NumPCRs = 24
+
+// A TPM is a TPM, 1.2 or 2.0. Version 1.2 only has SHA-1 PCRs; of 2.0, the
+// SHA-256 bank is used.
+type TPM struct {
+	rw io.ReadWriter
+	// Version is 1 for TPM 1.2, and 2 for 2.0.
+	Version int
+}
+
+// Open opens the TPM device dev.
+func Open(dev string) (*TPM, error) {
+	f, err := os.OpenFile(dev, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	t, err := New(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// New returns the TPM which rw sends commands to, and reads responses
+// from. A TPM 1.2 answers a TPM 2.0 command with a 1.2 error, which is
+// how its version is found.
+func New(rw io.ReadWriter) (*TPM, error) {
+	t := &TPM{rw: rw}
+	tag, _, err := t.command(stNoSessions, ccPCRRead, pcrSelection(0))
+	switch tag {
+	case tagRSPCommand:
+		t.Version = 1
+	case stNoSessions:
+		t.Version = 2
+	default:
+		if err == nil {
+			err = fmt.Errorf("TPM response tag %#x is neither 1.2 nor 2.0", tag)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// Close closes the TPM device.
+func (t *TPM) Close() error {
+	if c, ok := t.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Hash returns the hash of the PCRs used.
+func (t *TPM) Hash() crypto.Hash {
+	if t.Version == 1 {
+		return crypto.SHA1
+	}
+	return crypto.SHA256
+}
+
+// command sends a command with tag, ordinal or command code cc, and body,
+// and returns the tag and body of the response.
+func (t *TPM) command(tag uint16, cc uint32, body []byte) (uint16, []byte, error) {
+	cmd := make([]byte, 10, 10+len(body))
+	binary.BigEndian.PutUint16(cmd, tag)
+	binary.BigEndian.PutUint32(cmd[2:], uint32(10+len(body)))
+	binary.BigEndian.PutUint32(cmd[6:], cc)
+	if _, err := t.rw.Write(append(cmd, body...)); err != nil {
+		return 0, nil, err
+	}
+	// The response is read all at once.
+	r := make([]byte, 4096)
+	n, err := t.rw.Read(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n < 10 || int(binary.BigEndian.Uint32(r[2:])) != n {
+		return 0, nil, fmt.Errorf("TPM response of %d bytes is bad", n)
+	}
+	rtag := binary.BigEndian.Uint16(r)
+	if rc := binary.BigEndian.Uint32(r[6:]); rc != 0 {
+		return rtag, nil, fmt.Errorf("TPM command %#x: error %#x", cc, rc)
+	}
+	return rtag, r[10:n], nil
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// pcrSelection is a TPML_PCR_SELECTION of pcr in the SHA-256 bank.
+func pcrSelection(pcr uint32) []byte {
+	sel := []byte{0, 0, 0, 1, algSHA256 >> 8, algSHA256 & 0xff, 3, 0, 0, 0}
+	sel[7+pcr/8] = 1 << (pcr % 8)
+	return sel
+}
+
+func checkPCR(pcr uint32) error {
+	if pcr >= NumPCRs {
+		return fmt.Errorf("PCR %d: there are %d", pcr, NumPCRs)
+	}
+	return nil
+}
+
+// Extend extends PCR pcr with digest, which is of the size of t's Hash:
+// the PCR becomes the hash of what it was and digest.
+func (t *TPM) Extend(pcr uint32, digest []byte) error {
+	if err := checkPCR(pcr); err != nil {
+		return err
+	}
+	if len(digest) != t.Hash().Size() {
+		return fmt.Errorf("digest is %d bytes, not %d", len(digest), t.Hash().Size())
+	}
+	if t.Version == 1 {
+		_, _, err := t.command(tagRQUCommand, ordExtend, append(be32(pcr), digest...))
+		return err
+	}
+	// The password session, with an empty password, which PCRs take.
+	var b bytes.Buffer
+	b.Write(be32(pcr))
+	b.Write(be32(9))
+	b.Write(be32(rsPW))
+	b.Write([]byte{0, 0, 0, 0, 0})
+	b.Write(be32(1))
+	b.Write([]byte{algSHA256 >> 8, algSHA256 & 0xff})
+	b.Write(digest)
+	_, _, err := t.command(stSessions, ccPCRExtend, b.Bytes())
+	return err
+}
+
+// ReadPCR returns the value of PCR pcr.
+func (t *TPM) ReadPCR(pcr uint32) ([]byte, error) {
+	if err := checkPCR(pcr); err != nil {
+		return nil, err
+	}
+	if t.Version == 1 {
+		_, r, err := t.command(tagRQUCommand, ordPCRRead, be32(pcr))
+		if err != nil {
+			return nil, err
+		}
+		if len(r) != 20 {
+			return nil, fmt.Errorf("PCR %d is %d bytes, not 20", pcr, len(r))
+		}
+		return r, nil
+	}
+	_, r, err := t.command(stNoSessions, ccPCRRead, pcrSelection(pcr))
+	if err != nil {
+		return nil, err
+	}
+	// The update counter, the selection read, then the digests.
+	sel := pcrSelection(pcr)
+	if len(r) < 4+len(sel)+4+2 || !bytes.Equal(r[4:4+len(sel)], sel) {
+		return nil, fmt.Errorf("PCR %d is not in the SHA-256 bank", pcr)
+	}
+	r = r[4+len(sel):]
+	if binary.BigEndian.Uint32(r) != 1 {
+		return nil, fmt.Errorf("PCR %d: got %d digests, want 1", pcr, binary.BigEndian.Uint32(r))
+	}
+	n := int(binary.BigEndian.Uint16(r[4:]))
+	if len(r) < 6+n {
+		return nil, fmt.Errorf("PCR %d: digest is short", pcr)
+	}
+	return r[6 : 6+n], nil
+}
+
+// Measure extends PCR pcr with the hash of b, and returns the hash.
+func (t *TPM) Measure(pcr uint32, b []byte) ([]byte, error) {
+	h := t.Hash().New()
+	h.Write(b)
+	d := h.Sum(nil)
+	return d, t.Extend(pcr, d)
+}
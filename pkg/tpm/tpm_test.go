@@ -0,0 +1,134 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tpm
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// fakeTPM simulates the PCRs of a TPM, as a TPM device does: a write is a
+// command, and the next read its response.
+type fakeTPM struct {
+	version int
+	pcrs    [NumPCRs][]byte
+	resp    []byte
+}
+
+func newFakeTPM(version int) *fakeTPM {
+	f := &fakeTPM{version: version}
+	size := 32
+	if version == 1 {
+		size = 20
+	}
+	for i := range f.pcrs {
+		f.pcrs[i] = make([]byte, size)
+	}
+	return f
+}
+
+func (f *fakeTPM) respond(tag uint16, rc uint32, body []byte) {
+	f.resp = make([]byte, 10)
+	binary.BigEndian.PutUint16(f.resp, tag)
+	binary.BigEndian.PutUint32(f.resp[2:], uint32(10+len(body)))
+	binary.BigEndian.PutUint32(f.resp[6:], rc)
+	f.resp = append(f.resp, body...)
+}
+
+func (f *fakeTPM) extend(pcr uint32, d []byte) {
+	var sum []byte
+	if f.version == 1 {
+		s := sha1.Sum(append(f.pcrs[pcr], d...))
+		sum = s[:]
+	} else {
+		s := sha256.Sum256(append(f.pcrs[pcr], d...))
+		sum = s[:]
+	}
+	f.pcrs[pcr] = sum
+}
+
+func (f *fakeTPM) Write(b []byte) (int, error) {
+	be16, be32 := binary.BigEndian.Uint16, binary.BigEndian.Uint32
+	if int(be32(b[2:])) != len(b) {
+		return 0, errors.New("command size is wrong")
+	}
+	tag, cc, body := be16(b), be32(b[6:]), b[10:]
+	if f.version == 1 {
+		switch {
+		case tag != tagRQUCommand:
+			f.respond(tagRSPCommand, 0x1e, nil)
+		case cc == ordExtend:
+			f.extend(be32(body), body[4:])
+			f.respond(tagRSPCommand, 0, f.pcrs[be32(body)])
+		case cc == ordPCRRead:
+			f.respond(tagRSPCommand, 0, f.pcrs[be32(body)])
+		}
+		return len(b), nil
+	}
+	switch cc {
+	case ccPCRExtend:
+		if tag != stSessions || be32(body[8:]) != rsPW || be32(body[17:]) != 1 || be16(body[21:]) != algSHA256 {
+			f.respond(stNoSessions, 0x101, nil)
+			break
+		}
+		f.extend(be32(body), body[23:])
+		f.respond(stSessions, 0, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0})
+	case ccPCRRead:
+		var pcr uint32
+		for i := uint32(0); i < 24; i++ {
+			if body[7+i/8]&(1<<(i%8)) != 0 {
+				pcr = i
+			}
+		}
+		r := append([]byte{0, 0, 0, 1}, body...)
+		r = append(r, 0, 0, 0, 1, 0, 32)
+		f.respond(stNoSessions, 0, append(r, f.pcrs[pcr]...))
+	}
+	return len(b), nil
+}
+
+func (f *fakeTPM) Read(b []byte) (int, error) {
+	n := copy(b, f.resp)
+	f.resp = nil
+	return n, nil
+}
+
+func TestPCRs(t *testing.T) {
+	for _, v := range []int{1, 2} {
+		f := newFakeTPM(v)
+		tpm, err := New(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tpm.Version != v {
+			t.Errorf("got version %d, want %d", tpm.Version, v)
+		}
+		d, err := tpm.Measure(9, []byte("kernel"))
+		if err != nil {
+			t.Fatalf("TPM %d: %v", v, err)
+		}
+		h := tpm.Hash().New()
+		h.Write(make([]byte, tpm.Hash().Size()))
+		h.Write(d)
+		want := h.Sum(nil)
+		got, err := tpm.ReadPCR(9)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("TPM %d: ReadPCR(9): got %x, %v, want %x", v, got, err, want)
+		}
+		if got, err := tpm.ReadPCR(23); err != nil || !bytes.Equal(got, make([]byte, len(want))) {
+			t.Errorf("TPM %d: ReadPCR(23): got %x, %v, want zeros", v, got, err)
+		}
+		if err := tpm.Extend(24, d); err == nil {
+			t.Errorf("TPM %d: Extend(24): got nil, want error", v)
+		}
+		if err := tpm.Extend(0, []byte{1}); err == nil {
+			t.Errorf("TPM %d: Extend of a short digest: got nil, want error", v)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"golang.org/x/tools/imports"
@@ -36,7 +37,7 @@ func _forkbuiltin_{{.CmdName}}(c *Command) (err error) {
 }
 
 func {{.CmdName}}Init() {
-	addForkBuiltIn("{{.CmdName}}", _forkbuiltin_{{.CmdName}})
+	addForkBuiltIn("{{.CmdName}}", "", _forkbuiltin_{{.CmdName}})
 	{{.Init}}
 }
 `
@@ -60,12 +61,12 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-        "syscall"
+	"syscall"
 
 	"github.com/u-root/u-root/pkg/uroot/util"
 )
 
-func usage () {
+func usage() {
 	n := filepath.Base(os.Args[0])
 	fmt.Fprintf(os.Stderr, "Usage: %s:\n", n)
 	flag.VisitAll(func(f *flag.Flag) {
@@ -99,9 +100,9 @@ func init() {
 	}
 	util.Rootfs()
 
-        // spawn the first shell. We had been running the shell as pid 1
-        // but that makes control tty stuff messy. We think.
-        cloneFlags := uintptr(0)
+	// spawn the first shell. We had been running the shell as pid 1
+	// but that makes control tty stuff messy. We think.
+	cloneFlags := uintptr(0)
 	for _, v := range []string{"/inito", "/bbin/uinit", "/bbin/rush"} {
 		cmd := exec.Command(v)
 		cmd.Stdin = os.Stdin
@@ -118,7 +119,7 @@ func init() {
 	}
 
 	// This will drop us into a rush prompt, since this is the init for rush.
-	// That's a nice fallback for when everything goes wrong. 
+	// That's a nice fallback for when everything goes wrong.
 	return
 }
 `
@@ -191,6 +192,9 @@ func BBBuild(opts BuildOpts) (ArchiveFiles, error) {
 		if err != nil || fi.IsDir() {
 			return err
 		}
+		if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
 		b, err := ioutil.ReadFile(name)
 		if err != nil {
 			return err
@@ -0,0 +1,22 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"go/format"
+	"testing"
+)
+
+// TestInitGoFormatted checks that the init.go bb writes is as gofmt would
+// write it.
+func TestInitGoFormatted(t *testing.T) {
+	b, err := format.Source([]byte(initGo))
+	if err != nil {
+		t.Fatalf("initGo does not parse: %v", err)
+	}
+	if string(b) != initGo {
+		t.Errorf("initGo is not gofmt'ed; got:\n%s\nwant:\n%s", initGo, b)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+var compressors = map[string]Compressor{
+	"gzip": GzipCompressor{},
+	// The kernel's xz decoder only knows CRC32 checks, and wants a
+	// dictionary no bigger than it has memory for.
+	"xz": ExecCompressor{Name: "xz", Ext: "xz", Args: func(level int) []string {
+		if level == 0 {
+			level = 6
+		}
+		return []string{"-c", "-q", "--check=crc32", fmt.Sprintf("--lzma2=preset=%d,dict=1MiB", level)}
+	}},
+	"zstd": ExecCompressor{Name: "zstd", Ext: "zst", Args: func(level int) []string {
+		args := []string{"-c", "-q"}
+		if level != 0 {
+			args = append(args, "-"+strconv.Itoa(level))
+		}
+		return args
+	}},
+}
+
+// Compressor is a compression format for archives, in framing the kernel
+// can unpack an initramfs from.
+type Compressor interface {
+	// Compress returns a writer which writes what is written to it to w,
+	// compressed at level. Level 0 is the default of the format.
+	//
+	// Close must be called to flush it.
+	Compress(w io.Writer, level int) (io.WriteCloser, error)
+
+	// Extension is the file extension of the format, which goes after
+	// that of the archive.
+	Extension() string
+}
+
+// GetCompressor returns the named Compressor.
+func GetCompressor(name string) (Compressor, error) {
+	c, ok := compressors[name]
+	if !ok {
+		return nil, fmt.Errorf("couldn't find compression format %q", name)
+	}
+	return c, nil
+}
+
+// GzipCompressor is an implementation of Compressor for gzip.
+type GzipCompressor struct{}
+
+// Extension implements Compressor.Extension.
+func (GzipCompressor) Extension() string {
+	return "gz"
+}
+
+// Compress implements Compressor.Compress.
+func (GzipCompressor) Compress(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// ExecCompressor is an implementation of Compressor which runs a program,
+// which compresses its stdin to its stdout.
+type ExecCompressor struct {
+	// Name is the program.
+	Name string
+
+	// Ext is the file extension.
+	Ext string
+
+	// Args returns the arguments to the program to compress at level.
+	Args func(level int) []string
+}
+
+// Extension implements Compressor.Extension.
+func (e ExecCompressor) Extension() string {
+	return e.Ext
+}
+
+// Compress implements Compressor.Compress.
+func (e ExecCompressor) Compress(w io.Writer, level int) (io.WriteCloser, error) {
+	c := exec.Command(e.Name, e.Args(level)...)
+	c.Stdout, c.Stderr = w, os.Stderr
+	in, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	return &cmdWriter{WriteCloser: in, c: c}, nil
+}
+
+// cmdWriter writes to the stdin of c. Close waits for c to finish.
+type cmdWriter struct {
+	io.WriteCloser
+	c *exec.Cmd
+}
+
+func (w *cmdWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	if err := w.c.Wait(); err != nil {
+		return fmt.Errorf("%s: %v", w.c.Path, err)
+	}
+	return nil
+}
+
+// compressArchive runs archive with opts.OutputFile replaced by a pipe,
+// from which what the archiver writes is compressed to the real
+// opts.OutputFile as it is written.
+func compressArchive(a Archiver, opts ArchiveOpts, c Compressor, level int) error {
+	if opts.OutputFile == nil {
+		return fmt.Errorf("can only compress an archive file")
+	}
+	cw, err := c.Compress(opts.OutputFile, level)
+	if err != nil {
+		return err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		cw.Close()
+		return err
+	}
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(cw, r)
+		r.Close()
+		copied <- err
+	}()
+
+	opts.OutputFile = w
+	aerr := a.Archive(opts)
+	w.Close()
+	cerr := <-copied
+	if err := cw.Close(); cerr == nil {
+		cerr = err
+	}
+	if aerr != nil {
+		return aerr
+	}
+	return cerr
+}
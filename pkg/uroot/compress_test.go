@@ -0,0 +1,67 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+func TestCompressArchive(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		decompress []string
+	}{
+		{"gzip", []string{"gzip", "-dc"}},
+		{"xz", []string{"xz", "-dc"}},
+		{"zstd", []string{"zstd", "-dcq"}},
+	} {
+		if _, err := exec.LookPath(tt.decompress[0]); err != nil {
+			t.Logf("Skipping %s: %v", tt.name, err)
+			continue
+		}
+		c, err := GetCompressor(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ioutil.TempFile("", "uroot-compress")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+		opts := ArchiveOpts{ArchiveFiles: NewArchiveFiles(), OutputFile: out}
+		if err := opts.AddRecord(cpio.StaticRecord([]byte("hi\n"), cpio.Info{Name: "etc/motd", Mode: syscall.S_IFREG | 0644})); err != nil {
+			t.Fatal(err)
+		}
+		if err := compressArchive(TarArchiver{}, opts, c, 1); err != nil {
+			t.Errorf("%s: compressArchive: %v", tt.name, err)
+			continue
+		}
+		out.Close()
+
+		got, err := exec.Command(tt.decompress[0], append(tt.decompress[1:], out.Name())...).Output()
+		if err != nil {
+			t.Errorf("%s: decompressing: %v", tt.name, err)
+			continue
+		}
+		var want bytes.Buffer
+		opts.OutputFile = nil
+		opts.ArchiveFiles = NewArchiveFiles()
+		opts.AddRecord(cpio.StaticRecord([]byte("hi\n"), cpio.Info{Name: "etc/motd", Mode: syscall.S_IFREG | 0644}))
+		if err := writeArchive(cpio.NewWriter(newTarWriter(&want)), "newc", opts); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("%s: decompressed archive is not the archive", tt.name)
+		}
+	}
+}
@@ -27,13 +27,23 @@ func (ca CPIOArchiver) Archive(opts ArchiveOpts) error {
 	if err != nil {
 		return err
 	}
+	return writeArchive(archiver.Writer(opts.OutputFile), ca.Format, opts)
+}
 
-	init, err := ramfs.NewInitramfs(archiver.Writer(opts.OutputFile))
+// writeArchive writes the initramfs records, those of opts.BaseArchive, a
+// cpio archive in baseFormat, and opts.ArchiveFiles to w, and then the
+// trailer.
+func writeArchive(w cpio.Writer, baseFormat string, opts ArchiveOpts) error {
+	init, err := ramfs.NewInitramfs(w)
 	if err != nil {
 		return err
 	}
 
 	if opts.BaseArchive != nil {
+		archiver, err := cpio.Format(baseFormat)
+		if err != nil {
+			return err
+		}
 		transform := cpio.MakeReproducible
 
 		// Rename init to inito if there is another init.
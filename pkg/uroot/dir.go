@@ -0,0 +1,78 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+// DirArchiver is an implementation of Archiver which writes the files to a
+// directory, e.g. to be the root of an NFS or 9p mount, rather than to an
+// archive file.
+type DirArchiver struct{}
+
+// DefaultExtension implements Archiver.DefaultExtension.
+func (DirArchiver) DefaultExtension() string {
+	return "dir"
+}
+
+// Archive implements Archiver.Archive. The files are written below
+// opts.OutputDir, which is created if need be.
+//
+// A BaseArchive is read as a newc cpio archive.
+func (DirArchiver) Archive(opts ArchiveOpts) error {
+	if opts.OutputDir == "" {
+		return fmt.Errorf("must give output directory")
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return err
+	}
+	w := &dirWriter{
+		dir:  opts.OutputDir,
+		e:    cpio.NewExtractor(),
+		root: os.Getuid() == 0,
+	}
+	return writeArchive(cpio.NewWriter(w), "newc", opts)
+}
+
+// dirWriter is a cpio.RecordWriter which creates the files of the records
+// below dir.
+type dirWriter struct {
+	dir string
+	e   *cpio.Extractor
+
+	// root is whether files can be given away and device files made.
+	// If not, files belong to the user and device files are left out.
+	root bool
+}
+
+// WriteRecord implements cpio.RecordWriter.WriteRecord.
+func (d *dirWriter) WriteRecord(r cpio.Record) error {
+	if r.Name == cpio.Trailer {
+		return nil
+	}
+	if !d.root {
+		switch r.Mode & syscall.S_IFMT {
+		case syscall.S_IFCHR, syscall.S_IFBLK:
+			log.Printf("Skipping device %q: only root can make it", r.Name)
+			return nil
+		}
+		r.UID, r.GID = uint64(os.Getuid()), uint64(os.Getgid())
+	}
+	r.Name = filepath.Join(d.dir, r.Name)
+	// Directories and files may be there from an earlier build.
+	if r.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		if err := os.Remove(r.Name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return d.e.CreateFile(r)
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+func TestDirArchiver(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "uroot-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	opts := ArchiveOpts{OutputDir: filepath.Join(tmp, "root")}
+	// Twice, as if to update an earlier build.
+	for i := 0; i < 2; i++ {
+		opts.ArchiveFiles = NewArchiveFiles()
+		if err := opts.AddRecord(cpio.StaticRecord([]byte("hi\n"), cpio.Info{Name: "etc/motd", Mode: syscall.S_IFREG | 0644})); err != nil {
+			t.Fatal(err)
+		}
+		if err := opts.AddRecord(cpio.Symlink("bin/sh", "rush")); err != nil {
+			t.Fatal(err)
+		}
+		if err := (DirArchiver{}).Archive(opts); err != nil {
+			t.Fatalf("Archive: %v", err)
+		}
+	}
+
+	if b, err := ioutil.ReadFile(filepath.Join(opts.OutputDir, "etc/motd")); err != nil || string(b) != "hi\n" {
+		t.Errorf("etc/motd: got %q, %v, want %q, nil", b, err, "hi\n")
+	}
+	if l, err := os.Readlink(filepath.Join(opts.OutputDir, "bin/sh")); err != nil || l != "rush" {
+		t.Errorf("bin/sh: got %q, %v, want %q, nil", l, err, "rush")
+	}
+	// From the initramfs records.
+	if fi, err := os.Stat(filepath.Join(opts.OutputDir, "ubin")); err != nil || !fi.IsDir() {
+		t.Errorf("ubin: got %v, %v, want a directory", fi, err)
+	}
+}
@@ -12,19 +12,32 @@ import (
 	"github.com/u-root/u-root/pkg/golang"
 )
 
+// installcommand builds the other commands at boot, and is built by init,
+// so its source is always included.
+const installcommand = "github.com/u-root/u-root/cmds/installcommand"
+
 // SourceBuild is an implementation of Build that compiles the Go toolchain
 // (go, compile, link, asm) and an init process. It includes source files for
 // packages listed in `opts.Packages` to build from scratch.
+//
+// At boot, init builds installcommand and links each command in /buildbin
+// to it. The first time a command is run, installcommand builds it into
+// /ubin, which is before /buildbin in the PATH, so it is only built once.
 func SourceBuild(opts BuildOpts) (ArchiveFiles, error) {
 	af := NewArchiveFiles()
 
+	pkgs := opts.Packages
+	if !contains(pkgs, installcommand) {
+		pkgs = append(pkgs, installcommand)
+	}
+
 	if err := af.AddFile(filepath.Join(opts.Env.GOROOT, "pkg/include"), "go/pkg/include"); err != nil {
 		return ArchiveFiles{}, err
 	}
 
 	log.Printf("Collecting package files and dependencies...")
 	deps := make(map[string]struct{})
-	for _, pkg := range opts.Packages {
+	for _, pkg := range pkgs {
 		// Add high-level packages' src files to archive.
 		p := goListPkg(opts, pkg, &af)
 		if p == nil {
@@ -97,3 +110,12 @@ func goListPkg(opts BuildOpts, pkg string, out *ArchiveFiles) *golang.ListPackag
 	}
 	return p
 }
+
+func contains(s []string, e string) bool {
+	for _, v := range s {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+// TarArchiver is an implementation of Archiver for the tar format.
+type TarArchiver struct{}
+
+// DefaultExtension implements Archiver.DefaultExtension.
+func (TarArchiver) DefaultExtension() string {
+	return "tar"
+}
+
+// Archive implements Archiver.Archive.
+//
+// A BaseArchive is read as a newc cpio archive.
+func (TarArchiver) Archive(opts ArchiveOpts) error {
+	return writeArchive(cpio.NewWriter(newTarWriter(opts.OutputFile)), "newc", opts)
+}
+
+type devInode struct {
+	dev uint64
+	ino uint64
+}
+
+// tarWriter is a cpio.RecordWriter which writes a tar archive.
+type tarWriter struct {
+	w *tar.Writer
+
+	// inodes are the names of files already written with more than one
+	// link, so that the rest are written as hard links to them.
+	inodes map[devInode]string
+}
+
+func newTarWriter(w io.Writer) *tarWriter {
+	return &tarWriter{w: tar.NewWriter(w), inodes: make(map[devInode]string)}
+}
+
+// WriteRecord implements cpio.RecordWriter.WriteRecord. The trailer closes
+// the archive.
+func (t *tarWriter) WriteRecord(r cpio.Record) error {
+	if r.Name == cpio.Trailer {
+		return t.w.Close()
+	}
+	hdr := &tar.Header{
+		Name:     r.Name,
+		Mode:     int64(r.Mode & 07777),
+		Uid:      int(r.UID),
+		Gid:      int(r.GID),
+		ModTime:  time.Unix(int64(r.MTime), 0),
+		Devmajor: int64(r.Rmajor),
+		Devminor: int64(r.Rminor),
+	}
+	var data io.Reader
+	switch r.Mode & syscall.S_IFMT {
+	case syscall.S_IFREG:
+		hdr.Typeflag = tar.TypeReg
+		if r.NLink > 1 {
+			d := devInode{dev: r.Major<<8 | r.Minor, ino: r.Ino}
+			if old, ok := t.inodes[d]; ok {
+				hdr.Typeflag, hdr.Linkname = tar.TypeLink, old
+				break
+			}
+			t.inodes[d] = r.Name
+		}
+		hdr.Size = int64(r.FileSize)
+		data = r
+	case syscall.S_IFDIR:
+		hdr.Typeflag = tar.TypeDir
+		hdr.Name += "/"
+	case syscall.S_IFLNK:
+		hdr.Typeflag = tar.TypeSymlink
+		target, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		hdr.Linkname = string(target)
+	case syscall.S_IFCHR:
+		hdr.Typeflag = tar.TypeChar
+	case syscall.S_IFBLK:
+		hdr.Typeflag = tar.TypeBlock
+	case syscall.S_IFIFO:
+		hdr.Typeflag = tar.TypeFifo
+	default:
+		return fmt.Errorf("%q: can not put mode %#o in a tar archive", r.Name, r.Mode)
+	}
+	if err := t.w.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if data == nil || r.ReadCloser == nil {
+		return nil
+	}
+	_, err := io.CopyN(t.w, data, hdr.Size)
+	return err
+}
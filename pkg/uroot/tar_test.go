@@ -0,0 +1,73 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cpio"
+)
+
+func TestTarWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := cpio.NewWriter(newTarWriter(&b))
+	recs := []cpio.Record{
+		{Info: cpio.Info{Name: "etc", Mode: syscall.S_IFDIR | 0755}},
+		cpio.StaticRecord([]byte("hi\n"), cpio.Info{Name: "etc/motd", Mode: syscall.S_IFREG | 0644}),
+		cpio.Symlink("bin/sh", "rush"),
+		{Info: cpio.Info{Name: "dev/null", Mode: syscall.S_IFCHR | 0666, Rmajor: 1, Rminor: 3}},
+		cpio.StaticRecord([]byte("x"), cpio.Info{Name: "a", Mode: syscall.S_IFREG | 0755, Ino: 7, NLink: 2}),
+		cpio.StaticRecord(nil, cpio.Info{Name: "b", Mode: syscall.S_IFREG | 0755, Ino: 7, NLink: 2}),
+	}
+	if err := w.WriteRecords(recs); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteTrailer(); err != nil {
+		t.Fatal(err)
+	}
+
+	type file struct {
+		name     string
+		typ      byte
+		mode     int64
+		link     string
+		contents string
+		major    int64
+		minor    int64
+	}
+	want := []file{
+		{name: "etc/", typ: tar.TypeDir, mode: 0755},
+		{name: "etc/motd", typ: tar.TypeReg, mode: 0644, contents: "hi\n"},
+		{name: "bin/sh", typ: tar.TypeSymlink, mode: 0777, link: "rush"},
+		{name: "dev/null", typ: tar.TypeChar, mode: 0666, major: 1, minor: 3},
+		{name: "a", typ: tar.TypeReg, mode: 0755, contents: "x"},
+		{name: "b", typ: tar.TypeLink, mode: 0755, link: "a"},
+	}
+	var got []file
+	r := tar.NewReader(&b)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, file{h.Name, h.Typeflag, h.Mode, h.Linkname, string(c), h.Devmajor, h.Devminor})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tar archive: got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/golang"
+)
+
+// cmds returns the import paths of the u-root commands names.
+func cmds(names ...string) []string {
+	pkgs := make([]string, len(names))
+	for i, n := range names {
+		pkgs[i] = "github.com/u-root/u-root/cmds/" + n
+	}
+	return pkgs
+}
+
+// Templates are named sets of packages, which can be given instead of
+// packages. A template may name other templates. The "all" template is all
+// the u-root commands.
+var Templates = map[string][]string{
+	// minimal is enough for a shell to look around and mount things.
+	"minimal": cmds("init", "installcommand", "rush", "cat", "cp", "dmesg", "echo", "ls", "mkdir",
+		"mount", "mv", "ps", "pwd", "rm", "shutdown", "uinit", "umount"),
+	// core is the everyday commands.
+	"core": append([]string{"minimal"}, cmds("acpi", "awk-lite", "blkid", "chmod", "chroot", "cmp",
+		"comm", "cpio", "cpuid", "cryptsetup-lite", "cut", "date", "dd", "dhclient", "dirname",
+		"dmidecode", "dmsetup", "ed", "efibootmgr", "efivar", "false", "find", "flashrom-lite",
+		"free", "fsck.ext", "fsck.vfat", "fwupdate", "getty", "grep", "gunzip", "gzip", "head",
+		"hexdump", "hostname", "hwclock", "id", "insmod", "install", "io", "ip", "kill", "ldd", "ln",
+		"losetup", "lsblk", "lsmod", "lspci", "lsusb", "mdadm-lite", "mdev", "mkfifo", "mknod",
+		"mktemp", "modprobe", "more", "mountall", "msr", "netcat", "ping", "printenv", "readlink",
+		"rmmod", "sed-lite", "seq", "sleep", "sort", "stat", "stty", "sync", "sysctl", "tail", "tar",
+		"tee", "top", "tr", "true", "truncate", "uname", "uniq", "uptime", "vmstat", "watch", "wc",
+		"wget", "which", "xargs", "zcat")...),
+	// boot is what it takes to find and boot a kernel.
+	"boot": append([]string{"minimal"}, cmds("dhclient", "insmod", "ip", "kexec", "modprobe",
+		"pxeboot", "switch_root", "vboot", "wget")...),
+}
+
+// ParseTemplates parses templates, one per line, as a name, a colon, and
+// the packages and templates in it. Blank lines and those which start with
+// # are ignored.
+//
+// E.g.
+//     # A shell and the network commands.
+//     net: minimal github.com/u-root/u-root/cmds/ip github.com/u-root/u-root/cmds/wget
+func ParseTemplates(r io.Reader) (map[string][]string, error) {
+	t := make(map[string][]string)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		i := strings.Index(l, ":")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: %q is not NAME: PACKAGES", n, l)
+		}
+		t[strings.TrimSpace(l[:i])] = strings.Fields(l[i+1:])
+	}
+	return t, s.Err()
+}
+
+// ExpandTemplates replaces the names of templates in pkgs with their
+// packages. templates are looked in first, and then Templates.
+func ExpandTemplates(env golang.Environ, pkgs []string, templates map[string][]string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	var expand func(pkgs []string, in []string) error
+	expand = func(pkgs []string, in []string) error {
+		for _, p := range pkgs {
+			t, ok := templates[p]
+			if !ok {
+				t, ok = Templates[p]
+			}
+			if !ok && p == "all" {
+				all, err := DefaultPackageImports(env)
+				if err != nil {
+					return err
+				}
+				t, ok = all, true
+			}
+			if !ok {
+				if !seen[p] {
+					seen[p] = true
+					out = append(out, p)
+				}
+				continue
+			}
+			for _, n := range in {
+				if n == p {
+					return fmt.Errorf("template %q includes itself: %v", p, strings.Join(append(in, p), " -> "))
+				}
+			}
+			if err := expand(t, append(in, p)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := expand(pkgs, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
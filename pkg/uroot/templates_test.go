@@ -0,0 +1,86 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/golang"
+)
+
+func TestParseTemplates(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want map[string][]string
+		err  bool
+	}{
+		{in: "", want: map[string][]string{}},
+		{in: "# comment\n\nnet: minimal a/b\n  x:y  z \n", want: map[string][]string{"net": {"minimal", "a/b"}, "x": {"y", "z"}}},
+		{in: "empty:\n", want: map[string][]string{"empty": {}}},
+		{in: "no colon\n", err: true},
+		{in: ": a\n", err: true},
+	} {
+		got, err := ParseTemplates(strings.NewReader(tt.in))
+		if (err != nil) != tt.err {
+			t.Errorf("ParseTemplates(%q): got err %v, want err %v", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTemplates(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTemplates(t *testing.T) {
+	custom := map[string][]string{
+		"a":    {"x", "b", "y"},
+		"b":    {"y", "z"},
+		"loop": {"x", "loop2"},
+		// Custom templates come before the others.
+		"loop2":   {"loop"},
+		"minimal": {"m"},
+	}
+	for _, tt := range []struct {
+		in   []string
+		want []string
+		err  bool
+	}{
+		{in: []string{"p", "q"}, want: []string{"p", "q"}},
+		{in: []string{"a"}, want: []string{"x", "y", "z"}},
+		{in: []string{"b", "a", "b"}, want: []string{"y", "z", "x"}},
+		{in: []string{"minimal"}, want: []string{"m"}},
+		{in: []string{"boot"}, want: append([]string{"m"}, cmds("dhclient", "insmod", "ip", "kexec", "modprobe", "pxeboot", "switch_root", "vboot", "wget")...)},
+		{in: []string{"loop"}, err: true},
+	} {
+		got, err := ExpandTemplates(golang.Default(), tt.in, custom)
+		if (err != nil) != tt.err {
+			t.Errorf("ExpandTemplates(%q): got err %v, want err %v", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandTemplates(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestTemplateCommands checks that the templates only name commands which
+// are there.
+func TestTemplateCommands(t *testing.T) {
+	env := golang.Default()
+	for name := range Templates {
+		pkgs, err := ExpandTemplates(env, []string{name}, nil)
+		if err != nil {
+			t.Errorf("ExpandTemplates(%q): %v", name, err)
+			continue
+		}
+		for _, p := range pkgs {
+			if _, err := env.FindPackageDir(p); err != nil {
+				t.Errorf("template %q: %v", name, err)
+			}
+		}
+	}
+}
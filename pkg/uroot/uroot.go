@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/u-root/u-root/pkg/cpio"
 	"github.com/u-root/u-root/pkg/golang"
@@ -26,6 +27,8 @@ var (
 		"cpio": CPIOArchiver{
 			Format: "newc",
 		},
+		"tar": TarArchiver{},
+		"dir": DirArchiver{},
 	}
 )
 
@@ -41,7 +44,7 @@ type Opts struct {
 
 	// Archiver is the initramfs archival format.
 	//
-	// Only "cpio" is currently supported.
+	// This can currently be "cpio", "tar" or "dir".
 	Archiver Archiver
 
 	// Packages are the Go packages to add to the archive.
@@ -55,6 +58,10 @@ type Opts struct {
 	// ExtraFiles are files to add to the archive in addition to the Go
 	// packages.
 	//
+	// Each is either a host path, which is put at the same path in the
+	// archive, or SRC:DEST, which puts the host path SRC at DEST in the
+	// archive. Directories are added with everything below them.
+	//
 	// Shared library dependencies will automatically also be added to the
 	// archive using ldd.
 	ExtraFiles []string
@@ -65,6 +72,18 @@ type Opts struct {
 	// OutputFile is the archive output file.
 	OutputFile *os.File
 
+	// OutputDir is the output directory of an Archiver, such as
+	// DirArchiver, which writes a directory rather than a file.
+	OutputDir string
+
+	// Compressor, if not nil, compresses the archive as it is written to
+	// OutputFile.
+	Compressor Compressor
+
+	// CompressLevel is the level to compress at; 0 is the default of
+	// Compressor.
+	CompressLevel int
+
 	// BaseArchive is an existing initramfs to include in the resulting
 	// initramfs.
 	BaseArchive *os.File
@@ -77,13 +96,32 @@ type Opts struct {
 	UseExistingInit bool
 }
 
+// extraFile parses a file to add to the archive, SRC or SRC:DEST. It
+// returns the absolute path of SRC and where in the archive it goes: DEST,
+// or without it, the same path as SRC. Either way, it is relative to the
+// root of the archive.
+func extraFile(file string) (string, string, error) {
+	src, dest := file, ""
+	if i := strings.Index(file, ":"); i >= 0 {
+		src, dest = file[:i], file[i+1:]
+	}
+	path, err := filepath.Abs(src)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't find absolute path for %q: %v", src, err)
+	}
+	if dest == "" {
+		dest = path
+	}
+	return path, strings.TrimLeft(filepath.Clean(dest), "/"), nil
+}
+
 // CreateInitramfs creates an initramfs built to `opts`' specifications.
 func CreateInitramfs(opts Opts) error {
 	if _, err := os.Stat(opts.TempDir); os.IsNotExist(err) {
 		return fmt.Errorf("temp dir %q must exist: %v", opts.TempDir, err)
 	}
-	if opts.OutputFile == nil {
-		return fmt.Errorf("must give output file")
+	if opts.OutputFile == nil && opts.OutputDir == "" {
+		return fmt.Errorf("must give output file or directory")
 	}
 
 	var importPaths []string
@@ -107,6 +145,21 @@ func CreateInitramfs(opts Opts) error {
 		}
 	}
 
+	// Commands which do not build for the target, e.g. because they are
+	// only for one architecture, are left out.
+	buildErrs, err := opts.Env.BuildErrors(importPaths)
+	if err != nil {
+		return err
+	}
+	var pkgs []string
+	for _, p := range importPaths {
+		if err, ok := buildErrs[p]; ok {
+			log.Printf("Skipping %q, which does not build for %s_%s: %v", p, opts.Env.GOOS, opts.Env.GOARCH, err)
+			continue
+		}
+		pkgs = append(pkgs, p)
+	}
+
 	builderTmpDir, err := ioutil.TempDir(opts.TempDir, "builder")
 	if err != nil {
 		return err
@@ -115,7 +168,7 @@ func CreateInitramfs(opts Opts) error {
 	// Build the packages.
 	bOpts := BuildOpts{
 		Env:      opts.Env,
-		Packages: importPaths,
+		Packages: pkgs,
 		TempDir:  builderTmpDir,
 	}
 	files, err := opts.Builder(bOpts)
@@ -132,6 +185,7 @@ func CreateInitramfs(opts Opts) error {
 	archive := ArchiveOpts{
 		ArchiveFiles:    files,
 		OutputFile:      opts.OutputFile,
+		OutputDir:       opts.OutputDir,
 		BaseArchive:     opts.BaseArchive,
 		UseExistingInit: opts.UseExistingInit,
 		TempDir:         archiveTmpDir,
@@ -139,17 +193,21 @@ func CreateInitramfs(opts Opts) error {
 
 	// Add files from command line.
 	for _, file := range opts.ExtraFiles {
-		path, err := filepath.Abs(file)
+		path, dest, err := extraFile(file)
 		if err != nil {
-			return fmt.Errorf("couldn't find absolute path for %q: %v", file, err)
+			return err
 		}
-		if err := archive.AddFile(path, path[1:]); err != nil {
+		if err := archive.AddFile(path, dest); err != nil {
 			return fmt.Errorf("couldn't add %q to archive: %v", file, err)
 		}
 
-		// Pull dependencies in the case of binaries. If `path` is not
-		// a binary, `libs` will just be empty.
-		libs, err := ldd.List([]string{path})
+		// Pull dependencies in the case of binaries. If `path` holds
+		// no binaries, `libs` will just be empty.
+		bins, err := regularFiles(path)
+		if err != nil {
+			return err
+		}
+		libs, err := ldd.List(bins)
 		if err != nil {
 			return fmt.Errorf("couldn't list ldd dependencies for %q: %v", file, err)
 		}
@@ -161,12 +219,36 @@ func CreateInitramfs(opts Opts) error {
 	}
 
 	// Finally, write the archive.
-	if err := opts.Archiver.Archive(archive); err != nil {
+	if opts.Compressor != nil {
+		err = compressArchive(opts.Archiver, archive, opts.Compressor, opts.CompressLevel)
+	} else {
+		err = opts.Archiver.Archive(archive)
+	}
+	if err != nil {
 		return fmt.Errorf("error archiving: %v", err)
 	}
 	return nil
 }
 
+// regularFiles returns path if it is a file, or the regular files below it
+// if it is a directory.
+func regularFiles(path string) ([]string, error) {
+	if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err := filepath.Walk(path, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	return files, err
+}
+
 // BuildOpts are arguments to the Build function.
 type BuildOpts struct {
 	// Env is the Go environment to use to compile and link packages.
@@ -204,6 +286,10 @@ type ArchiveOpts struct {
 	// OutputFile is the file to write to.
 	OutputFile *os.File
 
+	// OutputDir is the directory to write to, for archivers which write
+	// a directory.
+	OutputDir string
+
 	// BaseArchive is an existing archive to add files to.
 	//
 	// BaseArchive may be nil.
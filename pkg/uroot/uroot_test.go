@@ -0,0 +1,39 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uroot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtraFile(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := wd[1:]
+	for _, tt := range []struct {
+		file, path, dest string
+	}{
+		// Without DEST, SRC goes where it is.
+		{"/bin/bash", "/bin/bash", "bin/bash"},
+		{"etc/motd", filepath.Join(wd, "etc/motd"), filepath.Join(rel, "etc/motd")},
+		{"./x/../motd", filepath.Join(wd, "motd"), filepath.Join(rel, "motd")},
+		// With it, DEST is where it goes, absolute or not.
+		{"/bin/bash:/bin/sh", "/bin/bash", "bin/sh"},
+		{"/bin/bash:bin/sh", "/bin/bash", "bin/sh"},
+		{"motd:/etc/motd", filepath.Join(wd, "motd"), "etc/motd"},
+		{"motd:etc//x/../motd", filepath.Join(wd, "motd"), "etc/motd"},
+		{"/lib/modules:/", "/lib/modules", ""},
+		{"/etc/motd:", "/etc/motd", "etc/motd"},
+	} {
+		path, dest, err := extraFile(tt.file)
+		if err != nil || path != tt.path || dest != tt.dest {
+			t.Errorf("extraFile(%q): got %q, %q, %v, want %q, %q, nil", tt.file, path, dest, err, tt.path, tt.dest)
+		}
+	}
+}
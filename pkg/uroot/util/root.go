@@ -14,6 +14,8 @@ import (
 	"os"
 	"runtime"
 	"syscall"
+
+	"github.com/u-root/u-root/pkg/devfs"
 )
 
 const (
@@ -103,6 +105,19 @@ func (d Dev) String() string {
 	return fmt.Sprintf("dev %q (mode %#o; magic %d)", d.Name, d.Mode, d.Dev)
 }
 
+// DevFS makes the essential device files in Dir which are not there.
+type DevFS struct {
+	Dir string
+}
+
+func (d DevFS) Create() error {
+	return devfs.Populate(d.Dir)
+}
+
+func (d DevFS) String() string {
+	return fmt.Sprintf("device files in %q", d.Dir)
+}
+
 type Mount struct {
 	Source string
 	Target string
@@ -138,14 +153,11 @@ var (
 		Mount{Target: "/tmp", FSType: "tmpfs"},
 
 		Dir{Name: "/dev", Mode: 0777},
-		Dev{Name: "/dev/tty", Mode: syscall.S_IFCHR | 0666, Dev: 0x0500},
-		Dev{Name: "/dev/urandom", Mode: syscall.S_IFCHR | 0444, Dev: 0x0109},
-		Dev{Name: "/dev/port", Mode: syscall.S_IFCHR | 0640, Dev: 0x0104},
-
 		// Kernel must be compiled with CONFIG_DEVTMPFS.
-		// Note that things kind of work even if this mount fails.
-		// TODO: move the Dir commands above below this line?
+		// If it is not, DevFS makes the device files we can not do
+		// without; with a devtmpfs they are all there already.
 		Mount{Target: "/dev", FSType: "devtmpfs"},
+		DevFS{Dir: "/dev"},
 
 		Dir{Name: "/dev/pts", Mode: 0777},
 		Mount{Target: "/dev/pts", FSType: "devpts", Opts: "newinstance,ptmxmode=666,gid=5,mode=620"},
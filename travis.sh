@@ -12,20 +12,20 @@ echo "Build u-root"
 
 echo "-----------------------> Initial bb test"
  (./u-root -build=bb)
-mv /tmp/initramfs.linux_amd64.cpio /tmp/i2
+mv /tmp/initramfs_linux_amd64.cpio /tmp/i2
 
 # Test for reproducible initramfs in busybox mode
 echo "-----------------------> Second bb test"
  (./u-root -build=bb)
 
 echo "-----------------------> cmp bb test output (test reproducibility)"
-cmp /tmp/initramfs.linux_amd64.cpio /tmp/i2
+cmp /tmp/initramfs_linux_amd64.cpio /tmp/i2
  which go
 
 # Test all architectures we care about. At some point we may just
 # grow the build matrix.
 echo "-----------------------> ARM64 test build"
- (GOARCH=arm64 ./u-root -build=bb)
+ (./u-root -build=bb -arch=arm64)
 echo "-----------------------> ppc64le test build"
  (GOARCH=ppc64le ./u-root -build=bb)
 
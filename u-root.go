@@ -19,18 +19,43 @@ import (
 // Flags for u-root builder.
 var (
 	build  = flag.String("build", "source", "u-root build format (e.g. bb or source)")
-	format = flag.String("format", "cpio", "Archival format (e.g. cpio)")
+	format = flag.String("format", "cpio", "Archival format: cpio, tar, or dir for a directory tree, e.g. for an NFS or 9p root")
+
+	compress      = flag.String("compress", "", "Compress the archive with gzip, xz or zstd as it is written")
+	compressLevel = flag.Int("compresslevel", 0, "Compression level; 0 is the default of the -compress format")
 
 	tmpDir = flag.String("tmpdir", "", "Temporary directory to put binaries in.")
 
 	base            = flag.String("base", "", "Base archive to add files to")
 	useExistingInit = flag.Bool("useinit", false, "Use existing init from base archive (only if --base was specified).")
 
-	extraFiles = flag.String("files", "", "Additional files, directories, and binaries (with their ldd dependencies) to add to archive.")
+	outputPath = flag.String("o", "", "Path to output initramfs file, or directory for -format=dir.")
+
+	goarch = flag.String("arch", "", "Architecture to build for, e.g. amd64, arm, arm64, riscv64, mips; the default is $GOARCH or the host's.")
+	goos   = flag.String("os", "", "OS to build for; the default is $GOOS or the host's.")
+
+	templateFile = flag.String("templates", "", "File of more templates, one per line as NAME: PACKAGES...")
 
-	outputPath = flag.String("o", "", "Path to output initramfs file.")
+	extraFiles multiFlag
 )
 
+func init() {
+	flag.Var(&extraFiles, "files", "Additional files, directories, and binaries (with their ldd dependencies) to add to archive. May be given more than once; SRC:DEST puts SRC at DEST in the archive.")
+}
+
+// multiFlag is a flag which may be given more than once. Each value may
+// hold several space-separated items.
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	return strings.Join(*m, " ")
+}
+
+func (m *multiFlag) Set(s string) error {
+	*m = append(*m, strings.Fields(s)...)
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
@@ -42,6 +67,12 @@ func main() {
 
 func Main() error {
 	env := golang.Default()
+	if *goarch != "" {
+		env.GOARCH = *goarch
+	}
+	if *goos != "" {
+		env.GOOS = *goos
+	}
 	if env.CgoEnabled {
 		log.Printf("Disabling CGO for u-root...")
 		env.CgoEnabled = false
@@ -60,6 +91,14 @@ func Main() error {
 		return err
 	}
 
+	var compressor uroot.Compressor
+	if *compress != "" {
+		compressor, err = uroot.GetCompressor(*compress)
+		if err != nil {
+			return err
+		}
+	}
+
 	tempDir := *tmpDir
 	if tempDir == "" {
 		var err error
@@ -79,26 +118,35 @@ func Main() error {
 	// Currently allowed formats:
 	//   Go package imports; e.g. github.com/u-root/u-root/cmds/ls
 	//   Paths to Go package directories; e.g. $GOPATH/src/github.com/u-root/u-root/cmds/*
+	//   Templates; e.g. core, minimal, boot or all
 	pkgs := flag.Args()
 	if len(pkgs) == 0 {
-		var err error
-		pkgs, err = uroot.DefaultPackageImports(env)
+		pkgs = []string{"all"}
+	}
+	var templates map[string][]string
+	if *templateFile != "" {
+		t, err := os.Open(*templateFile)
 		if err != nil {
 			return err
 		}
+		templates, err = uroot.ParseTemplates(t)
+		t.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", *templateFile, err)
+		}
 	}
-
-	// Open the target initramfs file.
-	filename := *outputPath
-	if filename == "" {
-		filename = fmt.Sprintf("/tmp/initramfs.%s_%s.%s", env.GOOS, env.GOARCH, archiver.DefaultExtension())
-	}
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	pkgs, err = uroot.ExpandTemplates(env, pkgs, templates)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
+	filename := *outputPath
+	if filename == "" {
+		filename = fmt.Sprintf("/tmp/initramfs_%s_%s.%s", env.GOOS, env.GOARCH, archiver.DefaultExtension())
+		if compressor != nil {
+			filename += "." + compressor.Extension()
+		}
+	}
 	var baseFile *os.File
 	if *base != "" {
 		var err error
@@ -109,18 +157,40 @@ func Main() error {
 		defer baseFile.Close()
 	}
 
+	// Open the target initramfs file.
+	var f *os.File
+	var dir string
+	if _, ok := archiver.(uroot.DirArchiver); ok {
+		dir = filename
+	} else {
+		f, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
 	opts := uroot.Opts{
 		Env:             env,
 		Builder:         builder,
 		Archiver:        archiver,
 		TempDir:         tempDir,
 		Packages:        pkgs,
-		ExtraFiles:      strings.Fields(*extraFiles),
+		ExtraFiles:      extraFiles,
 		OutputFile:      f,
+		OutputDir:       dir,
+		Compressor:      compressor,
+		CompressLevel:   *compressLevel,
 		BaseArchive:     baseFile,
 		UseExistingInit: *useExistingInit,
 	}
 	if err := uroot.CreateInitramfs(opts); err != nil {
+		// Don't leave an empty or partial archive behind, e.g. when
+		// the build fails.
+		if f != nil {
+			f.Close()
+			os.Remove(filename)
+		}
 		return err
 	}
 	log.Printf("Filename is %s", filename)
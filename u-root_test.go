@@ -0,0 +1,42 @@
+// Copyright 2017 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultiFlag(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want multiFlag
+	}{
+		{nil, nil},
+		{[]string{"-files", "/bin/bash"}, multiFlag{"/bin/bash"}},
+		{[]string{"-files", "/bin/bash:/bin/sh"}, multiFlag{"/bin/bash:/bin/sh"}},
+		{[]string{"-files", "motd:etc/motd"}, multiFlag{"motd:etc/motd"}},
+		{[]string{"-files", "/bin/bash /etc/motd:/etc/issue"}, multiFlag{"/bin/bash", "/etc/motd:/etc/issue"}},
+		{[]string{"-files", "/bin/bash", "-files=/bin/ls:/bbin/ls", "-files", " a  b "}, multiFlag{"/bin/bash", "/bin/ls:/bbin/ls", "a", "b"}},
+	} {
+		var m multiFlag
+		fs := flag.NewFlagSet("u-root", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		fs.Var(&m, "files", "")
+		if err := fs.Parse(tt.args); err != nil {
+			t.Errorf("Parse(%q): %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(m, tt.want) {
+			t.Errorf("Parse(%q): got %q, want %q", tt.args, m, tt.want)
+		}
+		if got, want := m.String(), strings.Join(tt.want, " "); got != want {
+			t.Errorf("String(): got %q, want %q", got, want)
+		}
+	}
+}